@@ -23,16 +23,20 @@ import (
 	"github.com/rook/rook/pkg/operator/cassandra/constants"
 	"github.com/rook/rook/pkg/operator/cassandra/controller"
 	"github.com/rook/rook/pkg/operator/k8sutil"
+	"github.com/rook/rook/pkg/operator/leader"
 	"github.com/rook/rook/pkg/util/flags"
 	"github.com/spf13/cobra"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apiserver/pkg/server"
 	kubeinformers "k8s.io/client-go/informers"
 	"k8s.io/client-go/informers/internalinterfaces"
 	"time"
 )
 
-const resyncPeriod = time.Second * 30
+const (
+	resyncPeriod = time.Second * 30
+	// the name of the configmap storing the leader of the operator replicas
+	leaderLockName = "rook-cassandra-operator-leader"
+)
 
 var operatorCmd = &cobra.Command{
 	Use:   "operator",
@@ -42,6 +46,8 @@ https://github.com/rook/rook`,
 }
 
 func init() {
+	rook.AddMetricsFlags(operatorCmd.Flags())
+	rook.AddLeaderElectionFlags(operatorCmd.Flags())
 	flags.SetFlagsFromEnv(operatorCmd.Flags(), rook.RookEnvVarPrefix)
 
 	operatorCmd.RunE = startOperator
@@ -50,6 +56,7 @@ func init() {
 func startOperator(cmd *cobra.Command, args []string) error {
 	rook.SetLogLevel()
 	rook.LogStartupInfo(operatorCmd.Flags())
+	rook.StartMetricsServer()
 
 	kubeClient, _, rookClient, err := rook.GetClientset()
 	if err != nil {
@@ -89,15 +96,15 @@ func startOperator(cmd *cobra.Command, args []string) error {
 		kubeInformerFactory.Core().V1().Pods(),
 	)
 
-	// Create a channel to receive OS signals
-	stopCh := server.SetupSignalHandler()
-
-	// Start the informer factories
-	go kubeInformerFactory.Start(stopCh)
-	go rookInformerFactory.Start(stopCh)
+	err = leader.Run(kubeClient, leaderLockName, func(stopCh chan struct{}) error {
+		// Start the informer factories
+		go kubeInformerFactory.Start(stopCh)
+		go rookInformerFactory.Start(stopCh)
 
-	// Start the controller
-	if err = c.Run(1, stopCh); err != nil {
+		// Start the controller
+		return c.Run(1, stopCh)
+	})
+	if err != nil {
 		logger.Fatalf("Error running controller: %s", err.Error())
 	}
 
@@ -59,7 +59,11 @@ func init() {
 		agentCmd,
 		osdCmd,
 		configCmd,
-		nfsCmd)
+		nfsCmd,
+		cleanCmd,
+		monCmd,
+		backupIdentityCmd,
+		restoreIdentityCmd)
 }
 
 func createContext() *clusterd.Context {
@@ -0,0 +1,80 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ceph
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/rook/rook/cmd/rook/rook"
+	"github.com/rook/rook/pkg/daemon/ceph/cleanup"
+	"github.com/rook/rook/pkg/operator/ceph/cluster"
+	opcleanup "github.com/rook/rook/pkg/operator/ceph/cluster/cleanup"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	"github.com/rook/rook/pkg/util/flags"
+	"github.com/spf13/cobra"
+)
+
+var cleanCmd = &cobra.Command{
+	Use:   "clean",
+	Short: "Wipes the data and the devices of a deleted cluster on the node",
+}
+
+var cleanClusterID string
+
+func init() {
+	cleanCmd.Flags().StringVar(&cfg.nodeName, "node-name", os.Getenv("HOSTNAME"), "the host name of the node")
+	cleanCmd.Flags().StringVar(&clusterInfo.Name, "cluster-name", "", "the namespace of the deleted cluster")
+	cleanCmd.Flags().StringVar(&cleanClusterID, "cluster-id", "", "the UID of the deleted cluster CRD")
+	cleanCmd.Flags().StringVar(&clusterInfo.FSID, "fsid", "", "the fsid of the deleted cluster, only its devices are zapped")
+	cleanCmd.Flags().StringVar(&cfg.dataDir, "config-dir", "/var/lib/rook", "the data dir of the cluster")
+	flags.SetFlagsFromEnv(cleanCmd.Flags(), rook.RookEnvVarPrefix)
+
+	cleanCmd.RunE = cleanHost
+}
+
+func cleanHost(cmd *cobra.Command, args []string) error {
+	required := []string{"node-name", "cluster-name", "fsid"}
+	if err := flags.VerifyRequiredFlags(cleanCmd, required); err != nil {
+		return err
+	}
+
+	rook.SetLogLevel()
+	rook.LogStartupInfo(cleanCmd.Flags())
+
+	clientset, _, _, err := rook.GetClientset()
+	if err != nil {
+		rook.TerminateFatal(fmt.Errorf("failed to init k8s client. %+v", err))
+	}
+	context := createContext()
+	context.Clientset = clientset
+
+	zapped, err := cleanup.CleanHost(context, cfg.dataDir, clusterInfo.FSID)
+	status := opcleanup.NodeStatus{Completed: err == nil, ZappedDevices: zapped}
+	if err != nil {
+		// the failure is reported in the status, the job would only fail again if it was restarted
+		logger.Errorf("failed to clean up node %s. %+v", cfg.nodeName, err)
+		status.Message = err.Error()
+	}
+
+	ownerRef := cluster.ClusterOwnerRef(clusterInfo.Name, cleanClusterID)
+	kv := k8sutil.NewConfigMapKVStore(clusterInfo.Name, clientset, ownerRef)
+	if err := opcleanup.UpdateNodeStatus(kv, cfg.nodeName, status); err != nil {
+		rook.TerminateFatal(err)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ceph
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/rook/rook/cmd/rook/rook"
+	"github.com/rook/rook/pkg/operator/ceph/cluster"
+	"github.com/rook/rook/pkg/util/flags"
+	"github.com/spf13/cobra"
+)
+
+// the file name writing the bundle to stdout or reading it from stdin
+const identityStdio = "-"
+
+var backupIdentityCmd = &cobra.Command{
+	Use:   "backup-identity",
+	Short: "Exports the identity of a cluster (fsid, keys, mon endpoints and OSD config) to a bundle",
+}
+
+var restoreIdentityCmd = &cobra.Command{
+	Use:   "restore-identity",
+	Short: "Restores the identity of a cluster from a bundle so a new cluster CRD adopts the existing mons and OSDs",
+}
+
+var (
+	identityNamespace  string
+	identityFile       string
+	identityPassphrase string
+)
+
+func init() {
+	backupIdentityCmd.Flags().StringVar(&identityNamespace, "namespace", "rook-ceph", "the namespace of the cluster")
+	restoreIdentityCmd.Flags().StringVar(&identityNamespace, "namespace", "", "the namespace of the restored cluster (default the namespace of the backed up cluster)")
+	for _, command := range []*cobra.Command{backupIdentityCmd, restoreIdentityCmd} {
+		command.Flags().StringVar(&identityFile, "file", identityStdio, "the path of the bundle, or - for stdout/stdin")
+		command.Flags().StringVar(&identityPassphrase, "passphrase", "", "the passphrase encrypting the bundle. the bundle is not encrypted if empty")
+		flags.SetFlagsFromEnv(command.Flags(), rook.RookEnvVarPrefix)
+	}
+
+	backupIdentityCmd.RunE = backupIdentity
+	restoreIdentityCmd.RunE = restoreIdentity
+}
+
+func backupIdentity(cmd *cobra.Command, args []string) error {
+	rook.SetLogLevel()
+
+	clientset, _, _, err := rook.GetClientset()
+	if err != nil {
+		return fmt.Errorf("failed to init k8s client. %+v", err)
+	}
+	bundle, err := cluster.BackupIdentity(clientset, identityNamespace)
+	if err != nil {
+		return err
+	}
+	data, err := cluster.EncodeIdentity(bundle, identityPassphrase)
+	if err != nil {
+		return err
+	}
+	if identityPassphrase == "" {
+		logger.Warningf("the identity bundle is not encrypted and contains the keys of the cluster. store it securely")
+	}
+
+	if identityFile == identityStdio {
+		_, err = os.Stdout.Write(data)
+	} else {
+		err = ioutil.WriteFile(identityFile, data, 0600)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write the identity bundle. %+v", err)
+	}
+	return nil
+}
+
+func restoreIdentity(cmd *cobra.Command, args []string) error {
+	rook.SetLogLevel()
+
+	var data []byte
+	var err error
+	if identityFile == identityStdio {
+		data, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		data, err = ioutil.ReadFile(identityFile)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read the identity bundle. %+v", err)
+	}
+	bundle, err := cluster.DecodeIdentity(data, identityPassphrase)
+	if err != nil {
+		return err
+	}
+	if identityNamespace != "" {
+		bundle.Namespace = identityNamespace
+	}
+
+	clientset, _, _, err := rook.GetClientset()
+	if err != nil {
+		return fmt.Errorf("failed to init k8s client. %+v", err)
+	}
+	return cluster.RestoreIdentity(clientset, bundle)
+}
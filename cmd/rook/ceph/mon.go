@@ -0,0 +1,119 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ceph
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rook/rook/cmd/rook/rook"
+	rookclient "github.com/rook/rook/pkg/client/clientset/versioned"
+	"github.com/rook/rook/pkg/operator/ceph/cluster/mon"
+	"github.com/rook/rook/pkg/util/flags"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var monCmd = &cobra.Command{
+	Use:   "mon",
+	Short: "Manages the mons of a cluster",
+}
+
+var restoreQuorumCmd = &cobra.Command{
+	Use:   "restore-quorum",
+	Short: "Restores the mon quorum of a cluster from a single surviving mon after the majority of the mons was lost",
+}
+
+var (
+	restoreQuorumNamespace string
+	restoreQuorumSurvivor  string
+	restoreQuorumTimeout   time.Duration
+	restoreQuorumInterval  = 5 * time.Second
+)
+
+func init() {
+	restoreQuorumCmd.Flags().StringVar(&restoreQuorumNamespace, "namespace", "rook-ceph", "the namespace of the cluster")
+	restoreQuorumCmd.Flags().StringVar(&restoreQuorumSurvivor, "from", "", "the name of the surviving mon (a, b, c...)")
+	restoreQuorumCmd.Flags().DurationVar(&restoreQuorumTimeout, "timeout", 30*time.Minute, "the time to wait for the operator to restore the quorum")
+	flags.SetFlagsFromEnv(restoreQuorumCmd.Flags(), rook.RookEnvVarPrefix)
+
+	restoreQuorumCmd.RunE = restoreQuorum
+	monCmd.AddCommand(restoreQuorumCmd)
+}
+
+func restoreQuorum(cmd *cobra.Command, args []string) error {
+	required := []string{"namespace", "from"}
+	if err := flags.VerifyRequiredFlags(restoreQuorumCmd, required); err != nil {
+		return err
+	}
+
+	rook.SetLogLevel()
+
+	_, _, rookClientset, err := rook.GetClientset()
+	if err != nil {
+		return fmt.Errorf("failed to init k8s client. %+v", err)
+	}
+	if err := requestQuorumRestore(rookClientset, restoreQuorumNamespace, restoreQuorumSurvivor, restoreQuorumTimeout); err != nil {
+		return err
+	}
+
+	logger.Infof("restored the mon quorum of cluster %s from mon %s", restoreQuorumNamespace, restoreQuorumSurvivor)
+	return nil
+}
+
+// requestQuorumRestore annotates the cluster CRD so the operator restores the mon quorum, then waits for the result
+func requestQuorumRestore(rookClientset rookclient.Interface, namespace, survivor string, timeout time.Duration) error {
+	clusters, err := rookClientset.CephV1().CephClusters(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get the cluster in namespace %s. %+v", namespace, err)
+	}
+	if len(clusters.Items) != 1 {
+		return fmt.Errorf("expected one cluster in namespace %s, found %d", namespace, len(clusters.Items))
+	}
+	cluster := &clusters.Items[0]
+
+	if cluster.Annotations == nil {
+		cluster.Annotations = map[string]string{}
+	}
+	cluster.Annotations[mon.RestoreQuorumAnnotation] = survivor
+	delete(cluster.Annotations, mon.RestoreQuorumStatusAnnotation)
+	delete(cluster.Annotations, mon.RestoreQuorumErrorAnnotation)
+	if _, err := rookClientset.CephV1().CephClusters(namespace).Update(cluster); err != nil {
+		return fmt.Errorf("failed to request the mon quorum restore of cluster %s. %+v", namespace, err)
+	}
+	logger.Infof("requested the operator to restore the mon quorum of cluster %s from mon %s", namespace, survivor)
+
+	for start := time.Now(); time.Since(start) < timeout; time.Sleep(restoreQuorumInterval) {
+		latest, err := rookClientset.CephV1().CephClusters(namespace).Get(cluster.Name, metav1.GetOptions{})
+		if err != nil {
+			logger.Warningf("failed to get the status of the mon quorum restore. %+v", err)
+			continue
+		}
+
+		switch latest.Annotations[mon.RestoreQuorumStatusAnnotation] {
+		case mon.RestoreQuorumRestored:
+			return nil
+		case mon.RestoreQuorumFailed:
+			return fmt.Errorf("the operator failed to restore the mon quorum. %s", latest.Annotations[mon.RestoreQuorumErrorAnnotation])
+		case mon.RestoreQuorumRestoring:
+			logger.Infof("waiting for the operator to restore the mon quorum")
+		default:
+			logger.Infof("waiting for the operator to start the mon quorum restore")
+		}
+	}
+	return fmt.Errorf("timed out waiting for the mon quorum restore of cluster %s. check the operator log", namespace)
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ceph
+
+import (
+	"testing"
+	"time"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	rookfake "github.com/rook/rook/pkg/client/clientset/versioned/fake"
+	"github.com/rook/rook/pkg/operator/ceph/cluster/mon"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRequestQuorumRestore(t *testing.T) {
+	restoreQuorumInterval = time.Millisecond
+	cluster := &cephv1.CephCluster{ObjectMeta: metav1.ObjectMeta{
+		Name:        "rook-ceph",
+		Namespace:   "rook-ceph",
+		Annotations: map[string]string{mon.RestoreQuorumStatusAnnotation: mon.RestoreQuorumFailed},
+	}}
+	clientset := rookfake.NewSimpleClientset(cluster)
+
+	// the operator never handles the request
+	err := requestQuorumRestore(clientset, "rook-ceph", "b", 10*time.Millisecond)
+	assert.NotNil(t, err)
+	cluster, _ = clientset.CephV1().CephClusters("rook-ceph").Get("rook-ceph", metav1.GetOptions{})
+	assert.Equal(t, "b", cluster.Annotations[mon.RestoreQuorumAnnotation])
+	_, ok := cluster.Annotations[mon.RestoreQuorumStatusAnnotation]
+	assert.False(t, ok)
+
+	// the operator failed to restore the quorum
+	cluster.Annotations[mon.RestoreQuorumStatusAnnotation] = mon.RestoreQuorumFailed
+	cluster.Annotations[mon.RestoreQuorumErrorAnnotation] = "mon b not found"
+	go func() {
+		time.Sleep(5 * time.Millisecond)
+		clientset.CephV1().CephClusters("rook-ceph").Update(cluster)
+	}()
+	err = requestQuorumRestore(clientset, "rook-ceph", "b", time.Second)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "mon b not found")
+
+	// there is no cluster in the namespace
+	err = requestQuorumRestore(clientset, "other", "b", time.Second)
+	assert.NotNil(t, err)
+}
@@ -22,10 +22,16 @@ import (
 	"github.com/rook/rook/cmd/rook/rook"
 	"github.com/rook/rook/pkg/clusterd"
 	"github.com/rook/rook/pkg/daemon/ceph/agent/flexvolume/attachment"
+	"github.com/rook/rook/pkg/daemon/ceph/client"
 	operator "github.com/rook/rook/pkg/operator/ceph"
+	"github.com/rook/rook/pkg/operator/ceph/cluster"
 	"github.com/rook/rook/pkg/operator/ceph/cluster/mon"
+	cephconfig "github.com/rook/rook/pkg/operator/ceph/config"
 	"github.com/rook/rook/pkg/operator/ceph/csi"
+	"github.com/rook/rook/pkg/operator/ceph/reconcile"
 	"github.com/rook/rook/pkg/operator/k8sutil"
+	"github.com/rook/rook/pkg/operator/metrics"
+	"github.com/rook/rook/pkg/operator/webhook"
 	"github.com/rook/rook/pkg/util/flags"
 	"github.com/spf13/cobra"
 )
@@ -42,6 +48,10 @@ https://github.com/rook/rook`,
 func init() {
 	operatorCmd.Flags().DurationVar(&mon.HealthCheckInterval, "mon-healthcheck-interval", mon.HealthCheckInterval, "mon health check interval (duration)")
 	operatorCmd.Flags().DurationVar(&mon.MonOutTimeout, "mon-out-timeout", mon.MonOutTimeout, "mon out timeout (duration)")
+	operatorCmd.Flags().DurationVar(&reconcile.ResyncPeriod, "resync-period", reconcile.ResyncPeriod, "period of the reconcile of all the pools, filesystems, object stores and other ceph resources (duration)")
+	operatorCmd.Flags().StringSliceVar(&cluster.WatchNamespaces, "watch-namespaces", nil, "comma separated namespaces of the clusters watched by the operator. all the namespaces are watched if empty")
+	operatorCmd.Flags().StringVar(&cluster.WatchNamespaceSelector, "watch-namespace-selector", "", "label selector of the namespaces of the clusters watched by the operator, exclusive with --watch-namespaces")
+	operatorCmd.Flags().Float64Var(&cephconfig.MemoryHeadroomRatio, "daemon-memory-headroom", cephconfig.MemoryHeadroomRatio, "fraction of the memory of the ceph daemon containers kept out of the memory targets of the daemons")
 
 	operatorCmd.Flags().BoolVar(&csi.EnableRBD, "csi-enable-rbd", false, "whether enable ceph-csi rbd driver")
 	operatorCmd.Flags().BoolVar(&csi.EnableCephFS, "csi-enable-cephfs", false, "whether enable ceph-csi cephfs driver")
@@ -61,6 +71,10 @@ func init() {
 	operatorCmd.Flags().StringVar(&csi.CephFSPluginTemplatePath, "csi-cephfs-plugin-template-path", csi.DefaultCephFSPluginTemplatePath, "path to ceph-csi cephfs plugin template")
 	operatorCmd.Flags().StringVar(&csi.CephFSProvisionerTemplatePath, "csi-cephfs-provisioner-template-path", csi.DefaultCephFSProvisionerTemplatePath, "path to ceph-csi cephfs provisioner template")
 
+	rook.AddMetricsFlags(operatorCmd.Flags())
+	rook.AddLeaderElectionFlags(operatorCmd.Flags())
+	rook.AddWebhookFlags(operatorCmd.Flags())
+
 	flags.SetFlagsFromEnv(operatorCmd.Flags(), rook.RookEnvVarPrefix)
 	flags.SetLoggingFlags(operatorCmd.Flags())
 	operatorCmd.RunE = startOperator
@@ -71,6 +85,8 @@ func startOperator(cmd *cobra.Command, args []string) error {
 	rook.SetLogLevel()
 
 	rook.LogStartupInfo(operatorCmd.Flags())
+	rook.StartMetricsServer()
+	client.CommandObserver = metrics.ObserveCephCommand
 
 	clientset, apiExtClientset, rookClientset, err := rook.GetClientset()
 	if err != nil {
@@ -84,6 +100,11 @@ func startOperator(cmd *cobra.Command, args []string) error {
 	context.Clientset = clientset
 	context.APIExtensionClientset = apiExtClientset
 	context.RookClientset = rookClientset
+
+	webhookServer := webhook.NewServer()
+	operator.RegisterValidators(webhookServer, context)
+	rook.StartWebhookServer(webhookServer)
+
 	volumeAttachment, err := attachment.New(context)
 	if err != nil {
 		rook.TerminateFatal(err)
@@ -25,6 +25,7 @@ import (
 	"github.com/rook/rook/cmd/rook/rook"
 	"github.com/rook/rook/pkg/daemon/ceph/client"
 	osddaemon "github.com/rook/rook/pkg/daemon/ceph/osd"
+	"github.com/rook/rook/pkg/daemon/ceph/osd/kms"
 	"github.com/rook/rook/pkg/operator/ceph/cluster"
 	"github.com/rook/rook/pkg/operator/ceph/cluster/mon"
 	oposd "github.com/rook/rook/pkg/operator/ceph/cluster/osd"
@@ -59,6 +60,10 @@ var osdStartCmd = &cobra.Command{
 	Use:   "start",
 	Short: "Starts the osd daemon", // OSDs that were provisioned by ceph-volume
 }
+var rewrapKeysCmd = &cobra.Command{
+	Use:   "rewrap-keys",
+	Short: "Rewraps the keys of the encrypted osds with the latest version of the key of the kms",
+}
 var (
 	osdDataDeviceFilter string
 	ownerRefID          string
@@ -69,6 +74,7 @@ var (
 	osdStoreType        string
 	osdStringID         string
 	osdUUID             string
+	osdLUKSUUID         string
 	osdIsDevice         bool
 )
 
@@ -99,13 +105,15 @@ func addOSDFlags(command *cobra.Command) {
 	osdStartCmd.Flags().StringVar(&osdStringID, "osd-id", "", "the osd ID")
 	osdStartCmd.Flags().StringVar(&osdUUID, "osd-uuid", "", "the osd UUID")
 	osdStartCmd.Flags().StringVar(&osdStoreType, "osd-store-type", "", "whether the osd is bluestore or filestore")
+	osdStartCmd.Flags().StringVar(&osdLUKSUUID, "osd-luks-uuid", "", "the luks uuid of the device encrypted with a key held by a kms")
 
 	// add the subcommands to the parent osd command
 	osdCmd.AddCommand(osdConfigCmd,
 		copyBinariesCmd,
 		provisionCmd,
 		filestoreDeviceCmd,
-		osdStartCmd)
+		osdStartCmd,
+		rewrapKeysCmd)
 }
 
 func addOSDConfigFlags(command *cobra.Command) {
@@ -131,12 +139,14 @@ func init() {
 	flags.SetFlagsFromEnv(provisionCmd.Flags(), rook.RookEnvVarPrefix)
 	flags.SetFlagsFromEnv(filestoreDeviceCmd.Flags(), rook.RookEnvVarPrefix)
 	flags.SetFlagsFromEnv(osdStartCmd.Flags(), rook.RookEnvVarPrefix)
+	flags.SetFlagsFromEnv(rewrapKeysCmd.Flags(), rook.RookEnvVarPrefix)
 
 	osdConfigCmd.RunE = writeOSDConfig
 	copyBinariesCmd.RunE = copyRookBinaries
 	provisionCmd.RunE = prepareOSD
 	filestoreDeviceCmd.RunE = runFilestoreDeviceOSD
 	osdStartCmd.RunE = startOSD
+	rewrapKeysCmd.RunE = rewrapKeys
 }
 
 // Start the osd daemon if provisioned by ceph-volume
@@ -149,6 +159,17 @@ func startOSD(cmd *cobra.Command, args []string) error {
 	commonOSDInit(osdStartCmd)
 
 	context := createContext()
+	if osdLUKSUUID != "" {
+		// open the device encrypted with a key held by the kms
+		keys, err := newKMS(os.Getenv(k8sutil.PodNamespaceEnvVar))
+		if err != nil {
+			rook.TerminateFatal(err)
+		}
+		if err := osddaemon.OpenEncryptedDevice(context, keys, osdLUKSUUID); err != nil {
+			rook.TerminateFatal(err)
+		}
+	}
+
 	err := osddaemon.StartOSD(context, osdStoreType, osdStringID, osdUUID, args)
 	if err != nil {
 		rook.TerminateFatal(err)
@@ -271,11 +292,19 @@ func prepareOSD(cmd *cobra.Command, args []string) error {
 	}
 	crushLocation := strings.Join(locArgs, " ")
 
+	var keys kms.KMS
+	if cfg.storeConfig.EncryptedDevice {
+		keys, err = kms.NewFromEnv(clientset, clusterInfo.Name)
+		if err != nil {
+			rook.TerminateFatal(fmt.Errorf("failed to init the kms. %+v", err))
+		}
+	}
+
 	forceFormat := false
 	ownerRef := cluster.ClusterOwnerRef(clusterInfo.Name, ownerRefID)
 	kv := k8sutil.NewConfigMapKVStore(clusterInfo.Name, clientset, ownerRef)
 	agent := osddaemon.NewAgent(context, dataDevices, cfg.metadataDevice, cfg.directories, forceFormat,
-		crushLocation, cfg.storeConfig, &clusterInfo, cfg.nodeName, kv)
+		crushLocation, cfg.storeConfig, &clusterInfo, cfg.nodeName, kv, keys)
 
 	err = osddaemon.Provision(context, agent)
 	if err != nil {
@@ -292,6 +321,38 @@ func prepareOSD(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// Rewrap the keys of the encrypted osds of the namespace after the key of the kms was rotated
+func rewrapKeys(cmd *cobra.Command, args []string) error {
+	rook.SetLogLevel()
+
+	keys, err := newKMS(os.Getenv(k8sutil.PodNamespaceEnvVar))
+	if err != nil {
+		rook.TerminateFatal(err)
+	}
+	count, err := keys.Rewrap()
+	if err != nil {
+		rook.TerminateFatal(fmt.Errorf("failed to rewrap the keys. %+v", err))
+	}
+	logger.Infof("rewrapped %d keys", count)
+	return nil
+}
+
+// newKMS creates the kms configured by the environment of the osd pod
+func newKMS(namespace string) (kms.KMS, error) {
+	clientset, _, _, err := rook.GetClientset()
+	if err != nil {
+		return nil, fmt.Errorf("failed to init k8s client. %+v", err)
+	}
+	keys, err := kms.NewFromEnv(clientset, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to init the kms. %+v", err)
+	}
+	if keys == nil {
+		return nil, fmt.Errorf("no kms is configured")
+	}
+	return keys, nil
+}
+
 func commonOSDInit(cmd *cobra.Command) {
 	rook.SetLogLevel()
 	rook.LogStartupInfo(cmd.Flags())
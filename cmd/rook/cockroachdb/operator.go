@@ -23,6 +23,7 @@ import (
 	"github.com/rook/rook/pkg/clusterd"
 	operator "github.com/rook/rook/pkg/operator/cockroachdb"
 	"github.com/rook/rook/pkg/operator/k8sutil"
+	"github.com/rook/rook/pkg/operator/webhook"
 	"github.com/rook/rook/pkg/util/flags"
 	"github.com/spf13/cobra"
 )
@@ -37,6 +38,9 @@ https://github.com/rook/rook`,
 }
 
 func init() {
+	rook.AddMetricsFlags(operatorCmd.Flags())
+	rook.AddLeaderElectionFlags(operatorCmd.Flags())
+	rook.AddWebhookFlags(operatorCmd.Flags())
 	flags.SetFlagsFromEnv(operatorCmd.Flags(), rook.RookEnvVarPrefix)
 	flags.SetLoggingFlags(operatorCmd.Flags())
 
@@ -46,6 +50,7 @@ func init() {
 func startOperator(cmd *cobra.Command, args []string) error {
 	rook.SetLogLevel()
 	rook.LogStartupInfo(operatorCmd.Flags())
+	rook.StartMetricsServer()
 
 	clientset, apiExtClientset, rookClientset, err := rook.GetClientset()
 	if err != nil {
@@ -60,6 +65,10 @@ func startOperator(cmd *cobra.Command, args []string) error {
 	context.APIExtensionClientset = apiExtClientset
 	context.RookClientset = rookClientset
 
+	webhookServer := webhook.NewServer()
+	webhookServer.Register(operator.ClusterResource, operator.Validator())
+	rook.StartWebhookServer(webhookServer)
+
 	// Using the current image version to deploy other rook pods
 	pod, err := k8sutil.GetRunningPod(clientset)
 	if err != nil {
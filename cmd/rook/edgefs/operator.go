@@ -37,6 +37,8 @@ https://github.com/rook/rook`,
 }
 
 func init() {
+	rook.AddMetricsFlags(operatorCmd.Flags())
+	rook.AddLeaderElectionFlags(operatorCmd.Flags())
 	flags.SetFlagsFromEnv(operatorCmd.Flags(), rook.RookEnvVarPrefix)
 
 	operatorCmd.RunE = startOperator
@@ -45,6 +47,7 @@ func init() {
 func startOperator(cmd *cobra.Command, args []string) error {
 	rook.SetLogLevel()
 	rook.LogStartupInfo(operatorCmd.Flags())
+	rook.StartMetricsServer()
 
 	clientset, apiExtClientset, rookClientset, err := rook.GetClientset()
 	if err != nil {
@@ -39,6 +39,8 @@ var (
 )
 
 func init() {
+	rook.AddMetricsFlags(operatorCmd.Flags())
+	rook.AddLeaderElectionFlags(operatorCmd.Flags())
 	flags.SetFlagsFromEnv(operatorCmd.Flags(), rook.RookEnvVarPrefix)
 	flags.SetLoggingFlags(operatorCmd.Flags())
 	operatorCmd.RunE = startOperator
@@ -48,6 +50,7 @@ func startOperator(cmd *cobra.Command, args []string) error {
 	rook.SetLogLevel()
 
 	rook.LogStartupInfo(operatorCmd.Flags())
+	rook.StartMetricsServer()
 
 	clientset, apiExtClientset, rookClientset, err := rook.GetClientset()
 	if err != nil {
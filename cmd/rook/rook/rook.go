@@ -28,6 +28,9 @@ import (
 	"k8s.io/client-go/rest"
 
 	rookclient "github.com/rook/rook/pkg/client/clientset/versioned"
+	"github.com/rook/rook/pkg/operator/leader"
+	"github.com/rook/rook/pkg/operator/metrics"
+	"github.com/rook/rook/pkg/operator/webhook"
 	"github.com/rook/rook/pkg/util/flags"
 	"github.com/rook/rook/pkg/version"
 	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
@@ -43,9 +46,12 @@ var RootCmd = &cobra.Command{
 }
 
 var (
-	logLevelRaw string
-	Cfg         = &Config{}
-	logger      = capnslog.NewPackageLogger("github.com/rook/rook", "rookcmd")
+	logLevelRaw    string
+	metricsPort    int
+	webhookPort    int
+	webhookCertDir string
+	Cfg            = &Config{}
+	logger         = capnslog.NewPackageLogger("github.com/rook/rook", "rookcmd")
 )
 
 type Config struct {
@@ -75,6 +81,35 @@ func SetLogLevel() {
 	capnslog.SetGlobalLogLevel(Cfg.LogLevel)
 }
 
+// AddMetricsFlags adds the flag to configure the port of the operator metrics endpoint
+func AddMetricsFlags(cmdFlags *pflag.FlagSet) {
+	cmdFlags.IntVar(&metricsPort, "metrics-port", metrics.DefaultPort, "port to serve the operator metrics on (0 to disable)")
+}
+
+// StartMetricsServer serves the operator metrics on the port given by the metrics-port flag
+func StartMetricsServer() {
+	metrics.StartServer(metricsPort)
+}
+
+// AddWebhookFlags adds the flags to configure the validating webhook of the operator
+func AddWebhookFlags(cmdFlags *pflag.FlagSet) {
+	cmdFlags.IntVar(&webhookPort, "webhook-port", 0, "port to serve the validating webhook on (0 to disable)")
+	cmdFlags.StringVar(&webhookCertDir, "webhook-cert-dir", webhook.DefaultCertDir, "directory with the tls.crt and tls.key files of the validating webhook")
+}
+
+// StartWebhookServer serves the validating webhook on the port given by the webhook-port flag
+func StartWebhookServer(server *webhook.Server) {
+	server.Start(webhookPort, webhookCertDir)
+}
+
+// AddLeaderElectionFlags adds the flags to configure the leader election between the replicas of the operator
+func AddLeaderElectionFlags(cmdFlags *pflag.FlagSet) {
+	cmdFlags.BoolVar(&leader.Enabled, "leader-elect", leader.Enabled, "run the controllers only in the replica of the operator elected as the leader")
+	cmdFlags.DurationVar(&leader.LeaseDuration, "leader-elect-lease-duration", leader.LeaseDuration, "time the standby replicas wait for the leader to renew its leadership before taking over (duration)")
+	cmdFlags.DurationVar(&leader.RenewDeadline, "leader-elect-renew-deadline", leader.RenewDeadline, "time the leader retries renewing its leadership before giving it up (duration)")
+	cmdFlags.DurationVar(&leader.RetryPeriod, "leader-elect-retry-period", leader.RetryPeriod, "interval between the attempts to acquire or renew the leadership (duration)")
+}
+
 // LogStartupInfo log the version number, arguments, and all final flag values (environment variable overrides have already been taken into account)
 func LogStartupInfo(cmdFlags *pflag.FlagSet) {
 
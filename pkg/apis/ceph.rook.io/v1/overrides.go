@@ -0,0 +1,119 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1
+
+import (
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	DaemonKeyMon       = "mon"
+	DaemonKeyMgr       = "mgr"
+	DaemonKeyOSD       = "osd"
+	DaemonKeyMDS       = "mds"
+	DaemonKeyRGW       = "rgw"
+	DaemonKeyNFS       = "nfs"
+	DaemonKeyRBDMirror = "rbdmirror"
+	// DaemonKeyCrashCollector is the key of the overrides of the crash collectors
+	DaemonKeyCrashCollector = "crashcollector"
+)
+
+// GetMonOverride returns the overrides of the monitors
+func GetMonOverride(o DaemonOverridesSpec) DaemonOverride {
+	return o[DaemonKeyMon]
+}
+
+// GetMgrOverride returns the overrides of the MGR service
+func GetMgrOverride(o DaemonOverridesSpec) DaemonOverride {
+	return o[DaemonKeyMgr]
+}
+
+// GetOSDOverride returns the overrides of the OSDs
+func GetOSDOverride(o DaemonOverridesSpec) DaemonOverride {
+	return o[DaemonKeyOSD]
+}
+
+// GetMDSOverride returns the overrides of the metadata servers of the filesystems
+func GetMDSOverride(o DaemonOverridesSpec) DaemonOverride {
+	return o[DaemonKeyMDS]
+}
+
+// GetRGWOverride returns the overrides of the gateways of the object stores
+func GetRGWOverride(o DaemonOverridesSpec) DaemonOverride {
+	return o[DaemonKeyRGW]
+}
+
+// GetNFSOverride returns the overrides of the NFS ganesha servers
+func GetNFSOverride(o DaemonOverridesSpec) DaemonOverride {
+	return o[DaemonKeyNFS]
+}
+
+// GetRBDMirrorOverride returns the overrides of the RBD mirrors
+func GetRBDMirrorOverride(o DaemonOverridesSpec) DaemonOverride {
+	return o[DaemonKeyRBDMirror]
+}
+
+// GetCrashCollectorOverride returns the overrides of the crash collectors
+func GetCrashCollectorOverride(o DaemonOverridesSpec) DaemonOverride {
+	return o[DaemonKeyCrashCollector]
+}
+
+// ApplyToPod applies the overrides to the metadata and the spec of a daemon pod. The containers running the ceph image
+// of the cluster run the image of the override instead. The labels and annotations set by Rook are not overridden.
+func (o DaemonOverride) ApplyToPod(meta *metav1.ObjectMeta, spec *v1.PodSpec, cephImage string) {
+	if o.Image != "" {
+		for i := range spec.InitContainers {
+			o.applyImage(&spec.InitContainers[i], cephImage)
+		}
+		for i := range spec.Containers {
+			o.applyImage(&spec.Containers[i], cephImage)
+		}
+	}
+	if o.PriorityClassName != "" {
+		spec.PriorityClassName = o.PriorityClassName
+	}
+	if len(o.Labels) > 0 && meta.Labels == nil {
+		meta.Labels = map[string]string{}
+	}
+	for key, value := range o.Labels {
+		if _, ok := meta.Labels[key]; !ok {
+			meta.Labels[key] = value
+		}
+	}
+	if len(o.Annotations) > 0 && meta.Annotations == nil {
+		meta.Annotations = map[string]string{}
+	}
+	for key, value := range o.Annotations {
+		if _, ok := meta.Annotations[key]; !ok {
+			meta.Annotations[key] = value
+		}
+	}
+}
+
+// applyImage replaces the ceph image of the cluster with the image of the override in the container and in its
+// CONTAINER_IMAGE variable
+func (o DaemonOverride) applyImage(container *v1.Container, cephImage string) {
+	if container.Image != cephImage {
+		return
+	}
+	container.Image = o.Image
+	for i := range container.Env {
+		if container.Env[i].Name == "CONTAINER_IMAGE" && container.Env[i].Value == cephImage {
+			container.Env[i].Value = o.Image
+		}
+	}
+}
@@ -0,0 +1,59 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestApplyDaemonOverride(t *testing.T) {
+	meta := metav1.ObjectMeta{Labels: map[string]string{"app": "rook-ceph-mon"}}
+	spec := v1.PodSpec{
+		InitContainers: []v1.Container{{Name: "copy-bins", Image: "rook/ceph:master"}},
+		Containers: []v1.Container{{
+			Name:  "mon",
+			Image: "ceph/ceph:v14",
+			Env:   []v1.EnvVar{{Name: "CONTAINER_IMAGE", Value: "ceph/ceph:v14"}},
+		}},
+	}
+
+	// no override
+	overrides := DaemonOverridesSpec{}
+	GetMonOverride(overrides).ApplyToPod(&meta, &spec, "ceph/ceph:v14")
+	assert.Equal(t, "ceph/ceph:v14", spec.Containers[0].Image)
+	assert.Equal(t, "", spec.PriorityClassName)
+	assert.Equal(t, map[string]string{"app": "rook-ceph-mon"}, meta.Labels)
+	assert.Nil(t, meta.Annotations)
+
+	overrides[DaemonKeyMon] = DaemonOverride{
+		Image:             "ceph/ceph:v14.2.1",
+		PriorityClassName: "system-cluster-critical",
+		Labels:            map[string]string{"app": "mon", "team": "storage"},
+		Annotations:       map[string]string{"sidecar.istio.io/inject": "false"},
+	}
+	GetMonOverride(overrides).ApplyToPod(&meta, &spec, "ceph/ceph:v14")
+	assert.Equal(t, "rook/ceph:master", spec.InitContainers[0].Image)
+	assert.Equal(t, "ceph/ceph:v14.2.1", spec.Containers[0].Image)
+	assert.Equal(t, "ceph/ceph:v14.2.1", spec.Containers[0].Env[0].Value)
+	assert.Equal(t, "system-cluster-critical", spec.PriorityClassName)
+	// the labels set by rook are not overridden
+	assert.Equal(t, map[string]string{"app": "rook-ceph-mon", "team": "storage"}, meta.Labels)
+	assert.Equal(t, map[string]string{"sidecar.istio.io/inject": "false"}, meta.Annotations)
+}
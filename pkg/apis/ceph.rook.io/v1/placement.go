@@ -24,6 +24,8 @@ const (
 	PlacementKeyMon       = "mon"
 	PlacementKeyOSD       = "osd"
 	PlacementKeyRBDMirror = "rbdmirror"
+	// PlacementKeyCrashCollector is the placement of the crash collectors, which must run on the nodes of the daemons
+	PlacementKeyCrashCollector = "crashcollector"
 )
 
 // GetMgrPlacement returns the placement for the MGR service
@@ -45,3 +47,8 @@ func GetOSDPlacement(p rook.PlacementSpec) rook.Placement {
 func GetRBDMirrorPlacement(p rook.PlacementSpec) rook.Placement {
 	return p.All().Merge(p[PlacementKeyRBDMirror])
 }
+
+// GetCrashCollectorPlacement returns the placement for the crash collectors
+func GetCrashCollectorPlacement(p rook.PlacementSpec) rook.Placement {
+	return p.All().Merge(p[PlacementKeyCrashCollector])
+}
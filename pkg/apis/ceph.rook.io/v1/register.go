@@ -55,12 +55,16 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 	scheme.AddKnownTypes(SchemeGroupVersion,
 		&CephCluster{},
 		&CephClusterList{},
+		&CephDashboardUser{},
+		&CephDashboardUserList{},
 		&CephBlockPool{},
 		&CephBlockPoolList{},
 		&CephFilesystem{},
 		&CephFilesystemList{},
 		&CephNFS{},
 		&CephNFSList{},
+		&CephNFSExport{},
+		&CephNFSExportList{},
 		&CephObjectStore{},
 		&CephObjectStoreList{},
 		&CephObjectStoreUser{},
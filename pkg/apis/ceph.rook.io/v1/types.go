@@ -54,6 +54,9 @@ type ClusterSpec struct {
 	// A spec for available storage in the cluster and how it should be used
 	Storage rook.StorageScopeSpec `json:"storage,omitempty"`
 
+	// Settings to derive the CRUSH location of the OSDs from the labels of their nodes
+	CrushTopology CrushTopologySpec `json:"crushTopology,omitempty"`
+
 	// The placement-related configuration to pass to kubernetes (affinity, node selector, tolerations).
 	Placement rook.PlacementSpec `json:"placement,omitempty"`
 
@@ -63,6 +66,12 @@ type ClusterSpec struct {
 	// Resources set resource requests and limits
 	Resources rook.ResourceSpec `json:"resources,omitempty"`
 
+	// Overrides of the image, priority class, labels and annotations of the daemons, keyed by the daemon type
+	DaemonOverrides DaemonOverridesSpec `json:"daemonOverrides,omitempty"`
+
+	// Security settings, such as the key management service holding the keys of the encrypted OSDs
+	Security SecuritySpec `json:"security,omitempty"`
+
 	// The path on the host where config and data can be persisted.
 	DataDirHostPath string `json:"dataDirHostPath,omitempty"`
 
@@ -74,6 +83,15 @@ type ClusterSpec struct {
 
 	// Dashboard settings
 	Dashboard DashboardSpec `json:"dashboard,omitempty"`
+
+	// Settings to take OSDs out of the cluster when their disk is about to fail
+	DiskHealth DiskHealthSpec `json:"diskHealth,omitempty"`
+
+	// Whether to wipe the data of the cluster on the hosts when the cluster is deleted
+	CleanupPolicy CleanupPolicySpec `json:"cleanupPolicy,omitempty"`
+
+	// Settings of the collection of the crash reports of the daemons
+	CrashCollector CrashCollectorSpec `json:"crashCollector,omitempty"`
 }
 
 // VersionSpec represents the settings for the Ceph version that Rook is orchestrating.
@@ -85,6 +103,87 @@ type CephVersionSpec struct {
 	AllowUnsupported bool `json:"allowUnsupported,omitempty"`
 }
 
+// DaemonOverridesSpec is the overrides of the daemons keyed by mon, mgr, osd, mds, rgw, nfs, rbdmirror and crashcollector
+type DaemonOverridesSpec map[string]DaemonOverride
+
+// DaemonOverride represents the settings of the pods of a daemon type which override the settings of the cluster
+type DaemonOverride struct {
+	// Image is the ceph image run by the daemons instead of the image of the cephVersion
+	Image string `json:"image,omitempty"`
+	// PriorityClassName is the priority class of the pods of the daemons
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+	// Labels are added to the pods of the daemons
+	Labels map[string]string `json:"labels,omitempty"`
+	// Annotations are added to the pods of the daemons
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// SecuritySpec represents the security settings of the cluster
+type SecuritySpec struct {
+	// KeyManagementService holds the keys of the encrypted OSDs outside of the cluster
+	KeyManagementService KeyManagementServiceSpec `json:"kms,omitempty"`
+	// KeyRotation rotates the cephx keys of the daemons and clients on a schedule
+	KeyRotation KeyRotationSpec `json:"keyRotation,omitempty"`
+	// Connections sets the modes of the msgr2 connections, such as the encryption of the traffic on the wire
+	Connections ConnectionsSpec `json:"connections,omitempty"`
+}
+
+// KeyManagementServiceSpec represents the key management service holding the dm-crypt keys of the encrypted OSDs.
+// The keys are stored in the mon config-key store by ceph-volume if no key management service is set.
+type KeyManagementServiceSpec struct {
+	// ConnectionDetails are the settings of the key management service, such as KMS_PROVIDER, VAULT_ADDR or
+	// VAULT_BACKEND. They are passed to the OSDs as environment variables.
+	ConnectionDetails map[string]string `json:"connectionDetails,omitempty"`
+	// TokenSecretName is the name of the secret holding the token of the key management service in its "token" key
+	TokenSecretName string `json:"tokenSecretName,omitempty"`
+	// CACertSecretName is the name of the secret holding the CA certificate of the key management service in its
+	// "ca.crt" key. The certificate is mounted in the OSD pods.
+	CACertSecretName string `json:"caCertSecretName,omitempty"`
+}
+
+// KeyRotationSpec represents the scheduled rotation of the cephx keys of the admin, of the mgr, rgw, mds and rbd-mirror
+// daemons and of the clients. The keys can also be rotated on demand with the ceph.rook.io/rotate-keys annotation.
+type KeyRotationSpec struct {
+	// Whether to rotate the keys on a schedule
+	Enabled bool `json:"enabled,omitempty"`
+	// The number of days between two rotations, 90 by default
+	PeriodDays int `json:"periodDays,omitempty"`
+}
+
+// ConnectionsSpec represents the modes of the connections of the msgr2 protocol, available from Nautilus. Each mode is
+// "crc" to check the integrity of the frames, "secure" to encrypt them, or both in the order of preference. Ceph
+// accepts both modes by default.
+type ConnectionsSpec struct {
+	// The mode of the connections between the daemons (ms_cluster_mode)
+	ClusterMode string `json:"clusterMode,omitempty"`
+	// The mode accepted by the daemons for the connections of the clients (ms_service_mode)
+	ServiceMode string `json:"serviceMode,omitempty"`
+	// The mode of the connections of the clients to the daemons (ms_client_mode)
+	ClientMode string `json:"clientMode,omitempty"`
+	// RequireMsgr2 stops the daemons from listening with the msgr1 protocol (ms_bind_msgr1), whose traffic is never
+	// encrypted. The kernel clients cannot connect to the cluster then.
+	RequireMsgr2 bool `json:"requireMsgr2,omitempty"`
+}
+
+// CrushTopologySpec represents the node labels from which the CRUSH location of the OSDs of a node is derived. The
+// location set on a node or on the storage takes precedence over the labels.
+type CrushTopologySpec struct {
+	// Whether to derive the CRUSH location of the OSDs from the labels of their nodes
+	Enabled bool `json:"enabled,omitempty"`
+	// The node labels of the CRUSH bucket types, such as rack: example.com/rack. The region and zone are read from the
+	// standard Kubernetes topology labels and the other types from the topology.rook.io/<type> labels by default.
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// CrashCollectorSpec represents the crash collector running on each node from Nautilus. The daemons write their crash
+// reports under the dataDirHostPath and the collector posts them to the crash module of the mgr.
+type CrashCollectorSpec struct {
+	// Whether to not run the crash collector
+	Disable bool `json:"disable,omitempty"`
+	// The number of days the crash reports are kept, 0 to keep them until they are removed with "ceph crash rm"
+	DaysToRetain int `json:"daysToRetain,omitempty"`
+}
+
 // DashboardSpec represents the settings for the Ceph dashboard
 type DashboardSpec struct {
 	// Whether to enable the dashboard
@@ -95,13 +194,111 @@ type DashboardSpec struct {
 	Port int `json:"port,omitempty"`
 	// Whether SSL should be used
 	SSL *bool `json:"ssl,omitempty"`
+	// The name of a kubernetes.io/tls secret holding the certificate to serve instead of a self-signed cert
+	CertificateSecretName string `json:"certificateSecretName,omitempty"`
+	// ExternalAccess exposes the dashboard outside of the cluster
+	ExternalAccess *DashboardExternalAccessSpec `json:"externalAccess,omitempty"`
+}
+
+// DashboardExternalAccessType is the kind of resource used to expose the dashboard
+type DashboardExternalAccessType string
+
+const (
+	// DashboardExternalAccessIngress exposes the dashboard with an Ingress
+	DashboardExternalAccessIngress DashboardExternalAccessType = "Ingress"
+	// DashboardExternalAccessLoadBalancer exposes the dashboard with a service of type LoadBalancer
+	DashboardExternalAccessLoadBalancer DashboardExternalAccessType = "LoadBalancer"
+)
+
+// DashboardExternalAccessSpec represents the settings for exposing the dashboard outside of the cluster
+type DashboardExternalAccessSpec struct {
+	// Type is either Ingress or LoadBalancer
+	Type DashboardExternalAccessType `json:"type"`
+	// Hostname where the dashboard will be reachable
+	Hostname string `json:"hostname,omitempty"`
+	// Annotations to add to the ingress or load balancer service
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// DiskHealthSpec represents the thresholds of the SMART health of a disk at which its OSD is marked out.
+// A disk failing its SMART self-assessment is always considered unhealthy. A threshold of 0 is ignored.
+type DiskHealthSpec struct {
+	// Whether to mark out the OSDs of unhealthy disks
+	Enabled bool `json:"enabled,omitempty"`
+	// The number of reallocated sectors of an ATA disk above which it is unhealthy
+	MaxReallocatedSectors int64 `json:"maxReallocatedSectors,omitempty"`
+	// The number of media errors of an NVMe disk above which it is unhealthy
+	MaxMediaErrors int64 `json:"maxMediaErrors,omitempty"`
+	// The percentage of the rated endurance of an NVMe disk above which it is unhealthy
+	MaxPercentageUsed int `json:"maxPercentageUsed,omitempty"`
+}
+
+const (
+	// CleanupConfirmation must be set as the cleanup policy confirmation to wipe the hosts when the cluster is deleted
+	CleanupConfirmation = "yes-really-destroy-data"
+)
+
+// CleanupPolicySpec represents the cleanup of the hosts when the cluster is deleted
+type CleanupPolicySpec struct {
+	// Confirmation must be "yes-really-destroy-data" to remove the data dirs and zap the OSD devices of the hosts
+	Confirmation string `json:"confirmation,omitempty"`
+}
+
+// HasDataDirCleanPolicy returns whether the hosts must be wiped when the cluster is deleted
+func (c *CleanupPolicySpec) HasDataDirCleanPolicy() bool {
+	return c.Confirmation == CleanupConfirmation
 }
 
 type ClusterStatus struct {
 	State   ClusterState `json:"state,omitempty"`
 	Message string       `json:"message,omitempty"`
+	// The state of the cleanup of the hosts while the cluster is deleted with a cleanup policy
+	CleanupState CleanupState `json:"cleanupState,omitempty"`
+	// The result of the cleanup of each host while the cluster is deleted with a cleanup policy
+	Cleanup []NodeCleanupStatus `json:"cleanup,omitempty"`
+	// The most recent crashes of the daemons reported by the crash module of the mgr
+	Crashes []CrashSummary `json:"crashes,omitempty"`
+}
+
+// CrashSummary is a crash of a daemon reported by the crash module of the mgr
+type CrashSummary struct {
+	// The ID of the crash, to get its details with "ceph crash info"
+	ID string `json:"id"`
+	// The name of the daemon that crashed, such as osd.3
+	Daemon string `json:"daemon"`
+	// The time of the crash
+	Time string `json:"time"`
+	// The signature of the backtrace, identical for the crashes with the same cause
+	Signature string `json:"signature,omitempty"`
 }
 
+// NodeCleanupStatus is the result of the cleanup of a host
+type NodeCleanupStatus struct {
+	Node  string           `json:"node"`
+	State NodeCleanupState `json:"state"`
+	// The error, if the cleanup failed
+	Message string `json:"message,omitempty"`
+	// The devices of the OSDs that were zapped
+	ZappedDevices []string `json:"zappedDevices,omitempty"`
+}
+
+type NodeCleanupState string
+
+const (
+	NodeCleanupStateRunning   NodeCleanupState = "Running"
+	NodeCleanupStateCompleted NodeCleanupState = "Completed"
+	NodeCleanupStateFailed    NodeCleanupState = "Failed"
+)
+
+type CleanupState string
+
+const (
+	// CleanupStateRunning is the state while the cleanup jobs run on the hosts
+	CleanupStateRunning CleanupState = "Running"
+	// CleanupStateDone is the state when all the cleanup jobs completed, failed or timed out
+	CleanupStateDone CleanupState = "Done"
+)
+
 type ClusterState string
 
 const (
@@ -127,7 +324,8 @@ type RBDMirroringSpec struct {
 type CephBlockPool struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata"`
-	Spec              PoolSpec `json:"spec"`
+	Spec              PoolSpec       `json:"spec"`
+	Status            ResourceStatus `json:"status,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -151,8 +349,26 @@ type PoolSpec struct {
 
 	// The erasure code settings
 	ErasureCoded ErasureCodedSpec `json:"erasureCoded"`
+
+	// Whether to keep the pool when the CephBlockPool is deleted. Ignored for the pools of filesystems and object stores.
+	PreservePoolOnDelete bool `json:"preservePoolOnDelete,omitempty"`
 }
 
+// ResourceStatus represents the status of a pool, filesystem or object store
+type ResourceStatus struct {
+	Phase ResourcePhase `json:"phase,omitempty"`
+	// The reason of the phase, such as why the deletion is blocked
+	Message string `json:"message,omitempty"`
+}
+
+// ResourcePhase is the phase of a pool, filesystem or object store
+type ResourcePhase string
+
+const (
+	// ResourcePhaseDeletionBlocked means the resource is deleted but it still holds data
+	ResourcePhaseDeletionBlocked ResourcePhase = "DeletionBlocked"
+)
+
 // ReplicationSpec represents the spec for replication in a pool
 type ReplicatedSpec struct {
 	// Number of copies per object in a replicated storage pool, including the object itself (required for replicated pool type)
@@ -179,6 +395,7 @@ type CephFilesystem struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata"`
 	Spec              FilesystemSpec `json:"spec"`
+	Status            ResourceStatus `json:"status,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -199,6 +416,9 @@ type FilesystemSpec struct {
 
 	// The mds pod info
 	MetadataServer MetadataServerSpec `json:"metadataServer"`
+
+	// Whether to keep the filesystem and its pools when the CephFilesystem is deleted
+	PreservePoolsOnDelete bool `json:"preservePoolsOnDelete,omitempty"`
 }
 
 type MetadataServerSpec struct {
@@ -209,6 +429,15 @@ type MetadataServerSpec struct {
 	// If false, standbys will still be available, but will not have a warm metadata cache.
 	ActiveStandby bool `json:"activeStandby"`
 
+	// The number of standby metadata servers. If not set, there will be one standby for each active server.
+	StandbyCount *int32 `json:"standbyCount,omitempty"`
+
+	// The maximum size in bytes of a file in the filesystem. If not set, the Ceph default of 1TiB applies.
+	MaxFileSize int64 `json:"maxFileSize,omitempty"`
+
+	// Pin directory subtrees to the given ranks of the active metadata servers
+	SubtreePins []MDSSubtreePinSpec `json:"subtreePins,omitempty"`
+
 	// The affinity to place the mds pods (default is to place on all available node) with a daemonset
 	Placement rook.Placement `json:"placement"`
 
@@ -216,6 +445,15 @@ type MetadataServerSpec struct {
 	Resources v1.ResourceRequirements `json:"resources"`
 }
 
+// MDSSubtreePinSpec pins a directory subtree of the filesystem to an active mds rank
+type MDSSubtreePinSpec struct {
+	// The absolute path of the directory in the filesystem
+	Path string `json:"path"`
+
+	// The rank of the active mds that will serve the subtree
+	Rank int32 `json:"rank"`
+}
+
 // +genclient
 // +genclient:noStatus
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -224,6 +462,7 @@ type CephObjectStore struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata"`
 	Spec              ObjectStoreSpec `json:"spec"`
+	Status            ResourceStatus  `json:"status,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -244,6 +483,9 @@ type ObjectStoreSpec struct {
 
 	// The rgw pod info
 	Gateway GatewaySpec `json:"gateway"`
+
+	// Whether to keep the realm and the pools when the CephObjectStore is deleted
+	PreservePoolsOnDelete bool `json:"preservePoolsOnDelete,omitempty"`
 }
 
 // +genclient
@@ -272,6 +514,34 @@ type ObjectStoreUserSpec struct {
 	DisplayName string `json:"displayName,omitempty"`
 }
 
+// +genclient
+// +genclient:noStatus
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+type CephDashboardUser struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+	Spec              DashboardUserSpec `json:"spec"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+type CephDashboardUserList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+	Items           []CephDashboardUser `json:"items"`
+}
+
+// DashboardUserSpec represents the spec of a dashboard user
+type DashboardUserSpec struct {
+	// Username to log in with. Defaults to the name of the resource.
+	Username string `json:"username,omitempty"`
+	// Roles granted to the user, such as read-only or administrator
+	Roles []string `json:"roles"`
+	// PasswordSecret selects the key of a secret in the cluster namespace holding the password
+	PasswordSecret v1.SecretKeySelector `json:"passwordSecret"`
+}
+
 type GatewaySpec struct {
 	// The port the rgw service will be listening on (http)
 	Port int32 `json:"port"`
@@ -338,3 +608,64 @@ type GaneshaServerSpec struct {
 	// Resources set resource requests and limits
 	Resources v1.ResourceRequirements `json:"resources,omitempty"`
 }
+
+// +genclient
+// +genclient:noStatus
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+type CephNFSExport struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+	Spec              NFSExportSpec `json:"spec"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+type CephNFSExportList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+	Items           []CephNFSExport `json:"items"`
+}
+
+// NFSExportSpec represents the spec of an export of a ganesha server
+type NFSExportSpec struct {
+	// NFS is the name of the CephNFS serving the export
+	NFS string `json:"nfs"`
+
+	// PseudoPath is the path of the export in the NFSv4 pseudo filesystem
+	PseudoPath string `json:"pseudoPath"`
+
+	// CephFS exports a path of a ceph filesystem
+	CephFS *NFSExportCephFSSpec `json:"cephfs,omitempty"`
+
+	// RGW exports a bucket of an object store
+	RGW *NFSExportRGWSpec `json:"rgw,omitempty"`
+
+	// AccessType is RW, RO or None. Defaults to RW.
+	AccessType string `json:"accessType,omitempty"`
+
+	// Squash is No_Root_Squash, Root_Squash, Root_Id_Squash or All_Squash. Defaults to No_Root_Squash.
+	Squash string `json:"squash,omitempty"`
+
+	// Clients are the addresses or CIDRs allowed to mount the export. All clients are allowed if empty.
+	Clients []string `json:"clients,omitempty"`
+}
+
+type NFSExportCephFSSpec struct {
+	// Filesystem is the name of the CephFilesystem to export
+	Filesystem string `json:"filesystem"`
+
+	// Path is the directory of the filesystem to export. Defaults to the root of the filesystem.
+	Path string `json:"path,omitempty"`
+}
+
+type NFSExportRGWSpec struct {
+	// Store is the name of the CephObjectStore
+	Store string `json:"store"`
+
+	// Bucket is the name of the bucket to export
+	Bucket string `json:"bucket"`
+
+	// User is the name of the CephObjectStoreUser whose keys are used to access the bucket
+	User string `json:"user"`
+}
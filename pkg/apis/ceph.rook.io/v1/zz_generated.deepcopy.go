@@ -31,6 +31,7 @@ func (in *CephBlockPool) DeepCopyInto(out *CephBlockPool) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	out.Spec = in.Spec
+	out.Status = in.Status
 	return
 }
 
@@ -91,7 +92,7 @@ func (in *CephCluster) DeepCopyInto(out *CephCluster) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 	return
 }
 
@@ -146,12 +147,73 @@ func (in *CephClusterList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CephDashboardUser) DeepCopyInto(out *CephDashboardUser) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CephDashboardUser.
+func (in *CephDashboardUser) DeepCopy() *CephDashboardUser {
+	if in == nil {
+		return nil
+	}
+	out := new(CephDashboardUser)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *CephDashboardUser) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CephDashboardUserList) DeepCopyInto(out *CephDashboardUserList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	out.ListMeta = in.ListMeta
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]CephDashboardUser, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CephDashboardUserList.
+func (in *CephDashboardUserList) DeepCopy() *CephDashboardUserList {
+	if in == nil {
+		return nil
+	}
+	out := new(CephDashboardUserList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *CephDashboardUserList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CephFilesystem) DeepCopyInto(out *CephFilesystem) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
+	out.Status = in.Status
 	return
 }
 
@@ -233,6 +295,66 @@ func (in *CephNFS) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CephNFSExport) DeepCopyInto(out *CephNFSExport) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CephNFSExport.
+func (in *CephNFSExport) DeepCopy() *CephNFSExport {
+	if in == nil {
+		return nil
+	}
+	out := new(CephNFSExport)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *CephNFSExport) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CephNFSExportList) DeepCopyInto(out *CephNFSExportList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	out.ListMeta = in.ListMeta
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]CephNFSExport, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CephNFSExportList.
+func (in *CephNFSExportList) DeepCopy() *CephNFSExportList {
+	if in == nil {
+		return nil
+	}
+	out := new(CephNFSExportList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *CephNFSExportList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CephNFSList) DeepCopyInto(out *CephNFSList) {
 	*out = *in
@@ -272,6 +394,7 @@ func (in *CephObjectStore) DeepCopyInto(out *CephObjectStore) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
+	out.Status = in.Status
 	return
 }
 
@@ -402,11 +525,28 @@ func (in *CephVersionSpec) DeepCopy() *CephVersionSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CleanupPolicySpec) DeepCopyInto(out *CleanupPolicySpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CleanupPolicySpec.
+func (in *CleanupPolicySpec) DeepCopy() *CleanupPolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CleanupPolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ClusterSpec) DeepCopyInto(out *ClusterSpec) {
 	*out = *in
 	out.CephVersion = in.CephVersion
 	in.Storage.DeepCopyInto(&out.Storage)
+	in.CrushTopology.DeepCopyInto(&out.CrushTopology)
 	if in.Placement != nil {
 		in, out := &in.Placement, &out.Placement
 		*out = make(v1alpha2.PlacementSpec, len(*in))
@@ -422,9 +562,20 @@ func (in *ClusterSpec) DeepCopyInto(out *ClusterSpec) {
 			(*out)[key] = *val.DeepCopy()
 		}
 	}
+	if in.DaemonOverrides != nil {
+		in, out := &in.DaemonOverrides, &out.DaemonOverrides
+		*out = make(DaemonOverridesSpec, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+	in.Security.DeepCopyInto(&out.Security)
 	out.Mon = in.Mon
 	out.RBDMirroring = in.RBDMirroring
 	in.Dashboard.DeepCopyInto(&out.Dashboard)
+	out.DiskHealth = in.DiskHealth
+	out.CleanupPolicy = in.CleanupPolicy
+	out.CrashCollector = in.CrashCollector
 	return
 }
 
@@ -441,6 +592,18 @@ func (in *ClusterSpec) DeepCopy() *ClusterSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ClusterStatus) DeepCopyInto(out *ClusterStatus) {
 	*out = *in
+	if in.Cleanup != nil {
+		in, out := &in.Cleanup, &out.Cleanup
+		*out = make([]NodeCleanupStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Crashes != nil {
+		in, out := &in.Crashes, &out.Crashes
+		*out = make([]CrashSummary, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -454,6 +617,152 @@ func (in *ClusterStatus) DeepCopy() *ClusterStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConnectionsSpec) DeepCopyInto(out *ConnectionsSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConnectionsSpec.
+func (in *ConnectionsSpec) DeepCopy() *ConnectionsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ConnectionsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CrashCollectorSpec) DeepCopyInto(out *CrashCollectorSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CrashCollectorSpec.
+func (in *CrashCollectorSpec) DeepCopy() *CrashCollectorSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CrashCollectorSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CrashSummary) DeepCopyInto(out *CrashSummary) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CrashSummary.
+func (in *CrashSummary) DeepCopy() *CrashSummary {
+	if in == nil {
+		return nil
+	}
+	out := new(CrashSummary)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CrushTopologySpec) DeepCopyInto(out *CrushTopologySpec) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CrushTopologySpec.
+func (in *CrushTopologySpec) DeepCopy() *CrushTopologySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CrushTopologySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DaemonOverride) DeepCopyInto(out *DaemonOverride) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DaemonOverride.
+func (in *DaemonOverride) DeepCopy() *DaemonOverride {
+	if in == nil {
+		return nil
+	}
+	out := new(DaemonOverride)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in DaemonOverridesSpec) DeepCopyInto(out *DaemonOverridesSpec) {
+	{
+		in := &in
+		*out = make(DaemonOverridesSpec, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+		return
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DaemonOverridesSpec.
+func (in DaemonOverridesSpec) DeepCopy() DaemonOverridesSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DaemonOverridesSpec)
+	in.DeepCopyInto(out)
+	return *out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DashboardExternalAccessSpec) DeepCopyInto(out *DashboardExternalAccessSpec) {
+	*out = *in
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DashboardExternalAccessSpec.
+func (in *DashboardExternalAccessSpec) DeepCopy() *DashboardExternalAccessSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DashboardExternalAccessSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DashboardSpec) DeepCopyInto(out *DashboardSpec) {
 	*out = *in
@@ -462,6 +771,11 @@ func (in *DashboardSpec) DeepCopyInto(out *DashboardSpec) {
 		*out = new(bool)
 		**out = **in
 	}
+	if in.ExternalAccess != nil {
+		in, out := &in.ExternalAccess, &out.ExternalAccess
+		*out = new(DashboardExternalAccessSpec)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -475,6 +789,44 @@ func (in *DashboardSpec) DeepCopy() *DashboardSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DashboardUserSpec) DeepCopyInto(out *DashboardUserSpec) {
+	*out = *in
+	if in.Roles != nil {
+		in, out := &in.Roles, &out.Roles
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.PasswordSecret.DeepCopyInto(&out.PasswordSecret)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DashboardUserSpec.
+func (in *DashboardUserSpec) DeepCopy() *DashboardUserSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DashboardUserSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DiskHealthSpec) DeepCopyInto(out *DiskHealthSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DiskHealthSpec.
+func (in *DiskHealthSpec) DeepCopy() *DiskHealthSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DiskHealthSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ErasureCodedSpec) DeepCopyInto(out *ErasureCodedSpec) {
 	*out = *in
@@ -566,9 +918,74 @@ func (in *GatewaySpec) DeepCopy() *GatewaySpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KeyManagementServiceSpec) DeepCopyInto(out *KeyManagementServiceSpec) {
+	*out = *in
+	if in.ConnectionDetails != nil {
+		in, out := &in.ConnectionDetails, &out.ConnectionDetails
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeyManagementServiceSpec.
+func (in *KeyManagementServiceSpec) DeepCopy() *KeyManagementServiceSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(KeyManagementServiceSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KeyRotationSpec) DeepCopyInto(out *KeyRotationSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeyRotationSpec.
+func (in *KeyRotationSpec) DeepCopy() *KeyRotationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(KeyRotationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MDSSubtreePinSpec) DeepCopyInto(out *MDSSubtreePinSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MDSSubtreePinSpec.
+func (in *MDSSubtreePinSpec) DeepCopy() *MDSSubtreePinSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(MDSSubtreePinSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MetadataServerSpec) DeepCopyInto(out *MetadataServerSpec) {
 	*out = *in
+	if in.StandbyCount != nil {
+		in, out := &in.StandbyCount, &out.StandbyCount
+		*out = new(int32)
+		**out = **in
+	}
+	if in.SubtreePins != nil {
+		in, out := &in.SubtreePins, &out.SubtreePins
+		*out = make([]MDSSubtreePinSpec, len(*in))
+		copy(*out, *in)
+	}
 	in.Placement.DeepCopyInto(&out.Placement)
 	in.Resources.DeepCopyInto(&out.Resources)
 	return
@@ -600,6 +1017,69 @@ func (in *MonSpec) DeepCopy() *MonSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NFSExportCephFSSpec) DeepCopyInto(out *NFSExportCephFSSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NFSExportCephFSSpec.
+func (in *NFSExportCephFSSpec) DeepCopy() *NFSExportCephFSSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(NFSExportCephFSSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NFSExportRGWSpec) DeepCopyInto(out *NFSExportRGWSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NFSExportRGWSpec.
+func (in *NFSExportRGWSpec) DeepCopy() *NFSExportRGWSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(NFSExportRGWSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NFSExportSpec) DeepCopyInto(out *NFSExportSpec) {
+	*out = *in
+	if in.CephFS != nil {
+		in, out := &in.CephFS, &out.CephFS
+		*out = new(NFSExportCephFSSpec)
+		**out = **in
+	}
+	if in.RGW != nil {
+		in, out := &in.RGW, &out.RGW
+		*out = new(NFSExportRGWSpec)
+		**out = **in
+	}
+	if in.Clients != nil {
+		in, out := &in.Clients, &out.Clients
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NFSExportSpec.
+func (in *NFSExportSpec) DeepCopy() *NFSExportSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(NFSExportSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NFSGaneshaSpec) DeepCopyInto(out *NFSGaneshaSpec) {
 	*out = *in
@@ -618,6 +1098,27 @@ func (in *NFSGaneshaSpec) DeepCopy() *NFSGaneshaSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeCleanupStatus) DeepCopyInto(out *NodeCleanupStatus) {
+	*out = *in
+	if in.ZappedDevices != nil {
+		in, out := &in.ZappedDevices, &out.ZappedDevices
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeCleanupStatus.
+func (in *NodeCleanupStatus) DeepCopy() *NodeCleanupStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeCleanupStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ObjectStoreSpec) DeepCopyInto(out *ObjectStoreSpec) {
 	*out = *in
@@ -702,3 +1203,38 @@ func (in *ReplicatedSpec) DeepCopy() *ReplicatedSpec {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResourceStatus) DeepCopyInto(out *ResourceStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceStatus.
+func (in *ResourceStatus) DeepCopy() *ResourceStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ResourceStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecuritySpec) DeepCopyInto(out *SecuritySpec) {
+	*out = *in
+	in.KeyManagementService.DeepCopyInto(&out.KeyManagementService)
+	out.KeyRotation = in.KeyRotation
+	out.Connections = in.Connections
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecuritySpec.
+func (in *SecuritySpec) DeepCopy() *SecuritySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SecuritySpec)
+	in.DeepCopyInto(out)
+	return out
+}
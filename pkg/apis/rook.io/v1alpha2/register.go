@@ -54,7 +54,9 @@ func init() {
 func addKnownTypes(scheme *runtime.Scheme) error {
 	scheme.AddKnownTypes(SchemeGroupVersion,
 		&Volume{},
-		&VolumeList{})
+		&VolumeList{},
+		&StorageDevice{},
+		&StorageDeviceList{})
 	metav1.AddToGroupVersion(scheme, SchemeGroupVersion)
 	return nil
 }
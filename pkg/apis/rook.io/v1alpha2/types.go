@@ -110,6 +110,7 @@ type Attachment struct {
 	ClusterName  string `json:"clusterName"`
 	MountDir     string `json:"mountDir"`
 	ReadOnly     bool   `json:"readOnly"`
+	MountUser    string `json:"mountUser,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -119,3 +120,85 @@ type VolumeList struct {
 	metav1.ListMeta `json:"metadata"`
 	Items           []Volume `json:"items"`
 }
+
+// +genclient
+// +genclient:nonNamespaced
+// +genclient:noStatus
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// StorageDevice is a disk discovered on a node by the rook-discover daemon
+type StorageDevice struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+	Spec              StorageDeviceSpec `json:"spec"`
+}
+
+// StorageDeviceSpec has the properties of the disk and the cluster claiming it
+type StorageDeviceSpec struct {
+	// Node is the name of the node the device is attached to
+	Node string `json:"node"`
+	// Name is the device name
+	Name string `json:"name"`
+	// Parent is the device parent's name
+	Parent string `json:"parent,omitempty"`
+	// HasChildren is whether the device has a children device
+	HasChildren bool `json:"hasChildren"`
+	// DevLinks is the persistent device path on the host
+	DevLinks string `json:"devLinks,omitempty"`
+	// Size is the device capacity in byte
+	Size uint64 `json:"size"`
+	// UUID is used by /dev/disk/by-uuid
+	UUID string `json:"uuid,omitempty"`
+	// Serial is the disk serial used by /dev/disk/by-id
+	Serial string `json:"serial,omitempty"`
+	// Type is disk type
+	Type string `json:"type"`
+	// Rotational is the boolean whether the device is rotational: true for hdd, false for ssd and nvme
+	Rotational bool `json:"rotational"`
+	// ReadOnly is the boolean whether the device is readonly
+	Readonly bool `json:"readOnly"`
+	// Partitions are the partitions of the device
+	Partitions []DevicePartition `json:"partitions,omitempty"`
+	// Filesystem is the filesystem currently on the device
+	Filesystem string `json:"filesystem,omitempty"`
+	// Vendor is the device vendor
+	Vendor string `json:"vendor,omitempty"`
+	// Model is the device model
+	Model string `json:"model,omitempty"`
+	// WWN is the world wide name of the device
+	WWN string `json:"wwn,omitempty"`
+	// WWNVendorExtension is the WWN_VENDOR_EXTENSION from udev info
+	WWNVendorExtension string `json:"wwnVendorExtension,omitempty"`
+	// Empty checks whether the device is completely empty
+	Empty bool `json:"empty"`
+	// Health is the SMART health of the device, nil when it could not be read
+	Health *DeviceHealth `json:"health,omitempty"`
+	// ClaimedBy is the name of the cluster which uses the device, empty if the device is not in use
+	ClaimedBy string `json:"claimedBy,omitempty"`
+}
+
+// DevicePartition is a partition of a StorageDevice
+type DevicePartition struct {
+	Name       string `json:"name"`
+	Size       uint64 `json:"size"`
+	Label      string `json:"label,omitempty"`
+	Filesystem string `json:"filesystem,omitempty"`
+}
+
+// DeviceHealth is the SMART health of a StorageDevice
+type DeviceHealth struct {
+	Passed             bool  `json:"passed"`
+	ReallocatedSectors int64 `json:"reallocatedSectors"`
+	PendingSectors     int64 `json:"pendingSectors"`
+	MediaErrors        int64 `json:"mediaErrors"`
+	PercentageUsed     int   `json:"percentageUsed"`
+	Temperature        int   `json:"temperature"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+type StorageDeviceList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+	Items           []StorageDevice `json:"items"`
+}
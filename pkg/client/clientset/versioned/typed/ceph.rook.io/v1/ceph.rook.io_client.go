@@ -29,8 +29,10 @@ type CephV1Interface interface {
 	RESTClient() rest.Interface
 	CephBlockPoolsGetter
 	CephClustersGetter
+	CephDashboardUsersGetter
 	CephFilesystemsGetter
 	CephNFSsGetter
+	CephNFSExportsGetter
 	CephObjectStoresGetter
 	CephObjectStoreUsersGetter
 }
@@ -48,6 +50,10 @@ func (c *CephV1Client) CephClusters(namespace string) CephClusterInterface {
 	return newCephClusters(c, namespace)
 }
 
+func (c *CephV1Client) CephDashboardUsers(namespace string) CephDashboardUserInterface {
+	return newCephDashboardUsers(c, namespace)
+}
+
 func (c *CephV1Client) CephFilesystems(namespace string) CephFilesystemInterface {
 	return newCephFilesystems(c, namespace)
 }
@@ -56,6 +62,10 @@ func (c *CephV1Client) CephNFSs(namespace string) CephNFSInterface {
 	return newCephNFSs(c, namespace)
 }
 
+func (c *CephV1Client) CephNFSExports(namespace string) CephNFSExportInterface {
+	return newCephNFSExports(c, namespace)
+}
+
 func (c *CephV1Client) CephObjectStores(namespace string) CephObjectStoreInterface {
 	return newCephObjectStores(c, namespace)
 }
@@ -36,6 +36,10 @@ func (c *FakeCephV1) CephClusters(namespace string) v1.CephClusterInterface {
 	return &FakeCephClusters{c, namespace}
 }
 
+func (c *FakeCephV1) CephDashboardUsers(namespace string) v1.CephDashboardUserInterface {
+	return &FakeCephDashboardUsers{c, namespace}
+}
+
 func (c *FakeCephV1) CephFilesystems(namespace string) v1.CephFilesystemInterface {
 	return &FakeCephFilesystems{c, namespace}
 }
@@ -44,6 +48,10 @@ func (c *FakeCephV1) CephNFSs(namespace string) v1.CephNFSInterface {
 	return &FakeCephNFSs{c, namespace}
 }
 
+func (c *FakeCephV1) CephNFSExports(namespace string) v1.CephNFSExportInterface {
+	return &FakeCephNFSExports{c, namespace}
+}
+
 func (c *FakeCephV1) CephObjectStores(namespace string) v1.CephObjectStoreInterface {
 	return &FakeCephObjectStores{c, namespace}
 }
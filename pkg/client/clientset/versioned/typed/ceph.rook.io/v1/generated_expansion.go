@@ -22,10 +22,14 @@ type CephBlockPoolExpansion interface{}
 
 type CephClusterExpansion interface{}
 
+type CephDashboardUserExpansion interface{}
+
 type CephFilesystemExpansion interface{}
 
 type CephNFSExpansion interface{}
 
+type CephNFSExportExpansion interface{}
+
 type CephObjectStoreExpansion interface{}
 
 type CephObjectStoreUserExpansion interface{}
@@ -28,10 +28,14 @@ type Interface interface {
 	CephBlockPools() CephBlockPoolInformer
 	// CephClusters returns a CephClusterInformer.
 	CephClusters() CephClusterInformer
+	// CephDashboardUsers returns a CephDashboardUserInformer.
+	CephDashboardUsers() CephDashboardUserInformer
 	// CephFilesystems returns a CephFilesystemInformer.
 	CephFilesystems() CephFilesystemInformer
 	// CephNFSs returns a CephNFSInformer.
 	CephNFSs() CephNFSInformer
+	// CephNFSExports returns a CephNFSExportInformer.
+	CephNFSExports() CephNFSExportInformer
 	// CephObjectStores returns a CephObjectStoreInformer.
 	CephObjectStores() CephObjectStoreInformer
 	// CephObjectStoreUsers returns a CephObjectStoreUserInformer.
@@ -59,6 +63,11 @@ func (v *version) CephClusters() CephClusterInformer {
 	return &cephClusterInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
 }
 
+// CephDashboardUsers returns a CephDashboardUserInformer.
+func (v *version) CephDashboardUsers() CephDashboardUserInformer {
+	return &cephDashboardUserInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
+
 // CephFilesystems returns a CephFilesystemInformer.
 func (v *version) CephFilesystems() CephFilesystemInformer {
 	return &cephFilesystemInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
@@ -69,6 +78,11 @@ func (v *version) CephNFSs() CephNFSInformer {
 	return &cephNFSInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
 }
 
+// CephNFSExports returns a CephNFSExportInformer.
+func (v *version) CephNFSExports() CephNFSExportInformer {
+	return &cephNFSExportInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
+
 // CephObjectStores returns a CephObjectStoreInformer.
 func (v *version) CephObjectStores() CephObjectStoreInformer {
 	return &cephObjectStoreInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
@@ -68,10 +68,14 @@ func (f *sharedInformerFactory) ForResource(resource schema.GroupVersionResource
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Ceph().V1().CephBlockPools().Informer()}, nil
 	case v1.SchemeGroupVersion.WithResource("cephclusters"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Ceph().V1().CephClusters().Informer()}, nil
+	case v1.SchemeGroupVersion.WithResource("cephdashboardusers"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Ceph().V1().CephDashboardUsers().Informer()}, nil
 	case v1.SchemeGroupVersion.WithResource("cephfilesystems"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Ceph().V1().CephFilesystems().Informer()}, nil
 	case v1.SchemeGroupVersion.WithResource("cephnfss"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Ceph().V1().CephNFSs().Informer()}, nil
+	case v1.SchemeGroupVersion.WithResource("cephnfsexports"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Ceph().V1().CephNFSExports().Informer()}, nil
 	case v1.SchemeGroupVersion.WithResource("cephobjectstores"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Ceph().V1().CephObjectStores().Informer()}, nil
 	case v1.SchemeGroupVersion.WithResource("cephobjectstoreusers"):
@@ -116,6 +120,8 @@ func (f *sharedInformerFactory) ForResource(resource schema.GroupVersionResource
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Nfs().V1alpha1().NFSServers().Informer()}, nil
 
 		// Group=rook.io, Version=v1alpha2
+	case v1alpha2.SchemeGroupVersion.WithResource("storagedevices"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Rook().V1alpha2().StorageDevices().Informer()}, nil
 	case v1alpha2.SchemeGroupVersion.WithResource("volumes"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Rook().V1alpha2().Volumes().Informer()}, nil
 
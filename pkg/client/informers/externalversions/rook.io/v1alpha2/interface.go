@@ -24,6 +24,8 @@ import (
 
 // Interface provides access to all the informers in this group version.
 type Interface interface {
+	// StorageDevices returns a StorageDeviceInformer.
+	StorageDevices() StorageDeviceInformer
 	// Volumes returns a VolumeInformer.
 	Volumes() VolumeInformer
 }
@@ -39,6 +41,11 @@ func New(f internalinterfaces.SharedInformerFactory, namespace string, tweakList
 	return &version{factory: f, namespace: namespace, tweakListOptions: tweakListOptions}
 }
 
+// StorageDevices returns a StorageDeviceInformer.
+func (v *version) StorageDevices() StorageDeviceInformer {
+	return &storageDeviceInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
+}
+
 // Volumes returns a VolumeInformer.
 func (v *version) Volumes() VolumeInformer {
 	return &volumeInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
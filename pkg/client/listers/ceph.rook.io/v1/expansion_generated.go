@@ -34,6 +34,14 @@ type CephClusterListerExpansion interface{}
 // CephClusterNamespaceLister.
 type CephClusterNamespaceListerExpansion interface{}
 
+// CephDashboardUserListerExpansion allows custom methods to be added to
+// CephDashboardUserLister.
+type CephDashboardUserListerExpansion interface{}
+
+// CephDashboardUserNamespaceListerExpansion allows custom methods to be added to
+// CephDashboardUserNamespaceLister.
+type CephDashboardUserNamespaceListerExpansion interface{}
+
 // CephFilesystemListerExpansion allows custom methods to be added to
 // CephFilesystemLister.
 type CephFilesystemListerExpansion interface{}
@@ -50,6 +58,14 @@ type CephNFSListerExpansion interface{}
 // CephNFSNamespaceLister.
 type CephNFSNamespaceListerExpansion interface{}
 
+// CephNFSExportListerExpansion allows custom methods to be added to
+// CephNFSExportLister.
+type CephNFSExportListerExpansion interface{}
+
+// CephNFSExportNamespaceListerExpansion allows custom methods to be added to
+// CephNFSExportNamespaceLister.
+type CephNFSExportNamespaceListerExpansion interface{}
+
 // CephObjectStoreListerExpansion allows custom methods to be added to
 // CephObjectStoreLister.
 type CephObjectStoreListerExpansion interface{}
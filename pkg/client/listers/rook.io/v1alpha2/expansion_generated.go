@@ -18,6 +18,10 @@ limitations under the License.
 
 package v1alpha2
 
+// StorageDeviceListerExpansion allows custom methods to be added to
+// StorageDeviceLister.
+type StorageDeviceListerExpansion interface{}
+
 // VolumeListerExpansion allows custom methods to be added to
 // VolumeLister.
 type VolumeListerExpansion interface{}
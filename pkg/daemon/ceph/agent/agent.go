@@ -59,6 +59,15 @@ func (a *Agent) Run() error {
 		return fmt.Errorf("failed to create volume manager: %+v", err)
 	}
 
+	// rbd-nbd is optional, the volumes can still be attached with the kernel rbd module when nbd is not available
+	var nbdManager flexvolume.VolumeManager
+	nbdVolumeManager, err := ceph.NewNbdVolumeManager(a.context, agent.RBDNbdStateDirPath)
+	if err != nil {
+		logger.Warningf("failed to create the rbd-nbd volume manager, the volumes with the nbd mounter can't be attached. %+v", err)
+	} else {
+		nbdManager = nbdVolumeManager
+	}
+
 	mountSecurityMode := os.Getenv(agent.AgentMountSecurityModeEnv)
 	// Don't check if it is not empty because the operator always sets it on the DaemonSet
 	// meaning if it is not set, there is something wrong thus return an error.
@@ -66,7 +75,16 @@ func (a *Agent) Run() error {
 		return fmt.Errorf("no mount security mode env var found on the agent, have you upgraded your Rook operator correctly?")
 	}
 
-	flexvolumeController := flexvolume.NewController(a.context, volumeAttachmentController, volumeManager, mountSecurityMode)
+	// the dead nodes are not fenced unless a fencing timeout is set
+	var fencingTimeout time.Duration
+	if value := os.Getenv(agent.RookFencingTimeoutEnv); value != "" {
+		fencingTimeout, err = time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid fencing timeout %s. %+v", value, err)
+		}
+	}
+
+	flexvolumeController := flexvolume.NewController(a.context, volumeAttachmentController, volumeManager, nbdManager, mountSecurityMode, fencingTimeout)
 
 	flexvolumeServer := flexvolume.NewFlexvolumeServer(
 		a.context,
@@ -115,6 +133,10 @@ func (a *Agent) Run() error {
 	stopChan := make(chan struct{})
 	clusterController.StartWatch(v1.NamespaceAll, stopChan)
 	go periodicallyRefreshFlexDrivers(driverName, stopChan)
+	go periodicallyUnfenceNode(flexvolumeController, stopChan)
+	if nbdManager != nil {
+		go nbdVolumeManager.ReattachMappings(stopChan)
+	}
 
 	sigc := make(chan os.Signal, 1)
 	signal.Notify(sigc, syscall.SIGTERM)
@@ -152,3 +174,18 @@ func periodicallyRefreshFlexDrivers(driverName string, stopCh chan struct{}) {
 		}
 	}
 }
+
+// Remove the clients of this node from the blacklist when the node is back after it was fenced
+func periodicallyUnfenceNode(controller *flexvolume.Controller, stopCh chan struct{}) {
+	for {
+		if err := controller.UnfenceNode(nil, nil); err != nil {
+			logger.Warningf("failed to unfence node. %+v", err)
+		}
+		select {
+		case <-time.After(time.Minute):
+		case <-stopCh:
+			logger.Infof("stopping unfence goroutine")
+			return
+		}
+	}
+}
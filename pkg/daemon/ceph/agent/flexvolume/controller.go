@@ -23,6 +23,7 @@ import (
 	"path"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/coreos/pkg/capnslog"
 	rookalpha "github.com/rook/rook/pkg/apis/rook.io/v1alpha2"
@@ -31,6 +32,7 @@ import (
 	"github.com/rook/rook/pkg/operator/ceph/agent"
 	"github.com/rook/rook/pkg/operator/ceph/cluster"
 	"github.com/rook/rook/pkg/operator/ceph/cluster/mon"
+	"github.com/rook/rook/pkg/operator/ceph/config/keyring"
 	"github.com/rook/rook/pkg/operator/k8sutil"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -51,8 +53,17 @@ const (
 	// PoolKey key for image name option.
 	ImageKey = "image"
 	// PoolKey key for data pool name option.
-	DataBlockPoolKey      = "dataBlockPool"
+	DataBlockPoolKey = "dataBlockPool"
+	// MounterKey key for the rbd client attaching the volume.
+	MounterKey = "mounter"
+	// MounterRBD attaches the volumes with the kernel rbd module.
+	MounterRBD = "rbd"
+	// MounterNbd attaches the volumes with rbd-nbd, supporting all the image features.
+	MounterNbd            = "nbd"
 	kubeletDefaultRootDir = "/var/lib/kubelet"
+	// defaultMountUser is the mount user of the volumes without a mount user. Without a mount secret, they are mapped
+	// with the flex user of the cluster, or with the admin until the operator creates the flex user.
+	defaultMountUser = "admin"
 )
 
 var driverLogger = capnslog.NewPackageLogger("github.com/rook/rook", "flexdriver")
@@ -61,8 +72,11 @@ var driverLogger = capnslog.NewPackageLogger("github.com/rook/rook", "flexdriver
 type Controller struct {
 	context           *clusterd.Context
 	volumeManager     VolumeManager
+	nbdVolumeManager  VolumeManager
 	volumeAttachment  attachment.Attachment
 	mountSecurityMode string
+	// fencingTimeout is how long a node must be not ready before the clients of its volumes are blacklisted
+	fencingTimeout time.Duration
 }
 
 // ClientAccessInfo hols info for Ceph access
@@ -73,12 +87,14 @@ type ClientAccessInfo struct {
 }
 
 // NewController create a new controller to handle events from the flexvolume driver
-func NewController(context *clusterd.Context, volumeAttachment attachment.Attachment, manager VolumeManager, mountSecurityMode string) *Controller {
+func NewController(context *clusterd.Context, volumeAttachment attachment.Attachment, manager, nbdManager VolumeManager, mountSecurityMode string, fencingTimeout time.Duration) *Controller {
 	return &Controller{
 		context:           context,
 		volumeAttachment:  volumeAttachment,
 		volumeManager:     manager,
+		nbdVolumeManager:  nbdManager,
 		mountSecurityMode: mountSecurityMode,
+		fencingTimeout:    fencingTimeout,
 	}
 }
 
@@ -87,6 +103,27 @@ func (c *Controller) Attach(attachOpts AttachOptions, devicePath *string) error
 	namespace := os.Getenv(k8sutil.PodNamespaceEnvVar)
 	node := os.Getenv(k8sutil.NodeNameEnvVar)
 
+	fenced, err := c.isFenced(attachOpts)
+	if err != nil {
+		return err
+	}
+	if fenced {
+		return fmt.Errorf("failed to attach volume %s/%s. The stale mapping of the volume fenced on node %s is not removed yet",
+			attachOpts.BlockPool, attachOpts.Image, node)
+	}
+
+	// the volumes without a mount secret are mapped with the flex user, whose key is not rotated
+	if attachOpts.MountUser == defaultMountUser && attachOpts.MountSecret == "" {
+		id, key, err := c.flexCredentials(attachOpts.ClusterNamespace)
+		if err != nil {
+			return err
+		}
+		if id != "" {
+			attachOpts.MountUser = id
+			attachOpts.MountSecret = key
+		}
+	}
+
 	// Name of CRD is the PV name. This is done so that the CRD can be use for fencing
 	crdName := attachOpts.VolumeName
 
@@ -107,6 +144,7 @@ func (c *Controller) Attach(attachOpts AttachOptions, devicePath *string) error
 			attachOpts.MountDir,
 			strings.ToLower(attachOpts.RW) == ReadOnly,
 		)
+		volumeattachObj.Attachments[0].MountUser = attachOpts.MountUser
 		logger.Infof("creating Volume attach Resource %s/%s: %+v", volumeattachObj.Namespace, volumeattachObj.Name, attachOpts)
 		err = c.volumeAttachment.Create(volumeattachObj)
 		if err != nil {
@@ -146,22 +184,31 @@ func (c *Controller) Attach(attachOpts AttachOptions, devicePath *string) error
 					}
 
 					logger.Infof("volume attachment record %s/%s is orphaned. Updating record with new attachment information for pod %s/%s", volumeattachObj.Namespace, volumeattachObj.Name, attachOpts.PodNamespace, attachOpts.Pod)
-
-					// Attachment is orphaned. Update attachment record and proceed with attaching
-					attachment.Node = node
-					attachment.MountDir = attachOpts.MountDir
-					attachment.PodNamespace = attachOpts.PodNamespace
-					attachment.PodName = attachOpts.Pod
-					attachment.ClusterName = attachOpts.ClusterNamespace
-					attachment.ReadOnly = attachOpts.RW == ReadOnly
-					err = c.volumeAttachment.Update(volumeattachObj)
+				} else {
+					// Attachment is not orphaned. Original pod still exists. Dont attach unless its node is dead.
+					fenced, err := c.fenceDeadNode(attachment, attachOpts)
 					if err != nil {
-						return fmt.Errorf("failed to update volume CRD %s. %+v", crdName, err)
+						return fmt.Errorf("failed to fence node %s of volume %s. %+v", attachment.Node, crdName, err)
 					}
-				} else {
-					// Attachment is not orphaned. Original pod still exists. Dont attach.
-					return fmt.Errorf("failed to attach volume %s for pod %s/%s. Volume is already attached by pod %s/%s. Status %+v",
-						crdName, attachOpts.PodNamespace, attachOpts.Pod, attachment.PodNamespace, attachment.PodName, pod.Status.Phase)
+					if !fenced {
+						return fmt.Errorf("failed to attach volume %s for pod %s/%s. Volume is already attached by pod %s/%s. Status %+v",
+							crdName, attachOpts.PodNamespace, attachOpts.Pod, attachment.PodNamespace, attachment.PodName, pod.Status.Phase)
+					}
+
+					logger.Infof("volume attachment record %s/%s is held by the fenced node %s. Updating record with new attachment information for pod %s/%s", volumeattachObj.Namespace, volumeattachObj.Name, attachment.Node, attachOpts.PodNamespace, attachOpts.Pod)
+				}
+
+				// Attachment is orphaned or its node is fenced. Update attachment record and proceed with attaching
+				attachment.Node = node
+				attachment.MountDir = attachOpts.MountDir
+				attachment.PodNamespace = attachOpts.PodNamespace
+				attachment.PodName = attachOpts.Pod
+				attachment.ClusterName = attachOpts.ClusterNamespace
+				attachment.ReadOnly = attachOpts.RW == ReadOnly
+				attachment.MountUser = attachOpts.MountUser
+				err = c.volumeAttachment.Update(volumeattachObj)
+				if err != nil {
+					return fmt.Errorf("failed to update volume CRD %s. %+v", crdName, err)
 				}
 			} else {
 				// No RW attachment found. Check if this is a RW attachment request.
@@ -179,6 +226,7 @@ func (c *Controller) Attach(attachOpts AttachOptions, devicePath *string) error
 					ClusterName:  attachOpts.ClusterNamespace,
 					MountDir:     attachOpts.MountDir,
 					ReadOnly:     attachOpts.RW == ReadOnly,
+					MountUser:    attachOpts.MountUser,
 				}
 				volumeattachObj.Attachments = append(volumeattachObj.Attachments, newAttach)
 				err = c.volumeAttachment.Update(volumeattachObj)
@@ -188,7 +236,11 @@ func (c *Controller) Attach(attachOpts AttachOptions, devicePath *string) error
 			}
 		}
 	}
-	*devicePath, err = c.volumeManager.Attach(attachOpts.Image, attachOpts.BlockPool, attachOpts.MountUser, attachOpts.MountSecret, attachOpts.ClusterNamespace)
+	volumeManager, err := c.getVolumeManager(attachOpts.Mounter)
+	if err != nil {
+		return err
+	}
+	*devicePath, err = volumeManager.Attach(attachOpts.Image, attachOpts.BlockPool, attachOpts.MountUser, attachOpts.MountSecret, attachOpts.ClusterNamespace)
 	if err != nil {
 		return fmt.Errorf("failed to attach volume %s/%s: %+v", attachOpts.BlockPool, attachOpts.Image, err)
 	}
@@ -206,7 +258,11 @@ func (c *Controller) DetachForce(detachOpts AttachOptions, _ *struct{} /* void r
 }
 
 func (c *Controller) doDetach(detachOpts AttachOptions, force bool) error {
-	if err := c.volumeManager.Detach(
+	volumeManager, err := c.getVolumeManager(detachOpts.Mounter)
+	if err != nil {
+		return err
+	}
+	if err := volumeManager.Detach(
 		detachOpts.Image,
 		detachOpts.BlockPool,
 		detachOpts.MountUser,
@@ -230,6 +286,21 @@ func (c *Controller) doDetach(detachOpts AttachOptions, force bool) error {
 	return nil
 }
 
+// getVolumeManager returns the volume manager of the rbd client set in the storage class, the kernel rbd module by default
+func (c *Controller) getVolumeManager(mounter string) (VolumeManager, error) {
+	switch mounter {
+	case "", MounterRBD:
+		return c.volumeManager, nil
+	case MounterNbd:
+		if c.nbdVolumeManager == nil {
+			return nil, fmt.Errorf("rbd-nbd is not available on node %s", os.Getenv(k8sutil.NodeNameEnvVar))
+		}
+		return c.nbdVolumeManager, nil
+	default:
+		return nil, fmt.Errorf("unknown mounter %s", mounter)
+	}
+}
+
 // RemoveAttachmentObject removes the attachment from the Volume CRD and returns whether the volume is safe to detach
 func (c *Controller) RemoveAttachmentObject(detachOpts AttachOptions, safeToDetach *bool) error {
 	namespace := os.Getenv(k8sutil.PodNamespaceEnvVar)
@@ -346,8 +417,11 @@ func (c *Controller) GetAttachInfoFromMountDir(mountDir string, attachOptions *A
 	if attachOptions.StorageClass == "" {
 		attachOptions.StorageClass = pv.Spec.PersistentVolumeSource.FlexVolume.Options[StorageClassKey]
 	}
+	if attachOptions.Mounter == "" {
+		attachOptions.Mounter = pv.Spec.PersistentVolumeSource.FlexVolume.Options[MounterKey]
+	}
 	if attachOptions.MountUser == "" {
-		attachOptions.MountUser = "admin"
+		attachOptions.MountUser = defaultMountUser
 	}
 	attachOptions.ClusterNamespace, err = c.parseClusterNamespace(attachOptions.StorageClass)
 	if err != nil {
@@ -392,8 +466,21 @@ func (c *Controller) GetClientAccessInfo(args []string, clientAccessInfo *Client
 		return fmt.Errorf("no mount user and/or mount secret given")
 	}
 
+	if c.mountSecurityMode == agent.MountSecurityModeAny && clientAccessInfo.UserName == "" && clientAccessInfo.SecretKey == "" {
+		// the filesystems are mounted with the flex user, whose key is not rotated
+		id, key, err := c.flexCredentials(clusterNamespace)
+		if err != nil {
+			return err
+		}
+		if id != "" {
+			clientAccessInfo.UserName = id
+			clientAccessInfo.SecretKey = key
+			return nil
+		}
+	}
+
 	if c.mountSecurityMode == agent.MountSecurityModeAny && clientAccessInfo.UserName == "" {
-		clientAccessInfo.UserName = "admin"
+		clientAccessInfo.UserName = defaultMountUser
 	}
 
 	if clientAccessInfo.SecretKey != "" {
@@ -417,6 +504,19 @@ func (c *Controller) GetClientAccessInfo(args []string, clientAccessInfo *Client
 	return nil
 }
 
+// flexCredentials returns the ID and the key of the flex user of the cluster. No ID is returned if the operator did not
+// create the flex user yet, the volumes are then mapped with the admin.
+func (c *Controller) flexCredentials(clusterNamespace string) (string, string, error) {
+	id, key, err := keyring.GetSecretStore(c.context, clusterNamespace, nil).Flex().Get()
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return "", "", nil
+		}
+		return "", "", fmt.Errorf("failed to get the flex user of cluster %s. %+v", clusterNamespace, err)
+	}
+	return id, key, nil
+}
+
 // GetKernelVersion returns the kernel version of the current node.
 func (c *Controller) GetKernelVersion(_ *struct{} /* no inputs */, kernelVersion *string) error {
 	nodeName := os.Getenv(k8sutil.NodeNameEnvVar)
@@ -31,6 +31,7 @@ import (
 	"github.com/rook/rook/pkg/clusterd"
 	"github.com/rook/rook/pkg/daemon/ceph/agent/flexvolume/attachment"
 	"github.com/rook/rook/pkg/daemon/ceph/agent/flexvolume/manager"
+	"github.com/rook/rook/pkg/operator/ceph/config/keyring"
 	"github.com/rook/rook/pkg/operator/k8sutil"
 	"github.com/rook/rook/pkg/operator/test"
 	"github.com/stretchr/testify/assert"
@@ -92,6 +93,126 @@ func TestAttach(t *testing.T) {
 	assert.False(t, a.ReadOnly)
 }
 
+func TestAttachFlexUser(t *testing.T) {
+	clientset := test.New(3)
+
+	os.Setenv(k8sutil.PodNamespaceEnvVar, "rook-system")
+	defer os.Unsetenv(k8sutil.PodNamespaceEnvVar)
+
+	os.Setenv(k8sutil.NodeNameEnvVar, "node1")
+	defer os.Unsetenv(k8sutil.NodeNameEnvVar)
+
+	context := &clusterd.Context{
+		Clientset:     clientset,
+		RookClientset: rookclient.NewSimpleClientset(),
+	}
+	k := keyring.GetSecretStore(context, "testCluster", &metav1.OwnerReference{})
+	assert.Nil(t, k.CreateOrUpdate("rook-ceph-flex", "[client.rook-flex]\n\tkey = flexkey\n"))
+
+	opts := AttachOptions{
+		Image:            "image123",
+		Pool:             "testpool",
+		ClusterNamespace: "testCluster",
+		StorageClass:     "storageclass1",
+		MountDir:         "/test/pods/pod123/volumes/rook.io~rook/pvc-123",
+		VolumeName:       "pvc-123",
+		Pod:              "myPod",
+		PodNamespace:     "Default",
+		RW:               "rw",
+		MountUser:        "admin",
+	}
+	att, err := attachment.New(context)
+	assert.Nil(t, err)
+
+	var mappedID, mappedKey string
+	controller := &Controller{
+		context:          context,
+		volumeAttachment: att,
+		volumeManager: &manager.FakeVolumeManager{
+			FakeAttach: func(image, pool, id, key, clusterName string) (string, error) {
+				mappedID = id
+				mappedKey = key
+				return "/dev/rbd0", nil
+			},
+		},
+	}
+
+	// the volume without a mount secret is mapped with the flex user instead of the admin
+	devicePath := ""
+	err = controller.Attach(opts, &devicePath)
+	assert.Nil(t, err)
+	assert.Equal(t, "rook-flex", mappedID)
+	assert.Equal(t, "flexkey", mappedKey)
+	volumeAttachment, err := context.RookClientset.RookV1alpha2().Volumes("rook-system").Get("pvc-123", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(volumeAttachment.Attachments))
+	assert.Equal(t, "rook-flex", volumeAttachment.Attachments[0].MountUser)
+}
+
+func TestAttachMounter(t *testing.T) {
+	clientset := test.New(3)
+
+	os.Setenv(k8sutil.PodNamespaceEnvVar, "rook-system")
+	defer os.Unsetenv(k8sutil.PodNamespaceEnvVar)
+
+	os.Setenv(k8sutil.NodeNameEnvVar, "node1")
+	defer os.Unsetenv(k8sutil.NodeNameEnvVar)
+
+	context := &clusterd.Context{
+		Clientset:     clientset,
+		RookClientset: rookclient.NewSimpleClientset(),
+	}
+
+	opts := AttachOptions{
+		Image:            "image123",
+		Pool:             "testpool",
+		ClusterNamespace: "testCluster",
+		StorageClass:     "storageclass1",
+		MountDir:         "/test/pods/pod123/volumes/rook.io~rook/pvc-123",
+		VolumeName:       "pvc-123",
+		Pod:              "myPod",
+		PodNamespace:     "Default",
+		RW:               "rw",
+		Mounter:          "nbd",
+	}
+	att, err := attachment.New(context)
+	assert.Nil(t, err)
+
+	controller := &Controller{
+		context:          context,
+		volumeAttachment: att,
+		volumeManager: &manager.FakeVolumeManager{
+			FakeAttach: func(image, pool, id, key, clusterName string) (string, error) {
+				return "/dev/rbd0", nil
+			},
+		},
+	}
+
+	// rbd-nbd is not available on the node
+	devicePath := ""
+	err = controller.Attach(opts, &devicePath)
+	assert.NotNil(t, err)
+
+	controller.nbdVolumeManager = &manager.FakeVolumeManager{
+		FakeAttach: func(image, pool, id, key, clusterName string) (string, error) {
+			return "/dev/nbd0", nil
+		},
+	}
+	err = controller.Attach(opts, &devicePath)
+	assert.Nil(t, err)
+	assert.Equal(t, "/dev/nbd0", devicePath)
+
+	// the kernel rbd module by default
+	opts.Mounter = ""
+	err = controller.Attach(opts, &devicePath)
+	assert.Nil(t, err)
+	assert.Equal(t, "/dev/rbd0", devicePath)
+
+	opts.Mounter = "fuse"
+	err = controller.Attach(opts, &devicePath)
+	assert.NotNil(t, err)
+}
+
 func TestAttachAlreadyExist(t *testing.T) {
 	clientset := test.New(3)
 
@@ -725,6 +846,7 @@ func TestGetAttachInfoFromMountDir(t *testing.T) {
 						PoolKey:          "pool123",
 						ImageKey:         "pvc-123",
 						DataBlockPoolKey: "",
+						MounterKey:       "nbd",
 					},
 				},
 			},
@@ -777,6 +899,7 @@ func TestGetAttachInfoFromMountDir(t *testing.T) {
 	assert.Equal(t, "pool123", opts.BlockPool)
 	assert.Equal(t, "storageClass1", opts.StorageClass)
 	assert.Equal(t, "testCluster", opts.ClusterNamespace)
+	assert.Equal(t, "nbd", opts.Mounter)
 }
 
 func TestParseClusterNamespace(t *testing.T) {
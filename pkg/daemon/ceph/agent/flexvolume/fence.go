@@ -0,0 +1,245 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package flexvolume
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	rookalpha "github.com/rook/rook/pkg/apis/rook.io/v1alpha2"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// FencingConfigMapName is the name of the configmap keeping the clients blacklisted on each node
+	FencingConfigMapName = "rook-ceph-agent-fencing"
+
+	// the stale mappings of the fenced volumes are removed with the admin keyring of the cluster
+	staleMappingUser = "admin"
+)
+
+// fencedVolume is a volume whose clients on a dead node were blacklisted
+type fencedVolume struct {
+	Pool    string   `json:"pool"`
+	Image   string   `json:"image"`
+	Mounter string   `json:"mounter,omitempty"`
+	Clients []string `json:"clients"`
+}
+
+// fencedVolumes are the fenced volumes of a node by cluster namespace
+type fencedVolumes map[string][]fencedVolume
+
+// fenceDeadNode blacklists the clients of the volume on the node of the attachment if the node has not been ready
+// for longer than the fencing timeout. Returns whether the node was fenced and the volume can be attached.
+func (c *Controller) fenceDeadNode(attachment *rookalpha.Attachment, attachOpts AttachOptions) (bool, error) {
+	if c.fencingTimeout == 0 || attachment.Node == os.Getenv(k8sutil.NodeNameEnvVar) {
+		return false, nil
+	}
+
+	node, err := c.context.Clientset.CoreV1().Nodes().Get(attachment.Node, metav1.GetOptions{})
+	if err != nil {
+		return false, fmt.Errorf("failed to get node %s. %+v", attachment.Node, err)
+	}
+	notReadySince, ready := nodeNotReadySince(node)
+	if ready {
+		return false, nil
+	}
+	if time.Since(notReadySince) < c.fencingTimeout {
+		logger.Infof("node %s of the volume attachment %s is not ready since %s, waiting for the fencing timeout of %s",
+			node.Name, attachOpts.VolumeName, notReadySince, c.fencingTimeout)
+		return false, nil
+	}
+
+	nodeIPs := []string{}
+	for _, address := range node.Status.Addresses {
+		if address.Type == v1.NodeInternalIP || address.Type == v1.NodeExternalIP {
+			nodeIPs = append(nodeIPs, address.Address)
+		}
+	}
+	logger.Warningf("node %s is not ready since %s, fencing its clients of volume %s/%s", node.Name, notReadySince, attachOpts.BlockPool, attachOpts.Image)
+	clients, fenceErr := c.volumeManager.Fence(attachOpts.Image, attachOpts.BlockPool, attachment.ClusterName, nodeIPs)
+	// record the clients blacklisted so far, even on failure, to remove them from the blacklist when the node is back
+	if len(clients) > 0 {
+		volume := fencedVolume{Pool: attachOpts.BlockPool, Image: attachOpts.Image, Mounter: attachOpts.Mounter, Clients: clients}
+		if err := c.recordFencedVolume(node.Name, attachment.ClusterName, volume); err != nil {
+			return false, err
+		}
+	}
+	if fenceErr != nil {
+		return false, fmt.Errorf("failed to fence node %s. %+v", node.Name, fenceErr)
+	}
+	return true, nil
+}
+
+// nodeNotReadySince returns the time the node stopped being ready, or true if the node is ready
+func nodeNotReadySince(node *v1.Node) (time.Time, bool) {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == v1.NodeReady {
+			return condition.LastTransitionTime.Time, condition.Status == v1.ConditionTrue
+		}
+	}
+	// the node never reported its status
+	return node.CreationTimestamp.Time, false
+}
+
+func (c *Controller) recordFencedVolume(nodeName, clusterNamespace string, volume fencedVolume) error {
+	namespace := os.Getenv(k8sutil.PodNamespaceEnvVar)
+	configMaps := c.context.Clientset.CoreV1().ConfigMaps(namespace)
+	cm, err := configMaps.Get(FencingConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to get configmap %s. %+v", FencingConfigMapName, err)
+		}
+		cm = &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: FencingConfigMapName, Namespace: namespace},
+			Data:       map[string]string{},
+		}
+		if cm, err = configMaps.Create(cm); err != nil {
+			return fmt.Errorf("failed to create configmap %s. %+v", FencingConfigMapName, err)
+		}
+	}
+
+	fenced := fencedVolumes{}
+	if data, ok := cm.Data[nodeName]; ok {
+		if err := json.Unmarshal([]byte(data), &fenced); err != nil {
+			return fmt.Errorf("failed to parse the fenced clients of node %s. %+v", nodeName, err)
+		}
+	}
+	fenced[clusterNamespace] = append(fenced[clusterNamespace], volume)
+	if err := setFencedVolumes(cm, nodeName, fenced); err != nil {
+		return err
+	}
+	if _, err := configMaps.Update(cm); err != nil {
+		return fmt.Errorf("failed to record the fenced clients of node %s. %+v", nodeName, err)
+	}
+	return nil
+}
+
+func setFencedVolumes(cm *v1.ConfigMap, nodeName string, fenced fencedVolumes) error {
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	if len(fenced) == 0 {
+		delete(cm.Data, nodeName)
+		return nil
+	}
+	data, err := json.Marshal(fenced)
+	if err != nil {
+		return err
+	}
+	cm.Data[nodeName] = string(data)
+	return nil
+}
+
+// isFenced returns whether the clients of this node were blacklisted for the volume and its stale mapping is not
+// removed yet. The volume cannot be attached again on this node until then, the stale mapping would be reused.
+func (c *Controller) isFenced(attachOpts AttachOptions) (bool, error) {
+	cm, err := c.context.Clientset.CoreV1().ConfigMaps(os.Getenv(k8sutil.PodNamespaceEnvVar)).Get(FencingConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get configmap %s. %+v", FencingConfigMapName, err)
+	}
+	nodeName := os.Getenv(k8sutil.NodeNameEnvVar)
+	data, ok := cm.Data[nodeName]
+	if !ok {
+		return false, nil
+	}
+	fenced := fencedVolumes{}
+	if err := json.Unmarshal([]byte(data), &fenced); err != nil {
+		return false, fmt.Errorf("failed to parse the fenced clients of node %s. %+v", nodeName, err)
+	}
+	for _, volume := range fenced[attachOpts.ClusterNamespace] {
+		if volume.Pool == attachOpts.BlockPool && volume.Image == attachOpts.Image {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// UnfenceNode removes the clients of this node from the blacklist once the node is ready again and the stale
+// mappings of the fenced volumes are removed
+func (c *Controller) UnfenceNode(_ *struct{} /* no inputs */, _ *struct{} /* void reply */) error {
+	nodeName := os.Getenv(k8sutil.NodeNameEnvVar)
+	configMaps := c.context.Clientset.CoreV1().ConfigMaps(os.Getenv(k8sutil.PodNamespaceEnvVar))
+	cm, err := configMaps.Get(FencingConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to get configmap %s. %+v", FencingConfigMapName, err)
+	}
+	data, ok := cm.Data[nodeName]
+	if !ok {
+		return nil
+	}
+
+	node, err := c.context.Clientset.CoreV1().Nodes().Get(nodeName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get node %s. %+v", nodeName, err)
+	}
+	if _, ready := nodeNotReadySince(node); !ready {
+		return nil
+	}
+
+	fenced := fencedVolumes{}
+	if err := json.Unmarshal([]byte(data), &fenced); err != nil {
+		return fmt.Errorf("failed to parse the fenced clients of node %s. %+v", nodeName, err)
+	}
+	remaining := fencedVolumes{}
+	for clusterNamespace, volumes := range fenced {
+		for _, volume := range volumes {
+			if err := c.unfenceVolume(clusterNamespace, volume); err != nil {
+				logger.Warningf("keeping the clients of volume %s/%s on node %s blacklisted. %+v", volume.Pool, volume.Image, nodeName, err)
+				remaining[clusterNamespace] = append(remaining[clusterNamespace], volume)
+			}
+		}
+	}
+
+	if err := setFencedVolumes(cm, nodeName, remaining); err != nil {
+		return err
+	}
+	if _, err := configMaps.Update(cm); err != nil {
+		return fmt.Errorf("failed to remove the fenced clients of node %s. %+v", nodeName, err)
+	}
+	if len(remaining) == 0 {
+		logger.Infof("node %s is ready, removed its clients from the blacklist", nodeName)
+	}
+	return nil
+}
+
+// unfenceVolume removes the stale mapping of a fenced volume from this node, then its clients from the blacklist.
+// The blacklisted clients could still write to the volume attached to another node if the mapping was kept.
+func (c *Controller) unfenceVolume(clusterNamespace string, volume fencedVolume) error {
+	volumeManager, err := c.getVolumeManager(volume.Mounter)
+	if err != nil {
+		return err
+	}
+	if err := volumeManager.Detach(volume.Image, volume.Pool, staleMappingUser, "", clusterNamespace, true /* force */); err != nil {
+		return fmt.Errorf("failed to remove the stale mapping. %+v", err)
+	}
+	if err := c.volumeManager.Unfence(clusterNamespace, volume.Clients); err != nil {
+		return fmt.Errorf("failed to remove the clients from the blacklist. %+v", err)
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package flexvolume
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	rookalpha "github.com/rook/rook/pkg/apis/rook.io/v1alpha2"
+	rookclient "github.com/rook/rook/pkg/client/clientset/versioned/fake"
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/daemon/ceph/agent/flexvolume/attachment"
+	"github.com/rook/rook/pkg/daemon/ceph/agent/flexvolume/manager"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	"github.com/rook/rook/pkg/operator/test"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func setNodeReady(t *testing.T, context *clusterd.Context, name string, status v1.ConditionStatus, since time.Time) {
+	node, err := context.Clientset.CoreV1().Nodes().Get(name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	node.Status.Conditions = []v1.NodeCondition{{Type: v1.NodeReady, Status: status, LastTransitionTime: metav1.NewTime(since)}}
+	_, err = context.Clientset.CoreV1().Nodes().Update(node)
+	assert.Nil(t, err)
+}
+
+func TestAttachFenceDeadNode(t *testing.T) {
+	clientset := test.New(3)
+
+	os.Setenv(k8sutil.PodNamespaceEnvVar, "rook-system")
+	defer os.Unsetenv(k8sutil.PodNamespaceEnvVar)
+
+	os.Setenv(k8sutil.NodeNameEnvVar, "node1")
+	defer os.Unsetenv(k8sutil.NodeNameEnvVar)
+
+	context := &clusterd.Context{
+		Clientset:     clientset,
+		RookClientset: rookclient.NewSimpleClientset(),
+	}
+
+	// the pod of the attachment still exists on node2
+	pod := v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "oldPod",
+			Namespace: "Default",
+		},
+		Status: v1.PodStatus{
+			Phase: "running",
+		},
+	}
+	clientset.CoreV1().Pods("Default").Create(&pod)
+
+	existingCRD := &rookalpha.Volume{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pvc-123",
+			Namespace: "rook-system",
+		},
+		Attachments: []rookalpha.Attachment{
+			{
+				Node:         "node2",
+				PodNamespace: "Default",
+				PodName:      "oldPod",
+				ClusterName:  "testCluster",
+				MountDir:     "/tmt/test",
+				ReadOnly:     false,
+			},
+		},
+	}
+	_, err := context.RookClientset.RookV1alpha2().Volumes("rook-system").Create(existingCRD)
+	assert.Nil(t, err)
+
+	opts := AttachOptions{
+		Image:            "image123",
+		BlockPool:        "testpool",
+		ClusterNamespace: "testCluster",
+		MountDir:         "/test/pods/pod123/volumes/rook.io~rook/pvc-123",
+		VolumeName:       "pvc-123",
+		Pod:              "newPod",
+		PodNamespace:     "Default",
+		RW:               "rw",
+	}
+
+	att, err := attachment.New(context)
+	assert.Nil(t, err)
+
+	fenceCalled := 0
+	unfenced := []string{}
+	var detachErr error
+	detached := 0
+	volumeManager := &manager.FakeVolumeManager{
+		FakeFence: func(image, pool, clusterName string, nodeIPs []string) ([]string, error) {
+			fenceCalled++
+			assert.Equal(t, "image123", image)
+			assert.Equal(t, "testpool", pool)
+			assert.Equal(t, "testCluster", clusterName)
+			assert.Equal(t, []string{"2.2.2.2"}, nodeIPs)
+			return []string{"2.2.2.2:0/1234"}, nil
+		},
+		FakeUnfence: func(clusterName string, clients []string) error {
+			assert.Equal(t, "testCluster", clusterName)
+			unfenced = append(unfenced, clients...)
+			return nil
+		},
+		FakeDetach: func(image, pool, clusterName string, force bool) error {
+			assert.Equal(t, "image123", image)
+			assert.Equal(t, "testpool", pool)
+			assert.True(t, force)
+			detached++
+			return detachErr
+		},
+	}
+	controller := &Controller{
+		context:          context,
+		volumeAttachment: att,
+		volumeManager:    volumeManager,
+		fencingTimeout:   5 * time.Minute,
+	}
+	devicePath := ""
+
+	// the node is ready, the volume is not attached
+	setNodeReady(t, context, "node2", v1.ConditionTrue, time.Now().Add(-time.Hour))
+	err = controller.Attach(opts, &devicePath)
+	assert.NotNil(t, err)
+	assert.Equal(t, 0, fenceCalled)
+
+	// the node is not ready for less than the timeout
+	setNodeReady(t, context, "node2", v1.ConditionUnknown, time.Now().Add(-time.Minute))
+	err = controller.Attach(opts, &devicePath)
+	assert.NotNil(t, err)
+	assert.Equal(t, 0, fenceCalled)
+
+	// the node is dead, it is fenced and the volume attached
+	setNodeReady(t, context, "node2", v1.ConditionUnknown, time.Now().Add(-10*time.Minute))
+	err = controller.Attach(opts, &devicePath)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, fenceCalled)
+
+	volAtt, err := context.RookClientset.RookV1alpha2().Volumes("rook-system").Get("pvc-123", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(volAtt.Attachments))
+	assert.Equal(t, "node1", volAtt.Attachments[0].Node)
+	assert.Equal(t, "newPod", volAtt.Attachments[0].PodName)
+
+	cm, err := clientset.CoreV1().ConfigMaps("rook-system").Get(FencingConfigMapName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, `{"testCluster":[{"pool":"testpool","image":"image123","clients":["2.2.2.2:0/1234"]}]}`, cm.Data["node2"])
+
+	// the clients are removed from the blacklist by the agent of node2 when it is back
+	os.Setenv(k8sutil.NodeNameEnvVar, "node2")
+	err = controller.UnfenceNode(nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, detached)
+	assert.Equal(t, 0, len(unfenced))
+
+	// the volume cannot be attached again on node2 while its stale mapping exists
+	setNodeReady(t, context, "node2", v1.ConditionTrue, time.Now())
+	opts.MountDir = "/test/pods/pod456/volumes/rook.io~rook/pvc-123"
+	opts.Pod = "otherPod"
+	err = controller.Attach(opts, &devicePath)
+	assert.NotNil(t, err)
+
+	// the clients stay blacklisted while the stale mapping cannot be removed
+	detachErr = errors.New("device busy")
+	err = controller.UnfenceNode(nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, detached)
+	assert.Equal(t, 0, len(unfenced))
+	cm, err = clientset.CoreV1().ConfigMaps("rook-system").Get(FencingConfigMapName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	_, ok := cm.Data["node2"]
+	assert.True(t, ok)
+
+	detachErr = nil
+	err = controller.UnfenceNode(nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, detached)
+	assert.Equal(t, []string{"2.2.2.2:0/1234"}, unfenced)
+	cm, err = clientset.CoreV1().ConfigMaps("rook-system").Get(FencingConfigMapName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	_, ok = cm.Data["node2"]
+	assert.False(t, ok)
+}
@@ -34,6 +34,7 @@ import (
 
 const (
 	findDevicePathMaxRetries = 10
+	adminID                  = "admin"
 	rbdKernelModuleName      = "rbd"
 	keyringTemplate          = `
 [client.%s]
@@ -204,6 +205,83 @@ func (vm *VolumeManager) Detach(image, pool, id, key, clusterNamespace string, f
 	return nil
 }
 
+// Fence blacklists the clients of the image running on a node which is not reachable anymore and breaks the
+// locks they hold on the image so that the image can be attached to another node. The blacklisted clients are
+// returned.
+func (vm *VolumeManager) Fence(image, pool, clusterNamespace string, nodeIPs []string) ([]string, error) {
+	return fence(vm.context, image, pool, clusterNamespace, nodeIPs)
+}
+
+// Unfence removes clients from the blacklist
+func (vm *VolumeManager) Unfence(clusterNamespace string, clients []string) error {
+	return unfence(vm.context, clusterNamespace, clients)
+}
+
+func fence(context *clusterd.Context, image, pool, clusterNamespace string, nodeIPs []string) ([]string, error) {
+	monitors, keyring, err := getClusterInfo(context, clusterNamespace)
+	defer os.Remove(keyring)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load cluster information from cluster %s: %+v", clusterNamespace, err)
+	}
+
+	watchers, err := cephclient.GetImageWatchers(context, image, pool, adminID, keyring, clusterNamespace, monitors)
+	if err != nil {
+		return nil, err
+	}
+	fenced := []string{}
+	for _, watcher := range watchers {
+		if !contains(nodeIPs, watcher.IP()) {
+			continue
+		}
+		logger.Warningf("blacklisting client %s of volume %s/%s", watcher.Address, pool, image)
+		if err := cephclient.BlacklistAdd(context, watcher.Address, adminID, keyring, clusterNamespace, monitors); err != nil {
+			return fenced, err
+		}
+		fenced = append(fenced, watcher.Address)
+	}
+
+	// break the exclusive lock of the blacklisted clients, the new client would otherwise wait for it
+	locks, err := cephclient.ListImageLocks(context, image, pool, adminID, keyring, clusterNamespace, monitors)
+	if err != nil {
+		return fenced, err
+	}
+	for _, lock := range locks {
+		if !contains(fenced, lock.Address) {
+			continue
+		}
+		logger.Infof("breaking lock %s of client %s on volume %s/%s", lock.ID, lock.Locker, pool, image)
+		if err := cephclient.RemoveImageLock(context, image, pool, lock.ID, lock.Locker, adminID, keyring, clusterNamespace, monitors); err != nil {
+			return fenced, err
+		}
+	}
+	return fenced, nil
+}
+
+func unfence(context *clusterd.Context, clusterNamespace string, clients []string) error {
+	monitors, keyring, err := getClusterInfo(context, clusterNamespace)
+	defer os.Remove(keyring)
+	if err != nil {
+		return fmt.Errorf("failed to load cluster information from cluster %s: %+v", clusterNamespace, err)
+	}
+
+	for _, client := range clients {
+		logger.Infof("removing client %s from the blacklist of cluster %s", client, clusterNamespace)
+		if err := cephclient.BlacklistRemove(context, client, adminID, keyring, clusterNamespace, monitors); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
 // Check if the volume is attached
 func (vm *VolumeManager) isAttached(image, pool, clusterNamespace string) (string, error) {
 	devicePath, err := vm.devicePathFinder.FindDevicePath(image, pool, clusterNamespace)
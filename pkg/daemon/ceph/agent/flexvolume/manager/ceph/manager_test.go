@@ -299,3 +299,65 @@ func TestAlreadyDetached(t *testing.T) {
 	err := vm.Detach("image1", "testpool", "admin", "", "testCluster", false)
 	assert.Nil(t, err)
 }
+
+func TestFence(t *testing.T) {
+	clientset := test.New(3)
+	clusterNamespace := "testCluster"
+	configDir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(configDir)
+	cm := &v1.ConfigMap{
+		Data: map[string]string{
+			"data": "rook-ceph-mon0=10.0.0.1:6789",
+		},
+	}
+	cm.Name = "rook-ceph-mon-endpoints"
+	clientset.CoreV1().ConfigMaps(clusterNamespace).Create(cm)
+
+	blacklisted := []string{}
+	removedLocks := []string{}
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName string, command string, args ...string) (string, error) {
+			if strings.Contains(command, "ceph-authtool") {
+				cephtest.CreateConfigDir(path.Join(configDir, clusterNamespace))
+			}
+			return "", nil
+		},
+		MockExecuteCommandWithTimeout: func(debug bool, timeout time.Duration, actionName string, command string, args ...string) (string, error) {
+			switch {
+			case command == "rbd" && args[0] == "status":
+				assert.Equal(t, "testpool/image1", args[1])
+				return `{"watchers":[{"address":"10.1.1.1:0/1234","client":4123,"cookie":1},{"address":"10.2.2.2:0/5678","client":4124,"cookie":2}]}`, nil
+			case command == "rbd" && args[0] == "lock" && args[1] == "ls":
+				return `[{"id":"auto 1","locker":"client.4123","address":"10.1.1.1:0/1234"}]`, nil
+			case command == "rbd" && args[0] == "lock" && args[1] == "rm":
+				assert.Equal(t, "testpool/image1", args[2])
+				assert.Equal(t, "client.4123", args[4])
+				removedLocks = append(removedLocks, args[3])
+				return "", nil
+			case command == "ceph" && args[0] == "osd" && args[1] == "blacklist":
+				blacklisted = append(blacklisted, args[2]+" "+args[3])
+				return "", nil
+			}
+			assert.Fail(t, fmt.Sprintf("unexpected command %s %v", command, args))
+			return "", nil
+		},
+	}
+
+	context := &clusterd.Context{
+		Clientset: clientset,
+		Executor:  executor,
+		ConfigDir: configDir,
+	}
+	vm := &VolumeManager{context: context}
+
+	// only the client of the dead node is blacklisted and its lock broken
+	fenced, err := vm.Fence("image1", "testpool", clusterNamespace, []string{"10.1.1.1"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"10.1.1.1:0/1234"}, fenced)
+	assert.Equal(t, []string{"add 10.1.1.1:0/1234"}, blacklisted)
+	assert.Equal(t, []string{"auto 1"}, removedLocks)
+
+	err = vm.Unfence(clusterNamespace, fenced)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"add 10.1.1.1:0/1234", "rm 10.1.1.1:0/1234"}, blacklisted)
+}
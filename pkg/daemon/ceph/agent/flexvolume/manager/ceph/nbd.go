@@ -0,0 +1,352 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ceph
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rook/rook/pkg/clusterd"
+	cephclient "github.com/rook/rook/pkg/daemon/ceph/client"
+	cephconfig "github.com/rook/rook/pkg/daemon/ceph/config"
+	"github.com/rook/rook/pkg/util/sys"
+)
+
+const (
+	nbdKernelModuleName  = "nbd"
+	nbdSuperviseInterval = 30 * time.Second
+	nbdClusterArg        = "--cluster="
+)
+
+// NbdVolumeManager attaches Ceph volumes with rbd-nbd, the userspace rbd client supporting all the image features.
+// The rbd-nbd processes run in the agent container and exit when the agent restarts, but the kernel keeps their nbd
+// devices and queues their I/O for a while. The mappings are saved in a state directory on the host, and the agent
+// attaches a new rbd-nbd process to the devices whose process exited when it starts again.
+type NbdVolumeManager struct {
+	context  *clusterd.Context
+	stateDir string
+	// procDir is where the command lines of the rbd-nbd processes are read to find their cluster
+	procDir string
+}
+
+// nbdMapping is the state of an image mapped with rbd-nbd, saved in the state directory. The credentials of the
+// mapping are not saved.
+type nbdMapping struct {
+	Image            string `json:"image"`
+	Pool             string `json:"pool"`
+	ClusterNamespace string `json:"clusterNamespace"`
+	Device           string `json:"device"`
+}
+
+// NewNbdVolumeManager creates an attacher for ceph volumes using rbd-nbd
+func NewNbdVolumeManager(context *clusterd.Context, stateDir string) (*NbdVolumeManager, error) {
+	vm := &NbdVolumeManager{
+		context:  context,
+		stateDir: stateDir,
+		procDir:  "/proc",
+	}
+	err := vm.Init()
+	return vm, err
+}
+
+// Init loads the nbd kernel module
+func (vm *NbdVolumeManager) Init() error {
+	if err := os.MkdirAll(vm.stateDir, 0700); err != nil {
+		return fmt.Errorf("failed to create rbd-nbd state dir %s. %+v", vm.stateDir, err)
+	}
+
+	in, err := sys.IsBuiltinKernelModule(nbdKernelModuleName, vm.context.Executor)
+	if err != nil {
+		return err
+	}
+	if in {
+		logger.Noticef("%s is a builtin kernel module, don't load it manually", nbdKernelModuleName)
+		return nil
+	}
+	return sys.LoadKernelModule(nbdKernelModuleName, nil, vm.context.Executor)
+}
+
+// Attach maps a ceph image with rbd-nbd
+func (vm *NbdVolumeManager) Attach(image, pool, id, key, clusterNamespace string) (string, error) {
+	// Check if the volume is already attached
+	devicePath, err := vm.findMappedDevice(image, pool, clusterNamespace)
+	if err != nil {
+		return "", fmt.Errorf("failed to check if volume %s/%s is already attached: %+v", pool, image, err)
+	}
+	if devicePath != "" {
+		logger.Infof("volume %s/%s is already attached with rbd-nbd. The device path is %s", pool, image, devicePath)
+		return devicePath, nil
+	}
+
+	// the device of a mapping whose process exited is still used by the filesystem mounted from it
+	mapping, err := vm.loadMapping(image, pool, clusterNamespace)
+	if err != nil {
+		return "", err
+	}
+	if mapping != nil {
+		logger.Infof("attaching volume %s/%s cluster %s again on %s", pool, image, clusterNamespace, mapping.Device)
+		if err := vm.reattach(*mapping); err != nil {
+			return "", fmt.Errorf("failed to attach volume %s/%s cluster %s again. %+v", pool, image, clusterNamespace, err)
+		}
+		return mapping.Device, nil
+	}
+
+	if id == "" && key == "" {
+		return "", fmt.Errorf("no id nor keyring given, can't mount without credentials")
+	}
+
+	logger.Infof("attaching volume %s/%s cluster %s with rbd-nbd", pool, image, clusterNamespace)
+	devicePath, err = vm.mapImage(image, pool, id, key, clusterNamespace)
+	if err != nil {
+		return "", err
+	}
+
+	mapping := nbdMapping{Image: image, Pool: pool, ClusterNamespace: clusterNamespace, Device: devicePath}
+	if err := vm.saveMapping(mapping); err != nil {
+		return "", err
+	}
+	return devicePath, nil
+}
+
+// Detach unmaps a ceph image mapped with rbd-nbd
+func (vm *NbdVolumeManager) Detach(image, pool, id, key, clusterNamespace string, force bool) error {
+	devicePath, err := vm.findMappedDevice(image, pool, clusterNamespace)
+	if err != nil {
+		return fmt.Errorf("failed to check if volume %s/%s is attached cluster %s", pool, image, clusterNamespace)
+	}
+	if devicePath == "" {
+		// the device of a mapping whose process exited is kept by the kernel until a process is attached again
+		mapping, err := vm.loadMapping(image, pool, clusterNamespace)
+		if err != nil {
+			return err
+		}
+		if mapping == nil {
+			logger.Infof("volume %s/%s is already detached cluster %s", pool, image, clusterNamespace)
+			return nil
+		}
+		if err := vm.reattach(*mapping); err != nil {
+			return fmt.Errorf("failed to detach volume %s/%s cluster %s. %+v", pool, image, clusterNamespace, err)
+		}
+		devicePath = mapping.Device
+	}
+
+	logger.Infof("detaching volume %s/%s cluster %s from %s", pool, image, clusterNamespace, devicePath)
+	if err := cephclient.NbdUnmapImage(vm.context, devicePath); err != nil {
+		return fmt.Errorf("failed to detach volume %s/%s cluster %s. %+v", pool, image, clusterNamespace, err)
+	}
+	logger.Infof("detached volume %s/%s", pool, image)
+	return vm.removeMapping(image, pool, clusterNamespace)
+}
+
+// Fence blacklists the clients of the image running on a node which is not reachable anymore
+func (vm *NbdVolumeManager) Fence(image, pool, clusterNamespace string, nodeIPs []string) ([]string, error) {
+	return fence(vm.context, image, pool, clusterNamespace, nodeIPs)
+}
+
+// Unfence removes clients from the blacklist
+func (vm *NbdVolumeManager) Unfence(clusterNamespace string, clients []string) error {
+	return unfence(vm.context, clusterNamespace, clients)
+}
+
+// ReattachMappings attaches a new rbd-nbd process to the saved mappings whose process is not running anymore, such as
+// after the agent restarted, until the stop channel is closed. A mapping which cannot be attached again is reported,
+// since the image cannot be mapped again under the filesystem mounted from the device: the volume is attached again
+// when the pod using it is restarted.
+func (vm *NbdVolumeManager) ReattachMappings(stopCh chan struct{}) {
+	for {
+		if err := vm.reattachExitedMappings(); err != nil {
+			logger.Warningf("failed to check the rbd-nbd mappings. %+v", err)
+		}
+		select {
+		case <-time.After(nbdSuperviseInterval):
+		case <-stopCh:
+			logger.Infof("stopping rbd-nbd supervision")
+			return
+		}
+	}
+}
+
+func (vm *NbdVolumeManager) reattachExitedMappings() error {
+	mappings, err := vm.loadMappings()
+	if err != nil {
+		return err
+	}
+	if len(mappings) == 0 {
+		return nil
+	}
+
+	mapped, err := cephclient.NbdListMappedImages(vm.context)
+	if err != nil {
+		return err
+	}
+	for _, mapping := range mappings {
+		if vm.isNbdMapped(mapped, mapping.Image, mapping.Pool, mapping.ClusterNamespace) != "" {
+			continue
+		}
+		logger.Warningf("rbd-nbd process of volume %s/%s on %s is not running, attaching a new process", mapping.Pool, mapping.Image, mapping.Device)
+		if err := vm.reattach(mapping); err != nil {
+			logger.Errorf("failed to attach volume %s/%s on %s again. the pods using the volume must be restarted to attach it again. %+v",
+				mapping.Pool, mapping.Image, mapping.Device, err)
+			// the volume is only reported once
+			if err := vm.removeMapping(mapping.Image, mapping.Pool, mapping.ClusterNamespace); err != nil {
+				logger.Warningf("%+v", err)
+			}
+			continue
+		}
+		logger.Infof("attached volume %s/%s on %s again", mapping.Pool, mapping.Image, mapping.Device)
+	}
+	return nil
+}
+
+// reattach starts a new rbd-nbd process for the device of a mapping. The credentials of the mapping are not saved, the
+// admin keyring of the cluster is used instead.
+func (vm *NbdVolumeManager) reattach(mapping nbdMapping) error {
+	monitors, keyring, err := getClusterInfo(vm.context, mapping.ClusterNamespace)
+	defer os.Remove(keyring)
+	if err != nil {
+		return fmt.Errorf("failed to load cluster information from cluster %s: %+v", mapping.ClusterNamespace, err)
+	}
+	return cephclient.NbdAttachImage(vm.context, mapping.Image, mapping.Pool, mapping.Device, adminID, keyring, mapping.ClusterNamespace, monitors)
+}
+
+func (vm *NbdVolumeManager) mapImage(image, pool, id, key, clusterNamespace string) (string, error) {
+	monitors, keyring, err := getClusterInfo(vm.context, clusterNamespace)
+	defer os.Remove(keyring)
+	if err != nil {
+		return "", fmt.Errorf("failed to load cluster information from cluster %s: %+v", clusterNamespace, err)
+	}
+
+	// Write the user given key to the keyring file
+	if key != "" {
+		keyringEval := func(key string) string {
+			return fmt.Sprintf(keyringTemplate, id, key)
+		}
+		if err = cephconfig.WriteKeyring(keyring, key, keyringEval); err != nil {
+			return "", fmt.Errorf("failed writing custom keyring for id %s. %+v", id, err)
+		}
+	}
+
+	devicePath, err := cephclient.NbdMapImage(vm.context, image, pool, id, keyring, clusterNamespace, monitors)
+	if err != nil {
+		return "", fmt.Errorf("failed to map image %s/%s cluster %s. %+v", pool, image, clusterNamespace, err)
+	}
+	return devicePath, nil
+}
+
+func (vm *NbdVolumeManager) findMappedDevice(image, pool, clusterNamespace string) (string, error) {
+	mapped, err := cephclient.NbdListMappedImages(vm.context)
+	if err != nil {
+		return "", err
+	}
+	return vm.isNbdMapped(mapped, image, pool, clusterNamespace), nil
+}
+
+// isNbdMapped returns the device of the image of the cluster if it is mapped. The images of different clusters can
+// have the same pool and name.
+func (vm *NbdVolumeManager) isNbdMapped(mapped []cephclient.NbdMappedImage, image, pool, clusterNamespace string) string {
+	for _, m := range mapped {
+		if m.Image == image && m.Pool == pool && vm.processCluster(m.Pid) == clusterNamespace {
+			return m.Device
+		}
+	}
+	return ""
+}
+
+// processCluster returns the cluster of an rbd-nbd process from its command line, which rbd-nbd does not list
+func (vm *NbdVolumeManager) processCluster(pid int) string {
+	cmdline, err := ioutil.ReadFile(path.Join(vm.procDir, strconv.Itoa(pid), "cmdline"))
+	if err != nil {
+		return ""
+	}
+	for _, arg := range strings.Split(string(cmdline), "\x00") {
+		if strings.HasPrefix(arg, nbdClusterArg) {
+			return strings.TrimPrefix(arg, nbdClusterArg)
+		}
+	}
+	return ""
+}
+
+func (vm *NbdVolumeManager) mappingPath(image, pool, clusterNamespace string) string {
+	return path.Join(vm.stateDir, fmt.Sprintf("%s_%s_%s.json", clusterNamespace, pool, image))
+}
+
+func (vm *NbdVolumeManager) saveMapping(mapping nbdMapping) error {
+	data, err := json.Marshal(mapping)
+	if err != nil {
+		return err
+	}
+	filePath := vm.mappingPath(mapping.Image, mapping.Pool, mapping.ClusterNamespace)
+	if err := ioutil.WriteFile(filePath, data, 0600); err != nil {
+		return fmt.Errorf("failed to save rbd-nbd mapping %s. %+v", filePath, err)
+	}
+	return nil
+}
+
+// loadMapping returns the saved mapping of an image, or nil if the image is not mapped
+func (vm *NbdVolumeManager) loadMapping(image, pool, clusterNamespace string) (*nbdMapping, error) {
+	filePath := vm.mappingPath(image, pool, clusterNamespace)
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read rbd-nbd mapping %s. %+v", filePath, err)
+	}
+	var mapping nbdMapping
+	if err := json.Unmarshal(data, &mapping); err != nil {
+		return nil, fmt.Errorf("failed to parse rbd-nbd mapping %s. %+v", filePath, err)
+	}
+	return &mapping, nil
+}
+
+func (vm *NbdVolumeManager) removeMapping(image, pool, clusterNamespace string) error {
+	filePath := vm.mappingPath(image, pool, clusterNamespace)
+	if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove rbd-nbd mapping %s. %+v", filePath, err)
+	}
+	return nil
+}
+
+func (vm *NbdVolumeManager) loadMappings() ([]nbdMapping, error) {
+	files, err := ioutil.ReadDir(vm.stateDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rbd-nbd state dir %s. %+v", vm.stateDir, err)
+	}
+	mappings := []nbdMapping{}
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+		data, err := ioutil.ReadFile(path.Join(vm.stateDir, file.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read rbd-nbd mapping %s. %+v", file.Name(), err)
+		}
+		var mapping nbdMapping
+		if err := json.Unmarshal(data, &mapping); err != nil {
+			logger.Warningf("ignoring invalid rbd-nbd mapping %s. %+v", file.Name(), err)
+			continue
+		}
+		mappings = append(mappings, mapping)
+	}
+	return mappings, nil
+}
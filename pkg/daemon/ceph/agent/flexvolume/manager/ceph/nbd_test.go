@@ -0,0 +1,179 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package ceph
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rook/rook/pkg/clusterd"
+	cephtest "github.com/rook/rook/pkg/daemon/ceph/test"
+	"github.com/rook/rook/pkg/operator/ceph/cluster/mon"
+	"github.com/rook/rook/pkg/operator/test"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNbdAttachDetach(t *testing.T) {
+	clientset := test.New(3)
+	clusterNamespace := "testCluster"
+	configDir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(configDir)
+	stateDir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(stateDir)
+	procDir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(procDir)
+	cm := &v1.ConfigMap{
+		Data: map[string]string{
+			"data": "rook-ceph-mon0=10.0.0.1:6789",
+		},
+	}
+	cm.Name = "rook-ceph-mon-endpoints"
+	clientset.CoreV1().ConfigMaps(clusterNamespace).Create(cm)
+
+	// the rbd-nbd processes of the image in this cluster and in another cluster
+	for pid, cluster := range map[string]string{"1234": clusterNamespace, "5678": "otherCluster"} {
+		os.MkdirAll(path.Join(procDir, pid), 0755)
+		cmdline := strings.Join([]string{"rbd-nbd", "map", "testpool/image1", "--id=user1", "--cluster=" + cluster}, "\x00")
+		ioutil.WriteFile(path.Join(procDir, pid, "cmdline"), []byte(cmdline), 0644)
+	}
+
+	mapped := "[]"
+	mapCalls := [][]string{}
+	attachCalls := [][]string{}
+	var attachErr error
+	unmapped := []string{}
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName string, command string, args ...string) (string, error) {
+			if strings.Contains(command, "ceph-authtool") {
+				cephtest.CreateConfigDir(path.Join(configDir, clusterNamespace))
+			}
+			return "", nil
+		},
+		MockExecuteCommandWithTimeout: func(debug bool, timeout time.Duration, actionName string, command string, args ...string) (string, error) {
+			assert.Equal(t, "rbd-nbd", command)
+			switch args[0] {
+			case "list-mapped":
+				return mapped, nil
+			case "map":
+				assert.Equal(t, "testpool/image1", args[1])
+				assert.Equal(t, "--id=user1", args[2])
+				assert.Equal(t, "--cluster=testCluster", args[3])
+				assert.True(t, strings.HasPrefix(args[4], "--keyring="))
+				assert.Equal(t, "10.0.0.1:6789", args[6])
+				assert.Equal(t, []string{"--try-netlink", "--reattach-timeout", "300"}, args[8:])
+				mapCalls = append(mapCalls, args)
+				return "2019-01-01 00:00:00.000 7f0 -1 some log\n/dev/nbd0\n", nil
+			case "attach":
+				assert.Equal(t, []string{"--device", "/dev/nbd0", "testpool/image1", "--id=admin", "--cluster=testCluster"}, args[1:6])
+				attachCalls = append(attachCalls, args)
+				if attachErr == nil {
+					mapped = `[{"pid":1234,"pool":"testpool","image":"image1","snap":"-","device":"/dev/nbd0"}]`
+				}
+				return "", attachErr
+			case "unmap":
+				unmapped = append(unmapped, args[1])
+				mapped = "[]"
+				return "", nil
+			}
+			assert.Fail(t, fmt.Sprintf("unexpected command %s %v", command, args))
+			return "", nil
+		},
+	}
+
+	context := &clusterd.Context{
+		Clientset: clientset,
+		Executor:  executor,
+		ConfigDir: configDir,
+	}
+	mon.CreateOrLoadClusterInfo(context, clusterNamespace, &metav1.OwnerReference{})
+	vm := &NbdVolumeManager{context: context, stateDir: stateDir, procDir: procDir}
+
+	// the image of another cluster with the same pool and name is not this image
+	mapped = `[{"pid":5678,"pool":"testpool","image":"image1","snap":"-","device":"/dev/nbd1"}]`
+
+	// the image is mapped and the mapping saved without the credentials
+	devicePath, err := vm.Attach("image1", "testpool", "user1", "never-gonna-give-you-up", clusterNamespace)
+	assert.Nil(t, err)
+	assert.Equal(t, "/dev/nbd0", devicePath)
+	assert.Equal(t, 1, len(mapCalls))
+	assert.Equal(t, 11, len(mapCalls[0]))
+	mappings, err := vm.loadMappings()
+	assert.Nil(t, err)
+	assert.Equal(t, []nbdMapping{{Image: "image1", Pool: "testpool", ClusterNamespace: clusterNamespace, Device: "/dev/nbd0"}}, mappings)
+	state, err := ioutil.ReadFile(vm.mappingPath("image1", "testpool", clusterNamespace))
+	assert.Nil(t, err)
+	assert.NotContains(t, string(state), "never-gonna-give-you-up")
+
+	// nothing to attach again while the process is running
+	mapped = `[{"pid":1234,"pool":"testpool","image":"image1","snap":"-","device":"/dev/nbd0"}]`
+	err = vm.reattachExitedMappings()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(attachCalls))
+
+	// already attached
+	devicePath, err = vm.Attach("image1", "testpool", "user1", "never-gonna-give-you-up", clusterNamespace)
+	assert.Nil(t, err)
+	assert.Equal(t, "/dev/nbd0", devicePath)
+	assert.Equal(t, 1, len(mapCalls))
+
+	// the rbd-nbd process exited with the agent, a new process is attached to the device
+	mapped = "[]"
+	err = vm.reattachExitedMappings()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(attachCalls))
+	assert.Equal(t, 1, len(mapCalls))
+	mappings, err = vm.loadMappings()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(mappings))
+
+	// the volume is attached again on the same device rather than mapped again
+	mapped = "[]"
+	devicePath, err = vm.Attach("image1", "testpool", "user1", "never-gonna-give-you-up", clusterNamespace)
+	assert.Nil(t, err)
+	assert.Equal(t, "/dev/nbd0", devicePath)
+	assert.Equal(t, 2, len(attachCalls))
+	assert.Equal(t, 1, len(mapCalls))
+
+	// the image is unmapped and the mapping removed
+	err = vm.Detach("image1", "testpool", "user1", "never-gonna-give-you-up", clusterNamespace, false)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"/dev/nbd0"}, unmapped)
+	mappings, err = vm.loadMappings()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(mappings))
+
+	// the device is gone, the mapping is reported once and not attached again
+	_, err = vm.Attach("image1", "testpool", "user1", "never-gonna-give-you-up", clusterNamespace)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(mapCalls))
+	mapped = "[]"
+	attachErr = errors.New("no such device")
+	err = vm.reattachExitedMappings()
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(attachCalls))
+	mappings, err = vm.loadMappings()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(mappings))
+}
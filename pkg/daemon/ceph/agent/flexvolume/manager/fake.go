@@ -20,9 +20,11 @@ import "fmt"
 
 // FakeVolumeManager represents a fake (mocked) implementation of the VolumeManager interface for testing.
 type FakeVolumeManager struct {
-	FakeInit   func() error
-	FakeAttach func(image, pool, id, key, clusterName string) (string, error)
-	FakeDetach func(image, pool, clusterName string, force bool) error
+	FakeInit    func() error
+	FakeAttach  func(image, pool, id, key, clusterName string) (string, error)
+	FakeDetach  func(image, pool, clusterName string, force bool) error
+	FakeFence   func(image, pool, clusterName string, nodeIPs []string) ([]string, error)
+	FakeUnfence func(clusterName string, clients []string) error
 }
 
 // Init initializes the FakeVolumeManager
@@ -48,3 +50,19 @@ func (f *FakeVolumeManager) Detach(image, pool, id, key, clusterName string, for
 	}
 	return nil
 }
+
+// Fence blacklists the clients of a volume image on a dead node
+func (f *FakeVolumeManager) Fence(image, pool, clusterName string, nodeIPs []string) ([]string, error) {
+	if f.FakeFence != nil {
+		return f.FakeFence(image, pool, clusterName, nodeIPs)
+	}
+	return nil, nil
+}
+
+// Unfence removes clients from the blacklist
+func (f *FakeVolumeManager) Unfence(clusterName string, clients []string) error {
+	if f.FakeUnfence != nil {
+		return f.FakeUnfence(clusterName, clients)
+	}
+	return nil
+}
@@ -28,6 +28,8 @@ type VolumeManager interface {
 	Init() error
 	Attach(image, pool, id, key, clusterName string) (string, error)
 	Detach(image, pool, id, key, clusterName string, force bool) error
+	Fence(image, pool, clusterName string, nodeIPs []string) ([]string, error)
+	Unfence(clusterName string, clients []string) error
 }
 
 type VolumeController interface {
@@ -51,6 +53,7 @@ type AttachOptions struct {
 	Path             string `json:"path"` // Path within the CephFS to mount
 	MountUser        string `json:"mountUser"`
 	MountSecret      string `json:"mountSecret"`
+	Mounter          string `json:"mounter"`
 	RW               string `json:"kubernetes.io/readwrite"`
 	FsType           string `json:"kubernetes.io/fsType"`
 	VolumeName       string `json:"kubernetes.io/pvOrVolumeName"` // only available on 1.7
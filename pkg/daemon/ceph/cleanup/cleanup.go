@@ -0,0 +1,323 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cleanup wipes the data of a deleted cluster on a host.
+package cleanup
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/coreos/pkg/capnslog"
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/operator/ceph/agent"
+	"github.com/rook/rook/pkg/util/sys"
+)
+
+var logger = capnslog.NewPackageLogger("github.com/rook/rook", "cleanup")
+
+const (
+	cephVolumeCmd    = "ceph-volume"
+	dmsetupCmd       = "dmsetup"
+	bluestoreToolCmd = "ceph-bluestore-tool"
+	clusterFSIDLVTag = "ceph.cluster_fsid"
+	encryptedLVTag   = "ceph.encrypted"
+	// the size wiped at the beginning of a device to remove the bluestore label
+	wipeSizeMB = 100
+)
+
+// the dirs of the data dir that don't belong to the cluster
+var preservedDataDirs = []string{filepath.Base(agent.RBDNbdStateDirPath)}
+
+type cephVolumeLV struct {
+	Devices []string          `json:"devices"`
+	LVName  string            `json:"lv_name"`
+	LVUUID  string            `json:"lv_uuid"`
+	VGName  string            `json:"vg_name"`
+	Type    string            `json:"type"`
+	Tags    map[string]string `json:"tags"`
+}
+
+type bluestoreLabel struct {
+	OSDUUID  string `json:"osd_uuid"`
+	CephFSID string `json:"ceph_fsid"`
+}
+
+// CleanHost wipes the host after the cluster with the given fsid is deleted: the devices of its OSDs are zapped,
+// their device mapper mappings are removed and the data dir is emptied. The devices of other clusters on the host
+// are not touched. Returns the zapped devices. The cleanup continues after a failure and all the failures are returned.
+func CleanHost(context *clusterd.Context, dataDir, fsid string) ([]string, error) {
+	var errs []string
+	zapped := []string{}
+
+	devices, mappings, err := zapCephVolumeDevices(context, fsid)
+	zapped = append(zapped, devices...)
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	if err := removeCephMappings(context, mappings); err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	devices, err = zapRookPartitions(context, fsid)
+	zapped = append(zapped, devices...)
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	if err := removeDataDir(dataDir); err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	if len(errs) > 0 {
+		return zapped, fmt.Errorf("%s", strings.Join(errs, ". "))
+	}
+	return zapped, nil
+}
+
+// zapCephVolumeDevices destroys the logical volumes, volume groups and dmcrypt mappings of the OSDs of the cluster
+// created by ceph-volume. The devices with a logical volume of another cluster are skipped. Returns the zapped devices
+// and the device mapper names of the volumes of the cluster, the dmcrypt mappings before the logical volumes under them.
+func zapCephVolumeDevices(context *clusterd.Context, fsid string) ([]string, []string, error) {
+	result, err := context.Executor.ExecuteCommandWithOutput(false, "", cephVolumeCmd, "lvm", "list", "--format", "json")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list the ceph-volume osds. %+v", err)
+	}
+
+	var osds map[string][]cephVolumeLV
+	if err := json.Unmarshal([]byte(result), &osds); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse the ceph-volume osds. %+v", err)
+	}
+
+	// a device is only zapped if all its logical volumes belong to the cluster
+	owned := map[string]bool{}
+	cryptMappings := []string{}
+	lvMappings := []string{}
+	for _, lvs := range osds {
+		for _, lv := range lvs {
+			belongs := lv.Tags[clusterFSIDLVTag] == fsid
+			if belongs {
+				if lv.Tags[encryptedLVTag] == "1" {
+					if name := dmcryptMapperName(lv); name != "" {
+						cryptMappings = append(cryptMappings, name)
+					}
+				}
+				if lv.LVName != "" {
+					lvMappings = append(lvMappings, deviceMapperName(lv.VGName, lv.LVName))
+				}
+			}
+			for _, device := range lv.Devices {
+				if v, ok := owned[device]; !ok || v {
+					owned[device] = belongs
+				}
+			}
+		}
+	}
+	devices := []string{}
+	for device, belongs := range owned {
+		if !belongs {
+			logger.Infof("skipping ceph-volume device %s that has volumes of another cluster", device)
+			continue
+		}
+		devices = append(devices, device)
+	}
+	sort.Strings(devices)
+	sort.Strings(cryptMappings)
+	sort.Strings(lvMappings)
+	mappings := append(cryptMappings, lvMappings...)
+
+	zapped := []string{}
+	for _, device := range devices {
+		logger.Infof("zapping ceph-volume device %s", device)
+		cmd := fmt.Sprintf("zap %s", device)
+		if err := context.Executor.ExecuteCommand(false, cmd, cephVolumeCmd, "lvm", "zap", "--destroy", device); err != nil {
+			return zapped, mappings, fmt.Errorf("failed to zap device %s. %+v", device, err)
+		}
+		zapped = append(zapped, device)
+	}
+	return zapped, mappings, nil
+}
+
+// deviceMapperName returns the device mapper name of a logical volume, where the dashes of the names are doubled
+func deviceMapperName(vg, lv string) string {
+	return fmt.Sprintf("%s-%s", strings.Replace(vg, "-", "--", -1), strings.Replace(lv, "-", "--", -1))
+}
+
+// dmcryptMapperName returns the device mapper name of the dmcrypt mapping of an encrypted volume, which ceph-volume
+// names after the uuid of the logical volume, or after the partition uuid of a db or wal partition
+func dmcryptMapperName(lv cephVolumeLV) string {
+	if lv.LVUUID != "" {
+		return lv.LVUUID
+	}
+	return lv.Tags[fmt.Sprintf("ceph.%s_uuid", lv.Type)]
+}
+
+// removeCephMappings removes the given device mapper mappings of the ceph-volume volumes left behind, in the given order
+func removeCephMappings(context *clusterd.Context, mappings []string) error {
+	if len(mappings) == 0 {
+		return nil
+	}
+	output, err := context.Executor.ExecuteCommandWithOutput(false, "list device mapper mappings", dmsetupCmd, "ls")
+	if err != nil {
+		return fmt.Errorf("failed to list the device mapper mappings. %+v", err)
+	}
+	existing := map[string]bool{}
+	for _, line := range strings.Split(output, "\n") {
+		if fields := strings.Fields(line); len(fields) > 0 {
+			existing[fields[0]] = true
+		}
+	}
+
+	for _, name := range mappings {
+		if !existing[name] {
+			continue
+		}
+		logger.Infof("removing device mapper mapping %s", name)
+		cmd := fmt.Sprintf("remove mapping %s", name)
+		if err := context.Executor.ExecuteCommand(false, cmd, dmsetupCmd, "remove", "--force", name); err != nil {
+			return fmt.Errorf("failed to remove device mapper mapping %s. %+v", name, err)
+		}
+	}
+	return nil
+}
+
+// zapRookPartitions removes the partitions of the OSDs of the cluster created by rook before ceph-volume. The cluster
+// of the partitions is found from their bluestore labels: the main device has the fsid of the cluster and the wal and
+// db devices have the uuid of their OSD. The devices with a partition of another cluster or without a bluestore label,
+// such as the filestore partitions, are skipped.
+func zapRookPartitions(context *clusterd.Context, fsid string) ([]string, error) {
+	disks, err := clusterd.DiscoverDevices(context.Executor)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover the devices. %+v", err)
+	}
+
+	rookDisks := map[string][]sys.Partition{}
+	labels := map[string]*bluestoreLabel{}
+	osds := map[string]bool{}
+	for _, disk := range disks {
+		if disk.Type != sys.DiskType && disk.Type != sys.SSDType {
+			continue
+		}
+		partitions, _, err := sys.GetDevicePartitions(disk.Name, context.Executor)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the partitions of device %s. %+v", disk.Name, err)
+		}
+		if len(partitions) == 0 || !sys.RookOwnsPartitions(partitions) {
+			continue
+		}
+		rookDisks[disk.Name] = partitions
+		for _, p := range partitions {
+			label, err := readBluestoreLabel(context, "/dev/"+p.Name)
+			if err != nil {
+				logger.Infof("no bluestore label on partition %s. %+v", p.Name, err)
+				continue
+			}
+			labels[p.Name] = label
+			if label.CephFSID == fsid {
+				osds[label.OSDUUID] = true
+			}
+		}
+	}
+
+	names := []string{}
+	for name := range rookDisks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	zapped := []string{}
+	for _, name := range names {
+		belongs := true
+		for _, p := range rookDisks[name] {
+			if label, ok := labels[p.Name]; !ok || !osds[label.OSDUUID] {
+				belongs = false
+			}
+		}
+		if !belongs {
+			logger.Infof("skipping device %s that has partitions not belonging to the cluster", name)
+			continue
+		}
+
+		logger.Infof("zapping the rook partitions of device %s", name)
+		if err := sys.RemovePartitions(name, context.Executor); err != nil {
+			return zapped, err
+		}
+		devicePath := "/dev/" + name
+		cmd := fmt.Sprintf("wipe %s", devicePath)
+		err = context.Executor.ExecuteCommand(false, cmd, "dd", "if=/dev/zero", "of="+devicePath, "bs=1M", fmt.Sprintf("count=%d", wipeSizeMB), "oflag=direct")
+		if err != nil {
+			return zapped, fmt.Errorf("failed to wipe device %s. %+v", devicePath, err)
+		}
+		zapped = append(zapped, devicePath)
+	}
+	return zapped, nil
+}
+
+// readBluestoreLabel reads the bluestore label at the beginning of a device
+func readBluestoreLabel(context *clusterd.Context, device string) (*bluestoreLabel, error) {
+	output, err := context.Executor.ExecuteCommandWithOutput(false, "", bluestoreToolCmd, "show-label", "--dev", device)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the bluestore label of %s. %+v", device, err)
+	}
+	var labels map[string]bluestoreLabel
+	if err := json.Unmarshal([]byte(output), &labels); err != nil {
+		return nil, fmt.Errorf("failed to parse the bluestore label of %s. %+v", device, err)
+	}
+	label, ok := labels[device]
+	if !ok {
+		return nil, fmt.Errorf("no bluestore label for %s", device)
+	}
+	return &label, nil
+}
+
+// removeDataDir removes the content of the data dir: the mon stores, the directory osds, the configs and the logs
+func removeDataDir(dataDir string) error {
+	files, err := ioutil.ReadDir(dataDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read data dir %s. %+v", dataDir, err)
+	}
+
+	for _, file := range files {
+		if contains(preservedDataDirs, file.Name()) {
+			continue
+		}
+		filePath := path.Join(dataDir, file.Name())
+		logger.Infof("removing %s", filePath)
+		if err := os.RemoveAll(filePath); err != nil {
+			return fmt.Errorf("failed to remove %s. %+v", filePath, err)
+		}
+	}
+	return nil
+}
+
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,157 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cleanup
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/rook/rook/pkg/clusterd"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+)
+
+const cephVolumeList = `{
+    "0": [
+        {
+            "devices": ["/dev/sdb"],
+            "lv_name": "osd-block-1",
+            "vg_name": "ceph-1234",
+            "tags": {"ceph.cluster_fsid": "fsid1"},
+            "type": "block"
+        }
+    ],
+    "1": [
+        {
+            "devices": ["/dev/sdc"],
+            "lv_name": "osd-block-2",
+            "lv_uuid": "Ab12Cd-block-2",
+            "vg_name": "ceph-5678",
+            "tags": {"ceph.cluster_fsid": "fsid1", "ceph.encrypted": "1"},
+            "type": "block"
+        },
+        {
+            "devices": ["/dev/sdd"],
+            "lv_name": "osd-db-2",
+            "lv_uuid": "Ab12Cd-db-2",
+            "vg_name": "ceph-9abc",
+            "tags": {"ceph.cluster_fsid": "fsid1", "ceph.encrypted": "1"},
+            "type": "db"
+        }
+    ],
+    "2": [
+        {
+            "devices": ["/dev/sde"],
+            "lv_name": "osd-block-3",
+            "vg_name": "ceph-def0",
+            "tags": {"ceph.cluster_fsid": "fsid2"},
+            "type": "block"
+        },
+        {
+            "devices": ["/dev/sdd"],
+            "lv_name": "osd-db-3",
+            "vg_name": "ceph-9abc",
+            "tags": {"ceph.cluster_fsid": "fsid2"},
+            "type": "db"
+        }
+    ]
+}`
+
+func TestZapCephVolumeDevices(t *testing.T) {
+	zapped := []string{}
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName string, command string, args ...string) (string, error) {
+			assert.Equal(t, "ceph-volume", command)
+			assert.Equal(t, []string{"lvm", "list", "--format", "json"}, args)
+			return cephVolumeList, nil
+		},
+		MockExecuteCommand: func(debug bool, actionName string, command string, args ...string) error {
+			assert.Equal(t, "ceph-volume", command)
+			assert.Equal(t, []string{"lvm", "zap", "--destroy"}, args[:3])
+			zapped = append(zapped, args[3])
+			return nil
+		},
+	}
+	context := &clusterd.Context{Executor: executor}
+
+	// the device shared with the other cluster and the devices of the other cluster are not zapped
+	devices, mappings, err := zapCephVolumeDevices(context, "fsid1")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"/dev/sdb", "/dev/sdc"}, devices)
+	assert.Equal(t, []string{"/dev/sdb", "/dev/sdc"}, zapped)
+	// the dmcrypt mappings named after the uuids of the encrypted volumes come before the volumes under them
+	assert.Equal(t, []string{"Ab12Cd-block-2", "Ab12Cd-db-2", "ceph--1234-osd--block--1", "ceph--5678-osd--block--2", "ceph--9abc-osd--db--2"}, mappings)
+}
+
+func TestRemoveCephMappings(t *testing.T) {
+	removed := []string{}
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName string, command string, args ...string) (string, error) {
+			return "ceph--1234-osd--block--5678\t(253:0)\nceph--9abc-osd--block--def0\t(253:1)\nvg0-root\t(253:2)\nAb12Cd-block-5678\t(253:3)\n", nil
+		},
+		MockExecuteCommand: func(debug bool, actionName string, command string, args ...string) error {
+			assert.Equal(t, "dmsetup", command)
+			removed = append(removed, args[len(args)-1])
+			return nil
+		},
+	}
+	context := &clusterd.Context{Executor: executor}
+
+	err := removeCephMappings(context, []string{"Ab12Cd-block-5678", "Ab12Cd-db-5678", "ceph--1234-osd--block--5678"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"Ab12Cd-block-5678", "ceph--1234-osd--block--5678"}, removed)
+}
+
+func TestReadBluestoreLabel(t *testing.T) {
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName string, command string, args ...string) (string, error) {
+			assert.Equal(t, "ceph-bluestore-tool", command)
+			assert.Equal(t, []string{"show-label", "--dev", "/dev/sdb2"}, args)
+			return `{"/dev/sdb2": {"osd_uuid": "uuid1", "description": "main", "ceph_fsid": "fsid1", "whoami": "0"}}`, nil
+		},
+	}
+	context := &clusterd.Context{Executor: executor}
+
+	label, err := readBluestoreLabel(context, "/dev/sdb2")
+	assert.Nil(t, err)
+	assert.Equal(t, "uuid1", label.OSDUUID)
+	assert.Equal(t, "fsid1", label.CephFSID)
+
+	_, err = readBluestoreLabel(context, "/dev/sdb3")
+	assert.NotNil(t, err)
+}
+
+func TestRemoveDataDir(t *testing.T) {
+	dataDir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(dataDir)
+	for _, dir := range []string{"mon-a", "osd0", "rook-ceph", "rbd-nbd"} {
+		os.MkdirAll(path.Join(dataDir, dir), 0755)
+	}
+	ioutil.WriteFile(path.Join(dataDir, "rook-ceph", "client.admin.keyring"), []byte("key"), 0600)
+
+	err := removeDataDir(dataDir)
+	assert.Nil(t, err)
+	files, _ := ioutil.ReadDir(dataDir)
+	assert.Equal(t, 1, len(files))
+	assert.Equal(t, "rbd-nbd", files[0].Name())
+
+	// a missing data dir is already clean
+	err = removeDataDir(path.Join(dataDir, "missing"))
+	assert.Nil(t, err)
+}
@@ -18,10 +18,27 @@ package client
 import (
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/rook/rook/pkg/clusterd"
 )
 
+// userGenerationRegex matches the generation suffix of the users created by a key rotation
+var userGenerationRegex = regexp.MustCompile(`^(.+)-([0-9]+)$`)
+
+// authEntity is a user listed by "ceph auth get"
+type authEntity struct {
+	Entity string            `json:"entity"`
+	Key    string            `json:"key"`
+	Caps   map[string]string `json:"caps"`
+}
+
 // AuthAdd will create a new user with the given capabilities and using the already generated keyring
 // found at the given keyring path.  This should not be used when the user may already exist.
 func AuthAdd(context *clusterd.Context, clusterName, name, keyringPath string, caps []string) error {
@@ -79,6 +96,100 @@ func AuthDelete(context *clusterd.Context, clusterName, name string) error {
 	return nil
 }
 
+// AuthGetCaps gets the capabilities of the given user, keyed by the daemon type they apply to.
+func AuthGetCaps(context *clusterd.Context, clusterName, name string) (map[string]string, error) {
+	args := []string{"auth", "get", name}
+	buf, err := ExecuteCephCommand(context, clusterName, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get auth for %s: %+v", name, err)
+	}
+
+	var entities []authEntity
+	if err := json.Unmarshal(buf, &entities); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal auth get response: %+v", err)
+	}
+	if len(entities) == 0 {
+		return nil, fmt.Errorf("auth for %s not found", name)
+	}
+	return entities[0].Caps, nil
+}
+
+// AuthImportKey sets the key and capabilities of the given user, which is created if it does not exist. Ceph holds
+// a single key for each user, the previous key of the user cannot authenticate once the new key is imported.
+func AuthImportKey(context *clusterd.Context, clusterName, name, key string, caps map[string]string) error {
+	keyring := fmt.Sprintf("[%s]\n\tkey = %s\n", name, key)
+	daemons := make([]string, 0, len(caps))
+	for daemon := range caps {
+		daemons = append(daemons, daemon)
+	}
+	sort.Strings(daemons)
+	for _, daemon := range daemons {
+		keyring += fmt.Sprintf("\tcaps %s = \"%s\"\n", daemon, caps[daemon])
+	}
+
+	// the keyring only lives for the duration of the import
+	keyringPath := path.Join(context.ConfigDir, clusterName, fmt.Sprintf("%s.import.keyring", name))
+	if err := ioutil.WriteFile(keyringPath, []byte(keyring), 0600); err != nil {
+		return fmt.Errorf("failed to write the keyring of %s to import. %+v", name, err)
+	}
+	defer os.Remove(keyringPath)
+
+	args := []string{"auth", "import", "-i", keyringPath}
+	if _, err := ExecuteCephCommand(context, clusterName, args); err != nil {
+		return fmt.Errorf("failed to auth import for %s: %+v", name, err)
+	}
+	return nil
+}
+
+// AuthRotateKey replaces the key of the given user with a new key, keeping the capabilities of the user. Returns the
+// new key.
+func AuthRotateKey(context *clusterd.Context, clusterName, name string) (string, error) {
+	caps, err := AuthGetCaps(context, clusterName, name)
+	if err != nil {
+		return "", err
+	}
+	key, err := GenerateAuthKey(context)
+	if err != nil {
+		return "", err
+	}
+	if err := AuthImportKey(context, clusterName, name, key, caps); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// GenerationUser returns the name of the given generation of a user. The first generation is the user itself, and the
+// next generations are the users created by the key rotations, such as client.crash-2.
+func GenerationUser(user string, generation int) string {
+	if generation <= 1 {
+		return user
+	}
+	return fmt.Sprintf("%s-%d", user, generation)
+}
+
+// UserGeneration returns the user and the generation of the given generation user. It is the reverse of
+// GenerationUser.
+func UserGeneration(name string) (string, int) {
+	match := userGenerationRegex.FindStringSubmatch(name)
+	if match == nil {
+		return name, 1
+	}
+	generation, err := strconv.Atoi(match[2])
+	if err != nil || generation <= 1 {
+		return name, 1
+	}
+	return match[1], generation
+}
+
+// GenerateAuthKey generates a new cephx key.
+func GenerateAuthKey(context *clusterd.Context) (string, error) {
+	key, err := context.Executor.ExecuteCommandWithOutput(false, "", "ceph-authtool", "--gen-print-key")
+	if err != nil {
+		return "", fmt.Errorf("failed to generate a key. %+v", err)
+	}
+	return strings.TrimSpace(key), nil
+}
+
 func parseAuthKey(buf []byte) (string, error) {
 	var resp map[string]interface{}
 	if err := json.Unmarshal(buf, &resp); err != nil {
@@ -0,0 +1,102 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package client
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/rook/rook/pkg/clusterd"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthRotateKey(t *testing.T) {
+	configDir, err := ioutil.TempDir("", "")
+	require.Nil(t, err)
+	defer os.RemoveAll(configDir)
+	require.Nil(t, os.MkdirAll(path.Join(configDir, "ns"), 0744))
+
+	imported := ""
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName string, command string, args ...string) (string, error) {
+			if command == "ceph-authtool" && args[0] == "--gen-print-key" {
+				return "newkey==\n", nil
+			}
+			return "", fmt.Errorf("unexpected command %s %v", command, args)
+		},
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName string, command, outfileArg string, args ...string) (string, error) {
+			if args[0] == "auth" && args[1] == "get" {
+				assert.Equal(t, "mgr.a", args[2])
+				return `[{"entity":"mgr.a","key":"oldkey==","caps":{"osd":"allow *","mon":"allow *"}}]`, nil
+			}
+			if args[0] == "auth" && args[1] == "import" {
+				keyring, err := ioutil.ReadFile(args[3])
+				assert.Nil(t, err)
+				imported = string(keyring)
+				return "", nil
+			}
+			return "", fmt.Errorf("unexpected ceph command %v", args)
+		},
+	}
+	context := &clusterd.Context{Executor: executor, ConfigDir: configDir}
+
+	key, err := AuthRotateKey(context, "ns", "mgr.a")
+	assert.Nil(t, err)
+	assert.Equal(t, "newkey==", key)
+	assert.Equal(t, "[mgr.a]\n\tkey = newkey==\n\tcaps mon = \"allow *\"\n\tcaps osd = \"allow *\"\n", imported)
+
+	// the imported keyring is removed
+	files, err := ioutil.ReadDir(path.Join(configDir, "ns"))
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(files))
+
+	// the key is not rotated if the caps of the user cannot be read
+	executor.MockExecuteCommandWithOutputFile = func(debug bool, actionName string, command, outfileArg string, args ...string) (string, error) {
+		if args[0] == "auth" && args[1] == "get" {
+			return "[]", nil
+		}
+		assert.Fail(t, "unexpected ceph command %v", args)
+		return "", nil
+	}
+	_, err = AuthRotateKey(context, "ns", "mgr.a")
+	assert.NotNil(t, err)
+}
+
+func TestUserGeneration(t *testing.T) {
+	assert.Equal(t, "client.crash", GenerationUser("client.crash", 1))
+	assert.Equal(t, "client.crash-2", GenerationUser("client.crash", 2))
+
+	for _, test := range []struct {
+		name       string
+		user       string
+		generation int
+	}{
+		{"client.crash", "client.crash", 1},
+		{"client.crash-2", "client.crash", 2},
+		{"client.rbd-mirror.a-12", "client.rbd-mirror.a", 12},
+		{"client.rbd-mirror.a", "client.rbd-mirror.a", 1},
+		{"client.crash-1", "client.crash-1", 1},
+	} {
+		user, generation := UserGeneration(test.name)
+		assert.Equal(t, test.user, user, test.name)
+		assert.Equal(t, test.generation, generation, test.name)
+	}
+}
@@ -43,6 +43,16 @@ const (
 	cephConnectionTimeout = "15" // in seconds
 )
 
+// CommandObserver is notified of the duration and the result of the ceph tools run by this package, such as for the
+// operator to record them in its metrics. Nothing is observed if it is nil.
+var CommandObserver func(tool string, args []string, start time.Time, err error)
+
+func observeCommand(tool string, args []string, start time.Time, err error) {
+	if CommandObserver != nil {
+		CommandObserver(tool, args, start, err)
+	}
+}
+
 // FinalizeCephCommandArgs builds the command line to be called
 func FinalizeCephCommandArgs(command string, args []string, configDir, clusterName string) (string, []string) {
 	// the rbd client tool does not support the '--connect-timeout' option
@@ -68,16 +78,25 @@ func FinalizeCephCommandArgs(command string, args []string, configDir, clusterNa
 	}
 
 	// Append the args to find the config and keyring
-	confFile := fmt.Sprintf("%s.config", clusterName)
-	keyringFile := fmt.Sprintf("%s.keyring", AdminUsername)
+	confFile, keyringFile := configFilePaths(configDir, clusterName)
 	configArgs := []string{
 		fmt.Sprintf("--cluster=%s", clusterName),
-		fmt.Sprintf("--conf=%s", path.Join(configDir, clusterName, confFile)),
-		fmt.Sprintf("--keyring=%s", path.Join(configDir, clusterName, keyringFile)),
+		fmt.Sprintf("--conf=%s", confFile),
+		fmt.Sprintf("--keyring=%s", keyringFile),
 	}
 	return command, append(args, configArgs...)
 }
 
+// configFilePaths returns the paths of the config file and of the admin keyring of the cluster
+func configFilePaths(configDir, clusterName string) (string, string) {
+	if clusterName == "ceph" && configDir == "/etc" {
+		return "/etc/ceph/ceph.conf", "/etc/ceph/ceph.client.admin.keyring"
+	}
+	confFile := fmt.Sprintf("%s.config", clusterName)
+	keyringFile := fmt.Sprintf("%s.keyring", AdminUsername)
+	return path.Join(configDir, clusterName, confFile), path.Join(configDir, clusterName, keyringFile)
+}
+
 // ExecuteCephCommandDebugLog executes the 'ceph' command with 'debug' logs instead of 'info' logs
 func ExecuteCephCommandDebugLog(context *clusterd.Context, clusterName string, args []string) ([]byte, error) {
 	return executeCephCommandWithOutputFile(context, clusterName, true, args)
@@ -124,12 +143,24 @@ func ExecuteRBDCommandNoFormat(context *clusterd.Context, clusterName string, ar
 
 // ExecuteRBDCommandWithTimeout executes the 'rbd' command with a timeout of 1 minute
 func ExecuteRBDCommandWithTimeout(context *clusterd.Context, clusterName string, args []string) (string, error) {
+	start := time.Now()
 	output, err := context.Executor.ExecuteCommandWithTimeout(false, cmdExecuteTimeout, "", RBDTool, args...)
+	observeCommand(RBDTool, args, start, err)
+	return output, err
+}
+
+// ExecuteCephCommandWithTimeout executes the 'ceph' command with a timeout of 1 minute
+func ExecuteCephCommandWithTimeout(context *clusterd.Context, clusterName string, args []string) (string, error) {
+	start := time.Now()
+	output, err := context.Executor.ExecuteCommandWithTimeout(false, cmdExecuteTimeout, "", CephTool, args...)
+	observeCommand(CephTool, args, start, err)
 	return output, err
 }
 
 func executeCommand(context *clusterd.Context, command string, args []string) ([]byte, error) {
+	start := time.Now()
 	output, err := context.Executor.ExecuteCommandWithOutput(false, "", command, args...)
+	observeCommand(command, args, start, err)
 	return []byte(output), err
 }
 
@@ -138,7 +169,9 @@ func executeCommandWithOutputFile(context *clusterd.Context, debug bool, command
 		// Kubectl commands targeting the toolbox container generate a temp file in the wrong place, so we will instead capture the output from stdout for the tests
 		return executeCommand(context, command, args)
 	}
+	start := time.Now()
 	output, err := context.Executor.ExecuteCommandWithOutputFile(debug, "", command, "--out-file", args...)
+	observeCommand(command, args, start, err)
 	return []byte(output), err
 }
 
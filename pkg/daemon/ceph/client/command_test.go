@@ -17,8 +17,12 @@ limitations under the License.
 package client
 
 import (
+	"errors"
 	"testing"
+	"time"
 
+	"github.com/rook/rook/pkg/clusterd"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -108,3 +112,28 @@ func TestFinalizeCephCommandArgsClusterDefaultName(t *testing.T) {
 	assert.Exactly(t, expectedCommand, cmd)
 	assert.Exactly(t, expectedArgs, args)
 }
+
+func TestCommandObserver(t *testing.T) {
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName string, command, outfileArg string, args ...string) (string, error) {
+			return "", errors.New("failed")
+		},
+	}
+	context := &clusterd.Context{Executor: executor}
+
+	// nothing is observed by default
+	_, err := ExecuteCephCommand(context, "ns", []string{"status"})
+	assert.NotNil(t, err)
+
+	var observed []string
+	var observedErr error
+	CommandObserver = func(tool string, args []string, start time.Time, err error) {
+		observed = append(observed, tool, args[0])
+		observedErr = err
+	}
+	defer func() { CommandObserver = nil }()
+	_, err = ExecuteCephCommand(context, "ns", []string{"status"})
+	assert.NotNil(t, err)
+	assert.Equal(t, []string{"ceph", "status"}, observed)
+	assert.Equal(t, err, observedErr)
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/rook/rook/pkg/clusterd"
+)
+
+// CrashInfo is a crash report posted to the crash module of the mgr
+type CrashInfo struct {
+	ID          string `json:"crash_id"`
+	Entity      string `json:"entity_name"`
+	Timestamp   string `json:"timestamp"`
+	StackSig    string `json:"stack_sig"`
+	ProcessName string `json:"process_name"`
+}
+
+// GetCrashList returns the crash reports posted to the crash module of the mgr
+func GetCrashList(context *clusterd.Context, clusterName string) ([]CrashInfo, error) {
+	args := []string{"crash", "ls"}
+	buf, err := ExecuteCephCommand(context, clusterName, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list crashes: %+v", err)
+	}
+
+	var crashes []CrashInfo
+	if err := json.Unmarshal(buf, &crashes); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal crash ls response: %+v", err)
+	}
+	return crashes, nil
+}
+
+// PruneCrashes removes the crash reports older than the given number of days
+func PruneCrashes(context *clusterd.Context, clusterName string, keepDays int) error {
+	args := []string{"crash", "prune", strconv.Itoa(keepDays)}
+	if _, err := ExecuteCephCommand(context, clusterName, args); err != nil {
+		return fmt.Errorf("failed to prune the crashes older than %d days: %+v", keepDays, err)
+	}
+	return nil
+}
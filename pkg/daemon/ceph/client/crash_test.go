@@ -0,0 +1,57 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package client
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/rook/rook/pkg/clusterd"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testCrashList = `[{"crash_id":"2019-08-01_10:00:00.123456Z_f3a1","timestamp":"2019-08-01 10:00:00.123456Z",
+"process_name":"ceph-osd","entity_name":"osd.1","stack_sig":"5f5e4a"}]`
+
+func TestCrashes(t *testing.T) {
+	var pruned []string
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName string, command, outfileArg string, args ...string) (string, error) {
+			if args[0] == "crash" && args[1] == "ls" {
+				return testCrashList, nil
+			}
+			if args[0] == "crash" && args[1] == "prune" {
+				pruned = append(pruned, args[2])
+				return "", nil
+			}
+			return "", fmt.Errorf("unexpected ceph command %v", args)
+		},
+	}
+	context := &clusterd.Context{Executor: executor}
+
+	crashes, err := GetCrashList(context, "ns")
+	assert.Nil(t, err)
+	require.Equal(t, 1, len(crashes))
+	assert.Equal(t, "2019-08-01_10:00:00.123456Z_f3a1", crashes[0].ID)
+	assert.Equal(t, "osd.1", crashes[0].Entity)
+	assert.Equal(t, "2019-08-01 10:00:00.123456Z", crashes[0].Timestamp)
+	assert.Equal(t, "5f5e4a", crashes[0].StackSig)
+
+	assert.Nil(t, PruneCrashes(context, "ns", 30))
+	assert.Equal(t, []string{"30"}, pruned)
+}
@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path"
 	"regexp"
 	"strconv"
 	"strings"
@@ -47,8 +48,9 @@ type 5 pdu
 type 6 pod
 type 7 room
 type 8 datacenter
-type 9 region
-type 10 root
+type 9 zone
+type 10 region
+type 11 root
 
 # default bucket
 root default {
@@ -178,6 +180,18 @@ func CrushRemove(context *clusterd.Context, clusterName, name string) (string, e
 	return string(buf), nil
 }
 
+// CrushMoveBucket moves a bucket of the crush map, such as a host, to the given location. The missing buckets of the
+// location are created.
+func CrushMoveBucket(context *clusterd.Context, clusterName, name string, location []string) (string, error) {
+	args := append([]string{"osd", "crush", "move", name}, location...)
+	buf, err := ExecuteCephCommand(context, clusterName, args)
+	if err != nil {
+		return "", fmt.Errorf("failed to crush move: %+v, %s", err, string(buf))
+	}
+
+	return string(buf), nil
+}
+
 func FindOSDInCrushMap(context *clusterd.Context, clusterName string, osdID int) (*CrushFindResult, error) {
 	args := []string{"osd", "find", strconv.Itoa(osdID)}
 	buf, err := ExecuteCephCommand(context, clusterName, args)
@@ -252,6 +266,101 @@ func CreateDefaultCrushMap(context *clusterd.Context, clusterName string) (strin
 	return "", nil
 }
 
+// AddCrushType adds a bucket type to the crush map of the cluster, right above the type named below. The ids of the
+// larger types are shifted since the buckets of a location are nested in the order of the ids of their types. The
+// crush maps created before the type existed, such as the zone type missing in the maps of older Rook versions,
+// can't place the buckets of the type otherwise.
+func AddCrushType(context *clusterd.Context, clusterName, name, below string) error {
+	tmpDir, err := ioutil.TempDir("", "crush")
+	if err != nil {
+		return fmt.Errorf("failed to create crush map temp dir. %+v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	compiledMap := path.Join(tmpDir, "crushmap")
+	decompiledMap := path.Join(tmpDir, "crushmap.txt")
+
+	args := []string{"osd", "getcrushmap", "-o", compiledMap}
+	if output, err := ExecuteCephCommandPlainNoOutputFile(context, clusterName, args); err != nil {
+		return fmt.Errorf("failed to get crush map. %+v, %s", err, string(output))
+	}
+	args = []string{"-d", compiledMap, "-o", decompiledMap}
+	if output, err := context.Executor.ExecuteCommandWithOutput(false, "", CrushTool, args...); err != nil {
+		return fmt.Errorf("failed to decompile crush map. %+v, %s", err, output)
+	}
+	buf, err := ioutil.ReadFile(decompiledMap)
+	if err != nil {
+		return fmt.Errorf("failed to read decompiled crush map. %+v", err)
+	}
+
+	crushMap, err := addCrushTypeToMap(string(buf), name, below)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(decompiledMap, []byte(crushMap), 0644); err != nil {
+		return fmt.Errorf("failed to write decompiled crush map. %+v", err)
+	}
+	args = []string{"-c", decompiledMap, "-o", compiledMap}
+	if output, err := context.Executor.ExecuteCommandWithOutput(false, "", CrushTool, args...); err != nil {
+		return fmt.Errorf("failed to compile crush map. %+v, %s", err, output)
+	}
+	if output, err := SetCrushMap(context, clusterName, compiledMap); err != nil {
+		return fmt.Errorf("failed to set crush map. %+v, %s", err, output)
+	}
+	return nil
+}
+
+// addCrushTypeToMap adds a bucket type to the types of a decompiled crush map, right above the type named below. The
+// buckets and rules refer to the types by name and are not changed.
+func addCrushTypeToMap(crushMap, name, below string) (string, error) {
+	lines := strings.Split(crushMap, "\n")
+	belowID := -1
+	for _, line := range lines {
+		id, typeName, ok := parseCrushTypeLine(line)
+		if !ok {
+			continue
+		}
+		if typeName == name {
+			return crushMap, nil
+		}
+		if typeName == below {
+			belowID = id
+		}
+	}
+	if belowID < 0 {
+		return "", fmt.Errorf("crush type %s not found to add type %s", below, name)
+	}
+
+	result := []string{}
+	for _, line := range lines {
+		id, typeName, ok := parseCrushTypeLine(line)
+		if !ok {
+			result = append(result, line)
+			continue
+		}
+		if id > belowID {
+			id++
+		}
+		result = append(result, fmt.Sprintf("type %d %s", id, typeName))
+		if typeName == below {
+			result = append(result, fmt.Sprintf("type %d %s", belowID+1, name))
+		}
+	}
+	return strings.Join(result, "\n"), nil
+}
+
+// parseCrushTypeLine parses a "type <id> <name>" line of a decompiled crush map
+func parseCrushTypeLine(line string) (int, string, bool) {
+	fields := strings.Fields(line)
+	if len(fields) != 3 || fields[0] != "type" {
+		return 0, "", false
+	}
+	id, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return 0, "", false
+	}
+	return id, fields[2], true
+}
+
 func FormatLocation(location, hostName string) ([]string, error) {
 	var pairs []string
 	if location == "" {
@@ -273,3 +273,43 @@ func TestCrushLocation(t *testing.T) {
 	assert.NotNil(t, err)
 	assert.Contains(t, err.Error(), "is not in a valid format")
 }
+
+func TestAddCrushTypeToMap(t *testing.T) {
+	crushMap := `# types
+type 0 osd
+type 1 host
+type 8 datacenter
+type 9 region
+type 10 root
+
+# buckets
+region us {
+	id -2
+	alg straw2
+	item dc1 weight 1.000
+}
+
+# rules
+rule replicated_rule {
+	id 0
+	type replicated
+	step chooseleaf firstn 0 type host
+	step emit
+}`
+
+	// the larger types are shifted
+	result, err := addCrushTypeToMap(crushMap, "zone", "datacenter")
+	assert.Nil(t, err)
+	assert.Contains(t, result, "type 8 datacenter\ntype 9 zone\ntype 10 region\ntype 11 root\n")
+	assert.Contains(t, result, "region us {")
+	assert.Contains(t, result, "\ttype replicated\n")
+
+	// the type is already in the map
+	result2, err := addCrushTypeToMap(result, "zone", "datacenter")
+	assert.Nil(t, err)
+	assert.Equal(t, result, result2)
+
+	// unknown type
+	_, err = addCrushTypeToMap(crushMap, "zone", "room")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"fmt"
+	"syscall"
+
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/util/exec"
+)
+
+// DashboardCreateUser creates a dashboard user with the given password. If the user already exists, its
+// password is reset instead.
+func DashboardCreateUser(context *clusterd.Context, clusterName, username, password string) error {
+	// the password is only written to the debug log
+	logger.Infof("creating dashboard user %s", username)
+	args := []string{"dashboard", "ac-user-create", username, password}
+	_, err := ExecuteCephCommandDebugLog(context, clusterName, args)
+	if err == nil {
+		return nil
+	}
+	cmdErr, ok := err.(*exec.CommandError)
+	if !ok || cmdErr.ExitStatus() != int(syscall.EEXIST) {
+		return fmt.Errorf("failed to create dashboard user %s. %+v", username, err)
+	}
+
+	logger.Infof("dashboard user %s already exists, setting its password", username)
+	args = []string{"dashboard", "ac-user-set-password", username, password}
+	if _, err := ExecuteCephCommandDebugLog(context, clusterName, args); err != nil {
+		return fmt.Errorf("failed to set password of dashboard user %s. %+v", username, err)
+	}
+	return nil
+}
+
+// DashboardSetUserRoles replaces the roles of a dashboard user
+func DashboardSetUserRoles(context *clusterd.Context, clusterName, username string, roles []string) error {
+	args := append([]string{"dashboard", "ac-user-set-roles", username}, roles...)
+	if _, err := ExecuteCephCommand(context, clusterName, args); err != nil {
+		return fmt.Errorf("failed to set roles %v on dashboard user %s. %+v", roles, username, err)
+	}
+	return nil
+}
+
+// DashboardDeleteUser deletes a dashboard user. It is not an error if the user does not exist.
+func DashboardDeleteUser(context *clusterd.Context, clusterName, username string) error {
+	args := []string{"dashboard", "ac-user-delete", username}
+	_, err := ExecuteCephCommand(context, clusterName, args)
+	if err != nil {
+		cmdErr, ok := err.(*exec.CommandError)
+		if ok && cmdErr.ExitStatus() == int(syscall.ENOENT) {
+			logger.Infof("dashboard user %s does not exist", username)
+			return nil
+		}
+		return fmt.Errorf("failed to delete dashboard user %s. %+v", username, err)
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+
+	"github.com/rook/rook/pkg/clusterd"
+)
+
+// ImageWatcher is a client watching an rbd image, usually the client which mapped the image
+type ImageWatcher struct {
+	// Address is the address of the client such as 10.0.0.5:0/3012034728
+	Address string `json:"address"`
+}
+
+// ImageLock is a lock held on an rbd image, such as the exclusive lock of the client writing to the image
+type ImageLock struct {
+	ID      string `json:"id"`
+	Locker  string `json:"locker"`
+	Address string `json:"address"`
+}
+
+// IP returns the IP of a ceph client address
+func (w ImageWatcher) IP() string {
+	return clientAddressIP(w.Address)
+}
+
+// clientAddressIP returns the IP part of a ceph client address such as 10.0.0.5:0/3012034728
+func clientAddressIP(address string) string {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return ""
+	}
+	return host
+}
+
+// credentialArgs are the args to connect to the cluster without a config file, as done by the agent
+func credentialArgs(id, keyring, clusterName, monitors string) []string {
+	return []string{
+		fmt.Sprintf("--id=%s", id),
+		fmt.Sprintf("--cluster=%s", clusterName),
+		fmt.Sprintf("--keyring=%s", keyring),
+		"-m", monitors,
+		"--conf=/dev/null", // no config file needed because we are passing all required config as arguments
+	}
+}
+
+// GetImageWatchers returns the clients watching an rbd image
+func GetImageWatchers(context *clusterd.Context, imageName, poolName, id, keyring, clusterName, monitors string) ([]ImageWatcher, error) {
+	imageSpec := getImageSpec(imageName, poolName)
+	args := append([]string{"status", imageSpec}, credentialArgs(id, keyring, clusterName, monitors)...)
+	args = append(args, "--format", "json")
+	output, err := ExecuteRBDCommandWithTimeout(context, clusterName, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get status of image %s: %+v. output: %s", imageSpec, err, output)
+	}
+
+	var status struct {
+		Watchers []ImageWatcher `json:"watchers"`
+	}
+	if err := json.Unmarshal([]byte(output), &status); err != nil {
+		return nil, fmt.Errorf("unmarshal failed: %+v. raw buffer response: %s", err, output)
+	}
+	return status.Watchers, nil
+}
+
+// ListImageLocks returns the locks held on an rbd image
+func ListImageLocks(context *clusterd.Context, imageName, poolName, id, keyring, clusterName, monitors string) ([]ImageLock, error) {
+	imageSpec := getImageSpec(imageName, poolName)
+	args := append([]string{"lock", "ls", imageSpec}, credentialArgs(id, keyring, clusterName, monitors)...)
+	args = append(args, "--format", "json")
+	output, err := ExecuteRBDCommandWithTimeout(context, clusterName, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list locks of image %s: %+v. output: %s", imageSpec, err, output)
+	}
+	return parseImageLocks(output)
+}
+
+func parseImageLocks(output string) ([]ImageLock, error) {
+	// nautilus returns a list of locks
+	var locks []ImageLock
+	if err := json.Unmarshal([]byte(output), &locks); err == nil {
+		return locks, nil
+	}
+
+	// older versions return the locks by id
+	var lockMap map[string]ImageLock
+	if err := json.Unmarshal([]byte(output), &lockMap); err != nil {
+		return nil, fmt.Errorf("unmarshal failed: %+v. raw buffer response: %s", err, output)
+	}
+	for lockID, lock := range lockMap {
+		lock.ID = lockID
+		locks = append(locks, lock)
+	}
+	return locks, nil
+}
+
+// RemoveImageLock breaks a lock held on an rbd image
+func RemoveImageLock(context *clusterd.Context, imageName, poolName, lockID, locker, id, keyring, clusterName, monitors string) error {
+	imageSpec := getImageSpec(imageName, poolName)
+	args := append([]string{"lock", "rm", imageSpec, lockID, locker}, credentialArgs(id, keyring, clusterName, monitors)...)
+	output, err := ExecuteRBDCommandWithTimeout(context, clusterName, args)
+	if err != nil {
+		return fmt.Errorf("failed to remove lock %s of image %s: %+v. output: %s", lockID, imageSpec, err, output)
+	}
+	return nil
+}
+
+// BlacklistAdd prevents a client from accessing the cluster
+func BlacklistAdd(context *clusterd.Context, address, id, keyring, clusterName, monitors string) error {
+	args := append([]string{"osd", "blacklist", "add", address}, credentialArgs(id, keyring, clusterName, monitors)...)
+	output, err := ExecuteCephCommandWithTimeout(context, clusterName, args)
+	if err != nil {
+		return fmt.Errorf("failed to blacklist client %s: %+v. output: %s", address, err, output)
+	}
+	return nil
+}
+
+// BlacklistRemove allows a blacklisted client to access the cluster again
+func BlacklistRemove(context *clusterd.Context, address, id, keyring, clusterName, monitors string) error {
+	args := append([]string{"osd", "blacklist", "rm", address}, credentialArgs(id, keyring, clusterName, monitors)...)
+	output, err := ExecuteCephCommandWithTimeout(context, clusterName, args)
+	if err != nil {
+		return fmt.Errorf("failed to remove client %s from the blacklist: %+v. output: %s", address, err, output)
+	}
+	return nil
+}
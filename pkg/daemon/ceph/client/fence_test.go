@@ -0,0 +1,82 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rook/rook/pkg/clusterd"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetImageWatchers(t *testing.T) {
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithTimeout: func(debug bool, timeout time.Duration, actionName string, command string, args ...string) (string, error) {
+			assert.Equal(t, "rbd", command)
+			assert.Equal(t, []string{"status", "pool1/image1"}, args[:2])
+			return `{"watchers":[{"address":"10.0.0.5:0/3012034728","client":4123,"cookie":18446462598732840961}]}`, nil
+		},
+	}
+	context := &clusterd.Context{Executor: executor}
+
+	watchers, err := GetImageWatchers(context, "image1", "pool1", "admin", "/tmp/keyring", "rook-ceph", "10.0.0.1:6789")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(watchers))
+	assert.Equal(t, "10.0.0.5:0/3012034728", watchers[0].Address)
+	assert.Equal(t, "10.0.0.5", watchers[0].IP())
+}
+
+func TestParseImageLocks(t *testing.T) {
+	// nautilus
+	locks, err := parseImageLocks(`[{"id":"auto 18446462598732840961","locker":"client.4123","address":"10.0.0.5:0/3012034728"}]`)
+	assert.Nil(t, err)
+	assert.Equal(t, []ImageLock{{ID: "auto 18446462598732840961", Locker: "client.4123", Address: "10.0.0.5:0/3012034728"}}, locks)
+
+	// mimic
+	locks, err = parseImageLocks(`{"auto 18446462598732840961":{"locker":"client.4123","address":"10.0.0.5:0/3012034728"}}`)
+	assert.Nil(t, err)
+	assert.Equal(t, []ImageLock{{ID: "auto 18446462598732840961", Locker: "client.4123", Address: "10.0.0.5:0/3012034728"}}, locks)
+
+	// no locks
+	locks, err = parseImageLocks(`[]`)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(locks))
+
+	_, err = parseImageLocks(`invalid`)
+	assert.NotNil(t, err)
+}
+
+func TestBlacklist(t *testing.T) {
+	var cmdArgs []string
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithTimeout: func(debug bool, timeout time.Duration, actionName string, command string, args ...string) (string, error) {
+			assert.Equal(t, "ceph", command)
+			cmdArgs = args
+			return "", nil
+		},
+	}
+	context := &clusterd.Context{Executor: executor}
+
+	err := BlacklistAdd(context, "10.0.0.5:0/3012034728", "admin", "/tmp/keyring", "rook-ceph", "10.0.0.1:6789")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"osd", "blacklist", "add", "10.0.0.5:0/3012034728"}, cmdArgs[:4])
+
+	err = BlacklistRemove(context, "10.0.0.5:0/3012034728", "admin", "/tmp/keyring", "rook-ceph", "10.0.0.1:6789")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"osd", "blacklist", "rm", "10.0.0.5:0/3012034728"}, cmdArgs[:4])
+}
@@ -25,6 +25,7 @@ import (
 
 	"github.com/rook/rook/pkg/clusterd"
 	cephver "github.com/rook/rook/pkg/operator/ceph/version"
+	"github.com/rook/rook/pkg/util/exec"
 	"k8s.io/apimachinery/pkg/util/wait"
 )
 
@@ -120,6 +121,64 @@ func AllowStandbyReplay(context *clusterd.Context, clusterName string, fsName st
 	return nil
 }
 
+// SetStandbyCountWanted sets the number of standby mds daemons below which Ceph raises a health warning.
+func SetStandbyCountWanted(context *clusterd.Context, clusterName string, fsName string, count int32) error {
+	args := []string{"fs", "set", fsName, "standby_count_wanted", strconv.Itoa(int(count))}
+	_, err := ExecuteCephCommand(context, clusterName, args)
+	if err != nil {
+		return fmt.Errorf("failed to set standby_count_wanted to filesystem %s: %+v", fsName, err)
+	}
+
+	return nil
+}
+
+// SetMaxFileSize sets the maximum size in bytes of a file in a Ceph filesystem.
+func SetMaxFileSize(context *clusterd.Context, clusterName string, fsName string, size int64) error {
+	args := []string{"fs", "set", fsName, "max_file_size", strconv.FormatInt(size, 10)}
+	_, err := ExecuteCephCommand(context, clusterName, args)
+	if err != nil {
+		return fmt.Errorf("failed to set max_file_size to filesystem %s: %+v", fsName, err)
+	}
+
+	return nil
+}
+
+// pinSubtreeScript sets the ceph.dir.pin attribute on a directory with libcephfs, which does not need the privileges
+// of a kernel or fuse mount of the filesystem
+const pinSubtreeScript = `
+import sys
+import cephfs
+conffile, keyring, fs_name, path, rank = sys.argv[1:6]
+fs = cephfs.LibCephFS(conffile=conffile, conf={'keyring': keyring, 'client_mds_namespace': fs_name})
+fs.mount()
+try:
+    fs.setxattr(path, 'ceph.dir.pin', rank.encode(), 0)
+finally:
+    fs.shutdown()
+`
+
+// pythonInterpreters are the interpreters that may run the cephfs bindings, depending on the base of the ceph image.
+// The next interpreter is only tried when the previous one is not installed.
+var pythonInterpreters = []string{"python3", "python"}
+
+// PinSubtree pins the directory subtree at the path to the given mds rank by setting the ceph.dir.pin attribute
+// on the directory. The pin is stored with the directory, so the balancer does not migrate the subtree away from
+// the rank and the pin is kept when the mds restart.
+func PinSubtree(context *clusterd.Context, clusterName string, fsName string, path string, rank int32) error {
+	confFile, keyringFile := configFilePaths(context.ConfigDir, clusterName)
+	args := []string{"-c", pinSubtreeScript, confFile, keyringFile, fsName, path, strconv.Itoa(int(rank))}
+	for _, python := range pythonInterpreters {
+		output, err := context.Executor.ExecuteCommandWithCombinedOutput(false, "", python, args...)
+		if err == nil {
+			return nil
+		}
+		if !exec.IsNotFound(err) {
+			return fmt.Errorf("failed to pin subtree %s of filesystem %s to rank %d: %+v. %s", path, fsName, rank, err, output)
+		}
+	}
+	return fmt.Errorf("failed to pin subtree %s of filesystem %s to rank %d: none of the interpreters %v is installed", path, fsName, rank, pythonInterpreters)
+}
+
 // CreateFilesystem performs software configuration steps for Ceph to provide a new filesystem.
 func CreateFilesystem(context *clusterd.Context, clusterName, name, metadataPool string, dataPools []string) error {
 	if len(dataPools) == 0 {
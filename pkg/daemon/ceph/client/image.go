@@ -23,6 +23,7 @@ import (
 	"strconv"
 
 	"regexp"
+	"strings"
 
 	"github.com/rook/rook/pkg/clusterd"
 	"github.com/rook/rook/pkg/util/exec"
@@ -30,8 +31,22 @@ import (
 
 const (
 	ImageMinSize = uint64(1048576) // 1 MB
+
+	imageMinObjectSize = uint64(4096)     // 4 KB
+	imageMaxObjectSize = uint64(33554432) // 32 MB
 )
 
+// ImageFeatures are the features that can be enabled when creating an image. The data-pool feature is enabled
+// by setting the data pool.
+var ImageFeatures = []string{"layering", "striping", "exclusive-lock", "object-map", "fast-diff", "deep-flatten", "journaling"}
+
+// imageFeatureDependencies are the features that must be enabled along with a feature
+var imageFeatureDependencies = map[string]string{
+	"object-map": "exclusive-lock",
+	"fast-diff":  "object-map",
+	"journaling": "exclusive-lock",
+}
+
 type CephBlockImage struct {
 	Name     string `json:"image"`
 	Size     uint64 `json:"size"`
@@ -39,6 +54,84 @@ type CephBlockImage struct {
 	InfoName string `json:"name"`
 }
 
+// CephTrashImage is an image moved to the trash of a pool
+type CephTrashImage struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// ImageOptions are the optional settings of an image. The zero values keep the defaults of the cluster.
+type ImageOptions struct {
+	// Features enabled on the image instead of the default features
+	Features []string
+	// ObjectSize in bytes, a power of two between 4 KB and 32 MB
+	ObjectSize uint64
+	// StripeUnit in bytes, must be set along with the StripeCount
+	StripeUnit uint64
+	// StripeCount is the number of objects to stripe over before looping
+	StripeCount uint64
+	// QosIopsLimit is the maximum IO operations per second of the image (Nautilus and newer)
+	QosIopsLimit uint64
+	// QosBpsLimit is the maximum bytes per second of the image (Nautilus and newer)
+	QosBpsLimit uint64
+}
+
+// Validate checks the image options before creating an image
+func (o *ImageOptions) Validate() error {
+	for _, feature := range o.Features {
+		if !contains(ImageFeatures, feature) {
+			return fmt.Errorf("unknown image feature %s, must be one of %v", feature, ImageFeatures)
+		}
+		if dependency, ok := imageFeatureDependencies[feature]; ok && !contains(o.Features, dependency) {
+			return fmt.Errorf("image feature %s requires the feature %s", feature, dependency)
+		}
+	}
+
+	objectSize := o.ObjectSize
+	if objectSize != 0 {
+		if objectSize < imageMinObjectSize || objectSize > imageMaxObjectSize || objectSize&(objectSize-1) != 0 {
+			return fmt.Errorf("invalid object size %d, must be a power of two between %d and %d bytes", objectSize, imageMinObjectSize, imageMaxObjectSize)
+		}
+	} else {
+		// the default object size of rbd
+		objectSize = 4 * ImageMinSize
+	}
+
+	if (o.StripeUnit == 0) != (o.StripeCount == 0) {
+		return fmt.Errorf("the stripe unit and the stripe count must be set together")
+	}
+	if o.StripeUnit != 0 && (o.StripeUnit > objectSize || objectSize%o.StripeUnit != 0) {
+		return fmt.Errorf("invalid stripe unit %d, the object size %d must be a multiple of it", o.StripeUnit, objectSize)
+	}
+	return nil
+}
+
+func (o *ImageOptions) createArgs() []string {
+	args := []string{}
+	for _, feature := range o.Features {
+		args = append(args, "--image-feature", feature)
+	}
+	if o.ObjectSize != 0 {
+		args = append(args, "--object-size", strconv.FormatUint(o.ObjectSize, 10))
+	}
+	if o.StripeUnit != 0 {
+		args = append(args, "--stripe-unit", strconv.FormatUint(o.StripeUnit, 10), "--stripe-count", strconv.FormatUint(o.StripeCount, 10))
+	}
+	return args
+}
+
+// qosConfig returns the rbd qos settings to override on the image
+func (o *ImageOptions) qosConfig() map[string]uint64 {
+	config := map[string]uint64{}
+	if o.QosIopsLimit != 0 {
+		config["rbd_qos_iops_limit"] = o.QosIopsLimit
+	}
+	if o.QosBpsLimit != 0 {
+		config["rbd_qos_bps_limit"] = o.QosBpsLimit
+	}
+	return config
+}
+
 func ListImages(context *clusterd.Context, clusterName, poolName string) ([]CephBlockImage, error) {
 	args := []string{"ls", "-l", poolName}
 	buf, err := ExecuteRBDCommand(context, clusterName, args)
@@ -64,6 +157,24 @@ func ListImages(context *clusterd.Context, clusterName, poolName string) ([]Ceph
 	return images, nil
 }
 
+// ListTrashImages lists the images moved to the trash of a pool, which are removed from the pool after their deferment
+func ListTrashImages(context *clusterd.Context, clusterName, poolName string) ([]CephTrashImage, error) {
+	args := []string{"trash", "ls", poolName}
+	buf, err := ExecuteRBDCommand(context, clusterName, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the images in the trash of pool %s: %+v", poolName, err)
+	}
+
+	images := []CephTrashImage{}
+	if strings.TrimSpace(string(buf)) == "" {
+		return images, nil
+	}
+	if err = json.Unmarshal(buf, &images); err != nil {
+		return nil, fmt.Errorf("unmarshal failed: %+v. raw buffer response: %s", err, string(buf))
+	}
+	return images, nil
+}
+
 func getImageInfo(context *clusterd.Context, clusterName, name, poolName string) (*CephBlockImage, error) {
 	imageSpec := getImageSpec(name, poolName)
 	args := []string{"info", imageSpec}
@@ -84,7 +195,8 @@ func getImageInfo(context *clusterd.Context, clusterName, name, poolName string)
 
 // CreateImage creates a block storage image.
 // If dataPoolName is not empty, the image will use poolName as the metadata pool and the dataPoolname for data.
-func CreateImage(context *clusterd.Context, clusterName, name, poolName, dataPoolName string, size uint64) (*CephBlockImage, error) {
+// The options must have been validated, the qos limits are applied after the image is created.
+func CreateImage(context *clusterd.Context, clusterName, name, poolName, dataPoolName string, size uint64, options ImageOptions) (*CephBlockImage, error) {
 	if size > 0 && size < ImageMinSize {
 		// rbd tool uses MB as the smallest unit for size input.  0 is OK but anything else smaller
 		// than 1 MB should just be rounded up to 1 MB.
@@ -103,6 +215,7 @@ func CreateImage(context *clusterd.Context, clusterName, name, poolName, dataPoo
 	if dataPoolName != "" {
 		args = append(args, fmt.Sprintf("--data-pool=%s", dataPoolName))
 	}
+	args = append(args, options.createArgs()...)
 
 	buf, err := ExecuteRBDCommandNoFormat(context, clusterName, args)
 	if err != nil {
@@ -116,6 +229,16 @@ func CreateImage(context *clusterd.Context, clusterName, name, poolName, dataPoo
 		}
 	}
 
+	for key, value := range options.qosConfig() {
+		if err := SetImageConfig(context, clusterName, name, poolName, key, strconv.FormatUint(value, 10)); err != nil {
+			// don't leave an image behind without its limits, the qos settings are only supported since nautilus
+			if deleteErr := DeleteImage(context, clusterName, name, poolName); deleteErr != nil {
+				logger.Warningf("failed to delete image %s after failing to set its qos limits. %+v", imageSpec, deleteErr)
+			}
+			return nil, fmt.Errorf("failed to set the qos limits of image %s, Nautilus or newer is required: %+v", imageSpec, err)
+		}
+	}
+
 	// now that the image is created, retrieve it
 	image, err := getImageInfo(context, clusterName, name, poolName)
 	if err != nil {
@@ -125,6 +248,18 @@ func CreateImage(context *clusterd.Context, clusterName, name, poolName, dataPoo
 	return image, nil
 }
 
+// SetImageConfig overrides a config setting of the rbd client for an image. Requires Nautilus or newer.
+func SetImageConfig(context *clusterd.Context, clusterName, name, poolName, key, value string) error {
+	imageSpec := getImageSpec(name, poolName)
+	args := []string{"config", "image", "set", imageSpec, key, value}
+	buf, err := ExecuteRBDCommandNoFormat(context, clusterName, args)
+	if err != nil {
+		return fmt.Errorf("failed to set %s=%s on image %s: %+v. output: %s",
+			key, value, imageSpec, err, string(buf))
+	}
+	return nil
+}
+
 func DeleteImage(context *clusterd.Context, clusterName, name, poolName string) error {
 	imageSpec := getImageSpec(name, poolName)
 	args := []string{"rm", imageSpec}
@@ -186,3 +321,12 @@ func UnMapImage(context *clusterd.Context, imageName, poolName, id, keyring, clu
 func getImageSpec(name, poolName string) string {
 	return fmt.Sprintf("%s/%s", poolName, name)
 }
+
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -43,7 +43,7 @@ func TestCreateImage(t *testing.T) {
 		}
 		return "", fmt.Errorf("unexpected ceph command '%v'", args)
 	}
-	image, err := CreateImage(context, "foocluster", "image1", "pool1", "", uint64(sizeMB)) // 1MB
+	image, err := CreateImage(context, "foocluster", "image1", "pool1", "", uint64(sizeMB), ImageOptions{}) // 1MB
 	assert.NotNil(t, err)
 	assert.True(t, strings.Contains(err.Error(), "mocked detailed ceph error output stream"))
 
@@ -67,7 +67,7 @@ func TestCreateImage(t *testing.T) {
 
 	// 0 byte --> 0 MB
 	expectedSizeArg = "0"
-	image, err = CreateImage(context, "foocluster", "image1", "pool1", "", uint64(0))
+	image, err = CreateImage(context, "foocluster", "image1", "pool1", "", uint64(0), ImageOptions{})
 	assert.Nil(t, err)
 	assert.NotNil(t, image)
 	assert.True(t, createCalled)
@@ -75,7 +75,7 @@ func TestCreateImage(t *testing.T) {
 
 	// 1 byte --> 1 MB
 	expectedSizeArg = "1"
-	image, err = CreateImage(context, "foocluster", "image1", "pool1", "", uint64(1))
+	image, err = CreateImage(context, "foocluster", "image1", "pool1", "", uint64(1), ImageOptions{})
 	assert.Nil(t, err)
 	assert.NotNil(t, image)
 	assert.True(t, createCalled)
@@ -83,7 +83,7 @@ func TestCreateImage(t *testing.T) {
 
 	// (1 MB - 1 byte) --> 1 MB
 	expectedSizeArg = "1"
-	image, err = CreateImage(context, "foocluster", "image1", "pool1", "", uint64(sizeMB-1))
+	image, err = CreateImage(context, "foocluster", "image1", "pool1", "", uint64(sizeMB-1), ImageOptions{})
 	assert.Nil(t, err)
 	assert.NotNil(t, image)
 	assert.True(t, createCalled)
@@ -91,7 +91,7 @@ func TestCreateImage(t *testing.T) {
 
 	// 1 MB
 	expectedSizeArg = "1"
-	image, err = CreateImage(context, "foocluster", "image1", "pool1", "", uint64(sizeMB))
+	image, err = CreateImage(context, "foocluster", "image1", "pool1", "", uint64(sizeMB), ImageOptions{})
 	assert.Nil(t, err)
 	assert.NotNil(t, image)
 	assert.True(t, createCalled)
@@ -102,7 +102,7 @@ func TestCreateImage(t *testing.T) {
 
 	// (1 MB + 1 byte) --> 2 MB
 	expectedSizeArg = "2"
-	image, err = CreateImage(context, "foocluster", "image1", "pool1", "", uint64(sizeMB+1))
+	image, err = CreateImage(context, "foocluster", "image1", "pool1", "", uint64(sizeMB+1), ImageOptions{})
 	assert.Nil(t, err)
 	assert.NotNil(t, image)
 	assert.True(t, createCalled)
@@ -110,7 +110,7 @@ func TestCreateImage(t *testing.T) {
 
 	// (2 MB - 1 byte) --> 2 MB
 	expectedSizeArg = "2"
-	image, err = CreateImage(context, "foocluster", "image1", "pool1", "", uint64(sizeMB*2-1))
+	image, err = CreateImage(context, "foocluster", "image1", "pool1", "", uint64(sizeMB*2-1), ImageOptions{})
 	assert.Nil(t, err)
 	assert.NotNil(t, image)
 	assert.True(t, createCalled)
@@ -118,7 +118,7 @@ func TestCreateImage(t *testing.T) {
 
 	// 2 MB
 	expectedSizeArg = "2"
-	image, err = CreateImage(context, "foocluster", "image1", "pool1", "", uint64(sizeMB*2))
+	image, err = CreateImage(context, "foocluster", "image1", "pool1", "", uint64(sizeMB*2), ImageOptions{})
 	assert.Nil(t, err)
 	assert.NotNil(t, image)
 	assert.True(t, createCalled)
@@ -126,7 +126,7 @@ func TestCreateImage(t *testing.T) {
 
 	// (2 MB + 1 byte) --> 3MB
 	expectedSizeArg = "3"
-	image, err = CreateImage(context, "foocluster", "image1", "pool1", "", uint64(sizeMB*2+1))
+	image, err = CreateImage(context, "foocluster", "image1", "pool1", "", uint64(sizeMB*2+1), ImageOptions{})
 	assert.Nil(t, err)
 	assert.NotNil(t, image)
 	assert.True(t, createCalled)
@@ -134,7 +134,7 @@ func TestCreateImage(t *testing.T) {
 
 	// Pool with data pool
 	expectedSizeArg = "1"
-	image, err = CreateImage(context, "foocluster", "image1", "pool1", "datapool1", uint64(sizeMB))
+	image, err = CreateImage(context, "foocluster", "image1", "pool1", "datapool1", uint64(sizeMB), ImageOptions{})
 	assert.Nil(t, err)
 	assert.NotNil(t, image)
 	assert.True(t, createCalled)
@@ -142,6 +142,93 @@ func TestCreateImage(t *testing.T) {
 
 }
 
+func TestCreateImageWithOptions(t *testing.T) {
+	executor := &exectest.MockExecutor{}
+	context := &clusterd.Context{Executor: executor}
+
+	createArgs := []string{}
+	config := map[string]string{}
+	deleteCalled := false
+	configErr := error(nil)
+	executor.MockExecuteCommandWithOutput = func(debug bool, actionName string, command string, args ...string) (string, error) {
+		switch {
+		case command == "rbd" && args[0] == "create":
+			createArgs = args[4:]
+			return "", nil
+		case command == "rbd" && args[0] == "config":
+			assert.Equal(t, []string{"image", "set", "pool1/image1"}, args[1:4])
+			config[args[4]] = args[5]
+			return "", configErr
+		case command == "rbd" && args[0] == "rm":
+			deleteCalled = true
+			return "", nil
+		case command == "rbd" && args[0] == "info":
+			return `{"name":"image1","size":1048576,"objects":1,"order":20,"object_size":1048576,"block_name_prefix":"pool1_data.229226b8b4567",` +
+				`"format":2,"features":["layering"],"op_features":[],"flags":[],"create_timestamp":"Fri Oct  5 19:46:20 2018"}`, nil
+		}
+		return "", fmt.Errorf("unexpected ceph command '%v'", args)
+	}
+
+	options := ImageOptions{
+		Features:     []string{"layering", "exclusive-lock", "journaling"},
+		ObjectSize:   8 * sizeMB,
+		StripeUnit:   sizeMB,
+		StripeCount:  4,
+		QosIopsLimit: 500,
+		QosBpsLimit:  100 * sizeMB,
+	}
+	image, err := CreateImage(context, "foocluster", "image1", "pool1", "", uint64(sizeMB), options)
+	assert.Nil(t, err)
+	assert.NotNil(t, image)
+	assert.Equal(t, []string{"--image-feature", "layering", "--image-feature", "exclusive-lock", "--image-feature", "journaling",
+		"--object-size", "8388608", "--stripe-unit", "1048576", "--stripe-count", "4"}, createArgs[:12])
+	assert.Equal(t, map[string]string{"rbd_qos_iops_limit": "500", "rbd_qos_bps_limit": "104857600"}, config)
+	assert.False(t, deleteCalled)
+
+	// the image is removed when the qos limits are not supported
+	configErr = fmt.Errorf("mocked unknown config command")
+	image, err = CreateImage(context, "foocluster", "image1", "pool1", "", uint64(sizeMB), options)
+	assert.NotNil(t, err)
+	assert.Nil(t, image)
+	assert.True(t, deleteCalled)
+}
+
+func TestValidateImageOptions(t *testing.T) {
+	options := ImageOptions{}
+	assert.Nil(t, options.Validate())
+
+	options = ImageOptions{Features: []string{"layering", "exclusive-lock", "object-map", "fast-diff"}}
+	assert.Nil(t, options.Validate())
+
+	options = ImageOptions{Features: []string{"layering", "fast-diff"}}
+	assert.EqualError(t, options.Validate(), "image feature fast-diff requires the feature object-map")
+
+	options = ImageOptions{Features: []string{"data-pool"}}
+	assert.NotNil(t, options.Validate())
+
+	options = ImageOptions{ObjectSize: 3 * sizeMB}
+	assert.NotNil(t, options.Validate())
+
+	options = ImageOptions{ObjectSize: 64 * sizeMB}
+	assert.NotNil(t, options.Validate())
+
+	options = ImageOptions{ObjectSize: 4096}
+	assert.Nil(t, options.Validate())
+
+	options = ImageOptions{StripeUnit: 65536}
+	assert.EqualError(t, options.Validate(), "the stripe unit and the stripe count must be set together")
+
+	// the stripe unit must divide the default object size
+	options = ImageOptions{StripeUnit: 65536, StripeCount: 16}
+	assert.Nil(t, options.Validate())
+
+	options = ImageOptions{StripeUnit: 3 * 65536, StripeCount: 16}
+	assert.NotNil(t, options.Validate())
+
+	options = ImageOptions{ObjectSize: sizeMB, StripeUnit: 2 * sizeMB, StripeCount: 2}
+	assert.NotNil(t, options.Validate())
+}
+
 func TestListImageLogLevelInfo(t *testing.T) {
 	executor := &exectest.MockExecutor{}
 	context := &clusterd.Context{Executor: executor}
@@ -242,3 +329,24 @@ func TestListImageLogLevelDebug(t *testing.T) {
 	assert.True(t, listCalled)
 	listCalled = false
 }
+
+func TestListTrashImages(t *testing.T) {
+	output := `[{"id":"1234","name":"image1"}]`
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName string, command string, args ...string) (string, error) {
+			assert.Equal(t, "rbd", command)
+			assert.Equal(t, []string{"trash", "ls", "pool1"}, args[:3])
+			return output, nil
+		},
+	}
+	context := &clusterd.Context{Executor: executor}
+
+	images, err := ListTrashImages(context, "foocluster", "pool1")
+	assert.Nil(t, err)
+	assert.Equal(t, []CephTrashImage{{ID: "1234", Name: "image1"}}, images)
+
+	output = ""
+	images, err = ListTrashImages(context, "foocluster", "pool1")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(images))
+}
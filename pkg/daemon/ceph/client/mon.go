@@ -123,3 +123,13 @@ func GetMonTimeStatus(context *clusterd.Context, clusterName string) (*MonTimeSt
 
 	return &timeStatus, nil
 }
+
+// EnableMessenger2 adds the msgr2 address of each mon on the default port to the mon map. Clients and daemons connect
+// to the mons with the msgr2 protocol once they know its address. The mons must all run Nautilus or newer.
+func EnableMessenger2(context *clusterd.Context, clusterName string) error {
+	args := []string{"mon", "enable-msgr2"}
+	if _, err := ExecuteCephCommand(context, clusterName, args); err != nil {
+		return fmt.Errorf("failed to enable msgr2: %+v", err)
+	}
+	return nil
+}
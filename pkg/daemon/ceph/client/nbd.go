@@ -0,0 +1,110 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rook/rook/pkg/clusterd"
+)
+
+const (
+	// RBDNbdTool is the name of the CLI tool mapping rbd images with the userspace rbd client
+	RBDNbdTool = "rbd-nbd"
+
+	// nbdReattachTimeout is how long the kernel keeps an nbd device and queues its I/O after its rbd-nbd process
+	// exited, waiting for a new process to attach to the device, in seconds
+	nbdReattachTimeout = 300
+)
+
+// NbdMappedImage is an rbd image mapped by an rbd-nbd process
+type NbdMappedImage struct {
+	Pid    int    `json:"pid"`
+	Pool   string `json:"pool"`
+	Image  string `json:"image"`
+	Device string `json:"device"`
+}
+
+func executeNbdCommand(context *clusterd.Context, args []string) (string, error) {
+	start := time.Now()
+	output, err := context.Executor.ExecuteCommandWithTimeout(false, cmdExecuteTimeout, "", RBDNbdTool, args...)
+	observeCommand(RBDNbdTool, args, start, err)
+	return output, err
+}
+
+// NbdMapImage maps an rbd image with an rbd-nbd process and returns the nbd device. The device is created with the
+// netlink interface so that the kernel keeps it when the process exits until a new process is attached to it.
+func NbdMapImage(context *clusterd.Context, imageName, poolName, id, keyring, clusterName, monitors string) (string, error) {
+	imageSpec := getImageSpec(imageName, poolName)
+	args := append([]string{"map", imageSpec}, credentialArgs(id, keyring, clusterName, monitors)...)
+	args = append(args, "--try-netlink", "--reattach-timeout", strconv.Itoa(nbdReattachTimeout))
+
+	output, err := executeNbdCommand(context, args)
+	if err != nil {
+		return "", fmt.Errorf("failed to map image %s with rbd-nbd: %+v. output: %s", imageSpec, err, output)
+	}
+	// rbd-nbd prints the device on the last line after the logs
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	mappedDevice := strings.TrimSpace(lines[len(lines)-1])
+	if !strings.HasPrefix(mappedDevice, "/dev/nbd") {
+		return "", fmt.Errorf("failed to find the device of image %s mapped with rbd-nbd. output: %s", imageSpec, output)
+	}
+	return mappedDevice, nil
+}
+
+// NbdAttachImage starts a new rbd-nbd process serving the existing nbd device of an rbd image, after the process
+// mapping the image exited
+func NbdAttachImage(context *clusterd.Context, imageName, poolName, device, id, keyring, clusterName, monitors string) error {
+	imageSpec := getImageSpec(imageName, poolName)
+	args := append([]string{"attach", "--device", device, imageSpec}, credentialArgs(id, keyring, clusterName, monitors)...)
+	args = append(args, "--reattach-timeout", strconv.Itoa(nbdReattachTimeout))
+	output, err := executeNbdCommand(context, args)
+	if err != nil {
+		return fmt.Errorf("failed to attach image %s to nbd device %s: %+v. output: %s", imageSpec, device, err, output)
+	}
+	return nil
+}
+
+// NbdUnmapImage unmaps the nbd device of an rbd image and stops its rbd-nbd process
+func NbdUnmapImage(context *clusterd.Context, device string) error {
+	output, err := executeNbdCommand(context, []string{"unmap", device})
+	if err != nil {
+		return fmt.Errorf("failed to unmap nbd device %s: %+v. output: %s", device, err, output)
+	}
+	return nil
+}
+
+// NbdListMappedImages returns the rbd images mapped by the running rbd-nbd processes
+func NbdListMappedImages(context *clusterd.Context) ([]NbdMappedImage, error) {
+	output, err := executeNbdCommand(context, []string{"list-mapped", "--format", "json"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the images mapped with rbd-nbd: %+v. output: %s", err, output)
+	}
+	if strings.TrimSpace(output) == "" {
+		return []NbdMappedImage{}, nil
+	}
+
+	var images []NbdMappedImage
+	if err := json.Unmarshal([]byte(output), &images); err != nil {
+		return nil, fmt.Errorf("unmarshal failed: %+v. raw buffer response: %s", err, output)
+	}
+	return images, nil
+}
@@ -65,6 +65,14 @@ type OSDDump struct {
 	} `json:"osds"`
 }
 
+// OSDMetadata is the subset of the metadata of an OSD read by rook
+type OSDMetadata struct {
+	ID       int    `json:"id"`
+	Hostname string `json:"hostname"`
+	// Devices is the comma separated list of the devices backing the OSD
+	Devices string `json:"devices"`
+}
+
 // StatusByID returns status and inCluster states for given OSD id
 func (dump *OSDDump) StatusByID(id int64) (int64, int64, error) {
 	for _, d := range dump.OSDs {
@@ -136,6 +144,38 @@ func GetOSDDump(context *clusterd.Context, clusterName string) (*OSDDump, error)
 	return &osdDump, nil
 }
 
+// GetOSDMetadata returns the metadata reported by the given OSD
+func GetOSDMetadata(context *clusterd.Context, clusterName string, osdID int) (*OSDMetadata, error) {
+	args := []string{"osd", "metadata", strconv.Itoa(osdID)}
+	buf, err := ExecuteCephCommand(context, clusterName, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get osd.%d metadata: %+v", osdID, err)
+	}
+
+	var metadata OSDMetadata
+	if err := json.Unmarshal(buf, &metadata); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal osd metadata response: %+v", err)
+	}
+
+	return &metadata, nil
+}
+
+// ListOSDMetadata returns the metadata reported by all the OSDs
+func ListOSDMetadata(context *clusterd.Context, clusterName string) ([]OSDMetadata, error) {
+	args := []string{"osd", "metadata"}
+	buf, err := ExecuteCephCommand(context, clusterName, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list osd metadata: %+v", err)
+	}
+
+	var metadata []OSDMetadata
+	if err := json.Unmarshal(buf, &metadata); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal osd metadata response: %+v", err)
+	}
+
+	return metadata, nil
+}
+
 func OSDOut(context *clusterd.Context, clusterName string, osdID int) (string, error) {
 	args := []string{"osd", "out", strconv.Itoa(osdID)}
 	buf, err := ExecuteCephCommand(context, clusterName, args)
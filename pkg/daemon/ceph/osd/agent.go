@@ -29,6 +29,7 @@ import (
 
 	"github.com/rook/rook/pkg/clusterd"
 	cephconfig "github.com/rook/rook/pkg/daemon/ceph/config"
+	"github.com/rook/rook/pkg/daemon/ceph/osd/kms"
 	oposd "github.com/rook/rook/pkg/operator/ceph/cluster/osd"
 	"github.com/rook/rook/pkg/operator/ceph/cluster/osd/config"
 	"github.com/rook/rook/pkg/operator/k8sutil"
@@ -58,6 +59,9 @@ type OsdAgent struct {
 	kv             *k8sutil.ConfigMapKVStore
 	configCounter  int32
 	osdsCompleted  chan struct{}
+
+	// kms holds the keys of the encrypted devices, nil if ceph-volume stores them in the mon config-key store
+	kms kms.KMS
 }
 
 type device struct {
@@ -66,7 +70,8 @@ type device struct {
 }
 
 func NewAgent(context *clusterd.Context, devices []DesiredDevice, metadataDevice, directories string, forceFormat bool,
-	location string, storeConfig config.StoreConfig, cluster *cephconfig.ClusterInfo, nodeName string, kv *k8sutil.ConfigMapKVStore) *OsdAgent {
+	location string, storeConfig config.StoreConfig, cluster *cephconfig.ClusterInfo, nodeName string, kv *k8sutil.ConfigMapKVStore,
+	keys kms.KMS) *OsdAgent {
 
 	return &OsdAgent{
 		devices:        devices,
@@ -78,6 +83,7 @@ func NewAgent(context *clusterd.Context, devices []DesiredDevice, metadataDevice
 		cluster:        cluster,
 		nodeName:       nodeName,
 		kv:             kv,
+		kms:            keys,
 		procMan:        proc.New(context.Executor),
 		osdProc:        make(map[int]*proc.MonitoredProc),
 	}
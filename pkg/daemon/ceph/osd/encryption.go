@@ -0,0 +1,226 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package osd
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/daemon/ceph/osd/kms"
+)
+
+// When the keys are held by a KMS, rook encrypts the devices itself instead of ceph-volume, which would store the keys
+// in the mon config-key store. Each device is formatted with LUKS and opened as /dev/mapper/rook-luks-<uuid>, and the
+// osds are prepared by ceph-volume on the logical volumes of the ceph-luks-<uuid> volume group of the opened device.
+const (
+	cryptsetupCmd     = "cryptsetup"
+	luksMapperPrefix  = "rook-luks-"
+	luksVGPrefix      = "ceph-luks-"
+	luksLVPrefix      = "osd-block-"
+	dmcryptKeyBytes   = 32
+	luksDevicesFilter = "TYPE=crypto_LUKS"
+)
+
+// encryptDevice formats the device with LUKS and a new key stored in the KMS, then opens it. Returns the LUKS UUID of
+// the device.
+func encryptDevice(context *clusterd.Context, keys kms.KMS, device string) (string, error) {
+	key, err := generateDmcryptKey()
+	if err != nil {
+		return "", err
+	}
+
+	err = withKeyFile(key, func(keyFile string) error {
+		return context.Executor.ExecuteCommand(false, "", cryptsetupCmd, "--batch-mode", "--key-file", keyFile, "luksFormat", device)
+	})
+	if err != nil {
+		wipeEncryptedDevice(context, device, "")
+		return "", fmt.Errorf("failed to format device %s with luks. %+v", device, err)
+	}
+
+	luksUUID, err := context.Executor.ExecuteCommandWithOutput(false, "", cryptsetupCmd, "luksUUID", device)
+	if err != nil {
+		wipeEncryptedDevice(context, device, "")
+		return "", fmt.Errorf("failed to get the luks uuid of device %s. %+v", device, err)
+	}
+	luksUUID = strings.TrimSpace(luksUUID)
+
+	// the key is stored before the device is used. the header of a device whose key could not be stored is wiped, so
+	// the device is formatted again by the next provisioning.
+	if err := keys.PutKey(luksUUID, key); err != nil {
+		wipeEncryptedDevice(context, device, luksUUID)
+		return "", err
+	}
+
+	if err := openDevice(context, device, luksUUID, key); err != nil {
+		wipeEncryptedDevice(context, device, luksUUID)
+		return "", err
+	}
+	logger.Infof("encrypted device %s with luks uuid %s", device, luksUUID)
+	return luksUUID, nil
+}
+
+// wipeEncryptedDevice removes the volume group of a device whose encryption or provisioning failed, closes the device
+// and wipes its LUKS header. A device left with a LUKS header would not be provisioned again, since it is not empty
+// anymore and its osds could not be activated. The key stored in the kms, if any, is not used anymore.
+func wipeEncryptedDevice(context *clusterd.Context, device, luksUUID string) {
+	if luksUUID != "" {
+		vg := luksVGPrefix + luksUUID
+		if err := context.Executor.ExecuteCommand(false, "", "vgremove", "--force", vg); err != nil {
+			logger.Infof("volume group %s not removed. %+v", vg, err)
+		}
+		if err := context.Executor.ExecuteCommand(false, "", cryptsetupCmd, "close", luksMapperPrefix+luksUUID); err != nil {
+			logger.Infof("encrypted device %s not closed. %+v", device, err)
+		}
+	}
+	// erase the key slots before removing the signature, so the key can't unlock the device anymore
+	if err := context.Executor.ExecuteCommand(false, "", cryptsetupCmd, "erase", "--batch-mode", device); err != nil {
+		logger.Warningf("failed to erase the luks key slots of device %s. %+v", device, err)
+	}
+	if err := context.Executor.ExecuteCommand(false, "", "wipefs", "--all", device); err != nil {
+		logger.Errorf("failed to wipe the luks header of device %s. wipe the device manually to provision it again. %+v", device, err)
+		return
+	}
+	logger.Infof("wiped the luks header of device %s", device)
+}
+
+// createEncryptedVolumes creates the volume group of the opened device and a logical volume for each of its osds.
+// Returns the logical volumes as vg/lv.
+func createEncryptedVolumes(context *clusterd.Context, luksUUID string, osdsPerDevice int) ([]string, error) {
+	vg := luksVGPrefix + luksUUID
+	if err := context.Executor.ExecuteCommand(false, "", "vgcreate", vg, luksMapperPath(luksUUID)); err != nil {
+		return nil, fmt.Errorf("failed to create volume group %s. %+v", vg, err)
+	}
+
+	var volumes []string
+	for i := 0; i < osdsPerDevice; i++ {
+		// each volume takes an equal share of the space left
+		extents := fmt.Sprintf("%d%%FREE", 100/(osdsPerDevice-i))
+		lv := fmt.Sprintf("%s%d", luksLVPrefix, i)
+		if err := context.Executor.ExecuteCommand(false, "", "lvcreate", "--yes", "-l", extents, "-n", lv, vg); err != nil {
+			return nil, fmt.Errorf("failed to create logical volume %s/%s. %+v", vg, lv, err)
+		}
+		volumes = append(volumes, path.Join(vg, lv))
+	}
+	return volumes, nil
+}
+
+// OpenEncryptedDevice opens the device with the given LUKS UUID with its key from the KMS and activates its volume
+// group, unless the device is already open
+func OpenEncryptedDevice(context *clusterd.Context, keys kms.KMS, luksUUID string) error {
+	if _, err := context.Executor.ExecuteStat(luksMapperPath(luksUUID)); err == nil {
+		logger.Infof("encrypted device %s is already open", luksUUID)
+	} else {
+		device, err := context.Executor.ExecuteCommandWithOutput(false, "", "blkid", "-U", luksUUID)
+		if err != nil {
+			return fmt.Errorf("failed to find the device with luks uuid %s. %+v", luksUUID, err)
+		}
+		key, err := keys.GetKey(luksUUID)
+		if err != nil {
+			return err
+		}
+		if err := openDevice(context, strings.TrimSpace(device), luksUUID, key); err != nil {
+			return err
+		}
+	}
+
+	vg := luksVGPrefix + luksUUID
+	if err := context.Executor.ExecuteCommand(false, "", "vgchange", "-ay", vg); err != nil {
+		return fmt.Errorf("failed to activate volume group %s. %+v", vg, err)
+	}
+	return nil
+}
+
+// openEncryptedDevices opens the devices of the node encrypted with a key held by the KMS, so that ceph-volume lists
+// their osds. The LUKS devices whose keys are not held by the KMS are skipped.
+func openEncryptedDevices(context *clusterd.Context, keys kms.KMS) error {
+	output, err := context.Executor.ExecuteCommandWithOutput(false, "", "blkid", "-t", luksDevicesFilter, "-o", "device")
+	if err != nil {
+		// blkid exits with 2 if no device matches
+		logger.Infof("no luks devices found. %+v", err)
+		return nil
+	}
+
+	for _, device := range strings.Fields(output) {
+		luksUUID, err := context.Executor.ExecuteCommandWithOutput(false, "", cryptsetupCmd, "luksUUID", device)
+		if err != nil {
+			return fmt.Errorf("failed to get the luks uuid of device %s. %+v", device, err)
+		}
+		luksUUID = strings.TrimSpace(luksUUID)
+		if _, err := keys.GetKey(luksUUID); kms.IsNotFound(err) {
+			logger.Infof("skipping luks device %s whose key is not held by the kms", device)
+			continue
+		}
+		if err := OpenEncryptedDevice(context, keys, luksUUID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func openDevice(context *clusterd.Context, device, luksUUID, key string) error {
+	err := withKeyFile(key, func(keyFile string) error {
+		return context.Executor.ExecuteCommand(false, "", cryptsetupCmd, "--key-file", keyFile, "luksOpen", device, luksMapperPrefix+luksUUID)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to open encrypted device %s. %+v", device, err)
+	}
+	return nil
+}
+
+// luksUUIDFromVG returns the LUKS UUID of the device of a volume group created by rook on an encrypted device, or an
+// empty string if the volume group is not on such a device
+func luksUUIDFromVG(vg string) string {
+	if !strings.HasPrefix(vg, luksVGPrefix) {
+		return ""
+	}
+	return strings.TrimPrefix(vg, luksVGPrefix)
+}
+
+func luksMapperPath(luksUUID string) string {
+	return path.Join("/dev/mapper", luksMapperPrefix+luksUUID)
+}
+
+func generateDmcryptKey() (string, error) {
+	key := make([]byte, dmcryptKeyBytes)
+	if _, err := rand.Read(key); err != nil {
+		return "", fmt.Errorf("failed to generate a dmcrypt key. %+v", err)
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// withKeyFile writes the key to a file only readable by root for the duration of the call, since cryptsetup reads
+// the key from a file
+func withKeyFile(key string, call func(keyFile string) error) error {
+	file, err := ioutil.TempFile("", "luks-key")
+	if err != nil {
+		return fmt.Errorf("failed to create the key file. %+v", err)
+	}
+	defer os.Remove(file.Name())
+
+	_, err = file.WriteString(key)
+	file.Close()
+	if err != nil {
+		return fmt.Errorf("failed to write the key file. %+v", err)
+	}
+	return call(file.Name())
+}
@@ -0,0 +1,179 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package osd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/daemon/ceph/osd/kms"
+	"github.com/rook/rook/pkg/operator/ceph/cluster/osd/config"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestInitializeEncryptedDevice(t *testing.T) {
+	keys, err := kms.New(fake.NewSimpleClientset(), "ns", map[string]string{kms.ProviderEnvVar: kms.ProviderSecrets, kms.SecretsNamespaceEnvVar: "ns-keys"})
+	require.Nil(t, err)
+
+	var commands []string
+	var formatKey, openKey string
+	failingCommand := ""
+	executor := &exectest.MockExecutor{
+		MockExecuteCommand: func(debug bool, actionName string, command string, args ...string) error {
+			commands = append(commands, command+" "+strings.Join(args, " "))
+			if command == "cryptsetup" && args[0] == "--batch-mode" {
+				key, err := ioutil.ReadFile(args[2])
+				assert.Nil(t, err)
+				formatKey = string(key)
+			} else if command == "cryptsetup" && args[0] == "--key-file" {
+				key, err := ioutil.ReadFile(args[1])
+				assert.Nil(t, err)
+				openKey = string(key)
+			}
+			if command == failingCommand {
+				return fmt.Errorf("mock %s failure", command)
+			}
+			return nil
+		},
+		MockExecuteCommandWithOutput: func(debug bool, actionName string, command string, args ...string) (string, error) {
+			if command == "cryptsetup" && args[0] == "luksUUID" {
+				return "0e3f1b6a-1c2d-4e5f-8a9b-0c1d2e3f4a5b\n", nil
+			}
+			return "", fmt.Errorf("unexpected command %s %v", command, args)
+		},
+	}
+	context := &clusterd.Context{Executor: executor}
+	agent := &OsdAgent{kms: keys, storeConfig: config.StoreConfig{StoreType: config.Bluestore, EncryptedDevice: true}}
+
+	err = agent.initializeEncryptedDevice(context, "/dev/sdb", "--bluestore", 2)
+	require.Nil(t, err)
+
+	// the device is formatted and opened with the key stored in the kms
+	assert.NotEmpty(t, formatKey)
+	assert.Equal(t, formatKey, openKey)
+	stored, err := keys.GetKey("0e3f1b6a-1c2d-4e5f-8a9b-0c1d2e3f4a5b")
+	assert.Nil(t, err)
+	assert.Equal(t, formatKey, stored)
+
+	vg := "ceph-luks-0e3f1b6a-1c2d-4e5f-8a9b-0c1d2e3f4a5b"
+	require.Equal(t, 7, len(commands))
+	assert.True(t, strings.HasSuffix(commands[0], "luksFormat /dev/sdb"))
+	assert.True(t, strings.HasSuffix(commands[1], "luksOpen /dev/sdb rook-luks-0e3f1b6a-1c2d-4e5f-8a9b-0c1d2e3f4a5b"))
+	assert.Equal(t, "vgcreate "+vg+" /dev/mapper/rook-luks-0e3f1b6a-1c2d-4e5f-8a9b-0c1d2e3f4a5b", commands[2])
+	assert.Equal(t, "lvcreate --yes -l 50%FREE -n osd-block-0 "+vg, commands[3])
+	assert.Equal(t, "lvcreate --yes -l 100%FREE -n osd-block-1 "+vg, commands[4])
+	assert.Equal(t, "ceph-volume lvm prepare --bluestore --data "+vg+"/osd-block-0", commands[5])
+	assert.Equal(t, "ceph-volume lvm prepare --bluestore --data "+vg+"/osd-block-1", commands[6])
+
+	// the luks header is wiped when the volumes can't be created, so the device is provisioned again
+	commands = nil
+	failingCommand = "vgcreate"
+	err = agent.initializeEncryptedDevice(context, "/dev/sdb", "--bluestore", 2)
+	assert.NotNil(t, err)
+	require.Equal(t, 7, len(commands))
+	assert.Equal(t, "vgcreate "+vg+" /dev/mapper/rook-luks-0e3f1b6a-1c2d-4e5f-8a9b-0c1d2e3f4a5b", commands[2])
+	assert.Equal(t, "vgremove --force "+vg, commands[3])
+	assert.Equal(t, "cryptsetup close rook-luks-0e3f1b6a-1c2d-4e5f-8a9b-0c1d2e3f4a5b", commands[4])
+	assert.Equal(t, "cryptsetup erase --batch-mode /dev/sdb", commands[5])
+	assert.Equal(t, "wipefs --all /dev/sdb", commands[6])
+
+	// or when ceph-volume fails
+	commands = nil
+	failingCommand = "ceph-volume"
+	err = agent.initializeEncryptedDevice(context, "/dev/sdb", "--bluestore", 1)
+	assert.NotNil(t, err)
+	require.Equal(t, 9, len(commands))
+	assert.Equal(t, "ceph-volume lvm prepare --bluestore --data "+vg+"/osd-block-0", commands[4])
+	assert.Equal(t, "wipefs --all /dev/sdb", commands[8])
+
+	// filestore is not supported
+	agent.storeConfig.StoreType = config.Filestore
+	assert.NotNil(t, agent.initializeEncryptedDevice(context, "/dev/sdc", "--filestore", 1))
+}
+
+func TestOpenEncryptedDevice(t *testing.T) {
+	keys, err := kms.New(fake.NewSimpleClientset(), "ns", map[string]string{kms.ProviderEnvVar: kms.ProviderSecrets, kms.SecretsNamespaceEnvVar: "ns-keys"})
+	require.Nil(t, err)
+	require.Nil(t, keys.PutKey("1234", "secret-key"))
+
+	var commands []string
+	opened := false
+	executor := &exectest.MockExecutor{
+		MockExecuteCommand: func(debug bool, actionName string, command string, args ...string) error {
+			commands = append(commands, command+" "+strings.Join(args, " "))
+			if command == "cryptsetup" {
+				key, err := ioutil.ReadFile(args[1])
+				assert.Nil(t, err)
+				assert.Equal(t, "secret-key", string(key))
+			}
+			return nil
+		},
+		MockExecuteCommandWithOutput: func(debug bool, actionName string, command string, args ...string) (string, error) {
+			if command == "blkid" && args[0] == "-U" {
+				return "/dev/sdb\n", nil
+			}
+			if command == "blkid" {
+				return "/dev/sdb\n/dev/sdc\n", nil
+			}
+			if command == "cryptsetup" && args[1] == "/dev/sdb" {
+				return "1234", nil
+			}
+			if command == "cryptsetup" && args[1] == "/dev/sdc" {
+				return "5678", nil
+			}
+			return "", fmt.Errorf("unexpected command %s %v", command, args)
+		},
+		MockExecuteStat: func(name string) (os.FileInfo, error) {
+			if opened {
+				return nil, nil
+			}
+			return nil, os.ErrNotExist
+		},
+	}
+	context := &clusterd.Context{Executor: executor}
+
+	// the device whose key is not held by the kms is skipped
+	err = openEncryptedDevices(context, keys)
+	assert.Nil(t, err)
+	require.Equal(t, 2, len(commands))
+	assert.True(t, strings.HasSuffix(commands[0], "luksOpen /dev/sdb rook-luks-1234"))
+	assert.Equal(t, "vgchange -ay ceph-luks-1234", commands[1])
+
+	// an open device is only activated
+	commands = nil
+	opened = true
+	err = OpenEncryptedDevice(context, keys, "1234")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"vgchange -ay ceph-luks-1234"}, commands)
+
+	// the key of the device is missing
+	opened = false
+	err = OpenEncryptedDevice(context, keys, "5678")
+	assert.True(t, kms.IsNotFound(err))
+}
+
+func TestLUKSUUIDFromVG(t *testing.T) {
+	assert.Equal(t, "1234", luksUUIDFromVG("ceph-luks-1234"))
+	assert.Equal(t, "", luksUUIDFromVG("ceph-93550251-f76c-4219-a33f-df8805de7b9e"))
+}
@@ -0,0 +1,138 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package kms holds the dm-crypt keys of the encrypted osds in a key management service outside of the ceph cluster.
+package kms
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/coreos/pkg/capnslog"
+	"k8s.io/client-go/kubernetes"
+)
+
+var logger = capnslog.NewPackageLogger("github.com/rook/rook", "osd-kms")
+
+const (
+	// ProviderEnvVar is the setting selecting the key management service
+	ProviderEnvVar = "KMS_PROVIDER"
+	// ProviderSecrets stores the keys in Kubernetes secrets
+	ProviderSecrets = "secrets"
+	// ProviderVault stores the keys in HashiCorp Vault
+	ProviderVault = "vault"
+	// SecretsNamespaceEnvVar is the setting of the namespace of the secrets holding the keys, or the wrapped keys of the
+	// vault transit backend. A dedicated namespace keeps the osds away from the other secrets of the cluster.
+	SecretsNamespaceEnvVar = "KMS_SECRETS_NAMESPACE"
+
+	// keyNamePrefix is the prefix of the names of the keys, which end with the LUKS UUID of their device
+	keyNamePrefix = "rook-ceph-osd-encryption-key-"
+	// vaultSettingPrefix is the prefix of the settings of the vault provider
+	vaultSettingPrefix = "VAULT_"
+)
+
+// KMS stores the dm-crypt keys of the encrypted devices
+type KMS interface {
+	// PutKey stores the key of the device with the given LUKS UUID
+	PutKey(luksUUID, key string) error
+	// GetKey returns the key of the device with the given LUKS UUID. A NotFoundError is returned if the key was
+	// not stored by the KMS.
+	GetKey(luksUUID string) (string, error)
+	// Rewrap wraps the stored keys with the latest version of the wrapping key. Returns the number of keys rewrapped.
+	Rewrap() (int, error)
+}
+
+// NotFoundError is returned when the KMS does not hold the key of a device
+type NotFoundError struct {
+	LUKSUUID string
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("key of device %s not found", e.LUKSUUID)
+}
+
+// IsNotFound returns whether the error is a NotFoundError
+func IsNotFound(err error) bool {
+	_, ok := err.(*NotFoundError)
+	return ok
+}
+
+// KeyName returns the name under which the key of the device with the given LUKS UUID is stored
+func KeyName(luksUUID string) string {
+	return keyNamePrefix + luksUUID
+}
+
+// New creates the KMS of the given settings for the cluster in the given namespace. The keys of the secrets provider, and
+// the wrapped keys of the vault transit backend, are stored in the secrets of the namespace set in the settings.
+func New(clientset kubernetes.Interface, namespace string, settings map[string]string) (KMS, error) {
+	if err := ValidateSecretsNamespace(namespace, settings); err != nil {
+		return nil, err
+	}
+	if settings[SecretsNamespaceEnvVar] != "" {
+		namespace = settings[SecretsNamespaceEnvVar]
+	}
+	switch settings[ProviderEnvVar] {
+	case ProviderSecrets:
+		return newSecretStore(clientset, namespace), nil
+	case ProviderVault:
+		return newVault(clientset, namespace, settings)
+	case "":
+		return nil, fmt.Errorf("%s is not set", ProviderEnvVar)
+	default:
+		return nil, fmt.Errorf("unknown kms provider %s", settings[ProviderEnvVar])
+	}
+}
+
+// ValidateSecretsNamespace checks that the keys stored in secrets, by the secrets provider or the vault transit backend,
+// are stored in a namespace other than the cluster namespace. The osds would otherwise be granted access to the
+// secrets of the cluster namespace, such as the admin keyring.
+func ValidateSecretsNamespace(clusterNamespace string, settings map[string]string) error {
+	provider := settings[ProviderEnvVar]
+	if provider != ProviderSecrets && (provider != ProviderVault || settings[VaultBackendEnvVar] != VaultBackendTransit) {
+		return nil
+	}
+	namespace := settings[SecretsNamespaceEnvVar]
+	if namespace == "" {
+		return fmt.Errorf("%s must be set to a dedicated namespace to store the keys in secrets", SecretsNamespaceEnvVar)
+	}
+	if namespace == clusterNamespace {
+		return fmt.Errorf("%s must be a namespace other than the cluster namespace %s", SecretsNamespaceEnvVar, clusterNamespace)
+	}
+	return nil
+}
+
+// NewFromEnv creates the KMS configured by the environment of the container. Nil is returned if no KMS is configured,
+// in which case ceph-volume stores the keys in the mon config-key store.
+func NewFromEnv(clientset kubernetes.Interface, namespace string) (KMS, error) {
+	settings := SettingsFromEnv()
+	if settings[ProviderEnvVar] == "" {
+		return nil, nil
+	}
+	return New(clientset, namespace, settings)
+}
+
+// SettingsFromEnv returns the settings of the KMS set in the environment of the container
+func SettingsFromEnv() map[string]string {
+	settings := map[string]string{}
+	for _, pair := range os.Environ() {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) == 2 && (parts[0] == ProviderEnvVar || parts[0] == SecretsNamespaceEnvVar || strings.HasPrefix(parts[0], vaultSettingPrefix)) {
+			settings[parts[0]] = parts[1]
+		}
+	}
+	return settings
+}
@@ -0,0 +1,215 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kms
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// fakeVault serves the kv version 2 and transit apis of a dev-mode vault. The transit key is "rotated" by bumping the
+// version prefixed to the ciphertexts.
+type fakeVault struct {
+	kv             map[string]map[string]interface{}
+	transitVersion int
+}
+
+func newFakeVault(t *testing.T) (*fakeVault, *httptest.Server) {
+	v := &fakeVault{kv: map[string]map[string]interface{}{}, transitVersion: 1}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "root" {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"errors":["permission denied"]}`)
+			return
+		}
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+
+		var data map[string]interface{}
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/v1/secret/data/") && r.Method == "POST":
+			v.kv[r.URL.Path] = body["data"].(map[string]interface{})
+			data = map[string]interface{}{"version": 1}
+		case strings.HasPrefix(r.URL.Path, "/v1/secret/data/"):
+			secret, ok := v.kv[r.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				fmt.Fprint(w, `{"errors":[]}`)
+				return
+			}
+			data = map[string]interface{}{"data": secret}
+		case r.URL.Path == "/v1/transit/encrypt/rook-ceph-osd":
+			data = map[string]interface{}{"ciphertext": fmt.Sprintf("vault:v%d:%s", v.transitVersion, body["plaintext"])}
+		case r.URL.Path == "/v1/transit/decrypt/rook-ceph-osd":
+			parts := strings.SplitN(body["ciphertext"].(string), ":", 3)
+			data = map[string]interface{}{"plaintext": parts[2]}
+		case r.URL.Path == "/v1/transit/rewrap/rook-ceph-osd":
+			parts := strings.SplitN(body["ciphertext"].(string), ":", 3)
+			data = map[string]interface{}{"ciphertext": fmt.Sprintf("vault:v%d:%s", v.transitVersion, parts[2])}
+		default:
+			t.Errorf("unexpected vault request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+	}))
+	return v, server
+}
+
+func TestSecretStore(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	keys, err := New(clientset, "ns", map[string]string{ProviderEnvVar: ProviderSecrets, SecretsNamespaceEnvVar: "ns-keys"})
+	require.Nil(t, err)
+
+	_, err = keys.GetKey("1234")
+	assert.True(t, IsNotFound(err))
+
+	assert.Nil(t, keys.PutKey("1234", "key1"))
+	key, err := keys.GetKey("1234")
+	assert.Nil(t, err)
+	assert.Equal(t, "key1", key)
+
+	// the key is updated if it exists
+	assert.Nil(t, keys.PutKey("1234", "key2"))
+	key, err = keys.GetKey("1234")
+	assert.Nil(t, err)
+	assert.Equal(t, "key2", key)
+
+	// the keys are stored in the dedicated namespace
+	secret, err := clientset.CoreV1().Secrets("ns-keys").Get("rook-ceph-osd-encryption-key-1234", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "rook-ceph-osd-encryption-key", secret.Labels["app"])
+	_, err = clientset.CoreV1().Secrets("ns").Get("rook-ceph-osd-encryption-key-1234", metav1.GetOptions{})
+	assert.NotNil(t, err)
+
+	count, err := keys.Rewrap()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, count)
+
+	// the keys are not stored in the cluster namespace
+	_, err = New(clientset, "ns", map[string]string{ProviderEnvVar: ProviderSecrets})
+	assert.NotNil(t, err)
+	_, err = New(clientset, "ns", map[string]string{ProviderEnvVar: ProviderSecrets, SecretsNamespaceEnvVar: "ns"})
+	assert.NotNil(t, err)
+}
+
+func TestVaultKV(t *testing.T) {
+	vault, server := newFakeVault(t)
+	defer server.Close()
+
+	clientset := fake.NewSimpleClientset()
+	settings := map[string]string{ProviderEnvVar: ProviderVault, VaultAddrEnvVar: server.URL, VaultTokenEnvVar: "root"}
+	keys, err := New(clientset, "ns", settings)
+	require.Nil(t, err)
+
+	_, err = keys.GetKey("1234")
+	assert.True(t, IsNotFound(err))
+
+	assert.Nil(t, keys.PutKey("1234", "key1"))
+	key, err := keys.GetKey("1234")
+	assert.Nil(t, err)
+	assert.Equal(t, "key1", key)
+	assert.Equal(t, "key1", vault.kv["/v1/secret/data/rook-ceph-osd-encryption-key-1234"]["dmcrypt-key"])
+
+	// the key is not stored in the cluster
+	secrets, err := clientset.CoreV1().Secrets("ns").List(metav1.ListOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(secrets.Items))
+
+	// a bad token fails
+	settings[VaultTokenEnvVar] = "bad"
+	keys, err = New(clientset, "ns", settings)
+	require.Nil(t, err)
+	_, err = keys.GetKey("1234")
+	assert.NotNil(t, err)
+	assert.False(t, IsNotFound(err))
+	assert.Contains(t, err.Error(), "permission denied")
+}
+
+func TestVaultTransit(t *testing.T) {
+	vault, server := newFakeVault(t)
+	defer server.Close()
+
+	clientset := fake.NewSimpleClientset()
+	settings := map[string]string{
+		ProviderEnvVar:     ProviderVault,
+		VaultAddrEnvVar:    server.URL + "/",
+		VaultTokenEnvVar:   "root",
+		VaultBackendEnvVar: VaultBackendTransit,
+	}
+	// the wrapped keys are not stored in the cluster namespace
+	_, err := New(clientset, "ns", settings)
+	assert.NotNil(t, err)
+
+	settings[SecretsNamespaceEnvVar] = "ns-keys"
+	keys, err := New(clientset, "ns", settings)
+	require.Nil(t, err)
+
+	assert.Nil(t, keys.PutKey("1234", "key1"))
+	assert.Nil(t, keys.PutKey("5678", "key2"))
+	key, err := keys.GetKey("1234")
+	assert.Nil(t, err)
+	assert.Equal(t, "key1", key)
+
+	// only the wrapped key is stored in the cluster
+	secret, err := clientset.CoreV1().Secrets("ns-keys").Get("rook-ceph-osd-encryption-key-1234", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "vault:v1:a2V5MQ==", string(secret.Data["dmcrypt-key"]))
+
+	// nothing to rewrap until the transit key is rotated
+	count, err := keys.Rewrap()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, count)
+
+	vault.transitVersion = 2
+	count, err = keys.Rewrap()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, count)
+	secret, err = clientset.CoreV1().Secrets("ns-keys").Get("rook-ceph-osd-encryption-key-1234", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "vault:v2:a2V5MQ==", string(secret.Data["dmcrypt-key"]))
+	key, err = keys.GetKey("1234")
+	assert.Nil(t, err)
+	assert.Equal(t, "key1", key)
+}
+
+func TestNewKMS(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+
+	_, err := New(clientset, "ns", map[string]string{})
+	assert.NotNil(t, err)
+	_, err = New(clientset, "ns", map[string]string{ProviderEnvVar: "foo"})
+	assert.NotNil(t, err)
+
+	// vault requires an address and a token
+	_, err = New(clientset, "ns", map[string]string{ProviderEnvVar: ProviderVault, VaultTokenEnvVar: "root"})
+	assert.NotNil(t, err)
+	_, err = New(clientset, "ns", map[string]string{ProviderEnvVar: ProviderVault, VaultAddrEnvVar: "http://vault:8200"})
+	assert.NotNil(t, err)
+	_, err = New(clientset, "ns", map[string]string{ProviderEnvVar: ProviderVault, VaultAddrEnvVar: "http://vault:8200",
+		VaultTokenEnvVar: "root", VaultBackendEnvVar: "foo"})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kms
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// keySecretAppName labels the secrets holding the keys
+	keySecretAppName = "rook-ceph-osd-encryption-key"
+	// keySecretDataKey is the key of the secret data holding the key
+	keySecretDataKey = "dmcrypt-key"
+)
+
+// secretStore stores the keys in Kubernetes secrets, which are held in etcd instead of the ceph cluster
+type secretStore struct {
+	clientset kubernetes.Interface
+	namespace string
+}
+
+func newSecretStore(clientset kubernetes.Interface, namespace string) *secretStore {
+	return &secretStore{clientset: clientset, namespace: namespace}
+}
+
+func (s *secretStore) PutKey(luksUUID, key string) error {
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      KeyName(luksUUID),
+			Namespace: s.namespace,
+			Labels:    map[string]string{k8sutil.AppAttr: keySecretAppName},
+		},
+		Data: map[string][]byte{keySecretDataKey: []byte(key)},
+		Type: k8sutil.RookType,
+	}
+	if _, err := s.clientset.CoreV1().Secrets(s.namespace).Create(secret); err != nil {
+		if !errors.IsAlreadyExists(err) {
+			return fmt.Errorf("failed to create secret %s. %+v", secret.Name, err)
+		}
+		if _, err := s.clientset.CoreV1().Secrets(s.namespace).Update(secret); err != nil {
+			return fmt.Errorf("failed to update secret %s. %+v", secret.Name, err)
+		}
+	}
+	logger.Infof("stored the key of device %s in secret %s", luksUUID, secret.Name)
+	return nil
+}
+
+func (s *secretStore) GetKey(luksUUID string) (string, error) {
+	secret, err := s.clientset.CoreV1().Secrets(s.namespace).Get(KeyName(luksUUID), metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return "", &NotFoundError{LUKSUUID: luksUUID}
+		}
+		return "", fmt.Errorf("failed to get secret %s. %+v", KeyName(luksUUID), err)
+	}
+	key, ok := secret.Data[keySecretDataKey]
+	if !ok {
+		return "", fmt.Errorf("secret %s has no %s", secret.Name, keySecretDataKey)
+	}
+	return string(key), nil
+}
+
+// Rewrap does nothing since the keys in the secrets are not wrapped
+func (s *secretStore) Rewrap() (int, error) {
+	logger.Infof("the keys stored in secrets are not wrapped")
+	return 0, nil
+}
+
+// listKeys returns the LUKS UUIDs of the devices whose keys are stored in the secrets
+func (s *secretStore) listKeys() ([]string, error) {
+	options := metav1.ListOptions{LabelSelector: fmt.Sprintf("%s=%s", k8sutil.AppAttr, keySecretAppName)}
+	secrets, err := s.clientset.CoreV1().Secrets(s.namespace).List(options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the key secrets. %+v", err)
+	}
+	var uuids []string
+	for _, secret := range secrets.Items {
+		uuids = append(uuids, strings.TrimPrefix(secret.Name, keyNamePrefix))
+	}
+	return uuids, nil
+}
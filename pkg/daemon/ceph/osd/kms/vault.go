@@ -0,0 +1,280 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kms
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// VaultAddrEnvVar is the address of the vault server, such as https://vault.example.com:8200
+	VaultAddrEnvVar = "VAULT_ADDR"
+	// VaultTokenEnvVar is the token authenticating the osds to vault
+	VaultTokenEnvVar = "VAULT_TOKEN"
+	// VaultBackendEnvVar is the secrets engine storing the keys, either "kv" (version 2) or "transit"
+	VaultBackendEnvVar = "VAULT_BACKEND"
+	// VaultBackendPathEnvVar is the path where the secrets engine is mounted
+	VaultBackendPathEnvVar = "VAULT_BACKEND_PATH"
+	// VaultTransitKeyEnvVar is the name of the transit key wrapping the keys
+	VaultTransitKeyEnvVar = "VAULT_TRANSIT_KEY"
+	// VaultCACertEnvVar is the path of the CA certificate of the vault server
+	VaultCACertEnvVar = "VAULT_CACERT"
+	// VaultSkipVerifyEnvVar disables the verification of the certificate of the vault server
+	VaultSkipVerifyEnvVar = "VAULT_SKIP_VERIFY"
+
+	// VaultBackendKV stores the keys in the kv secrets engine
+	VaultBackendKV = "kv"
+	// VaultBackendTransit wraps the keys with the transit secrets engine. The wrapped keys are stored in secrets.
+	VaultBackendTransit = "transit"
+
+	defaultVaultKVPath      = "secret"
+	defaultVaultTransitPath = "transit"
+	defaultVaultTransitKey  = "rook-ceph-osd"
+	vaultTimeout            = 30 * time.Second
+)
+
+// vault stores the keys in the kv secrets engine of HashiCorp Vault, or wraps them with its transit secrets engine and
+// stores the wrapped keys in Kubernetes secrets. The wrapped keys cannot be read without vault.
+type vault struct {
+	client     *http.Client
+	address    string
+	token      string
+	backend    string
+	path       string
+	transitKey string
+	secrets    *secretStore
+}
+
+func newVault(clientset kubernetes.Interface, namespace string, settings map[string]string) (*vault, error) {
+	v := &vault{
+		address:    strings.TrimSuffix(settings[VaultAddrEnvVar], "/"),
+		token:      settings[VaultTokenEnvVar],
+		backend:    settings[VaultBackendEnvVar],
+		path:       strings.Trim(settings[VaultBackendPathEnvVar], "/"),
+		transitKey: settings[VaultTransitKeyEnvVar],
+		secrets:    newSecretStore(clientset, namespace),
+	}
+	if v.address == "" {
+		return nil, fmt.Errorf("%s is not set", VaultAddrEnvVar)
+	}
+	if v.token == "" {
+		return nil, fmt.Errorf("%s is not set", VaultTokenEnvVar)
+	}
+
+	switch v.backend {
+	case "", VaultBackendKV:
+		v.backend = VaultBackendKV
+		if v.path == "" {
+			v.path = defaultVaultKVPath
+		}
+	case VaultBackendTransit:
+		if v.path == "" {
+			v.path = defaultVaultTransitPath
+		}
+		if v.transitKey == "" {
+			v.transitKey = defaultVaultTransitKey
+		}
+	default:
+		return nil, fmt.Errorf("unknown vault backend %s", v.backend)
+	}
+
+	tlsConfig := &tls.Config{}
+	if skip, err := strconv.ParseBool(settings[VaultSkipVerifyEnvVar]); err == nil && skip {
+		tlsConfig.InsecureSkipVerify = true
+	}
+	if caFile := settings[VaultCACertEnvVar]; caFile != "" {
+		ca, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the vault CA certificate %s. %+v", caFile, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("invalid vault CA certificate %s", caFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	v.client = &http.Client{Timeout: vaultTimeout, Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+	return v, nil
+}
+
+func (v *vault) PutKey(luksUUID, key string) error {
+	if v.backend == VaultBackendKV {
+		body := map[string]interface{}{"data": map[string]string{keySecretDataKey: key}}
+		if _, err := v.request("POST", v.kvPath(luksUUID), body); err != nil {
+			return fmt.Errorf("failed to store the key of device %s in vault. %+v", luksUUID, err)
+		}
+		logger.Infof("stored the key of device %s in vault", luksUUID)
+		return nil
+	}
+
+	body := map[string]interface{}{"plaintext": base64.StdEncoding.EncodeToString([]byte(key))}
+	data, err := v.request("POST", v.transitPath("encrypt"), body)
+	if err != nil {
+		return fmt.Errorf("failed to wrap the key of device %s with vault. %+v", luksUUID, err)
+	}
+	ciphertext, ok := data["ciphertext"].(string)
+	if !ok {
+		return fmt.Errorf("vault returned no ciphertext for the key of device %s", luksUUID)
+	}
+	return v.secrets.PutKey(luksUUID, ciphertext)
+}
+
+func (v *vault) GetKey(luksUUID string) (string, error) {
+	if v.backend == VaultBackendKV {
+		data, err := v.request("GET", v.kvPath(luksUUID), nil)
+		if err != nil {
+			return "", fmt.Errorf("failed to get the key of device %s from vault. %+v", luksUUID, err)
+		}
+		if data == nil {
+			return "", &NotFoundError{LUKSUUID: luksUUID}
+		}
+		// the kv version 2 engine nests the secret in the data of the response
+		secret, _ := data["data"].(map[string]interface{})
+		key, ok := secret[keySecretDataKey].(string)
+		if !ok {
+			return "", fmt.Errorf("vault returned no key for device %s", luksUUID)
+		}
+		return key, nil
+	}
+
+	ciphertext, err := v.secrets.GetKey(luksUUID)
+	if err != nil {
+		return "", err
+	}
+	data, err := v.request("POST", v.transitPath("decrypt"), map[string]interface{}{"ciphertext": ciphertext})
+	if err != nil {
+		return "", fmt.Errorf("failed to unwrap the key of device %s with vault. %+v", luksUUID, err)
+	}
+	plaintext, ok := data["plaintext"].(string)
+	if !ok {
+		return "", fmt.Errorf("vault returned no plaintext for the key of device %s", luksUUID)
+	}
+	key, err := base64.StdEncoding.DecodeString(plaintext)
+	if err != nil {
+		return "", fmt.Errorf("invalid plaintext for the key of device %s. %+v", luksUUID, err)
+	}
+	return string(key), nil
+}
+
+// Rewrap wraps the keys with the latest version of the transit key, after the transit key was rotated. The keys stored
+// in the kv engine are not wrapped.
+func (v *vault) Rewrap() (int, error) {
+	if v.backend == VaultBackendKV {
+		logger.Infof("the keys stored in the vault kv engine are not wrapped")
+		return 0, nil
+	}
+
+	uuids, err := v.secrets.listKeys()
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, luksUUID := range uuids {
+		ciphertext, err := v.secrets.GetKey(luksUUID)
+		if err != nil {
+			return count, err
+		}
+		data, err := v.request("POST", v.transitPath("rewrap"), map[string]interface{}{"ciphertext": ciphertext})
+		if err != nil {
+			return count, fmt.Errorf("failed to rewrap the key of device %s with vault. %+v", luksUUID, err)
+		}
+		rewrapped, ok := data["ciphertext"].(string)
+		if !ok {
+			return count, fmt.Errorf("vault returned no ciphertext for the key of device %s", luksUUID)
+		}
+		if rewrapped == ciphertext {
+			continue
+		}
+		if err := v.secrets.PutKey(luksUUID, rewrapped); err != nil {
+			return count, err
+		}
+		count++
+	}
+	logger.Infof("rewrapped %d of %d keys with the transit key %s", count, len(uuids), v.transitKey)
+	return count, nil
+}
+
+func (v *vault) kvPath(luksUUID string) string {
+	return fmt.Sprintf("%s/data/%s", v.path, KeyName(luksUUID))
+}
+
+func (v *vault) transitPath(operation string) string {
+	return fmt.Sprintf("%s/%s/%s", v.path, operation, v.transitKey)
+}
+
+// request sends a request to the vault api and returns the data of the response. Nil is returned if vault does not
+// hold the requested path.
+func (v *vault) request(method, path string, body map[string]interface{}) (map[string]interface{}, error) {
+	payload := []byte{}
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return nil, err
+		}
+	}
+
+	req, err := http.NewRequest(method, fmt.Sprintf("%s/v1/%s", v.address, path), bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", v.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotFound && method == "GET" {
+		return nil, nil
+	}
+	if resp.StatusCode == http.StatusNoContent {
+		return map[string]interface{}{}, nil
+	}
+
+	var result struct {
+		Data   map[string]interface{} `json:"data"`
+		Errors []string               `json:"errors"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return nil, fmt.Errorf("invalid vault response with status %d. %+v", resp.StatusCode, err)
+	}
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("vault returned status %d. %s", resp.StatusCode, strings.Join(result.Errors, ", "))
+	}
+	if result.Data == nil {
+		result.Data = map[string]interface{}{}
+	}
+	return result.Data, nil
+}
@@ -41,6 +41,13 @@ func (a *OsdAgent) configureCVDevices(context *clusterd.Context, devices *Device
 	var osds []oposd.OSDInfo
 
 	var err error
+	if a.kms != nil {
+		// open the devices encrypted by rook so that ceph-volume lists their osds
+		if err := openEncryptedDevices(context, a.kms); err != nil {
+			return nil, fmt.Errorf("failed to open the encrypted devices. %+v", err)
+		}
+	}
+
 	if len(devices.Entries) == 0 {
 		logger.Infof("no new devices to configure. returning devices already configured with ceph-volume.")
 		osds, err = getCephVolumeOSDs(context, a.cluster.Name)
@@ -70,7 +77,9 @@ func (a *OsdAgent) initializeDevices(context *clusterd.Context, devices *DeviceO
 	}
 
 	baseArgs := []string{"lvm", "batch", "--prepare", storeFlag, "--yes"}
-	if a.storeConfig.EncryptedDevice {
+	// the devices are encrypted by rook if their keys are held by a kms
+	encryptWithKMS := a.storeConfig.EncryptedDevice && a.kms != nil
+	if a.storeConfig.EncryptedDevice && !encryptWithKMS {
 		baseArgs = append(baseArgs, encryptedFlag)
 	}
 
@@ -93,7 +102,11 @@ func (a *OsdAgent) initializeDevices(context *clusterd.Context, devices *DeviceO
 		if device.Data == -1 {
 			logger.Infof("configuring new device %s", name)
 			deviceArg := path.Join("/dev", name)
-			if metadataDeviceSpecified {
+			if encryptWithKMS {
+				if err := a.initializeEncryptedDevice(context, deviceArg, storeFlag, device.Config.OSDsPerDevice); err != nil {
+					return err
+				}
+			} else if metadataDeviceSpecified {
 				// the device will be configured as a batch at the end of the method
 				batchArgs = append(batchArgs, deviceArg)
 				configured++
@@ -124,6 +137,34 @@ func (a *OsdAgent) initializeDevices(context *clusterd.Context, devices *DeviceO
 	return nil
 }
 
+// initializeEncryptedDevice encrypts the device with a key held by the kms and prepares its osds on logical volumes of
+// the opened device
+func (a *OsdAgent) initializeEncryptedDevice(context *clusterd.Context, device, storeFlag string, osdsPerDevice int) error {
+	if a.storeConfig.StoreType == config.Filestore {
+		return fmt.Errorf("encrypted filestore osds are not supported with a kms")
+	}
+
+	luksUUID, err := encryptDevice(context, a.kms, device)
+	if err != nil {
+		return err
+	}
+	count, _ := strconv.Atoi(sanitizeOSDsPerDevice(osdsPerDevice))
+	volumes, err := createEncryptedVolumes(context, luksUUID, count)
+	if err != nil {
+		wipeEncryptedDevice(context, device, luksUUID)
+		return err
+	}
+
+	for _, volume := range volumes {
+		if err := context.Executor.ExecuteCommand(false, "", cephVolumeCmd, "lvm", "prepare", storeFlag, "--data", volume); err != nil {
+			// the osds already prepared on the device never ran, they are left down in the osd map
+			wipeEncryptedDevice(context, device, luksUUID)
+			return fmt.Errorf("failed ceph-volume on encrypted device %s. %+v", device, err)
+		}
+	}
+	return nil
+}
+
 func sanitizeOSDsPerDevice(count int) string {
 	if count < 1 {
 		count = 1
@@ -168,10 +209,13 @@ func getCephVolumeOSDs(context *clusterd.Context, clusterName string) ([]oposd.O
 			logger.Errorf("bad osd returned from ceph-volume: %s", name)
 			continue
 		}
-		var osdFSID string
+		var osdFSID, luksUUID string
 		isFilestore := false
 		for _, osd := range osdInfo {
 			osdFSID = osd.Tags.OSDFSID
+			if uuid := luksUUIDFromVG(osd.VGName); uuid != "" {
+				luksUUID = uuid
+			}
 			if osd.Type == "journal" {
 				isFilestore = true
 			}
@@ -188,6 +232,7 @@ func getCephVolumeOSDs(context *clusterd.Context, clusterName string) ([]oposd.O
 			UUID:                osdFSID,
 			CephVolumeInitiated: true,
 			IsFileStore:         isFilestore,
+			LUKSUUID:            luksUUID,
 		}
 		osds = append(osds, osd)
 	}
@@ -197,9 +242,11 @@ func getCephVolumeOSDs(context *clusterd.Context, clusterName string) ([]oposd.O
 }
 
 type osdInfo struct {
-	Name string  `json:"name"`
-	Path string  `json:"path"`
-	Tags osdTags `json:"tags"`
+	Name string `json:"name"`
+	Path string `json:"path"`
+	// the volume group of the logical volume
+	VGName string  `json:"vg_name"`
+	Tags   osdTags `json:"tags"`
 	// "data" or "journal" for filestore and "block" for bluestore
 	Type string `json:"type"`
 }
@@ -81,6 +81,10 @@ func updateDeviceCM(context *clusterd.Context) error {
 		logger.Infof("failed to probe devices: %v", err)
 		return err
 	}
+	// the storage devices are not required by the configmap consumers, a failure is only logged
+	if err := updateStorageDevices(context, nodeName, devices); err != nil {
+		logger.Warningf("failed to update storage devices. %+v", err)
+	}
 	deviceJson, err := json.Marshal(devices)
 	if err != nil {
 		logger.Infof("failed to marshal: %v", err)
@@ -139,6 +143,7 @@ func probeDevices(context *clusterd.Context) ([]sys.LocalDisk, error) {
 	if err != nil {
 		return devices, fmt.Errorf("failed initial hardware discovery. %+v", err)
 	}
+	smartctlSupported := true
 	for _, device := range localDevices {
 		if device == nil {
 			continue
@@ -163,6 +168,19 @@ func probeDevices(context *clusterd.Context) ([]sys.LocalDisk, error) {
 		device.Filesystem = fs
 		device.Empty = clusterd.GetDeviceEmpty(device)
 
+		// the health is best effort, smartctl is not able to read all devices
+		if smartctlSupported {
+			health, err := sys.GetDeviceHealth(device.Name, context.Executor)
+			if err == sys.ErrSmartctlUnsupported {
+				logger.Warningf("not reading the health of the devices. %+v", err)
+				smartctlSupported = false
+			} else if err != nil {
+				logger.Debugf("failed to get the health of device %s. %+v", device.Name, err)
+			} else {
+				device.Health = health
+			}
+		}
+
 		devices = append(devices, *device)
 	}
 
@@ -92,6 +92,10 @@ testa2   centos_host13-home
 
 		case "get disk testa fs serial":
 			output = udevOutput
+
+		case "get smart health of testa":
+			output = `{"smartctl":{"exit_status":0},"smart_status":{"passed":true},` +
+				`"ata_smart_attributes":{"table":[{"id":5,"raw":{"value":3}}]}}`
 		}
 
 		return output, nil
@@ -103,5 +107,7 @@ testa2   centos_host13-home
 	assert.Nil(t, err)
 	assert.Equal(t, 1, len(devices))
 	assert.Equal(t, "ext2", devices[0].Filesystem)
-
+	assert.NotNil(t, devices[0].Health)
+	assert.True(t, devices[0].Health.Passed)
+	assert.Equal(t, int64(3), devices[0].Health.ReallocatedSectors)
 }
@@ -0,0 +1,133 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package discover
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	rookalpha "github.com/rook/rook/pkg/apis/rook.io/v1alpha2"
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	"github.com/rook/rook/pkg/util/sys"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// StorageDeviceName returns the name of the StorageDevice resource of a device on a node
+func StorageDeviceName(node, device string) string {
+	return strings.ToLower(fmt.Sprintf("%s-%s", node, strings.Replace(device, "/", "-", -1)))
+}
+
+func newStorageDeviceSpec(node string, disk sys.LocalDisk) rookalpha.StorageDeviceSpec {
+	spec := rookalpha.StorageDeviceSpec{
+		Node:               node,
+		Name:               disk.Name,
+		Parent:             disk.Parent,
+		HasChildren:        disk.HasChildren,
+		DevLinks:           disk.DevLinks,
+		Size:               disk.Size,
+		UUID:               disk.UUID,
+		Serial:             disk.Serial,
+		Type:               disk.Type,
+		Rotational:         disk.Rotational,
+		Readonly:           disk.Readonly,
+		Filesystem:         disk.Filesystem,
+		Vendor:             disk.Vendor,
+		Model:              disk.Model,
+		WWN:                disk.WWN,
+		WWNVendorExtension: disk.WWNVendorExtension,
+		Empty:              disk.Empty,
+	}
+	for _, p := range disk.Partitions {
+		spec.Partitions = append(spec.Partitions, rookalpha.DevicePartition{
+			Name:       p.Name,
+			Size:       p.Size,
+			Label:      p.Label,
+			Filesystem: p.Filesystem,
+		})
+	}
+	if disk.Health != nil {
+		spec.Health = &rookalpha.DeviceHealth{
+			Passed:             disk.Health.Passed,
+			ReallocatedSectors: disk.Health.ReallocatedSectors,
+			PendingSectors:     disk.Health.PendingSectors,
+			MediaErrors:        disk.Health.MediaErrors,
+			PercentageUsed:     disk.Health.PercentageUsed,
+			Temperature:        disk.Health.Temperature,
+		}
+	}
+	return spec
+}
+
+// updateStorageDevices creates or updates a StorageDevice resource for each device of the node and
+// deletes the resources of the devices that are gone. The cluster claiming a device is preserved.
+func updateStorageDevices(context *clusterd.Context, node string, disks []sys.LocalDisk) error {
+	client := context.RookClientset.RookV1alpha2().StorageDevices()
+	listOpts := metav1.ListOptions{LabelSelector: fmt.Sprintf("%s=%s", NodeAttr, node)}
+	existing, err := client.List(listOpts)
+	if err != nil {
+		return fmt.Errorf("failed to list storage devices. %+v", err)
+	}
+	current := map[string]*rookalpha.StorageDevice{}
+	for i := range existing.Items {
+		current[existing.Items[i].Name] = &existing.Items[i]
+	}
+
+	for _, disk := range disks {
+		name := StorageDeviceName(node, disk.Name)
+		spec := newStorageDeviceSpec(node, disk)
+		device, ok := current[name]
+		delete(current, name)
+		if !ok {
+			device = &rookalpha.StorageDevice{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: name,
+					Labels: map[string]string{
+						k8sutil.AppAttr: AppName,
+						NodeAttr:        node,
+					},
+				},
+				Spec: spec,
+			}
+			if _, err := client.Create(device); err != nil {
+				return fmt.Errorf("failed to create storage device %s. %+v", name, err)
+			}
+			logger.Infof("created storage device %s", name)
+			continue
+		}
+
+		spec.ClaimedBy = device.Spec.ClaimedBy
+		if reflect.DeepEqual(device.Spec, spec) {
+			continue
+		}
+		device.Spec = spec
+		if _, err := client.Update(device); err != nil {
+			return fmt.Errorf("failed to update storage device %s. %+v", name, err)
+		}
+		logger.Infof("updated storage device %s", name)
+	}
+
+	// the remaining devices are not attached to the node anymore
+	for name := range current {
+		if err := client.Delete(name, &metav1.DeleteOptions{}); err != nil {
+			return fmt.Errorf("failed to delete storage device %s. %+v", name, err)
+		}
+		logger.Infof("deleted storage device %s", name)
+	}
+	return nil
+}
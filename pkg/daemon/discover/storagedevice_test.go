@@ -0,0 +1,78 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package discover
+
+import (
+	"testing"
+
+	rookfake "github.com/rook/rook/pkg/client/clientset/versioned/fake"
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/util/sys"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestStorageDeviceName(t *testing.T) {
+	assert.Equal(t, "node1-sda", StorageDeviceName("node1", "sda"))
+	assert.Equal(t, "node1-mapper-vg-lv", StorageDeviceName("Node1", "mapper/vg-lv"))
+}
+
+func TestUpdateStorageDevices(t *testing.T) {
+	context := &clusterd.Context{RookClientset: rookfake.NewSimpleClientset()}
+	client := context.RookClientset.RookV1alpha2().StorageDevices()
+	disks := []sys.LocalDisk{
+		{Name: "sda", Size: 1024, Rotational: true, Type: sys.DiskType,
+			Partitions: []sys.Partition{{Name: "sda1", Size: 512, Filesystem: "xfs"}}},
+		{Name: "nvme0n1", Size: 2048, Type: sys.DiskType, Empty: true},
+	}
+
+	// the devices are created
+	err := updateStorageDevices(context, "node1", disks)
+	assert.Nil(t, err)
+	device, err := client.Get("node1-sda", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "node1", device.Spec.Node)
+	assert.Equal(t, uint64(1024), device.Spec.Size)
+	assert.True(t, device.Spec.Rotational)
+	assert.Equal(t, "xfs", device.Spec.Partitions[0].Filesystem)
+	assert.Equal(t, "node1", device.Labels[NodeAttr])
+
+	// the claim is preserved when the device changes
+	device.Spec.ClaimedBy = "rook-ceph"
+	_, err = client.Update(device)
+	assert.Nil(t, err)
+	disks[0].Partitions = nil
+	err = updateStorageDevices(context, "node1", disks)
+	assert.Nil(t, err)
+	device, err = client.Get("node1-sda", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "rook-ceph", device.Spec.ClaimedBy)
+	assert.Equal(t, 0, len(device.Spec.Partitions))
+
+	// a device that is gone is deleted, the devices of other nodes are left alone
+	err = updateStorageDevices(context, "node2", disks[1:])
+	assert.Nil(t, err)
+	err = updateStorageDevices(context, "node1", disks[:1])
+	assert.Nil(t, err)
+	list, err := client.List(metav1.ListOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(list.Items))
+	_, err = client.Get("node1-nvme0n1", metav1.GetOptions{})
+	assert.NotNil(t, err)
+	_, err = client.Get("node2-nvme0n1", metav1.GetOptions{})
+	assert.Nil(t, err)
+}
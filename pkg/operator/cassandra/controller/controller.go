@@ -26,6 +26,7 @@ import (
 	informersv1alpha1 "github.com/rook/rook/pkg/client/informers/externalversions/cassandra.rook.io/v1alpha1"
 	listersv1alpha1 "github.com/rook/rook/pkg/client/listers/cassandra.rook.io/v1alpha1"
 	"github.com/rook/rook/pkg/operator/cassandra/controller/util"
+	"github.com/rook/rook/pkg/operator/metrics"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
@@ -238,7 +239,12 @@ func (cc *ClusterController) processNextWorkItem() bool {
 			cc.queue.Forget(obj)
 			runtime.HandleError(fmt.Errorf("expected string in queue but got %#v", obj))
 		}
-		if err := cc.syncHandler(key); err != nil {
+		start := time.Now()
+		err := cc.syncHandler(key)
+		if namespace, name, splitErr := cache.SplitMetaNamespaceKey(key); splitErr == nil {
+			metrics.ObserveReconcile(controllerName, namespace, name, start, err)
+		}
+		if err != nil {
 			cc.queue.AddRateLimited(key)
 			return fmt.Errorf("error syncing '%s', requeueing: %s", key, err.Error())
 		}
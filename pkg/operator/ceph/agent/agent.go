@@ -23,6 +23,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/coreos/pkg/capnslog"
 	"github.com/rook/rook/pkg/operator/k8sutil"
@@ -44,6 +45,13 @@ const (
 	AgentMountSecurityModeEnv      = "AGENT_MOUNT_SECURITY_MODE"
 	RookEnableSelinuxRelabelingEnv = "ROOK_ENABLE_SELINUX_RELABELING"
 	RookEnableFSGroupEnv           = "ROOK_ENABLE_FSGROUP"
+	RookFencingTimeoutEnv          = "ROOK_FENCING_TIMEOUT"
+
+	// fencingDisabled is the fencing timeout of the agent when none is set, the dead nodes are not fenced
+	fencingDisabled = "0"
+
+	// RBDNbdStateDirPath is the host dir where the agent saves the images mapped with rbd-nbd
+	RBDNbdStateDirPath = "/var/lib/rook/rbd-nbd"
 
 	// MountSecurityModeAny "any" security mode for the agent for mount action
 	MountSecurityModeAny = "Any"
@@ -102,6 +110,15 @@ func (a *Agent) createAgentDaemonSet(namespace, agentImage, serviceAccount strin
 		rookEnableFSGroup = "true"
 	}
 
+	// the fencing is opt-in, the clients of a node are blacklisted only if a timeout is set
+	rookFencingTimeout := os.Getenv(RookFencingTimeoutEnv)
+	if _, err = time.ParseDuration(rookFencingTimeout); err != nil {
+		if rookFencingTimeout != "" {
+			logger.Warningf("Invalid %s value \"%s\". Disabling the fencing.", RookFencingTimeoutEnv, rookFencingTimeout)
+		}
+		rookFencingTimeout = fencingDisabled
+	}
+
 	privileged := true
 	ds := &apps.DaemonSet{
 		ObjectMeta: metav1.ObjectMeta{
@@ -149,6 +166,10 @@ func (a *Agent) createAgentDaemonSet(namespace, agentImage, serviceAccount strin
 									Name:      "libmodules",
 									MountPath: "/lib/modules",
 								},
+								{
+									Name:      "rbd-nbd",
+									MountPath: RBDNbdStateDirPath,
+								},
 							},
 							Env: []v1.EnvVar{
 								k8sutil.NamespaceEnvVar(),
@@ -156,6 +177,7 @@ func (a *Agent) createAgentDaemonSet(namespace, agentImage, serviceAccount strin
 								{Name: AgentMountSecurityModeEnv, Value: agentMountSecurityMode},
 								{Name: RookEnableSelinuxRelabelingEnv, Value: rookEnableSelinuxRelabeling},
 								{Name: RookEnableFSGroupEnv, Value: rookEnableFSGroup},
+								{Name: RookFencingTimeoutEnv, Value: rookFencingTimeout},
 							},
 						},
 					},
@@ -192,6 +214,14 @@ func (a *Agent) createAgentDaemonSet(namespace, agentImage, serviceAccount strin
 								},
 							},
 						},
+						{
+							Name: "rbd-nbd",
+							VolumeSource: v1.VolumeSource{
+								HostPath: &v1.HostPathVolumeSource{
+									Path: RBDNbdStateDirPath,
+								},
+							},
+						},
 					},
 					HostNetwork: true,
 				},
@@ -0,0 +1,379 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cleanup wipes the hosts of a deleted cluster when the cleanup policy is set.
+package cleanup
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/coreos/pkg/capnslog"
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	rookalpha "github.com/rook/rook/pkg/apis/rook.io/v1alpha2"
+	"github.com/rook/rook/pkg/clusterd"
+	opspec "github.com/rook/rook/pkg/operator/ceph/spec"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	batch "k8s.io/api/batch/v1"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kubernetes/pkg/kubelet/apis"
+)
+
+var logger = capnslog.NewPackageLogger("github.com/rook/rook", "op-cleanup")
+
+const (
+	appName            = "rook-ceph-cleanup"
+	jobNameFmt         = "rook-ceph-cleanup-%s"
+	statusMapNameFmt   = "rook-ceph-cleanup-%s-status"
+	statusKey          = "status"
+	serviceAccountName = "rook-ceph-osd"
+	// the osd prepare jobs ran on all the nodes with devices, even if no osd was created
+	prepareAppName = "rook-ceph-osd-prepare"
+
+	rookBinariesMountPath  = "/rook"
+	rookBinariesVolumeName = "rook-binaries"
+	devicesVolumeName      = "devices"
+	udevVolumeName         = "udev"
+	// the secret of the mons with the fsid of the cluster
+	monSecretName = "rook-ceph-mon"
+	fsidSecretKey = "fsid"
+)
+
+var (
+	// the daemons stopped before the hosts are cleaned
+	daemonAppNames = []string{
+		"rook-ceph-mon",
+		"rook-ceph-mgr",
+		"rook-ceph-osd",
+		"rook-ceph-mds",
+		"rook-ceph-rgw",
+		"rook-ceph-nfs",
+		"rook-ceph-rbd-mirror",
+	}
+
+	daemonsStopTimeout = 5 * time.Minute
+	cleanupTimeout     = 20 * time.Minute
+	pollInterval       = 5 * time.Second
+)
+
+// NodeStatus is the result of the cleanup of a node, written to a configmap by the cleanup job
+type NodeStatus struct {
+	Completed     bool     `json:"completed"`
+	Message       string   `json:"message"`
+	ZappedDevices []string `json:"zappedDevices"`
+}
+
+// UpdateNodeStatus saves the result of the cleanup of a node
+func UpdateNodeStatus(kv *k8sutil.ConfigMapKVStore, node string, status NodeStatus) error {
+	labels := map[string]string{
+		k8sutil.AppAttr: appName,
+	}
+	s, err := json.Marshal(status)
+	if err != nil {
+		return fmt.Errorf("failed to marshal cleanup status of node %s. %+v", node, err)
+	}
+	if err := kv.SetValueWithLabels(k8sutil.TruncateNodeName(statusMapNameFmt, node), statusKey, string(s), labels); err != nil {
+		return fmt.Errorf("failed to set cleanup status of node %s. %+v", node, err)
+	}
+	return nil
+}
+
+// Cluster stops the daemons of a deleted cluster and runs a job on each of its nodes to wipe the cluster data
+type Cluster struct {
+	context         *clusterd.Context
+	Namespace       string
+	rookImage       string
+	cephImage       string
+	dataDirHostPath string
+	placement       rookalpha.PlacementSpec
+	ownerRef        metav1.OwnerReference
+}
+
+// New creates the cleaner of the hosts of a cluster
+func New(context *clusterd.Context, namespace, rookImage, cephImage, dataDirHostPath string, placement rookalpha.PlacementSpec, ownerRef metav1.OwnerReference) *Cluster {
+	return &Cluster{
+		context:         context,
+		Namespace:       namespace,
+		rookImage:       rookImage,
+		cephImage:       cephImage,
+		dataDirHostPath: dataDirHostPath,
+		placement:       placement,
+		ownerRef:        ownerRef,
+	}
+}
+
+// Start stops the daemons of the cluster, then starts the jobs wiping its data on the nodes where the daemons ran,
+// found with GetNodes before the daemons are stopped. The result of the jobs is collected with Wait.
+func (c *Cluster) Start(nodes []string) error {
+	if len(nodes) == 0 {
+		logger.Infof("no nodes to clean up for cluster %s", c.Namespace)
+		return nil
+	}
+
+	// the daemons must be stopped before their data and devices are wiped
+	if err := c.stopDaemons(); err != nil {
+		return err
+	}
+
+	for _, node := range nodes {
+		// clear the status of a previous cleanup
+		if err := c.kv().ClearStore(k8sutil.TruncateNodeName(statusMapNameFmt, node)); err != nil {
+			logger.Warningf("failed to clear the cleanup status of node %s. %+v", node, err)
+		}
+		job := c.makeJob(node)
+		logger.Infof("starting cleanup job %s on node %s", job.Name, node)
+		if err := k8sutil.RunReplaceableJob(c.context.Clientset, job); err != nil {
+			return fmt.Errorf("failed to run cleanup job on node %s. %+v", node, err)
+		}
+	}
+	return nil
+}
+
+// Wait waits for the cleanup jobs started on the nodes and returns the result of the cleanup on each node
+func (c *Cluster) Wait(nodes []string) []cephv1.NodeCleanupStatus {
+	return c.waitForNodes(nodes, cleanupTimeout)
+}
+
+// GetNodes returns the nodes where the daemons of the cluster ran. The nodes are found from the pods of the daemons,
+// so they must be saved before the daemons are stopped.
+func (c *Cluster) GetNodes() ([]string, error) {
+	opts := metav1.ListOptions{LabelSelector: fmt.Sprintf("%s in (%s,%s)", k8sutil.AppAttr, strings.Join(daemonAppNames, ","), prepareAppName)}
+	pods, err := c.context.Clientset.CoreV1().Pods(c.Namespace).List(opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the pods of cluster %s. %+v", c.Namespace, err)
+	}
+
+	unique := map[string]bool{}
+	for _, pod := range pods.Items {
+		if pod.Spec.NodeName != "" {
+			unique[pod.Spec.NodeName] = true
+		}
+	}
+	nodes := []string{}
+	for node := range unique {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+	return nodes, nil
+}
+
+// stopDaemons deletes the deployments of the daemons and waits for their pods to terminate
+func (c *Cluster) stopDaemons() error {
+	selector := fmt.Sprintf("%s in (%s)", k8sutil.AppAttr, strings.Join(daemonAppNames, ","))
+	opts := metav1.ListOptions{LabelSelector: selector}
+	deployments, err := c.context.Clientset.AppsV1().Deployments(c.Namespace).List(opts)
+	if err != nil {
+		return fmt.Errorf("failed to list the daemons of cluster %s. %+v", c.Namespace, err)
+	}
+	propagation := metav1.DeletePropagationForeground
+	for _, d := range deployments.Items {
+		logger.Infof("stopping daemon %s", d.Name)
+		err := c.context.Clientset.AppsV1().Deployments(c.Namespace).Delete(d.Name, &metav1.DeleteOptions{PropagationPolicy: &propagation})
+		if err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete deployment %s. %+v", d.Name, err)
+		}
+	}
+
+	for start := time.Now(); ; {
+		pods, err := c.context.Clientset.CoreV1().Pods(c.Namespace).List(opts)
+		if err != nil {
+			return fmt.Errorf("failed to list the daemon pods of cluster %s. %+v", c.Namespace, err)
+		}
+		if len(pods.Items) == 0 {
+			logger.Infof("all the daemons of cluster %s are stopped", c.Namespace)
+			return nil
+		}
+		if time.Since(start) > daemonsStopTimeout {
+			return fmt.Errorf("timed out waiting for %d daemon pods of cluster %s to terminate", len(pods.Items), c.Namespace)
+		}
+		logger.Infof("waiting for %d daemon pods of cluster %s to terminate", len(pods.Items), c.Namespace)
+		<-time.After(pollInterval)
+	}
+}
+
+// waitForNodes collects the status reported by the cleanup job of each node
+func (c *Cluster) waitForNodes(nodes []string, timeout time.Duration) []cephv1.NodeCleanupStatus {
+	results := map[string]cephv1.NodeCleanupStatus{}
+	for start := time.Now(); ; {
+		for _, node := range nodes {
+			if _, ok := results[node]; ok {
+				continue
+			}
+			status, err := c.getNodeStatus(node)
+			if err != nil {
+				logger.Warningf("failed to get the cleanup status of node %s. %+v", node, err)
+				continue
+			}
+			if status == nil {
+				continue
+			}
+			result := cephv1.NodeCleanupStatus{Node: node, Message: status.Message, ZappedDevices: status.ZappedDevices}
+			if status.Completed {
+				result.State = cephv1.NodeCleanupStateCompleted
+				logger.Infof("cleaned up node %s. zapped devices: %v", node, status.ZappedDevices)
+			} else {
+				result.State = cephv1.NodeCleanupStateFailed
+				logger.Errorf("failed to clean up node %s. %s", node, status.Message)
+			}
+			results[node] = result
+		}
+
+		if len(results) == len(nodes) || time.Since(start) > timeout {
+			break
+		}
+		<-time.After(pollInterval)
+	}
+
+	statuses := []cephv1.NodeCleanupStatus{}
+	for _, node := range nodes {
+		result, ok := results[node]
+		if !ok {
+			logger.Errorf("timed out waiting for the cleanup of node %s", node)
+			result = cephv1.NodeCleanupStatus{Node: node, State: cephv1.NodeCleanupStateFailed, Message: "timed out"}
+		}
+		statuses = append(statuses, result)
+	}
+	return statuses
+}
+
+func (c *Cluster) getNodeStatus(node string) (*NodeStatus, error) {
+	store, err := c.kv().GetStore(k8sutil.TruncateNodeName(statusMapNameFmt, node))
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	raw, ok := store[statusKey]
+	if !ok {
+		return nil, nil
+	}
+	var status NodeStatus
+	if err := json.Unmarshal([]byte(raw), &status); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal cleanup status %s. %+v", raw, err)
+	}
+	return &status, nil
+}
+
+func (c *Cluster) kv() *k8sutil.ConfigMapKVStore {
+	return k8sutil.NewConfigMapKVStore(c.Namespace, c.context.Clientset, c.ownerRef)
+}
+
+func (c *Cluster) makeJob(node string) *batch.Job {
+	binariesMount := v1.VolumeMount{Name: rookBinariesVolumeName, MountPath: rookBinariesMountPath}
+	volumes := append(opspec.PodVolumes(c.dataDirHostPath),
+		v1.Volume{Name: rookBinariesVolumeName, VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}}},
+		v1.Volume{Name: devicesVolumeName, VolumeSource: v1.VolumeSource{HostPath: &v1.HostPathVolumeSource{Path: "/dev"}}},
+		v1.Volume{Name: udevVolumeName, VolumeSource: v1.VolumeSource{HostPath: &v1.HostPathVolumeSource{Path: "/run/udev"}}},
+	)
+	volumeMounts := append(opspec.RookVolumeMounts(),
+		binariesMount,
+		v1.VolumeMount{Name: devicesVolumeName, MountPath: "/dev"},
+		v1.VolumeMount{Name: udevVolumeName, MountPath: "/run/udev"},
+	)
+
+	privileged := true
+	runAsUser := int64(0)
+	podSpec := v1.PodSpec{
+		ServiceAccountName: serviceAccountName,
+		// the rook binary is copied to the ceph image which has the tools to zap the devices
+		InitContainers: []v1.Container{
+			{
+				Args:         []string{"ceph", "osd", "copybins"},
+				Name:         "copy-bins",
+				Image:        c.rookImage,
+				VolumeMounts: []v1.VolumeMount{binariesMount},
+				Env:          []v1.EnvVar{{Name: "ROOK_PATH", Value: rookBinariesMountPath}},
+			},
+		},
+		Containers: []v1.Container{
+			{
+				Command:      []string{path.Join(rookBinariesMountPath, "tini")},
+				Args:         []string{"--", path.Join(rookBinariesMountPath, "rook"), "ceph", "clean"},
+				Name:         "cleanup",
+				Image:        c.cephImage,
+				VolumeMounts: volumeMounts,
+				Env: []v1.EnvVar{
+					{Name: "ROOK_NODE_NAME", Value: node},
+					{Name: "ROOK_CLUSTER_NAME", Value: c.Namespace},
+					{Name: "ROOK_CLUSTER_ID", Value: string(c.ownerRef.UID)},
+					// only the devices of the cluster are zapped
+					{Name: "ROOK_FSID", ValueFrom: &v1.EnvVarSource{
+						SecretKeyRef: &v1.SecretKeySelector{
+							LocalObjectReference: v1.LocalObjectReference{Name: monSecretName},
+							Key:                  fsidSecretKey,
+						},
+					}},
+				},
+				SecurityContext: &v1.SecurityContext{
+					Privileged: &privileged,
+					RunAsUser:  &runAsUser,
+				},
+			},
+		},
+		NodeSelector:  map[string]string{apis.LabelHostname: node},
+		Tolerations:   c.tolerations(),
+		RestartPolicy: v1.RestartPolicyOnFailure,
+		Volumes:       volumes,
+		// cryptsetup synchronizes with udev on the host through semaphores
+		HostIPC: true,
+	}
+
+	job := &batch.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      k8sutil.TruncateNodeName(jobNameFmt, node),
+			Namespace: c.Namespace,
+			Labels: map[string]string{
+				k8sutil.AppAttr:     appName,
+				k8sutil.ClusterAttr: c.Namespace,
+			},
+		},
+		Spec: batch.JobSpec{
+			Template: v1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						k8sutil.AppAttr:     appName,
+						k8sutil.ClusterAttr: c.Namespace,
+					},
+				},
+				Spec: podSpec,
+			},
+		},
+	}
+	k8sutil.SetOwnerRef(c.context.Clientset, c.Namespace, &job.ObjectMeta, &c.ownerRef)
+	return job
+}
+
+// tolerations returns the tolerations of all the daemons so the job runs on every node where a daemon ran
+func (c *Cluster) tolerations() []v1.Toleration {
+	keys := []string{}
+	for key := range c.placement {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var tolerations []v1.Toleration
+	for _, key := range keys {
+		tolerations = append(tolerations, c.placement[key].Tolerations...)
+	}
+	return tolerations
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cleanup
+
+import (
+	"testing"
+	"time"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	rookalpha "github.com/rook/rook/pkg/apis/rook.io/v1alpha2"
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	"github.com/rook/rook/pkg/operator/test"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func createPod(t *testing.T, c *Cluster, name, app, node string) {
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: c.Namespace, Labels: map[string]string{k8sutil.AppAttr: app}},
+		Spec:       v1.PodSpec{NodeName: node},
+	}
+	_, err := c.context.Clientset.CoreV1().Pods(c.Namespace).Create(pod)
+	assert.Nil(t, err)
+}
+
+func TestRunCleanupJobs(t *testing.T) {
+	clientset := test.New(3)
+	context := &clusterd.Context{Clientset: clientset}
+	placement := rookalpha.PlacementSpec{
+		"all": {Tolerations: []v1.Toleration{{Key: "storage", Operator: v1.TolerationOpExists}}},
+	}
+	c := New(context, "ns", "rook/rook:myversion", "ceph/ceph:v14", "/var/lib/rook", placement, metav1.OwnerReference{UID: "uid"})
+
+	createPod(t, c, "prepare1", prepareAppName, "node1")
+	createPod(t, c, "prepare2", prepareAppName, "node2")
+	createPod(t, c, "prepare3", prepareAppName, "node2")
+	createPod(t, c, "other", "rook-ceph-tools", "node3")
+
+	nodes, err := c.GetNodes()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"node1", "node2"}, nodes)
+
+	// the jobs don't report their status
+	cleanupTimeout = 0
+	defer func() { cleanupTimeout = 20 * time.Minute }()
+	err = c.Start(nodes)
+	assert.Nil(t, err)
+	results := c.Wait(nodes)
+	assert.Equal(t, 2, len(results))
+	assert.Equal(t, cephv1.NodeCleanupStateFailed, results[0].State)
+	assert.Equal(t, "timed out", results[0].Message)
+
+	job, err := clientset.BatchV1().Jobs("ns").Get("rook-ceph-cleanup-node1", metav1.GetOptions{})
+	assert.Nil(t, err)
+	podSpec := job.Spec.Template.Spec
+	assert.Equal(t, "node1", podSpec.NodeSelector["kubernetes.io/hostname"])
+	assert.Equal(t, "rook/rook:myversion", podSpec.InitContainers[0].Image)
+	assert.Equal(t, "ceph/ceph:v14", podSpec.Containers[0].Image)
+	assert.Equal(t, []string{"--", "/rook/rook", "ceph", "clean"}, podSpec.Containers[0].Args)
+	assert.True(t, *podSpec.Containers[0].SecurityContext.Privileged)
+	assert.Equal(t, 1, len(podSpec.Tolerations))
+	assert.Equal(t, "storage", podSpec.Tolerations[0].Key)
+	assert.Equal(t, v1.EnvVar{Name: "ROOK_NODE_NAME", Value: "node1"}, podSpec.Containers[0].Env[0])
+	assert.Equal(t, "ROOK_FSID", podSpec.Containers[0].Env[3].Name)
+	assert.Equal(t, "rook-ceph-mon", podSpec.Containers[0].Env[3].ValueFrom.SecretKeyRef.Name)
+	_, err = clientset.BatchV1().Jobs("ns").Get("rook-ceph-cleanup-node2", metav1.GetOptions{})
+	assert.Nil(t, err)
+}
+
+func TestWaitForNodes(t *testing.T) {
+	clientset := test.New(3)
+	context := &clusterd.Context{Clientset: clientset}
+	c := New(context, "ns", "rook/rook:myversion", "ceph/ceph:v14", "/var/lib/rook", rookalpha.PlacementSpec{}, metav1.OwnerReference{})
+
+	err := UpdateNodeStatus(c.kv(), "node1", NodeStatus{Completed: true, ZappedDevices: []string{"/dev/sdb"}})
+	assert.Nil(t, err)
+	err = UpdateNodeStatus(c.kv(), "node2", NodeStatus{Completed: false, Message: "failed to zap device /dev/sdc"})
+	assert.Nil(t, err)
+
+	results := c.waitForNodes([]string{"node1", "node2", "node3"}, 0)
+	assert.Equal(t, []cephv1.NodeCleanupStatus{
+		{Node: "node1", State: cephv1.NodeCleanupStateCompleted, ZappedDevices: []string{"/dev/sdb"}},
+		{Node: "node2", State: cephv1.NodeCleanupStateFailed, Message: "failed to zap device /dev/sdc"},
+		{Node: "node3", State: cephv1.NodeCleanupStateFailed, Message: "timed out"},
+	}, results)
+}
@@ -30,16 +30,19 @@ import (
 	"github.com/rook/rook/pkg/clusterd"
 	"github.com/rook/rook/pkg/daemon/ceph/client"
 	cephconfig "github.com/rook/rook/pkg/daemon/ceph/config"
+	"github.com/rook/rook/pkg/operator/ceph/cluster/crash"
 	"github.com/rook/rook/pkg/operator/ceph/cluster/mgr"
 	"github.com/rook/rook/pkg/operator/ceph/cluster/mon"
 	"github.com/rook/rook/pkg/operator/ceph/cluster/osd"
 	"github.com/rook/rook/pkg/operator/ceph/cluster/rbd"
+	"github.com/rook/rook/pkg/operator/ceph/config/keyring"
 	cephver "github.com/rook/rook/pkg/operator/ceph/version"
 	"github.com/rook/rook/pkg/operator/k8sutil"
 	batch "k8s.io/api/batch/v1"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
 )
 
 const (
@@ -54,10 +57,12 @@ type cluster struct {
 	mons                 *mon.Cluster
 	stopCh               chan struct{}
 	ownerRef             metav1.OwnerReference
+	events               *k8sutil.ObjectEventRecorder
 	orchestrationRunning bool
 	orchestrationPending bool
 	orchRunMux           sync.Mutex
 	orchPenMux           sync.Mutex
+	rotateKeysCh         chan struct{}
 }
 
 func newCluster(c *cephv1.CephCluster, context *clusterd.Context) *cluster {
@@ -66,16 +71,23 @@ func newCluster(c *cephv1.CephCluster, context *clusterd.Context) *cluster {
 		// at this phase of the cluster creation process, the identity components of the cluster are
 		// not yet established. we reserve this struct which is filled in as soon as the cluster's
 		// identity can be established.
-		Info:      nil,
-		Namespace: c.Namespace,
-		Spec:      &c.Spec,
-		context:   context,
-		stopCh:    make(chan struct{}),
-		ownerRef:  ownerRef,
-		mons:      mon.New(context, c.Namespace, c.Spec.DataDirHostPath, c.Spec.Network.HostNetwork, ownerRef),
+		Info:         nil,
+		Namespace:    c.Namespace,
+		Spec:         &c.Spec,
+		context:      context,
+		stopCh:       make(chan struct{}),
+		ownerRef:     ownerRef,
+		mons:         mon.New(context, c.Namespace, c.Spec.DataDirHostPath, c.Spec.Network.HostNetwork, ownerRef),
+		rotateKeysCh: make(chan struct{}, 1),
 	}
 }
 
+// setEventRecorder records the events of the orchestration of the cluster and its mons and osds on the cluster CRD
+func (c *cluster) setEventRecorder(recorder record.EventRecorder, clusterObj *cephv1.CephCluster) {
+	c.events = k8sutil.NewObjectEventRecorder(recorder, clusterObj)
+	c.mons.Events = c.events
+}
+
 func (c *cluster) detectCephVersion(image string, timeout time.Duration) (*cephver.CephVersion, error) {
 	// get the major ceph version by running "ceph --version" in the ceph image
 	podSpec := v1.PodSpec{
@@ -185,17 +197,24 @@ func (c *cluster) createInstance(rookImage string, cephVersion cephver.CephVersi
 			return fmt.Errorf("failed to create initial crushmap: %+v", err)
 		}
 
+		// the flex driver maps and mounts the volumes with its own user instead of the admin
+		err = keyring.GetSecretStore(c.context, c.Namespace, &c.ownerRef).Flex().CreateOrUpdate()
+		if err != nil {
+			return fmt.Errorf("failed to create the flex driver keyring. %+v", err)
+		}
+
 		mgrs := mgr.New(c.Info, c.context, c.Namespace, rookImage,
 			spec.CephVersion, cephv1.GetMgrPlacement(spec.Placement), spec.Network.HostNetwork,
 			spec.Dashboard, cephv1.GetMgrResources(spec.Resources), c.ownerRef)
+		mgrs.Override = cephv1.GetMgrOverride(spec.DaemonOverrides)
+		mgrs.DataDirHostPath = spec.DataDirHostPath
 		err = mgrs.Start()
 		if err != nil {
 			return fmt.Errorf("failed to start the ceph mgr. %+v", err)
 		}
 
 		// Start the OSDs
-		osds := osd.New(c.context, c.Namespace, rookImage, spec.CephVersion, spec.Storage, spec.DataDirHostPath,
-			cephv1.GetOSDPlacement(spec.Placement), spec.Network.HostNetwork, cephv1.GetOSDResources(spec.Resources), c.ownerRef)
+		osds := c.newOSDs(rookImage, spec)
 		err = osds.Start()
 		if err != nil {
 			return fmt.Errorf("failed to start the osds. %+v", err)
@@ -204,17 +223,73 @@ func (c *cluster) createInstance(rookImage string, cephVersion cephver.CephVersi
 		// Start the rbd mirroring daemon(s)
 		rbdmirror := rbd.New(c.Info, c.context, c.Namespace, rookImage, spec.CephVersion, cephv1.GetRBDMirrorPlacement(spec.Placement),
 			spec.Network.HostNetwork, spec.RBDMirroring, cephv1.GetRBDMirrorResources(spec.Resources), c.ownerRef)
+		rbdmirror.Override = cephv1.GetRBDMirrorOverride(spec.DaemonOverrides)
+		rbdmirror.DataDirHostPath = spec.DataDirHostPath
 		err = rbdmirror.Start()
 		if err != nil {
 			return fmt.Errorf("failed to start the rbd mirrors. %+v", err)
 		}
 
+		// Start the crash collectors
+		collector := crash.New(c.Info, c.context, c.Namespace, spec.CephVersion, cephv1.GetCrashCollectorPlacement(spec.Placement),
+			spec.Network.HostNetwork, spec.DataDirHostPath, spec.CrashCollector, c.ownerRef)
+		collector.Override = cephv1.GetCrashCollectorOverride(spec.DaemonOverrides)
+		err = collector.Start()
+		if err != nil {
+			return fmt.Errorf("failed to start the crash collectors. %+v", err)
+		}
+
+		err = c.restartConnectionClients()
+		if err != nil {
+			return fmt.Errorf("failed to restart the daemons with the new connection modes. %+v", err)
+		}
+
 		logger.Infof("Done creating rook instance in namespace %s", c.Namespace)
 	}
 
 	return nil
 }
 
+// updateNodeLocation updates the crush location of the osds of a node whose topology labels changed, without orchestrating
+// the other nodes. If an orchestration is running, it is run once more instead to derive the new location.
+func (c *cluster) updateNodeLocation(rookImage, nodeName string) error {
+	if c.checkSetOrchestrationRunning() {
+		logger.Debugf("orchestration is running. updating the crush location of node %s with the pending orchestration", nodeName)
+		c.setOrchestrationPending()
+		return nil
+	}
+
+	err := c.newOSDs(rookImage, c.Spec.DeepCopy()).UpdateNodeLocation(nodeName)
+	c.unsetOrchestrationRunning()
+	if c.checkUnsetOrchestrationPending() {
+		// an orchestration was requested while the osds of the node were updated
+		if err := c.createInstance(rookImage, c.Info.CephVersion); err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+func (c *cluster) newOSDs(rookImage string, spec *cephv1.ClusterSpec) *osd.Cluster {
+	osds := osd.New(c.context, c.Namespace, rookImage, spec.CephVersion, spec.Storage, spec.DataDirHostPath,
+		cephv1.GetOSDPlacement(spec.Placement), spec.Network.HostNetwork, cephv1.GetOSDResources(spec.Resources), c.ownerRef)
+	osds.Events = c.events
+	osds.CrushTopology = spec.CrushTopology
+	osds.Override = cephv1.GetOSDOverride(spec.DaemonOverrides)
+	osds.KMS = spec.Security.KeyManagementService
+	return osds
+}
+
+// restoreMonQuorum restores the quorum of the mons from the surviving mon
+func (c *cluster) restoreMonQuorum(rookImage, survivor string, cephVersion cephver.CephVersion) error {
+	clusterInfo, err := c.mons.RestoreQuorum(survivor, rookImage, cephVersion, *c.Spec)
+	if err != nil {
+		return fmt.Errorf("failed to restore the mon quorum from mon %s. %+v", survivor, err)
+	}
+	c.Info = clusterInfo
+	return nil
+}
+
 func (c *cluster) createInitialCrushMap() error {
 	configMapExists := false
 	createCrushMap := false
@@ -0,0 +1,79 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/rook/rook/pkg/operator/ceph/cluster/mon"
+	"github.com/rook/rook/pkg/operator/ceph/config"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// restartConnectionClients restarts the daemons reading the stored config when its connection modes changed since the
+// daemons were last restarted, since they only read the modes when they start. The mons are restarted first, then the
+// other daemons, one at a time, and each daemon is ready before the next one is restarted. The restart is run again at
+// the next orchestration if a daemon fails to restart.
+func (c *cluster) restartConnectionClients() error {
+	s := config.GetStore(c.context, c.Namespace, &c.ownerRef)
+	hash, pending, err := s.PendingConnections()
+	if err != nil {
+		return err
+	}
+	if !pending {
+		return nil
+	}
+
+	deployments, err := c.context.Clientset.Apps().Deployments(c.Namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list deployments. %+v", err)
+	}
+	var mons, daemons []string
+	for _, d := range deployments.Items {
+		if !usesStoredConfig(d.Spec.Template.Spec) {
+			continue
+		}
+		if d.Labels[k8sutil.AppAttr] == mon.AppName {
+			mons = append(mons, d.Name)
+		} else {
+			daemons = append(daemons, d.Name)
+		}
+	}
+	sort.Strings(mons)
+	sort.Strings(daemons)
+
+	logger.Infof("restarting the daemons of cluster %s with the new connection modes", c.Namespace)
+	for _, name := range append(mons, daemons...) {
+		if err := restartDeployment(c.context.Clientset, c.Namespace, name); err != nil {
+			return fmt.Errorf("failed to restart deployment %s with the new connection modes. %+v", name, err)
+		}
+	}
+	return s.SetConnectionsApplied(hash)
+}
+
+func usesStoredConfig(spec v1.PodSpec) bool {
+	configMapName := config.StoredFileVolume().ConfigMap.Name
+	for _, volume := range spec.Volumes {
+		if volume.ConfigMap != nil && volume.ConfigMap.Name == configMapName {
+			return true
+		}
+	}
+	return false
+}
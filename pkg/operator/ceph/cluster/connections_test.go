@@ -0,0 +1,91 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"fmt"
+	"testing"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/operator/ceph/cluster/mon"
+	"github.com/rook/rook/pkg/operator/ceph/config"
+	cephver "github.com/rook/rook/pkg/operator/ceph/version"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	testop "github.com/rook/rook/pkg/operator/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apps "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+func TestRestartConnectionClients(t *testing.T) {
+	clientset := testop.New(1)
+	context := &clusterd.Context{Clientset: clientset}
+	c := newCluster(&cephv1.CephCluster{ObjectMeta: metav1.ObjectMeta{Namespace: "ns"}}, context)
+
+	for _, name := range []string{"rook-ceph-osd-0", "rook-ceph-mon-b", "rook-ceph-mon-a", "rook-ceph-mgr-a", "rook-ceph-tools"} {
+		d := &apps.Deployment{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns"}}
+		if name == "rook-ceph-mon-a" || name == "rook-ceph-mon-b" {
+			d.Labels = map[string]string{k8sutil.AppAttr: mon.AppName}
+		}
+		// the toolbox does not read the stored config
+		if name != "rook-ceph-tools" {
+			d.Spec.Template.Spec.Volumes = []v1.Volume{config.StoredFileVolume()}
+		}
+		_, err := clientset.Apps().Deployments("ns").Create(d)
+		require.Nil(t, err)
+	}
+
+	var restarted []string
+	restartDeployment = func(clientset kubernetes.Interface, namespace, name string) error {
+		restarted = append(restarted, name)
+		return nil
+	}
+	defer func() { restartDeployment = k8sutil.RestartDeploymentAndWait }()
+
+	// the daemons started with the first stored config are not restarted
+	s := config.GetStore(context, "ns", &c.ownerRef)
+	info := testop.CreateConfigDir(1)
+	info.CephVersion = cephver.Nautilus
+	require.Nil(t, s.CreateOrUpdate(info, cephv1.ConnectionsSpec{}))
+	assert.Nil(t, c.restartConnectionClients())
+	assert.Equal(t, 0, len(restarted))
+
+	// the mons are restarted first with new modes
+	require.Nil(t, s.CreateOrUpdate(info, cephv1.ConnectionsSpec{ClusterMode: "secure"}))
+	assert.Nil(t, c.restartConnectionClients())
+	assert.Equal(t, []string{"rook-ceph-mon-a", "rook-ceph-mon-b", "rook-ceph-mgr-a", "rook-ceph-osd-0"}, restarted)
+	assert.Nil(t, c.restartConnectionClients())
+	assert.Equal(t, 4, len(restarted))
+
+	// the restart is retried until all the daemons are restarted with the modes
+	restarted = nil
+	restartDeployment = func(clientset kubernetes.Interface, namespace, name string) error {
+		restarted = append(restarted, name)
+		if name == "rook-ceph-mgr-a" {
+			return fmt.Errorf("mock restart failure")
+		}
+		return nil
+	}
+	require.Nil(t, s.CreateOrUpdate(info, cephv1.ConnectionsSpec{ClusterMode: "secure", RequireMsgr2: true}))
+	assert.NotNil(t, c.restartConnectionClients())
+	assert.NotNil(t, c.restartConnectionClients())
+	assert.Equal(t, []string{"rook-ceph-mon-a", "rook-ceph-mon-b", "rook-ceph-mgr-a", "rook-ceph-mon-a", "rook-ceph-mon-b", "rook-ceph-mgr-a"}, restarted)
+}
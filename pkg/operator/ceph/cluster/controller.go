@@ -21,22 +21,30 @@ import (
 	"fmt"
 	"os"
 	"reflect"
+	"sync"
 	"time"
 
 	"github.com/coreos/pkg/capnslog"
 	opkit "github.com/rook/operator-kit"
 	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
 	cephbeta "github.com/rook/rook/pkg/apis/ceph.rook.io/v1beta1"
+	rookScheme "github.com/rook/rook/pkg/client/clientset/versioned/scheme"
 	"github.com/rook/rook/pkg/clusterd"
 	"github.com/rook/rook/pkg/daemon/ceph/agent/flexvolume/attachment"
+	"github.com/rook/rook/pkg/operator/ceph/cluster/cleanup"
 	"github.com/rook/rook/pkg/operator/ceph/cluster/mon"
 	"github.com/rook/rook/pkg/operator/ceph/cluster/osd"
+	dashboarduser "github.com/rook/rook/pkg/operator/ceph/dashboard/user"
 	"github.com/rook/rook/pkg/operator/ceph/file"
 	"github.com/rook/rook/pkg/operator/ceph/nfs"
 	"github.com/rook/rook/pkg/operator/ceph/object"
 	objectuser "github.com/rook/rook/pkg/operator/ceph/object/user"
 	"github.com/rook/rook/pkg/operator/ceph/pool"
+	"github.com/rook/rook/pkg/operator/ceph/reconcile"
+	cephver "github.com/rook/rook/pkg/operator/ceph/version"
+	"github.com/rook/rook/pkg/operator/discover"
 	"github.com/rook/rook/pkg/operator/k8sutil"
+	"github.com/rook/rook/pkg/operator/metrics"
 	v1 "k8s.io/api/core/v1"
 	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -44,7 +52,9 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/kubernetes/pkg/kubelet/apis"
 )
 
@@ -62,6 +72,11 @@ const (
 	DefaultClusterName         = "rook-ceph"
 	clusterDeleteRetryInterval = 2 //seconds
 	clusterDeleteMaxRetries    = 15
+	// PausedAnnotation stops the orchestration of a cluster when set to "true". The daemons keep running and the
+	// cluster is orchestrated again when the annotation is removed.
+	PausedAnnotation = "ceph.rook.io/paused"
+	pausedReason     = "Paused"
+	resumedReason    = "Resumed"
 )
 
 var (
@@ -95,30 +110,41 @@ type ClusterController struct {
 	devicesInUse     bool
 	rookImage        string
 	clusterMap       map[string]*cluster
+	recorder         record.EventRecorder
+	// handlerLock serializes the events of the clusters when several namespaces are watched
+	handlerLock      sync.Mutex
+	namespaceStopChs map[string]chan struct{}
+	namespaceLock    sync.Mutex
+	// the namespaces of the deleted clusters whose hosts are being cleaned up, protected by the handler lock
+	cleanups map[string]bool
+	// the nodes whose topology labels changed, queued by cluster namespace so the node informer is not blocked
+	nodeLocationQueue *reconcile.Queue
 }
 
 // NewClusterController create controller for watching cluster custom resources created
 func NewClusterController(context *clusterd.Context, rookImage string, volumeAttachment attachment.Attachment) *ClusterController {
-	return &ClusterController{
+	// add the rook types to the default scheme so events can be recorded on the pools, filesystems and object stores
+	rookScheme.AddToScheme(scheme.Scheme)
+	c := &ClusterController{
 		context:          context,
 		volumeAttachment: volumeAttachment,
 		rookImage:        rookImage,
 		clusterMap:       make(map[string]*cluster),
+		recorder:         k8sutil.NewEventRecorder(context.Clientset, "rook-ceph-operator"),
+		namespaceStopChs: make(map[string]chan struct{}),
+		cleanups:         make(map[string]bool),
 	}
+	c.nodeLocationQueue = reconcile.New("node-location", c.reconcileNodeLocation)
+	return c
 }
 
-// Watch watches instances of cluster resources
-func (c *ClusterController) StartWatch(namespace string, stopCh chan struct{}) error {
-	resourceHandlerFuncs := cache.ResourceEventHandlerFuncs{
-		AddFunc:    c.onAdd,
-		UpdateFunc: c.onUpdate,
-		DeleteFunc: c.onDelete,
+// StartWatch watches the clusters in the namespaces selected by WatchNamespaces or WatchNamespaceSelector, or in all
+// the namespaces if none are selected
+func (c *ClusterController) StartWatch(stopCh chan struct{}) error {
+	if len(WatchNamespaces) > 0 && WatchNamespaceSelector != "" {
+		return fmt.Errorf("the watched namespaces %v and the namespace selector %q are mutually exclusive", WatchNamespaces, WatchNamespaceSelector)
 	}
 
-	logger.Infof("start watching clusters in all namespaces")
-	watcher := opkit.NewWatcher(ClusterResource, namespace, resourceHandlerFuncs, c.context.RookClientset.CephV1().RESTClient())
-	go watcher.Watch(&cephv1.CephCluster{}, stopCh)
-
 	// watch for events on new/updated K8s nodes, too
 
 	lwNodes := &cache.ListWatch{
@@ -141,14 +167,53 @@ func (c *ClusterController) StartWatch(namespace string, stopCh chan struct{}) e
 		},
 	)
 	go nodeController.Run(stopCh)
+	c.nodeLocationQueue.Start(stopCh)
+
+	switch {
+	case len(WatchNamespaces) > 0:
+		for _, namespace := range WatchNamespaces {
+			logger.Infof("start watching clusters in namespace %s", namespace)
+			c.watchClusters(namespace, stopCh)
+		}
+	case WatchNamespaceSelector != "":
+		return c.watchNamespaces(stopCh)
+	default:
+		logger.Infof("start watching clusters in all namespaces")
+		c.watchClusters(v1.NamespaceAll, stopCh)
+	}
+	return nil
+}
+
+func (c *ClusterController) watchClusters(namespace string, stopCh chan struct{}) {
+	// handle the events one at a time as a single watcher would, even if several namespaces are watched
+	resourceHandlerFuncs := cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			c.handlerLock.Lock()
+			defer c.handlerLock.Unlock()
+			c.onAdd(obj)
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			c.handlerLock.Lock()
+			defer c.handlerLock.Unlock()
+			c.onUpdate(oldObj, newObj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			c.handlerLock.Lock()
+			defer c.handlerLock.Unlock()
+			c.onDelete(obj)
+		},
+	}
+
+	watcher := opkit.NewWatcher(ClusterResource, namespace, resourceHandlerFuncs, c.context.RookClientset.CephV1().RESTClient())
+	go watcher.Watch(&cephv1.CephCluster{}, stopCh)
 
 	// watch for events on all legacy types too
 	c.watchLegacyClusters(namespace, stopCh, resourceHandlerFuncs)
-
-	return nil
 }
 
+// StopWatch stops the clusters and the watchers of the selected namespaces
 func (c *ClusterController) StopWatch() {
+	c.stopNamespaces()
 	for _, cluster := range c.clusterMap {
 		close(cluster.stopCh)
 	}
@@ -207,7 +272,13 @@ func (c *ClusterController) onAdd(obj interface{}) {
 		return
 	}
 
+	if isPaused(clusterObj) {
+		logger.Infof("not starting cluster in namespace %s. the orchestration is paused by the annotation %s", clusterObj.Namespace, PausedAnnotation)
+		return
+	}
+
 	cluster := newCluster(clusterObj, c.context)
+	cluster.setEventRecorder(c.recorder, clusterObj)
 	c.clusterMap[cluster.Namespace] = cluster
 
 	logger.Infof("starting cluster in namespace %s", cluster.Namespace)
@@ -215,6 +286,7 @@ func (c *ClusterController) onAdd(obj interface{}) {
 	if c.devicesInUse && cluster.Spec.Storage.AnyUseAllDevices() {
 		message := "using all devices in more than one namespace not supported"
 		logger.Error(message)
+		cluster.events.Warningf(reconcile.ReasonReconcileFailed, "%s", message)
 		if err := c.updateClusterStatus(clusterObj.Namespace, clusterObj.Name, cephv1.ClusterStateError, message); err != nil {
 			logger.Errorf("failed to update cluster status in namespace %s: %+v", cluster.Namespace, err)
 		}
@@ -241,26 +313,33 @@ func (c *ClusterController) onAdd(obj interface{}) {
 	cephVersion, err := cluster.detectCephVersion(cluster.Spec.CephVersion.Image, 15*time.Minute)
 	if err != nil {
 		logger.Errorf("unknown ceph major version. %+v", err)
+		cluster.events.Warningf(reconcile.ReasonReconcileFailed, "failed to detect the ceph version of image %s. %+v", cluster.Spec.CephVersion.Image, err)
 		return
 	}
 
 	if !cluster.Spec.CephVersion.AllowUnsupported {
 		if !cephVersion.Supported() {
 			logger.Errorf("unsupported ceph version detected: %s. allowUnsupported must be set to true to run with this version.", cephVersion)
+			cluster.events.Warningf(reconcile.ReasonReconcileFailed, "unsupported ceph version %s. allowUnsupported must be set to true to run with this version", cephVersion)
 			return
 		}
 	}
 
 	// Start the Rook cluster components. Retry several times in case of failure.
+	start := time.Now()
 	err = wait.Poll(clusterCreateInterval, clusterCreateTimeout, func() (bool, error) {
 		if err := c.updateClusterStatus(clusterObj.Namespace, clusterObj.Name, cephv1.ClusterStateCreating, ""); err != nil {
 			logger.Errorf("failed to update cluster status in namespace %s: %+v", cluster.Namespace, err)
 			return false, nil
 		}
 
+		// the mons can't be started if their quorum was lost while the operator was down
+		c.restoreMonQuorum(cluster, clusterObj.Name, *cephVersion)
+
 		err := cluster.createInstance(c.rookImage, *cephVersion)
 		if err != nil {
 			logger.Errorf("failed to create cluster in namespace %s. %+v", cluster.Namespace, err)
+			cluster.events.Warningf(reconcile.ReasonReconcileFailed, "failed to create cluster. retrying. %+v", err)
 			return false, nil
 		}
 
@@ -272,43 +351,60 @@ func (c *ClusterController) onAdd(obj interface{}) {
 
 		return true, nil
 	})
+	metrics.ObserveReconcile(ClusterResource.Name, clusterObj.Namespace, clusterObj.Name, start, err)
 	if err != nil {
 		message := fmt.Sprintf("giving up creating cluster in namespace %s after %s", cluster.Namespace, clusterCreateTimeout)
 		logger.Error(message)
+		cluster.events.Warningf(reconcile.ReasonReconcileFailed, "%s", message)
 		if err := c.updateClusterStatus(clusterObj.Namespace, clusterObj.Name, cephv1.ClusterStateError, message); err != nil {
 			logger.Errorf("failed to update cluster status in namespace %s: %+v", cluster.Namespace, err)
 		}
 		return
 	}
+	cluster.events.Normalf(reconcile.ReasonReconciled, "created cluster with ceph version %s", cephVersion)
 
 	// Start pool CRD watcher
-	poolController := pool.NewPoolController(c.context)
+	poolController := pool.NewPoolController(c.context, c.volumeAttachment, c.recorder)
 	poolController.StartWatch(cluster.Namespace, cluster.stopCh)
 
 	// Start object store CRD watcher
-	objectStoreController := object.NewObjectStoreController(cluster.Info, c.context, c.rookImage, cluster.Spec.CephVersion, cluster.Spec.Network.HostNetwork, cluster.ownerRef)
+	objectStoreController := object.NewObjectStoreController(cluster.Info, c.context, c.rookImage, cluster.Spec.CephVersion, cluster.Spec.Network.HostNetwork, cluster.Spec.DataDirHostPath, cluster.ownerRef, c.recorder)
 	objectStoreController.StartWatch(cluster.Namespace, cluster.stopCh)
 
 	// Start object store user CRD watcher
-	objectStoreUserController := objectuser.NewObjectStoreUserController(c.context, cluster.ownerRef)
+	objectStoreUserController := objectuser.NewObjectStoreUserController(c.context, cluster.ownerRef, c.recorder)
 	objectStoreUserController.StartWatch(cluster.Namespace, cluster.stopCh)
 
+	// Start dashboard user CRD watcher
+	dashboardUserController := dashboarduser.NewDashboardUserController(cluster.Info, c.context)
+	dashboardUserController.StartWatch(cluster.Namespace, cluster.stopCh)
+
 	// Start file system CRD watcher
-	fileController := file.NewFilesystemController(cluster.Info, c.context, c.rookImage, cluster.Spec.CephVersion, cluster.Spec.Network.HostNetwork, cluster.ownerRef)
+	fileController := file.NewFilesystemController(cluster.Info, c.context, c.rookImage, cluster.Spec.CephVersion, cluster.Spec.Network.HostNetwork, cluster.Spec.DataDirHostPath, cluster.ownerRef, c.recorder)
 	fileController.StartWatch(cluster.Namespace, cluster.stopCh)
 
 	// Start nfs ganesha CRD watcher
-	ganeshaController := nfs.NewCephNFSController(cluster.Info, c.context, c.rookImage, cluster.Spec.CephVersion, cluster.Spec.Network.HostNetwork, cluster.ownerRef)
+	ganeshaController := nfs.NewCephNFSController(cluster.Info, c.context, c.rookImage, cluster.Spec.CephVersion, cluster.Spec.Network.HostNetwork, cluster.ownerRef, c.recorder)
 	ganeshaController.StartWatch(cluster.Namespace, cluster.stopCh)
 
+	// Start nfs ganesha export CRD watcher
+	ganeshaExportController := nfs.NewCephNFSExportController(cluster.Info, c.context)
+	ganeshaExportController.StartWatch(cluster.Namespace, cluster.stopCh)
+
 	// Start mon health checker
 	healthChecker := mon.NewHealthChecker(cluster.mons)
 	go healthChecker.Check(cluster.stopCh)
 
 	// Start the osd health checker
-	osdChecker := osd.NewMonitor(c.context, cluster.Namespace)
+	osdChecker := osd.NewMonitor(c.context, cluster.Namespace, c.recorder)
 	go osdChecker.Start(cluster.stopCh)
 
+	// Start the scheduled rotation of the cephx keys
+	go c.checkKeyRotation(cluster, clusterObj.Name)
+
+	// Start summarising the crashes of the daemons in the status
+	go c.checkCrashes(cluster, clusterObj.Name)
+
 	// add the finalizer to the crd
 	err = c.addFinalizer(clusterObj)
 	if err != nil {
@@ -338,6 +434,11 @@ func (c *ClusterController) onK8sNodeUpdate(oldObj, newObj interface{}) {
 		return
 	}
 
+	// a node becoming schedulable is orchestrated below, which also derives the crush location of its osds
+	if !k8sutil.GetNodeSchedulable(*newNode) || k8sutil.GetNodeSchedulable(*oldNode) {
+		c.onK8sNodeLocationUpdate(oldNode, newNode)
+	}
+
 	if k8sutil.GetNodeSchedulable(*newNode) == false {
 		logger.Debugf("Skipping cluster update. Updated node %s is unschedulable", newNode.Labels[apis.LabelHostname])
 		return
@@ -365,6 +466,40 @@ func (c *ClusterController) onK8sNodeUpdate(oldObj, newObj interface{}) {
 	}
 }
 
+// onK8sNodeLocationUpdate queues the update of the crush location of the osds of a node in the clusters storing on the
+// node when its topology labels changed. The osd deployments are updated from the queue since waiting for their
+// rollout would block the events of the other nodes.
+func (c *ClusterController) onK8sNodeLocationUpdate(oldNode, newNode *v1.Node) {
+	nodeName := newNode.Labels[apis.LabelHostname]
+	for _, cluster := range c.clusterMap {
+		if !osd.TopologyChanged(cluster.Spec.CrushTopology, oldNode.Labels, newNode.Labels) {
+			continue
+		}
+		if !osd.IsStorageNode(cluster.Spec.Storage, nodeName) {
+			logger.Debugf("skipping the topology update of node %s. it is not a storage node of cluster %s", nodeName, cluster.Namespace)
+			continue
+		}
+		logger.Infof("topology labels of node %s changed. queuing the update of the crush location of its osds in cluster %s", nodeName, cluster.Namespace)
+		c.nodeLocationQueue.Add(cache.ExplicitKey(cluster.Namespace + "/" + nodeName))
+	}
+}
+
+// reconcileNodeLocation moves the osds of a node in the crush map of the cluster in the given namespace. The labels of
+// the node are read again, so the resync repairs a location changed outside of the operator.
+func (c *ClusterController) reconcileNodeLocation(namespace, nodeName string) error {
+	c.handlerLock.Lock()
+	cluster, ok := c.clusterMap[namespace]
+	c.handlerLock.Unlock()
+	if !ok {
+		logger.Debugf("skipping the crush location update of node %s. cluster %s is not running", nodeName, namespace)
+		return nil
+	}
+	if err := cluster.updateNodeLocation(c.rookImage, nodeName); err != nil {
+		return fmt.Errorf("failed to update the crush location of the osds of node %s in cluster %s. %+v", nodeName, namespace, err)
+	}
+	return nil
+}
+
 func (c *ClusterController) onUpdate(oldObj, newObj interface{}) {
 	oldClust, _, err := getClusterObject(oldObj)
 	if err != nil {
@@ -408,16 +543,56 @@ func (c *ClusterController) onUpdate(oldObj, newObj interface{}) {
 			logger.Errorf("failed finalizer for cluster. %+v", err)
 			return
 		}
+		if newClust.Spec.CleanupPolicy.HasDataDirCleanPolicy() {
+			if !c.cleanupHosts(newClust) {
+				// the finalizer is removed on the update event of the cleanup results in the status
+				return
+			}
+		} else if newClust.Spec.CleanupPolicy.Confirmation != "" {
+			logger.Warningf("not cleaning up the hosts of cluster %s. the cleanup confirmation must be %q", newClust.Namespace, cephv1.CleanupConfirmation)
+		}
+		// the pools, filesystems and object stores of a deleted cluster must not block their own deletion
+		c.removeChildFinalizers(newClust.Namespace)
+		// the devices of the cleaned up hosts can be used by another cluster
+		discover.ReleaseStorageDevices(c.context, newClust.Namespace)
+
 		// remove the finalizer from the crd, which indicates to k8s that the resource can safely be deleted
 		c.removeFinalizer(newClust)
 		return
 	}
+
+	if isPaused(newClust) {
+		if _, ok := c.clusterMap[newClust.Namespace]; ok {
+			logger.Infof("pausing the orchestration of cluster %s", newClust.Namespace)
+			k8sutil.NewObjectEventRecorder(c.recorder, newClust).Normalf(pausedReason, "paused the orchestration of the cluster with the annotation %s", PausedAnnotation)
+			c.stopCluster(newClust.Namespace)
+			if newClust.Spec.Storage.AnyUseAllDevices() {
+				c.devicesInUse = false
+			}
+		}
+		return
+	}
+	if _, ok := c.clusterMap[newClust.Namespace]; !ok && isPaused(oldClust) {
+		logger.Infof("resuming the orchestration of cluster %s", newClust.Namespace)
+		k8sutil.NewObjectEventRecorder(c.recorder, newClust).Normalf(resumedReason, "resumed the orchestration of the cluster")
+		c.onAdd(newObj)
+		return
+	}
+
 	cluster, ok := c.clusterMap[newClust.Namespace]
 	if !ok {
 		logger.Errorf("Cannot update cluster %s that does not exist", newClust.Namespace)
 		return
 	}
 
+	if cluster.Info != nil && c.restoreMonQuorum(cluster, newClust.Name, cluster.Info.CephVersion) {
+		return
+	}
+	if _, ok := newClust.Annotations[RotateKeysAnnotation]; ok {
+		// the rotation restarts the daemons, so it runs with the scheduled rotations instead of blocking the other events
+		cluster.requestKeyRotation()
+	}
+
 	changed, _ := clusterChanged(oldClust.Spec, newClust.Spec, cluster)
 	if !changed {
 		logger.Infof("update event for cluster %s is not supported", newClust.Namespace)
@@ -432,6 +607,7 @@ func (c *ClusterController) onUpdate(oldObj, newObj interface{}) {
 		version, err := cluster.detectCephVersion(newClust.Spec.CephVersion.Image, 15*time.Minute)
 		if err != nil {
 			logger.Errorf("unknown ceph major version. %+v", err)
+			cluster.events.Warningf(reconcile.ReasonReconcileFailed, "failed to detect the ceph version of image %s. %+v", newClust.Spec.CephVersion.Image, err)
 			return
 		}
 		cluster.Info.CephVersion = *version
@@ -446,17 +622,21 @@ func (c *ClusterController) onUpdate(oldObj, newObj interface{}) {
 
 	// attempt to update the cluster.  note this is done outside of wait.Poll because that function
 	// will wait for the retry interval before trying for the first time.
+	start := time.Now()
 	done, _ := c.handleUpdate(newClust.Name, cluster)
 	if done {
+		metrics.ObserveReconcile(ClusterResource.Name, newClust.Namespace, newClust.Name, start, nil)
 		return
 	}
 
 	err = wait.Poll(updateClusterInterval, updateClusterTimeout, func() (bool, error) {
 		return c.handleUpdate(newClust.Name, cluster)
 	})
+	metrics.ObserveReconcile(ClusterResource.Name, newClust.Namespace, newClust.Name, start, err)
 	if err != nil {
 		message := fmt.Sprintf("giving up trying to update cluster in namespace %s after %s", cluster.Namespace, updateClusterTimeout)
 		logger.Error(message)
+		cluster.events.Warningf(reconcile.ReasonReconcileFailed, "%s", message)
 		if err := c.updateClusterStatus(newClust.Namespace, newClust.Name, cephv1.ClusterStateError, message); err != nil {
 			logger.Errorf("failed to update cluster status in namespace %s: %+v", newClust.Namespace, err)
 		}
@@ -472,6 +652,7 @@ func (c *ClusterController) handleUpdate(crdName string, cluster *cluster) (bool
 
 	if err := cluster.createInstance(c.rookImage, cluster.Info.CephVersion); err != nil {
 		logger.Errorf("failed to update cluster in namespace %s. %+v", cluster.Namespace, err)
+		cluster.events.Warningf(reconcile.ReasonReconcileFailed, "failed to update cluster. retrying. %+v", err)
 		return false, nil
 	}
 
@@ -481,6 +662,7 @@ func (c *ClusterController) handleUpdate(crdName string, cluster *cluster) (bool
 	}
 
 	logger.Infof("succeeded updating cluster in namespace %s", cluster.Namespace)
+	cluster.events.Normalf(reconcile.ReasonReconciled, "updated cluster with ceph version %s", &cluster.Info.CephVersion)
 	return true, nil
 }
 
@@ -507,13 +689,11 @@ func (c *ClusterController) onDelete(obj interface{}) {
 	if err != nil {
 		logger.Errorf("failed to delete cluster. %+v", err)
 	}
-	if cluster, ok := c.clusterMap[clust.Namespace]; ok {
-		close(cluster.stopCh)
-		delete(c.clusterMap, clust.Namespace)
-	}
+	c.stopCluster(clust.Namespace)
 	if clust.Spec.Storage.AnyUseAllDevices() {
 		c.devicesInUse = false
 	}
+	discover.ReleaseStorageDevices(c.context, clust.Namespace)
 }
 
 func (c *ClusterController) handleDelete(cluster *cephv1.CephCluster, retryInterval time.Duration) error {
@@ -562,6 +742,167 @@ func (c *ClusterController) handleDelete(cluster *cephv1.CephCluster, retryInter
 	return nil
 }
 
+// restoreMonQuorum restores the mon quorum if it is requested with the restore annotation of the cluster CRD.
+// Returns whether a restore was requested.
+func (c *ClusterController) restoreMonQuorum(cluster *cluster, name string, cephVersion cephver.CephVersion) bool {
+	// get the latest object since the events queued during a restore still have the annotation
+	clust, err := c.context.RookClientset.CephV1().CephClusters(cluster.Namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		logger.Errorf("failed to get cluster %s to check for a mon quorum restore. %+v", cluster.Namespace, err)
+		return false
+	}
+	survivor, ok := clust.Annotations[mon.RestoreQuorumAnnotation]
+	if !ok {
+		return false
+	}
+
+	// remove the request before the restore so it is never repeated
+	delete(clust.Annotations, mon.RestoreQuorumAnnotation)
+	delete(clust.Annotations, mon.RestoreQuorumErrorAnnotation)
+	clust.Annotations[mon.RestoreQuorumStatusAnnotation] = mon.RestoreQuorumRestoring
+	if _, err := c.context.RookClientset.CephV1().CephClusters(cluster.Namespace).Update(clust); err != nil {
+		logger.Errorf("failed to start the mon quorum restore of cluster %s. %+v", cluster.Namespace, err)
+		return true
+	}
+
+	logger.Warningf("restoring the mon quorum of cluster %s from mon %s", cluster.Namespace, survivor)
+	status := mon.RestoreQuorumRestored
+	restoreErr := cluster.restoreMonQuorum(c.rookImage, survivor, cephVersion)
+	if restoreErr != nil {
+		logger.Errorf("%+v", restoreErr)
+		status = mon.RestoreQuorumFailed
+	}
+
+	clust, err = c.context.RookClientset.CephV1().CephClusters(cluster.Namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		logger.Errorf("failed to get cluster %s to save the mon quorum restore status. %+v", cluster.Namespace, err)
+		return true
+	}
+	if clust.Annotations == nil {
+		clust.Annotations = map[string]string{}
+	}
+	clust.Annotations[mon.RestoreQuorumStatusAnnotation] = status
+	if restoreErr != nil {
+		clust.Annotations[mon.RestoreQuorumErrorAnnotation] = restoreErr.Error()
+	}
+	if _, err := c.context.RookClientset.CephV1().CephClusters(cluster.Namespace).Update(clust); err != nil {
+		logger.Errorf("failed to save the mon quorum restore status of cluster %s. %+v", cluster.Namespace, err)
+	}
+	return true
+}
+
+// removeChildFinalizers removes the finalizers of the pools, filesystems and object stores of a deleted cluster
+func (c *ClusterController) removeChildFinalizers(namespace string) {
+	if err := pool.RemoveFinalizers(c.context, namespace); err != nil {
+		logger.Errorf("failed to remove the finalizers of the pools in namespace %s. %+v", namespace, err)
+	}
+	if err := file.RemoveFinalizers(c.context, namespace); err != nil {
+		logger.Errorf("failed to remove the finalizers of the filesystems in namespace %s. %+v", namespace, err)
+	}
+	if err := object.RemoveFinalizers(c.context, namespace); err != nil {
+		logger.Errorf("failed to remove the finalizers of the object stores in namespace %s. %+v", namespace, err)
+	}
+}
+
+// cleanupHosts stops the cluster and starts wiping its data on the hosts in the background. The cleanup jobs are
+// tracked in the cluster status, whose last update triggers the removal of the finalizer. Returns whether the
+// cleanup is done. A cleanup interrupted by a restart of the operator is started again.
+func (c *ClusterController) cleanupHosts(clust *cephv1.CephCluster) bool {
+	if clust.Status.CleanupState == cephv1.CleanupStateDone {
+		return true
+	}
+	if c.cleanups[clust.Namespace] {
+		logger.Infof("waiting for the cleanup of the hosts of cluster %s", clust.Namespace)
+		return false
+	}
+
+	// stop the orchestration and the monitoring of the cluster so the daemons are not started again
+	c.stopCluster(clust.Namespace)
+
+	logger.Infof("cleaning up the hosts of cluster %s", clust.Namespace)
+	c.cleanups[clust.Namespace] = true
+	go c.runCleanup(clust)
+	return false
+}
+
+// runCleanup runs the cleanup jobs of the cluster and saves their progress and their results in the cluster status
+func (c *ClusterController) runCleanup(clust *cephv1.CephCluster) {
+	defer func() {
+		c.handlerLock.Lock()
+		defer c.handlerLock.Unlock()
+		delete(c.cleanups, clust.Namespace)
+	}()
+
+	ownerRef := ClusterOwnerRef(clust.Namespace, string(clust.UID))
+	cleaner := cleanup.New(c.context, clust.Namespace, c.rookImage, clust.Spec.CephVersion.Image, clust.Spec.DataDirHostPath, clust.Spec.Placement, ownerRef)
+
+	// the nodes are found from the pods of the daemons, so they are saved before the daemons are stopped. A cleanup
+	// interrupted by a restart of the operator runs again on the saved nodes.
+	nodes := []string{}
+	if clust.Status.CleanupState == cephv1.CleanupStateRunning {
+		for _, node := range clust.Status.Cleanup {
+			nodes = append(nodes, node.Node)
+		}
+	} else {
+		var err error
+		nodes, err = cleaner.GetNodes()
+		if err != nil {
+			// the deletion of the cluster is not blocked by a failed cleanup
+			logger.Errorf("failed to clean up the hosts of cluster %s. %+v", clust.Namespace, err)
+			c.updateCleanupStatus(clust, cephv1.CleanupStateDone, []cephv1.NodeCleanupStatus{})
+			return
+		}
+		running := []cephv1.NodeCleanupStatus{}
+		for _, node := range nodes {
+			running = append(running, cephv1.NodeCleanupStatus{Node: node, State: cephv1.NodeCleanupStateRunning})
+		}
+		if !c.updateCleanupStatus(clust, cephv1.CleanupStateRunning, running) {
+			// the daemons are not stopped until the nodes are saved, the cleanup is started again on the next update
+			return
+		}
+	}
+
+	if err := cleaner.Start(nodes); err != nil {
+		// the deletion of the cluster is not blocked by a failed cleanup
+		logger.Errorf("failed to clean up the hosts of cluster %s. %+v", clust.Namespace, err)
+		c.updateCleanupStatus(clust, cephv1.CleanupStateDone, []cephv1.NodeCleanupStatus{})
+		return
+	}
+
+	results := cleaner.Wait(nodes)
+	c.updateCleanupStatus(clust, cephv1.CleanupStateDone, results)
+}
+
+// updateCleanupStatus saves the state of the cleanup and the result of each host in the status of the cluster.
+// Returns whether the status was saved.
+func (c *ClusterController) updateCleanupStatus(clust *cephv1.CephCluster, state cephv1.CleanupState, results []cephv1.NodeCleanupStatus) bool {
+	// get the most recent cluster CRD object to save the results in the status
+	cluster, err := c.context.RookClientset.CephV1().CephClusters(clust.Namespace).Get(clust.Name, metav1.GetOptions{})
+	if err != nil {
+		logger.Errorf("failed to get cluster %s to update the cleanup status. %+v", clust.Namespace, err)
+		return false
+	}
+	cluster.Status.CleanupState = state
+	cluster.Status.Cleanup = results
+	if _, err := c.context.RookClientset.CephV1().CephClusters(cluster.Namespace).Update(cluster); err != nil {
+		logger.Errorf("failed to update the cleanup status of cluster %s. %+v", cluster.Namespace, err)
+		return false
+	}
+	return true
+}
+
+// stopCluster stops the orchestration and the monitoring of the cluster in the namespace, leaving its daemons running
+func (c *ClusterController) stopCluster(namespace string) {
+	if cluster, ok := c.clusterMap[namespace]; ok {
+		close(cluster.stopCh)
+		delete(c.clusterMap, namespace)
+	}
+}
+
+func isPaused(clust *cephv1.CephCluster) bool {
+	return clust.Annotations[PausedAnnotation] == "true"
+}
+
 func isLegacyClusterObjectDeleted(obj interface{}) bool {
 	// if the object is a legacy cluster type and the deletion timestamp on the legacy cluster object is set,
 	// it has been requested to be deleted
@@ -667,7 +1008,8 @@ func (c *ClusterController) updateClusterStatus(namespace, name string, state ce
 	}
 
 	// update the status on the retrieved cluster object
-	cluster.Status = cephv1.ClusterStatus{State: state, Message: message}
+	// the crashes are kept until the next check of the crashes
+	cluster.Status = cephv1.ClusterStatus{State: state, Message: message, Crashes: cluster.Status.Crashes}
 	if _, err := c.context.RookClientset.CephV1().CephClusters(cluster.Namespace).Update(cluster); err != nil {
 		return fmt.Errorf("failed to update cluster %s status: %+v", cluster.Namespace, err)
 	}
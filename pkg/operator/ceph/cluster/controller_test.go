@@ -28,11 +28,13 @@ import (
 	rookfake "github.com/rook/rook/pkg/client/clientset/versioned/fake"
 	"github.com/rook/rook/pkg/clusterd"
 	"github.com/rook/rook/pkg/daemon/ceph/agent/flexvolume/attachment"
+	"github.com/rook/rook/pkg/operator/ceph/cluster/cleanup"
 	"github.com/rook/rook/pkg/operator/ceph/cluster/mon"
 	"github.com/rook/rook/pkg/operator/k8sutil"
 	testop "github.com/rook/rook/pkg/operator/test"
 	exectest "github.com/rook/rook/pkg/util/exec/test"
 	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -229,3 +231,138 @@ func TestRemoveFinalizer(t *testing.T) {
 	assert.NotNil(t, legacyRookCluster)
 	assert.Len(t, legacyRookCluster.Finalizers, 0)
 }
+
+func TestPauseCluster(t *testing.T) {
+	context := &clusterd.Context{Clientset: testop.New(1)}
+	controller := NewClusterController(context, "", &attachment.MockAttachment{})
+	clust := &cephv1.CephCluster{ObjectMeta: metav1.ObjectMeta{Name: "rook-ceph", Namespace: "ns"}}
+	cluster := newCluster(clust, context)
+	controller.clusterMap[clust.Namespace] = cluster
+
+	// the orchestration of the cluster is stopped when the annotation is set
+	paused := clust.DeepCopy()
+	paused.Annotations = map[string]string{PausedAnnotation: "true"}
+	controller.onUpdate(clust, paused)
+	assert.Equal(t, 0, len(controller.clusterMap))
+	select {
+	case <-cluster.stopCh:
+	default:
+		t.Fatal("the cluster was not stopped")
+	}
+
+	// a paused cluster is not started
+	controller.onAdd(paused)
+	assert.Equal(t, 0, len(controller.clusterMap))
+}
+
+func TestStopNamespace(t *testing.T) {
+	context := &clusterd.Context{Clientset: testop.New(1)}
+	controller := NewClusterController(context, "", &attachment.MockAttachment{})
+	cluster := newCluster(&cephv1.CephCluster{ObjectMeta: metav1.ObjectMeta{Namespace: "ns"}}, context)
+	controller.clusterMap["ns"] = cluster
+	stopCh := make(chan struct{})
+	controller.namespaceStopChs["ns"] = stopCh
+
+	// the watcher and the cluster of the namespace are stopped when the namespace is not selected anymore
+	controller.onNamespaceDelete(&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns"}})
+	assert.Equal(t, 0, len(controller.clusterMap))
+	assert.Equal(t, 0, len(controller.namespaceStopChs))
+	select {
+	case <-stopCh:
+	default:
+		t.Fatal("the namespace watcher was not stopped")
+	}
+	select {
+	case <-cluster.stopCh:
+	default:
+		t.Fatal("the cluster was not stopped")
+	}
+
+	// unknown namespaces are ignored
+	controller.onNamespaceDelete(&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "other"}})
+}
+
+func TestNodeLocationUpdate(t *testing.T) {
+	context := &clusterd.Context{Clientset: testop.New(1)}
+	controller := NewClusterController(context, "", &attachment.MockAttachment{})
+	clust := &cephv1.CephCluster{ObjectMeta: metav1.ObjectMeta{Namespace: "ns"}}
+	clust.Spec.CrushTopology.Enabled = true
+	clust.Spec.Storage.UseAllNodes = true
+	cluster := newCluster(clust, context)
+	controller.clusterMap["ns"] = cluster
+
+	// the update is queued without waiting for the osds
+	cluster.checkSetOrchestrationRunning()
+	oldNode := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1", Labels: map[string]string{"kubernetes.io/hostname": "node1"}}}
+	newNode := oldNode.DeepCopy()
+	newNode.Labels["topology.rook.io/rack"] = "rack1"
+	controller.onK8sNodeLocationUpdate(oldNode, newNode)
+	assert.False(t, cluster.getOrchestrationPending())
+
+	// the running orchestration derives the new location
+	err := controller.reconcileNodeLocation("ns", "node1")
+	assert.Nil(t, err)
+	assert.True(t, cluster.checkUnsetOrchestrationPending())
+
+	// the update of a stopped cluster is skipped
+	err = controller.reconcileNodeLocation("other", "node1")
+	assert.Nil(t, err)
+}
+
+func TestCleanupHosts(t *testing.T) {
+	clust := &cephv1.CephCluster{ObjectMeta: metav1.ObjectMeta{Name: "rook-ceph", Namespace: "ns"}}
+	context := &clusterd.Context{Clientset: testop.New(1), RookClientset: rookfake.NewSimpleClientset(clust)}
+	controller := NewClusterController(context, "", &attachment.MockAttachment{})
+	cluster := newCluster(clust, context)
+	controller.clusterMap[clust.Namespace] = cluster
+
+	// the cleanup runs in the background after the cluster is stopped
+	controller.handlerLock.Lock()
+	assert.False(t, controller.cleanupHosts(clust))
+	assert.Equal(t, 0, len(controller.clusterMap))
+	// the cleanup is not started twice
+	assert.False(t, controller.cleanupHosts(clust))
+	controller.handlerLock.Unlock()
+
+	// the end of the cleanup is saved in the status
+	var updated *cephv1.CephCluster
+	for i := 0; i < 50; i++ {
+		var err error
+		updated, err = context.RookClientset.CephV1().CephClusters("ns").Get("rook-ceph", metav1.GetOptions{})
+		assert.Nil(t, err)
+		if updated.Status.CleanupState == cephv1.CleanupStateDone {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	assert.Equal(t, cephv1.CleanupStateDone, updated.Status.CleanupState)
+	assert.True(t, controller.cleanupHosts(updated))
+}
+
+func TestCleanupHostsResumed(t *testing.T) {
+	// the operator restarted after the nodes were saved and the daemons were stopped
+	clust := &cephv1.CephCluster{ObjectMeta: metav1.ObjectMeta{Name: "rook-ceph", Namespace: "ns"}}
+	clust.Status.CleanupState = cephv1.CleanupStateRunning
+	clust.Status.Cleanup = []cephv1.NodeCleanupStatus{{Node: "node1", State: cephv1.NodeCleanupStateRunning}}
+	clientset := testop.New(1)
+	context := &clusterd.Context{Clientset: clientset, RookClientset: rookfake.NewSimpleClientset(clust)}
+	controller := NewClusterController(context, "", &attachment.MockAttachment{})
+
+	// the job of the saved node reports its result
+	go func() {
+		for i := 0; i < 50; i++ {
+			if _, err := clientset.BatchV1().Jobs("ns").Get("rook-ceph-cleanup-node1", metav1.GetOptions{}); err == nil {
+				kv := k8sutil.NewConfigMapKVStore("ns", clientset, metav1.OwnerReference{})
+				cleanup.UpdateNodeStatus(kv, "node1", cleanup.NodeStatus{Completed: true})
+				return
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+	}()
+	controller.runCleanup(clust)
+
+	updated, err := context.RookClientset.CephV1().CephClusters("ns").Get("rook-ceph", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, cephv1.CleanupStateDone, updated.Status.CleanupState)
+	assert.Equal(t, []cephv1.NodeCleanupStatus{{Node: "node1", State: cephv1.NodeCleanupStateCompleted}}, updated.Status.Cleanup)
+}
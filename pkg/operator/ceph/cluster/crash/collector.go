@@ -0,0 +1,140 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package crash runs the crash collectors posting the crash reports of the daemons to the mgr
+package crash
+
+import (
+	"fmt"
+
+	"github.com/coreos/pkg/capnslog"
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	rookalpha "github.com/rook/rook/pkg/apis/rook.io/v1alpha2"
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/daemon/ceph/client"
+	cephconfig "github.com/rook/rook/pkg/daemon/ceph/config"
+	"github.com/rook/rook/pkg/operator/ceph/config/keyring"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var logger = capnslog.NewPackageLogger("github.com/rook/rook", "op-crash-collector")
+
+const (
+	// AppName is the name of the crash collector daemonset
+	AppName = "rook-ceph-crashcollector"
+
+	crashModuleName = "crash"
+	crashUser       = "client.crash"
+	keyringTemplate = `
+[%s]
+	key = %s
+	caps mon = "allow profile crash"
+	caps mgr = "allow profile crash"
+`
+)
+
+// Collector represents the crash collectors running on the nodes of the cluster. The daemons write their crash reports
+// under the dataDirHostPath of their node, and the collector of the node posts them to the crash module of the mgr.
+type Collector struct {
+	clusterInfo     *cephconfig.ClusterInfo
+	context         *clusterd.Context
+	Namespace       string
+	placement       rookalpha.Placement
+	hostNetwork     bool
+	dataDirHostPath string
+	spec            cephv1.CrashCollectorSpec
+	cephVersion     cephv1.CephVersionSpec
+	ownerRef        metav1.OwnerReference
+	// Override is the image, priority class, labels and annotations of the crash collector pods
+	Override cephv1.DaemonOverride
+	// user is the user of the collectors, a generation of the crash user after a key rotation
+	user string
+}
+
+// New creates an instance of the crash collectors
+func New(
+	clusterInfo *cephconfig.ClusterInfo,
+	context *clusterd.Context,
+	namespace string,
+	cephVersion cephv1.CephVersionSpec,
+	placement rookalpha.Placement,
+	hostNetwork bool,
+	dataDirHostPath string,
+	spec cephv1.CrashCollectorSpec,
+	ownerRef metav1.OwnerReference,
+) *Collector {
+	return &Collector{
+		clusterInfo:     clusterInfo,
+		context:         context,
+		Namespace:       namespace,
+		placement:       placement,
+		hostNetwork:     hostNetwork,
+		dataDirHostPath: dataDirHostPath,
+		spec:            spec,
+		cephVersion:     cephVersion,
+		ownerRef:        ownerRef,
+		user:            crashUser,
+	}
+}
+
+// Start enables the crash module of the mgr and runs the crash collectors. The collectors are removed if they are
+// disabled or if the crash module is not available before Nautilus.
+func (c *Collector) Start() error {
+	if !c.clusterInfo.CephVersion.IsAtLeastNautilus() || c.spec.Disable {
+		logger.Infof("crash collector is not enabled in cluster %s", c.Namespace)
+		return c.remove()
+	}
+
+	if err := client.MgrEnableModule(c.context, c.Namespace, crashModuleName, false); err != nil {
+		return fmt.Errorf("failed to enable the mgr %s module. %+v", crashModuleName, err)
+	}
+	if err := c.generateKeyring(); err != nil {
+		return fmt.Errorf("failed to generate the crash collector keyring. %+v", err)
+	}
+
+	ds := c.makeDaemonSet()
+	if err := k8sutil.CreateDaemonSet(AppName, c.Namespace, c.context.Clientset, ds); err != nil {
+		return err
+	}
+	logger.Infof("%s daemonset started", AppName)
+	return nil
+}
+
+func (c *Collector) generateKeyring() error {
+	access := []string{"mon", "allow profile crash", "mgr", "allow profile crash"}
+	s := keyring.GetSecretStore(c.context, c.Namespace, &c.ownerRef)
+
+	user, err := s.StoredUser(AppName, crashUser)
+	if err != nil {
+		return err
+	}
+	key, err := s.GenerateKey(AppName, user, access)
+	if err != nil {
+		return err
+	}
+	c.user = user
+	return s.CreateOrUpdate(AppName, fmt.Sprintf(keyringTemplate, user, key))
+}
+
+func (c *Collector) remove() error {
+	err := c.context.Clientset.Apps().DaemonSets(c.Namespace).Delete(AppName, &metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to remove the %s daemonset. %+v", AppName, err)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crash
+
+import (
+	"strings"
+	"testing"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	rookalpha "github.com/rook/rook/pkg/apis/rook.io/v1alpha2"
+	"github.com/rook/rook/pkg/clusterd"
+	cephconfig "github.com/rook/rook/pkg/daemon/ceph/config"
+	"github.com/rook/rook/pkg/operator/ceph/config/keyring"
+	cephver "github.com/rook/rook/pkg/operator/ceph/version"
+	testop "github.com/rook/rook/pkg/operator/test"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestStartCollector(t *testing.T) {
+	clientset := testop.New(1)
+	keysCreated := map[string]bool{}
+	modulesEnabled := map[string]bool{}
+	executor := &exectest.MockExecutor{}
+	executor.MockExecuteCommandWithOutputFile = func(debug bool, actionName string, command string, outFileArg string, args ...string) (string, error) {
+		if args[0] == "auth" && args[1] == "get-or-create-key" {
+			keysCreated[args[2]] = true
+			return "{\"key\":\"mysecurekey\"}", nil
+		}
+		if args[0] == "mgr" && args[1] == "module" && args[2] == "enable" {
+			modulesEnabled[args[3]] = true
+		}
+		return "", nil
+	}
+
+	clusterInfo := &cephconfig.ClusterInfo{FSID: "myfsid", CephVersion: cephver.Nautilus}
+	c := New(
+		clusterInfo,
+		&clusterd.Context{Clientset: clientset, Executor: executor},
+		"ns",
+		cephv1.CephVersionSpec{Image: "ceph/ceph:v14"},
+		rookalpha.Placement{},
+		false,
+		"/var/lib/rook",
+		cephv1.CrashCollectorSpec{},
+		metav1.OwnerReference{},
+	)
+
+	err := c.Start()
+	assert.Nil(t, err)
+	assert.True(t, keysCreated[crashUser])
+	assert.True(t, modulesEnabled[crashModuleName])
+
+	ds, err := clientset.Apps().DaemonSets("ns").Get(AppName, metav1.GetOptions{})
+	require.Nil(t, err)
+	podSpec := ds.Spec.Template.Spec
+	require.Equal(t, 1, len(podSpec.Containers))
+	container := podSpec.Containers[0]
+	assert.Equal(t, "ceph/ceph:v14", container.Image)
+	assert.Equal(t, []string{"ceph-crash"}, container.Command)
+	cephArgs := container.Env[len(container.Env)-1]
+	assert.Equal(t, "CEPH_ARGS", cephArgs.Name)
+	assert.True(t, strings.Contains(cephArgs.Value, "--name=client.crash"), cephArgs.Value)
+	assert.True(t, strings.Contains(cephArgs.Value, "--fsid=myfsid"), cephArgs.Value)
+
+	// the crash dir of the host is mounted at the default crash dir
+	crashDir := ""
+	for _, v := range podSpec.Volumes {
+		if v.HostPath != nil {
+			crashDir = v.HostPath.Path
+		}
+	}
+	assert.Equal(t, "/var/lib/rook/crash", crashDir)
+	assert.Equal(t, "/var/lib/ceph/crash", container.VolumeMounts[len(container.VolumeMounts)-1].MountPath)
+
+	// the collectors are removed when they are disabled
+	c.spec.Disable = true
+	err = c.Start()
+	assert.Nil(t, err)
+	_, err = clientset.Apps().DaemonSets("ns").Get(AppName, metav1.GetOptions{})
+	assert.NotNil(t, err)
+
+	// the collectors do not run before nautilus
+	keysCreated = map[string]bool{}
+	c.spec.Disable = false
+	clusterInfo.CephVersion = cephver.Mimic
+	err = c.Start()
+	assert.Nil(t, err)
+	assert.False(t, keysCreated[crashUser])
+	_, err = clientset.Apps().DaemonSets("ns").Get(AppName, metav1.GetOptions{})
+	assert.NotNil(t, err)
+
+	// the collectors run with the user created by the last key rotation
+	clusterInfo.CephVersion = cephver.Nautilus
+	k := keyring.GetSecretStore(c.context, "ns", &c.ownerRef)
+	require.Nil(t, k.CreateOrUpdate(AppName, "[client.crash-2]\n\tkey = mysecurekey\n"))
+	err = c.Start()
+	assert.Nil(t, err)
+	assert.True(t, keysCreated["client.crash-2"])
+	assert.False(t, keysCreated[crashUser])
+	ds, err = clientset.Apps().DaemonSets("ns").Get(AppName, metav1.GetOptions{})
+	require.Nil(t, err)
+	cephArgs = ds.Spec.Template.Spec.Containers[0].Env[len(container.Env)-1]
+	assert.True(t, strings.Contains(cephArgs.Value, "--name=client.crash-2"), cephArgs.Value)
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crash
+
+import (
+	"path"
+	"strings"
+
+	"github.com/rook/rook/pkg/operator/ceph/config"
+	"github.com/rook/rook/pkg/operator/ceph/config/keyring"
+	opspec "github.com/rook/rook/pkg/operator/ceph/spec"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	apps "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func (c *Collector) makeDaemonSet() *apps.DaemonSet {
+	dataPathMap := config.NewDatalessDaemonDataPathMap()
+	podSpec := v1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   AppName,
+			Labels: opspec.AppLabels(AppName, c.Namespace),
+		},
+		Spec: v1.PodSpec{
+			InitContainers: []v1.Container{
+				c.makeCrashDirInitContainer(),
+			},
+			Containers: []v1.Container{
+				c.makeCollectorContainer(dataPathMap),
+			},
+			RestartPolicy: v1.RestartPolicyAlways,
+			Volumes: append(
+				opspec.DaemonVolumes(dataPathMap, AppName),
+				opspec.CrashVolume(c.dataDirHostPath),
+			),
+			HostNetwork: c.hostNetwork,
+		},
+	}
+	if c.hostNetwork {
+		podSpec.Spec.DNSPolicy = v1.DNSClusterFirstWithHostNet
+	}
+	c.placement.ApplyToPodSpec(&podSpec.Spec)
+	c.Override.ApplyToPod(&podSpec.ObjectMeta, &podSpec.Spec, c.cephVersion.Image)
+
+	ds := &apps.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      AppName,
+			Namespace: c.Namespace,
+			Labels:    opspec.AppLabels(AppName, c.Namespace),
+		},
+		Spec: apps.DaemonSetSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: opspec.AppLabels(AppName, c.Namespace),
+			},
+			UpdateStrategy: apps.DaemonSetUpdateStrategy{
+				Type: apps.RollingUpdateDaemonSetStrategyType,
+			},
+			Template: podSpec,
+		},
+	}
+	k8sutil.SetOwnerRef(c.context.Clientset, c.Namespace, &ds.ObjectMeta, &c.ownerRef)
+	return ds
+}
+
+// makeCrashDirInitContainer creates the dir into which ceph-crash moves the reports it posted
+func (c *Collector) makeCrashDirInitContainer() v1.Container {
+	return v1.Container{
+		Name: "make-crash-dir",
+		Command: []string{
+			"mkdir",
+		},
+		Args: []string{
+			"-p",
+			path.Join(opspec.CrashVolumeMount().MountPath, "posted"),
+		},
+		Image: c.cephVersion.Image,
+		VolumeMounts: []v1.VolumeMount{
+			opspec.CrashVolumeMount(),
+		},
+	}
+}
+
+func (c *Collector) makeCollectorContainer(dataPathMap *config.DataPathMap) v1.Container {
+	// ceph-crash posts the reports with the ceph cli, which reads its flags from CEPH_ARGS
+	cephArgs := append(
+		config.DefaultFlags(c.clusterInfo.FSID, keyring.VolumeMount().KeyringFilePath()),
+		config.NewFlag("name", c.user),
+	)
+	return v1.Container{
+		Name: "ceph-crash",
+		Command: []string{
+			"ceph-crash",
+		},
+		Image: c.cephVersion.Image,
+		VolumeMounts: append(
+			opspec.DaemonVolumeMounts(dataPathMap, AppName),
+			opspec.CrashVolumeMount(),
+		),
+		Env: append(
+			opspec.DaemonEnvVars(c.cephVersion.Image),
+			v1.EnvVar{Name: "CEPH_ARGS", Value: strings.Join(cephArgs, " ")},
+		),
+	}
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crash
+
+import (
+	"sort"
+	"time"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	"github.com/rook/rook/pkg/daemon/ceph/client"
+)
+
+const (
+	// the crash module reports the time of the crashes in UTC, with optional fractional seconds
+	crashTimeLayout = "2006-01-02 15:04:05Z"
+
+	recentCrashDays  = 14
+	maxRecentCrashes = 10
+)
+
+// RecentCrashes returns the summary of the crashes of the last two weeks reported by the crash module, the most recent
+// first. Only the ten most recent crashes are summarised, the others are listed with "ceph crash ls".
+func RecentCrashes(crashes []client.CrashInfo, now time.Time) []cephv1.CrashSummary {
+	type crashTime struct {
+		crash client.CrashInfo
+		time  time.Time
+	}
+	var recent []crashTime
+	for _, crash := range crashes {
+		t, err := time.Parse(crashTimeLayout, crash.Timestamp)
+		if err != nil {
+			logger.Warningf("invalid time %s of crash %s. %+v", crash.Timestamp, crash.ID, err)
+			continue
+		}
+		if now.Sub(t) > recentCrashDays*24*time.Hour {
+			continue
+		}
+		recent = append(recent, crashTime{crash: crash, time: t})
+	}
+	sort.Slice(recent, func(i, j int) bool { return recent[i].time.After(recent[j].time) })
+	if len(recent) > maxRecentCrashes {
+		recent = recent[:maxRecentCrashes]
+	}
+
+	var summaries []cephv1.CrashSummary
+	for _, r := range recent {
+		summaries = append(summaries, cephv1.CrashSummary{
+			ID:        r.crash.ID,
+			Daemon:    r.crash.Entity,
+			Time:      r.time.UTC().Format(time.RFC3339),
+			Signature: r.crash.StackSig,
+		})
+	}
+	return summaries
+}
@@ -0,0 +1,57 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crash
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rook/rook/pkg/daemon/ceph/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecentCrashes(t *testing.T) {
+	now := time.Date(2019, 8, 20, 12, 0, 0, 0, time.UTC)
+	crashes := []client.CrashInfo{
+		{ID: "old", Entity: "osd.0", Timestamp: "2019-07-01 10:00:00.000000Z", StackSig: "aaa"},
+		{ID: "first", Entity: "osd.1", Timestamp: "2019-08-10 10:00:00.123456Z", StackSig: "bbb"},
+		{ID: "second", Entity: "mon.a", Timestamp: "2019-08-19 08:30:00.654321Z", StackSig: "ccc"},
+		{ID: "invalid", Entity: "mgr.a", Timestamp: "yesterday"},
+	}
+
+	summaries := RecentCrashes(crashes, now)
+	require.Equal(t, 2, len(summaries))
+	assert.Equal(t, "second", summaries[0].ID)
+	assert.Equal(t, "mon.a", summaries[0].Daemon)
+	assert.Equal(t, "2019-08-19T08:30:00Z", summaries[0].Time)
+	assert.Equal(t, "ccc", summaries[0].Signature)
+	assert.Equal(t, "first", summaries[1].ID)
+
+	// only the most recent crashes are summarised
+	crashes = nil
+	for i := 0; i < 15; i++ {
+		crashes = append(crashes, client.CrashInfo{ID: fmt.Sprintf("%d", i), Timestamp: fmt.Sprintf("2019-08-20 %02d:00:00Z", i)})
+	}
+	summaries = RecentCrashes(crashes, now)
+	require.Equal(t, maxRecentCrashes, len(summaries))
+	assert.Equal(t, "14", summaries[0].ID)
+	assert.Equal(t, "5", summaries[9].ID)
+
+	assert.Nil(t, RecentCrashes(nil, now))
+}
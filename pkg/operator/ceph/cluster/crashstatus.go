@@ -0,0 +1,92 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/rook/rook/pkg/daemon/ceph/client"
+	"github.com/rook/rook/pkg/operator/ceph/cluster/crash"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	crashCheckInterval  = 10 * time.Minute
+	daemonCrashedReason = "DaemonCrashed"
+)
+
+// updateCrashes prunes the crash reports older than the retention of the crash collector, and summarises the recent
+// crashes in the status of the cluster CRD
+func (c *ClusterController) updateCrashes(cluster *cluster, name string) {
+	// the crash module is only available from nautilus, after the mons are started
+	if cluster.Info == nil || !cluster.Info.CephVersion.IsAtLeastNautilus() {
+		return
+	}
+
+	clust, err := c.context.RookClientset.CephV1().CephClusters(cluster.Namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		logger.Errorf("failed to get cluster %s to check for crashes. %+v", cluster.Namespace, err)
+		return
+	}
+	if clust.Spec.CrashCollector.Disable {
+		return
+	}
+
+	if clust.Spec.CrashCollector.DaysToRetain > 0 {
+		if err := client.PruneCrashes(c.context, cluster.Namespace, clust.Spec.CrashCollector.DaysToRetain); err != nil {
+			logger.Warningf("failed to prune the crashes of cluster %s. %+v", cluster.Namespace, err)
+		}
+	}
+	crashes, err := client.GetCrashList(c.context, cluster.Namespace)
+	if err != nil {
+		logger.Warningf("failed to list the crashes of cluster %s. %+v", cluster.Namespace, err)
+		return
+	}
+
+	recent := crash.RecentCrashes(crashes, time.Now())
+	if reflect.DeepEqual(recent, clust.Status.Crashes) {
+		return
+	}
+	reported := map[string]bool{}
+	for _, summary := range clust.Status.Crashes {
+		reported[summary.ID] = true
+	}
+	for _, summary := range recent {
+		if !reported[summary.ID] {
+			logger.Warningf("%s crashed at %s in cluster %s. crash id %s", summary.Daemon, summary.Time, cluster.Namespace, summary.ID)
+			cluster.events.Warningf(daemonCrashedReason, "%s crashed at %s. run \"ceph crash info %s\" for the details", summary.Daemon, summary.Time, summary.ID)
+		}
+	}
+	clust.Status.Crashes = recent
+	if _, err := c.context.RookClientset.CephV1().CephClusters(cluster.Namespace).Update(clust); err != nil {
+		logger.Errorf("failed to update the crashes in the status of cluster %s. %+v", cluster.Namespace, err)
+	}
+}
+
+// checkCrashes updates the crashes in the status of the cluster until the cluster is stopped
+func (c *ClusterController) checkCrashes(cluster *cluster, name string) {
+	for {
+		c.updateCrashes(cluster, name)
+		select {
+		case <-cluster.stopCh:
+			logger.Infof("stopping the crash checks of cluster %s", cluster.Namespace)
+			return
+		case <-time.After(crashCheckInterval):
+		}
+	}
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	rookfake "github.com/rook/rook/pkg/client/clientset/versioned/fake"
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/daemon/ceph/agent/flexvolume/attachment"
+	cephconfig "github.com/rook/rook/pkg/daemon/ceph/config"
+	cephver "github.com/rook/rook/pkg/operator/ceph/version"
+	testop "github.com/rook/rook/pkg/operator/test"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestUpdateCrashes(t *testing.T) {
+	clust := &cephv1.CephCluster{ObjectMeta: metav1.ObjectMeta{Name: "rook-ceph", Namespace: "ns"}}
+	clust.Spec.CrashCollector.DaysToRetain = 30
+	clust.Status.State = cephv1.ClusterStateCreated
+	rookClientset := rookfake.NewSimpleClientset(clust)
+
+	crashTime := time.Now().UTC().Add(-time.Hour)
+	var pruned []string
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName string, command string, outFileArg string, args ...string) (string, error) {
+			if args[0] == "crash" && args[1] == "ls" {
+				return fmt.Sprintf(`[{"crash_id":"crash1","timestamp":"%s","entity_name":"osd.2","stack_sig":"abc"}]`,
+					crashTime.Format("2006-01-02 15:04:05.000000Z")), nil
+			}
+			if args[0] == "crash" && args[1] == "prune" {
+				pruned = append(pruned, args[2])
+				return "", nil
+			}
+			return "", fmt.Errorf("unexpected ceph command %v", args)
+		},
+	}
+	context := &clusterd.Context{Clientset: testop.New(1), RookClientset: rookClientset, Executor: executor}
+	controller := NewClusterController(context, "", &attachment.MockAttachment{})
+	c := newCluster(clust, context)
+
+	// the crashes are not checked before the mons are started
+	controller.updateCrashes(c, "rook-ceph")
+	assert.Nil(t, pruned)
+
+	c.Info = &cephconfig.ClusterInfo{CephVersion: cephver.Nautilus}
+	controller.updateCrashes(c, "rook-ceph")
+	assert.Equal(t, []string{"30"}, pruned)
+	updated, err := rookClientset.CephV1().CephClusters("ns").Get("rook-ceph", metav1.GetOptions{})
+	require.Nil(t, err)
+	require.Equal(t, 1, len(updated.Status.Crashes))
+	assert.Equal(t, "crash1", updated.Status.Crashes[0].ID)
+	assert.Equal(t, "osd.2", updated.Status.Crashes[0].Daemon)
+	assert.Equal(t, crashTime.Format(time.RFC3339), updated.Status.Crashes[0].Time)
+	assert.Equal(t, "abc", updated.Status.Crashes[0].Signature)
+	assert.Equal(t, cephv1.ClusterStateCreated, updated.Status.State)
+
+	// the crashes are kept when the state of the cluster is updated
+	err = controller.updateClusterStatus("ns", "rook-ceph", cephv1.ClusterStateUpdating, "")
+	assert.Nil(t, err)
+	updated, err = rookClientset.CephV1().CephClusters("ns").Get("rook-ceph", metav1.GetOptions{})
+	require.Nil(t, err)
+	assert.Equal(t, cephv1.ClusterStateUpdating, updated.Status.State)
+	assert.Equal(t, 1, len(updated.Status.Crashes))
+
+	// the crashes are not checked when the collector is disabled
+	updated.Spec.CrashCollector.Disable = true
+	_, err = rookClientset.CephV1().CephClusters("ns").Update(updated)
+	require.Nil(t, err)
+	pruned = nil
+	controller.updateCrashes(c, "rook-ceph")
+	assert.Nil(t, pruned)
+}
@@ -0,0 +1,260 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/rook/rook/pkg/operator/ceph/cluster/mon"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	"golang.org/x/crypto/pbkdf2"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// the version of the format of the identity bundles
+	identityBundleVersion = 1
+	identityEncryption    = "aes-256-gcm-pbkdf2-sha256"
+	identityKeyIterations = 100000
+
+	// the OSD config stores are named rook-ceph-osd-<node>-config and rook-ceph-osd-<id>-fs-backup
+	osdStorePrefix          = "rook-ceph-osd-"
+	osdConfigStoreSuffix    = "-config"
+	osdFSBackupStoreSuffix  = "-fs-backup"
+	identityBundleKeyLength = 32
+)
+
+// IdentityBundle holds the objects with the identity of a cluster: the fsid and the keys, the mon endpoints and
+// services, and the OSD config stores. Once restored, a new cluster CRD in the namespace adopts the existing mons and
+// OSDs instead of creating a new cluster.
+type IdentityBundle struct {
+	Namespace  string         `json:"namespace"`
+	Secrets    []v1.Secret    `json:"secrets"`
+	ConfigMaps []v1.ConfigMap `json:"configMaps"`
+	Services   []v1.Service   `json:"services"`
+}
+
+// identityEnvelope is the versioned format of a bundle, which is encrypted if a passphrase is given
+type identityEnvelope struct {
+	Version    int             `json:"version"`
+	Encryption string          `json:"encryption,omitempty"`
+	Salt       []byte          `json:"salt,omitempty"`
+	Nonce      []byte          `json:"nonce,omitempty"`
+	Encrypted  []byte          `json:"encrypted,omitempty"`
+	Bundle     *IdentityBundle `json:"bundle,omitempty"`
+}
+
+// BackupIdentity collects the identity objects of the cluster in the namespace
+func BackupIdentity(clientset kubernetes.Interface, namespace string) (*IdentityBundle, error) {
+	bundle := &IdentityBundle{Namespace: namespace}
+
+	secret, err := clientset.CoreV1().Secrets(namespace).Get(mon.AppName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the identity of cluster %s. %+v", namespace, err)
+	}
+	bundle.Secrets = append(bundle.Secrets, *secret)
+
+	configMaps, err := clientset.CoreV1().ConfigMaps(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list configmaps of cluster %s. %+v", namespace, err)
+	}
+	for _, cm := range configMaps.Items {
+		if isIdentityConfigMap(cm.Name) {
+			bundle.ConfigMaps = append(bundle.ConfigMaps, cm)
+		}
+	}
+
+	services, err := clientset.CoreV1().Services(namespace).List(metav1.ListOptions{LabelSelector: fmt.Sprintf("%s=%s", k8sutil.AppAttr, mon.AppName)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list mon services of cluster %s. %+v", namespace, err)
+	}
+	bundle.Services = services.Items
+
+	for i := range bundle.Secrets {
+		cleanIdentityMeta(&bundle.Secrets[i].ObjectMeta)
+	}
+	for i := range bundle.ConfigMaps {
+		cleanIdentityMeta(&bundle.ConfigMaps[i].ObjectMeta)
+	}
+	for i := range bundle.Services {
+		cleanIdentityMeta(&bundle.Services[i].ObjectMeta)
+		bundle.Services[i].Status = v1.ServiceStatus{}
+	}
+
+	logger.Infof("backed up the identity of cluster %s: %d secrets, %d configmaps and %d services",
+		namespace, len(bundle.Secrets), len(bundle.ConfigMaps), len(bundle.Services))
+	return bundle, nil
+}
+
+func isIdentityConfigMap(name string) bool {
+	if name == mon.EndpointConfigMapName || name == crushConfigMapName {
+		return true
+	}
+	return strings.HasPrefix(name, osdStorePrefix) &&
+		(strings.HasSuffix(name, osdConfigStoreSuffix) || strings.HasSuffix(name, osdFSBackupStoreSuffix))
+}
+
+// cleanIdentityMeta removes the metadata of the objects that can't be restored. The owner references point to the
+// deleted cluster CRD.
+func cleanIdentityMeta(meta *metav1.ObjectMeta) {
+	*meta = metav1.ObjectMeta{
+		Name:        meta.Name,
+		Namespace:   meta.Namespace,
+		Labels:      meta.Labels,
+		Annotations: meta.Annotations,
+	}
+}
+
+// RestoreIdentity creates the identity objects of the bundle in its namespace. The objects that already exist are kept,
+// but the restore fails if the namespace holds the identity of another cluster.
+func RestoreIdentity(clientset kubernetes.Interface, bundle *IdentityBundle) error {
+	namespace := bundle.Namespace
+	for _, secret := range bundle.Secrets {
+		secret.Namespace = namespace
+		_, err := clientset.CoreV1().Secrets(namespace).Create(&secret)
+		if err == nil {
+			continue
+		}
+		if !errors.IsAlreadyExists(err) {
+			return fmt.Errorf("failed to restore secret %s. %+v", secret.Name, err)
+		}
+		existing, err := clientset.CoreV1().Secrets(namespace).Get(secret.Name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get secret %s. %+v", secret.Name, err)
+		}
+		if !reflect.DeepEqual(existing.Data, secret.Data) {
+			return fmt.Errorf("namespace %s already holds the identity of another cluster in secret %s", namespace, secret.Name)
+		}
+		logger.Infof("secret %s already restored", secret.Name)
+	}
+
+	for _, cm := range bundle.ConfigMaps {
+		cm.Namespace = namespace
+		if _, err := clientset.CoreV1().ConfigMaps(namespace).Create(&cm); err != nil {
+			if !errors.IsAlreadyExists(err) {
+				return fmt.Errorf("failed to restore configmap %s. %+v", cm.Name, err)
+			}
+			logger.Infof("configmap %s already exists", cm.Name)
+		}
+	}
+
+	// the services keep their cluster IP since the IPs of the mons are in the monmap
+	for _, service := range bundle.Services {
+		service.Namespace = namespace
+		if _, err := clientset.CoreV1().Services(namespace).Create(&service); err != nil {
+			if !errors.IsAlreadyExists(err) {
+				return fmt.Errorf("failed to restore service %s. %+v", service.Name, err)
+			}
+			logger.Infof("service %s already exists", service.Name)
+		}
+	}
+
+	logger.Infof("restored the identity of cluster %s", namespace)
+	return nil
+}
+
+// EncodeIdentity serializes the bundle. The bundle is encrypted with a key derived from the passphrase if not empty.
+func EncodeIdentity(bundle *IdentityBundle, passphrase string) ([]byte, error) {
+	envelope := identityEnvelope{Version: identityBundleVersion}
+	if passphrase == "" {
+		envelope.Bundle = bundle
+		return json.MarshalIndent(envelope, "", "  ")
+	}
+
+	plain, err := json.Marshal(bundle)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize the identity bundle. %+v", err)
+	}
+	envelope.Encryption = identityEncryption
+	envelope.Salt = make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, envelope.Salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt. %+v", err)
+	}
+	gcm, err := identityCipher(passphrase, envelope.Salt)
+	if err != nil {
+		return nil, err
+	}
+	envelope.Nonce = make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, envelope.Nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce. %+v", err)
+	}
+	envelope.Encrypted = gcm.Seal(nil, envelope.Nonce, plain, nil)
+	return json.MarshalIndent(envelope, "", "  ")
+}
+
+// DecodeIdentity deserializes a bundle, decrypting it with the passphrase if it is encrypted
+func DecodeIdentity(data []byte, passphrase string) (*IdentityBundle, error) {
+	var envelope identityEnvelope
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, fmt.Errorf("failed to parse the identity bundle. %+v", err)
+	}
+	if envelope.Version != identityBundleVersion {
+		return nil, fmt.Errorf("unsupported identity bundle version %d", envelope.Version)
+	}
+
+	switch envelope.Encryption {
+	case "":
+		if envelope.Bundle == nil {
+			return nil, fmt.Errorf("empty identity bundle")
+		}
+		return envelope.Bundle, nil
+	case identityEncryption:
+		if passphrase == "" {
+			return nil, fmt.Errorf("the identity bundle is encrypted, a passphrase is required")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported identity bundle encryption %s", envelope.Encryption)
+	}
+
+	gcm, err := identityCipher(passphrase, envelope.Salt)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := gcm.Open(nil, envelope.Nonce, envelope.Encrypted, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt the identity bundle, the passphrase may be wrong. %+v", err)
+	}
+	var bundle IdentityBundle
+	if err := json.Unmarshal(plain, &bundle); err != nil {
+		return nil, fmt.Errorf("failed to parse the decrypted identity bundle. %+v", err)
+	}
+	return &bundle, nil
+}
+
+func identityCipher(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key := pbkdf2.Key([]byte(passphrase), salt, identityKeyIterations, identityBundleKeyLength, sha256.New)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher. %+v", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher. %+v", err)
+	}
+	return gcm, nil
+}
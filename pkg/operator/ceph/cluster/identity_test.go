@@ -0,0 +1,130 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"testing"
+
+	"github.com/rook/rook/pkg/operator/ceph/cluster/mon"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	testop "github.com/rook/rook/pkg/operator/test"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestBackupRestoreIdentity(t *testing.T) {
+	namespace := "ns"
+	clientset := testop.New(3)
+	owner := []metav1.OwnerReference{{Name: "cluster", UID: types.UID("123")}}
+
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: mon.AppName, Namespace: namespace, OwnerReferences: owner, ResourceVersion: "1"},
+		Data:       map[string][]byte{"fsid": []byte("abc"), "mon-secret": []byte("monkey")},
+	}
+	_, err := clientset.CoreV1().Secrets(namespace).Create(secret)
+	assert.Nil(t, err)
+	for _, name := range []string{mon.EndpointConfigMapName, crushConfigMapName, "rook-ceph-osd-node1-config",
+		"rook-ceph-osd-0-fs-backup", "rook-ceph-osd-node1-status", "other"} {
+		cm := &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, OwnerReferences: owner}}
+		_, err := clientset.CoreV1().ConfigMaps(namespace).Create(cm)
+		assert.Nil(t, err)
+	}
+	for _, name := range []string{"rook-ceph-mon-a", "rook-ceph-mgr"} {
+		app := name
+		if name == "rook-ceph-mon-a" {
+			app = mon.AppName
+		}
+		service := &v1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: map[string]string{k8sutil.AppAttr: app}},
+			Spec:       v1.ServiceSpec{ClusterIP: "10.0.0.1"},
+		}
+		_, err := clientset.CoreV1().Services(namespace).Create(service)
+		assert.Nil(t, err)
+	}
+
+	bundle, err := BackupIdentity(clientset, namespace)
+	assert.Nil(t, err)
+	assert.Equal(t, namespace, bundle.Namespace)
+	assert.Equal(t, 1, len(bundle.Secrets))
+	assert.Equal(t, 0, len(bundle.Secrets[0].OwnerReferences))
+	assert.Equal(t, "", bundle.Secrets[0].ResourceVersion)
+	assert.Equal(t, 4, len(bundle.ConfigMaps))
+	for _, cm := range bundle.ConfigMaps {
+		assert.True(t, isIdentityConfigMap(cm.Name))
+		assert.Equal(t, 0, len(cm.OwnerReferences))
+	}
+	assert.Equal(t, 1, len(bundle.Services))
+	assert.Equal(t, "rook-ceph-mon-a", bundle.Services[0].Name)
+
+	// no identity to back up
+	_, err = BackupIdentity(clientset, "other")
+	assert.NotNil(t, err)
+
+	// restore in a new cluster
+	restored := testop.New(3)
+	assert.Nil(t, RestoreIdentity(restored, bundle))
+	s, err := restored.CoreV1().Secrets(namespace).Get(mon.AppName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "monkey", string(s.Data["mon-secret"]))
+	cms, err := restored.CoreV1().ConfigMaps(namespace).List(metav1.ListOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(cms.Items))
+	svc, err := restored.CoreV1().Services(namespace).Get("rook-ceph-mon-a", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "10.0.0.1", svc.Spec.ClusterIP)
+
+	// restoring again keeps the existing objects
+	assert.Nil(t, RestoreIdentity(restored, bundle))
+
+	// the namespace holds the identity of another cluster
+	bundle.Secrets[0].Data["fsid"] = []byte("def")
+	assert.NotNil(t, RestoreIdentity(restored, bundle))
+}
+
+func TestEncodeIdentity(t *testing.T) {
+	bundle := &IdentityBundle{
+		Namespace: "ns",
+		Secrets:   []v1.Secret{{ObjectMeta: metav1.ObjectMeta{Name: mon.AppName}, Data: map[string][]byte{"fsid": []byte("abc")}}},
+	}
+
+	// plain bundle
+	data, err := EncodeIdentity(bundle, "")
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), mon.AppName)
+	decoded, err := DecodeIdentity(data, "")
+	assert.Nil(t, err)
+	assert.Equal(t, bundle, decoded)
+
+	// encrypted bundle
+	data, err = EncodeIdentity(bundle, "secret")
+	assert.Nil(t, err)
+	assert.NotContains(t, string(data), mon.AppName)
+	decoded, err = DecodeIdentity(data, "secret")
+	assert.Nil(t, err)
+	assert.Equal(t, bundle, decoded)
+
+	_, err = DecodeIdentity(data, "")
+	assert.NotNil(t, err)
+	_, err = DecodeIdentity(data, "wrong")
+	assert.NotNil(t, err)
+
+	// unknown version
+	_, err = DecodeIdentity([]byte(`{"version": 2}`), "")
+	assert.NotNil(t, err)
+}
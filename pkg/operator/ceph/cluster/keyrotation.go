@@ -0,0 +1,516 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/daemon/ceph/agent/flexvolume/attachment"
+	"github.com/rook/rook/pkg/daemon/ceph/client"
+	"github.com/rook/rook/pkg/operator/ceph/cluster/mon"
+	"github.com/rook/rook/pkg/operator/ceph/cluster/osd"
+	"github.com/rook/rook/pkg/operator/ceph/config"
+	"github.com/rook/rook/pkg/operator/ceph/config/keyring"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	apps "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// RotateKeysAnnotation is the annotation of the cluster CRD requesting to rotate the cephx keys. The operator
+	// removes it when the rotation starts.
+	RotateKeysAnnotation = "ceph.rook.io/rotate-keys"
+	// KeysRotatedAnnotation is the annotation of the cluster CRD with the time of the last successful key rotation
+	KeysRotatedAnnotation = "ceph.rook.io/keys-rotated"
+	// KeyRotationErrorAnnotation is the annotation of the cluster CRD with the error of a failed key rotation
+	KeyRotationErrorAnnotation = "ceph.rook.io/key-rotation-error"
+
+	defaultKeyRotationPeriodDays = 90
+	keyRotationCheckInterval     = time.Hour
+	keysRotatedReason            = "KeysRotated"
+	keyRotationFailedReason      = "KeyRotationFailed"
+	// adminMountUser is the user of the volumes mapped by the flex driver with the admin key
+	adminMountUser = "admin"
+)
+
+var (
+	// restartDeployment restarts the pods of a deployment using a rotated key and waits for them to be ready
+	restartDeployment = k8sutil.RestartDeploymentAndWait
+	// updateDeployment updates a deployment whose daemons connect with a new user and waits for them to be ready
+	updateDeployment = func(context *clusterd.Context, d *apps.Deployment, namespace string) error {
+		_, err := k8sutil.UpdateDeploymentAndWait(context, d, namespace)
+		return err
+	}
+	// updateDaemonSet updates a daemonset whose daemons connect with a new user and waits for them to be ready
+	updateDaemonSet = k8sutil.UpdateDaemonSetAndWait
+	// orchestrationWaitInterval is the interval at which a key rotation checks for the end of the orchestration
+	orchestrationWaitInterval = 5 * time.Second
+)
+
+// rotateKeys rotates the cephx keys of the daemons whose keyrings are stored in secrets, then the key of the admin. The
+// keys are rotated one user at a time, and the daemons of a user are ready with its new key before the key of the next
+// user is rotated. The rotation stops at the first user whose daemons fail to restart, after restoring its previous
+// key.
+func (c *cluster) rotateKeys(rookImage string, volumeAttachment attachment.Attachment) error {
+	// the orchestration would store the keys it read before the rotation
+	for c.checkSetOrchestrationRunning() {
+		logger.Infof("waiting for the orchestration of cluster %s to rotate the keys", c.Namespace)
+		time.Sleep(orchestrationWaitInterval)
+	}
+	err := c.rotateAllKeys(volumeAttachment)
+	c.unsetOrchestrationRunning()
+
+	// run the orchestration requested during the rotation
+	if c.checkUnsetOrchestrationPending() {
+		if err := c.createInstance(rookImage, c.Info.CephVersion); err != nil {
+			logger.Errorf("failed to update cluster in namespace %s after the key rotation. %+v", c.Namespace, err)
+		}
+	}
+	return err
+}
+
+func (c *cluster) rotateAllKeys(volumeAttachment attachment.Attachment) error {
+	logger.Infof("rotating the keys of cluster %s", c.Namespace)
+	k := keyring.GetSecretStore(c.context, c.Namespace, &c.ownerRef)
+	keyrings, err := k.List()
+	if err != nil {
+		return err
+	}
+	// the keyrings of a user share its key, such as the keyrings of the rgw of all the object stores. the keyrings of
+	// the generations of a client user are grouped under the user.
+	users := map[string][]keyring.StoredKeyring{}
+	for _, stored := range keyrings {
+		user := keyring.User(stored.Keyring)
+		// the mons share the mon. key, which is not rotated. the admin key is rotated last, and the key of the flex
+		// driver is not rotated since the kernel reconnects the mapped volumes with it.
+		if user == "" || user == "mon." || user == client.AdminUsername || user == keyring.FlexUser {
+			continue
+		}
+		if strings.HasPrefix(user, "client.") {
+			user, _ = client.UserGeneration(user)
+		}
+		users[user] = append(users[user], stored)
+	}
+	names := make([]string, 0, len(users))
+	for user := range users {
+		names = append(names, user)
+	}
+	sort.Strings(names)
+	for _, user := range names {
+		if strings.HasPrefix(user, "client.") {
+			err = c.rotateClientKey(k, user, users[user])
+		} else {
+			err = c.rotateDaemonKey(k, user, users[user])
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := c.checkAdminMappings(volumeAttachment); err != nil {
+		return err
+	}
+	if err := c.mons.RotateAdminKey(c.restartAdminClients); err != nil {
+		return err
+	}
+	logger.Infof("rotated the keys of cluster %s", c.Namespace)
+	return nil
+}
+
+// checkAdminMappings returns an error if volumes of the cluster are mapped with the admin key by the flex driver. The
+// kernel reconnects the mapped volumes with the key they were mapped with, so they would fail to reconnect after the
+// rotation of the admin key. The volumes mapped by this version of the driver use the flex user instead.
+func (c *cluster) checkAdminMappings(volumeAttachment attachment.Attachment) error {
+	namespace := os.Getenv(k8sutil.PodNamespaceEnvVar)
+	vols, err := volumeAttachment.List(namespace)
+	if err != nil {
+		return fmt.Errorf("failed to get volume attachments for operator namespace %s. %+v", namespace, err)
+	}
+	var mapped []string
+	for _, vol := range vols.Items {
+		for _, a := range vol.Attachments {
+			if a.ClusterName == c.Namespace && (a.MountUser == "" || a.MountUser == adminMountUser) {
+				mapped = append(mapped, vol.Name)
+				break
+			}
+		}
+	}
+	if len(mapped) > 0 {
+		return fmt.Errorf("the admin key is not rotated while volumes %v are mapped with it. remap them to use the %s user", mapped, keyring.FlexUser)
+	}
+	return nil
+}
+
+// restartAdminClients restarts the daemons reading the admin key when they start, such as the mgr, the nfs daemons
+// and the toolbox, and waits for them to be ready. The osds are not restarted since they only use the admin key when
+// they are prepared.
+func (c *cluster) restartAdminClients() error {
+	deployments, err := c.context.Clientset.Apps().Deployments(c.Namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list deployments. %+v", err)
+	}
+	for _, d := range deployments.Items {
+		if d.Labels[k8sutil.AppAttr] == osd.AppName || !usesAdminKey(d.Spec.Template.Spec) {
+			continue
+		}
+		if err := restartDeployment(c.context.Clientset, c.Namespace, d.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rotateClientKey rotates the key of a client user by creating the user of the next generation, such as
+// client.crash-2 for client.crash, with a new key and the caps of the user. The keyrings of the user are switched to
+// the new user one at a time, and the daemons using each keyring are updated to connect with the new user while the
+// other daemons still connect with the previous user. The previous users are deleted once all the daemons are ready
+// with the new user. If any step fails, the keyrings and the daemons are switched back and the new user is deleted.
+func (c *cluster) rotateClientKey(k *keyring.SecretStore, user string, keyrings []keyring.StoredKeyring) error {
+	generation := 0
+	previous := map[string]bool{}
+	for _, stored := range keyrings {
+		entity := keyring.User(stored.Keyring)
+		previous[entity] = true
+		if _, g := client.UserGeneration(entity); g > generation {
+			generation = g
+		}
+	}
+	newUser := client.GenerationUser(user, generation+1)
+
+	caps, err := client.AuthGetCaps(c.context, c.Namespace, keyring.User(keyrings[0].Keyring))
+	if err != nil {
+		return err
+	}
+	key, err := client.GenerateAuthKey(c.context)
+	if err != nil {
+		return err
+	}
+	if err := client.AuthImportKey(c.context, c.Namespace, newUser, key, caps); err != nil {
+		return err
+	}
+
+	if err := c.switchClientUser(k, newUser, key, keyrings); err != nil {
+		logger.Errorf("failed to rotate the key of %s. restoring the previous user. %+v", user, err)
+		if restoreErr := c.restoreClientUser(k, newUser, keyrings); restoreErr != nil {
+			return fmt.Errorf("failed to rotate the key of %s, and failed to restore the previous user. %+v. %+v", user, err, restoreErr)
+		}
+		return fmt.Errorf("failed to rotate the key of %s. restored the previous user. %+v", user, err)
+	}
+
+	entities := make([]string, 0, len(previous))
+	for entity := range previous {
+		entities = append(entities, entity)
+	}
+	sort.Strings(entities)
+	for _, entity := range entities {
+		if err := client.AuthDelete(c.context, c.Namespace, entity); err != nil {
+			return fmt.Errorf("rotated the key of %s to user %s, but failed to delete the previous user %s. %+v", user, newUser, entity, err)
+		}
+	}
+	logger.Infof("rotated the key of %s to user %s", user, newUser)
+	return nil
+}
+
+// switchClientUser stores the new user and its key in the keyrings of a client user, and updates the daemons using
+// each keyring to connect with the new user
+func (c *cluster) switchClientUser(k *keyring.SecretStore, newUser, key string, keyrings []keyring.StoredKeyring) error {
+	for _, stored := range keyrings {
+		if err := k.CreateOrUpdate(stored.ResourceName, keyring.WithUser(keyring.WithKey(stored.Keyring, key), newUser)); err != nil {
+			return fmt.Errorf("failed to save the key of %s. %+v", newUser, err)
+		}
+		if err := c.updateDaemonUser(stored.SecretName, keyring.User(stored.Keyring), newUser); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restoreClientUser stores the previous keyrings of a client user, updates the daemons using them to connect with
+// their previous user, and deletes the new user
+func (c *cluster) restoreClientUser(k *keyring.SecretStore, newUser string, keyrings []keyring.StoredKeyring) error {
+	for _, stored := range keyrings {
+		if err := k.CreateOrUpdate(stored.ResourceName, stored.Keyring); err != nil {
+			return fmt.Errorf("failed to save the previous key of %s. %+v", keyring.User(stored.Keyring), err)
+		}
+		if err := c.updateDaemonUser(stored.SecretName, newUser, keyring.User(stored.Keyring)); err != nil {
+			return err
+		}
+	}
+	return client.AuthDelete(c.context, c.Namespace, newUser)
+}
+
+// updateDaemonUser updates the deployments and the daemonsets mounting a keyring secret to connect with a new user,
+// and waits for their daemons to be ready
+func (c *cluster) updateDaemonUser(secretName, previous, user string) error {
+	deployments, err := c.context.Clientset.Apps().Deployments(c.Namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list deployments. %+v", err)
+	}
+	for i := range deployments.Items {
+		d := &deployments.Items[i]
+		if !usesSecret(d.Spec.Template.Spec, secretName) || !setPodUser(&d.Spec.Template.Spec, previous, user) {
+			continue
+		}
+		if err := updateDeployment(c.context, d, c.Namespace); err != nil {
+			return fmt.Errorf("failed to update deployment %s with user %s. %+v", d.Name, user, err)
+		}
+	}
+
+	daemonsets, err := c.context.Clientset.Apps().DaemonSets(c.Namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list daemonsets. %+v", err)
+	}
+	for i := range daemonsets.Items {
+		ds := &daemonsets.Items[i]
+		if !usesSecret(ds.Spec.Template.Spec, secretName) || !setPodUser(&ds.Spec.Template.Spec, previous, user) {
+			continue
+		}
+		if err := updateDaemonSet(c.context.Clientset, c.Namespace, ds); err != nil {
+			return fmt.Errorf("failed to update daemonset %s with user %s. %+v", ds.Name, user, err)
+		}
+	}
+	return nil
+}
+
+// rotateDaemonKey rotates the key of a mgr or mds user in place. Their users are named after the daemons, which cannot
+// be renamed, so the new key replaces the key of the user and each daemon is restarted right away. The running daemon
+// keeps its authenticated session until it is restarted with the new key. The previous key is imported and stored
+// again if any step fails.
+func (c *cluster) rotateDaemonKey(k *keyring.SecretStore, entity string, keyrings []keyring.StoredKeyring) error {
+	caps, err := client.AuthGetCaps(c.context, c.Namespace, entity)
+	if err != nil {
+		return err
+	}
+	key, err := client.GenerateAuthKey(c.context)
+	if err != nil {
+		return err
+	}
+	err = c.applyDaemonKey(k, entity, key, caps, keyrings)
+	if err == nil {
+		logger.Infof("rotated the key of %s", entity)
+		return nil
+	}
+
+	logger.Errorf("failed to rotate the key of %s. restoring the previous key. %+v", entity, err)
+	if restoreErr := c.applyDaemonKey(k, entity, keyring.Key(keyrings[0].Keyring), caps, keyrings); restoreErr != nil {
+		return fmt.Errorf("failed to rotate the key of %s, and failed to restore the previous key. %+v. %+v", entity, err, restoreErr)
+	}
+	return fmt.Errorf("failed to rotate the key of %s. restored the previous key. %+v", entity, err)
+}
+
+// applyDaemonKey imports the key of a user, stores it in the keyrings of the user and restarts the daemons using them
+func (c *cluster) applyDaemonKey(k *keyring.SecretStore, entity, key string, caps map[string]string, keyrings []keyring.StoredKeyring) error {
+	if err := client.AuthImportKey(c.context, c.Namespace, entity, key, caps); err != nil {
+		return err
+	}
+	var secrets []string
+	for _, stored := range keyrings {
+		if err := k.CreateOrUpdate(stored.ResourceName, keyring.WithKey(stored.Keyring, key)); err != nil {
+			return fmt.Errorf("failed to save the key of %s. %+v", entity, err)
+		}
+		secrets = append(secrets, stored.SecretName)
+	}
+
+	deployments, err := c.context.Clientset.Apps().Deployments(c.Namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list deployments. %+v", err)
+	}
+	for _, d := range deployments.Items {
+		if !usesAnySecret(d.Spec.Template.Spec, secrets) {
+			continue
+		}
+		if err := restartDeployment(c.context.Clientset, c.Namespace, d.Name); err != nil {
+			return fmt.Errorf("failed to restart deployment %s with the key of %s. %+v", d.Name, entity, err)
+		}
+	}
+	return nil
+}
+
+// rotateKeys rotates the keys of the cluster if it is requested with the rotate annotation of the cluster CRD, or if
+// the scheduled rotation is due. Returns whether a rotation was started.
+func (c *ClusterController) rotateKeys(cluster *cluster, name string) bool {
+	// get the latest object since the events queued during a rotation still have the annotation
+	clust, err := c.context.RookClientset.CephV1().CephClusters(cluster.Namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		logger.Errorf("failed to get cluster %s to check for a key rotation. %+v", cluster.Namespace, err)
+		return false
+	}
+	if _, ok := clust.Annotations[RotateKeysAnnotation]; !ok && !keyRotationDue(clust, time.Now()) {
+		return false
+	}
+
+	// remove the request before the rotation so it is never repeated
+	if clust.Annotations != nil {
+		delete(clust.Annotations, RotateKeysAnnotation)
+		delete(clust.Annotations, KeyRotationErrorAnnotation)
+		if _, err := c.context.RookClientset.CephV1().CephClusters(cluster.Namespace).Update(clust); err != nil {
+			logger.Errorf("failed to start the key rotation of cluster %s. %+v", cluster.Namespace, err)
+			return true
+		}
+	}
+
+	rotateErr := cluster.rotateKeys(c.rookImage, c.volumeAttachment)
+	if rotateErr != nil {
+		logger.Errorf("failed to rotate the keys of cluster %s. %+v", cluster.Namespace, rotateErr)
+		cluster.events.Warningf(keyRotationFailedReason, "failed to rotate the keys. %+v", rotateErr)
+	} else {
+		cluster.events.Normalf(keysRotatedReason, "rotated the keys of the cluster")
+	}
+
+	clust, err = c.context.RookClientset.CephV1().CephClusters(cluster.Namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		logger.Errorf("failed to get cluster %s to save the key rotation status. %+v", cluster.Namespace, err)
+		return true
+	}
+	if clust.Annotations == nil {
+		clust.Annotations = map[string]string{}
+	}
+	if rotateErr != nil {
+		clust.Annotations[KeyRotationErrorAnnotation] = rotateErr.Error()
+	} else {
+		clust.Annotations[KeysRotatedAnnotation] = time.Now().UTC().Format(time.RFC3339)
+	}
+	if _, err := c.context.RookClientset.CephV1().CephClusters(cluster.Namespace).Update(clust); err != nil {
+		logger.Errorf("failed to save the key rotation status of cluster %s. %+v", cluster.Namespace, err)
+	}
+	return true
+}
+
+// checkKeyRotation rotates the keys of the cluster when the scheduled rotation is due, or when the rotation is
+// requested with the annotation, until the cluster is stopped. All the rotations of the cluster run in this goroutine
+// so they never run at the same time. A failed scheduled rotation is retried at the next check.
+func (c *ClusterController) checkKeyRotation(cluster *cluster, name string) {
+	for {
+		c.rotateKeys(cluster, name)
+		select {
+		case <-cluster.stopCh:
+			logger.Infof("stopping the key rotation checks of cluster %s", cluster.Namespace)
+			return
+		case <-cluster.rotateKeysCh:
+		case <-time.After(keyRotationCheckInterval):
+		}
+	}
+}
+
+// requestKeyRotation wakes up the key rotation checks of the cluster. The request is dropped if a check is already
+// pending, since the check reads the latest annotations of the cluster CRD.
+func (c *cluster) requestKeyRotation() {
+	select {
+	case c.rotateKeysCh <- struct{}{}:
+	default:
+	}
+}
+
+// keyRotationDue returns whether the rotation period elapsed since the last rotation of the keys, or since the creation
+// of the cluster if the keys were never rotated
+func keyRotationDue(clust *cephv1.CephCluster, now time.Time) bool {
+	spec := clust.Spec.Security.KeyRotation
+	if !spec.Enabled {
+		return false
+	}
+	periodDays := spec.PeriodDays
+	if periodDays <= 0 {
+		periodDays = defaultKeyRotationPeriodDays
+	}
+
+	last := clust.CreationTimestamp.Time
+	if rotated, ok := clust.Annotations[KeysRotatedAnnotation]; ok {
+		if t, err := time.Parse(time.RFC3339, rotated); err == nil {
+			last = t
+		} else {
+			logger.Warningf("invalid key rotation time %s in cluster %s. %+v", rotated, clust.Namespace, err)
+		}
+	}
+	return now.Sub(last) >= time.Duration(periodDays)*24*time.Hour
+}
+
+// setPodUser replaces the --name flag of a user in the args and in the CEPH_ARGS of the containers of a pod. Returns
+// whether the pod changed.
+func setPodUser(spec *v1.PodSpec, previous, user string) bool {
+	flag := config.NewFlag("name", previous)
+	newFlag := config.NewFlag("name", user)
+	changed := false
+	for _, containers := range [][]v1.Container{spec.InitContainers, spec.Containers} {
+		for i := range containers {
+			for j, arg := range containers[i].Args {
+				if arg == flag {
+					containers[i].Args[j] = newFlag
+					changed = true
+				}
+			}
+			for j, env := range containers[i].Env {
+				args := strings.Fields(env.Value)
+				envChanged := false
+				for m, arg := range args {
+					if arg == flag {
+						args[m] = newFlag
+						envChanged = true
+					}
+				}
+				if envChanged {
+					containers[i].Env[j].Value = strings.Join(args, " ")
+					changed = true
+				}
+			}
+		}
+	}
+	return changed
+}
+
+// usesAdminKey returns whether a pod reads the admin key from the mon secret or mounts the admin keyring
+func usesAdminKey(spec v1.PodSpec) bool {
+	if usesSecret(spec, keyring.Volume().Admin().Secret.SecretName) {
+		return true
+	}
+	ref := mon.AdminSecretEnvVar().ValueFrom.SecretKeyRef
+	for _, containers := range [][]v1.Container{spec.InitContainers, spec.Containers} {
+		for _, container := range containers {
+			for _, env := range container.Env {
+				if env.ValueFrom != nil && env.ValueFrom.SecretKeyRef != nil &&
+					env.ValueFrom.SecretKeyRef.Name == ref.Name && env.ValueFrom.SecretKeyRef.Key == ref.Key {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func usesSecret(spec v1.PodSpec, secretName string) bool {
+	return usesAnySecret(spec, []string{secretName})
+}
+
+func usesAnySecret(spec v1.PodSpec, secretNames []string) bool {
+	for _, volume := range spec.Volumes {
+		if volume.Secret == nil {
+			continue
+		}
+		for _, name := range secretNames {
+			if volume.Secret.SecretName == name {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,372 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	rookalpha "github.com/rook/rook/pkg/apis/rook.io/v1alpha2"
+	rookfake "github.com/rook/rook/pkg/client/clientset/versioned/fake"
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/daemon/ceph/agent/flexvolume/attachment"
+	"github.com/rook/rook/pkg/operator/ceph/cluster/mon"
+	"github.com/rook/rook/pkg/operator/ceph/cluster/osd"
+	"github.com/rook/rook/pkg/operator/ceph/config/keyring"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	testop "github.com/rook/rook/pkg/operator/test"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apps "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const testMgrKeyring = `
+[mgr.a]
+	key = oldkey==
+	caps mon = "allow *"
+`
+
+func TestKeyRotationDue(t *testing.T) {
+	created := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	clust := &cephv1.CephCluster{ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.NewTime(created)}}
+
+	// the keys are not rotated on a schedule by default
+	assert.False(t, keyRotationDue(clust, created.Add(365*24*time.Hour)))
+
+	// the keys are rotated every 90 days by default, starting from the creation of the cluster
+	clust.Spec.Security.KeyRotation.Enabled = true
+	assert.False(t, keyRotationDue(clust, created.Add(89*24*time.Hour)))
+	assert.True(t, keyRotationDue(clust, created.Add(90*24*time.Hour)))
+
+	// the period starts again at the last rotation
+	clust.Annotations = map[string]string{KeysRotatedAnnotation: created.Add(60 * 24 * time.Hour).Format(time.RFC3339)}
+	assert.False(t, keyRotationDue(clust, created.Add(90*24*time.Hour)))
+	assert.True(t, keyRotationDue(clust, created.Add(150*24*time.Hour)))
+
+	clust.Spec.Security.KeyRotation.PeriodDays = 30
+	assert.True(t, keyRotationDue(clust, created.Add(90*24*time.Hour)))
+}
+
+func TestRotateDaemonKey(t *testing.T) {
+	configDir, err := ioutil.TempDir("", "")
+	require.Nil(t, err)
+	defer os.RemoveAll(configDir)
+	require.Nil(t, os.MkdirAll(path.Join(configDir, "ns"), 0744))
+
+	var imported []string
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName string, command string, args ...string) (string, error) {
+			if command == "ceph-authtool" {
+				return "newkey==", nil
+			}
+			return "", fmt.Errorf("unexpected command %s %v", command, args)
+		},
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName string, command string, outFileArg string, args ...string) (string, error) {
+			if args[1] == "get" {
+				return `[{"entity":"mgr.a","key":"oldkey==","caps":{"mon":"allow *"}}]`, nil
+			}
+			if args[1] == "import" {
+				keyring, err := ioutil.ReadFile(args[3])
+				assert.Nil(t, err)
+				imported = append(imported, string(keyring))
+				return "", nil
+			}
+			return "", fmt.Errorf("unexpected ceph command %v", args)
+		},
+	}
+	clientset := testop.New(1)
+	context := &clusterd.Context{Clientset: clientset, Executor: executor, ConfigDir: configDir}
+	c := newCluster(&cephv1.CephCluster{ObjectMeta: metav1.ObjectMeta{Namespace: "ns"}}, context)
+
+	k := keyring.GetSecretStore(context, "ns", &c.ownerRef)
+	require.Nil(t, k.CreateOrUpdate("rook-ceph-mgr-a", testMgrKeyring))
+	for name, secret := range map[string]string{"rook-ceph-mgr-a": "rook-ceph-mgr-a-keyring", "rook-ceph-mds-a": "rook-ceph-mds-a-keyring"} {
+		d := &apps.Deployment{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns"}}
+		d.Spec.Template.Spec.Volumes = []v1.Volume{keyring.Volume().Resource(name)}
+		_, err := clientset.Apps().Deployments("ns").Create(d)
+		require.Nil(t, err)
+		assert.Equal(t, secret, d.Spec.Template.Spec.Volumes[0].Secret.SecretName)
+	}
+
+	var restarted []string
+	restartDeployment = func(clientset kubernetes.Interface, namespace, name string) error {
+		restarted = append(restarted, name)
+		return nil
+	}
+	defer func() { restartDeployment = k8sutil.RestartDeploymentAndWait }()
+
+	keyrings, err := k.List()
+	require.Nil(t, err)
+	require.Equal(t, 1, len(keyrings))
+	err = c.rotateDaemonKey(k, "mgr.a", keyrings)
+	assert.Nil(t, err)
+	require.Equal(t, 1, len(imported))
+	assert.Contains(t, imported[0], "key = newkey==")
+
+	// the new key is stored and only the daemon using the key is restarted
+	secret, err := clientset.CoreV1().Secrets("ns").Get("rook-ceph-mgr-a-keyring", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Contains(t, secret.StringData["keyring"], "key = newkey==")
+	assert.Equal(t, []string{"rook-ceph-mgr-a"}, restarted)
+}
+
+func TestRotateClientKey(t *testing.T) {
+	configDir, err := ioutil.TempDir("", "")
+	require.Nil(t, err)
+	defer os.RemoveAll(configDir)
+	require.Nil(t, os.MkdirAll(path.Join(configDir, "ns"), 0744))
+
+	var imported, deleted []string
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName string, command string, args ...string) (string, error) {
+			if command == "ceph-authtool" {
+				return "newkey==", nil
+			}
+			return "", fmt.Errorf("unexpected command %s %v", command, args)
+		},
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName string, command string, outFileArg string, args ...string) (string, error) {
+			if args[1] == "get" {
+				assert.Equal(t, "client.radosgw.gateway", args[2])
+				return `[{"entity":"client.radosgw.gateway","key":"oldkey==","caps":{"mon":"allow rw"}}]`, nil
+			}
+			if args[1] == "import" {
+				keyring, err := ioutil.ReadFile(args[3])
+				assert.Nil(t, err)
+				imported = append(imported, string(keyring))
+				return "", nil
+			}
+			if args[1] == "del" {
+				deleted = append(deleted, args[2])
+				return "", nil
+			}
+			return "", fmt.Errorf("unexpected ceph command %v", args)
+		},
+	}
+	clientset := testop.New(1)
+	context := &clusterd.Context{Clientset: clientset, Executor: executor, ConfigDir: configDir}
+	c := newCluster(&cephv1.CephCluster{ObjectMeta: metav1.ObjectMeta{Namespace: "ns"}}, context)
+
+	// the rgw of all the object stores share the same user
+	k := keyring.GetSecretStore(context, "ns", &c.ownerRef)
+	for _, name := range []string{"rook-ceph-rgw-a", "rook-ceph-rgw-b"} {
+		require.Nil(t, k.CreateOrUpdate(name, "[client.radosgw.gateway]\n\tkey = oldkey==\n"))
+		d := &apps.Deployment{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns"}}
+		d.Spec.Template.Spec.Volumes = []v1.Volume{keyring.Volume().Resource(name)}
+		d.Spec.Template.Spec.Containers = []v1.Container{{Args: []string{"--id", "a", "--name=client.radosgw.gateway"}}}
+		_, err := clientset.Apps().Deployments("ns").Create(d)
+		require.Nil(t, err)
+	}
+
+	var updated []string
+	updateDeployment = func(context *clusterd.Context, d *apps.Deployment, namespace string) error {
+		updated = append(updated, d.Name)
+		_, err := context.Clientset.Apps().Deployments(namespace).Update(d)
+		return err
+	}
+	defer func() {
+		updateDeployment = func(context *clusterd.Context, d *apps.Deployment, namespace string) error {
+			_, err := k8sutil.UpdateDeploymentAndWait(context, d, namespace)
+			return err
+		}
+	}()
+
+	keyrings, err := k.List()
+	require.Nil(t, err)
+	require.Equal(t, 2, len(keyrings))
+	err = c.rotateClientKey(k, "client.radosgw.gateway", keyrings)
+	assert.Nil(t, err)
+
+	// the new key is imported once for the next generation of the user, which both stores switch to
+	require.Equal(t, 1, len(imported))
+	assert.Contains(t, imported[0], "[client.radosgw.gateway-2]")
+	assert.Contains(t, imported[0], "key = newkey==")
+	assert.Contains(t, imported[0], "caps mon = \"allow rw\"")
+	assert.ElementsMatch(t, []string{"rook-ceph-rgw-a", "rook-ceph-rgw-b"}, updated)
+	for _, name := range []string{"rook-ceph-rgw-a", "rook-ceph-rgw-b"} {
+		secret, err := clientset.CoreV1().Secrets("ns").Get(name+"-keyring", metav1.GetOptions{})
+		assert.Nil(t, err)
+		assert.Equal(t, "[client.radosgw.gateway-2]\n\tkey = newkey==\n", secret.StringData["keyring"])
+		d, err := clientset.Apps().Deployments("ns").Get(name, metav1.GetOptions{})
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"--id", "a", "--name=client.radosgw.gateway-2"}, d.Spec.Template.Spec.Containers[0].Args)
+	}
+	// the previous user is deleted once the daemons are ready with the new user
+	assert.Equal(t, []string{"client.radosgw.gateway"}, deleted)
+
+	// the second daemon is not ready with the next generation
+	imported, deleted, updated = nil, nil, nil
+	updateDeployment = func(context *clusterd.Context, d *apps.Deployment, namespace string) error {
+		updated = append(updated, d.Name)
+		if len(updated) == 2 {
+			return fmt.Errorf("mock update failure")
+		}
+		_, err := context.Clientset.Apps().Deployments(namespace).Update(d)
+		return err
+	}
+	executor.MockExecuteCommandWithOutputFile = func(debug bool, actionName string, command string, outFileArg string, args ...string) (string, error) {
+		if args[1] == "get" {
+			return `[{"entity":"client.radosgw.gateway-2","key":"newkey==","caps":{"mon":"allow rw"}}]`, nil
+		}
+		if args[1] == "import" {
+			keyring, err := ioutil.ReadFile(args[3])
+			assert.Nil(t, err)
+			imported = append(imported, string(keyring))
+			return "", nil
+		}
+		if args[1] == "del" {
+			deleted = append(deleted, args[2])
+			return "", nil
+		}
+		return "", fmt.Errorf("unexpected ceph command %v", args)
+	}
+	keyrings, err = k.List()
+	require.Nil(t, err)
+	err = c.rotateClientKey(k, "client.radosgw.gateway", keyrings)
+	assert.NotNil(t, err)
+
+	// the keyrings and the daemons are switched back to the previous user, and the new user is deleted
+	require.Equal(t, 1, len(imported))
+	assert.Contains(t, imported[0], "[client.radosgw.gateway-3]")
+	assert.Equal(t, []string{"client.radosgw.gateway-3"}, deleted)
+	for _, name := range []string{"rook-ceph-rgw-a", "rook-ceph-rgw-b"} {
+		secret, err := clientset.CoreV1().Secrets("ns").Get(name+"-keyring", metav1.GetOptions{})
+		assert.Nil(t, err)
+		assert.Equal(t, "[client.radosgw.gateway-2]\n\tkey = newkey==\n", secret.StringData["keyring"])
+		d, err := clientset.Apps().Deployments("ns").Get(name, metav1.GetOptions{})
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"--id", "a", "--name=client.radosgw.gateway-2"}, d.Spec.Template.Spec.Containers[0].Args)
+	}
+}
+
+func TestSetPodUser(t *testing.T) {
+	spec := v1.PodSpec{
+		InitContainers: []v1.Container{{Args: []string{"--name=client.crash"}}},
+		Containers: []v1.Container{{Env: []v1.EnvVar{
+			{Name: "CEPH_ARGS", Value: "--mon-host=1.2.3.4 --name=client.crash --keyring=/etc/ceph/keyring"},
+			{Name: "OTHER", Value: "--name=client.crash-2"},
+		}}},
+	}
+	assert.True(t, setPodUser(&spec, "client.crash", "client.crash-2"))
+	assert.Equal(t, []string{"--name=client.crash-2"}, spec.InitContainers[0].Args)
+	assert.Equal(t, "--mon-host=1.2.3.4 --name=client.crash-2 --keyring=/etc/ceph/keyring", spec.Containers[0].Env[0].Value)
+	assert.Equal(t, "--name=client.crash-2", spec.Containers[0].Env[1].Value)
+
+	// the pod does not change if it connects with another user
+	assert.False(t, setPodUser(&spec, "client.rbd-mirror.a", "client.rbd-mirror.a-2"))
+}
+
+func TestRestartAdminClients(t *testing.T) {
+	clientset := testop.New(1)
+	context := &clusterd.Context{Clientset: clientset}
+	c := newCluster(&cephv1.CephCluster{ObjectMeta: metav1.ObjectMeta{Namespace: "ns"}}, context)
+
+	// the nfs daemons and the toolbox read the admin key from the mon secret, the mgr mounts the admin keyring, and the
+	// osds only read the admin key when they are prepared
+	pods := map[string]v1.PodSpec{
+		"rook-ceph-nfs-a": {Containers: []v1.Container{{Env: []v1.EnvVar{mon.AdminSecretEnvVar()}}}},
+		"rook-ceph-tools": {InitContainers: []v1.Container{{Env: []v1.EnvVar{mon.AdminSecretEnvVar()}}}},
+		"rook-ceph-mgr-a": {Volumes: []v1.Volume{keyring.Volume().Admin()}},
+		"rook-ceph-osd-0": {Containers: []v1.Container{{Env: []v1.EnvVar{mon.AdminSecretEnvVar()}}}},
+		"rook-ceph-rgw-a": {Volumes: []v1.Volume{keyring.Volume().Resource("rook-ceph-rgw-a")}},
+	}
+	for name, spec := range pods {
+		d := &apps.Deployment{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns"}}
+		if name == "rook-ceph-osd-0" {
+			d.Labels = map[string]string{k8sutil.AppAttr: osd.AppName}
+		}
+		d.Spec.Template.Spec = spec
+		_, err := clientset.Apps().Deployments("ns").Create(d)
+		require.Nil(t, err)
+	}
+
+	var restarted []string
+	restartDeployment = func(clientset kubernetes.Interface, namespace, name string) error {
+		restarted = append(restarted, name)
+		return nil
+	}
+	defer func() { restartDeployment = k8sutil.RestartDeploymentAndWait }()
+
+	assert.Nil(t, c.restartAdminClients())
+	assert.ElementsMatch(t, []string{"rook-ceph-nfs-a", "rook-ceph-tools", "rook-ceph-mgr-a"}, restarted)
+}
+
+func TestCheckAdminMappings(t *testing.T) {
+	c := newCluster(&cephv1.CephCluster{ObjectMeta: metav1.ObjectMeta{Namespace: "ns"}}, &clusterd.Context{})
+	volumes := &rookalpha.VolumeList{Items: []rookalpha.Volume{
+		{ObjectMeta: metav1.ObjectMeta{Name: "pvc-1"}, Attachments: []rookalpha.Attachment{{ClusterName: "ns", MountUser: "rook-flex"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "pvc-2"}, Attachments: []rookalpha.Attachment{{ClusterName: "other", MountUser: "admin"}}},
+	}}
+	volumeAttachment := &attachment.MockAttachment{
+		MockList: func(namespace string) (*rookalpha.VolumeList, error) {
+			return volumes, nil
+		},
+	}
+	assert.Nil(t, c.checkAdminMappings(volumeAttachment))
+
+	// the volumes mapped before the flex user was created are mapped with the admin key
+	volumes.Items = append(volumes.Items, rookalpha.Volume{
+		ObjectMeta:  metav1.ObjectMeta{Name: "pvc-3"},
+		Attachments: []rookalpha.Attachment{{ClusterName: "ns"}},
+	})
+	err := c.checkAdminMappings(volumeAttachment)
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "pvc-3")
+}
+
+func TestRotateKeysAnnotation(t *testing.T) {
+	clust := &cephv1.CephCluster{ObjectMeta: metav1.ObjectMeta{
+		Name:        "rook-ceph",
+		Namespace:   "ns",
+		Annotations: map[string]string{RotateKeysAnnotation: "", KeyRotationErrorAnnotation: "previous error"},
+	}}
+	rookClientset := rookfake.NewSimpleClientset(clust)
+	context := &clusterd.Context{Clientset: testop.New(1), RookClientset: rookClientset, Executor: &exectest.MockExecutor{}}
+	controller := NewClusterController(context, "", &attachment.MockAttachment{})
+	c := newCluster(clust, context)
+
+	// the rotation fails since the mons were not started
+	assert.True(t, controller.rotateKeys(c, "rook-ceph"))
+	updated, err := rookClientset.CephV1().CephClusters("ns").Get("rook-ceph", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.NotContains(t, updated.Annotations, RotateKeysAnnotation)
+	assert.NotContains(t, updated.Annotations, KeysRotatedAnnotation)
+	assert.NotEqual(t, "previous error", updated.Annotations[KeyRotationErrorAnnotation])
+	assert.NotEmpty(t, updated.Annotations[KeyRotationErrorAnnotation])
+
+	// the rotation is not repeated
+	assert.False(t, controller.rotateKeys(c, "rook-ceph"))
+}
+
+func TestRequestKeyRotation(t *testing.T) {
+	c := newCluster(&cephv1.CephCluster{ObjectMeta: metav1.ObjectMeta{Namespace: "ns"}}, &clusterd.Context{})
+
+	// the requests do not block the events while a rotation is pending
+	c.requestKeyRotation()
+	c.requestKeyRotation()
+	assert.Equal(t, 1, len(c.rotateKeysCh))
+	<-c.rotateKeysCh
+	assert.Equal(t, 0, len(c.rotateKeysCh))
+}
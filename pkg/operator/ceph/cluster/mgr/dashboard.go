@@ -22,9 +22,11 @@ import (
 	"math/rand"
 	"os/exec"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
 	"github.com/rook/rook/pkg/daemon/ceph/client"
 	"github.com/rook/rook/pkg/operator/k8sutil"
 	"k8s.io/api/core/v1"
@@ -38,10 +40,13 @@ const (
 	dashboardPortHTTP              = 7000
 	dashboardUsername              = "admin"
 	dashboardPasswordName          = "rook-ceph-dashboard-password"
+	dashboardCertKey               = "mgr/dashboard/crt"
+	dashboardCertPrivateKey        = "mgr/dashboard/key"
 	passwordLength                 = 10
 	passwordKeyName                = "password"
 	certAlreadyConfiguredErrorCode = 5
 	invalidArgErrorCode            = int(syscall.EINVAL)
+	externalDNSHostnameAnnotation  = "external-dns.alpha.kubernetes.io/hostname"
 )
 
 var (
@@ -80,14 +85,114 @@ func (c *Cluster) configureDashboard(port int) error {
 		} else {
 			logger.Infof("dashboard service started")
 		}
+
+		if err := c.configureDashboardExternalAccess(port); err != nil {
+			return fmt.Errorf("failed to configure dashboard external access. %+v", err)
+		}
 	} else {
 		// delete the dashboard service if it exists
 		err := c.context.Clientset.CoreV1().Services(c.Namespace).Delete(dashboardService.Name, &metav1.DeleteOptions{})
 		if err != nil && !errors.IsNotFound(err) {
 			return fmt.Errorf("failed to delete dashboard service. %+v", err)
 		}
+		if err := c.removeDashboardExternalAccess(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// configureDashboardExternalAccess creates or updates the ingress or load balancer service exposing the dashboard,
+// and removes the one that is not requested
+func (c *Cluster) configureDashboardExternalAccess(port int) error {
+	access := c.dashboard.ExternalAccess
+	if access == nil {
+		return c.removeDashboardExternalAccess()
+	}
+
+	switch access.Type {
+	case cephv1.DashboardExternalAccessIngress:
+		if err := c.deleteDashboardLoadBalancer(); err != nil {
+			return err
+		}
+		return c.createOrUpdateDashboardIngress(port)
+	case cephv1.DashboardExternalAccessLoadBalancer:
+		if err := c.deleteDashboardIngress(); err != nil {
+			return err
+		}
+		return c.createOrUpdateDashboardLoadBalancer(port)
+	default:
+		return fmt.Errorf("unknown dashboard external access type %q", access.Type)
+	}
+}
+
+func (c *Cluster) createOrUpdateDashboardIngress(port int) error {
+	ingress := c.makeDashboardIngress(appName, port)
+	ingresses := c.context.Clientset.ExtensionsV1beta1().Ingresses(c.Namespace)
+	if _, err := ingresses.Create(ingress); err != nil {
+		if !errors.IsAlreadyExists(err) {
+			return fmt.Errorf("failed to create dashboard ingress. %+v", err)
+		}
+		original, err := ingresses.Get(ingress.Name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get dashboard ingress. %+v", err)
+		}
+		original.Annotations = ingress.Annotations
+		original.Spec = ingress.Spec
+		if _, err := ingresses.Update(original); err != nil {
+			return fmt.Errorf("failed to update dashboard ingress. %+v", err)
+		}
+		return nil
+	}
+	logger.Infof("dashboard ingress created")
+	return nil
+}
+
+func (c *Cluster) createOrUpdateDashboardLoadBalancer(port int) error {
+	service := c.makeDashboardLoadBalancerService(appName, port)
+	services := c.context.Clientset.CoreV1().Services(c.Namespace)
+	if _, err := services.Create(service); err != nil {
+		if !errors.IsAlreadyExists(err) {
+			return fmt.Errorf("failed to create dashboard load balancer service. %+v", err)
+		}
+		original, err := services.Get(service.Name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get dashboard load balancer service. %+v", err)
+		}
+		// keep the cluster IP and node ports that were allocated to the service
+		original.Annotations = service.Annotations
+		original.Spec.Ports[0].Port = service.Spec.Ports[0].Port
+		original.Spec.Ports[0].TargetPort = service.Spec.Ports[0].TargetPort
+		if _, err := services.Update(original); err != nil {
+			return fmt.Errorf("failed to update dashboard load balancer service. %+v", err)
+		}
+		return nil
+	}
+	logger.Infof("dashboard load balancer service created")
+	return nil
+}
+
+func (c *Cluster) removeDashboardExternalAccess() error {
+	if err := c.deleteDashboardIngress(); err != nil {
+		return err
+	}
+	return c.deleteDashboardLoadBalancer()
+}
+
+func (c *Cluster) deleteDashboardIngress() error {
+	err := c.context.Clientset.ExtensionsV1beta1().Ingresses(c.Namespace).Delete(dashboardExternalName(appName), &metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete dashboard ingress. %+v", err)
 	}
+	return nil
+}
 
+func (c *Cluster) deleteDashboardLoadBalancer() error {
+	err := c.context.Clientset.CoreV1().Services(c.Namespace).Delete(dashboardExternalName(appName), &metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete dashboard load balancer service. %+v", err)
+	}
 	return nil
 }
 
@@ -163,12 +268,22 @@ func (c *Cluster) initializeSecureDashboard() error {
 	}
 
 	if c.dashboard.SSL == nil || *c.dashboard.SSL {
-		alreadyCreated, err := c.createSelfSignedCert()
-		if err != nil {
-			return fmt.Errorf("failed to create a self signed cert. %+v", err)
-		}
-		if alreadyCreated {
-			return nil
+		if c.dashboard.CertificateSecretName != "" {
+			changed, err := c.setCertFromSecret()
+			if err != nil {
+				return fmt.Errorf("failed to set the dashboard cert from secret %s. %+v", c.dashboard.CertificateSecretName, err)
+			}
+			if !changed {
+				return nil
+			}
+		} else {
+			alreadyCreated, err := c.createSelfSignedCert()
+			if err != nil {
+				return fmt.Errorf("failed to create a self signed cert. %+v", err)
+			}
+			if alreadyCreated {
+				return nil
+			}
 		}
 	}
 
@@ -206,6 +321,41 @@ func (c *Cluster) createSelfSignedCert() (bool, error) {
 	return false, nil
 }
 
+// setCertFromSecret configures the dashboard with the cert and key of a kubernetes.io/tls secret.
+// Returns whether the cert was changed.
+func (c *Cluster) setCertFromSecret() (bool, error) {
+	secret, err := c.context.Clientset.CoreV1().Secrets(c.Namespace).Get(c.dashboard.CertificateSecretName, metav1.GetOptions{})
+	if err != nil {
+		return false, fmt.Errorf("failed to get secret. %+v", err)
+	}
+	cert, ok := secret.Data[v1.TLSCertKey]
+	if !ok {
+		return false, fmt.Errorf("%s not found in secret", v1.TLSCertKey)
+	}
+	key, ok := secret.Data[v1.TLSPrivateKeyKey]
+	if !ok {
+		return false, fmt.Errorf("%s not found in secret", v1.TLSPrivateKeyKey)
+	}
+
+	// the cert is public, so it can be compared to the cert the dashboard is currently using
+	buf, err := client.ExecuteCephCommand(c.context, c.Namespace, []string{"config-key", "get", dashboardCertKey})
+	if err == nil && strings.TrimSpace(string(buf)) == strings.TrimSpace(string(cert)) {
+		logger.Infof("dashboard is already configured with the cert from secret %s", secret.Name)
+		return false, nil
+	}
+
+	if _, err := client.ExecuteCephCommand(c.context, c.Namespace, []string{"config-key", "set", dashboardCertKey, string(cert)}); err != nil {
+		return false, fmt.Errorf("failed to set dashboard cert. %+v", err)
+	}
+	// Write the command/args to the debug log so we don't write the private key by default to the log.
+	logger.Infof("Running command: ceph config-key set %s *******", dashboardCertPrivateKey)
+	if _, err := client.ExecuteCephCommandDebugLog(c.context, c.Namespace, []string{"config-key", "set", dashboardCertPrivateKey, string(key)}); err != nil {
+		return false, fmt.Errorf("failed to set dashboard cert key. %+v", err)
+	}
+	logger.Infof("dashboard cert set from secret %s", secret.Name)
+	return true, nil
+}
+
 // Get the return code from the process
 func getExitCode(err error) (int, bool) {
 	if exiterr, ok := err.(*exec.ExitError); ok {
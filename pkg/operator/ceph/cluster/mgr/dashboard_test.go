@@ -27,6 +27,7 @@ import (
 	exectest "github.com/rook/rook/pkg/util/exec/test"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -130,3 +131,91 @@ func TestStartSecureDashboard(t *testing.T) {
 	assert.True(t, errors.IsNotFound(err))
 	assert.Nil(t, svc)
 }
+
+func TestDashboardExternalAccess(t *testing.T) {
+	c := &Cluster{context: &clusterd.Context{Clientset: test.New(3)}, Namespace: "myns",
+		dashboard: cephv1.DashboardSpec{Enabled: true, CertificateSecretName: "dashboard-tls",
+			ExternalAccess: &cephv1.DashboardExternalAccessSpec{Type: cephv1.DashboardExternalAccessIngress, Hostname: "ceph.example.com"}}}
+	ingresses := c.context.Clientset.ExtensionsV1beta1().Ingresses(c.Namespace)
+	services := c.context.Clientset.CoreV1().Services(c.Namespace)
+
+	// expose with an ingress
+	err := c.configureDashboardExternalAccess(dashboardPortHTTPS)
+	assert.Nil(t, err)
+	ingress, err := ingresses.Get("rook-ceph-mgr-dashboard-external", metav1.GetOptions{})
+	require.Nil(t, err)
+	assert.Equal(t, "ceph.example.com", ingress.Spec.Rules[0].Host)
+	assert.Equal(t, "rook-ceph-mgr-dashboard", ingress.Spec.Rules[0].HTTP.Paths[0].Backend.ServiceName)
+	assert.Equal(t, "dashboard-tls", ingress.Spec.TLS[0].SecretName)
+
+	// idempotent
+	err = c.configureDashboardExternalAccess(dashboardPortHTTPS)
+	assert.Nil(t, err)
+
+	// switch to a load balancer
+	c.dashboard.ExternalAccess.Type = cephv1.DashboardExternalAccessLoadBalancer
+	err = c.configureDashboardExternalAccess(dashboardPortHTTPS)
+	assert.Nil(t, err)
+	_, err = ingresses.Get("rook-ceph-mgr-dashboard-external", metav1.GetOptions{})
+	assert.True(t, errors.IsNotFound(err))
+	svc, err := services.Get("rook-ceph-mgr-dashboard-external", metav1.GetOptions{})
+	require.Nil(t, err)
+	assert.Equal(t, "LoadBalancer", string(svc.Spec.Type))
+	assert.Equal(t, "ceph.example.com", svc.Annotations[externalDNSHostnameAnnotation])
+
+	// remove the external access
+	c.dashboard.ExternalAccess = nil
+	err = c.configureDashboardExternalAccess(dashboardPortHTTPS)
+	assert.Nil(t, err)
+	_, err = services.Get("rook-ceph-mgr-dashboard-external", metav1.GetOptions{})
+	assert.True(t, errors.IsNotFound(err))
+
+	// unknown types are rejected
+	c.dashboard.ExternalAccess = &cephv1.DashboardExternalAccessSpec{Type: "NodePort"}
+	err = c.configureDashboardExternalAccess(dashboardPortHTTPS)
+	assert.NotNil(t, err)
+}
+
+func TestSetCertFromSecret(t *testing.T) {
+	currentCert := ""
+	sets := map[string]string{}
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName string, command string, outFileArg string, args ...string) (string, error) {
+			if args[0] == "config-key" && args[1] == "get" {
+				return currentCert, nil
+			}
+			if args[0] == "config-key" && args[1] == "set" {
+				sets[args[2]] = args[3]
+			}
+			return "", nil
+		},
+	}
+	c := &Cluster{context: &clusterd.Context{Clientset: test.New(3), Executor: executor}, Namespace: "myns",
+		dashboard: cephv1.DashboardSpec{Enabled: true, CertificateSecretName: "dashboard-tls"}}
+
+	// the secret does not exist
+	_, err := c.setCertFromSecret()
+	assert.NotNil(t, err)
+
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "dashboard-tls", Namespace: c.Namespace},
+		Data:       map[string][]byte{v1.TLSCertKey: []byte("mycert"), v1.TLSPrivateKeyKey: []byte("mykey")},
+		Type:       v1.SecretTypeTLS,
+	}
+	_, err = c.context.Clientset.CoreV1().Secrets(c.Namespace).Create(secret)
+	require.Nil(t, err)
+
+	changed, err := c.setCertFromSecret()
+	assert.Nil(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, "mycert", sets[dashboardCertKey])
+	assert.Equal(t, "mykey", sets[dashboardCertPrivateKey])
+
+	// the same cert is not set again
+	currentCert = "mycert\n"
+	sets = map[string]string{}
+	changed, err = c.setCertFromSecret()
+	assert.Nil(t, err)
+	assert.False(t, changed)
+	assert.Equal(t, 0, len(sets))
+}
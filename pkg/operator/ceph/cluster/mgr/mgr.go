@@ -60,6 +60,10 @@ type Cluster struct {
 	cephVersion cephv1.CephVersionSpec
 	rookVersion string
 	exitCode    func(err error) (int, bool)
+	// Override is the image, priority class, labels and annotations of the mgr pods
+	Override cephv1.DaemonOverride
+	// DataDirHostPath is the host path under which the crash reports of the mgr are kept
+	DataDirHostPath string
 }
 
 // New creates an instance of the mgr
@@ -26,7 +26,9 @@ import (
 	"github.com/rook/rook/pkg/operator/k8sutil"
 	apps "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 func (c *Cluster) makeDeployment(mgrConfig *mgrConfig) *apps.Deployment {
@@ -50,6 +52,7 @@ func (c *Cluster) makeDeployment(mgrConfig *mgrConfig) *apps.Deployment {
 			Volumes: append(
 				opspec.DaemonVolumes(mgrConfig.DataPathMap, mgrConfig.ResourceName),
 				keyring.Volume().Admin(), // ceph config set commands want admin keyring
+				opspec.CrashVolume(c.DataDirHostPath),
 			),
 			HostNetwork: c.HostNetwork,
 		},
@@ -64,6 +67,7 @@ func (c *Cluster) makeDeployment(mgrConfig *mgrConfig) *apps.Deployment {
 			[]v1.Container{c.makeCopyKeyringInitContainer(mgrConfig)},
 			podSpec.Spec.InitContainers...)
 	}
+	c.Override.ApplyToPod(&podSpec.ObjectMeta, &podSpec.Spec, c.cephVersion.Image)
 
 	replicas := int32(1)
 	d := &apps.Deployment{
@@ -163,8 +167,11 @@ func (c *Cluster) makeMgrDaemonContainer(mgrConfig *mgrConfig) v1.Container {
 			opspec.DaemonFlags(c.clusterInfo, mgrConfig.DaemonID),
 			"--foreground",
 		),
-		Image:        c.cephVersion.Image,
-		VolumeMounts: opspec.DaemonVolumeMounts(mgrConfig.DataPathMap, mgrConfig.ResourceName),
+		Image: c.cephVersion.Image,
+		VolumeMounts: append(
+			opspec.DaemonVolumeMounts(mgrConfig.DataPathMap, mgrConfig.ResourceName),
+			opspec.CrashVolumeMount(),
+		),
 		Ports: []v1.ContainerPort{
 			{
 				Name:          "mgr",
@@ -237,6 +244,89 @@ func (c *Cluster) makeDashboardService(name string, port int) *v1.Service {
 	return svc
 }
 
+func dashboardExternalName(name string) string {
+	return fmt.Sprintf("%s-dashboard-external", name)
+}
+
+func (c *Cluster) makeDashboardLoadBalancerService(name string, port int) *v1.Service {
+	labels := opspec.AppLabels(appName, c.Namespace)
+	annotations := map[string]string{}
+	for k, v := range c.dashboard.ExternalAccess.Annotations {
+		annotations[k] = v
+	}
+	if hostname := c.dashboard.ExternalAccess.Hostname; hostname != "" {
+		// the hostname is picked up by external-dns to create the dns record for the load balancer
+		if _, ok := annotations[externalDNSHostnameAnnotation]; !ok {
+			annotations[externalDNSHostnameAnnotation] = hostname
+		}
+	}
+	svc := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        dashboardExternalName(name),
+			Namespace:   c.Namespace,
+			Labels:      labels,
+			Annotations: annotations,
+		},
+		Spec: v1.ServiceSpec{
+			Selector: labels,
+			Type:     v1.ServiceTypeLoadBalancer,
+			Ports: []v1.ServicePort{
+				{
+					Name:       "https-dashboard",
+					Port:       int32(port),
+					TargetPort: intstr.FromInt(port),
+					Protocol:   v1.ProtocolTCP,
+				},
+			},
+		},
+	}
+	k8sutil.SetOwnerRef(c.context.Clientset, c.Namespace, &svc.ObjectMeta, &c.ownerRef)
+	return svc
+}
+
+func (c *Cluster) makeDashboardIngress(name string, port int) *extensions.Ingress {
+	access := c.dashboard.ExternalAccess
+	backend := extensions.IngressBackend{
+		ServiceName: fmt.Sprintf("%s-dashboard", name),
+		ServicePort: intstr.FromInt(port),
+	}
+	path := "/"
+	if c.dashboard.UrlPrefix != "" {
+		path = c.dashboard.UrlPrefix
+	}
+	ingress := &extensions.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        dashboardExternalName(name),
+			Namespace:   c.Namespace,
+			Labels:      opspec.AppLabels(appName, c.Namespace),
+			Annotations: access.Annotations,
+		},
+		Spec: extensions.IngressSpec{
+			Rules: []extensions.IngressRule{
+				{
+					Host: access.Hostname,
+					IngressRuleValue: extensions.IngressRuleValue{
+						HTTP: &extensions.HTTPIngressRuleValue{
+							Paths: []extensions.HTTPIngressPath{{Path: path, Backend: backend}},
+						},
+					},
+				},
+			},
+		},
+	}
+	// terminate tls at the ingress with the same cert the dashboard is serving
+	if access.Hostname != "" && c.dashboard.CertificateSecretName != "" {
+		ingress.Spec.TLS = []extensions.IngressTLS{
+			{
+				Hosts:      []string{access.Hostname},
+				SecretName: c.dashboard.CertificateSecretName,
+			},
+		}
+	}
+	k8sutil.SetOwnerRef(c.context.Clientset, c.Namespace, &ingress.ObjectMeta, &c.ownerRef)
+	return ingress
+}
+
 func (c *Cluster) getPodLabels(daemonName string) map[string]string {
 	labels := opspec.PodLabels(appName, c.Namespace, "mgr", daemonName)
 	// leave "instance" key for legacy usage
@@ -25,6 +25,7 @@ import (
 	"github.com/rook/rook/pkg/daemon/ceph/client"
 	cephconfig "github.com/rook/rook/pkg/daemon/ceph/config"
 	"github.com/rook/rook/pkg/operator/k8sutil"
+	"github.com/rook/rook/pkg/operator/metrics"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -69,6 +70,7 @@ func (hc *HealthChecker) Check(stopCh chan struct{}) {
 func (c *Cluster) checkHealth() error {
 	c.acquireOrchestrationLock()
 	defer c.releaseOrchestrationLock()
+	defer func() { metrics.SetMonsPendingFailover(c.Namespace, len(c.monTimeoutList)) }()
 
 	logger.Debugf("Checking health for mons (desired=%d). %+v", c.spec.Mon.Count, c.clusterInfo)
 
@@ -241,18 +243,26 @@ func (c *Cluster) failMon(monCount, desiredMonCount int, name string) {
 	if monCount > desiredMonCount {
 		// no need to create a new mon since we have an extra
 		if err := c.removeMon(name); err != nil {
+			metrics.IncOrchestrationFailure(c.Namespace, "mon")
 			logger.Errorf("failed to remove mon %s. %+v", name, err)
 		}
 	} else {
 		// bring up a new mon to replace the unhealthy mon
 		if err := c.failoverMon(name); err != nil {
+			metrics.IncOrchestrationFailure(c.Namespace, "mon")
 			logger.Errorf("failed to failover mon %s. %+v", name, err)
 		}
 	}
 }
 
-func (c *Cluster) failoverMon(name string) error {
+func (c *Cluster) failoverMon(name string) (err error) {
 	logger.Infof("Failing over monitor %s", name)
+	c.Events.Normalf(monFailoverReason, "failing over mon %s", name)
+	defer func() {
+		if err != nil {
+			c.Events.Warningf(monFailoverFailedReason, "failed to fail over mon %s. %+v", name, err)
+		}
+	}()
 
 	// Start a new monitor
 	m := c.newMonConfig(c.maxMonID + 1)
@@ -289,19 +299,50 @@ func (c *Cluster) failoverMon(name string) error {
 
 	// Only increment the max mon id if the new pod started successfully
 	c.maxMonID++
+	c.Events.Normalf(monFailoverReason, "started mon %s to replace mon %s", m.DaemonName, name)
 
 	return c.removeMon(name)
 }
 
-func (c *Cluster) removeMon(daemonName string) error {
+func (c *Cluster) removeMon(daemonName string) (err error) {
 	logger.Infof("ensuring removal of unhealthy monitor %s", daemonName)
-
-	resourceName := resourceName(daemonName)
+	defer func() {
+		if err != nil {
+			c.Events.Warningf(monRemoveFailedReason, "failed to remove mon %s. %+v", daemonName, err)
+		} else {
+			c.Events.Normalf(monRemovedReason, "removed mon %s", daemonName)
+		}
+	}()
 
 	// Remove the mon pod if it is still there
-	var gracePeriod int64
-	propagation := metav1.DeletePropagationForeground
-	options := &metav1.DeleteOptions{GracePeriodSeconds: &gracePeriod, PropagationPolicy: &propagation}
+	if err := c.deleteMonDeployment(daemonName); err != nil {
+		return err
+	}
+
+	// Remove the bad monitor from quorum
+	if err := removeMonitorFromQuorum(c.context, c.clusterInfo.Name, daemonName); err != nil {
+		return fmt.Errorf("failed to remove mon %s from quorum. %+v", daemonName, err)
+	}
+
+	if err := c.forgetMon(daemonName); err != nil {
+		return err
+	}
+
+	if err := c.saveMonConfig(); err != nil {
+		return fmt.Errorf("failed to save mon config after failing over mon %s. %+v", daemonName, err)
+	}
+
+	// make sure to rewrite the config so NO new connections are made to the removed mon
+	if err := writeConnectionConfig(c.context, c.clusterInfo); err != nil {
+		return fmt.Errorf("failed to write connection config after failing over mon %s. %+v", daemonName, err)
+	}
+
+	return nil
+}
+
+func (c *Cluster) deleteMonDeployment(daemonName string) error {
+	resourceName := resourceName(daemonName)
+	options := deleteOptions()
 	if err := c.context.Clientset.Apps().Deployments(c.Namespace).Delete(resourceName, options); err != nil {
 		if errors.IsNotFound(err) {
 			logger.Infof("dead mon %s was already gone", resourceName)
@@ -309,11 +350,11 @@ func (c *Cluster) removeMon(daemonName string) error {
 			return fmt.Errorf("failed to remove dead mon deployment %s. %+v", resourceName, err)
 		}
 	}
+	return nil
+}
 
-	// Remove the bad monitor from quorum
-	if err := removeMonitorFromQuorum(c.context, c.clusterInfo.Name, daemonName); err != nil {
-		return fmt.Errorf("failed to remove mon %s from quorum. %+v", daemonName, err)
-	}
+// forgetMon removes the mon from the cluster info and the node mapping and deletes its service
+func (c *Cluster) forgetMon(daemonName string) error {
 	delete(c.clusterInfo.Monitors, daemonName)
 	// check if a mapping exists for the mon
 	if _, ok := c.mapping.Node[daemonName]; ok {
@@ -333,6 +374,8 @@ func (c *Cluster) removeMon(daemonName string) error {
 	}
 
 	// Remove the service endpoint
+	resourceName := resourceName(daemonName)
+	options := deleteOptions()
 	if err := c.context.Clientset.CoreV1().Services(c.Namespace).Delete(resourceName, options); err != nil {
 		if errors.IsNotFound(err) {
 			logger.Infof("dead mon service %s was already gone", resourceName)
@@ -340,19 +383,16 @@ func (c *Cluster) removeMon(daemonName string) error {
 			return fmt.Errorf("failed to remove dead mon service %s. %+v", resourceName, err)
 		}
 	}
-
-	if err := c.saveMonConfig(); err != nil {
-		return fmt.Errorf("failed to save mon config after failing over mon %s. %+v", daemonName, err)
-	}
-
-	// make sure to rewrite the config so NO new connections are made to the removed mon
-	if err := writeConnectionConfig(c.context, c.clusterInfo); err != nil {
-		return fmt.Errorf("failed to write connection config after failing over mon %s. %+v", daemonName, err)
-	}
-
 	return nil
 }
 
+// deleteOptions removes the resources of a dead mon right away
+func deleteOptions() *metav1.DeleteOptions {
+	var gracePeriod int64
+	propagation := metav1.DeletePropagationForeground
+	return &metav1.DeleteOptions{GracePeriodSeconds: &gracePeriod, PropagationPolicy: &propagation}
+}
+
 func removeMonitorFromQuorum(context *clusterd.Context, clusterName, name string) error {
 	logger.Debugf("removing monitor %s", name)
 	args := []string{"mon", "remove", name}
@@ -27,6 +27,7 @@ import (
 	"github.com/rook/rook/pkg/clusterd"
 	"github.com/rook/rook/pkg/daemon/ceph/client"
 	clienttest "github.com/rook/rook/pkg/daemon/ceph/client/test"
+	"github.com/rook/rook/pkg/operator/k8sutil"
 	testopk8s "github.com/rook/rook/pkg/operator/k8sutil/test"
 	"github.com/rook/rook/pkg/operator/test"
 	exectest "github.com/rook/rook/pkg/util/exec/test"
@@ -34,6 +35,7 @@ import (
 	apps "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/kubernetes/pkg/kubelet/apis"
 )
 
@@ -74,8 +76,14 @@ func TestCheckHealth(t *testing.T) {
 	assert.ElementsMatch(t, []string{}, testopk8s.DeploymentNamesUpdated(deploymentsUpdated))
 	testopk8s.ClearDeploymentsUpdated(deploymentsUpdated)
 
+	// the failover is recorded on the cluster CRD
+	recorder := record.NewFakeRecorder(10)
+	c.Events = k8sutil.NewObjectEventRecorder(recorder, &cephv1.CephCluster{ObjectMeta: metav1.ObjectMeta{Name: "ns", Namespace: "ns"}})
 	err = c.failoverMon("f")
 	assert.Nil(t, err)
+	assert.Equal(t, "Normal MonFailover failing over mon f", <-recorder.Events)
+	assert.Equal(t, "Normal MonFailover started mon g to replace mon f", <-recorder.Events)
+	assert.Equal(t, "Normal MonRemoved removed mon f", <-recorder.Events)
 	// No updates in unit tests w/ workaround
 	assert.ElementsMatch(t, []string{}, testopk8s.DeploymentNamesUpdated(deploymentsUpdated))
 	testopk8s.ClearDeploymentsUpdated(deploymentsUpdated)
@@ -0,0 +1,102 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"fmt"
+
+	"github.com/rook/rook/pkg/daemon/ceph/client"
+	"github.com/rook/rook/pkg/operator/ceph/config/keyring"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// RotateAdminKey replaces the key of the admin user with a new key in two phases. The new key is imported and stored
+// in the secrets read by the daemons and the agents when they start, then restartClients restarts the daemons using
+// the admin key and waits for them to be ready. The previous key is kept until then, and is imported and stored again
+// if any step fails.
+func (c *Cluster) RotateAdminKey(restartClients func() error) error {
+	// the health checks and the orchestration of the mons connect with the admin key
+	c.acquireOrchestrationLock()
+	defer c.releaseOrchestrationLock()
+
+	if c.clusterInfo == nil {
+		return fmt.Errorf("the cluster info of %s is not loaded", c.Namespace)
+	}
+
+	caps, err := client.AuthGetCaps(c.context, c.Namespace, client.AdminUsername)
+	if err != nil {
+		return err
+	}
+	key, err := client.GenerateAuthKey(c.context)
+	if err != nil {
+		return err
+	}
+	previousKey := c.clusterInfo.AdminSecret
+	err = c.applyAdminKey(key, caps, restartClients)
+	if err == nil {
+		logger.Infof("rotated the admin key of cluster %s", c.Namespace)
+		return nil
+	}
+
+	logger.Errorf("failed to rotate the admin key. restoring the previous key. %+v", err)
+	if restoreErr := c.applyAdminKey(previousKey, caps, restartClients); restoreErr != nil {
+		return fmt.Errorf("failed to rotate the admin key, and failed to restore the previous key. %+v. %+v", err, restoreErr)
+	}
+	return fmt.Errorf("failed to rotate the admin key. restored the previous key. %+v", err)
+}
+
+// applyAdminKey imports the admin key, stores it and restarts the daemons using it. The operator connects to the
+// cluster with the key as soon as it is imported.
+func (c *Cluster) applyAdminKey(key string, caps map[string]string, restartClients func() error) error {
+	if err := client.AuthImportKey(c.context, c.Namespace, client.AdminUsername, key, caps); err != nil {
+		return err
+	}
+	if err := c.saveAdminKey(key); err != nil {
+		return fmt.Errorf("failed to save the admin key. %+v", err)
+	}
+	if err := restartClients(); err != nil {
+		return fmt.Errorf("failed to restart the daemons with the admin key. %+v", err)
+	}
+	return nil
+}
+
+// saveAdminKey writes the admin key to the connection config of the operator and to the secrets of the cluster
+func (c *Cluster) saveAdminKey(key string) error {
+	c.clusterInfo.AdminSecret = key
+	if err := writeConnectionConfig(c.context, c.clusterInfo); err != nil {
+		return err
+	}
+
+	secret, err := c.context.Clientset.CoreV1().Secrets(c.Namespace).Get(AppName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get mon secrets. %+v", err)
+	}
+	secret.Data[adminSecretName] = []byte(key)
+	if _, err := c.context.Clientset.CoreV1().Secrets(c.Namespace).Update(secret); err != nil {
+		return fmt.Errorf("failed to update mon secrets. %+v", err)
+	}
+
+	k := keyring.GetSecretStore(c.context, c.Namespace, &c.ownerRef)
+	// the keyring shared by the mons also holds the admin key
+	if err := k.CreateOrUpdate(keyringStoreName, c.genMonSharedKeyring()); err != nil {
+		return fmt.Errorf("failed to save mon keyring secret. %+v", err)
+	}
+	if err := k.Admin().CreateOrUpdate(c.clusterInfo); err != nil {
+		return fmt.Errorf("failed to save admin keyring secret. %+v", err)
+	}
+	return nil
+}
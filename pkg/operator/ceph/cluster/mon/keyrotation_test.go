@@ -0,0 +1,138 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/operator/test"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRotateAdminKey(t *testing.T) {
+	configDir, err := ioutil.TempDir("", "")
+	require.Nil(t, err)
+	defer os.RemoveAll(configDir)
+	require.Nil(t, os.MkdirAll(path.Join(configDir, "ns"), 0744))
+
+	var imported []string
+	generated := 0
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName string, command string, args ...string) (string, error) {
+			if command == "ceph-authtool" {
+				generated++
+				return fmt.Sprintf("newkey%d\n", generated), nil
+			}
+			return "", fmt.Errorf("unexpected command %s %v", command, args)
+		},
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName string, command string, outFileArg string, args ...string) (string, error) {
+			if args[1] == "get" {
+				return `[{"entity":"client.admin","key":"adminsecret","caps":{"mon":"allow *"}}]`, nil
+			}
+			if args[1] == "import" {
+				keyring, err := ioutil.ReadFile(args[3])
+				assert.Nil(t, err)
+				imported = append(imported, string(keyring))
+				return "", nil
+			}
+			return "", fmt.Errorf("unexpected ceph command %v", args)
+		},
+	}
+	clientset := test.New(1)
+	context := &clusterd.Context{Clientset: clientset, Executor: executor, ConfigDir: configDir}
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: AppName, Namespace: "ns"},
+		Data:       map[string][]byte{adminSecretName: []byte("adminsecret")},
+	}
+	_, err = clientset.CoreV1().Secrets("ns").Create(secret)
+	require.Nil(t, err)
+
+	c := New(context, "ns", "", false, metav1.OwnerReference{})
+	c.clusterInfo = test.CreateConfigDir(1)
+	c.clusterInfo.Name = "ns"
+
+	restarted := 0
+	restartClients := func() error {
+		restarted++
+		return nil
+	}
+	err = c.RotateAdminKey(restartClients)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, restarted)
+	require.Equal(t, 1, len(imported))
+	assert.Contains(t, imported[0], "key = newkey1")
+	assert.Equal(t, "newkey1", c.clusterInfo.AdminSecret)
+
+	// the operator connects with the new key
+	keyring, err := ioutil.ReadFile(path.Join(configDir, "ns", "client.admin.keyring"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(keyring), "newkey1")
+
+	// the new key is stored in the secrets
+	secret, err = clientset.CoreV1().Secrets("ns").Get(AppName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "newkey1", string(secret.Data[adminSecretName]))
+	for _, name := range []string{"rook-ceph-admin-keyring", "rook-ceph-mons-keyring"} {
+		secret, err = clientset.CoreV1().Secrets("ns").Get(name, metav1.GetOptions{})
+		assert.Nil(t, err)
+		assert.True(t, strings.Contains(secret.StringData["keyring"], "newkey1"), name)
+	}
+
+	// the previous key is imported and stored again if the daemons are not ready with the new key
+	imported = nil
+	restarted = 0
+	failRestart := true
+	err = c.RotateAdminKey(func() error {
+		restarted++
+		if failRestart {
+			failRestart = false
+			return fmt.Errorf("mock restart failure")
+		}
+		return nil
+	})
+	assert.NotNil(t, err)
+	assert.Equal(t, 2, restarted)
+	require.Equal(t, 2, len(imported))
+	assert.Contains(t, imported[0], "key = newkey2")
+	assert.Contains(t, imported[1], "key = newkey1")
+	assert.Equal(t, "newkey1", c.clusterInfo.AdminSecret)
+	secret, err = clientset.CoreV1().Secrets("ns").Get(AppName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "newkey1", string(secret.Data[adminSecretName]))
+
+	// the previous key is restored if the new key cannot be stored
+	imported = nil
+	restarted = 0
+	assert.Nil(t, clientset.CoreV1().Secrets("ns").Delete(AppName, &metav1.DeleteOptions{}))
+	err = c.RotateAdminKey(restartClients)
+	assert.NotNil(t, err)
+	assert.Equal(t, 0, restarted)
+	require.Equal(t, 2, len(imported))
+	assert.Contains(t, imported[0], "key = newkey3")
+	assert.Contains(t, imported[1], "key = newkey1")
+	assert.Equal(t, "newkey1", c.clusterInfo.AdminSecret)
+}
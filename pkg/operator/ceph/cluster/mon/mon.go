@@ -53,7 +53,8 @@ const (
 	// MappingKey is the name of the mapping for the mon->node and node->port
 	MappingKey = "mapping"
 
-	appName           = "rook-ceph-mon"
+	// AppName is the app label of the mon pods and services, and the name of the secret with the cluster identity
+	AppName           = "rook-ceph-mon"
 	monNodeAttr       = "mon_node"
 	monClusterAttr    = "mon_cluster"
 	tprName           = "mon.rook.io"
@@ -70,6 +71,12 @@ const (
 	Msgr2port int32 = 3300
 	// minimum amount of memory in MB to run the pod
 	cephMonPodMinimumMemory uint64 = 1024
+
+	// the reasons of the events recorded on the cluster CRD
+	monFailoverReason       = "MonFailover"
+	monFailoverFailedReason = "MonFailoverFailed"
+	monRemovedReason        = "MonRemoved"
+	monRemoveFailedReason   = "MonRemoveFailed"
 )
 
 var (
@@ -97,6 +104,8 @@ type Cluster struct {
 	monTimeoutList      map[string]time.Time
 	mapping             *Mapping
 	ownerRef            metav1.OwnerReference
+	// Events records the failovers and removals of the mons on the cluster CRD
+	Events *k8sutil.ObjectEventRecorder
 }
 
 // monConfig for a single monitor
@@ -169,6 +178,10 @@ func (c *Cluster) Start(clusterInfo *cephconfig.ClusterInfo, rookVersion string,
 		return nil, fmt.Errorf("%v", err)
 	}
 
+	if err := config.ValidateConnections(c.spec.Security.Connections, cephVersion); err != nil {
+		return nil, fmt.Errorf("invalid connections settings. %+v", err)
+	}
+
 	logger.Infof("start running mons")
 
 	logger.Debugf("establishing ceph cluster info")
@@ -204,6 +217,14 @@ func (c *Cluster) startMons() error {
 		}
 	}
 
+	// the mons listen on the msgr2 port when they run Nautilus, but the clusters created or upgraded from an older version
+	// only publish their msgr1 addresses until msgr2 is enabled. the mons were all updated to Nautilus at this point.
+	if c.clusterInfo.CephVersion.IsAtLeastNautilus() {
+		if err := client.EnableMessenger2(c.context, c.Namespace); err != nil {
+			return err
+		}
+	}
+
 	logger.Debugf("mon endpoints used are: %s", FlattenMonEndpoints(c.clusterInfo.Monitors))
 	return nil
 }
@@ -319,10 +340,10 @@ func (c *Cluster) newMonConfig(monID int) *monConfig {
 
 // resourceName ensures the mon name has the rook-ceph-mon prefix
 func resourceName(name string) string {
-	if strings.HasPrefix(name, appName) {
+	if strings.HasPrefix(name, AppName) {
 		return name
 	}
-	return fmt.Sprintf("%s-%s", appName, name)
+	return fmt.Sprintf("%s-%s", AppName, name)
 }
 
 func (c *Cluster) initMonIPs(mons []*monConfig) error {
@@ -455,7 +476,7 @@ func (c *Cluster) saveMonConfig() error {
 
 	// Every time the mon config is updated, must also update the global config so that all daemons
 	// have the most updated version if they restart.
-	config.GetStore(c.context, c.Namespace, &c.ownerRef).CreateOrUpdate(c.clusterInfo)
+	config.GetStore(c.context, c.Namespace, &c.ownerRef).CreateOrUpdate(c.clusterInfo, c.spec.Security.Connections)
 
 	// write the latest config to the config dir
 	if err := writeConnectionConfig(c.context, c.clusterInfo); err != nil {
@@ -519,7 +540,7 @@ func waitForQuorumWithMons(context *clusterd.Context, clusterName string, mons [
 		allPodsRunning := true
 		var runningMonNames []string
 		for _, m := range mons {
-			running, err := k8sutil.PodsRunningWithLabel(context.Clientset, clusterName, fmt.Sprintf("app=%s,mon=%s", appName, m))
+			running, err := k8sutil.PodsRunningWithLabel(context.Clientset, clusterName, fmt.Sprintf("app=%s,mon=%s", AppName, m))
 			if err != nil {
 				logger.Infof("failed to query mon pod status, trying again. %+v", err)
 				continue
@@ -159,6 +159,50 @@ func TestStartMonPods(t *testing.T) {
 	validateStart(t, c)
 }
 
+func TestStartMonPodsNautilus(t *testing.T) {
+	namespace := "ns"
+	context := newTestStartCluster(namespace)
+	executor := context.Executor.(*exectest.MockExecutor)
+	quorumResponse := executor.MockExecuteCommandWithOutputFile
+	msgr2Enabled := false
+	executor.MockExecuteCommandWithOutputFile = func(debug bool, actionName string, command string, outFileArg string, args ...string) (string, error) {
+		if args[0] == "mon" && args[1] == "enable-msgr2" {
+			msgr2Enabled = true
+			return "", nil
+		}
+		return quorumResponse(debug, actionName, command, outFileArg, args...)
+	}
+	c := newCluster(context, namespace, false, true, v1.ResourceRequirements{})
+
+	// the service of a mon created before the upgrade to nautilus only has the msgr1 port
+	s := &v1.Service{ObjectMeta: metav1.ObjectMeta{Name: "rook-ceph-mon-a", Namespace: namespace}}
+	addServicePort(s, "rook-ceph-mon-a", DefaultPort)
+	_, err := context.Clientset.CoreV1().Services(namespace).Create(s)
+	assert.Nil(t, err)
+
+	_, err = c.Start(c.clusterInfo, c.rookVersion, cephver.Nautilus, c.spec)
+	assert.Nil(t, err)
+	validateStart(t, c)
+	assert.True(t, msgr2Enabled)
+
+	s, err = context.Clientset.CoreV1().Services(namespace).Get("rook-ceph-mon-a", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.True(t, hasServicePort(s, DefaultPort))
+	assert.True(t, hasServicePort(s, Msgr2port))
+	d, err := context.Clientset.Apps().Deployments(namespace).Get("rook-ceph-mon-a", metav1.GetOptions{})
+	assert.Nil(t, err)
+	ports := d.Spec.Template.Spec.Containers[0].Ports
+	assert.Equal(t, 2, len(ports))
+	assert.Equal(t, Msgr2port, ports[1].ContainerPort)
+
+	// the connection modes require nautilus
+	c.spec.Security.Connections.ServiceMode = "secure"
+	_, err = c.Start(c.clusterInfo, c.rookVersion, cephver.Nautilus, c.spec)
+	assert.Nil(t, err)
+	_, err = c.Start(c.clusterInfo, c.rookVersion, cephver.Mimic, c.spec)
+	assert.NotNil(t, err)
+}
+
 func TestOperatorRestart(t *testing.T) {
 	namespace := "ns"
 	context := newTestStartCluster(namespace)
@@ -210,7 +254,7 @@ func TestOperatorRestartHostNetwork(t *testing.T) {
 }
 
 func validateStart(t *testing.T, c *Cluster) {
-	s, err := c.context.Clientset.CoreV1().Secrets(c.Namespace).Get(appName, metav1.GetOptions{})
+	s, err := c.context.Clientset.CoreV1().Secrets(c.Namespace).Get(AppName, metav1.GetOptions{})
 	assert.Nil(t, err) // there shouldn't be an error due the secret existing
 	assert.Equal(t, 4, len(s.Data))
 
@@ -85,7 +85,7 @@ func (c *Cluster) getAvailableMonNodes() ([]v1.Node, *v1.NodeList, error) {
 
 func (c *Cluster) getNodesWithMons(nodes *v1.NodeList) (*util.Set, error) {
 	// get the mon pods and their node affinity
-	options := metav1.ListOptions{LabelSelector: fmt.Sprintf("app=%s", appName)}
+	options := metav1.ListOptions{LabelSelector: fmt.Sprintf("app=%s", AppName)}
 	pods, err := c.context.Clientset.CoreV1().Pods(c.Namespace).List(options)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,163 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"fmt"
+	"path"
+	"sort"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	cephconfig "github.com/rook/rook/pkg/daemon/ceph/config"
+	cephutil "github.com/rook/rook/pkg/daemon/ceph/util"
+	"github.com/rook/rook/pkg/operator/ceph/config"
+	opspec "github.com/rook/rook/pkg/operator/ceph/spec"
+	cephver "github.com/rook/rook/pkg/operator/ceph/version"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	// RestoreQuorumAnnotation is the annotation of the cluster CRD requesting to restore the mon quorum from the
+	// mon named in its value. The operator removes it when the restore starts.
+	RestoreQuorumAnnotation = "ceph.rook.io/restore-mon-quorum"
+	// RestoreQuorumStatusAnnotation is the annotation of the cluster CRD with the status of the last quorum restore
+	RestoreQuorumStatusAnnotation = "ceph.rook.io/restore-mon-quorum-status"
+	// RestoreQuorumErrorAnnotation is the annotation of the cluster CRD with the error of a failed quorum restore
+	RestoreQuorumErrorAnnotation = "ceph.rook.io/restore-mon-quorum-error"
+
+	// RestoreQuorumRestoring is the status of a quorum restore in progress
+	RestoreQuorumRestoring = "Restoring"
+	// RestoreQuorumRestored is the status of a successful quorum restore
+	RestoreQuorumRestored = "Restored"
+	// RestoreQuorumFailed is the status of a failed quorum restore
+	RestoreQuorumFailed = "Failed"
+)
+
+// RestoreQuorum restores the quorum of the mons from a single surviving mon after the majority of the mons was lost.
+// The other mons are removed from the monmap of the surviving mon, which is restarted alone before new mons are
+// started to reach the desired mon count again.
+func (c *Cluster) RestoreQuorum(survivor, rookVersion string, cephVersion cephver.CephVersion, spec cephv1.ClusterSpec) (*cephconfig.ClusterInfo, error) {
+	// the health checks and the orchestration of the mons wait for the restore
+	c.acquireOrchestrationLock()
+	defer c.releaseOrchestrationLock()
+
+	c.rookVersion = rookVersion
+	c.spec = spec
+
+	// the cluster info is loaded from the secrets since the operator may not have started the mons yet
+	if err := c.initClusterInfo(cephVersion); err != nil {
+		return nil, fmt.Errorf("failed to initialize ceph cluster info. %+v", err)
+	}
+
+	if err := c.restoreQuorum(survivor); err != nil {
+		return nil, err
+	}
+
+	logger.Infof("restored the mon quorum from mon %s. starting the other mons", survivor)
+	return c.clusterInfo, c.startMons()
+}
+
+func (c *Cluster) restoreQuorum(survivor string) error {
+	info, ok := c.clusterInfo.Monitors[survivor]
+	if !ok {
+		return fmt.Errorf("mon %s not found in the mons %s", survivor, FlattenMonEndpoints(c.clusterInfo.Monitors))
+	}
+	node, ok := c.mapping.Node[survivor]
+	if !ok {
+		return fmt.Errorf("mon %s is not assigned to a node", survivor)
+	}
+
+	dead := []string{}
+	for name := range c.clusterInfo.Monitors {
+		if name != survivor {
+			dead = append(dead, name)
+		}
+	}
+	sort.Strings(dead)
+	logger.Warningf("restoring the mon quorum from mon %s. removing mons %v", survivor, dead)
+
+	// stop the dead mons so they can't join the restored mon with their old monmap
+	for _, name := range dead {
+		if err := c.deleteMonDeployment(name); err != nil {
+			return err
+		}
+		if err := c.forgetMon(name); err != nil {
+			return err
+		}
+	}
+
+	// the endpoints and the config of the daemons only contain the surviving mon from now on
+	if err := c.saveMonConfig(); err != nil {
+		return fmt.Errorf("failed to save mon config after removing mons %v. %+v", dead, err)
+	}
+
+	m := &monConfig{
+		ResourceName: resourceName(survivor),
+		DaemonName:   survivor,
+		PublicIP:     cephutil.GetIPFromEndpoint(info.Endpoint),
+		Port:         cephutil.GetPortFromEndpoint(info.Endpoint),
+		DataPathMap: config.NewStatefulDaemonDataPathMap(
+			c.dataDirHostPath, dataDirRelativeHostPath(survivor), config.MonType, survivor),
+	}
+
+	// restart the surviving mon with a monmap without the dead mons
+	d := c.makeDeployment(m, node.Hostname)
+	d.Spec.Template.Spec.InitContainers = append(d.Spec.Template.Spec.InitContainers, c.makeMonmapRestoreContainers(m, dead)...)
+	if _, err := updateDeploymentAndWait(c.context, d, c.Namespace); err != nil {
+		return fmt.Errorf("failed to restart mon %s with the restored monmap. %+v", survivor, err)
+	}
+	if err := c.waitForMonsToJoin([]*monConfig{m}, true); err != nil {
+		return fmt.Errorf("mon %s did not form a quorum alone. %+v", survivor, err)
+	}
+
+	// start the mon normally again, the monmap must not be edited when it restarts after the new mons joined
+	if _, err := updateDeploymentAndWait(c.context, c.makeDeployment(m, node.Hostname), c.Namespace); err != nil {
+		return fmt.Errorf("failed to restart mon %s after restoring the quorum. %+v", survivor, err)
+	}
+	return nil
+}
+
+// makeMonmapRestoreContainers returns the init containers extracting the monmap of the mon, removing the dead mons
+// from it and injecting it back into the mon store
+func (c *Cluster) makeMonmapRestoreContainers(monConfig *monConfig, dead []string) []v1.Container {
+	monmap := path.Join(monConfig.DataPathMap.ContainerDataDir, monmapFile)
+	removeArgs := []string{monmap}
+	for _, name := range dead {
+		removeArgs = append(removeArgs, "--rm", name)
+	}
+
+	return []v1.Container{
+		c.makeMonmapContainer("extract-monmap", monConfig, cephMonCommand,
+			append(opspec.DaemonFlags(c.clusterInfo, monConfig.DaemonName), config.NewFlag("extract-monmap", monmap))),
+		c.makeMonmapContainer("remove-dead-mons", monConfig, monmaptoolCommand, removeArgs),
+		c.makeMonmapContainer("inject-monmap", monConfig, cephMonCommand,
+			append(opspec.DaemonFlags(c.clusterInfo, monConfig.DaemonName), config.NewFlag("inject-monmap", monmap))),
+	}
+}
+
+func (c *Cluster) makeMonmapContainer(name string, monConfig *monConfig, command string, args []string) v1.Container {
+	return v1.Container{
+		Name:            name,
+		Command:         []string{command},
+		Args:            args,
+		Image:           c.spec.CephVersion.Image,
+		VolumeMounts:    opspec.DaemonVolumeMounts(monConfig.DataPathMap, keyringStoreName),
+		SecurityContext: podSecurityContext(),
+		Env:             opspec.DaemonEnvVars(c.spec.CephVersion.Image),
+		Resources:       cephv1.GetMonResources(c.spec.Resources),
+	}
+}
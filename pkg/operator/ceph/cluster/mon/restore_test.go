@@ -0,0 +1,82 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	"github.com/rook/rook/pkg/clusterd"
+	testopk8s "github.com/rook/rook/pkg/operator/k8sutil/test"
+	"github.com/rook/rook/pkg/operator/test"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+	apps "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRestoreQuorum(t *testing.T) {
+	var deploymentsUpdated *[]*apps.Deployment
+	updateDeploymentAndWait, deploymentsUpdated = testopk8s.UpdateDeploymentAndWaitStub()
+
+	clientset := test.New(3)
+	configDir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(configDir)
+	context := &clusterd.Context{
+		Clientset: clientset,
+		ConfigDir: configDir,
+		Executor:  &exectest.MockExecutor{},
+	}
+	c := New(context, "ns", "/var/lib/rook", false, metav1.OwnerReference{})
+	setCommonMonProperties(c, 3, cephv1.MonSpec{Count: 3}, "myversion")
+	c.spec.CephVersion.Image = "ceph/ceph:v14"
+	c.waitForStart = false
+	for i, name := range []string{"a", "b", "c"} {
+		node := fmt.Sprintf("node%d", i)
+		c.mapping.Node[name] = &NodeInfo{Name: node, Hostname: node}
+	}
+
+	// the surviving mon must exist
+	err := c.restoreQuorum("z")
+	assert.NotNil(t, err)
+	assert.Equal(t, 0, len(*deploymentsUpdated))
+
+	err = c.restoreQuorum("b")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(c.clusterInfo.Monitors))
+	assert.NotNil(t, c.clusterInfo.Monitors["b"])
+	assert.Equal(t, 1, len(c.mapping.Node))
+
+	// the endpoints only contain the surviving mon
+	cm, err := clientset.CoreV1().ConfigMaps("ns").Get(EndpointConfigMapName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "b=1.2.3.2:6789", cm.Data[EndpointDataKey])
+
+	// the mon is restarted with the edited monmap, then normally
+	assert.Equal(t, 2, len(*deploymentsUpdated))
+	restore := (*deploymentsUpdated)[0].Spec.Template.Spec.InitContainers
+	assert.Equal(t, 4, len(restore))
+	assert.Equal(t, "extract-monmap", restore[1].Name)
+	assert.Equal(t, "remove-dead-mons", restore[2].Name)
+	assert.Equal(t, []string{"/var/lib/ceph/mon/ceph-b/monmap", "--rm", "a", "--rm", "c"}, restore[2].Args)
+	assert.Equal(t, "inject-monmap", restore[3].Name)
+	assert.Equal(t, "--inject-monmap=/var/lib/ceph/mon/ceph-b/monmap", restore[3].Args[len(restore[3].Args)-1])
+	assert.Equal(t, 1, len((*deploymentsUpdated)[1].Spec.Template.Spec.InitContainers))
+}
@@ -65,6 +65,16 @@ func (c *Cluster) createService(mon *monConfig) (string, error) {
 		if err != nil {
 			return "", fmt.Errorf("failed to get mon %s service ip. %+v", mon.ResourceName, err)
 		}
+
+		// the services of the mons created before the upgrade to Nautilus only expose the msgr1 port
+		if s != nil && c.clusterInfo.CephVersion.IsAtLeastNautilus() && !hasServicePort(s, Msgr2port) {
+			logger.Infof("adding the msgr2 port to mon %s service", mon.ResourceName)
+			addServicePort(s, "msgr2", Msgr2port)
+			s, err = c.context.Clientset.CoreV1().Services(c.Namespace).Update(s)
+			if err != nil {
+				return "", fmt.Errorf("failed to add the msgr2 port to mon %s service. %+v", mon.ResourceName, err)
+			}
+		}
 	}
 
 	if s == nil {
@@ -42,7 +42,7 @@ const (
 func (c *Cluster) getLabels(daemonName string) map[string]string {
 	// Mons have a service for each mon, so the additional pod data is relevant for its services
 	// Use pod labels to keep "mon: id" for legacy
-	labels := opspec.PodLabels(appName, c.Namespace, "mon", daemonName)
+	labels := opspec.PodLabels(AppName, c.Namespace, "mon", daemonName)
 	// Add "mon_cluster: <namespace>" for legacy
 	labels[monClusterAttr] = c.Namespace
 	return labels
@@ -92,8 +92,11 @@ func (c *Cluster) makeMonPod(monConfig *monConfig, hostname string) *v1.Pod {
 		},
 		RestartPolicy: v1.RestartPolicyAlways,
 		NodeSelector:  map[string]string{apis.LabelHostname: hostname},
-		Volumes:       opspec.DaemonVolumes(monConfig.DataPathMap, keyringStoreName),
-		HostNetwork:   c.HostNetwork,
+		Volumes: append(
+			opspec.DaemonVolumes(monConfig.DataPathMap, keyringStoreName),
+			opspec.CrashVolume(c.dataDirHostPath),
+		),
+		HostNetwork: c.HostNetwork,
 	}
 	if c.HostNetwork {
 		podSpec.DNSPolicy = v1.DNSClusterFirstWithHostNet
@@ -115,6 +118,7 @@ func (c *Cluster) makeMonPod(monConfig *monConfig, hostname string) *v1.Pod {
 		},
 		Spec: podSpec,
 	}
+	cephv1.GetMonOverride(c.spec.DaemonOverrides).ApplyToPod(&pod.ObjectMeta, &pod.Spec, c.spec.CephVersion.Image)
 
 	return pod
 }
@@ -167,8 +171,11 @@ func (c *Cluster) makeMonDaemonContainer(monConfig *monConfig) v1.Container {
 			config.NewFlag("public-addr", monConfig.PublicIP),
 			config.NewFlag("public-bind-addr", opspec.ContainerEnvVarReference(podIPEnvVar)),
 		),
-		Image:           c.spec.CephVersion.Image,
-		VolumeMounts:    opspec.DaemonVolumeMounts(monConfig.DataPathMap, keyringStoreName),
+		Image: c.spec.CephVersion.Image,
+		VolumeMounts: append(
+			opspec.DaemonVolumeMounts(monConfig.DataPathMap, keyringStoreName),
+			opspec.CrashVolumeMount(),
+		),
 		SecurityContext: podSecurityContext(),
 		Ports: []v1.ContainerPort{
 			{
@@ -186,7 +193,9 @@ func (c *Cluster) makeMonDaemonContainer(monConfig *monConfig) v1.Container {
 
 	// If deploying Nautilus and newer we need a new port of the monitor container
 	if c.clusterInfo.CephVersion.IsAtLeastNautilus() {
-		addContainerPort(container, "msgr2", 3300)
+		addContainerPort(&container, "msgr2", Msgr2port)
+		// The mon memory target is new in Nautilus
+		container.Args = append(container.Args, config.MonMemoryFlags(container.Resources)...)
 	}
 
 	return container
@@ -64,9 +64,33 @@ func testPodSpec(t *testing.T, monID string) {
 
 	// Deployment should have Ceph labels
 	cephtest.AssertLabelsContainCephRequirements(t, d.ObjectMeta.Labels,
-		config.MonType, monID, appName, "ns")
+		config.MonType, monID, AppName, "ns")
 
 	podTemplate := cephtest.NewPodTemplateSpecTester(t, &d.Spec.Template)
-	podTemplate.RunFullSuite(config.MonType, monID, appName, "ns", "ceph/ceph:myceph",
+	podTemplate.RunFullSuite(config.MonType, monID, AppName, "ns", "ceph/ceph:myceph",
 		"200", "100", "1337", "500" /* resources */)
 }
+
+func TestDaemonOverride(t *testing.T) {
+	c := New(&clusterd.Context{Clientset: testop.New(1), ConfigDir: "/var/lib/rook"}, "ns", "/var/lib/rook", false, metav1.OwnerReference{})
+	setCommonMonProperties(c, 0, cephv1.MonSpec{Count: 3, AllowMultiplePerNode: true}, "rook/rook:myversion")
+	c.spec.CephVersion = cephv1.CephVersionSpec{Image: "ceph/ceph:myceph"}
+	c.spec.DaemonOverrides = cephv1.DaemonOverridesSpec{
+		"mon": {
+			Image:             "ceph/ceph:mymon",
+			PriorityClassName: "system-cluster-critical",
+			Labels:            map[string]string{"team": "storage"},
+			Annotations:       map[string]string{"backup.velero.io/backup-volumes-excludes": "ceph-daemon-data"},
+		},
+	}
+
+	d := c.makeDeployment(testGenMonConfig("a"), "node0")
+	spec := d.Spec.Template.Spec
+	assert.Equal(t, "system-cluster-critical", spec.PriorityClassName)
+	assert.Equal(t, "ceph/ceph:mymon", spec.InitContainers[0].Image)
+	assert.Equal(t, "ceph/ceph:mymon", spec.Containers[0].Image)
+	assert.Equal(t, "storage", d.Spec.Template.Labels["team"])
+	assert.Equal(t, "ceph-daemon-data", d.Spec.Template.Annotations["backup.velero.io/backup-volumes-excludes"])
+	// the selector of the deployment is not changed
+	assert.NotContains(t, d.Spec.Selector.MatchLabels, "team")
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+)
+
+var (
+	// WatchNamespaces are the namespaces of the clusters watched by the operator. All the namespaces are watched if empty.
+	WatchNamespaces []string
+	// WatchNamespaceSelector is the label selector of the namespaces of the clusters watched by the operator
+	WatchNamespaceSelector string
+)
+
+// WatchesAllNamespaces returns whether the operator watches the clusters of all the namespaces. Otherwise the operator
+// is only granted access to the watched namespaces.
+func WatchesAllNamespaces() bool {
+	return len(WatchNamespaces) == 0 && WatchNamespaceSelector == ""
+}
+
+// watchNamespaces watches the clusters in the namespaces matching the selector. The clusters of a namespace are
+// stopped when the namespace is deleted or does not match the selector anymore.
+func (c *ClusterController) watchNamespaces(stopCh chan struct{}) error {
+	selector, err := labels.Parse(WatchNamespaceSelector)
+	if err != nil {
+		return fmt.Errorf("invalid namespace selector %q. %+v", WatchNamespaceSelector, err)
+	}
+
+	logger.Infof("start watching clusters in the namespaces matching %q", selector.String())
+	lwNamespaces := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			options.LabelSelector = selector.String()
+			return c.context.Clientset.CoreV1().Namespaces().List(options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			options.LabelSelector = selector.String()
+			return c.context.Clientset.CoreV1().Namespaces().Watch(options)
+		},
+	}
+
+	_, namespaceController := cache.NewInformer(
+		lwNamespaces,
+		&v1.Namespace{},
+		0,
+		cache.ResourceEventHandlerFuncs{
+			AddFunc:    c.onNamespaceAdd,
+			UpdateFunc: nil,
+			DeleteFunc: c.onNamespaceDelete,
+		},
+	)
+	go namespaceController.Run(stopCh)
+	return nil
+}
+
+func (c *ClusterController) onNamespaceAdd(obj interface{}) {
+	namespace, ok := obj.(*v1.Namespace)
+	if !ok {
+		logger.Warningf("expected a namespace but handler received %#v", obj)
+		return
+	}
+
+	c.namespaceLock.Lock()
+	defer c.namespaceLock.Unlock()
+	if _, ok := c.namespaceStopChs[namespace.Name]; ok {
+		return
+	}
+	logger.Infof("start watching clusters in namespace %s", namespace.Name)
+	stopCh := make(chan struct{})
+	c.namespaceStopChs[namespace.Name] = stopCh
+	c.watchClusters(namespace.Name, stopCh)
+}
+
+func (c *ClusterController) onNamespaceDelete(obj interface{}) {
+	name, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		logger.Warningf("failed to get the name of the deleted namespace %#v. %+v", obj, err)
+		return
+	}
+
+	c.namespaceLock.Lock()
+	stopCh, ok := c.namespaceStopChs[name]
+	delete(c.namespaceStopChs, name)
+	c.namespaceLock.Unlock()
+	if !ok {
+		return
+	}
+
+	logger.Infof("stop watching clusters in namespace %s", name)
+	close(stopCh)
+	c.handlerLock.Lock()
+	defer c.handlerLock.Unlock()
+	if cluster, ok := c.clusterMap[name]; ok && cluster.Spec.Storage.AnyUseAllDevices() {
+		c.devicesInUse = false
+	}
+	c.stopCluster(name)
+}
+
+func (c *ClusterController) stopNamespaces() {
+	c.namespaceLock.Lock()
+	defer c.namespaceLock.Unlock()
+	for _, stopCh := range c.namespaceStopChs {
+		close(stopCh)
+	}
+	c.namespaceStopChs = make(map[string]chan struct{})
+}
@@ -0,0 +1,59 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package osd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rook/rook/pkg/daemon/ceph/client"
+	"github.com/rook/rook/pkg/operator/discover"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kubernetes/pkg/kubelet/apis"
+)
+
+// claimDevices records the cluster using the devices of its osds in their StorageDevice resources, and releases the
+// devices of the osds that were removed. The devices of an osd are read from its metadata, and its node from the
+// node selector of its deployment. A failure is only logged.
+func (c *Cluster) claimDevices() {
+	metadata, err := client.ListOSDMetadata(c.context, c.Namespace)
+	if err != nil {
+		logger.Warningf("failed to get the devices of the osds to claim them. %+v", err)
+		return
+	}
+	osdDevices := map[int]string{}
+	for _, m := range metadata {
+		osdDevices[m.ID] = m.Devices
+	}
+
+	listOpts := metav1.ListOptions{LabelSelector: fmt.Sprintf("app=%s", AppName)}
+	deployments, err := c.context.Clientset.AppsV1().Deployments(c.Namespace).List(listOpts)
+	if err != nil {
+		logger.Warningf("failed to list osd deployments to claim their devices. %+v", err)
+		return
+	}
+	nodeDevices := map[string][]string{}
+	for i, d := range deployments.Items {
+		nodeName := d.Spec.Template.Spec.NodeSelector[apis.LabelHostname]
+		id := getIDFromDeployment(&deployments.Items[i])
+		if nodeName == "" || id == unknownID || osdDevices[id] == "" {
+			continue
+		}
+		nodeDevices[nodeName] = append(nodeDevices[nodeName], strings.Split(osdDevices[id], ",")...)
+	}
+	discover.ClaimStorageDevices(c.context, c.Namespace, nodeDevices)
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package osd
+
+import (
+	"testing"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	rookalpha "github.com/rook/rook/pkg/apis/rook.io/v1alpha2"
+	rookfake "github.com/rook/rook/pkg/client/clientset/versioned/fake"
+	"github.com/rook/rook/pkg/clusterd"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+	apps "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestClaimDevices(t *testing.T) {
+	clusterName := "rook-ceph"
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName string, command string, outFileArg string, args ...string) (string, error) {
+			if args[0] == "osd" && args[1] == "metadata" {
+				return `[{"id": 0, "hostname": "pod0", "devices": "sda"}, {"id": 1, "hostname": "pod1", "devices": "sdb,nvme0n1"}]`, nil
+			}
+			return "", nil
+		},
+	}
+
+	// sdc was used by a removed osd, and sdd is used by another cluster
+	rookClientset := rookfake.NewSimpleClientset(
+		newTestStorageDevice("node1", "sda", "", nil),
+		newTestStorageDevice("node1", "sdb", "", nil),
+		newTestStorageDevice("node1", "nvme0n1", "", nil),
+		newTestStorageDevice("node1", "sdc", clusterName, nil),
+		newTestStorageDevice("node1", "sdd", "other", nil),
+	)
+	clientset := fake.NewSimpleClientset()
+	for _, d := range []*apps.Deployment{newTestOSDDeployment("0", "node1"), newTestOSDDeployment("1", "node1")} {
+		_, err := clientset.AppsV1().Deployments(clusterName).Create(d)
+		assert.Nil(t, err)
+	}
+	context := &clusterd.Context{Executor: executor, Clientset: clientset, RookClientset: rookClientset}
+	c := New(context, clusterName, "myversion", cephv1.CephVersionSpec{}, rookalpha.StorageScopeSpec{}, "",
+		rookalpha.Placement{}, false, v1.ResourceRequirements{}, metav1.OwnerReference{})
+
+	c.claimDevices()
+	for device, claimedBy := range map[string]string{"sda": clusterName, "sdb": clusterName, "nvme0n1": clusterName, "sdc": "", "sdd": "other"} {
+		d, err := rookClientset.RookV1alpha2().StorageDevices().Get("node1-"+device, metav1.GetOptions{})
+		assert.Nil(t, err)
+		assert.Equal(t, claimedBy, d.Spec.ClaimedBy, device)
+	}
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package osd
+
+import (
+	"fmt"
+	"strings"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	rookalpha "github.com/rook/rook/pkg/apis/rook.io/v1alpha2"
+	"github.com/rook/rook/pkg/daemon/ceph/client"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kubernetes/pkg/kubelet/apis"
+)
+
+const (
+	inStatus = 1
+
+	// UnhealthyDiskReason is the reason of the event emitted when an osd is marked out because of its disk health
+	UnhealthyDiskReason = "UnhealthyDisk"
+)
+
+// unhealthyDiskReason returns why the disk is considered about to fail, or an empty string if the disk is healthy
+func unhealthyDiskReason(health *rookalpha.DeviceHealth, spec cephv1.DiskHealthSpec) string {
+	if !health.Passed {
+		return "SMART self-assessment failed"
+	}
+	if spec.MaxReallocatedSectors > 0 && health.ReallocatedSectors > spec.MaxReallocatedSectors {
+		return fmt.Sprintf("%d reallocated sectors exceed the threshold of %d", health.ReallocatedSectors, spec.MaxReallocatedSectors)
+	}
+	if spec.MaxMediaErrors > 0 && health.MediaErrors > spec.MaxMediaErrors {
+		return fmt.Sprintf("%d media errors exceed the threshold of %d", health.MediaErrors, spec.MaxMediaErrors)
+	}
+	if spec.MaxPercentageUsed > 0 && health.PercentageUsed > spec.MaxPercentageUsed {
+		return fmt.Sprintf("%d%% of the endurance used exceeds the threshold of %d%%", health.PercentageUsed, spec.MaxPercentageUsed)
+	}
+	return ""
+}
+
+// getDiskHealthSpec returns the disk health settings of the current cluster of the namespace, so that the changes of
+// the thresholds are applied at the next check.
+func (m *Monitor) getDiskHealthSpec() (cephv1.DiskHealthSpec, error) {
+	clusters, err := m.context.RookClientset.CephV1().CephClusters(m.clusterName).List(metav1.ListOptions{})
+	if err != nil {
+		return cephv1.DiskHealthSpec{}, fmt.Errorf("failed to get the cluster of namespace %s. %+v", m.clusterName, err)
+	}
+	if len(clusters.Items) == 0 {
+		return cephv1.DiskHealthSpec{}, nil
+	}
+	return clusters.Items[0].Spec.DiskHealth, nil
+}
+
+// checkDiskHealth marks out the osds running on the disks of the cluster that are about to fail, so that their
+// data is moved to other osds before the disk dies.
+func (m *Monitor) checkDiskHealth() error {
+	diskHealth, err := m.getDiskHealthSpec()
+	if err != nil {
+		return err
+	}
+	if !diskHealth.Enabled {
+		return nil
+	}
+
+	// find the unhealthy devices claimed by the cluster, by node and device name
+	devices, err := m.context.RookClientset.RookV1alpha2().StorageDevices().List(metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list storage devices. %+v", err)
+	}
+	unhealthy := map[string]map[string]string{}
+	for _, device := range devices.Items {
+		if device.Spec.ClaimedBy != m.clusterName || device.Spec.Health == nil {
+			continue
+		}
+		reason := unhealthyDiskReason(device.Spec.Health, diskHealth)
+		if reason == "" {
+			continue
+		}
+		logger.Warningf("disk %s on node %s is unhealthy: %s", device.Spec.Name, device.Spec.Node, reason)
+		if _, ok := unhealthy[device.Spec.Node]; !ok {
+			unhealthy[device.Spec.Node] = map[string]string{}
+		}
+		unhealthy[device.Spec.Node][device.Spec.Name] = reason
+	}
+	if len(unhealthy) == 0 {
+		return nil
+	}
+
+	osdDump, err := client.GetOSDDump(m.context, m.clusterName)
+	if err != nil {
+		return err
+	}
+	listOpts := metav1.ListOptions{LabelSelector: fmt.Sprintf("app=%s", AppName)}
+	osdDeployments, err := m.context.Clientset.AppsV1().Deployments(m.clusterName).List(listOpts)
+	if err != nil {
+		return fmt.Errorf("failed to list osd deployments. %+v", err)
+	}
+
+	for i, osdDeployment := range osdDeployments.Items {
+		nodeName := osdDeployment.Spec.Template.Spec.NodeSelector[apis.LabelHostname]
+		nodeDevices, ok := unhealthy[nodeName]
+		if !ok {
+			continue
+		}
+		id := getIDFromDeployment(&osdDeployments.Items[i])
+		if id == unknownID {
+			continue
+		}
+		_, in, err := osdDump.StatusByID(int64(id))
+		if err != nil {
+			logger.Warningf("failed to get the status of osd.%d. %+v", id, err)
+			continue
+		}
+		if in != inStatus {
+			continue
+		}
+
+		metadata, err := client.GetOSDMetadata(m.context, m.clusterName, id)
+		if err != nil {
+			logger.Warningf("failed to get the devices of osd.%d. %+v", id, err)
+			continue
+		}
+		for _, device := range strings.Split(metadata.Devices, ",") {
+			reason, ok := nodeDevices[device]
+			if !ok {
+				continue
+			}
+			logger.Warningf("marking osd.%d out since its disk %s on node %s is about to fail: %s", id, device, nodeName, reason)
+			if _, err := client.OSDOut(m.context, m.clusterName, id); err != nil {
+				logger.Errorf("failed to mark osd.%d out. %+v", id, err)
+				break
+			}
+			m.recorder.Eventf(&osdDeployments.Items[i], v1.EventTypeWarning, UnhealthyDiskReason,
+				"osd.%d marked out since disk %s on node %s is about to fail: %s", id, device, nodeName, reason)
+			break
+		}
+	}
+	return nil
+}
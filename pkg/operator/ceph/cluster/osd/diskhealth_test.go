@@ -0,0 +1,125 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package osd
+
+import (
+	"testing"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	rookalpha "github.com/rook/rook/pkg/apis/rook.io/v1alpha2"
+	rookfake "github.com/rook/rook/pkg/client/clientset/versioned/fake"
+	"github.com/rook/rook/pkg/clusterd"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+	apps "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/kubernetes/pkg/kubelet/apis"
+)
+
+func TestUnhealthyDiskReason(t *testing.T) {
+	spec := cephv1.DiskHealthSpec{Enabled: true, MaxReallocatedSectors: 100, MaxMediaErrors: 10, MaxPercentageUsed: 90}
+
+	assert.Equal(t, "", unhealthyDiskReason(&rookalpha.DeviceHealth{Passed: true}, spec))
+	assert.Equal(t, "SMART self-assessment failed", unhealthyDiskReason(&rookalpha.DeviceHealth{Passed: false}, spec))
+	assert.Equal(t, "", unhealthyDiskReason(&rookalpha.DeviceHealth{Passed: true, ReallocatedSectors: 100}, spec))
+	assert.NotEqual(t, "", unhealthyDiskReason(&rookalpha.DeviceHealth{Passed: true, ReallocatedSectors: 101}, spec))
+	assert.NotEqual(t, "", unhealthyDiskReason(&rookalpha.DeviceHealth{Passed: true, MediaErrors: 11}, spec))
+	assert.NotEqual(t, "", unhealthyDiskReason(&rookalpha.DeviceHealth{Passed: true, PercentageUsed: 95}, spec))
+
+	// a threshold of 0 is ignored
+	spec = cephv1.DiskHealthSpec{Enabled: true}
+	assert.Equal(t, "", unhealthyDiskReason(&rookalpha.DeviceHealth{Passed: true, ReallocatedSectors: 1000, MediaErrors: 50, PercentageUsed: 100}, spec))
+}
+
+func newTestStorageDevice(node, name, claimedBy string, health *rookalpha.DeviceHealth) *rookalpha.StorageDevice {
+	return &rookalpha.StorageDevice{
+		ObjectMeta: metav1.ObjectMeta{Name: node + "-" + name},
+		Spec:       rookalpha.StorageDeviceSpec{Node: node, Name: name, ClaimedBy: claimedBy, Health: health},
+	}
+}
+
+func newTestOSDDeployment(id, node string) *apps.Deployment {
+	d := &apps.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "rook-ceph-osd-" + id,
+			Labels: map[string]string{"app": AppName, osdLabelKey: id},
+		},
+	}
+	d.Spec.Template.Spec.NodeSelector = map[string]string{apis.LabelHostname: node}
+	return d
+}
+
+func TestCheckDiskHealth(t *testing.T) {
+	clusterName := "rook-ceph"
+	outOSDs := []string{}
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName string, command string, outFileArg string, args ...string) (string, error) {
+			switch {
+			case args[0] == "osd" && args[1] == "dump":
+				return `{"osds": [{"osd": 0, "up": 1, "in": 1}, {"osd": 1, "up": 1, "in": 1}, {"osd": 2, "up": 1, "in": 0}]}`, nil
+			case args[0] == "osd" && args[1] == "metadata":
+				devices := map[string]string{"0": "sda", "1": "sdb", "2": "sdc"}
+				return `{"id": ` + args[2] + `, "hostname": "node1", "devices": "` + devices[args[2]] + `"}`, nil
+			case args[0] == "osd" && args[1] == "out":
+				outOSDs = append(outOSDs, args[2])
+				return "", nil
+			}
+			return "", nil
+		},
+	}
+
+	// sda is failing, sdb is healthy, sdc is failing but its osd is already out,
+	// sdd is failing but used by another cluster
+	cluster := &cephv1.CephCluster{ObjectMeta: metav1.ObjectMeta{Name: clusterName, Namespace: clusterName}}
+	rookClientset := rookfake.NewSimpleClientset(
+		cluster,
+		newTestStorageDevice("node1", "sda", clusterName, &rookalpha.DeviceHealth{Passed: true, ReallocatedSectors: 500}),
+		newTestStorageDevice("node1", "sdb", clusterName, &rookalpha.DeviceHealth{Passed: true, ReallocatedSectors: 2}),
+		newTestStorageDevice("node1", "sdc", clusterName, &rookalpha.DeviceHealth{Passed: false}),
+		newTestStorageDevice("node1", "sdd", "other", &rookalpha.DeviceHealth{Passed: false}),
+	)
+	clientset := fake.NewSimpleClientset()
+	for _, d := range []*apps.Deployment{newTestOSDDeployment("0", "node1"), newTestOSDDeployment("1", "node1"), newTestOSDDeployment("2", "node1")} {
+		_, err := clientset.AppsV1().Deployments(clusterName).Create(d)
+		assert.Nil(t, err)
+	}
+	context := &clusterd.Context{Executor: executor, Clientset: clientset, RookClientset: rookClientset}
+	recorder := record.NewFakeRecorder(10)
+
+	// nothing is done when the disk health check is disabled
+	m := NewMonitor(context, clusterName, recorder)
+	assert.Nil(t, m.checkDiskHealth())
+	assert.Equal(t, 0, len(outOSDs))
+
+	// the thresholds of the cluster are read at each check
+	cluster.Spec.DiskHealth = cephv1.DiskHealthSpec{Enabled: true, MaxReallocatedSectors: 1000}
+	_, err := rookClientset.CephV1().CephClusters(clusterName).Update(cluster)
+	assert.Nil(t, err)
+	assert.Nil(t, m.checkDiskHealth())
+	assert.Equal(t, 0, len(outOSDs))
+
+	cluster.Spec.DiskHealth.MaxReallocatedSectors = 100
+	_, err = rookClientset.CephV1().CephClusters(clusterName).Update(cluster)
+	assert.Nil(t, err)
+	assert.Nil(t, m.checkDiskHealth())
+	assert.Equal(t, []string{"0"}, outOSDs)
+	assert.Equal(t, 1, len(recorder.Events))
+	event := <-recorder.Events
+	assert.Contains(t, event, UnhealthyDiskReason)
+	assert.Contains(t, event, "osd.0")
+}
@@ -20,6 +20,8 @@ import (
 
 	"github.com/rook/rook/pkg/clusterd"
 	"github.com/rook/rook/pkg/daemon/ceph/client"
+	"github.com/rook/rook/pkg/operator/metrics"
+	"k8s.io/client-go/tools/record"
 )
 
 const upStatus = 1
@@ -37,11 +39,16 @@ type Monitor struct {
 	// lastStatus keeps track of OSDs status
 	// key - OSD id; value: time of the status change.
 	lastStatus map[int]time.Time
+
+	// pastGracePeriod keeps track of the OSDs that have been down for longer than the grace period
+	pastGracePeriod map[int]bool
+
+	recorder record.EventRecorder
 }
 
 // newMonitor instantiates OSD monitoring
-func NewMonitor(context *clusterd.Context, clusterName string) *Monitor {
-	return &Monitor{context, clusterName, make(map[int]time.Time)}
+func NewMonitor(context *clusterd.Context, clusterName string, recorder record.EventRecorder) *Monitor {
+	return &Monitor{context, clusterName, make(map[int]time.Time), make(map[int]bool), recorder}
 }
 
 // Run runs monitoring logic for osds status at set intervals
@@ -55,6 +62,9 @@ func (m *Monitor) Start(stopCh chan struct{}) {
 			if err != nil {
 				logger.Warningf("Failed OSD status check: %+v", err)
 			}
+			if err := m.checkDiskHealth(); err != nil {
+				logger.Warningf("Failed OSD disk health check: %+v", err)
+			}
 
 		case <-stopCh:
 			logger.Infof("Stopping monitoring of OSDs in namespace %s", m.clusterName)
@@ -72,13 +82,19 @@ func (m *Monitor) osdStatus() error {
 	}
 	logger.Debugf("osd dump %v", osdDump)
 
+	downPastGracePeriod := 0
 	evalDownStatus := func(id int) {
 		if now := time.Now(); now.Sub(m.lastStatus[id]) > osdGracePeriod {
 			logger.Warningf("osd.%d has been down for longer than the grace period (down since %+v)", id, m.lastStatus[id])
 			m.lastStatus[id] = time.Now()
+			m.pastGracePeriod[id] = true
 		} else {
 			logger.Warningf("waiting for the osd.%d to exceed the grace period", id)
 		}
+		// the osd is still past the grace period after the warning was repeated
+		if m.pastGracePeriod[id] {
+			downPastGracePeriod++
+		}
 	}
 
 	for _, osdStatus := range osdDump.OSDs {
@@ -109,9 +125,11 @@ func (m *Monitor) osdStatus() error {
 			if tracked {
 				logger.Debugf("osd.%d recovered, stopping tracking.", id)
 				delete(m.lastStatus, id)
+				delete(m.pastGracePeriod, id)
 			}
 		}
 	}
 
+	metrics.SetOSDsDown(m.clusterName, downPastGracePeriod)
 	return nil
 }
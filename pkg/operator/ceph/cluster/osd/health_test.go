@@ -23,6 +23,7 @@ import (
 	exectest "github.com/rook/rook/pkg/util/exec/test"
 
 	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/tools/record"
 )
 
 func TestOSDStatus(t *testing.T) {
@@ -57,7 +58,7 @@ func TestOSDStatus(t *testing.T) {
 		Executor: executor,
 	}
 	// Initializing an OSD monitoring
-	osdMon := NewMonitor(context, cluster)
+	osdMon := NewMonitor(context, cluster, record.NewFakeRecorder(10))
 	// Run OSD monitoring routine
 	err := osdMon.osdStatus()
 	assert.Nil(t, err)
@@ -74,11 +75,22 @@ func TestOSDStatus(t *testing.T) {
 	assert.Equal(t, 2, execCount)
 	// OSD monitor should stop tracking that process once the action is triggered
 	assert.Equal(t, 1, len(osdMon.lastStatus))
+	// the down time is reset after the warning so it is repeated once per grace period
+	osdGracePeriod = time.Hour
+	lastWarning := osdMon.lastStatus[0]
+	assert.True(t, osdMon.pastGracePeriod[0])
+
+	// the osd is still counted as down past the grace period until it is up again
+	err = osdMon.osdStatus()
+	assert.Nil(t, err)
+	assert.Equal(t, lastWarning, osdMon.lastStatus[0])
+	assert.True(t, osdMon.pastGracePeriod[0])
+	osdGracePeriod = 600 * time.Second
 }
 
 func TestMonitorStart(t *testing.T) {
 	stopCh := make(chan struct{})
-	osdMon := NewMonitor(&clusterd.Context{}, "cluster")
+	osdMon := NewMonitor(&clusterd.Context{}, "cluster", record.NewFakeRecorder(10))
 	logger.Infof("starting osd monitor")
 	go osdMon.Start(stopCh)
 	close(stopCh)
@@ -28,6 +28,7 @@ import (
 	rookalpha "github.com/rook/rook/pkg/apis/rook.io/v1alpha2"
 	"github.com/rook/rook/pkg/clusterd"
 	"github.com/rook/rook/pkg/daemon/ceph/client"
+	"github.com/rook/rook/pkg/daemon/ceph/osd/kms"
 	osdconfig "github.com/rook/rook/pkg/operator/ceph/cluster/osd/config"
 	opspec "github.com/rook/rook/pkg/operator/ceph/spec"
 	"github.com/rook/rook/pkg/operator/discover"
@@ -44,7 +45,8 @@ import (
 var logger = capnslog.NewPackageLogger("github.com/rook/rook", "op-osd")
 
 const (
-	appName                             = "rook-ceph-osd"
+	// AppName is the app label of the osd pods
+	AppName                             = "rook-ceph-osd"
 	prepareAppName                      = "rook-ceph-osd-prepare"
 	prepareAppNameFmt                   = "rook-ceph-osd-prepare-%s"
 	legacyAppNameFmt                    = "rook-ceph-osd-id-%d"
@@ -70,6 +72,15 @@ type Cluster struct {
 	resources       v1.ResourceRequirements
 	ownerRef        metav1.OwnerReference
 	kv              *k8sutil.ConfigMapKVStore
+	// Events records the failures of the osd orchestration on the cluster CRD
+	Events *k8sutil.ObjectEventRecorder
+	// CrushTopology derives the crush location of the osds from the labels of their nodes
+	CrushTopology cephv1.CrushTopologySpec
+	crushTypes    map[string]bool
+	// Override is the image, priority class, labels and annotations of the osd pods
+	Override cephv1.DaemonOverride
+	// KMS holds the keys of the encrypted osds outside of the cluster
+	KMS cephv1.KeyManagementServiceSpec
 }
 
 // New creates an instance of the OSD manager
@@ -113,6 +124,8 @@ type OSDInfo struct {
 	IsDirectory         bool   `json:"is-directory"`
 	DevicePartUUID      string `json:"device-part-uuid"`
 	CephVolumeInitiated bool   `json:"ceph-volume-initiated"`
+	// LUKSUUID is the LUKS UUID of the device encrypted by rook with a key held by a kms
+	LUKSUUID string `json:"luks-uuid,omitempty"`
 }
 
 type OrchestrationStatus struct {
@@ -130,6 +143,10 @@ func (c *Cluster) Start() error {
 		return fmt.Errorf("%v", err)
 	}
 
+	if err := kms.ValidateSecretsNamespace(c.Namespace, c.KMS.ConnectionDetails); err != nil {
+		return fmt.Errorf("invalid kms settings. %+v", err)
+	}
+
 	logger.Infof("start running osds in namespace %s", c.Namespace)
 
 	if c.Storage.UseAllNodes == false && len(c.Storage.Nodes) == 0 {
@@ -183,6 +200,12 @@ func (c *Cluster) Start() error {
 	logger.Infof("%d of the %d storage nodes are valid", len(validNodes), len(c.Storage.Nodes))
 	c.Storage.Nodes = validNodes
 
+	if c.CrushTopology.Enabled {
+		if err := c.loadCrushTypes(); err != nil {
+			return fmt.Errorf("failed to derive the crush location of the osds from the topology labels. %+v", err)
+		}
+	}
+
 	// start the jobs to provision the OSD devices and directories
 	config := newProvisionConfig()
 	logger.Infof("start provisioning the osds on nodes, if needed")
@@ -196,6 +219,8 @@ func (c *Cluster) Start() error {
 	logger.Infof("checking if any nodes were removed")
 	c.handleRemovedNodes(config)
 
+	c.claimDevices()
+
 	if len(config.errorMessages) > 0 {
 		return fmt.Errorf("%d failures encountered while running osds in namespace %s: %+v",
 			len(config.errorMessages), c.Namespace, strings.Join(config.errorMessages, "\n"))
@@ -235,7 +260,7 @@ func (c *Cluster) startProvisioning(config *provisionConfig) {
 		// create the job that prepares osds on the node
 		storeConfig := osdconfig.ToStoreConfig(n.Config)
 		metadataDevice := osdconfig.MetadataDevice(n.Config)
-		job, err := c.makeJob(n.Name, n.Devices, n.Selection, n.Resources, storeConfig, metadataDevice, n.Location)
+		job, err := c.makeJob(n.Name, n.Devices, n.Selection, n.Resources, storeConfig, metadataDevice, c.crushLocation(n))
 		if err != nil {
 			message := fmt.Sprintf("failed to create prepare job node %s: %v", n.Name, err)
 			config.addError(message)
@@ -285,11 +310,12 @@ func (c *Cluster) startOSDDaemonsOnNode(nodeName string, config *provisionConfig
 
 	storeConfig := osdconfig.ToStoreConfig(n.Config)
 	metadataDevice := osdconfig.MetadataDevice(n.Config)
+	location := c.crushLocation(n)
 
 	// start osds
 	for _, osd := range osds {
 		logger.Debugf("start osd %v", osd)
-		dp, err := c.makeDeployment(n.Name, n.Selection, n.Resources, storeConfig, metadataDevice, n.Location, osd)
+		dp, err := c.makeDeployment(n.Name, n.Selection, n.Resources, storeConfig, metadataDevice, location, osd)
 		if err != nil {
 			errMsg := fmt.Sprintf("failed to create deployment for node %s: %v", n.Name, err)
 			config.addError(errMsg)
@@ -311,6 +337,14 @@ func (c *Cluster) startOSDDaemonsOnNode(nodeName string, config *provisionConfig
 
 		logger.Infof("started deployment for osd %d (dir=%t, type=%s)", osd.ID, osd.IsDirectory, storeConfig.StoreType)
 	}
+
+	// move the host to the location derived from the labels of the node if they changed. the host is not in the crush
+	// map until its first osd is up, so the move is retried by the next orchestration.
+	if c.CrushTopology.Enabled && len(osds) > 0 {
+		if err := c.moveHost(n.Name, location); err != nil {
+			logger.Warningf("failed to move node %s in the crush map. %+v", n.Name, err)
+		}
+	}
 }
 
 func (c *Cluster) handleRemovedNodes(config *provisionConfig) {
@@ -413,7 +447,7 @@ func (c *Cluster) cleanupRemovedNode(config *provisionConfig, nodeName, crushNam
 
 func (c *Cluster) discoverStorageNodes() (map[string][]*apps.Deployment, error) {
 
-	listOpts := metav1.ListOptions{LabelSelector: fmt.Sprintf("app=%s", appName)}
+	listOpts := metav1.ListOptions{LabelSelector: fmt.Sprintf("app=%s", AppName)}
 	osdDeployments, err := c.context.Clientset.Apps().Deployments(c.Namespace).List(listOpts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list osd deployment: %+v", err)
@@ -22,12 +22,14 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 
 	rookalpha "github.com/rook/rook/pkg/apis/rook.io/v1alpha2"
 	opmon "github.com/rook/rook/pkg/operator/ceph/cluster/mon"
 	"github.com/rook/rook/pkg/operator/ceph/cluster/osd/config"
+	cephconfig "github.com/rook/rook/pkg/operator/ceph/config"
 	opspec "github.com/rook/rook/pkg/operator/ceph/spec"
 	"github.com/rook/rook/pkg/operator/k8sutil"
 	apps "k8s.io/api/apps/v1"
@@ -45,6 +47,13 @@ const (
 	osdJournalSizeEnvVarName    = "ROOK_OSD_JOURNAL_SIZE"
 	osdsPerDeviceEnvVarName     = "ROOK_OSDS_PER_DEVICE"
 	encryptedDeviceEnvVarName   = "ROOK_ENCRYPTED_DEVICE"
+	osdLUKSUUIDEnvVarName       = "ROOK_OSD_LUKS_UUID"
+	kmsTokenEnvVarName          = "VAULT_TOKEN"
+	kmsTokenSecretKey           = "token"
+	kmsCACertEnvVarName         = "VAULT_CACERT"
+	kmsCACertSecretKey          = "ca.crt"
+	kmsCACertVolumeName         = "kms-ca-cert"
+	kmsCACertMountPath          = "/etc/rook/kms"
 	osdMetadataDeviceEnvVarName = "ROOK_METADATA_DEVICE"
 	rookBinariesMountPath       = "/rook"
 	rookBinariesVolumeName      = "rook-binaries"
@@ -132,6 +141,13 @@ func (c *Cluster) makeDeployment(nodeName string, selection rookalpha.Selection,
 		{Name: "ROOK_OSD_ID", Value: osdID},
 		{Name: "ROOK_OSD_STORE_TYPE", Value: storeType},
 	}...)
+	if osd.LUKSUUID != "" {
+		// the osd opens its device with the key held by the kms before it is activated
+		envVars = append(envVars, v1.EnvVar{Name: osdLUKSUUIDEnvVarName, Value: osd.LUKSUUID})
+		envVars = append(envVars, c.kmsEnvVars()...)
+		volumes = append(volumes, c.kmsVolumes()...)
+		volumeMounts = append(volumeMounts, c.kmsVolumeMounts()...)
+	}
 	configEnvVars := append(c.getConfigEnvVars(storeConfig, dataDir, nodeName, location), []v1.EnvVar{
 		tiniEnvVar,
 		{Name: "ROOK_OSD_ID", Value: osdID},
@@ -151,11 +167,9 @@ func (c *Cluster) makeDeployment(nodeName string, selection rookalpha.Selection,
 		"--osd-uuid", osd.UUID,
 	}
 
-	// Set osd memory target to the best appropriate value
+	// Size the bluestore caches from the memory of the container
 	if !osd.IsFileStore {
-		if !c.resources.Limits.Memory().IsZero() {
-			commonArgs = append(commonArgs, "--osd-memory-target", strconv.Itoa(int(c.resources.Limits.Memory().Value())))
-		}
+		commonArgs = append(commonArgs, cephconfig.OsdMemoryFlags(c.resources)...)
 	}
 
 	if osd.IsFileStore {
@@ -167,6 +181,10 @@ func (c *Cluster) makeDeployment(nodeName string, selection rookalpha.Selection,
 	volumes = append(volumes, copyBinariesVolume)
 	volumeMounts = append(volumeMounts, copyBinariesContainer.VolumeMounts[0])
 
+	// the crash reports of the osd are written on the host, where the crash collector of the node posts them to the mgr
+	volumes = append(volumes, opspec.CrashVolume(c.dataDirHostPath))
+	volumeMounts = append(volumeMounts, opspec.CrashVolumeMount())
+
 	var command []string
 	var args []string
 	if !osd.IsDirectory && osd.IsFileStore && !osd.CephVolumeInitiated {
@@ -199,11 +217,9 @@ func (c *Cluster) makeDeployment(nodeName string, selection rookalpha.Selection,
 			"--cluster", "ceph",
 		}
 
-		// Set osd memory target to the best appropriate value
+		// Size the bluestore caches from the memory of the container
 		if !osd.IsFileStore {
-			if !c.resources.Limits.Memory().IsZero() {
-				args = append(args, "--osd-memory-target", strconv.Itoa(int(c.resources.Limits.Memory().Value())))
-			}
+			args = append(args, cephconfig.OsdMemoryFlags(c.resources)...)
 		}
 
 	} else {
@@ -233,7 +249,7 @@ func (c *Cluster) makeDeployment(nodeName string, selection rookalpha.Selection,
 			Name:      fmt.Sprintf(osdAppNameFmt, osd.ID),
 			Namespace: c.Namespace,
 			Labels: map[string]string{
-				k8sutil.AppAttr:     appName,
+				k8sutil.AppAttr:     AppName,
 				k8sutil.ClusterAttr: c.Namespace,
 				osdLabelKey:         fmt.Sprintf("%d", osd.ID),
 			},
@@ -241,7 +257,7 @@ func (c *Cluster) makeDeployment(nodeName string, selection rookalpha.Selection,
 		Spec: apps.DeploymentSpec{
 			Selector: &metav1.LabelSelector{
 				MatchLabels: map[string]string{
-					k8sutil.AppAttr:     appName,
+					k8sutil.AppAttr:     AppName,
 					k8sutil.ClusterAttr: c.Namespace,
 					osdLabelKey:         fmt.Sprintf("%d", osd.ID),
 				},
@@ -251,9 +267,9 @@ func (c *Cluster) makeDeployment(nodeName string, selection rookalpha.Selection,
 			},
 			Template: v1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
-					Name: appName,
+					Name: AppName,
 					Labels: map[string]string{
-						k8sutil.AppAttr:     appName,
+						k8sutil.AppAttr:     AppName,
 						k8sutil.ClusterAttr: c.Namespace,
 						osdLabelKey:         fmt.Sprintf("%d", osd.ID),
 					},
@@ -298,6 +314,7 @@ func (c *Cluster) makeDeployment(nodeName string, selection rookalpha.Selection,
 	}
 	k8sutil.SetOwnerRef(c.context.Clientset, c.Namespace, &deployment.ObjectMeta, &c.ownerRef)
 	c.placement.ApplyToPodSpec(&deployment.Spec.Template.Spec)
+	c.Override.ApplyToPod(&deployment.Spec.Template.ObjectMeta, &deployment.Spec.Template.Spec, c.cephVersion.Image)
 	return deployment, nil
 }
 
@@ -323,6 +340,9 @@ func (c *Cluster) provisionPodTemplateSpec(devices []rookalpha.Device, selection
 	copyBinariesVolume, copyBinariesContainer := c.getCopyBinariesContainer()
 
 	volumes := append(opspec.PodVolumes(c.dataDirHostPath), copyBinariesVolume)
+	if storeConfig.EncryptedDevice {
+		volumes = append(volumes, c.kmsVolumes()...)
+	}
 
 	// by default, don't define any volume config unless it is required
 	if len(devices) > 0 || selection.DeviceFilter != "" || selection.GetUseAllDevices() || metadataDevice != "" {
@@ -369,9 +389,9 @@ func (c *Cluster) provisionPodTemplateSpec(devices []rookalpha.Device, selection
 	// host through semaphore
 	podSpec.HostIPC = storeConfig.EncryptedDevice
 
-	return &v1.PodTemplateSpec{
+	podTemplateSpec := &v1.PodTemplateSpec{
 		ObjectMeta: metav1.ObjectMeta{
-			Name: appName,
+			Name: AppName,
 			Labels: map[string]string{
 				k8sutil.AppAttr:     prepareAppName,
 				k8sutil.ClusterAttr: c.Namespace,
@@ -379,7 +399,9 @@ func (c *Cluster) provisionPodTemplateSpec(devices []rookalpha.Device, selection
 			Annotations: map[string]string{},
 		},
 		Spec: podSpec,
-	}, nil
+	}
+	c.Override.ApplyToPod(&podTemplateSpec.ObjectMeta, &podTemplateSpec.Spec, c.cephVersion.Image)
+	return podTemplateSpec, nil
 }
 
 func (c *Cluster) getConfigEnvVars(storeConfig config.StoreConfig, dataDir, nodeName, location string) []v1.EnvVar {
@@ -433,6 +455,58 @@ func (c *Cluster) getConfigEnvVars(storeConfig config.StoreConfig, dataDir, node
 	return envVars
 }
 
+// kmsEnvVars returns the settings of the kms holding the keys of the encrypted osds. No settings are returned if the
+// keys are stored in the mon config-key store by ceph-volume.
+func (c *Cluster) kmsEnvVars() []v1.EnvVar {
+	var names []string
+	for name := range c.KMS.ConnectionDetails {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	envVars := []v1.EnvVar{}
+	for _, name := range names {
+		if name == kmsCACertEnvVarName && c.KMS.CACertSecretName != "" {
+			continue
+		}
+		envVars = append(envVars, v1.EnvVar{Name: name, Value: c.KMS.ConnectionDetails[name]})
+	}
+	if c.KMS.CACertSecretName != "" {
+		envVars = append(envVars, v1.EnvVar{Name: kmsCACertEnvVarName, Value: path.Join(kmsCACertMountPath, kmsCACertSecretKey)})
+	}
+	if c.KMS.TokenSecretName != "" {
+		envVars = append(envVars, v1.EnvVar{Name: kmsTokenEnvVarName, ValueFrom: &v1.EnvVarSource{
+			SecretKeyRef: &v1.SecretKeySelector{
+				LocalObjectReference: v1.LocalObjectReference{Name: c.KMS.TokenSecretName},
+				Key:                  kmsTokenSecretKey,
+			},
+		}})
+	}
+	return envVars
+}
+
+// kmsVolumes returns the volume of the CA certificate of the kms, if it is set
+func (c *Cluster) kmsVolumes() []v1.Volume {
+	if c.KMS.CACertSecretName == "" {
+		return nil
+	}
+	return []v1.Volume{{
+		Name: kmsCACertVolumeName,
+		VolumeSource: v1.VolumeSource{Secret: &v1.SecretVolumeSource{
+			SecretName: c.KMS.CACertSecretName,
+			Items:      []v1.KeyToPath{{Key: kmsCACertSecretKey, Path: kmsCACertSecretKey}},
+		}},
+	}}
+}
+
+// kmsVolumeMounts returns the mount of the CA certificate of the kms, if it is set
+func (c *Cluster) kmsVolumeMounts() []v1.VolumeMount {
+	if c.KMS.CACertSecretName == "" {
+		return nil
+	}
+	return []v1.VolumeMount{{Name: kmsCACertVolumeName, MountPath: kmsCACertMountPath, ReadOnly: true}}
+}
+
 func (c *Cluster) provisionOSDContainer(devices []rookalpha.Device, selection rookalpha.Selection, resources v1.ResourceRequirements,
 	storeConfig config.StoreConfig, metadataDevice, nodeName, location string, copyBinariesMount v1.VolumeMount) v1.Container {
 
@@ -461,12 +535,19 @@ func (c *Cluster) provisionOSDContainer(devices []rookalpha.Device, selection ro
 		devMountNeeded = true
 	}
 
+	if storeConfig.EncryptedDevice {
+		envVars = append(envVars, c.kmsEnvVars()...)
+	}
+
 	if metadataDevice != "" {
 		envVars = append(envVars, metadataDeviceEnvVar(metadataDevice))
 		devMountNeeded = true
 	}
 
 	volumeMounts := append(opspec.CephVolumeMounts(), copyBinariesMount)
+	if storeConfig.EncryptedDevice {
+		volumeMounts = append(volumeMounts, c.kmsVolumeMounts()...)
+	}
 	if devMountNeeded {
 		devMount := v1.VolumeMount{Name: "devices", MountPath: "/dev"}
 		volumeMounts = append(volumeMounts, devMount)
@@ -96,20 +96,20 @@ func testPodDevices(t *testing.T, dataDir, deviceName string, allDevices bool) {
 	assert.Equal(t, "node1", deployment.Spec.Template.Spec.NodeSelector[apis.LabelHostname])
 	assert.Equal(t, v1.RestartPolicyAlways, deployment.Spec.Template.Spec.RestartPolicy)
 	if devMountNeeded && len(dataDir) > 0 {
-		assert.Equal(t, 5, len(deployment.Spec.Template.Spec.Volumes))
+		assert.Equal(t, 6, len(deployment.Spec.Template.Spec.Volumes))
 	}
 	if devMountNeeded && len(dataDir) == 0 {
-		assert.Equal(t, 5, len(deployment.Spec.Template.Spec.Volumes))
+		assert.Equal(t, 6, len(deployment.Spec.Template.Spec.Volumes))
 	}
 	if !devMountNeeded && len(dataDir) > 0 {
-		assert.Equal(t, 2, len(deployment.Spec.Template.Spec.Volumes))
+		assert.Equal(t, 3, len(deployment.Spec.Template.Spec.Volumes))
 	}
 
 	assert.Equal(t, "rook-data", deployment.Spec.Template.Spec.Volumes[0].Name)
 	assert.Equal(t, "ceph-default-config-dir", deployment.Spec.Template.Spec.Volumes[1].Name)
 
-	assert.Equal(t, appName, deployment.Spec.Template.ObjectMeta.Name)
-	assert.Equal(t, appName, deployment.Spec.Template.ObjectMeta.Labels["app"])
+	assert.Equal(t, AppName, deployment.Spec.Template.ObjectMeta.Name)
+	assert.Equal(t, AppName, deployment.Spec.Template.ObjectMeta.Labels["app"])
 	assert.Equal(t, c.Namespace, deployment.Spec.Template.ObjectMeta.Labels["rook_cluster"])
 	assert.Equal(t, 0, len(deployment.Spec.Template.ObjectMeta.Annotations))
 
@@ -122,7 +122,8 @@ func testPodDevices(t *testing.T, dataDir, deviceName string, allDevices bool) {
 	assert.Equal(t, 1, len(deployment.Spec.Template.Spec.Containers))
 	cont := deployment.Spec.Template.Spec.Containers[0]
 	assert.Equal(t, cephVersion.Image, cont.Image)
-	assert.Equal(t, 4, len(cont.VolumeMounts))
+	assert.Equal(t, 5, len(cont.VolumeMounts))
+	assert.Equal(t, "/var/lib/ceph/crash", cont.VolumeMounts[4].MountPath)
 	assert.Equal(t, "ceph-osd", cont.Command[0])
 }
 
@@ -170,10 +171,10 @@ func TestStorageSpecDevicesAndDirectories(t *testing.T) {
 	assert.Nil(t, err)
 	// pod spec should have a volume for the given dir in the main container and the init container
 	podSpec := deployment.Spec.Template.Spec
-	assert.Equal(t, 5, len(podSpec.Volumes))
+	assert.Equal(t, 6, len(podSpec.Volumes))
 	require.Equal(t, 1, len(podSpec.Containers))
 	cont := podSpec.Containers[0]
-	assert.Equal(t, 4, len(cont.VolumeMounts))
+	assert.Equal(t, 5, len(cont.VolumeMounts))
 	assert.Equal(t, "/var/lib/rook", cont.VolumeMounts[0].MountPath)
 	assert.Equal(t, "/etc/ceph", cont.VolumeMounts[1].MountPath)
 	assert.Equal(t, "/my/root/path", cont.VolumeMounts[2].MountPath)
@@ -197,10 +198,10 @@ func TestStorageSpecDevicesAndDirectories(t *testing.T) {
 	assert.Nil(t, err)
 	// pod spec should have a volume for the given dir in the main container and the init container
 	podSpec = deployment.Spec.Template.Spec
-	assert.Equal(t, 4, len(podSpec.Volumes))
+	assert.Equal(t, 5, len(podSpec.Volumes))
 	require.Equal(t, 1, len(podSpec.Containers))
 	cont = podSpec.Containers[0]
-	require.Equal(t, 3, len(cont.VolumeMounts))
+	require.Equal(t, 4, len(cont.VolumeMounts))
 	assert.Equal(t, "/var/lib/rook", cont.VolumeMounts[0].MountPath)
 	assert.Equal(t, "/etc/ceph", cont.VolumeMounts[1].MountPath)
 
@@ -312,3 +313,81 @@ func TestHostNetwork(t *testing.T) {
 	assert.Equal(t, true, r.Spec.Template.Spec.HostNetwork)
 	assert.Equal(t, v1.DNSClusterFirstWithHostNet, r.Spec.Template.Spec.DNSPolicy)
 }
+
+func TestEncryptedDeviceKMS(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	storageSpec := rookalpha.StorageScopeSpec{Nodes: []rookalpha.Node{{Name: "node1"}}}
+	c := New(&clusterd.Context{Clientset: clientset, ConfigDir: "/var/lib/rook", Executor: &exectest.MockExecutor{}}, "ns", "rook/rook:myversion", cephv1.CephVersionSpec{},
+		storageSpec, "", rookalpha.Placement{}, false, v1.ResourceRequirements{}, metav1.OwnerReference{})
+	c.KMS = cephv1.KeyManagementServiceSpec{
+		ConnectionDetails: map[string]string{"KMS_PROVIDER": "vault", "VAULT_ADDR": "http://vault:8200"},
+		TokenSecretName:   "vault-token",
+	}
+	storeConfig := config.StoreConfig{EncryptedDevice: true}
+
+	// the osd opens its device with the key held by the kms
+	osd := OSDInfo{ID: 0, CephVolumeInitiated: true, LUKSUUID: "1234"}
+	deployment, err := c.makeDeployment("node1", rookalpha.Selection{}, v1.ResourceRequirements{}, storeConfig, "", "", osd)
+	require.Nil(t, err)
+	envVars := deployment.Spec.Template.Spec.Containers[0].Env
+	verifyEnvVar(t, envVars, "ROOK_OSD_LUKS_UUID", "1234", true)
+	verifyEnvVar(t, envVars, "KMS_PROVIDER", "vault", true)
+	verifyEnvVar(t, envVars, "VAULT_ADDR", "http://vault:8200", true)
+	verifyEnvVar(t, envVars, "VAULT_TOKEN", "", true)
+	assert.True(t, deployment.Spec.Template.Spec.HostIPC)
+
+	// the keys of the osds encrypted by ceph-volume are in the mon config-key store
+	osd.LUKSUUID = ""
+	deployment, err = c.makeDeployment("node1", rookalpha.Selection{}, v1.ResourceRequirements{}, storeConfig, "", "", osd)
+	require.Nil(t, err)
+	verifyEnvVar(t, deployment.Spec.Template.Spec.Containers[0].Env, "KMS_PROVIDER", "", false)
+
+	// the prepare job encrypts the new devices with keys held by the kms
+	podSpec, err := c.provisionPodTemplateSpec([]rookalpha.Device{{Name: "sda"}}, rookalpha.Selection{}, v1.ResourceRequirements{}, storeConfig, "", "node1", "", v1.RestartPolicyOnFailure)
+	require.Nil(t, err)
+	envVars = podSpec.Spec.Containers[1].Env
+	verifyEnvVar(t, envVars, "KMS_PROVIDER", "vault", true)
+	verifyEnvVar(t, envVars, "VAULT_TOKEN", "", true)
+
+	podSpec, err = c.provisionPodTemplateSpec([]rookalpha.Device{{Name: "sda"}}, rookalpha.Selection{}, v1.ResourceRequirements{}, config.StoreConfig{}, "", "node1", "", v1.RestartPolicyOnFailure)
+	require.Nil(t, err)
+	verifyEnvVar(t, podSpec.Spec.Containers[1].Env, "KMS_PROVIDER", "", false)
+}
+
+func TestKMSCACert(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	storageSpec := rookalpha.StorageScopeSpec{Nodes: []rookalpha.Node{{Name: "node1"}}}
+	c := New(&clusterd.Context{Clientset: clientset, ConfigDir: "/var/lib/rook", Executor: &exectest.MockExecutor{}}, "ns", "rook/rook:myversion", cephv1.CephVersionSpec{},
+		storageSpec, "", rookalpha.Placement{}, false, v1.ResourceRequirements{}, metav1.OwnerReference{})
+	c.KMS = cephv1.KeyManagementServiceSpec{
+		ConnectionDetails: map[string]string{"KMS_PROVIDER": "vault", "VAULT_ADDR": "https://vault:8200", "VAULT_CACERT": "/tmp/ca.crt"},
+		TokenSecretName:   "vault-token",
+		CACertSecretName:  "vault-ca",
+	}
+	storeConfig := config.StoreConfig{EncryptedDevice: true}
+
+	// the ca certificate is mounted in the osd container from the secret
+	osd := OSDInfo{ID: 0, CephVolumeInitiated: true, LUKSUUID: "1234"}
+	deployment, err := c.makeDeployment("node1", rookalpha.Selection{}, v1.ResourceRequirements{}, storeConfig, "", "", osd)
+	require.Nil(t, err)
+	verifyEnvVar(t, deployment.Spec.Template.Spec.Containers[0].Env, "VAULT_CACERT", "/etc/rook/kms/ca.crt", true)
+	assert.Contains(t, deployment.Spec.Template.Spec.Containers[0].VolumeMounts, v1.VolumeMount{Name: "kms-ca-cert", MountPath: "/etc/rook/kms", ReadOnly: true})
+	assert.Contains(t, deployment.Spec.Template.Spec.Volumes, c.kmsVolumes()[0])
+	assert.Equal(t, "vault-ca", c.kmsVolumes()[0].Secret.SecretName)
+
+	// and in the provision container
+	podSpec, err := c.provisionPodTemplateSpec([]rookalpha.Device{{Name: "sda"}}, rookalpha.Selection{}, v1.ResourceRequirements{}, storeConfig, "", "node1", "", v1.RestartPolicyOnFailure)
+	require.Nil(t, err)
+	verifyEnvVar(t, podSpec.Spec.Containers[1].Env, "VAULT_CACERT", "/etc/rook/kms/ca.crt", true)
+	assert.Contains(t, podSpec.Spec.Containers[1].VolumeMounts, v1.VolumeMount{Name: "kms-ca-cert", MountPath: "/etc/rook/kms", ReadOnly: true})
+	assert.Contains(t, podSpec.Spec.Volumes, c.kmsVolumes()[0])
+
+	// no volume without the secret
+	c.KMS.CACertSecretName = ""
+	deployment, err = c.makeDeployment("node1", rookalpha.Selection{}, v1.ResourceRequirements{}, storeConfig, "", "", osd)
+	require.Nil(t, err)
+	verifyEnvVar(t, deployment.Spec.Template.Spec.Containers[0].Env, "VAULT_CACERT", "/tmp/ca.crt", true)
+	for _, volume := range deployment.Spec.Template.Spec.Volumes {
+		assert.NotEqual(t, "kms-ca-cert", volume.Name)
+	}
+}
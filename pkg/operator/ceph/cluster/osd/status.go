@@ -23,6 +23,7 @@ import (
 	"time"
 
 	"github.com/rook/rook/pkg/operator/k8sutil"
+	"github.com/rook/rook/pkg/operator/metrics"
 	"github.com/rook/rook/pkg/util"
 	apps "k8s.io/api/apps/v1"
 	"k8s.io/api/core/v1"
@@ -44,6 +45,8 @@ const (
 	nodeLabelKey                     = "node"
 	completeProvisionTimeout         = 20
 	completeProvisionSkipOSDTimeout  = 5
+	// orchestrationFailedReason is the reason of the events recorded when the osds of a node can't be prepared
+	orchestrationFailedReason = "OSDPrepareFailed"
 )
 
 type provisionConfig struct {
@@ -65,7 +68,7 @@ func (c *Cluster) updateNodeStatus(node string, status OrchestrationStatus) erro
 
 func UpdateNodeStatus(kv *k8sutil.ConfigMapKVStore, node string, status OrchestrationStatus) error {
 	labels := map[string]string{
-		k8sutil.AppAttr:        appName,
+		k8sutil.AppAttr:        AppName,
 		orchestrationStatusKey: provisioningLabelKey,
 		nodeLabelKey:           node,
 	}
@@ -85,6 +88,8 @@ func UpdateNodeStatus(kv *k8sutil.ConfigMapKVStore, node string, status Orchestr
 
 func (c *Cluster) handleOrchestrationFailure(config *provisionConfig, nodeName, message string) {
 	config.addError(message)
+	c.Events.Warningf(orchestrationFailedReason, "failed to prepare the osds on node %s. %s", nodeName, message)
+	metrics.IncOrchestrationFailure(c.Namespace, AppName)
 	status := OrchestrationStatus{Status: OrchestrationStatusFailed, Message: message}
 	if err := c.updateNodeStatus(nodeName, status); err != nil {
 		config.addError("failed to update status for node %s. %+v", nodeName, err)
@@ -161,7 +166,7 @@ func (c *Cluster) checkNodesCompleted(selector string, config *provisionConfig,
 
 func (c *Cluster) completeOSDsForAllNodes(config *provisionConfig, configOSDs bool, timeoutMinutes int) bool {
 	selector := fmt.Sprintf("%s=%s,%s=%s",
-		k8sutil.AppAttr, appName,
+		k8sutil.AppAttr, AppName,
 		orchestrationStatusKey, provisioningLabelKey,
 	)
 
@@ -235,6 +240,7 @@ func (c *Cluster) completeOSDsForAllNodes(config *provisionConfig, configOSDs bo
 				// log every so often while we are waiting
 				currentTimeoutMinutes++
 				if currentTimeoutMinutes == timeoutMinutes {
+					metrics.IncOrchestrationFailure(c.Namespace, AppName)
 					config.addError("timed out waiting for %d nodes: %+v", remainingNodes.Count(), remainingNodes)
 					return false
 				}
@@ -0,0 +1,248 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package osd
+
+import (
+	"fmt"
+	"strings"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	rookalpha "github.com/rook/rook/pkg/apis/rook.io/v1alpha2"
+	"github.com/rook/rook/pkg/daemon/ceph/client"
+	opspec "github.com/rook/rook/pkg/operator/ceph/spec"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	apps "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kubernetes/pkg/kubelet/apis"
+)
+
+// topologyLabelPrefix is the prefix of the default node labels of the CRUSH bucket types other than region and zone
+const topologyLabelPrefix = "topology.rook.io/"
+
+// crushTopologyTypes are the CRUSH bucket types above the host that are derived from the node labels, from the
+// largest to the smallest
+var crushTopologyTypes = []string{"region", "zone", "datacenter", "room", "pod", "pdu", "row", "rack", "chassis"}
+
+// topologyLabel returns the node label holding the CRUSH bucket of the given type
+func topologyLabel(spec cephv1.CrushTopologySpec, crushType string) string {
+	if label, ok := spec.Labels[crushType]; ok {
+		return label
+	}
+	switch crushType {
+	case "region":
+		return apis.LabelZoneRegion
+	case "zone":
+		return apis.LabelZoneFailureDomain
+	}
+	return topologyLabelPrefix + crushType
+}
+
+// TopologyLocation returns the CRUSH location derived from the labels of a node, such as "zone=a,rack=b". An empty
+// location is returned if the topology is not enabled.
+func TopologyLocation(spec cephv1.CrushTopologySpec, nodeLabels map[string]string) string {
+	if !spec.Enabled {
+		return ""
+	}
+	var pairs []string
+	for _, crushType := range crushTopologyTypes {
+		if value := nodeLabels[topologyLabel(spec, crushType)]; value != "" {
+			pairs = append(pairs, fmt.Sprintf("%s=%s", crushType, value))
+		}
+	}
+	return strings.Join(pairs, ",")
+}
+
+// TopologyChanged returns whether the CRUSH location derived from the labels of a node changed
+func TopologyChanged(spec cephv1.CrushTopologySpec, oldLabels, newLabels map[string]string) bool {
+	return TopologyLocation(spec, oldLabels) != TopologyLocation(spec, newLabels)
+}
+
+// crushLocation returns the CRUSH location of the osds of a node. The location set on the node or on the storage takes
+// precedence over the location derived from the labels of the node.
+func (c *Cluster) crushLocation(n *rookalpha.Node) string {
+	if !c.CrushTopology.Enabled {
+		return n.Location
+	}
+
+	options := metav1.ListOptions{LabelSelector: fmt.Sprintf("%s=%s", apis.LabelHostname, n.Name)}
+	nodes, err := c.context.Clientset.CoreV1().Nodes().List(options)
+	if err != nil || len(nodes.Items) == 0 {
+		logger.Warningf("failed to get node %s to derive its crush location from its labels. %+v", n.Name, err)
+		return n.Location
+	}
+
+	pairs := []string{}
+	if n.Location != "" {
+		pairs = strings.Split(n.Location, ",")
+	}
+	topology := TopologyLocation(c.CrushTopology, nodes.Items[0].Labels)
+	if topology == "" {
+		return n.Location
+	}
+	for _, pair := range strings.Split(topology, ",") {
+		crushType := strings.Split(pair, "=")[0]
+		if c.crushTypes != nil && !c.crushTypes[crushType] {
+			logger.Warningf("ignoring %s of node %s. crush type %s is not in the crush map", pair, n.Name, crushType)
+			continue
+		}
+		if !locationHasType(pairs, crushType) {
+			pairs = append(pairs, pair)
+		}
+	}
+	return strings.Join(pairs, ",")
+}
+
+// loadCrushTypes loads the bucket types of the crush map. The topology types missing in the crush map, such as the zone
+// type of the maps created by older Rook versions, are added to the map. The osds are not configured with a location
+// that the crush map can't hold.
+func (c *Cluster) loadCrushTypes() error {
+	crushMap, err := client.GetCrushMap(c.context, c.Namespace)
+	if err != nil {
+		return fmt.Errorf("failed to get the crush types. %+v", err)
+	}
+	c.crushTypes = map[string]bool{}
+	for _, t := range crushMap.Types {
+		c.crushTypes[t.Name] = true
+	}
+
+	// add the missing types above the next smaller type of the map
+	below := "host"
+	for i := len(crushTopologyTypes) - 1; i >= 0; i-- {
+		crushType := crushTopologyTypes[i]
+		if !c.crushTypes[crushType] {
+			logger.Infof("adding crush type %s above type %s to the crush map", crushType, below)
+			if err := client.AddCrushType(c.context, c.Namespace, crushType, below); err != nil {
+				return fmt.Errorf("failed to add crush type %s. %+v", crushType, err)
+			}
+			c.crushTypes[crushType] = true
+		}
+		below = crushType
+	}
+	return nil
+}
+
+// moveHost moves the host bucket of a node to its crush location. A restarted osd does not move its host bucket when
+// its location changes, it would only join its host bucket at the previous location.
+func (c *Cluster) moveHost(nodeName, location string) error {
+	pairs, err := client.FormatLocation(location, nodeName)
+	if err != nil {
+		return fmt.Errorf("invalid crush location %s. %+v", location, err)
+	}
+
+	var host string
+	var ancestors []string
+	for _, pair := range pairs {
+		if strings.HasPrefix(pair, "host=") {
+			host = strings.TrimPrefix(pair, "host=")
+		} else {
+			ancestors = append(ancestors, pair)
+		}
+	}
+
+	if _, err := client.CrushMoveBucket(c.context, c.Namespace, host, ancestors); err != nil {
+		return fmt.Errorf("failed to move host %s to %s. %+v", host, strings.Join(ancestors, ","), err)
+	}
+	return nil
+}
+
+// IsStorageNode returns whether the osds of the storage spec may run on the node with the given name
+func IsStorageNode(storage rookalpha.StorageScopeSpec, nodeName string) bool {
+	if storage.UseAllNodes {
+		return true
+	}
+	for _, n := range storage.Nodes {
+		if n.Name == nodeName {
+			return true
+		}
+	}
+	return false
+}
+
+// UpdateNodeLocation updates the crush location of the osds of a node after its topology labels changed. Only the osd
+// deployments of the node are updated and its host bucket moved, the node is not provisioned again.
+func (c *Cluster) UpdateNodeLocation(nodeName string) error {
+	if !c.CrushTopology.Enabled || !IsStorageNode(c.Storage, nodeName) {
+		return nil
+	}
+	storageNodes, err := c.discoverStorageNodes()
+	if err != nil {
+		return fmt.Errorf("failed to get the osds of node %s. %+v", nodeName, err)
+	}
+	deployments := storageNodes[nodeName]
+	if len(deployments) == 0 {
+		logger.Debugf("no osd on node %s", nodeName)
+		return nil
+	}
+
+	if c.Storage.UseAllNodes {
+		// the storage nodes are only listed by the orchestration when all the nodes are used
+		c.Storage.Nodes = []rookalpha.Node{{Name: nodeName}}
+	}
+	n := c.resolveNode(nodeName)
+	if n == nil {
+		return fmt.Errorf("node %s did not resolve to update its osds", nodeName)
+	}
+	if err := c.loadCrushTypes(); err != nil {
+		return fmt.Errorf("failed to update the crush location of the osds of node %s. %+v", nodeName, err)
+	}
+	location := c.crushLocation(n)
+
+	for _, d := range deployments {
+		if !setLocationEnvVar(d, location) {
+			logger.Debugf("crush location of osd deployment %s is unchanged", d.Name)
+			continue
+		}
+		logger.Infof("updating the crush location of osd deployment %s to %s", d.Name, location)
+		if _, err := k8sutil.UpdateDeploymentAndWait(c.context, d, c.Namespace); err != nil {
+			return fmt.Errorf("failed to update the crush location of osd deployment %s. %+v", d.Name, err)
+		}
+	}
+
+	return c.moveHost(n.Name, location)
+}
+
+// setLocationEnvVar sets the crush location in the env of the container configuring the osd. Returns whether the
+// location changed.
+func setLocationEnvVar(d *apps.Deployment, location string) bool {
+	for i, container := range d.Spec.Template.Spec.InitContainers {
+		if container.Name != opspec.ConfigInitContainerName || rookalpha.GetLocationFromContainer(container) == location {
+			continue
+		}
+		env := []v1.EnvVar{}
+		for _, envVar := range container.Env {
+			if envVar.Name != rookalpha.LocationEnvVarName {
+				env = append(env, envVar)
+			}
+		}
+		if location != "" {
+			env = append(env, rookalpha.LocationEnvVar(location))
+		}
+		d.Spec.Template.Spec.InitContainers[i].Env = env
+		return true
+	}
+	return false
+}
+
+func locationHasType(pairs []string, crushType string) bool {
+	for _, pair := range pairs {
+		if strings.Split(pair, "=")[0] == crushType {
+			return true
+		}
+	}
+	return false
+}
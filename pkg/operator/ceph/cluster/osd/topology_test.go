@@ -0,0 +1,158 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package osd
+
+import (
+	"testing"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	rookalpha "github.com/rook/rook/pkg/apis/rook.io/v1alpha2"
+	"github.com/rook/rook/pkg/clusterd"
+	opspec "github.com/rook/rook/pkg/operator/ceph/spec"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+	apps "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestTopologyLocation(t *testing.T) {
+	labels := map[string]string{
+		"failure-domain.beta.kubernetes.io/region": "us",
+		"failure-domain.beta.kubernetes.io/zone":   "us-1a",
+		"topology.rook.io/rack":                    "rack1",
+		"example.com/row":                          "row2",
+	}
+
+	// the labels are ignored if the topology is not enabled
+	spec := cephv1.CrushTopologySpec{}
+	assert.Equal(t, "", TopologyLocation(spec, labels))
+
+	spec.Enabled = true
+	assert.Equal(t, "region=us,zone=us-1a,rack=rack1", TopologyLocation(spec, labels))
+
+	// custom labels
+	spec.Labels = map[string]string{"row": "example.com/row", "zone": "example.com/zone"}
+	assert.Equal(t, "region=us,row=row2,rack=rack1", TopologyLocation(spec, labels))
+
+	newLabels := map[string]string{"example.com/row": "row3"}
+	assert.True(t, TopologyChanged(spec, labels, newLabels))
+	assert.False(t, TopologyChanged(spec, labels, labels))
+	assert.False(t, TopologyChanged(cephv1.CrushTopologySpec{}, labels, newLabels))
+}
+
+func TestCrushLocation(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "node1",
+			Labels: map[string]string{
+				"kubernetes.io/hostname":                 "node1",
+				"failure-domain.beta.kubernetes.io/zone": "us-1a",
+				"topology.rook.io/rack":                  "rack1",
+				"topology.rook.io/row":                   "row1",
+			},
+		},
+	}
+	_, err := clientset.CoreV1().Nodes().Create(node)
+	assert.Nil(t, err)
+
+	c := &Cluster{context: &clusterd.Context{Clientset: clientset}}
+	n := &rookalpha.Node{Name: "node1", Location: "rack=rack2"}
+
+	// the topology is not enabled
+	assert.Equal(t, "rack=rack2", c.crushLocation(n))
+
+	// the location of the node takes precedence
+	c.CrushTopology.Enabled = true
+	assert.Equal(t, "rack=rack2,zone=us-1a,row=row1", c.crushLocation(n))
+
+	// the types missing in the crush map are skipped
+	c.crushTypes = map[string]bool{"host": true, "rack": true, "row": true, "root": true}
+	assert.Equal(t, "rack=rack2,row=row1", c.crushLocation(n))
+
+	// unknown node
+	n = &rookalpha.Node{Name: "node2", Location: "rack=rack2"}
+	assert.Equal(t, "rack=rack2", c.crushLocation(n))
+}
+
+func TestMoveHost(t *testing.T) {
+	var moveArgs []string
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName, command, outputFile string, args ...string) (string, error) {
+			if args[0] == "osd" && args[1] == "crush" && args[2] == "move" {
+				moveArgs = args[3:7]
+			}
+			return "", nil
+		},
+	}
+	c := &Cluster{context: &clusterd.Context{Executor: executor}, Namespace: "ns"}
+
+	err := c.moveHost("node1.example.com", "zone=us-1a,rack=rack1")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"node1-example-com", "zone=us-1a", "rack=rack1", "root=default"}, moveArgs)
+}
+
+func TestIsStorageNode(t *testing.T) {
+	storage := rookalpha.StorageScopeSpec{Nodes: []rookalpha.Node{{Name: "node1"}}}
+	assert.True(t, IsStorageNode(storage, "node1"))
+	assert.False(t, IsStorageNode(storage, "node2"))
+
+	storage.UseAllNodes = true
+	assert.True(t, IsStorageNode(storage, "node2"))
+}
+
+func TestSetLocationEnvVar(t *testing.T) {
+	d := &apps.Deployment{}
+	d.Spec.Template.Spec.InitContainers = []v1.Container{
+		{Name: opspec.ConfigInitContainerName, Env: []v1.EnvVar{nodeNameEnvVar("node1"), rookalpha.LocationEnvVar("zone=a")}},
+		{Name: "copy-bins"},
+	}
+
+	assert.False(t, setLocationEnvVar(d, "zone=a"))
+	assert.True(t, setLocationEnvVar(d, "zone=b,rack=r1"))
+	assert.Equal(t, "zone=b,rack=r1", rookalpha.GetLocationFromContainer(d.Spec.Template.Spec.InitContainers[0]))
+	assert.Equal(t, 2, len(d.Spec.Template.Spec.InitContainers[0].Env))
+
+	// the location is removed with the last topology label
+	assert.True(t, setLocationEnvVar(d, ""))
+	assert.Equal(t, []v1.EnvVar{nodeNameEnvVar("node1")}, d.Spec.Template.Spec.InitContainers[0].Env)
+	assert.Nil(t, d.Spec.Template.Spec.InitContainers[1].Env)
+}
+
+func TestUpdateNodeLocationSkipped(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName, command, outputFile string, args ...string) (string, error) {
+			assert.Fail(t, "unexpected ceph command")
+			return "", nil
+		},
+	}
+	c := &Cluster{context: &clusterd.Context{Clientset: clientset, Executor: executor}, Namespace: "ns"}
+	c.Storage.Nodes = []rookalpha.Node{{Name: "node1"}}
+
+	// the topology is not enabled
+	assert.Nil(t, c.UpdateNodeLocation("node1"))
+
+	// not a storage node
+	c.CrushTopology.Enabled = true
+	assert.Nil(t, c.UpdateNodeLocation("node2"))
+
+	// no osd on the node
+	assert.Nil(t, c.UpdateNodeLocation("node1"))
+}
@@ -27,7 +27,7 @@ import (
 
 const (
 	keyringTemplate = `
-[client.rbd-mirror.%s]
+[%s]
 	key = %s
 	caps mon = "profile rbd-mirror"
 	caps osd = "profile rbd"
@@ -38,18 +38,23 @@ const (
 type daemonConfig struct {
 	ResourceName string              // the name rook gives to mirror resources in k8s metadata
 	DaemonID     string              // the ID of the Ceph daemon ("a", "b", ...)
+	User         string              // the Ceph user of the daemon, a generation of its user after a key rotation
 	DataPathMap  *config.DataPathMap // location to store data in container
 }
 
 func (m *Mirroring) generateKeyring(daemonConfig *daemonConfig) error {
-	user := fullDaemonName(daemonConfig.DaemonID)
 	access := []string{"mon", "profile rbd-mirror", "osd", "profile rbd"}
 	s := keyring.GetSecretStore(m.context, m.Namespace, &m.ownerRef)
 
+	user, err := s.StoredUser(daemonConfig.ResourceName, fullDaemonName(daemonConfig.DaemonID))
+	if err != nil {
+		return err
+	}
 	key, err := s.GenerateKey(daemonConfig.ResourceName, user, access)
 	if err != nil {
 		return err
 	}
+	daemonConfig.User = user
 
 	// Delete legacy key store for upgrade from Rook v0.9.x to v1.0.x
 	err = m.context.Clientset.CoreV1().Secrets(m.Namespace).Delete(daemonConfig.ResourceName, &metav1.DeleteOptions{})
@@ -61,7 +66,7 @@ func (m *Mirroring) generateKeyring(daemonConfig *daemonConfig) error {
 		}
 	}
 
-	keyring := fmt.Sprintf(keyringTemplate, daemonConfig.DaemonID, key)
+	keyring := fmt.Sprintf(keyringTemplate, user, key)
 	return s.CreateOrUpdate(daemonConfig.ResourceName, keyring)
 }
 
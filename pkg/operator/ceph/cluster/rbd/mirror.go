@@ -50,6 +50,10 @@ type Mirroring struct {
 	cephVersion cephv1.CephVersionSpec
 	rookVersion string
 	hostNetwork bool
+	// Override is the image, priority class, labels and annotations of the rbd mirror pods
+	Override cephv1.DaemonOverride
+	// DataDirHostPath is the host path under which the crash reports of the rbd mirrors are kept
+	DataDirHostPath string
 }
 
 // New creates an instance of the rbd mirroring
@@ -37,14 +37,18 @@ func (m *Mirroring) makeDeployment(daemonConfig *daemonConfig) *apps.Deployment
 				m.makeMirroringDaemonContainer(daemonConfig),
 			},
 			RestartPolicy: v1.RestartPolicyAlways,
-			Volumes:       opspec.DaemonVolumes(daemonConfig.DataPathMap, daemonConfig.ResourceName),
-			HostNetwork:   m.hostNetwork,
+			Volumes: append(
+				opspec.DaemonVolumes(daemonConfig.DataPathMap, daemonConfig.ResourceName),
+				opspec.CrashVolume(m.DataDirHostPath),
+			),
+			HostNetwork: m.hostNetwork,
 		},
 	}
 	if m.hostNetwork {
 		podSpec.Spec.DNSPolicy = v1.DNSClusterFirstWithHostNet
 	}
 	m.placement.ApplyToPodSpec(&podSpec.Spec)
+	m.Override.ApplyToPod(&podSpec.ObjectMeta, &podSpec.Spec, m.cephVersion.Image)
 
 	replicas := int32(1)
 	d := &apps.Deployment{
@@ -55,7 +59,7 @@ func (m *Mirroring) makeDeployment(daemonConfig *daemonConfig) *apps.Deployment
 		},
 		Spec: apps.DeploymentSpec{
 			Selector: &metav1.LabelSelector{
-				MatchLabels: podSpec.Labels,
+				MatchLabels: opspec.PodLabels(appName, m.Namespace, string(config.RbdMirrorType), daemonConfig.DaemonID),
 			},
 			Template: podSpec,
 			Replicas: &replicas,
@@ -74,12 +78,15 @@ func (m *Mirroring) makeMirroringDaemonContainer(daemonConfig *daemonConfig) v1.
 		Args: append(
 			opspec.DaemonFlags(m.ClusterInfo, daemonConfig.DaemonID),
 			"--foreground",
-			"--name="+fullDaemonName(daemonConfig.DaemonID),
+			"--name="+daemonConfig.User,
 		),
-		Image:        m.cephVersion.Image,
-		VolumeMounts: opspec.DaemonVolumeMounts(daemonConfig.DataPathMap, daemonConfig.ResourceName),
-		Env:          opspec.DaemonEnvVars(m.cephVersion.Image),
-		Resources:    m.resources,
+		Image: m.cephVersion.Image,
+		VolumeMounts: append(
+			opspec.DaemonVolumeMounts(daemonConfig.DataPathMap, daemonConfig.ResourceName),
+			opspec.CrashVolumeMount(),
+		),
+		Env:       opspec.DaemonEnvVars(m.cephVersion.Image),
+		Resources: m.resources,
 	}
 	return container
 }
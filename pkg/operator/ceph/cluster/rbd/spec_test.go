@@ -24,6 +24,7 @@ import (
 	"github.com/rook/rook/pkg/clusterd"
 	cephconfig "github.com/rook/rook/pkg/daemon/ceph/config"
 	"github.com/rook/rook/pkg/operator/ceph/config"
+	opspec "github.com/rook/rook/pkg/operator/ceph/spec"
 	cephtest "github.com/rook/rook/pkg/operator/ceph/test"
 	optest "github.com/rook/rook/pkg/operator/test"
 	"github.com/stretchr/testify/assert"
@@ -54,14 +55,21 @@ func TestPodSpec(t *testing.T) {
 		},
 		metav1.OwnerReference{},
 	)
+	c.DataDirHostPath = "/var/lib/rook"
 	daemonConf := daemonConfig{
 		DaemonID:     "a",
+		User:         "client.rbd-mirror.a-2",
 		ResourceName: "rook-ceph-rbd-mirror-a",
 		DataPathMap:  config.NewDatalessDaemonDataPathMap(),
 	}
 
 	d := c.makeDeployment(&daemonConf)
 	assert.Equal(t, "rook-ceph-rbd-mirror-a", d.Name)
+	assert.Contains(t, d.Spec.Template.Spec.Containers[0].Args, "--name=client.rbd-mirror.a-2")
+
+	// the crash reports are written under the data dir of the host
+	assert.Contains(t, d.Spec.Template.Spec.Volumes, opspec.CrashVolume("/var/lib/rook"))
+	assert.Contains(t, d.Spec.Template.Spec.Containers[0].VolumeMounts, opspec.CrashVolumeMount())
 
 	// Deployment should have Ceph labels
 	cephtest.AssertLabelsContainCephRequirements(t, d.ObjectMeta.Labels,
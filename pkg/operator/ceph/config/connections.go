@@ -0,0 +1,121 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	cephver "github.com/rook/rook/pkg/operator/ceph/version"
+)
+
+const (
+	// ConnectionModeCRC checks the integrity of the frames of the msgr2 connections
+	ConnectionModeCRC = "crc"
+	// ConnectionModeSecure encrypts the frames of the msgr2 connections
+	ConnectionModeSecure = "secure"
+
+	bindMsgr1Option = "ms bind msgr1"
+)
+
+type connectionMode struct {
+	option string
+	mode   string
+}
+
+// ValidateConnections returns an error if a connection mode is not a list of "crc" and "secure", or if a mode or msgr2
+// is required for a version of Ceph without the msgr2 protocol.
+func ValidateConnections(spec cephv1.ConnectionsSpec, cephVersion cephver.CephVersion) error {
+	modes := connectionModes(spec)
+	if len(modes) == 0 && !spec.RequireMsgr2 {
+		return nil
+	}
+	if !cephVersion.IsAtLeastNautilus() {
+		return fmt.Errorf("the connection modes require the msgr2 protocol of Nautilus or newer, not ceph version %s", cephVersion.String())
+	}
+	for _, m := range modes {
+		if err := validateConnectionMode(m.mode); err != nil {
+			return fmt.Errorf("invalid %s. %+v", m.option, err)
+		}
+	}
+	return nil
+}
+
+// ConnectionConfigs returns the config options of the connection modes set in the spec
+func ConnectionConfigs(spec cephv1.ConnectionsSpec) *Config {
+	c := NewConfig()
+	s := c.Section("global")
+	for _, m := range connectionModes(spec) {
+		s.Set(m.option, strings.Join(strings.Fields(m.mode), " "))
+	}
+	if spec.RequireMsgr2 {
+		s.Set(bindMsgr1Option, "false")
+	}
+	return c
+}
+
+// ConnectionsHash returns a hash of the connection options set in the spec, or "" if none is set. The daemons are
+// restarted when the hash of the stored config changes since they only read the options when they start.
+func ConnectionsHash(spec cephv1.ConnectionsSpec) string {
+	options := []string{}
+	for _, m := range connectionModes(spec) {
+		options = append(options, fmt.Sprintf("%s=%s", m.option, strings.Join(strings.Fields(m.mode), " ")))
+	}
+	if spec.RequireMsgr2 {
+		options = append(options, bindMsgr1Option+"=false")
+	}
+	if len(options) == 0 {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(strings.Join(options, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+// connectionModes returns the modes set in the spec by config option, in a stable order so the stored config does not
+// change between orchestrations
+func connectionModes(spec cephv1.ConnectionsSpec) []connectionMode {
+	modes := []connectionMode{}
+	for _, m := range []connectionMode{
+		{option: "ms cluster mode", mode: spec.ClusterMode},
+		{option: "ms service mode", mode: spec.ServiceMode},
+		{option: "ms client mode", mode: spec.ClientMode},
+	} {
+		if m.mode != "" {
+			modes = append(modes, m)
+		}
+	}
+	return modes
+}
+
+func validateConnectionMode(mode string) error {
+	fields := strings.Fields(mode)
+	if len(fields) == 0 || len(fields) > 2 {
+		return fmt.Errorf("mode %q must be %q, %q or both in the order of preference", mode, ConnectionModeCRC, ConnectionModeSecure)
+	}
+	for i, field := range fields {
+		if field != ConnectionModeCRC && field != ConnectionModeSecure {
+			return fmt.Errorf("unknown connection mode %q in %q", field, mode)
+		}
+		if i > 0 && field == fields[0] {
+			return fmt.Errorf("connection mode %q is repeated in %q", field, mode)
+		}
+	}
+	return nil
+}
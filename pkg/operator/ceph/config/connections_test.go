@@ -0,0 +1,108 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"testing"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	"github.com/rook/rook/pkg/clusterd"
+	cephver "github.com/rook/rook/pkg/operator/ceph/version"
+	testop "github.com/rook/rook/pkg/operator/test"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestValidateConnections(t *testing.T) {
+	// no mode is set by default for any version
+	assert.Nil(t, ValidateConnections(cephv1.ConnectionsSpec{}, cephver.Mimic))
+	assert.Nil(t, ValidateConnections(cephv1.ConnectionsSpec{}, cephver.Nautilus))
+
+	spec := cephv1.ConnectionsSpec{ClusterMode: "secure", ServiceMode: "secure crc", ClientMode: " crc  secure"}
+	assert.Nil(t, ValidateConnections(spec, cephver.Nautilus))
+	// the modes require msgr2
+	assert.NotNil(t, ValidateConnections(spec, cephver.Mimic))
+	assert.NotNil(t, ValidateConnections(cephv1.ConnectionsSpec{RequireMsgr2: true}, cephver.Mimic))
+	assert.Nil(t, ValidateConnections(cephv1.ConnectionsSpec{RequireMsgr2: true}, cephver.Nautilus))
+
+	for _, mode := range []string{" ", "tls", "crc,secure", "secure secure", "crc secure crc"} {
+		assert.NotNil(t, ValidateConnections(cephv1.ConnectionsSpec{ServiceMode: mode}, cephver.Nautilus), mode)
+	}
+}
+
+func TestStoreConnections(t *testing.T) {
+	clientset := testop.New(1)
+	s := GetStore(&clusterd.Context{Clientset: clientset}, "rook-ceph", &metav1.OwnerReference{})
+	spec := cephv1.ConnectionsSpec{ClusterMode: "secure", ClientMode: "secure  crc"}
+
+	// the modes are not stored for the versions without msgr2
+	info := testop.CreateConfigDir(3)
+	info.CephVersion = cephver.Mimic
+	assert.Nil(t, s.CreateOrUpdate(info, spec))
+	cm, err := clientset.CoreV1().ConfigMaps("rook-ceph").Get(storeName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.NotContains(t, cm.Data[confFileName], "ms_cluster_mode")
+
+	info.CephVersion = cephver.Nautilus
+	assert.Nil(t, s.CreateOrUpdate(info, spec))
+	cm, err = clientset.CoreV1().ConfigMaps("rook-ceph").Get(storeName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Contains(t, cm.Data[confFileName], "ms_cluster_mode")
+	assert.Contains(t, cm.Data[confFileName], "= secure\n")
+	assert.Contains(t, cm.Data[confFileName], "= secure crc\n")
+	assert.NotContains(t, cm.Data[confFileName], "ms_service_mode")
+	assert.NotContains(t, cm.Data[confFileName], "ms_bind_msgr1")
+
+	// the daemons started with the first stored config are restarted with the new modes
+	hash, pending, err := s.PendingConnections()
+	assert.Nil(t, err)
+	assert.True(t, pending)
+	assert.Equal(t, ConnectionsHash(spec), hash)
+	assert.Nil(t, s.SetConnectionsApplied(hash))
+	_, pending, err = s.PendingConnections()
+	assert.Nil(t, err)
+	assert.False(t, pending)
+
+	// the daemons are not restarted when the config is stored again with the same modes
+	assert.Nil(t, s.CreateOrUpdate(info, spec))
+	_, pending, err = s.PendingConnections()
+	assert.Nil(t, err)
+	assert.False(t, pending)
+
+	spec.RequireMsgr2 = true
+	assert.Nil(t, s.CreateOrUpdate(info, spec))
+	cm, err = clientset.CoreV1().ConfigMaps("rook-ceph").Get(storeName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Contains(t, cm.Data[confFileName], "ms_bind_msgr1")
+	_, pending, err = s.PendingConnections()
+	assert.Nil(t, err)
+	assert.True(t, pending)
+}
+
+func TestConnectionsHash(t *testing.T) {
+	assert.Equal(t, "", ConnectionsHash(cephv1.ConnectionsSpec{}))
+
+	spec := cephv1.ConnectionsSpec{ClusterMode: "secure crc"}
+	hash := ConnectionsHash(spec)
+	assert.NotEqual(t, "", hash)
+	// the spaces between the modes do not change the options
+	assert.Equal(t, hash, ConnectionsHash(cephv1.ConnectionsSpec{ClusterMode: " secure  crc"}))
+	assert.NotEqual(t, hash, ConnectionsHash(cephv1.ConnectionsSpec{ClusterMode: "crc secure"}))
+	assert.NotEqual(t, hash, ConnectionsHash(cephv1.ConnectionsSpec{ServiceMode: "secure crc"}))
+	spec.RequireMsgr2 = true
+	assert.NotEqual(t, hash, ConnectionsHash(spec))
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keyring
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// FlexUser is the user the flex driver maps and mounts the volumes with when they have no mount secret
+	FlexUser = "client.rook-flex"
+
+	flexKeyringResourceName = "rook-ceph-flex"
+
+	flexKeyringTemplate = `
+[client.rook-flex]
+	key = %s
+	caps mds = "allow rw"
+	caps mon = "profile rbd"
+	caps osd = "allow rwx"
+`
+)
+
+// the flex driver maps the rbd images and mounts the filesystems of the cluster
+var flexAccess = []string{"mds", "allow rw", "mon", "profile rbd", "osd", "allow rwx"}
+
+// A FlexStore is a specialized derivative of the SecretStore helper for storing the keyring of the user the flex driver
+// maps and mounts the volumes with.
+type FlexStore struct {
+	secretStore *SecretStore
+}
+
+// Flex returns the special Flex keyring store type.
+func (s *SecretStore) Flex() *FlexStore {
+	return &FlexStore{secretStore: s}
+}
+
+// CreateOrUpdate creates the flex user if it does not exist and stores its keyring. The key of the flex user is not
+// rotated since the kernel clients of the mapped volumes keep their key until the volumes are unmapped.
+func (f *FlexStore) CreateOrUpdate() error {
+	key, err := f.secretStore.GenerateKey(flexKeyringResourceName, FlexUser, flexAccess)
+	if err != nil {
+		return err
+	}
+	return f.secretStore.CreateOrUpdate(flexKeyringResourceName, fmt.Sprintf(flexKeyringTemplate, key))
+}
+
+// Get returns the ID and the key of the flex user. The error is a NotFound error if the operator did not create the
+// flex user yet.
+func (f *FlexStore) Get() (string, string, error) {
+	keyring, err := f.secretStore.Get(flexKeyringResourceName)
+	if err != nil {
+		return "", "", err
+	}
+	key := Key(keyring)
+	if key == "" {
+		return "", "", fmt.Errorf("no key in the keyring of %s", FlexUser)
+	}
+	return strings.TrimPrefix(User(keyring), "client."), key, nil
+}
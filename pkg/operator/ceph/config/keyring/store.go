@@ -21,6 +21,8 @@ package keyring
 
 import (
 	"fmt"
+	"regexp"
+	"strings"
 
 	"github.com/coreos/pkg/capnslog"
 	"github.com/rook/rook/pkg/clusterd"
@@ -33,9 +35,15 @@ import (
 
 var logger = capnslog.NewPackageLogger("github.com/rook/rook", "op-cfg-keyring")
 
+var (
+	userRegex = regexp.MustCompile(`(?m)^([ \t]*\[)(.+)(\][ \t]*)$`)
+	keyRegex  = regexp.MustCompile(`(?m)^([ \t]*key[ \t]*=[ \t]*)(\S+)[ \t]*$`)
+)
+
 const (
 	keyKeyName      = "key"
 	keyringFileName = "keyring"
+	keyringSuffix   = "-keyring"
 )
 
 // SecretStore is a helper to store Ceph daemon keyrings as Kubernetes secrets.
@@ -59,7 +67,17 @@ func keySecretName(resourceName string) string {
 }
 
 func keyringSecretName(resourceName string) string {
-	return resourceName + "-keyring" // all keyrings named by suffixing keyring to the resource name
+	return resourceName + keyringSuffix // all keyrings named by suffixing keyring to the resource name
+}
+
+// StoredKeyring is a keyring stored in a secret by the SecretStore.
+type StoredKeyring struct {
+	// ResourceName is the name of the resource the keyring was stored for
+	ResourceName string
+	// SecretName is the name of the secret holding the keyring
+	SecretName string
+	// Keyring is the content of the keyring
+	Keyring string
 }
 
 // GenerateKey generates a key for a Ceph user with the given access permissions. It returns the key
@@ -74,6 +92,33 @@ func (k *SecretStore) GenerateKey(resourceName, user string, access []string) (s
 	return key, nil
 }
 
+// StoredUser returns the user of the keyring stored for the resource. After a key rotation, the user of the keyring
+// is a new generation of the given user, which the daemons of the resource must run with. The given user is returned
+// if no keyring is stored for the resource, or if the stored keyring is for another user.
+func (k *SecretStore) StoredUser(resourceName, user string) (string, error) {
+	keyring, err := k.Get(resourceName)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return user, nil
+		}
+		return "", err
+	}
+	stored := User(keyring)
+	if base, _ := client.UserGeneration(stored); base != user {
+		return user, nil
+	}
+	return stored, nil
+}
+
+// Get returns the keyring stored for the resource. The error is a NotFound error if no keyring is stored.
+func (k *SecretStore) Get(resourceName string) (string, error) {
+	secret, err := k.context.Clientset.CoreV1().Secrets(k.namespace).Get(keyringSecretName(resourceName), metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	return secretKeyring(secret), nil
+}
+
 // CreateOrUpdate creates or updates the keyring secret for the resource with the keyring specified.
 // WARNING: Do not use "rook-ceph-admin" as the resource name; conflicts with the AdminStore.
 func (k *SecretStore) CreateOrUpdate(resourceName, keyring string) error {
@@ -103,6 +148,74 @@ func (k *SecretStore) Delete(resourceName string) error {
 	return nil
 }
 
+// List returns the keyrings stored for the resources of the namespace. The admin keyring is not listed.
+func (k *SecretStore) List() ([]StoredKeyring, error) {
+	secrets, err := k.context.Clientset.CoreV1().Secrets(k.namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list secrets. %+v", err)
+	}
+
+	keyrings := []StoredKeyring{}
+	for _, secret := range secrets.Items {
+		if secret.Type != k8sutil.RookType || !strings.HasSuffix(secret.Name, keyringSuffix) ||
+			secret.Name == keyringSecretName(adminKeyringResourceName) {
+			continue
+		}
+		keyring := secretKeyring(&secret)
+		if keyring == "" {
+			continue
+		}
+		keyrings = append(keyrings, StoredKeyring{
+			ResourceName: strings.TrimSuffix(secret.Name, keyringSuffix),
+			SecretName:   secret.Name,
+			Keyring:      keyring,
+		})
+	}
+	return keyrings, nil
+}
+
+// User returns the user of a keyring
+func User(keyring string) string {
+	match := userRegex.FindStringSubmatch(keyring)
+	if match == nil {
+		return ""
+	}
+	return match[2]
+}
+
+// Key returns the key of a keyring
+func Key(keyring string) string {
+	match := keyRegex.FindStringSubmatch(keyring)
+	if match == nil {
+		return ""
+	}
+	return match[2]
+}
+
+// WithUser returns the keyring with the given user instead of its user
+func WithUser(keyring, user string) string {
+	return userRegex.ReplaceAllStringFunc(keyring, func(line string) string {
+		match := userRegex.FindStringSubmatch(line)
+		return match[1] + user + match[3]
+	})
+}
+
+// WithKey returns the keyring with the given key instead of its key
+func WithKey(keyring, key string) string {
+	return keyRegex.ReplaceAllStringFunc(keyring, func(line string) string {
+		return keyRegex.FindStringSubmatch(line)[1] + key
+	})
+}
+
+func secretKeyring(secret *v1.Secret) string {
+	keyring := string(secret.Data[keyringFileName])
+	if keyring == "" {
+		// the api server moves the string data to the data of the secret, which the fake clientset does not
+		keyring = secret.StringData[keyringFileName]
+	}
+	return keyring
+}
+
 func (k *SecretStore) createSecret(secret *v1.Secret) error {
 	secretName := secret.ObjectMeta.Name
 	_, err := k.context.Clientset.CoreV1().Secrets(k.namespace).Get(secretName, metav1.GetOptions{})
@@ -26,6 +26,7 @@ import (
 	testop "github.com/rook/rook/pkg/operator/test"
 	exectest "github.com/rook/rook/pkg/util/exec/test"
 	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -111,6 +112,94 @@ func TestKeyringStore(t *testing.T) {
 	assertKeyringData("second-resource-keyring", "lkjhgfdsa")
 }
 
+func TestListKeyrings(t *testing.T) {
+	clientset := testop.New(1)
+	ctx := &clusterd.Context{
+		Clientset: clientset,
+	}
+	ns := "rook-ceph"
+	owner := metav1.OwnerReference{}
+	k := GetSecretStore(ctx, ns, &owner)
+
+	keyrings, err := k.List()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(keyrings))
+
+	k.CreateOrUpdate("rook-ceph-mgr-a", "[mgr.a]")
+	k.Admin().CreateOrUpdate(testop.CreateConfigDir(1))
+	// secrets not created by the store are not listed
+	_, err = clientset.CoreV1().Secrets(ns).Create(&v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "other-keyring", Namespace: ns}})
+	assert.NoError(t, err)
+
+	keyrings, err = k.List()
+	assert.NoError(t, err)
+	assert.Equal(t, []StoredKeyring{{ResourceName: "rook-ceph-mgr-a", SecretName: "rook-ceph-mgr-a-keyring", Keyring: "[mgr.a]"}}, keyrings)
+}
+
+func TestStoredUser(t *testing.T) {
+	ctx := &clusterd.Context{
+		Clientset: testop.New(1),
+	}
+	owner := metav1.OwnerReference{}
+	k := GetSecretStore(ctx, "rook-ceph", &owner)
+
+	// the user is returned until a keyring is stored
+	user, err := k.StoredUser("rook-ceph-crashcollector", "client.crash")
+	assert.NoError(t, err)
+	assert.Equal(t, "client.crash", user)
+
+	k.CreateOrUpdate("rook-ceph-crashcollector", "[client.crash]\n\tkey = abc\n")
+	user, err = k.StoredUser("rook-ceph-crashcollector", "client.crash")
+	assert.NoError(t, err)
+	assert.Equal(t, "client.crash", user)
+
+	// the generation of the user created by a key rotation is returned
+	k.CreateOrUpdate("rook-ceph-crashcollector", "[client.crash-3]\n\tkey = abc\n")
+	user, err = k.StoredUser("rook-ceph-crashcollector", "client.crash")
+	assert.NoError(t, err)
+	assert.Equal(t, "client.crash-3", user)
+
+	// the keyring of another user is ignored
+	user, err = k.StoredUser("rook-ceph-crashcollector", "client.radosgw.gateway")
+	assert.NoError(t, err)
+	assert.Equal(t, "client.radosgw.gateway", user)
+}
+
+func TestKeyringContent(t *testing.T) {
+	keyring := "\n[mgr.a]\n\tkey = oldkey==\n\tcaps mon = \"allow *\"\n"
+	assert.Equal(t, "mgr.a", User(keyring))
+	assert.Equal(t, "oldkey==", Key(keyring))
+	assert.Equal(t, "\n[mgr.a]\n\tkey = newkey==\n\tcaps mon = \"allow *\"\n", WithKey(keyring, "newkey=="))
+	assert.Equal(t, "\n[mgr.b]\n\tkey = oldkey==\n\tcaps mon = \"allow *\"\n", WithUser(keyring, "mgr.b"))
+	assert.Equal(t, "", User("key = abc"))
+}
+
+func TestFlexKeyringStore(t *testing.T) {
+	clientset := testop.New(1)
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName string, command string, outFileArg string, args ...string) (string, error) {
+			assert.Equal(t, FlexUser, args[2])
+			return "{\"key\": \"flexkey\"}", nil
+		},
+	}
+	ctx := &clusterd.Context{
+		Clientset: clientset,
+		Executor:  executor,
+	}
+	owner := metav1.OwnerReference{}
+	k := GetSecretStore(ctx, "rook-ceph", &owner)
+
+	// the flex driver maps the volumes with the admin until the flex user is created
+	_, _, err := k.Flex().Get()
+	assert.True(t, errors.IsNotFound(err))
+
+	assert.NoError(t, k.Flex().CreateOrUpdate())
+	id, key, err := k.Flex().Get()
+	assert.NoError(t, err)
+	assert.Equal(t, "rook-flex", id)
+	assert.Equal(t, "flexkey", key)
+}
+
 func TestResourceVolumeAndMount(t *testing.T) {
 	clientset := testop.New(1)
 	ctx := &clusterd.Context{
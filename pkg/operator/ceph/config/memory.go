@@ -0,0 +1,75 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"strconv"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// MemoryHeadroomRatio is the fraction of the memory of a daemon container which is kept out of the memory target of
+// the daemon. The daemons only bound their caches to the target, so the headroom absorbs the rest of their allocations
+// and keeps them from being OOM-killed.
+var MemoryHeadroomRatio = 0.2
+
+// MemoryLimit returns the memory limit of a container, or its memory request if no limit is set. Zero is returned if
+// neither is set.
+func MemoryLimit(resources v1.ResourceRequirements) int64 {
+	memory := resources.Limits.Memory()
+	if memory.IsZero() {
+		memory = resources.Requests.Memory()
+	}
+	return memory.Value()
+}
+
+// MemoryTarget returns the memory a daemon should target to stay within the memory of its container. Zero is returned
+// if the container has no memory set and the Ceph default applies.
+func MemoryTarget(resources v1.ResourceRequirements) int64 {
+	ratio := MemoryHeadroomRatio
+	if ratio < 0 || ratio >= 1 {
+		logger.Warningf("ignoring invalid memory headroom ratio %f", ratio)
+		ratio = 0
+	}
+	return int64(float64(MemoryLimit(resources)) * (1 - ratio))
+}
+
+// OsdMemoryFlags returns the flags sizing the memory of a bluestore osd from the memory of its container. The
+// bluestore caches are autotuned to keep the osd within its memory target.
+func OsdMemoryFlags(resources v1.ResourceRequirements) []string {
+	target := MemoryTarget(resources)
+	if target == 0 {
+		return []string{}
+	}
+	return []string{
+		NewFlag("osd-memory-target", strconv.FormatInt(target, 10)),
+		NewFlag("bluestore-cache-autotune", "true"),
+	}
+}
+
+// MonMemoryFlags returns the flags sizing the memory of a mon from the memory of its container. The mon memory target
+// is new in Nautilus and must not be set for older versions.
+func MonMemoryFlags(resources v1.ResourceRequirements) []string {
+	target := MemoryTarget(resources)
+	if target == 0 {
+		return []string{}
+	}
+	return []string{
+		NewFlag("mon-memory-target", strconv.FormatInt(target, 10)),
+		NewFlag("mon-memory-autotune", "true"),
+	}
+}
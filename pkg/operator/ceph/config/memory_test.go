@@ -0,0 +1,53 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestMemoryTarget(t *testing.T) {
+	defer func(ratio float64) { MemoryHeadroomRatio = ratio }(MemoryHeadroomRatio)
+	MemoryHeadroomRatio = 0.25
+
+	// no memory set, the ceph default applies
+	assert.Equal(t, int64(0), MemoryTarget(v1.ResourceRequirements{}))
+	assert.Equal(t, []string{}, OsdMemoryFlags(v1.ResourceRequirements{}))
+	assert.Equal(t, []string{}, MonMemoryFlags(v1.ResourceRequirements{}))
+
+	// derived from the request if there is no limit
+	resources := v1.ResourceRequirements{
+		Requests: v1.ResourceList{v1.ResourceMemory: *resource.NewQuantity(2147483648, resource.BinarySI)},
+	}
+	assert.Equal(t, int64(2147483648), MemoryLimit(resources))
+	assert.Equal(t, int64(1610612736), MemoryTarget(resources))
+
+	// the limit takes precedence
+	resources.Limits = v1.ResourceList{v1.ResourceMemory: *resource.NewQuantity(4294967296, resource.BinarySI)}
+	assert.Equal(t, int64(4294967296), MemoryLimit(resources))
+	assert.Equal(t, int64(3221225472), MemoryTarget(resources))
+	assert.Equal(t, []string{"--osd-memory-target=3221225472", "--bluestore-cache-autotune=true"}, OsdMemoryFlags(resources))
+	assert.Equal(t, []string{"--mon-memory-target=3221225472", "--mon-memory-autotune=true"}, MonMemoryFlags(resources))
+
+	// an invalid ratio leaves no headroom
+	MemoryHeadroomRatio = 1
+	assert.Equal(t, int64(4294967296), MemoryTarget(resources))
+}
@@ -26,6 +26,7 @@ import (
 	"strings"
 
 	"github.com/go-ini/ini"
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
 	"github.com/rook/rook/pkg/clusterd"
 	cephconfig "github.com/rook/rook/pkg/daemon/ceph/config"
 	cephutil "github.com/rook/rook/pkg/daemon/ceph/util"
@@ -43,6 +44,9 @@ const (
 	confFileName         = "ceph.conf"
 	monHostKey           = "mon_host"
 	monInitialMembersKey = "mon_initial_members"
+	// the hash of the connection modes of the stored config, and of the modes the daemons were last restarted with
+	connectionsHashKey        = "connections_hash"
+	appliedConnectionsHashKey = "applied_connections_hash"
 	// Msgr2port is the listening port of the messenger v2 protocol
 	Msgr2port = 3300
 )
@@ -67,15 +71,23 @@ func GetStore(context *clusterd.Context, namespace string, ownerRef *metav1.Owne
 	}
 }
 
-// CreateOrUpdate creates or updates the stored Ceph config based on the cluster info.
-func (s *Store) CreateOrUpdate(clusterInfo *cephconfig.ClusterInfo) error {
+// CreateOrUpdate creates or updates the stored Ceph config based on the cluster info and the
+// connection modes of the cluster CRD.
+func (s *Store) CreateOrUpdate(clusterInfo *cephconfig.ClusterInfo, connections cephv1.ConnectionsSpec) error {
 	c := DefaultCentralizedConfigs()
 
 	// DefaultLegacyConfigs need to be added to the Ceph config file until the integration tests can be
 	// made to override these options for the Ceph clusters it creates.
 	c.Merge(DefaultLegacyConfigs())
 
-	/* TODO: config overrides from the CRD will go here */
+	// the connection modes only apply to the msgr2 protocol of Nautilus
+	connectionsHash := ""
+	if clusterInfo.CephVersion.IsAtLeastNautilus() {
+		c.Merge(ConnectionConfigs(connections))
+		connectionsHash = ConnectionsHash(connections)
+	}
+
+	/* TODO: other config overrides from the CRD will go here */
 
 	f, err := c.IniFile()
 	if err != nil {
@@ -96,11 +108,23 @@ func (s *Store) CreateOrUpdate(clusterInfo *cephconfig.ClusterInfo) error {
 	}
 	txt := b.String()
 
+	// no daemon runs before the config is first stored, so the daemons start with the current connection modes
+	_, err = s.configMapStore.GetValue(storeName, confFileName)
+	firstStore := errors.IsNotFound(err)
+
 	// Store the config in a configmap
 	if err := s.configMapStore.SetValue(storeName, confFileName, txt); err != nil {
 		return fmt.Errorf("failed to store the Ceph config file. failed to store config to configmap. %+v", err)
 	}
 	logger.Debugf("Generated and stored config file:\n%s", txt)
+	if err := s.configMapStore.SetValue(storeName, connectionsHashKey, connectionsHash); err != nil {
+		return fmt.Errorf("failed to store the hash of the connection modes. %+v", err)
+	}
+	if firstStore {
+		if err := s.SetConnectionsApplied(connectionsHash); err != nil {
+			return err
+		}
+	}
 
 	// these are used for all ceph daemons on the commandline and must *always* be stored
 	if err := s.createOrUpdateMonHostSecrets(clusterInfo); err != nil {
@@ -119,6 +143,24 @@ func (s *Store) CreateOrUpdate(clusterInfo *cephconfig.ClusterInfo) error {
 	return nil
 }
 
+// PendingConnections returns the hash of the connection modes of the stored config, and whether the daemons must be
+// restarted to connect with them since they were last restarted with other modes
+func (s *Store) PendingConnections() (string, bool, error) {
+	data, err := s.configMapStore.GetStore(storeName)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to get the stored config. %+v", err)
+	}
+	return data[connectionsHashKey], data[connectionsHashKey] != data[appliedConnectionsHashKey], nil
+}
+
+// SetConnectionsApplied records that the daemons were restarted with the connection modes of the hash
+func (s *Store) SetConnectionsApplied(hash string) error {
+	if err := s.configMapStore.SetValue(storeName, appliedConnectionsHashKey, hash); err != nil {
+		return fmt.Errorf("failed to store the hash of the applied connection modes. %+v", err)
+	}
+	return nil
+}
+
 func (s *Store) applyLegacyOverrides(toFile *ini.File) error {
 	ovrTxt := []byte(s.overrideConfig())
 	if err := toFile.Append(ovrTxt); err != nil {
@@ -201,6 +243,7 @@ func (s *Store) createOrUpdateMonHostSecrets(clusterInfo *cephconfig.ClusterInfo
 			if _, err := clientset.CoreV1().Secrets(s.namespace).Create(secret); err != nil {
 				return fmt.Errorf("failed to create config secret %+v. %+v", secret, err)
 			}
+			return nil
 		}
 		return fmt.Errorf("failed to get config secret %s. %+v", storeName, err)
 	}
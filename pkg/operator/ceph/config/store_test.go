@@ -24,6 +24,7 @@ import (
 	"strings"
 	"testing"
 
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
 	"github.com/rook/rook/pkg/clusterd"
 	cephconfig "github.com/rook/rook/pkg/daemon/ceph/config"
 	"github.com/rook/rook/pkg/operator/k8sutil"
@@ -83,10 +84,10 @@ func TestStore(t *testing.T) {
 	i1 := testop.CreateConfigDir(1) // cluster w/ one mon
 	i3 := testop.CreateConfigDir(3) // same cluster w/ 3 mons
 
-	s.CreateOrUpdate(i1)
+	s.CreateOrUpdate(i1, cephv1.ConnectionsSpec{})
 	assertConfigStore(i1)
 
-	s.CreateOrUpdate(i3)
+	s.CreateOrUpdate(i3, cephv1.ConnectionsSpec{})
 	assertConfigStore(i3)
 	// the config should be the same regardless of how many mons there are
 	assert.Equal(t, previousConfigText, recentConfigText)
@@ -95,19 +96,19 @@ func TestStore(t *testing.T) {
 	// test overrides
 	//
 	createOverrideMap(t, ctx, ns, &owner)
-	s.CreateOrUpdate(i3)
+	s.CreateOrUpdate(i3, cephv1.ConnectionsSpec{})
 	assertConfigStore(i3)
 	// configs should still be equal since the created map doesn't have data in it
 	assert.Equal(t, previousConfigText, recentConfigText)
 
 	updateOverrideMap(t, "", ctx, ns, &owner)
-	s.CreateOrUpdate(i3)
+	s.CreateOrUpdate(i3, cephv1.ConnectionsSpec{})
 	assertConfigStore(i3)
 	// configs should still be equal since the created map's data is blank
 	assert.Equal(t, previousConfigText, recentConfigText)
 
 	updateOverrideMap(t, "this is not valid ini file text", ctx, ns, &owner)
-	s.CreateOrUpdate(i3)
+	s.CreateOrUpdate(i3, cephv1.ConnectionsSpec{})
 	assertConfigStore(i3)
 	// configs should still be equal since the created map's data is invalid ini
 	assert.Equal(t, previousConfigText, recentConfigText)
@@ -121,7 +122,7 @@ log stderr prefix = '"debug  "'
 [mon]
 debug_mon = makehaste
 `, ctx, ns, &owner)
-	s.CreateOrUpdate(i3)
+	s.CreateOrUpdate(i3, cephv1.ConnectionsSpec{})
 	assertConfigStore(i3)
 	// Verify some simple truths about the overridden config vs the original
 	assert.NotEqual(t, previousConfigText, recentConfigText)                       // the new config has changed (finally)
@@ -181,7 +182,7 @@ func TestFileVolumeAndMount(t *testing.T) {
 	owner := metav1.OwnerReference{}
 
 	s := GetStore(ctx, ns, &owner)
-	s.CreateOrUpdate(testop.CreateConfigDir(3))
+	s.CreateOrUpdate(testop.CreateConfigDir(3), cephv1.ConnectionsSpec{})
 
 	v := StoredFileVolume()
 	m := StoredFileVolumeMount()
@@ -201,7 +202,7 @@ func TestEnvVarsAndFlags(t *testing.T) {
 	owner := metav1.OwnerReference{}
 
 	s := GetStore(ctx, ns, &owner)
-	s.CreateOrUpdate(testop.CreateConfigDir(3))
+	s.CreateOrUpdate(testop.CreateConfigDir(3), cephv1.ConnectionsSpec{})
 
 	v := StoredMonHostEnvVars()
 	f := StoredMonHostEnvVarReferences().GlobalFlags()
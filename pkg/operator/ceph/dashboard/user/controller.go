@@ -0,0 +1,249 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package dashboarduser to manage the users of the ceph dashboard.
+package dashboarduser
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/coreos/pkg/capnslog"
+	opkit "github.com/rook/operator-kit"
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/daemon/ceph/client"
+	cephconfig "github.com/rook/rook/pkg/daemon/ceph/config"
+	"github.com/rook/rook/pkg/operator/ceph/reconcile"
+	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+var logger = capnslog.NewPackageLogger("github.com/rook/rook", "op-dashboard-user")
+
+// DashboardUserResource represents the dashboard user custom resource
+var DashboardUserResource = opkit.CustomResource{
+	Name:    "cephdashboarduser",
+	Plural:  "cephdashboardusers",
+	Group:   cephv1.CustomResourceGroup,
+	Version: cephv1.Version,
+	Scope:   apiextensionsv1beta1.NamespaceScoped,
+	Kind:    reflect.TypeOf(cephv1.CephDashboardUser{}).Name(),
+}
+
+// DashboardUserController represents a controller object for dashboard user custom resources
+type DashboardUserController struct {
+	clusterInfo *cephconfig.ClusterInfo
+	context     *clusterd.Context
+	queue       *reconcile.Queue
+	// the users last applied, kept to rename and delete the users. Only accessed by the worker of the queue.
+	applied map[string]*cephv1.CephDashboardUser
+}
+
+// NewDashboardUserController create controller for watching dashboard user custom resources created
+func NewDashboardUserController(clusterInfo *cephconfig.ClusterInfo, context *clusterd.Context) *DashboardUserController {
+	c := &DashboardUserController{
+		clusterInfo: clusterInfo,
+		context:     context,
+		applied:     map[string]*cephv1.CephDashboardUser{},
+	}
+	c.queue = reconcile.New(DashboardUserResource.Name, c.reconcile)
+	return c
+}
+
+// StartWatch watches for instances of CephDashboardUser custom resources and acts on them
+func (c *DashboardUserController) StartWatch(namespace string, stopCh chan struct{}) error {
+	c.queue.Start(stopCh)
+
+	resourceHandlerFuncs := cache.ResourceEventHandlerFuncs{
+		AddFunc:    c.onAdd,
+		UpdateFunc: c.onUpdate,
+		DeleteFunc: c.onDelete,
+	}
+
+	logger.Infof("start watching dashboard user resources in namespace %s", namespace)
+	watcher := opkit.NewWatcher(DashboardUserResource, namespace, resourceHandlerFuncs, c.context.RookClientset.CephV1().RESTClient())
+	go watcher.Watch(&cephv1.CephDashboardUser{}, stopCh)
+
+	return nil
+}
+
+func (c *DashboardUserController) onAdd(obj interface{}) {
+	user, err := getDashboardUserObject(obj)
+	if err != nil {
+		logger.Errorf("failed to get dashboard user object: %+v", err)
+		return
+	}
+
+	c.queue.Add(user)
+}
+
+func (c *DashboardUserController) onUpdate(oldObj, newObj interface{}) {
+	oldUser, err := getDashboardUserObject(oldObj)
+	if err != nil {
+		logger.Errorf("failed to get old dashboard user object: %+v", err)
+		return
+	}
+	newUser, err := getDashboardUserObject(newObj)
+	if err != nil {
+		logger.Errorf("failed to get new dashboard user object: %+v", err)
+		return
+	}
+
+	// Any change to the resource is reconciled, not only spec changes. This allows a password
+	// change in the secret to be picked up by updating an annotation on the resource.
+	if reflect.DeepEqual(oldUser, newUser) {
+		return
+	}
+
+	c.queue.Add(newUser)
+}
+
+func (c *DashboardUserController) onDelete(obj interface{}) {
+	user, err := getDashboardUserObject(obj)
+	if err != nil {
+		logger.Errorf("failed to get dashboard user object: %+v", err)
+		return
+	}
+
+	c.queue.Remove(user)
+}
+
+// reconcile creates or updates the dashboard user, renaming the user last applied, or deletes the user if the
+// CephDashboardUser was removed
+func (c *DashboardUserController) reconcile(namespace, name string) error {
+	key := namespace + "/" + name
+	applied, ok := c.applied[key]
+	user, err := c.context.RookClientset.CephV1().CephDashboardUsers(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to get dashboard user %s. %+v", name, err)
+		}
+		if !ok {
+			return nil
+		}
+		if err := c.deleteUser(applied); err != nil {
+			return fmt.Errorf("failed to delete dashboard user %s. %+v", name, err)
+		}
+		delete(c.applied, key)
+		return nil
+	}
+
+	if ok && username(applied) != username(user) {
+		logger.Infof("dashboard user %s renamed from %s to %s", user.Name, username(applied), username(user))
+		if err := c.deleteUser(applied); err != nil {
+			return fmt.Errorf("failed to delete previous dashboard user %s. %+v", username(applied), err)
+		}
+		delete(c.applied, key)
+	}
+	if err := c.createOrUpdateUser(user); err != nil {
+		return fmt.Errorf("failed to update dashboard user %s. %+v", user.Name, err)
+	}
+	c.applied[key] = user
+	return nil
+}
+
+func getDashboardUserObject(obj interface{}) (user *cephv1.CephDashboardUser, err error) {
+	var ok bool
+	user, ok = obj.(*cephv1.CephDashboardUser)
+	if ok {
+		// the dashboard user object is of the latest type, simply return it
+		return user.DeepCopy(), nil
+	}
+	return nil, fmt.Errorf("not a known dashboard user object: %+v", obj)
+}
+
+// username returns the name used to log in to the dashboard
+func username(u *cephv1.CephDashboardUser) string {
+	if u.Spec.Username != "" {
+		return u.Spec.Username
+	}
+	return u.Name
+}
+
+// Create the user or update the password and roles of an existing user
+func (c *DashboardUserController) createOrUpdateUser(u *cephv1.CephDashboardUser) error {
+	if err := ValidateUser(u); err != nil {
+		return fmt.Errorf("invalid dashboard user %s arguments. %+v", u.Name, err)
+	}
+	if c.clusterInfo.CephVersion.IsLuminous() {
+		return fmt.Errorf("dashboard users are only supported with Mimic or newer")
+	}
+
+	password, err := c.getPassword(u)
+	if err != nil {
+		return err
+	}
+
+	name := username(u)
+	if err := client.DashboardCreateUser(c.context, u.Namespace, name, password); err != nil {
+		return err
+	}
+	if err := client.DashboardSetUserRoles(c.context, u.Namespace, name, u.Spec.Roles); err != nil {
+		return err
+	}
+
+	logger.Infof("dashboard user %s configured with roles %v", name, u.Spec.Roles)
+	return nil
+}
+
+// Delete the user
+func (c *DashboardUserController) deleteUser(u *cephv1.CephDashboardUser) error {
+	if c.clusterInfo.CephVersion.IsLuminous() {
+		return nil
+	}
+	if err := client.DashboardDeleteUser(c.context, u.Namespace, username(u)); err != nil {
+		return err
+	}
+	logger.Infof("dashboard user %s deleted successfully", username(u))
+	return nil
+}
+
+// getPassword reads the password of the user from the referenced secret
+func (c *DashboardUserController) getPassword(u *cephv1.CephDashboardUser) (string, error) {
+	ref := u.Spec.PasswordSecret
+	secret, err := c.context.Clientset.CoreV1().Secrets(u.Namespace).Get(ref.Name, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get password secret %s for dashboard user %s. %+v", ref.Name, u.Name, err)
+	}
+	password, ok := secret.Data[ref.Key]
+	if !ok || len(password) == 0 {
+		return "", fmt.Errorf("key %s not found in password secret %s", ref.Key, ref.Name)
+	}
+	return string(password), nil
+}
+
+// ValidateUser validates the user arguments
+func ValidateUser(u *cephv1.CephDashboardUser) error {
+	if u.Name == "" {
+		return fmt.Errorf("missing name")
+	}
+	if u.Namespace == "" {
+		return fmt.Errorf("missing namespace")
+	}
+	if len(u.Spec.Roles) == 0 {
+		return fmt.Errorf("missing roles")
+	}
+	if u.Spec.PasswordSecret.Name == "" {
+		return fmt.Errorf("missing passwordSecret name")
+	}
+	if u.Spec.PasswordSecret.Key == "" {
+		return fmt.Errorf("missing passwordSecret key")
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dashboarduser
+
+import (
+	"testing"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	"github.com/rook/rook/pkg/clusterd"
+	cephconfig "github.com/rook/rook/pkg/daemon/ceph/config"
+	cephver "github.com/rook/rook/pkg/operator/ceph/version"
+	"github.com/rook/rook/pkg/operator/test"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newTestUser() *cephv1.CephDashboardUser {
+	return &cephv1.CephDashboardUser{
+		ObjectMeta: metav1.ObjectMeta{Name: "oncall", Namespace: "myns"},
+		Spec: cephv1.DashboardUserSpec{
+			Roles: []string{"read-only"},
+			PasswordSecret: v1.SecretKeySelector{
+				LocalObjectReference: v1.LocalObjectReference{Name: "oncall-password"},
+				Key:                  "password",
+			},
+		},
+	}
+}
+
+func TestGetDashboardUserObject(t *testing.T) {
+	user, err := getDashboardUserObject(&cephv1.CephDashboardUser{})
+	assert.NotNil(t, user)
+	assert.Nil(t, err)
+
+	// try to get an object that isn't a dashboard user, should return with an error
+	user, err = getDashboardUserObject(&map[string]string{})
+	assert.Nil(t, user)
+	assert.NotNil(t, err)
+}
+
+func TestValidateUser(t *testing.T) {
+	u := newTestUser()
+	assert.Nil(t, ValidateUser(u))
+	assert.Equal(t, "oncall", username(u))
+	u.Spec.Username = "jdoe"
+	assert.Equal(t, "jdoe", username(u))
+
+	u.Spec.Roles = nil
+	assert.NotNil(t, ValidateUser(u))
+
+	u = newTestUser()
+	u.Spec.PasswordSecret.Key = ""
+	assert.NotNil(t, ValidateUser(u))
+}
+
+func TestCreateOrUpdateUser(t *testing.T) {
+	var commands [][]string
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName string, command string, outFileArg string, args ...string) (string, error) {
+			commands = append(commands, args)
+			return "", nil
+		},
+	}
+	clusterInfo := &cephconfig.ClusterInfo{CephVersion: cephver.Mimic}
+	context := &clusterd.Context{Clientset: test.New(1), Executor: executor}
+	c := NewDashboardUserController(clusterInfo, context)
+	u := newTestUser()
+
+	// the password secret does not exist yet
+	err := c.createOrUpdateUser(u)
+	assert.NotNil(t, err)
+	assert.Equal(t, 0, len(commands))
+
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "oncall-password", Namespace: "myns"},
+		Data:       map[string][]byte{"password": []byte("s3cret")},
+	}
+	_, err = context.Clientset.CoreV1().Secrets("myns").Create(secret)
+	assert.Nil(t, err)
+
+	err = c.createOrUpdateUser(u)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(commands))
+	assert.Equal(t, []string{"dashboard", "ac-user-create", "oncall", "s3cret"}, commands[0][:4])
+	assert.Equal(t, []string{"dashboard", "ac-user-set-roles", "oncall", "read-only"}, commands[1][:4])
+
+	// users cannot be created on luminous
+	clusterInfo.CephVersion = cephver.Luminous
+	err = c.createOrUpdateUser(u)
+	assert.NotNil(t, err)
+	assert.Equal(t, 2, len(commands))
+}
@@ -0,0 +1,244 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package deletion protects the pools, filesystems and object stores holding data from being deleted.
+package deletion
+
+import (
+	"fmt"
+
+	"github.com/coreos/pkg/capnslog"
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/operator/ceph/reconcile"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+)
+
+var logger = capnslog.NewPackageLogger("github.com/rook/rook", "op-deletion")
+
+const (
+	// ReasonDeletionBlocked is the reason of the events emitted when the deletion of a resource is blocked
+	ReasonDeletionBlocked = "DeletionBlocked"
+)
+
+// Object is a resource protected by a finalizer, such as a CephBlockPool
+type Object interface {
+	runtime.Object
+	metav1.Object
+}
+
+// Client gets and updates the resources of a kind, implemented with the typed clientset of the kind
+type Client interface {
+	Get(namespace, name string) (Object, error)
+	List(namespace string) ([]Object, error)
+	Update(obj Object) error
+	// SetStatus sets the status of the object. Returns whether the status changed.
+	SetStatus(obj Object, status cephv1.ResourceStatus) bool
+}
+
+// Guard holds the finalizer of a kind of resource, deletes the Ceph resources of the objects marked for deletion
+// and reports the blocked deletions
+type Guard struct {
+	context   *clusterd.Context
+	client    Client
+	kind      string
+	recorder  record.EventRecorder
+	reporter  *reconcile.Reporter
+	finalizer string
+}
+
+// NewGuard creates the guard of the resources of the kind with the given finalizer. The kind names the resources in
+// the messages, such as "pool".
+func NewGuard(context *clusterd.Context, client Client, kind string, recorder record.EventRecorder, reporter *reconcile.Reporter, finalizer string) *Guard {
+	return &Guard{
+		context:   context,
+		client:    client,
+		kind:      kind,
+		recorder:  recorder,
+		reporter:  reporter,
+		finalizer: finalizer,
+	}
+}
+
+// HasFinalizer returns whether the object has the finalizer
+func (g *Guard) HasFinalizer(meta metav1.Object) bool {
+	for _, f := range meta.GetFinalizers() {
+		if f == g.finalizer {
+			return true
+		}
+	}
+	return false
+}
+
+// AddFinalizer adds the finalizer to the object. Returns whether the object needs to be updated.
+func (g *Guard) AddFinalizer(meta metav1.Object) bool {
+	return AddFinalizer(meta, g.finalizer)
+}
+
+// RemoveFinalizer removes the finalizer from the object. Returns whether the object needs to be updated.
+func (g *Guard) RemoveFinalizer(meta metav1.Object) bool {
+	return RemoveFinalizer(meta, g.finalizer)
+}
+
+// Blocked records a warning event with the reason the deletion of the object is blocked
+func (g *Guard) Blocked(obj runtime.Object, meta metav1.Object, reason string) {
+	logger.Warningf("deletion of %s/%s is blocked. %s", meta.GetNamespace(), meta.GetName(), reason)
+	if g.recorder != nil {
+		g.recorder.Event(obj, v1.EventTypeWarning, ReasonDeletionBlocked, reason)
+	}
+}
+
+// EnsureFinalizer adds the finalizer to the object and updates it if it did not have the finalizer
+func (g *Guard) EnsureFinalizer(obj Object) error {
+	if !g.AddFinalizer(obj) {
+		return nil
+	}
+	return g.client.Update(obj)
+}
+
+// HandleDeletion deletes the Ceph resources of an object marked for deletion with remove, then removes the finalizer
+// so the object goes away. The resources are not deleted if remove is nil, or if the cluster is deleted since it is not
+// running anymore. The deletion is blocked while blocked returns a reason, which is reported in an event and in the
+// status of the object, and the error returned for the deletion to be retried. The check is skipped if blocked is nil.
+func (g *Guard) HandleDeletion(obj Object, blocked func() (string, error), remove func() error) error {
+	if !g.HasFinalizer(obj) {
+		return nil
+	}
+
+	name := obj.GetName()
+	clusterDeleted, err := ClusterDeleted(g.context, obj.GetNamespace())
+	if err != nil {
+		return g.reporter.Failed(obj, fmt.Errorf("failed to delete %s %s. %+v", g.kind, name, err))
+	}
+	if clusterDeleted {
+		logger.Infof("not deleting %s %s of deleted cluster %s", g.kind, name, obj.GetNamespace())
+	} else if remove == nil {
+		logger.Infof("preserving %s %s in namespace %s", g.kind, name, obj.GetNamespace())
+	} else {
+		if blocked != nil {
+			reason, err := blocked()
+			if err != nil {
+				return g.reporter.Failed(obj, fmt.Errorf("failed to check if %s %s can be deleted. %+v", g.kind, name, err))
+			}
+			if reason != "" {
+				g.Blocked(obj, obj, reason)
+				g.updateStatus(obj, cephv1.ResourceStatus{Phase: cephv1.ResourcePhaseDeletionBlocked, Message: reason})
+				return fmt.Errorf("deletion of %s %s is blocked. %s", g.kind, name, reason)
+			}
+		}
+		if err := remove(); err != nil {
+			return g.reporter.Failed(obj, fmt.Errorf("failed to delete %s %s. %+v", g.kind, name, err))
+		}
+		g.reporter.Deleted(obj, "deleted %s %s", g.kind, name)
+	}
+
+	if err := g.removeFinalizer(obj); err != nil {
+		return g.reporter.Failed(obj, fmt.Errorf("failed to remove finalizer from %s %s. %+v", g.kind, name, err))
+	}
+	return nil
+}
+
+func (g *Guard) updateStatus(obj Object, status cephv1.ResourceStatus) {
+	latest, err := g.client.Get(obj.GetNamespace(), obj.GetName())
+	if err != nil {
+		logger.Errorf("failed to get %s %s to update its status. %+v", g.kind, obj.GetName(), err)
+		return
+	}
+	if !g.client.SetStatus(latest, status) {
+		return
+	}
+	if err := g.client.Update(latest); err != nil {
+		logger.Errorf("failed to update the status of %s %s. %+v", g.kind, obj.GetName(), err)
+	}
+}
+
+func (g *Guard) removeFinalizer(obj Object) error {
+	// get the latest object since its status may have been updated
+	latest, err := g.client.Get(obj.GetNamespace(), obj.GetName())
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if !g.RemoveFinalizer(latest) {
+		return nil
+	}
+	return g.client.Update(latest)
+}
+
+// RemoveFinalizers removes the finalizer of the resources of the kind in the namespace of a deleted cluster. Their
+// Ceph resources are not deleted.
+func RemoveFinalizers(client Client, kind, namespace, finalizer string) error {
+	objs, err := client.List(namespace)
+	if err != nil {
+		return fmt.Errorf("failed to list %ss in namespace %s. %+v", kind, namespace, err)
+	}
+	for _, obj := range objs {
+		if !RemoveFinalizer(obj, finalizer) {
+			continue
+		}
+		if err := client.Update(obj); err != nil {
+			return fmt.Errorf("failed to remove finalizer from %s %s. %+v", kind, obj.GetName(), err)
+		}
+	}
+	return nil
+}
+
+// ClusterDeleted returns whether the cluster of the namespace is gone or being deleted. The resources of a deleted
+// cluster are not checked since the cluster is not running anymore.
+func ClusterDeleted(context *clusterd.Context, namespace string) (bool, error) {
+	clusters, err := context.RookClientset.CephV1().CephClusters(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, fmt.Errorf("failed to get the cluster of namespace %s. %+v", namespace, err)
+	}
+	for _, cluster := range clusters.Items {
+		if cluster.DeletionTimestamp == nil {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// AddFinalizer adds a finalizer to the object. Returns whether it was added.
+func AddFinalizer(meta metav1.Object, finalizer string) bool {
+	for _, f := range meta.GetFinalizers() {
+		if f == finalizer {
+			return false
+		}
+	}
+	meta.SetFinalizers(append(meta.GetFinalizers(), finalizer))
+	return true
+}
+
+// RemoveFinalizer removes a finalizer from the object. Returns whether it was removed.
+func RemoveFinalizer(meta metav1.Object, finalizer string) bool {
+	finalizers := meta.GetFinalizers()
+	for i, f := range finalizers {
+		if f == finalizer {
+			meta.SetFinalizers(append(finalizers[:i], finalizers[i+1:]...))
+			return true
+		}
+	}
+	return false
+}
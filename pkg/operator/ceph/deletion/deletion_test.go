@@ -0,0 +1,168 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deletion
+
+import (
+	"fmt"
+	"testing"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	rookfake "github.com/rook/rook/pkg/client/clientset/versioned/fake"
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/operator/ceph/reconcile"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestFinalizers(t *testing.T) {
+	guard := NewGuard(nil, nil, "pool", nil, nil, "cephblockpool.ceph.rook.io")
+	meta := &metav1.ObjectMeta{Finalizers: []string{"other"}}
+	assert.False(t, guard.HasFinalizer(meta))
+
+	assert.True(t, guard.AddFinalizer(meta))
+	assert.False(t, guard.AddFinalizer(meta))
+	assert.True(t, guard.HasFinalizer(meta))
+	assert.Equal(t, []string{"other", "cephblockpool.ceph.rook.io"}, meta.Finalizers)
+
+	assert.True(t, guard.RemoveFinalizer(meta))
+	assert.False(t, guard.RemoveFinalizer(meta))
+	assert.Equal(t, []string{"other"}, meta.Finalizers)
+}
+
+func TestBlocked(t *testing.T) {
+	recorder := record.NewFakeRecorder(1)
+	guard := NewGuard(nil, nil, "pool", recorder, nil, "cephblockpool.ceph.rook.io")
+	pool := &cephv1.CephBlockPool{ObjectMeta: metav1.ObjectMeta{Name: "mypool", Namespace: "ns"}}
+	guard.Blocked(pool, &pool.ObjectMeta, "pool mypool holds 3 objects")
+	assert.Equal(t, "Warning DeletionBlocked pool mypool holds 3 objects", <-recorder.Events)
+}
+
+func TestClusterDeleted(t *testing.T) {
+	context := &clusterd.Context{RookClientset: rookfake.NewSimpleClientset()}
+	deleted, err := ClusterDeleted(context, "ns")
+	assert.Nil(t, err)
+	assert.True(t, deleted)
+
+	cluster := &cephv1.CephCluster{ObjectMeta: metav1.ObjectMeta{Name: "mycluster", Namespace: "ns"}}
+	context = &clusterd.Context{RookClientset: rookfake.NewSimpleClientset(cluster)}
+	deleted, err = ClusterDeleted(context, "ns")
+	assert.Nil(t, err)
+	assert.False(t, deleted)
+
+	now := metav1.Now()
+	cluster.DeletionTimestamp = &now
+	context = &clusterd.Context{RookClientset: rookfake.NewSimpleClientset(cluster)}
+	deleted, err = ClusterDeleted(context, "ns")
+	assert.Nil(t, err)
+	assert.True(t, deleted)
+}
+
+// poolClient gets and updates the CephBlockPools of the fake clientset
+type poolClient struct {
+	context *clusterd.Context
+}
+
+func (c *poolClient) Get(namespace, name string) (Object, error) {
+	return c.context.RookClientset.CephV1().CephBlockPools(namespace).Get(name, metav1.GetOptions{})
+}
+
+func (c *poolClient) List(namespace string) ([]Object, error) {
+	list, err := c.context.RookClientset.CephV1().CephBlockPools(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	objs := []Object{}
+	for i := range list.Items {
+		objs = append(objs, &list.Items[i])
+	}
+	return objs, nil
+}
+
+func (c *poolClient) Update(obj Object) error {
+	_, err := c.context.RookClientset.CephV1().CephBlockPools(obj.GetNamespace()).Update(obj.(*cephv1.CephBlockPool))
+	return err
+}
+
+func (c *poolClient) SetStatus(obj Object, status cephv1.ResourceStatus) bool {
+	pool := obj.(*cephv1.CephBlockPool)
+	if pool.Status == status {
+		return false
+	}
+	pool.Status = status
+	return true
+}
+
+func TestHandleDeletion(t *testing.T) {
+	finalizer := "cephblockpool.ceph.rook.io"
+	now := metav1.Now()
+	cluster := &cephv1.CephCluster{ObjectMeta: metav1.ObjectMeta{Name: "mycluster", Namespace: "ns"}}
+	pool := &cephv1.CephBlockPool{ObjectMeta: metav1.ObjectMeta{
+		Name: "mypool", Namespace: "ns", Finalizers: []string{finalizer}, DeletionTimestamp: &now}}
+	context := &clusterd.Context{RookClientset: rookfake.NewSimpleClientset(cluster, pool)}
+	client := &poolClient{context: context}
+	guard := NewGuard(context, client, "pool", nil, reconcile.NewReporter(nil), finalizer)
+
+	reason := "pool mypool holds 1 images"
+	blocked := func() (string, error) { return reason, nil }
+	removed := false
+	remove := func() error {
+		removed = true
+		return nil
+	}
+
+	// the deletion is blocked and reported in the status
+	err := guard.HandleDeletion(pool, blocked, remove)
+	assert.NotNil(t, err)
+	assert.False(t, removed)
+	latest, err := client.Get("ns", "mypool")
+	assert.Nil(t, err)
+	assert.Equal(t, cephv1.ResourceStatus{Phase: cephv1.ResourcePhaseDeletionBlocked, Message: reason}, latest.(*cephv1.CephBlockPool).Status)
+	assert.Equal(t, []string{finalizer}, latest.GetFinalizers())
+
+	// a failed deletion keeps the finalizer
+	reason = ""
+	err = guard.HandleDeletion(latest, blocked, func() error { return fmt.Errorf("mock failure") })
+	assert.NotNil(t, err)
+	latest, _ = client.Get("ns", "mypool")
+	assert.Equal(t, []string{finalizer}, latest.GetFinalizers())
+
+	// the resources are deleted and the finalizer removed
+	err = guard.HandleDeletion(latest, blocked, remove)
+	assert.Nil(t, err)
+	assert.True(t, removed)
+	latest, _ = client.Get("ns", "mypool")
+	assert.Equal(t, 0, len(latest.GetFinalizers()))
+
+	// preserved resources are not deleted
+	removed = false
+	latest.SetFinalizers([]string{finalizer})
+	assert.Nil(t, client.Update(latest))
+	err = guard.HandleDeletion(latest, nil, nil)
+	assert.Nil(t, err)
+	assert.False(t, removed)
+	latest, _ = client.Get("ns", "mypool")
+	assert.Equal(t, 0, len(latest.GetFinalizers()))
+
+	// the finalizers of a deleted cluster are removed without deleting the resources
+	latest.SetFinalizers([]string{finalizer, "other"})
+	assert.Nil(t, client.Update(latest))
+	err = RemoveFinalizers(client, "pool", "ns", finalizer)
+	assert.Nil(t, err)
+	latest, _ = client.Get("ns", "mypool")
+	assert.Equal(t, []string{"other"}, latest.GetFinalizers())
+}
@@ -26,12 +26,20 @@ import (
 	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
 	cephbeta "github.com/rook/rook/pkg/apis/ceph.rook.io/v1beta1"
 	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/daemon/ceph/client"
 	cephconfig "github.com/rook/rook/pkg/daemon/ceph/config"
+	"github.com/rook/rook/pkg/operator/ceph/deletion"
+	"github.com/rook/rook/pkg/operator/ceph/file/mds"
 	"github.com/rook/rook/pkg/operator/ceph/pool"
+	"github.com/rook/rook/pkg/operator/ceph/reconcile"
+	opspec "github.com/rook/rook/pkg/operator/ceph/spec"
+	"github.com/rook/rook/pkg/operator/webhook"
 	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 )
 
 var logger = capnslog.NewPackageLogger("github.com/rook/rook", "op-file")
@@ -55,6 +63,8 @@ var filesystemResourceRookLegacy = opkit.CustomResource{
 	Kind:    reflect.TypeOf(cephbeta.Filesystem{}).Name(),
 }
 
+var finalizerName = fmt.Sprintf("%s.%s", FilesystemResource.Name, FilesystemResource.Group)
+
 // FilesystemController represents a controller for filesystem custom resources
 type FilesystemController struct {
 	clusterInfo *cephconfig.ClusterInfo
@@ -62,7 +72,12 @@ type FilesystemController struct {
 	rookVersion string
 	cephVersion cephv1.CephVersionSpec
 	hostNetwork bool
-	ownerRef    metav1.OwnerReference
+	// dataDirHostPath is the host path under which the crash reports of the mdses are kept
+	dataDirHostPath string
+	ownerRef        metav1.OwnerReference
+	guard           *deletion.Guard
+	queue           *reconcile.Queue
+	reporter        *reconcile.Reporter
 }
 
 // NewFilesystemController create controller for watching filesystem custom resources created
@@ -72,20 +87,28 @@ func NewFilesystemController(
 	rookVersion string,
 	cephVersion cephv1.CephVersionSpec,
 	hostNetwork bool,
+	dataDirHostPath string,
 	ownerRef metav1.OwnerReference,
+	recorder record.EventRecorder,
 ) *FilesystemController {
-	return &FilesystemController{
-		clusterInfo: clusterInfo,
-		context:     context,
-		rookVersion: rookVersion,
-		cephVersion: cephVersion,
-		hostNetwork: hostNetwork,
-		ownerRef:    ownerRef,
+	c := &FilesystemController{
+		clusterInfo:     clusterInfo,
+		context:         context,
+		rookVersion:     rookVersion,
+		cephVersion:     cephVersion,
+		hostNetwork:     hostNetwork,
+		dataDirHostPath: dataDirHostPath,
+		ownerRef:        ownerRef,
+		reporter:        reconcile.NewReporter(recorder),
 	}
+	c.guard = deletion.NewGuard(context, &filesystemClient{context: context}, "filesystem", recorder, c.reporter, finalizerName)
+	c.queue = reconcile.New(FilesystemResource.Name, c.reconcile)
+	return c
 }
 
 // StartWatch watches for instances of Filesystem custom resources and acts on them
 func (c *FilesystemController) StartWatch(namespace string, stopCh chan struct{}) error {
+	c.queue.Start(stopCh)
 
 	resourceHandlerFuncs := cache.ResourceEventHandlerFuncs{
 		AddFunc:    c.onAdd,
@@ -117,10 +140,7 @@ func (c *FilesystemController) onAdd(obj interface{}) {
 		return
 	}
 
-	err = createFilesystem(c.clusterInfo, c.context, *filesystem, c.rookVersion, c.cephVersion, c.hostNetwork, c.filesystemOwners(filesystem))
-	if err != nil {
-		logger.Errorf("failed to create filesystem %s: %+v", filesystem.Name, err)
-	}
+	c.queue.Add(filesystem)
 }
 
 func (c *FilesystemController) onUpdate(oldObj, newObj interface{}) {
@@ -142,6 +162,11 @@ func (c *FilesystemController) onUpdate(oldObj, newObj interface{}) {
 		return
 	}
 
+	if newFS.DeletionTimestamp != nil {
+		c.queue.Add(newFS)
+		return
+	}
+
 	if !filesystemChanged(oldFS.Spec, newFS.Spec) {
 		logger.Debugf("filesystem %s not updated", newFS.Name)
 		return
@@ -149,10 +174,34 @@ func (c *FilesystemController) onUpdate(oldObj, newObj interface{}) {
 
 	// if the filesystem is modified, allow the filesystem to be created if it wasn't already
 	logger.Infof("updating filesystem %s", newFS.Name)
-	err = createFilesystem(c.clusterInfo, c.context, *newFS, c.rookVersion, c.cephVersion, c.hostNetwork, c.filesystemOwners(newFS))
+	c.queue.Add(newFS)
+}
+
+// reconcile creates or updates the filesystem of the CephFilesystem, or deletes it if the CephFilesystem is being deleted
+func (c *FilesystemController) reconcile(namespace, name string) error {
+	fs, err := c.context.RookClientset.CephV1().CephFilesystems(namespace).Get(name, metav1.GetOptions{})
 	if err != nil {
-		logger.Errorf("failed to create (modify) filesystem %s: %+v", newFS.Name, err)
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to get filesystem %s. %+v", name, err)
+	}
+
+	if fs.DeletionTimestamp != nil {
+		return c.handleDeletion(fs)
+	}
+	if err := c.guard.EnsureFinalizer(fs); err != nil {
+		return c.reporter.Failed(fs, fmt.Errorf("failed to add finalizer to filesystem %s. %+v", fs.Name, err))
+	}
+	overrides, err := opspec.GetDaemonOverrides(c.context, namespace)
+	if err != nil {
+		return c.reporter.Failed(fs, fmt.Errorf("failed to get the mds overrides of filesystem %s. %+v", fs.Name, err))
+	}
+	if err := createFilesystem(c.clusterInfo, c.context, *fs, c.rookVersion, c.cephVersion, cephv1.GetMDSOverride(overrides), c.hostNetwork, c.dataDirHostPath, c.filesystemOwners(fs)); err != nil {
+		return c.reporter.Failed(fs, fmt.Errorf("failed to create filesystem %s. %+v", fs.Name, err))
 	}
+	c.reporter.Succeeded(fs, "created filesystem %s with %d active mds", fs.Name, fs.Spec.MetadataServer.ActiveCount)
+	return nil
 }
 
 func (c *FilesystemController) onDelete(obj interface{}) {
@@ -167,10 +216,89 @@ func (c *FilesystemController) onDelete(obj interface{}) {
 		return
 	}
 
-	err = deleteFilesystem(c.context, *filesystem)
+	// the filesystem was deleted, or preserved, before the finalizer was removed
+	logger.Infof("filesystem %s removed from namespace %s", filesystem.Name, filesystem.Namespace)
+	c.queue.Remove(filesystem)
+}
+
+// handleDeletion deletes the filesystem of a CephFilesystem marked for deletion unless its data pools still hold data,
+// then removes the finalizer so the CephFilesystem goes away. The deletion is retried while it is blocked.
+func (c *FilesystemController) handleDeletion(fs *cephv1.CephFilesystem) error {
+	var blocked func() (string, error)
+	if !fs.Spec.PreservePoolsOnDelete {
+		blocked = func() (string, error) { return deletionBlocked(c.context, fs) }
+	}
+	return c.guard.HandleDeletion(fs, blocked, func() error { return deleteFilesystem(c.context, *fs) })
+}
+
+// deletionBlocked returns the reason the filesystem can't be deleted, or an empty string
+func deletionBlocked(context *clusterd.Context, fs *cephv1.CephFilesystem) (string, error) {
+	filesystems, err := client.ListFilesystems(context, fs.Namespace)
+	if err != nil {
+		return "", err
+	}
+	var dataPools []string
+	for _, f := range filesystems {
+		if f.Name == fs.Name {
+			dataPools = f.DataPools
+		}
+	}
+	if len(dataPools) == 0 {
+		return "", nil
+	}
+
+	stats, err := client.GetPoolStats(context, fs.Namespace)
 	if err != nil {
-		logger.Errorf("failed to delete filesystem %s: %+v", filesystem.Name, err)
+		return "", err
+	}
+	for _, p := range stats.Pools {
+		for _, name := range dataPools {
+			if p.Name == name && p.Stats.Objects > 0 {
+				return fmt.Sprintf("data pool %s of filesystem %s holds %d objects", name, fs.Name, int64(p.Stats.Objects)), nil
+			}
+		}
 	}
+	return "", nil
+}
+
+// RemoveFinalizers removes the finalizer of the CephFilesystems of a deleted cluster. Their filesystems are not deleted.
+func RemoveFinalizers(context *clusterd.Context, namespace string) error {
+	return deletion.RemoveFinalizers(&filesystemClient{context: context}, "filesystem", namespace, finalizerName)
+}
+
+// filesystemClient gets and updates the CephFilesystems for the deletion guard
+type filesystemClient struct {
+	context *clusterd.Context
+}
+
+func (c *filesystemClient) Get(namespace, name string) (deletion.Object, error) {
+	return c.context.RookClientset.CephV1().CephFilesystems(namespace).Get(name, metav1.GetOptions{})
+}
+
+func (c *filesystemClient) List(namespace string) ([]deletion.Object, error) {
+	list, err := c.context.RookClientset.CephV1().CephFilesystems(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	objs := []deletion.Object{}
+	for i := range list.Items {
+		objs = append(objs, &list.Items[i])
+	}
+	return objs, nil
+}
+
+func (c *filesystemClient) Update(obj deletion.Object) error {
+	_, err := c.context.RookClientset.CephV1().CephFilesystems(obj.GetNamespace()).Update(obj.(*cephv1.CephFilesystem))
+	return err
+}
+
+func (c *filesystemClient) SetStatus(obj deletion.Object, status cephv1.ResourceStatus) bool {
+	fs := obj.(*cephv1.CephFilesystem)
+	if fs.Status == status {
+		return false
+	}
+	fs.Status = status
+	return true
 }
 
 func (c *FilesystemController) filesystemOwners(fs *cephv1.CephFilesystem) []metav1.OwnerReference {
@@ -181,6 +309,24 @@ func (c *FilesystemController) filesystemOwners(fs *cephv1.CephFilesystem) []met
 	return []metav1.OwnerReference{c.ownerRef}
 }
 
+// Validator returns the validator of the CephFilesystems for the validating webhook. Only the spec is validated, the
+// crush properties of the pools are checked against the cluster when the filesystem is created.
+func Validator(context *clusterd.Context) webhook.Validator {
+	return webhook.Validator{
+		NewObject: func() runtime.Object { return &cephv1.CephFilesystem{} },
+		Validate: func(oldObj, newObj runtime.Object) error {
+			fs := newObj.(*cephv1.CephFilesystem)
+			if err := validateFilesystemSettings(*fs); err != nil {
+				return err
+			}
+			if oldObj == nil {
+				return nil
+			}
+			return validateFilesystemUpdate(oldObj.(*cephv1.CephFilesystem).Spec, fs.Spec)
+		},
+	}
+}
+
 func filesystemChanged(oldFS, newFS cephv1.FilesystemSpec) bool {
 	if len(oldFS.DataPools) != len(newFS.DataPools) {
 		logger.Infof("number of data pools changed from %d to %d", len(oldFS.DataPools), len(newFS.DataPools))
@@ -194,6 +340,23 @@ func filesystemChanged(oldFS, newFS cephv1.FilesystemSpec) bool {
 		logger.Infof("mds active standby changed from %t to %t", oldFS.MetadataServer.ActiveStandby, newFS.MetadataServer.ActiveStandby)
 		return true
 	}
+	if mds.StandbyCount(oldFS.MetadataServer) != mds.StandbyCount(newFS.MetadataServer) {
+		logger.Infof("number of mds standby changed from %d to %d", mds.StandbyCount(oldFS.MetadataServer), mds.StandbyCount(newFS.MetadataServer))
+		return true
+	}
+	if oldFS.MetadataServer.MaxFileSize != newFS.MetadataServer.MaxFileSize {
+		logger.Infof("max file size changed from %d to %d", oldFS.MetadataServer.MaxFileSize, newFS.MetadataServer.MaxFileSize)
+		return true
+	}
+	if !reflect.DeepEqual(oldFS.MetadataServer.SubtreePins, newFS.MetadataServer.SubtreePins) {
+		logger.Infof("mds subtree pins changed")
+		return true
+	}
+	if !reflect.DeepEqual(oldFS.MetadataServer.Resources, newFS.MetadataServer.Resources) {
+		// the mds cache size follows the memory of the mds pods
+		logger.Infof("mds resources changed")
+		return true
+	}
 	return false
 }
 
@@ -48,6 +48,24 @@ func TestFilesystemChanged(t *testing.T) {
 
 	new = cephv1.FilesystemSpec{MetadataServer: cephv1.MetadataServerSpec{ActiveCount: 1, ActiveStandby: false}}
 	assert.True(t, filesystemChanged(old, new))
+
+	// the default standby count is the active count
+	standby := int32(1)
+	new = cephv1.FilesystemSpec{MetadataServer: cephv1.MetadataServerSpec{ActiveCount: 1, ActiveStandby: true, StandbyCount: &standby}}
+	assert.False(t, filesystemChanged(old, new))
+	standby = 2
+	assert.True(t, filesystemChanged(old, new))
+
+	new = cephv1.FilesystemSpec{MetadataServer: cephv1.MetadataServerSpec{ActiveCount: 1, ActiveStandby: true, MaxFileSize: 1024}}
+	assert.True(t, filesystemChanged(old, new))
+
+	new = cephv1.FilesystemSpec{MetadataServer: cephv1.MetadataServerSpec{ActiveCount: 1, ActiveStandby: true,
+		SubtreePins: []cephv1.MDSSubtreePinSpec{{Path: "/home", Rank: 0}}}}
+	assert.True(t, filesystemChanged(old, new))
+
+	new = cephv1.FilesystemSpec{MetadataServer: cephv1.MetadataServerSpec{ActiveCount: 1, ActiveStandby: true,
+		Resources: v1.ResourceRequirements{Limits: v1.ResourceList{v1.ResourceMemory: resource.MustParse("4Gi")}}}}
+	assert.True(t, filesystemChanged(old, new))
 }
 
 func TestGetFilesystemObject(t *testing.T) {
@@ -87,7 +105,7 @@ func TestMigrateFilesystemObject(t *testing.T) {
 	}
 	clusterInfo := &cephconfig.ClusterInfo{FSID: "myfsid"}
 
-	controller := NewFilesystemController(clusterInfo, context, "", cephv1.CephVersionSpec{}, false, metav1.OwnerReference{})
+	controller := NewFilesystemController(clusterInfo, context, "", cephv1.CephVersionSpec{}, false, "", metav1.OwnerReference{}, nil)
 
 	// convert the legacy filesystem object in memory and assert that a migration is needed
 	convertedFilesystem, migrationNeeded, err := getFilesystemObject(legacyFilesystem)
@@ -18,6 +18,7 @@ package file
 
 import (
 	"fmt"
+	"strings"
 
 	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
 	"github.com/rook/rook/pkg/clusterd"
@@ -51,7 +52,9 @@ func createFilesystem(
 	fs cephv1.CephFilesystem,
 	rookVersion string,
 	cephVersion cephv1.CephVersionSpec,
+	override cephv1.DaemonOverride,
 	hostNetwork bool,
+	dataDirHostPath string,
 	ownerRefs []metav1.OwnerReference,
 ) error {
 	if err := validateFilesystem(context, fs); err != nil {
@@ -90,17 +93,49 @@ func createFilesystem(
 		}
 	}
 
+	// warn when there are fewer standby mdses than requested
+	if err = client.SetStandbyCountWanted(context, fs.Namespace, fs.Name, mds.StandbyCount(fs.Spec.MetadataServer)); err != nil {
+		logger.Warningf("failed setting wanted standby mds count. %+v", err)
+	}
+
+	if fs.Spec.MetadataServer.MaxFileSize > 0 {
+		if err = client.SetMaxFileSize(context, fs.Namespace, fs.Name, fs.Spec.MetadataServer.MaxFileSize); err != nil {
+			return err
+		}
+	}
+
 	logger.Infof("start running mdses for filesystem %s", fs.Name)
-	c := mds.NewCluster(clusterInfo, context, rookVersion, cephVersion, hostNetwork, fs, filesystem, ownerRefs)
+	c := mds.NewCluster(clusterInfo, context, rookVersion, cephVersion, override, hostNetwork, fs, filesystem, ownerRefs)
+	c.DataDirHostPath = dataDirHostPath
 	if err := c.Start(); err != nil {
 		return err
 	}
 
+	applySubtreePins(context, fs)
+
 	return nil
 }
 
-// deleteFileSystem deletes the filesystem and the metadata servers
+// applySubtreePins pins the subtrees to their ranks. The pins are set each time the filesystem is reconciled. A
+// failure is not fatal since the directories may not exist yet.
+func applySubtreePins(context *clusterd.Context, fs cephv1.CephFilesystem) {
+	for _, pin := range fs.Spec.MetadataServer.SubtreePins {
+		if err := client.PinSubtree(context, fs.Namespace, fs.Name, pin.Path, pin.Rank); err != nil {
+			logger.Warningf("failed to pin subtree of filesystem %s. %+v", fs.Name, err)
+			continue
+		}
+		logger.Infof("pinned subtree %s of filesystem %s to mds rank %d", pin.Path, fs.Name, pin.Rank)
+	}
+}
+
+// deleteFileSystem deletes the filesystem and the metadata servers. The filesystem and its pools are kept if
+// preservePoolsOnDelete is set.
 func deleteFilesystem(context *clusterd.Context, fs cephv1.CephFilesystem) error {
+	if fs.Spec.PreservePoolsOnDelete {
+		logger.Infof("preserving filesystem %s and its pools", fs.Name)
+		return mds.DeleteCluster(context, fs.Namespace, fs.Name)
+	}
+
 	// The most important part of deletion is that the filesystem gets removed from Ceph
 	if err := downFilesystem(context, fs.Namespace, fs.Name); err != nil {
 		// If the fs isn't deleted from Ceph, leave the daemons so it can still be used.
@@ -117,7 +152,28 @@ func deleteFilesystem(context *clusterd.Context, fs cephv1.CephFilesystem) error
 	return mds.DeleteCluster(context, fs.Namespace, fs.Name)
 }
 
+// validateFilesystem validates the filesystem arguments, and the crush properties of its pools against the cluster
 func validateFilesystem(context *clusterd.Context, f cephv1.CephFilesystem) error {
+	if err := validateFilesystemSettings(f); err != nil {
+		return err
+	}
+	// No data pool means that we expect the fs to exist already
+	if len(f.Spec.DataPools) == 0 {
+		return nil
+	}
+	if err := pool.ValidatePoolSpec(context, f.Namespace, &f.Spec.MetadataPool); err != nil {
+		return fmt.Errorf("invalid metadata pool: %+v", err)
+	}
+	for _, p := range f.Spec.DataPools {
+		if err := pool.ValidatePoolSpec(context, f.Namespace, &p); err != nil {
+			return fmt.Errorf("Invalid data pool: %+v", err)
+		}
+	}
+	return nil
+}
+
+// validateFilesystemSettings validates the filesystem arguments without the state of the cluster
+func validateFilesystemSettings(f cephv1.CephFilesystem) error {
 	if f.Name == "" {
 		return fmt.Errorf("missing name")
 	}
@@ -127,15 +183,33 @@ func validateFilesystem(context *clusterd.Context, f cephv1.CephFilesystem) erro
 	if f.Spec.MetadataServer.ActiveCount < 1 {
 		return fmt.Errorf("MetadataServer.ActiveCount must be at least 1")
 	}
+	if f.Spec.MetadataServer.StandbyCount != nil && *f.Spec.MetadataServer.StandbyCount < 0 {
+		return fmt.Errorf("MetadataServer.StandbyCount must not be negative")
+	}
+	// with standby-replay, a standby mds follows the journal of each active mds
+	if f.Spec.MetadataServer.ActiveStandby && mds.StandbyCount(f.Spec.MetadataServer) < f.Spec.MetadataServer.ActiveCount {
+		return fmt.Errorf("MetadataServer.StandbyCount must be at least MetadataServer.ActiveCount when MetadataServer.ActiveStandby is set")
+	}
+	if f.Spec.MetadataServer.MaxFileSize < 0 {
+		return fmt.Errorf("MetadataServer.MaxFileSize must not be negative")
+	}
+	for _, pin := range f.Spec.MetadataServer.SubtreePins {
+		if !strings.HasPrefix(pin.Path, "/") {
+			return fmt.Errorf("subtree pin path %s must be an absolute path", pin.Path)
+		}
+		if pin.Rank < 0 || pin.Rank >= f.Spec.MetadataServer.ActiveCount {
+			return fmt.Errorf("subtree pin rank %d of path %s must be lower than MetadataServer.ActiveCount", pin.Rank, pin.Path)
+		}
+	}
 	// No data pool means that we expect the fs to exist already
 	if len(f.Spec.DataPools) == 0 {
 		return nil
 	}
-	if err := pool.ValidatePoolSpec(context, f.Namespace, &f.Spec.MetadataPool); err != nil {
+	if err := pool.ValidatePoolSpecSettings(&f.Spec.MetadataPool); err != nil {
 		return fmt.Errorf("invalid metadata pool: %+v", err)
 	}
 	for _, p := range f.Spec.DataPools {
-		if err := pool.ValidatePoolSpec(context, f.Namespace, &p); err != nil {
+		if err := pool.ValidatePoolSpecSettings(&p); err != nil {
 			return fmt.Errorf("Invalid data pool: %+v", err)
 		}
 	}
@@ -143,6 +217,19 @@ func validateFilesystem(context *clusterd.Context, f cephv1.CephFilesystem) erro
 	return nil
 }
 
+// validateFilesystemUpdate returns an error if the pools of the filesystem can't be changed to the new spec
+func validateFilesystemUpdate(oldFS, newFS cephv1.FilesystemSpec) error {
+	if err := pool.ValidatePoolSpecUpdate(&oldFS.MetadataPool, &newFS.MetadataPool); err != nil {
+		return fmt.Errorf("invalid metadata pool update: %+v", err)
+	}
+	for i := 0; i < len(oldFS.DataPools) && i < len(newFS.DataPools); i++ {
+		if err := pool.ValidatePoolSpecUpdate(&oldFS.DataPools[i], &newFS.DataPools[i]); err != nil {
+			return fmt.Errorf("invalid update of data pool %d: %+v", i, err)
+		}
+	}
+	return nil
+}
+
 // newFS creates a new instance of the file (MDS) service
 func newFS(name string, metadataPool *model.Pool, dataPools []*model.Pool, activeMDSCount int32) *Filesystem {
 
@@ -20,6 +20,7 @@ import (
 	"errors"
 	"io/ioutil"
 	"os"
+	osexec "os/exec"
 	"path"
 	"strings"
 	"testing"
@@ -31,6 +32,7 @@ import (
 	"github.com/rook/rook/pkg/operator/ceph/file/mds"
 	testopk8s "github.com/rook/rook/pkg/operator/k8sutil/test"
 	testop "github.com/rook/rook/pkg/operator/test"
+	"github.com/rook/rook/pkg/util/exec"
 	exectest "github.com/rook/rook/pkg/util/exec/test"
 	"github.com/stretchr/testify/assert"
 	apps "k8s.io/api/apps/v1"
@@ -66,6 +68,61 @@ func TestValidateSpec(t *testing.T) {
 
 	// valid!
 	assert.Nil(t, validateFilesystem(context, fs))
+
+	// negative standby count
+	standby := int32(-1)
+	fs.Spec.MetadataServer.StandbyCount = &standby
+	assert.NotNil(t, validateFilesystem(context, fs))
+	standby = 0
+	assert.Nil(t, validateFilesystem(context, fs))
+
+	// standby-replay needs a standby for each active mds
+	fs.Spec.MetadataServer.ActiveStandby = true
+	assert.NotNil(t, validateFilesystem(context, fs))
+	fs.Spec.MetadataServer.StandbyCount = nil
+	assert.Nil(t, validateFilesystem(context, fs))
+
+	// subtree pins must be absolute paths on an active rank
+	fs.Spec.MetadataServer.SubtreePins = []cephv1.MDSSubtreePinSpec{{Path: "home", Rank: 0}}
+	assert.NotNil(t, validateFilesystem(context, fs))
+	fs.Spec.MetadataServer.SubtreePins = []cephv1.MDSSubtreePinSpec{{Path: "/home", Rank: 1}}
+	assert.NotNil(t, validateFilesystem(context, fs))
+	fs.Spec.MetadataServer.ActiveCount = 2
+	assert.Nil(t, validateFilesystem(context, fs))
+}
+
+func TestApplySubtreePins(t *testing.T) {
+	var pinned [][]string
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithCombinedOutput: func(debug bool, actionName string, command string, args ...string) (string, error) {
+			pinned = append(pinned, append([]string{command}, args[4:]...))
+			if args[5] == "/fail" {
+				return "No such file or directory", errors.New("exit status 1")
+			}
+			// the image only has python 2
+			if command == "python3" {
+				return "", &exec.CommandError{Err: &osexec.Error{Name: command, Err: osexec.ErrNotFound}}
+			}
+			return "", nil
+		},
+	}
+	context := &clusterd.Context{Executor: executor, ConfigDir: "/var/lib/rook"}
+	fs := cephv1.CephFilesystem{
+		ObjectMeta: metav1.ObjectMeta{Name: "myfs", Namespace: "ns"},
+		Spec: cephv1.FilesystemSpec{
+			MetadataServer: cephv1.MetadataServerSpec{
+				ActiveCount: 2,
+				SubtreePins: []cephv1.MDSSubtreePinSpec{{Path: "/fail", Rank: 1}, {Path: "/home", Rank: 1}},
+			},
+		},
+	}
+
+	// a failed pin is not retried with another interpreter, and does not prevent the others from being applied
+	applySubtreePins(context, fs)
+	assert.Equal(t, 3, len(pinned))
+	assert.Equal(t, []string{"python3", "myfs", "/fail", "1"}, pinned[0])
+	assert.Equal(t, []string{"python3", "myfs", "/home", "1"}, pinned[1])
+	assert.Equal(t, []string{"python", "myfs", "/home", "1"}, pinned[2])
 }
 
 func TestCreateFilesystem(t *testing.T) {
@@ -114,14 +171,14 @@ func TestCreateFilesystem(t *testing.T) {
 	clusterInfo := &cephconfig.ClusterInfo{FSID: "myfsid"}
 
 	// start a basic cluster
-	err := createFilesystem(clusterInfo, context, fs, "v0.1", cephv1.CephVersionSpec{}, false, []metav1.OwnerReference{})
+	err := createFilesystem(clusterInfo, context, fs, "v0.1", cephv1.CephVersionSpec{}, cephv1.DaemonOverride{}, false, "", []metav1.OwnerReference{})
 	assert.Nil(t, err)
 	validateStart(t, context, fs)
 	assert.ElementsMatch(t, []string{}, testopk8s.DeploymentNamesUpdated(deploymentsUpdated))
 	testopk8s.ClearDeploymentsUpdated(deploymentsUpdated)
 
 	// starting again should be a no-op
-	err = createFilesystem(clusterInfo, context, fs, "v0.1", cephv1.CephVersionSpec{}, false, []metav1.OwnerReference{})
+	err = createFilesystem(clusterInfo, context, fs, "v0.1", cephv1.CephVersionSpec{}, cephv1.DaemonOverride{}, false, "", []metav1.OwnerReference{})
 	assert.Nil(t, err)
 	validateStart(t, context, fs)
 	assert.ElementsMatch(t, []string{"rook-ceph-mds-myfs-a", "rook-ceph-mds-myfs-b"}, testopk8s.DeploymentNamesUpdated(deploymentsUpdated))
@@ -142,7 +199,7 @@ func TestCreateFilesystem(t *testing.T) {
 		Clientset: testop.New(3)}
 
 	//Create another filesystem which should fail
-	err = createFilesystem(clusterInfo, context, fs, "v0.1", cephv1.CephVersionSpec{}, false, []metav1.OwnerReference{})
+	err = createFilesystem(clusterInfo, context, fs, "v0.1", cephv1.CephVersionSpec{}, cephv1.DaemonOverride{}, false, "", []metav1.OwnerReference{})
 	assert.Equal(t, "failed to create filesystem myfs: Cannot create multiple filesystems. Enable ROOK_ALLOW_MULTIPLE_FILESYSTEMS env variable to create more than one", err.Error())
 }
 
@@ -179,12 +236,12 @@ func TestCreateNopoolFilesystem(t *testing.T) {
 	clusterInfo := &cephconfig.ClusterInfo{FSID: "myfsid"}
 
 	// start a basic cluster
-	err := createFilesystem(clusterInfo, context, fs, "v0.1", cephv1.CephVersionSpec{}, false, []metav1.OwnerReference{})
+	err := createFilesystem(clusterInfo, context, fs, "v0.1", cephv1.CephVersionSpec{}, cephv1.DaemonOverride{}, false, "", []metav1.OwnerReference{})
 	assert.Nil(t, err)
 	validateStart(t, context, fs)
 
 	// starting again should be a no-op
-	err = createFilesystem(clusterInfo, context, fs, "v0.1", cephv1.CephVersionSpec{}, false, []metav1.OwnerReference{})
+	err = createFilesystem(clusterInfo, context, fs, "v0.1", cephv1.CephVersionSpec{}, cephv1.DaemonOverride{}, false, "", []metav1.OwnerReference{})
 	assert.Nil(t, err)
 	validateStart(t, context, fs)
 
@@ -55,10 +55,13 @@ type Cluster struct {
 	context     *clusterd.Context
 	rookVersion string
 	cephVersion cephv1.CephVersionSpec
+	override    cephv1.DaemonOverride
 	HostNetwork bool
 	fs          cephv1.CephFilesystem
 	fsID        string
 	ownerRefs   []metav1.OwnerReference
+	// DataDirHostPath is the host path under which the crash reports of the mdses are kept
+	DataDirHostPath string
 }
 
 type mdsConfig struct {
@@ -73,6 +76,7 @@ func NewCluster(
 	context *clusterd.Context,
 	rookVersion string,
 	cephVersion cephv1.CephVersionSpec,
+	override cephv1.DaemonOverride,
 	hostNetwork bool,
 	fs cephv1.CephFilesystem,
 	fsdetails *client.CephFilesystemDetails,
@@ -83,6 +87,7 @@ func NewCluster(
 		context:     context,
 		rookVersion: rookVersion,
 		cephVersion: cephVersion,
+		override:    override,
 		HostNetwork: hostNetwork,
 		fs:          fs,
 		fsID:        strconv.Itoa(fsdetails.ID),
@@ -113,8 +118,8 @@ func (c *Cluster) Start() error {
 		}
 	}()
 
-	// Always create double the number of metadata servers to have standby mdses available
-	replicas := c.fs.Spec.MetadataServer.ActiveCount * 2
+	// Create the standby mdses in addition to the active ones
+	replicas := c.fs.Spec.MetadataServer.ActiveCount + StandbyCount(c.fs.Spec.MetadataServer)
 	// keep list of deployments we want so unwanted ones can be deleted later
 	desiredDeployments := map[string]bool{} // improvised set
 	// Create/update deployments
@@ -211,6 +216,15 @@ func (c *Cluster) scaleDownDeployments(replicas int32, desiredDeployments map[st
 	return nil
 }
 
+// StandbyCount returns the number of standby mdses of the filesystem. If not specified, there is one standby for
+// each active mds.
+func StandbyCount(spec cephv1.MetadataServerSpec) int32 {
+	if spec.StandbyCount != nil {
+		return *spec.StandbyCount
+	}
+	return spec.ActiveCount
+}
+
 // DeleteCluster deletes a Ceph mds cluster from Kubernetes.
 func DeleteCluster(context *clusterd.Context, namespace, fsName string) error {
 	// Try to delete all mds deployments and secret keys serving the filesystem, and aggregate
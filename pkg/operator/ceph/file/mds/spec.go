@@ -31,10 +31,10 @@ import (
 
 const (
 	mdsDaemonCommand = "ceph-mds"
-	// MDS cache memory limit should be set to 50-60% of RAM reserved for the MDS container
-	// MDS uses approximately 125% of the value of mds_cache_memory_limit in RAM.
+	// MDS uses approximately 125% of the value of mds_cache_memory_limit in RAM, so the cache is limited to 80% of the
+	// memory target of the MDS container.
 	// Eventually we will tune this automatically: http://tracker.ceph.com/issues/36663
-	mdsCacheMemoryLimitFactor = 0.5
+	mdsCacheMemoryLimitFactor = 0.8
 )
 
 func (c *Cluster) makeDeployment(mdsConfig *mdsConfig) *apps.Deployment {
@@ -50,14 +50,18 @@ func (c *Cluster) makeDeployment(mdsConfig *mdsConfig) *apps.Deployment {
 				c.makeMdsDaemonContainer(mdsConfig),
 			},
 			RestartPolicy: v1.RestartPolicyAlways,
-			Volumes:       opspec.DaemonVolumes(mdsConfig.DataPathMap, mdsConfig.ResourceName),
-			HostNetwork:   c.HostNetwork,
+			Volumes: append(
+				opspec.DaemonVolumes(mdsConfig.DataPathMap, mdsConfig.ResourceName),
+				opspec.CrashVolume(c.DataDirHostPath),
+			),
+			HostNetwork: c.HostNetwork,
 		},
 	}
 	if c.HostNetwork {
 		podSpec.Spec.DNSPolicy = v1.DNSClusterFirstWithHostNet
 	}
 	c.fs.Spec.MetadataServer.Placement.ApplyToPodSpec(&podSpec.Spec)
+	c.override.ApplyToPod(&podSpec.ObjectMeta, &podSpec.Spec, c.cephVersion.Image)
 
 	replicas := int32(1)
 	d := &apps.Deployment{
@@ -68,7 +72,7 @@ func (c *Cluster) makeDeployment(mdsConfig *mdsConfig) *apps.Deployment {
 		},
 		Spec: apps.DeploymentSpec{
 			Selector: &metav1.LabelSelector{
-				MatchLabels: podSpec.Labels,
+				MatchLabels: c.podLabels(mdsConfig),
 			},
 			Template: podSpec,
 			Replicas: &replicas,
@@ -97,9 +101,8 @@ func (c *Cluster) makeMdsDaemonContainer(mdsConfig *mdsConfig) v1.Container {
 
 	// Set mds cache memory limit to the best appropriate value
 	// This is new in Luminous so there is no need to check for a Ceph version
-	if !c.fs.Spec.MetadataServer.Resources.Limits.Memory().IsZero() {
-		mdsCacheMemoryLimit := float64(c.fs.Spec.MetadataServer.Resources.Limits.Memory().Value()) * mdsCacheMemoryLimitFactor
-		args = append(args, config.NewFlag("mds-cache-memory-limit", strconv.Itoa(int(mdsCacheMemoryLimit))))
+	if cacheLimit := cacheMemoryLimit(c.fs.Spec.MetadataServer.Resources); cacheLimit > 0 {
+		args = append(args, config.NewFlag("mds-cache-memory-limit", strconv.FormatInt(cacheLimit, 10)))
 	}
 
 	container := v1.Container{
@@ -107,9 +110,12 @@ func (c *Cluster) makeMdsDaemonContainer(mdsConfig *mdsConfig) v1.Container {
 		Command: []string{
 			"ceph-mds",
 		},
-		Args:         args,
-		Image:        c.cephVersion.Image,
-		VolumeMounts: opspec.DaemonVolumeMounts(mdsConfig.DataPathMap, mdsConfig.ResourceName),
+		Args:  args,
+		Image: c.cephVersion.Image,
+		VolumeMounts: append(
+			opspec.DaemonVolumeMounts(mdsConfig.DataPathMap, mdsConfig.ResourceName),
+			opspec.CrashVolumeMount(),
+		),
 		Env: append(
 			opspec.DaemonEnvVars(c.cephVersion.Image),
 		),
@@ -119,6 +125,12 @@ func (c *Cluster) makeMdsDaemonContainer(mdsConfig *mdsConfig) v1.Container {
 	return container
 }
 
+// cacheMemoryLimit returns the mds cache size derived from the memory target of the container. Zero is returned if the
+// container has no memory set and the Ceph default applies.
+func cacheMemoryLimit(resources v1.ResourceRequirements) int64 {
+	return int64(float64(config.MemoryTarget(resources)) * mdsCacheMemoryLimitFactor)
+}
+
 func (c *Cluster) podLabels(mdsConfig *mdsConfig) map[string]string {
 	labels := opspec.PodLabels(AppName, c.fs.Namespace, "mds", mdsConfig.DaemonID)
 	labels["rook_file_system"] = c.fs.Name
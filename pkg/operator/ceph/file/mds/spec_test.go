@@ -66,6 +66,7 @@ func testDeploymentObject(hostNetwork bool) *apps.Deployment {
 		&clusterd.Context{Clientset: testop.New(1)},
 		"rook/rook:myversion",
 		cephv1.CephVersionSpec{Image: "ceph/ceph:testversion"},
+		cephv1.DaemonOverride{},
 		hostNetwork,
 		fs,
 		&client.CephFilesystemDetails{ID: 15},
@@ -100,3 +101,30 @@ func TestHostNetwork(t *testing.T) {
 	assert.Equal(t, true, d.Spec.Template.Spec.HostNetwork)
 	assert.Equal(t, v1.DNSClusterFirstWithHostNet, d.Spec.Template.Spec.DNSPolicy)
 }
+
+func TestCacheMemoryLimit(t *testing.T) {
+	defer func(ratio float64) { config.MemoryHeadroomRatio = ratio }(config.MemoryHeadroomRatio)
+	config.MemoryHeadroomRatio = 0.2
+
+	// no memory set, the ceph default applies
+	assert.Equal(t, int64(0), cacheMemoryLimit(v1.ResourceRequirements{}))
+
+	// derived from the request if there is no limit
+	resources := v1.ResourceRequirements{
+		Requests: v1.ResourceList{v1.ResourceMemory: *resource.NewQuantity(2147483648, resource.BinarySI)},
+	}
+	assert.Equal(t, int64(1374389534), cacheMemoryLimit(resources))
+
+	// the limit takes precedence
+	resources.Limits = v1.ResourceList{v1.ResourceMemory: *resource.NewQuantity(8589934592, resource.BinarySI)}
+	assert.Equal(t, int64(5497558138), cacheMemoryLimit(resources))
+}
+
+func TestStandbyCount(t *testing.T) {
+	spec := cephv1.MetadataServerSpec{ActiveCount: 2}
+	assert.Equal(t, int32(2), StandbyCount(spec))
+
+	standby := int32(0)
+	spec.StandbyCount = &standby
+	assert.Equal(t, int32(0), StandbyCount(spec))
+}
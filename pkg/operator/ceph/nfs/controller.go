@@ -18,6 +18,7 @@ limitations under the License.
 package nfs
 
 import (
+	"fmt"
 	"reflect"
 
 	"github.com/coreos/pkg/capnslog"
@@ -25,9 +26,15 @@ import (
 	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
 	"github.com/rook/rook/pkg/clusterd"
 	cephconfig "github.com/rook/rook/pkg/daemon/ceph/config"
+	"github.com/rook/rook/pkg/operator/ceph/reconcile"
+	opspec "github.com/rook/rook/pkg/operator/ceph/spec"
+	"github.com/rook/rook/pkg/operator/webhook"
 	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 )
 
 var logger = capnslog.NewPackageLogger("github.com/rook/rook", "op-nfs")
@@ -50,22 +57,36 @@ type CephNFSController struct {
 	cephVersion cephv1.CephVersionSpec
 	hostNetwork bool
 	ownerRef    metav1.OwnerReference
+	queue       *reconcile.Queue
+	reporter    *reconcile.Reporter
+	// the nfs servers last applied, kept to scale and remove the servers. Only accessed by the worker of the queue.
+	applied map[string]*cephv1.CephNFS
+	// the nfs overrides of the cluster, read at each reconcile. Only accessed by the worker of the queue.
+	override cephv1.DaemonOverride
+	// the nfs overrides last applied to the servers, kept to restart the servers when they change
+	appliedOverrides map[string]cephv1.DaemonOverride
 }
 
 // NewNFSCephNFSController create controller for watching NFS custom resources created
-func NewCephNFSController(clusterInfo *cephconfig.ClusterInfo, context *clusterd.Context, rookImage string, cephVersion cephv1.CephVersionSpec, hostNetwork bool, ownerRef metav1.OwnerReference) *CephNFSController {
-	return &CephNFSController{
-		clusterInfo: clusterInfo,
-		context:     context,
-		rookImage:   rookImage,
-		cephVersion: cephVersion,
-		hostNetwork: hostNetwork,
-		ownerRef:    ownerRef,
+func NewCephNFSController(clusterInfo *cephconfig.ClusterInfo, context *clusterd.Context, rookImage string, cephVersion cephv1.CephVersionSpec, hostNetwork bool, ownerRef metav1.OwnerReference, recorder record.EventRecorder) *CephNFSController {
+	c := &CephNFSController{
+		clusterInfo:      clusterInfo,
+		context:          context,
+		rookImage:        rookImage,
+		cephVersion:      cephVersion,
+		hostNetwork:      hostNetwork,
+		ownerRef:         ownerRef,
+		reporter:         reconcile.NewReporter(recorder),
+		applied:          map[string]*cephv1.CephNFS{},
+		appliedOverrides: map[string]cephv1.DaemonOverride{},
 	}
+	c.queue = reconcile.New(CephNFSResource.Name, c.reconcile)
+	return c
 }
 
 // StartWatch watches for instances of CephNFS custom resources and acts on them
 func (c *CephNFSController) StartWatch(namespace string, stopCh chan struct{}) error {
+	c.queue.Start(stopCh)
 
 	resourceHandlerFuncs := cache.ResourceEventHandlerFuncs{
 		AddFunc:    c.onAdd,
@@ -87,10 +108,7 @@ func (c *CephNFSController) onAdd(obj interface{}) {
 		return
 	}
 
-	err := c.upCephNFS(*nfs, 0)
-	if err != nil {
-		logger.Errorf("failed to create NFS Ganesha %s. %+v", nfs.Name, err)
-	}
+	c.queue.Add(nfs)
 }
 
 func (c *CephNFSController) onUpdate(oldObj, newObj interface{}) {
@@ -107,18 +125,7 @@ func (c *CephNFSController) onUpdate(oldObj, newObj interface{}) {
 	}
 
 	logger.Infof("Updating the ganesha server from %d to %d active count", oldNFS.Spec.Server.Active, newNFS.Spec.Server.Active)
-	if oldNFS.Spec.Server.Active < newNFS.Spec.Server.Active {
-		err := c.upCephNFS(*newNFS, oldNFS.Spec.Server.Active)
-		if err != nil {
-			logger.Errorf("Failed to start daemons for CephNFS %s. %+v", newNFS.Name, err)
-		}
-	} else {
-		err := c.downCephNFS(*oldNFS, newNFS.Spec.Server.Active)
-		if err != nil {
-			logger.Errorf("Failed to stop daemons for CephNFS %s. %+v", newNFS.Name, err)
-		}
-	}
-
+	c.queue.Add(newNFS)
 }
 
 func (c *CephNFSController) onDelete(obj interface{}) {
@@ -128,10 +135,68 @@ func (c *CephNFSController) onDelete(obj interface{}) {
 		return
 	}
 
-	err := c.downCephNFS(*nfs, 0)
+	c.queue.Remove(nfs)
+}
+
+// reconcile starts the ganesha servers of the CephNFS, scaling them from the active count last applied, or stops them
+// if the CephNFS was removed
+func (c *CephNFSController) reconcile(namespace, name string) error {
+	key := namespace + "/" + name
+	applied, ok := c.applied[key]
+	overrides, err := opspec.GetDaemonOverrides(c.context, namespace)
+	if err != nil {
+		return fmt.Errorf("failed to get the nfs overrides of nfs %s. %+v", name, err)
+	}
+	c.override = cephv1.GetNFSOverride(overrides)
+	nfs, err := c.context.RookClientset.CephV1().CephNFSs(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to get nfs %s. %+v", name, err)
+		}
+		if !ok {
+			// the nfs was deleted before its servers were started, or while they were failing to start
+			deleted, _ := c.queue.Deleted(namespace, name).(*cephv1.CephNFS)
+			if deleted == nil {
+				return nil
+			}
+			applied = deleted
+		}
+		if err := c.downCephNFS(*applied, 0); err != nil {
+			return c.reporter.Failed(applied, fmt.Errorf("failed to delete nfs %s. %+v", name, err))
+		}
+		c.reporter.Deleted(applied, "deleted the %d ganesha servers of nfs %s", applied.Spec.Server.Active, name)
+		delete(c.applied, key)
+		delete(c.appliedOverrides, key)
+		return nil
+	}
+
+	switch {
+	case !ok:
+		err = c.upCephNFS(*nfs, 0)
+	case applied.Spec.Server.Active < nfs.Spec.Server.Active:
+		err = c.upCephNFS(*nfs, applied.Spec.Server.Active)
+	case applied.Spec.Server.Active > nfs.Spec.Server.Active:
+		err = c.downCephNFS(*applied, nfs.Spec.Server.Active)
+	}
+	if err == nil && ok && !reflect.DeepEqual(c.appliedOverrides[key], c.override) {
+		// the servers started before the overrides changed are restarted with the new overrides
+		running := applied.Spec.Server.Active
+		if nfs.Spec.Server.Active < running {
+			running = nfs.Spec.Server.Active
+		}
+		err = c.restartCephNFS(*nfs, running)
+	}
 	if err != nil {
-		logger.Errorf("failed to delete file system %s. %+v", nfs.Name, err)
+		return c.reporter.Failed(nfs, fmt.Errorf("failed to update the daemons of nfs %s. %+v", nfs.Name, err))
 	}
+	if ok && applied.Spec.Server.Active != nfs.Spec.Server.Active {
+		c.reporter.Normal(nfs, reconcile.ReasonReconciled, "scaled nfs %s from %d to %d ganesha servers", nfs.Name, applied.Spec.Server.Active, nfs.Spec.Server.Active)
+	} else {
+		c.reporter.Succeeded(nfs, "started %d ganesha servers for nfs %s", nfs.Spec.Server.Active, nfs.Name)
+	}
+	c.applied[key] = nfs
+	c.appliedOverrides[key] = c.override
+	return nil
 }
 
 func nfsChanged(oldNFS, newNFS cephv1.NFSGaneshaSpec) bool {
@@ -140,3 +205,13 @@ func nfsChanged(oldNFS, newNFS cephv1.NFSGaneshaSpec) bool {
 	}
 	return false
 }
+
+// Validator returns the validator of the CephNFSes for the validating webhook
+func Validator(context *clusterd.Context) webhook.Validator {
+	return webhook.Validator{
+		NewObject: func() runtime.Object { return &cephv1.CephNFS{} },
+		Validate: func(oldObj, newObj runtime.Object) error {
+			return validateGanesha(context, *newObj.(*cephv1.CephNFS))
+		},
+	}
+}
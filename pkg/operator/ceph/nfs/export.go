@@ -0,0 +1,278 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package nfs for NFS ganesha
+package nfs
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	"github.com/rook/rook/pkg/util/exec"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	defaultAccessType = "RW"
+	defaultSquash     = "No_Root_Squash"
+	maxExportID       = 65535
+)
+
+var (
+	exportIDRegexp = regexp.MustCompile(`Export_ID\s*=\s*(\d+);`)
+	accessTypes    = []string{"RW", "RO", "None"}
+	squashTypes    = []string{"No_Root_Squash", "Root_Squash", "Root_Id_Squash", "All_Squash"}
+)
+
+// rgwCredentials are the keys of the object store user accessing an exported bucket
+type rgwCredentials struct {
+	accessKey string
+	secretKey string
+}
+
+func getExportObject(n cephv1.CephNFS, exportName string) string {
+	return fmt.Sprintf("export-%s-%s", n.Name, exportName)
+}
+
+// getExportConfig renders the ganesha EXPORT block of an export
+func getExportConfig(e cephv1.CephNFSExport, id int, rgw *rgwCredentials) string {
+	accessType := e.Spec.AccessType
+	if accessType == "" {
+		accessType = defaultAccessType
+	}
+	squash := e.Spec.Squash
+	if squash == "" {
+		squash = defaultSquash
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "EXPORT {\n")
+	fmt.Fprintf(&b, "\tExport_ID = %d;\n", id)
+	if e.Spec.CephFS != nil {
+		path := e.Spec.CephFS.Path
+		if path == "" {
+			path = "/"
+		}
+		fmt.Fprintf(&b, "\tPath = %q;\n", path)
+	} else {
+		fmt.Fprintf(&b, "\tPath = %q;\n", e.Spec.RGW.Bucket)
+	}
+	fmt.Fprintf(&b, "\tPseudo = %q;\n", e.Spec.PseudoPath)
+	if len(e.Spec.Clients) > 0 {
+		// only the listed clients are granted access
+		fmt.Fprintf(&b, "\tAccess_Type = \"None\";\n")
+	} else {
+		fmt.Fprintf(&b, "\tAccess_Type = %q;\n", accessType)
+	}
+	fmt.Fprintf(&b, "\tSquash = %q;\n", squash)
+	fmt.Fprintf(&b, "\tProtocols = 4;\n")
+	fmt.Fprintf(&b, "\tTransports = \"TCP\";\n")
+	fmt.Fprintf(&b, "\tFSAL {\n")
+	if e.Spec.CephFS != nil {
+		fmt.Fprintf(&b, "\t\tName = \"CEPH\";\n")
+		fmt.Fprintf(&b, "\t\tUser_Id = %q;\n", userID)
+		fmt.Fprintf(&b, "\t\tFilesystem = %q;\n", e.Spec.CephFS.Filesystem)
+	} else {
+		fmt.Fprintf(&b, "\t\tName = \"RGW\";\n")
+		fmt.Fprintf(&b, "\t\tUser_Id = %q;\n", e.Spec.RGW.User)
+		fmt.Fprintf(&b, "\t\tAccess_Key_Id = %q;\n", rgw.accessKey)
+		fmt.Fprintf(&b, "\t\tSecret_Access_Key = %q;\n", rgw.secretKey)
+	}
+	fmt.Fprintf(&b, "\t}\n")
+	if len(e.Spec.Clients) > 0 {
+		fmt.Fprintf(&b, "\tCLIENT {\n")
+		fmt.Fprintf(&b, "\t\tClients = %s;\n", strings.Join(e.Spec.Clients, ", "))
+		fmt.Fprintf(&b, "\t\tAccess_Type = %q;\n", accessType)
+		fmt.Fprintf(&b, "\t}\n")
+	}
+	fmt.Fprintf(&b, "}\n")
+	return b.String()
+}
+
+// getExportsConfig renders the config object of a ganesha server, which includes the config of all the exports
+func getExportsConfig(n cephv1.CephNFS, exports []cephv1.CephNFSExport) string {
+	var b bytes.Buffer
+	for _, e := range exports {
+		fmt.Fprintf(&b, "%%url\t%s\n", getRadosObjectURL(n, getExportObject(n, e.Name)))
+	}
+	return b.String()
+}
+
+func parseExportID(config string) (int, bool) {
+	match := exportIDRegexp.FindStringSubmatch(config)
+	if match == nil {
+		return 0, false
+	}
+	id, err := strconv.Atoi(match[1])
+	if err != nil {
+		return 0, false
+	}
+	return id, true
+}
+
+// getExportID returns the id the export was stored with, or the lowest id not used by another export of the server
+func getExportID(context *clusterd.Context, n cephv1.CephNFS, e cephv1.CephNFSExport, exports []cephv1.CephNFSExport) (int, error) {
+	used := map[int]bool{}
+	for _, other := range exports {
+		config, err := getRADOSObject(context, n, getExportObject(n, other.Name))
+		if err != nil {
+			// the export is not stored yet
+			continue
+		}
+		id, ok := parseExportID(config)
+		if !ok {
+			continue
+		}
+		if other.Name == e.Name {
+			return id, nil
+		}
+		used[id] = true
+	}
+
+	for id := 1; id <= maxExportID; id++ {
+		if !used[id] {
+			return id, nil
+		}
+	}
+	return 0, fmt.Errorf("no export id available on nfs %s", n.Name)
+}
+
+// listExports returns the exports of the server sorted by name
+func listExports(context *clusterd.Context, n cephv1.CephNFS) ([]cephv1.CephNFSExport, error) {
+	list, err := context.RookClientset.CephV1().CephNFSExports(n.Namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nfs exports. %+v", err)
+	}
+	var exports []cephv1.CephNFSExport
+	for _, e := range list.Items {
+		if e.Spec.NFS == n.Name {
+			exports = append(exports, e)
+		}
+	}
+	sort.Slice(exports, func(i, j int) bool { return exports[i].Name < exports[j].Name })
+	return exports, nil
+}
+
+// updateExportsConfig stores the list of exports in the config object of every ganesha server and
+// notifies the servers watching the objects so they reload their exports
+func updateExportsConfig(context *clusterd.Context, n cephv1.CephNFS) error {
+	exports, err := listExports(context, n)
+	if err != nil {
+		return err
+	}
+
+	config := getExportsConfig(n, exports)
+	for i := 0; i < n.Spec.Server.Active; i++ {
+		object := getGaneshaConfigObject(getNFSNodeID(n, k8sutil.IndexToName(i)))
+		if err := putRADOSObject(context, n, object, config); err != nil {
+			return fmt.Errorf("failed to store exports config for nfs %s. %+v", n.Name, err)
+		}
+		if err := notifyRADOSObject(context, n, object); err != nil {
+			// the server may not be running yet, it will load the exports when it starts
+			logger.Warningf("failed to notify nfs server %s of the exports update. %+v", object, err)
+		}
+	}
+	logger.Infof("updated nfs %s with %d exports", n.Name, len(exports))
+	return nil
+}
+
+func radosArgs(n cephv1.CephNFS, args ...string) []string {
+	return append([]string{"--pool", n.Spec.RADOS.Pool, "--namespace", n.Spec.RADOS.Namespace}, args...)
+}
+
+func getRADOSObject(context *clusterd.Context, n cephv1.CephNFS, object string) (string, error) {
+	return context.Executor.ExecuteCommandWithOutput(true, "", "rados", radosArgs(n, "get", object, "-")...)
+}
+
+func putRADOSObject(context *clusterd.Context, n cephv1.CephNFS, object, content string) error {
+	f, err := ioutil.TempFile("", "rook-nfs-")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file. %+v", err)
+	}
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(content)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("failed to write temp file. %+v", err)
+	}
+	return context.Executor.ExecuteCommand(false, "", "rados", radosArgs(n, "put", object, f.Name())...)
+}
+
+func removeRADOSObject(context *clusterd.Context, n cephv1.CephNFS, object string) error {
+	err := context.Executor.ExecuteCommand(false, "", "rados", radosArgs(n, "rm", object)...)
+	if err != nil {
+		cmdErr, ok := err.(*exec.CommandError)
+		if ok && cmdErr.ExitStatus() == int(syscall.ENOENT) {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+func notifyRADOSObject(context *clusterd.Context, n cephv1.CephNFS, object string) error {
+	return context.Executor.ExecuteCommand(false, "", "rados", radosArgs(n, "notify", object, object)...)
+}
+
+func validateExport(e cephv1.CephNFSExport) error {
+	if e.Name == "" {
+		return fmt.Errorf("missing name")
+	}
+	if e.Namespace == "" {
+		return fmt.Errorf("missing namespace")
+	}
+	if e.Spec.NFS == "" {
+		return fmt.Errorf("missing nfs")
+	}
+	if !strings.HasPrefix(e.Spec.PseudoPath, "/") {
+		return fmt.Errorf("pseudoPath must be an absolute path")
+	}
+	if (e.Spec.CephFS == nil) == (e.Spec.RGW == nil) {
+		return fmt.Errorf("exactly one of cephfs or rgw must be set")
+	}
+	if e.Spec.CephFS != nil && e.Spec.CephFS.Filesystem == "" {
+		return fmt.Errorf("missing cephfs.filesystem")
+	}
+	if e.Spec.RGW != nil && (e.Spec.RGW.Store == "" || e.Spec.RGW.Bucket == "" || e.Spec.RGW.User == "") {
+		return fmt.Errorf("rgw.store, rgw.bucket and rgw.user are required")
+	}
+	if e.Spec.AccessType != "" && !contains(accessTypes, e.Spec.AccessType) {
+		return fmt.Errorf("invalid accessType %s. must be one of %v", e.Spec.AccessType, accessTypes)
+	}
+	if e.Spec.Squash != "" && !contains(squashTypes, e.Spec.Squash) {
+		return fmt.Errorf("invalid squash %s. must be one of %v", e.Spec.Squash, squashTypes)
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,239 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package nfs to manage a NFS Ganesha server
+package nfs
+
+import (
+	"fmt"
+	"reflect"
+
+	opkit "github.com/rook/operator-kit"
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	"github.com/rook/rook/pkg/clusterd"
+	cephconfig "github.com/rook/rook/pkg/daemon/ceph/config"
+	"github.com/rook/rook/pkg/operator/ceph/reconcile"
+	"github.com/rook/rook/pkg/operator/webhook"
+	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
+)
+
+// CephNFSExportResource represents the nfs export custom resource
+var CephNFSExportResource = opkit.CustomResource{
+	Name:    "cephnfsexport",
+	Plural:  "cephnfsexports",
+	Group:   cephv1.CustomResourceGroup,
+	Version: cephv1.Version,
+	Scope:   apiextensionsv1beta1.NamespaceScoped,
+	Kind:    reflect.TypeOf(cephv1.CephNFSExport{}).Name(),
+}
+
+// CephNFSExportController represents a controller for NFS export custom resources
+type CephNFSExportController struct {
+	clusterInfo *cephconfig.ClusterInfo
+	context     *clusterd.Context
+	queue       *reconcile.Queue
+	// the exports last applied, kept to move and remove the exports. Only accessed by the worker of the queue.
+	applied map[string]*cephv1.CephNFSExport
+}
+
+// NewCephNFSExportController create controller for watching NFS export custom resources created
+func NewCephNFSExportController(clusterInfo *cephconfig.ClusterInfo, context *clusterd.Context) *CephNFSExportController {
+	c := &CephNFSExportController{
+		clusterInfo: clusterInfo,
+		context:     context,
+		applied:     map[string]*cephv1.CephNFSExport{},
+	}
+	c.queue = reconcile.New(CephNFSExportResource.Name, c.reconcile)
+	return c
+}
+
+// StartWatch watches for instances of CephNFSExport custom resources and acts on them
+func (c *CephNFSExportController) StartWatch(namespace string, stopCh chan struct{}) error {
+	c.queue.Start(stopCh)
+
+	resourceHandlerFuncs := cache.ResourceEventHandlerFuncs{
+		AddFunc:    c.onAdd,
+		UpdateFunc: c.onUpdate,
+		DeleteFunc: c.onDelete,
+	}
+
+	logger.Infof("start watching ceph nfs export resource in namespace %s", namespace)
+	watcher := opkit.NewWatcher(CephNFSExportResource, namespace, resourceHandlerFuncs, c.context.RookClientset.CephV1().RESTClient())
+	go watcher.Watch(&cephv1.CephNFSExport{}, stopCh)
+
+	return nil
+}
+
+func (c *CephNFSExportController) onAdd(obj interface{}) {
+	export := obj.(*cephv1.CephNFSExport).DeepCopy()
+	if !c.clusterInfo.CephVersion.IsAtLeastNautilus() {
+		logger.Errorf("Ceph NFS is only supported with Nautilus or newer. CRD %s will be ignored.", export.Name)
+		return
+	}
+
+	c.queue.Add(export)
+}
+
+func (c *CephNFSExportController) onUpdate(oldObj, newObj interface{}) {
+	oldExport := oldObj.(*cephv1.CephNFSExport).DeepCopy()
+	newExport := newObj.(*cephv1.CephNFSExport).DeepCopy()
+	if !c.clusterInfo.CephVersion.IsAtLeastNautilus() {
+		logger.Errorf("Ceph NFS is only supported with Nautilus or newer. CRD %s will be ignored.", newExport.Name)
+		return
+	}
+
+	if reflect.DeepEqual(oldExport.Spec, newExport.Spec) {
+		logger.Debugf("nfs export %s not updated", newExport.Name)
+		return
+	}
+
+	c.queue.Add(newExport)
+}
+
+func (c *CephNFSExportController) onDelete(obj interface{}) {
+	export := obj.(*cephv1.CephNFSExport).DeepCopy()
+	if !c.clusterInfo.CephVersion.IsAtLeastNautilus() {
+		logger.Errorf("Ceph NFS is only supported with Nautilus or newer. CRD %s cleanup will be ignored.", export.Name)
+		return
+	}
+
+	c.queue.Remove(export)
+}
+
+// reconcile stores the export and adds it to the ganesha servers, moving it from the nfs it was last applied to, or
+// removes the export if the CephNFSExport was removed
+func (c *CephNFSExportController) reconcile(namespace, name string) error {
+	key := namespace + "/" + name
+	applied, ok := c.applied[key]
+	export, err := c.context.RookClientset.CephV1().CephNFSExports(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to get nfs export %s. %+v", name, err)
+		}
+		if !ok {
+			return nil
+		}
+		if err := c.removeExport(*applied); err != nil {
+			return fmt.Errorf("failed to delete nfs export %s. %+v", name, err)
+		}
+		delete(c.applied, key)
+		return nil
+	}
+
+	if ok && applied.Spec.NFS != export.Spec.NFS {
+		logger.Infof("moving nfs export %s from nfs %s to %s", export.Name, applied.Spec.NFS, export.Spec.NFS)
+		if err := c.removeExport(*applied); err != nil {
+			return fmt.Errorf("failed to remove nfs export %s from nfs %s. %+v", applied.Name, applied.Spec.NFS, err)
+		}
+		delete(c.applied, key)
+	}
+	if err := c.createOrUpdateExport(*export); err != nil {
+		return fmt.Errorf("failed to update nfs export %s. %+v", export.Name, err)
+	}
+	c.applied[key] = export
+	return nil
+}
+
+// Store the export config and add it to the ganesha servers
+func (c *CephNFSExportController) createOrUpdateExport(e cephv1.CephNFSExport) error {
+	if err := validateExport(e); err != nil {
+		return fmt.Errorf("invalid nfs export %s. %+v", e.Name, err)
+	}
+
+	n, err := c.context.RookClientset.CephV1().CephNFSs(e.Namespace).Get(e.Spec.NFS, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get nfs %s. %+v", e.Spec.NFS, err)
+	}
+
+	exports, err := listExports(c.context, *n)
+	if err != nil {
+		return err
+	}
+	id, err := getExportID(c.context, *n, e, exports)
+	if err != nil {
+		return err
+	}
+
+	var rgw *rgwCredentials
+	if e.Spec.RGW != nil {
+		rgw, err = c.getRGWCredentials(e)
+		if err != nil {
+			return err
+		}
+	}
+
+	logger.Infof("storing nfs export %s with id %d in nfs %s", e.Name, id, n.Name)
+	if err := putRADOSObject(c.context, *n, getExportObject(*n, e.Name), getExportConfig(e, id, rgw)); err != nil {
+		return fmt.Errorf("failed to store export config. %+v", err)
+	}
+
+	return updateExportsConfig(c.context, *n)
+}
+
+// Remove the export from the ganesha servers and delete its config
+func (c *CephNFSExportController) removeExport(e cephv1.CephNFSExport) error {
+	n, err := c.context.RookClientset.CephV1().CephNFSs(e.Namespace).Get(e.Spec.NFS, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			logger.Infof("nfs %s of export %s does not exist", e.Spec.NFS, e.Name)
+			return nil
+		}
+		return fmt.Errorf("failed to get nfs %s. %+v", e.Spec.NFS, err)
+	}
+
+	// stop serving the export before its config is deleted
+	if err := updateExportsConfig(c.context, *n); err != nil {
+		return err
+	}
+	if err := removeRADOSObject(c.context, *n, getExportObject(*n, e.Name)); err != nil {
+		return fmt.Errorf("failed to remove export config. %+v", err)
+	}
+
+	logger.Infof("removed nfs export %s from nfs %s", e.Name, n.Name)
+	return nil
+}
+
+// getRGWCredentials reads the keys of the object store user from the secret created for the user
+func (c *CephNFSExportController) getRGWCredentials(e cephv1.CephNFSExport) (*rgwCredentials, error) {
+	name := fmt.Sprintf("rook-ceph-object-user-%s-%s", e.Spec.RGW.Store, e.Spec.RGW.User)
+	secret, err := c.context.Clientset.CoreV1().Secrets(e.Namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get keys of object store user %s. %+v", e.Spec.RGW.User, err)
+	}
+	accessKey, ok := secret.Data["AccessKey"]
+	if !ok {
+		return nil, fmt.Errorf("AccessKey not found in secret %s", name)
+	}
+	secretKey, ok := secret.Data["SecretKey"]
+	if !ok {
+		return nil, fmt.Errorf("SecretKey not found in secret %s", name)
+	}
+	return &rgwCredentials{accessKey: string(accessKey), secretKey: string(secretKey)}, nil
+}
+
+// ExportValidator returns the validator of the CephNFSExports for the validating webhook
+func ExportValidator() webhook.Validator {
+	return webhook.Validator{
+		NewObject: func() runtime.Object { return &cephv1.CephNFSExport{} },
+		Validate: func(oldObj, newObj runtime.Object) error {
+			return validateExport(*newObj.(*cephv1.CephNFSExport))
+		},
+	}
+}
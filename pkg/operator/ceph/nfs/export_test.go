@@ -0,0 +1,146 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfs
+
+import (
+	"fmt"
+	"testing"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	"github.com/rook/rook/pkg/clusterd"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newTestNFS() cephv1.CephNFS {
+	return cephv1.CephNFS{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-nfs", Namespace: "rook-ceph"},
+		Spec: cephv1.NFSGaneshaSpec{
+			RADOS:  cephv1.GaneshaRADOSSpec{Pool: "nfs-pool", Namespace: "nfs-ns"},
+			Server: cephv1.GaneshaServerSpec{Active: 2},
+		},
+	}
+}
+
+func newTestExport(name string) cephv1.CephNFSExport {
+	return cephv1.CephNFSExport{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "rook-ceph"},
+		Spec: cephv1.NFSExportSpec{
+			NFS:        "my-nfs",
+			PseudoPath: "/" + name,
+			CephFS:     &cephv1.NFSExportCephFSSpec{Filesystem: "myfs", Path: "/volumes/" + name},
+		},
+	}
+}
+
+func TestExportConfig(t *testing.T) {
+	e := newTestExport("share")
+	config := getExportConfig(e, 3, nil)
+	assert.Contains(t, config, "\tExport_ID = 3;\n")
+	assert.Contains(t, config, "\tPath = \"/volumes/share\";\n")
+	assert.Contains(t, config, "\tPseudo = \"/share\";\n")
+	assert.Contains(t, config, "\tAccess_Type = \"RW\";\n")
+	assert.Contains(t, config, "\tSquash = \"No_Root_Squash\";\n")
+	assert.Contains(t, config, "\t\tName = \"CEPH\";\n")
+	assert.Contains(t, config, "\t\tFilesystem = \"myfs\";\n")
+	assert.NotContains(t, config, "CLIENT")
+
+	id, ok := parseExportID(config)
+	assert.True(t, ok)
+	assert.Equal(t, 3, id)
+
+	// restricted to clients
+	e.Spec.AccessType = "RO"
+	e.Spec.Clients = []string{"10.0.0.0/8", "192.168.1.5"}
+	config = getExportConfig(e, 3, nil)
+	assert.Contains(t, config, "\tAccess_Type = \"None\";\n")
+	assert.Contains(t, config, "\t\tClients = 10.0.0.0/8, 192.168.1.5;\n\t\tAccess_Type = \"RO\";\n")
+
+	// rgw bucket
+	e = newTestExport("bucket")
+	e.Spec.CephFS = nil
+	e.Spec.RGW = &cephv1.NFSExportRGWSpec{Store: "my-store", Bucket: "my-bucket", User: "my-user"}
+	config = getExportConfig(e, 1, &rgwCredentials{accessKey: "access", secretKey: "secret"})
+	assert.Contains(t, config, "\tPath = \"my-bucket\";\n")
+	assert.Contains(t, config, "\t\tName = \"RGW\";\n")
+	assert.Contains(t, config, "\t\tUser_Id = \"my-user\";\n")
+	assert.Contains(t, config, "\t\tAccess_Key_Id = \"access\";\n")
+	assert.Contains(t, config, "\t\tSecret_Access_Key = \"secret\";\n")
+
+	_, ok = parseExportID("")
+	assert.False(t, ok)
+}
+
+func TestExportsConfig(t *testing.T) {
+	n := newTestNFS()
+	config := getExportsConfig(n, []cephv1.CephNFSExport{newTestExport("a"), newTestExport("b")})
+	assert.Equal(t, "%url\trados://nfs-pool/nfs-ns/export-my-nfs-a\n%url\trados://nfs-pool/nfs-ns/export-my-nfs-b\n", config)
+	assert.Equal(t, "", getExportsConfig(n, nil))
+}
+
+func TestExportID(t *testing.T) {
+	stored := map[string]int{"export-my-nfs-a": 1, "export-my-nfs-c": 3}
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName string, command string, args ...string) (string, error) {
+			assert.Equal(t, "rados", command)
+			assert.Equal(t, []string{"--pool", "nfs-pool", "--namespace", "nfs-ns", "get"}, args[:5])
+			id, ok := stored[args[5]]
+			if !ok {
+				return "", fmt.Errorf("no such object")
+			}
+			return getExportConfig(newTestExport("x"), id, nil), nil
+		},
+	}
+	context := &clusterd.Context{Executor: executor}
+	n := newTestNFS()
+	exports := []cephv1.CephNFSExport{newTestExport("a"), newTestExport("b"), newTestExport("c"), newTestExport("d")}
+
+	// an export keeps its stored id
+	id, err := getExportID(context, n, exports[2], exports)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, id)
+
+	// a new export gets the lowest free id
+	id, err = getExportID(context, n, exports[1], exports)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, id)
+}
+
+func TestValidateExport(t *testing.T) {
+	e := newTestExport("share")
+	assert.Nil(t, validateExport(e))
+
+	e.Spec.PseudoPath = "share"
+	assert.NotNil(t, validateExport(e))
+
+	e = newTestExport("share")
+	e.Spec.RGW = &cephv1.NFSExportRGWSpec{Store: "s", Bucket: "b", User: "u"}
+	assert.NotNil(t, validateExport(e))
+	e.Spec.CephFS = nil
+	assert.Nil(t, validateExport(e))
+
+	e = newTestExport("share")
+	e.Spec.AccessType = "rw"
+	assert.NotNil(t, validateExport(e))
+
+	e = newTestExport("share")
+	e.Spec.Squash = "Root_Squash"
+	assert.Nil(t, validateExport(e))
+	e.Spec.Squash = "root"
+	assert.NotNil(t, validateExport(e))
+}
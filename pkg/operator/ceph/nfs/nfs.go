@@ -34,6 +34,9 @@ import (
 
 const (
 	ganeshaRadosGraceCmd = "ganesha-rados-grace"
+	// graceFailedReason is the reason of the events emitted when a ganesha server can't be added to or removed from
+	// the grace database
+	graceFailedReason = "GraceFailed"
 )
 
 // Create the ganesha server
@@ -81,6 +84,11 @@ func (c *CephNFSController) upCephNFS(n cephv1.CephNFS, oldActive int) error {
 		}
 	}
 
+	// new servers need the config of the exports that were already created
+	if err := updateExportsConfig(c.context, n); err != nil {
+		return fmt.Errorf("failed to configure nfs exports. %+v", err)
+	}
+
 	return nil
 }
 
@@ -101,7 +109,7 @@ func (c *CephNFSController) addServerToDatabase(n cephv1.CephNFS, name string) e
 	logger.Infof("Adding ganesha %s to grace db", name)
 
 	if err := c.runGaneshaRadosGraceJob(n, name, "add", 10*time.Minute); err != nil {
-		logger.Errorf("failed to add %s to grace db. %+v", name, err)
+		c.reporter.Warning(&n, graceFailedReason, "failed to add ganesha server %s to the grace database. %+v", name, err)
 	}
 	return nil
 }
@@ -110,7 +118,7 @@ func (c *CephNFSController) removeServerFromDatabase(n cephv1.CephNFS, name stri
 	logger.Infof("Removing ganesha %s from grace db", name)
 
 	if err := c.runGaneshaRadosGraceJob(n, name, "remove", 10*time.Minute); err != nil {
-		logger.Errorf("failed to remmove %s from grace db. %+v", name, err)
+		c.reporter.Warning(&n, graceFailedReason, "failed to remove ganesha server %s from the grace database. %+v", name, err)
 	}
 	return nil
 }
@@ -169,6 +177,7 @@ func (c *CephNFSController) runGaneshaRadosGraceJob(n cephv1.CephNFS, name, acti
 			},
 		},
 	}
+	c.override.ApplyToPod(&job.Spec.Template.ObjectMeta, &job.Spec.Template.Spec, c.cephVersion.Image)
 	k8sutil.SetOwnerRef(c.context.Clientset, n.Namespace, &job.ObjectMeta, &c.ownerRef)
 
 	// run the job to detect the version
@@ -195,7 +204,7 @@ func (c *CephNFSController) generateConfig(n cephv1.CephNFS, name string) (strin
 	}
 	configMap := &v1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      fmt.Sprintf("%s-%s-%s", appName, n.Name, name),
+			Name:      configMapName(n, name),
 			Namespace: n.Namespace,
 			Labels:    getLabels(n, name),
 		},
@@ -213,6 +222,23 @@ func (c *CephNFSController) generateConfig(n cephv1.CephNFS, name string) (strin
 	return configMap.Name, nil
 }
 
+func configMapName(n cephv1.CephNFS, name string) string {
+	return fmt.Sprintf("%s-%s-%s", appName, n.Name, name)
+}
+
+// restartCephNFS updates the deployments of the first running ganesha servers, restarting them with the overrides of the
+// cluster changed since they started
+func (c *CephNFSController) restartCephNFS(n cephv1.CephNFS, active int) error {
+	for i := 0; i < active; i++ {
+		name := k8sutil.IndexToName(i)
+		deployment := c.makeDeployment(n, name, configMapName(n, name))
+		if _, err := k8sutil.UpdateDeploymentAndWait(c.context, deployment, n.Namespace); err != nil {
+			return fmt.Errorf("failed to update ganesha deployment %s. %+v", deployment.Name, err)
+		}
+	}
+	return nil
+}
+
 // Delete the ganesha server
 func (c *CephNFSController) downCephNFS(n cephv1.CephNFS, newActive int) error {
 	for i := n.Spec.Server.Active - 1; i >= newActive; i-- {
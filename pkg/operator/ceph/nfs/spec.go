@@ -116,6 +116,7 @@ func (c *CephNFSController) makeDeployment(n cephv1.CephNFS, name, configName st
 		},
 		Spec: podSpec,
 	}
+	c.override.ApplyToPod(&podTemplateSpec.ObjectMeta, &podTemplateSpec.Spec, c.cephVersion.Image)
 
 	// Multiple replicas of the nfs service would be handled by creating a service and a new deployment for each one, rather than increasing the pod count here
 	replicas := int32(1)
@@ -28,8 +28,10 @@ import (
 )
 
 const (
+	// rgwUser is the user shared by the rgw of all the object stores
+	rgwUser         = "client.radosgw.gateway"
 	keyringTemplate = `
-[client.radosgw.gateway]
+[%s]
 key = %s
 caps mon = "allow rw"
 caps osd = "allow rwx"
@@ -75,12 +77,11 @@ func (c *clusterConfig) portString() string {
 }
 
 func (c *clusterConfig) generateKeyring(replicationControllerOwnerRef *metav1.OwnerReference) error {
-	user := "client.radosgw.gateway"
 	/* TODO: this says `osd allow rwx` while template says `osd allow *`; which is correct? */
 	access := []string{"osd", "allow rwx", "mon", "allow rw"}
 	s := keyring.GetSecretStore(c.context, c.store.Namespace, replicationControllerOwnerRef)
 
-	key, err := s.GenerateKey(c.instanceName(), user, access)
+	key, err := s.GenerateKey(c.instanceName(), c.user, access)
 	if err != nil {
 		return err
 	}
@@ -95,6 +96,6 @@ func (c *clusterConfig) generateKeyring(replicationControllerOwnerRef *metav1.Ow
 		}
 	}
 
-	keyring := fmt.Sprintf(keyringTemplate, key)
+	keyring := fmt.Sprintf(keyringTemplate, c.user, key)
 	return s.CreateOrUpdate(c.instanceName(), keyring)
 }
@@ -27,11 +27,17 @@ import (
 	"github.com/rook/rook/pkg/clusterd"
 	daemonconfig "github.com/rook/rook/pkg/daemon/ceph/config"
 	cephconfig "github.com/rook/rook/pkg/operator/ceph/config"
+	"github.com/rook/rook/pkg/operator/ceph/deletion"
 	"github.com/rook/rook/pkg/operator/ceph/pool"
+	"github.com/rook/rook/pkg/operator/ceph/reconcile"
+	opspec "github.com/rook/rook/pkg/operator/ceph/spec"
+	"github.com/rook/rook/pkg/operator/webhook"
 	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 )
 
 var logger = capnslog.NewPackageLogger("github.com/rook/rook", "op-object")
@@ -62,9 +68,20 @@ type ObjectStoreController struct {
 	rookImage   string
 	cephVersion cephv1.CephVersionSpec
 	hostNetwork bool
-	ownerRef    metav1.OwnerReference
+	// dataDirHostPath is the host path under which the crash reports of the rgws are kept
+	dataDirHostPath string
+	ownerRef        metav1.OwnerReference
+	guard           *deletion.Guard
+	queue           *reconcile.Queue
+	reporter        *reconcile.Reporter
+	// the specs last applied to the object stores, only accessed by the worker of the queue
+	applied map[string]cephv1.ObjectStoreSpec
+	// the rgw overrides of the cluster last applied to the object stores, only accessed by the worker of the queue
+	appliedOverrides map[string]cephv1.DaemonOverride
 }
 
+var finalizerName = fmt.Sprintf("%s.%s", ObjectStoreResource.Name, ObjectStoreResource.Group)
+
 // NewObjectStoreController create controller for watching object store custom resources created
 func NewObjectStoreController(
 	clusterInfo *daemonconfig.ClusterInfo,
@@ -72,20 +89,30 @@ func NewObjectStoreController(
 	rookImage string,
 	cephVersion cephv1.CephVersionSpec,
 	hostNetwork bool,
+	dataDirHostPath string,
 	ownerRef metav1.OwnerReference,
+	recorder record.EventRecorder,
 ) *ObjectStoreController {
-	return &ObjectStoreController{
-		clusterInfo: clusterInfo,
-		context:     context,
-		rookImage:   rookImage,
-		cephVersion: cephVersion,
-		hostNetwork: hostNetwork,
-		ownerRef:    ownerRef,
+	c := &ObjectStoreController{
+		clusterInfo:      clusterInfo,
+		context:          context,
+		rookImage:        rookImage,
+		cephVersion:      cephVersion,
+		hostNetwork:      hostNetwork,
+		dataDirHostPath:  dataDirHostPath,
+		ownerRef:         ownerRef,
+		reporter:         reconcile.NewReporter(recorder),
+		applied:          map[string]cephv1.ObjectStoreSpec{},
+		appliedOverrides: map[string]cephv1.DaemonOverride{},
 	}
+	c.guard = deletion.NewGuard(context, &objectStoreClient{context: context}, "object store", recorder, c.reporter, finalizerName)
+	c.queue = reconcile.New(ObjectStoreResource.Name, c.reconcile)
+	return c
 }
 
 // StartWatch watches for instances of ObjectStore custom resources and acts on them
 func (c *ObjectStoreController) StartWatch(namespace string, stopCh chan struct{}) error {
+	c.queue.Start(stopCh)
 
 	resourceHandlerFuncs := cache.ResourceEventHandlerFuncs{
 		AddFunc:    c.onAdd,
@@ -117,19 +144,7 @@ func (c *ObjectStoreController) onAdd(obj interface{}) {
 		return
 	}
 
-	cfg := clusterConfig{
-		clusterInfo: c.clusterInfo,
-		context:     c.context,
-		store:       *objectstore,
-		rookVersion: c.rookImage,
-		cephVersion: c.cephVersion,
-		hostNetwork: c.hostNetwork,
-		ownerRefs:   c.storeOwners(objectstore),
-		DataPathMap: cephconfig.NewStatelessDaemonDataPathMap(cephconfig.RgwType, objectstore.Name),
-	}
-	if err = cfg.createStore(); err != nil {
-		logger.Errorf("failed to create object store %s. %+v", objectstore.Name, err)
-	}
+	c.queue.Add(objectstore)
 }
 
 func (c *ObjectStoreController) onUpdate(oldObj, newObj interface{}) {
@@ -152,25 +167,75 @@ func (c *ObjectStoreController) onUpdate(oldObj, newObj interface{}) {
 		return
 	}
 
+	if newStore.DeletionTimestamp != nil {
+		c.queue.Add(newStore)
+		return
+	}
+
 	if !storeChanged(oldStore.Spec, newStore.Spec) {
 		logger.Debugf("object store %s did not change", newStore.Name)
 		return
 	}
 
 	logger.Infof("applying object store %s changes", newStore.Name)
+	c.queue.Add(newStore)
+}
+
+// reconcile creates the object store of the CephObjectStore, or updates it if the spec changed since it was last
+// applied. The object store is deleted if the CephObjectStore is being deleted.
+func (c *ObjectStoreController) reconcile(namespace, name string) error {
+	key := namespace + "/" + name
+	store, err := c.context.RookClientset.CephV1().CephObjectStores(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			delete(c.applied, key)
+			delete(c.appliedOverrides, key)
+			return nil
+		}
+		return fmt.Errorf("failed to get object store %s. %+v", name, err)
+	}
+
+	if store.DeletionTimestamp != nil {
+		delete(c.applied, key)
+		delete(c.appliedOverrides, key)
+		return c.handleDeletion(store)
+	}
+	if err := c.guard.EnsureFinalizer(store); err != nil {
+		return c.reporter.Failed(store, fmt.Errorf("failed to add finalizer to object store %s. %+v", store.Name, err))
+	}
+	overrides, err := opspec.GetDaemonOverrides(c.context, namespace)
+	if err != nil {
+		return c.reporter.Failed(store, fmt.Errorf("failed to get the rgw overrides of object store %s. %+v", store.Name, err))
+	}
+	override := cephv1.GetRGWOverride(overrides)
+
 	cfg := clusterConfig{
-		c.clusterInfo,
-		c.context,
-		*newStore,
-		c.rookImage,
-		c.cephVersion,
-		c.hostNetwork,
-		c.storeOwners(newStore),
-		cephconfig.NewStatelessDaemonDataPathMap(cephconfig.RgwType, newStore.Name),
-	}
-	if err = cfg.updateStore(); err != nil {
-		logger.Errorf("failed to create (modify) object store %s. %+v", newStore.Name, err)
+		clusterInfo:     c.clusterInfo,
+		context:         c.context,
+		store:           *store,
+		rookVersion:     c.rookImage,
+		cephVersion:     c.cephVersion,
+		override:        override,
+		hostNetwork:     c.hostNetwork,
+		dataDirHostPath: c.dataDirHostPath,
+		ownerRefs:       c.storeOwners(store),
+		DataPathMap:     cephconfig.NewStatelessDaemonDataPathMap(cephconfig.RgwType, store.Name),
+	}
+	// the update restarts the rgw pods, only run it when the spec changed
+	if applied, ok := c.applied[key]; ok && (storeChanged(applied, store.Spec) || overrideChanged(c.appliedOverrides[key], override)) {
+		if err := cfg.updateStore(); err != nil {
+			return c.reporter.Failed(store, fmt.Errorf("failed to update object store %s. %+v", store.Name, err))
+		}
+		c.reporter.Normal(store, reconcile.ReasonReconciled, "updated object store %s and restarted its rgw pods", store.Name)
+	} else {
+		if err := cfg.createStore(); err != nil {
+			return c.reporter.Failed(store, fmt.Errorf("failed to create object store %s. %+v", store.Name, err))
+		}
+		c.reporter.Succeeded(store, "created object store %s with its realm, zone group, zone and pools", store.Name)
 	}
+	c.applied[key] = store.Spec
+	c.appliedOverrides[key] = override
+	return nil
 }
 
 func (c *ObjectStoreController) onDelete(obj interface{}) {
@@ -185,10 +250,60 @@ func (c *ObjectStoreController) onDelete(obj interface{}) {
 		return
 	}
 
-	cfg := clusterConfig{context: c.context, store: *objectstore}
-	if err = cfg.deleteStore(); err != nil {
-		logger.Errorf("failed to delete object store %s. %+v", objectstore.Name, err)
+	// the object store was deleted, or preserved, before the finalizer was removed
+	logger.Infof("object store %s removed from namespace %s", objectstore.Name, objectstore.Namespace)
+	c.queue.Remove(objectstore)
+}
+
+// handleDeletion deletes the object store of a CephObjectStore marked for deletion unless it still has buckets,
+// then removes the finalizer so the CephObjectStore goes away. The deletion is retried while it is blocked.
+func (c *ObjectStoreController) handleDeletion(store *cephv1.CephObjectStore) error {
+	cfg := clusterConfig{context: c.context, store: *store}
+	var blocked func() (string, error)
+	if !store.Spec.PreservePoolsOnDelete {
+		blocked = cfg.deletionBlocked
 	}
+	return c.guard.HandleDeletion(store, blocked, cfg.deleteStore)
+}
+
+// RemoveFinalizers removes the finalizer of the CephObjectStores of a deleted cluster. Their pools are not deleted.
+func RemoveFinalizers(context *clusterd.Context, namespace string) error {
+	return deletion.RemoveFinalizers(&objectStoreClient{context: context}, "object store", namespace, finalizerName)
+}
+
+// objectStoreClient gets and updates the CephObjectStores for the deletion guard
+type objectStoreClient struct {
+	context *clusterd.Context
+}
+
+func (c *objectStoreClient) Get(namespace, name string) (deletion.Object, error) {
+	return c.context.RookClientset.CephV1().CephObjectStores(namespace).Get(name, metav1.GetOptions{})
+}
+
+func (c *objectStoreClient) List(namespace string) ([]deletion.Object, error) {
+	list, err := c.context.RookClientset.CephV1().CephObjectStores(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	objs := []deletion.Object{}
+	for i := range list.Items {
+		objs = append(objs, &list.Items[i])
+	}
+	return objs, nil
+}
+
+func (c *objectStoreClient) Update(obj deletion.Object) error {
+	_, err := c.context.RookClientset.CephV1().CephObjectStores(obj.GetNamespace()).Update(obj.(*cephv1.CephObjectStore))
+	return err
+}
+
+func (c *objectStoreClient) SetStatus(obj deletion.Object, status cephv1.ResourceStatus) bool {
+	store := obj.(*cephv1.CephObjectStore)
+	if store.Status == status {
+		return false
+	}
+	store.Status = status
+	return true
 }
 
 func (c *ObjectStoreController) storeOwners(store *cephv1.CephObjectStore) []metav1.OwnerReference {
@@ -199,6 +314,32 @@ func (c *ObjectStoreController) storeOwners(store *cephv1.CephObjectStore) []met
 	return []metav1.OwnerReference{c.ownerRef}
 }
 
+// Validator returns the validator of the CephObjectStores for the validating webhook. Only the spec is validated, the
+// crush properties of the pools are checked against the cluster when the object store is created.
+func Validator(context *clusterd.Context) webhook.Validator {
+	return webhook.Validator{
+		NewObject: func() runtime.Object { return &cephv1.CephObjectStore{} },
+		Validate: func(oldObj, newObj runtime.Object) error {
+			store := newObj.(*cephv1.CephObjectStore)
+			if err := validateStoreSettings(*store); err != nil {
+				return err
+			}
+			if oldObj == nil {
+				return nil
+			}
+			return validateStoreUpdate(oldObj.(*cephv1.CephObjectStore).Spec, store.Spec)
+		},
+	}
+}
+
+func overrideChanged(oldOverride, newOverride cephv1.DaemonOverride) bool {
+	if !reflect.DeepEqual(oldOverride, newOverride) {
+		logger.Infof("rgw overrides of the cluster changed")
+		return true
+	}
+	return false
+}
+
 func storeChanged(oldStore, newStore cephv1.ObjectStoreSpec) bool {
 	if oldStore.DataPool.Replicated.Size != newStore.DataPool.Replicated.Size {
 		logger.Infof("data pool replication changed from %d to %d", oldStore.DataPool.Replicated.Size, newStore.DataPool.Replicated.Size)
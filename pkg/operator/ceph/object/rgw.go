@@ -19,11 +19,13 @@ package object
 
 import (
 	"fmt"
+	"sort"
 
 	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
 	"github.com/rook/rook/pkg/clusterd"
 	cephconfig "github.com/rook/rook/pkg/daemon/ceph/config"
 	"github.com/rook/rook/pkg/operator/ceph/config"
+	"github.com/rook/rook/pkg/operator/ceph/config/keyring"
 	"github.com/rook/rook/pkg/operator/ceph/pool"
 	"github.com/rook/rook/pkg/operator/k8sutil"
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -37,9 +39,14 @@ type clusterConfig struct {
 	store       cephv1.CephObjectStore
 	rookVersion string
 	cephVersion cephv1.CephVersionSpec
+	override    cephv1.DaemonOverride
 	hostNetwork bool
-	ownerRefs   []metav1.OwnerReference
-	DataPathMap *config.DataPathMap
+	// dataDirHostPath is the host path under which the crash reports of the rgws are kept
+	dataDirHostPath string
+	ownerRefs       []metav1.OwnerReference
+	DataPathMap     *config.DataPathMap
+	// user is the user of the rgw, a generation of the gateway user after a key rotation
+	user string
 }
 
 // Start the rgw manager
@@ -91,6 +98,12 @@ func (c *clusterConfig) createOrUpdate(update bool) error {
 }
 
 func (c *clusterConfig) startRGWPods(update bool) error {
+	// the rgw run with the user of the last key rotation
+	user, err := keyring.GetSecretStore(c.context, c.store.Namespace, nil).StoredUser(c.instanceName(), rgwUser)
+	if err != nil {
+		return fmt.Errorf("failed to get the rgw user. %+v", err)
+	}
+	c.user = user
 
 	// if intended to update, remove the old pods so they can be created with the new spec settings
 	if update {
@@ -132,7 +145,7 @@ func (c *clusterConfig) startRGWPods(update bool) error {
 
 	// Generate the keyring after starting the replication controller so that the keyring may use
 	// the controller as its owner reference; the keyring is deleted with the controller
-	err := c.generateKeyring(resourceControllerOwnerRef)
+	err = c.generateKeyring(resourceControllerOwnerRef)
 	if err != nil {
 		return fmt.Errorf("failed to create rgw keyring. %+v", err)
 	}
@@ -186,6 +199,11 @@ func (c *clusterConfig) deleteStore() error {
 		logger.Warningf("failed to delete rgw secret. %+v", err)
 	}
 
+	if c.store.Spec.PreservePoolsOnDelete {
+		logger.Infof("Preserving the realm and pools of object store %s", c.store.Name)
+		return nil
+	}
+
 	// Delete the realm and pools
 	objContext := NewContext(c.context, c.store.Name, c.store.Namespace)
 	err = deleteRealmAndPools(objContext)
@@ -197,6 +215,31 @@ func (c *clusterConfig) deleteStore() error {
 	return nil
 }
 
+// deletionBlocked returns the reason the object store can't be deleted, or an empty string
+func (c *clusterConfig) deletionBlocked() (string, error) {
+	exists, err := c.storeExists()
+	if err != nil {
+		return "", fmt.Errorf("failed to detect if there is an object store to delete. %+v", err)
+	}
+	if !exists {
+		return "", nil
+	}
+
+	buckets, err := ListBuckets(NewContext(c.context, c.store.Name, c.store.Namespace))
+	if err != nil {
+		return "", err
+	}
+	if len(buckets) > 0 {
+		names := []string{}
+		for _, b := range buckets {
+			names = append(names, b.Name)
+		}
+		sort.Strings(names)
+		return fmt.Sprintf("object store %s has buckets %v", c.store.Name, names), nil
+	}
+	return "", nil
+}
+
 // Check if the object store exists depending on either the deployment or the daemonset
 func (c *clusterConfig) storeExists() (bool, error) {
 	_, err := c.context.Clientset.Apps().Deployments(c.store.Namespace).Get(c.instanceName(), metav1.GetOptions{})
@@ -225,20 +268,44 @@ func (c *clusterConfig) instanceName() string {
 	return fmt.Sprintf("%s-%s", AppName, c.store.Name)
 }
 
-// Validate the object store arguments
+// validateStore validates the object store arguments, and the crush properties of its pools against the cluster
 func validateStore(context *clusterd.Context, s cephv1.CephObjectStore) error {
+	if err := validateStoreSettings(s); err != nil {
+		return err
+	}
+	if err := pool.ValidatePoolSpec(context, s.Namespace, &s.Spec.MetadataPool); err != nil {
+		return fmt.Errorf("invalid metadata pool spec. %+v", err)
+	}
+	if err := pool.ValidatePoolSpec(context, s.Namespace, &s.Spec.DataPool); err != nil {
+		return fmt.Errorf("invalid data pool spec. %+v", err)
+	}
+	return nil
+}
+
+// validateStoreSettings validates the object store arguments without the state of the cluster
+func validateStoreSettings(s cephv1.CephObjectStore) error {
 	if s.Name == "" {
 		return fmt.Errorf("missing name")
 	}
 	if s.Namespace == "" {
 		return fmt.Errorf("missing namespace")
 	}
-	if err := pool.ValidatePoolSpec(context, s.Namespace, &s.Spec.MetadataPool); err != nil {
+	if err := pool.ValidatePoolSpecSettings(&s.Spec.MetadataPool); err != nil {
 		return fmt.Errorf("invalid metadata pool spec. %+v", err)
 	}
-	if err := pool.ValidatePoolSpec(context, s.Namespace, &s.Spec.DataPool); err != nil {
+	if err := pool.ValidatePoolSpecSettings(&s.Spec.DataPool); err != nil {
 		return fmt.Errorf("invalid data pool spec. %+v", err)
 	}
+	return nil
+}
 
+// validateStoreUpdate returns an error if the pools of the object store can't be changed to the new spec
+func validateStoreUpdate(oldStore, newStore cephv1.ObjectStoreSpec) error {
+	if err := pool.ValidatePoolSpecUpdate(&oldStore.MetadataPool, &newStore.MetadataPool); err != nil {
+		return fmt.Errorf("invalid metadata pool update. %+v", err)
+	}
+	if err := pool.ValidatePoolSpecUpdate(&oldStore.DataPool, &newStore.DataPool); err != nil {
+		return fmt.Errorf("invalid data pool update. %+v", err)
+	}
 	return nil
 }
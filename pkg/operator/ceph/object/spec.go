@@ -114,6 +114,7 @@ func (c *clusterConfig) makeRGWPodSpec() v1.PodTemplateSpec {
 		Volumes: append(
 			opspec.DaemonVolumes(c.DataPathMap, c.instanceName()),
 			c.mimeTypesVolume(),
+			opspec.CrashVolume(c.dataDirHostPath),
 		),
 		HostNetwork: c.hostNetwork,
 	}
@@ -138,7 +139,7 @@ func (c *clusterConfig) makeRGWPodSpec() v1.PodTemplateSpec {
 
 	c.store.Spec.Gateway.Placement.ApplyToPodSpec(&podSpec)
 
-	return v1.PodTemplateSpec{
+	podTemplateSpec := v1.PodTemplateSpec{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:        c.instanceName(),
 			Labels:      c.getLabels(),
@@ -146,6 +147,9 @@ func (c *clusterConfig) makeRGWPodSpec() v1.PodTemplateSpec {
 		},
 		Spec: podSpec,
 	}
+	c.override.ApplyToPod(&podTemplateSpec.ObjectMeta, &podTemplateSpec.Spec, c.cephVersion.Image)
+
+	return podTemplateSpec
 }
 
 func (c *clusterConfig) makeDaemonContainer() v1.Container {
@@ -161,7 +165,7 @@ func (c *clusterConfig) makeDaemonContainer() v1.Container {
 			append(
 				opspec.DaemonFlags(c.clusterInfo, c.store.Name),
 				"--foreground",
-				"--name=client.radosgw.gateway",
+				"--name="+c.user,
 				cephconfig.NewFlag("host", opspec.ContainerEnvVarReference("POD_NAME")),
 				cephconfig.NewFlag("rgw-mime-types-file", mimeTypesMountPath()),
 			), c.defaultSettings().GlobalFlags()..., // use default settings as flags until mon kv store supported
@@ -169,6 +173,7 @@ func (c *clusterConfig) makeDaemonContainer() v1.Container {
 		VolumeMounts: append(
 			opspec.DaemonVolumeMounts(c.DataPathMap, c.instanceName()),
 			c.mimeTypesVolumeMount(),
+			opspec.CrashVolumeMount(),
 		),
 		Env:       opspec.DaemonEnvVars(c.cephVersion.Image),
 		Resources: c.store.Spec.Gateway.Resources,
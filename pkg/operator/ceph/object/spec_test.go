@@ -54,6 +54,7 @@ func TestPodSpecs(t *testing.T) {
 		cephVersion: cephv1.CephVersionSpec{Image: "ceph/ceph:v13.2.1"},
 		hostNetwork: true,
 		DataPathMap: data,
+		user:        rgwUser,
 	}
 
 	s := c.makeRGWPodSpec()
@@ -88,6 +89,7 @@ func TestSSLPodSpec(t *testing.T) {
 		cephVersion: cephv1.CephVersionSpec{Image: "ceph/ceph:v13.2.1"},
 		hostNetwork: true,
 		DataPathMap: data,
+		user:        rgwUser,
 	}
 	c.hostNetwork = true
 
@@ -26,11 +26,14 @@ import (
 	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
 	"github.com/rook/rook/pkg/clusterd"
 	"github.com/rook/rook/pkg/operator/ceph/object"
+	"github.com/rook/rook/pkg/operator/ceph/reconcile"
 	"github.com/rook/rook/pkg/operator/k8sutil"
 	"k8s.io/api/core/v1"
 	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 )
 
 const (
@@ -53,18 +56,27 @@ var ObjectStoreUserResource = opkit.CustomResource{
 type ObjectStoreUserController struct {
 	context  *clusterd.Context
 	ownerRef metav1.OwnerReference
+	queue    *reconcile.Queue
+	reporter *reconcile.Reporter
+	// the users last created, kept to delete the users of the removed CRDs. Only accessed by the worker of the queue.
+	applied map[string]*cephv1.CephObjectStoreUser
 }
 
 // NewObjectStoreUserController create controller for watching object store user custom resources created
-func NewObjectStoreUserController(context *clusterd.Context, ownerRef metav1.OwnerReference) *ObjectStoreUserController {
-	return &ObjectStoreUserController{
+func NewObjectStoreUserController(context *clusterd.Context, ownerRef metav1.OwnerReference, recorder record.EventRecorder) *ObjectStoreUserController {
+	c := &ObjectStoreUserController{
 		context:  context,
 		ownerRef: ownerRef,
+		reporter: reconcile.NewReporter(recorder),
+		applied:  map[string]*cephv1.CephObjectStoreUser{},
 	}
+	c.queue = reconcile.New(ObjectStoreUserResource.Name, c.reconcile)
+	return c
 }
 
 // StartWatch watches for instances of ObjectStoreUser custom resources and acts on them
 func (c *ObjectStoreUserController) StartWatch(namespace string, stopCh chan struct{}) error {
+	c.queue.Start(stopCh)
 
 	resourceHandlerFuncs := cache.ResourceEventHandlerFuncs{
 		AddFunc:    c.onAdd,
@@ -86,9 +98,7 @@ func (c *ObjectStoreUserController) onAdd(obj interface{}) {
 		return
 	}
 
-	if err = c.createUser(c.context, user); err != nil {
-		logger.Errorf("failed to create object store user %s. %+v", user.Name, err)
-	}
+	c.queue.Add(user)
 }
 
 func (c *ObjectStoreUserController) onUpdate(oldObj, newObj interface{}) {
@@ -102,9 +112,41 @@ func (c *ObjectStoreUserController) onDelete(obj interface{}) {
 		return
 	}
 
-	if err = deleteUser(c.context, user); err != nil {
-		logger.Errorf("failed to delete object store user %s. %+v", user.Name, err)
+	c.queue.Remove(user)
+}
+
+// reconcile creates the user of the CephObjectStoreUser, or deletes the user last created if the CephObjectStoreUser
+// was removed
+func (c *ObjectStoreUserController) reconcile(namespace, name string) error {
+	key := namespace + "/" + name
+	user, err := c.context.RookClientset.CephV1().CephObjectStoreUsers(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to get object store user %s. %+v", name, err)
+		}
+		applied, ok := c.applied[key]
+		if !ok {
+			// the user was deleted before it was created, or while its creation was failing
+			deleted, _ := c.queue.Deleted(namespace, name).(*cephv1.CephObjectStoreUser)
+			if deleted == nil {
+				return nil
+			}
+			applied = deleted
+		}
+		if err := deleteUser(c.context, applied); err != nil {
+			return c.reporter.Failed(applied, fmt.Errorf("failed to delete object store user %s. %+v", name, err))
+		}
+		c.reporter.Deleted(applied, "deleted object store user %s", name)
+		delete(c.applied, key)
+		return nil
+	}
+
+	if err := c.createUser(c.context, user); err != nil {
+		return c.reporter.Failed(user, fmt.Errorf("failed to create object store user %s. %+v", user.Name, err))
 	}
+	c.reporter.Succeeded(user, "created user %s in object store %s", user.Name, user.Spec.Store)
+	c.applied[key] = user
+	return nil
 }
 
 func (c *ObjectStoreUserController) storeUserOwners(store *cephv1.CephObjectStoreUser) []metav1.OwnerReference {
@@ -125,7 +167,7 @@ func getObjectStoreUserObject(obj interface{}) (objectstoreuser *cephv1.CephObje
 	return nil, fmt.Errorf("not a known objectstoreuser object: %+v", obj)
 }
 
-// Create the user
+// Create the user if it does not exist yet, and store its keys in a secret
 func (c *ObjectStoreUserController) createUser(context *clusterd.Context, u *cephv1.CephObjectStoreUser) error {
 	// validate the user settings
 	if err := ValidateUser(context, u); err != nil {
@@ -137,17 +179,23 @@ func (c *ObjectStoreUserController) createUser(context *clusterd.Context, u *cep
 		displayName = u.Name
 	}
 
-	// create the user
-	logger.Infof("creating user %s in namespace %s", u.Name, u.Namespace)
-	userConfig := object.ObjectUser{
-		UserID:      u.Name,
-		DisplayName: &displayName,
-	}
 	objContext := object.NewContext(context, u.Spec.Store, u.Namespace)
-
-	user, rgwerr, err := object.CreateUser(objContext, userConfig)
+	user, rgwerr, err := object.GetUser(objContext, u.Name)
 	if err != nil {
-		return fmt.Errorf("failed to create user %s. RadosGW returned error %d: %+v", u.Name, rgwerr, err)
+		if rgwerr != object.RGWErrorNotFound {
+			return fmt.Errorf("failed to get user %s. RadosGW returned error %d: %+v", u.Name, rgwerr, err)
+		}
+
+		// create the user
+		logger.Infof("creating user %s in namespace %s", u.Name, u.Namespace)
+		userConfig := object.ObjectUser{
+			UserID:      u.Name,
+			DisplayName: &displayName,
+		}
+		user, rgwerr, err = object.CreateUser(objContext, userConfig)
+		if err != nil {
+			return fmt.Errorf("failed to create user %s. RadosGW returned error %d: %+v", u.Name, rgwerr, err)
+		}
 	}
 
 	// Store the keys in a secret
@@ -173,7 +221,12 @@ func (c *ObjectStoreUserController) createUser(context *clusterd.Context, u *cep
 
 	_, err = context.Clientset.CoreV1().Secrets(u.Namespace).Create(secret)
 	if err != nil {
-		return fmt.Errorf("failed to save user %s secret. %+v", u.Name, err)
+		if !errors.IsAlreadyExists(err) {
+			return fmt.Errorf("failed to save user %s secret. %+v", u.Name, err)
+		}
+		if _, err := context.Clientset.CoreV1().Secrets(u.Namespace).Update(secret); err != nil {
+			return fmt.Errorf("failed to update user %s secret. %+v", u.Name, err)
+		}
 	}
 	logger.Infof("created user %s", u.Name)
 	return nil
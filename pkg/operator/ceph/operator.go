@@ -20,8 +20,6 @@ package operator
 import (
 	"fmt"
 	"os"
-	"os/signal"
-	"syscall"
 
 	"github.com/coreos/pkg/capnslog"
 	"github.com/kubernetes-sigs/sig-storage-lib-external-provisioner/controller"
@@ -33,13 +31,15 @@ import (
 	"github.com/rook/rook/pkg/operator/ceph/cluster"
 	"github.com/rook/rook/pkg/operator/ceph/csi"
 	"github.com/rook/rook/pkg/operator/ceph/file"
+	"github.com/rook/rook/pkg/operator/ceph/nfs"
 	"github.com/rook/rook/pkg/operator/ceph/object"
 	"github.com/rook/rook/pkg/operator/ceph/object/user"
 	"github.com/rook/rook/pkg/operator/ceph/pool"
 	"github.com/rook/rook/pkg/operator/ceph/provisioner"
 	"github.com/rook/rook/pkg/operator/discover"
 	"github.com/rook/rook/pkg/operator/k8sutil"
-	"k8s.io/api/core/v1"
+	"github.com/rook/rook/pkg/operator/leader"
+	"github.com/rook/rook/pkg/operator/webhook"
 )
 
 // volume provisioner constant
@@ -48,6 +48,9 @@ const (
 	provisionerNameLegacy = "rook.io/block"
 )
 
+// the name of the configmap storing the leader of the operator replicas
+const leaderLockName = "rook-ceph-operator-leader"
+
 var logger = capnslog.NewPackageLogger("github.com/rook/rook", "operator")
 
 // The supported configurations for the volume provisioner
@@ -82,7 +85,16 @@ func New(context *clusterd.Context, volumeAttachmentWrapper attachment.Attachmen
 	}
 }
 
-// Run the operator instance
+// RegisterValidators registers the validators of the Ceph custom resources in the webhook server
+func RegisterValidators(server *webhook.Server, context *clusterd.Context) {
+	server.Register(pool.PoolResource, pool.Validator(context))
+	server.Register(file.FilesystemResource, file.Validator(context))
+	server.Register(object.ObjectStoreResource, object.Validator(context))
+	server.Register(nfs.CephNFSResource, nfs.Validator(context))
+	server.Register(nfs.CephNFSExportResource, nfs.ExportValidator())
+}
+
+// Run the operator instance once it is elected as the leader
 func (o *Operator) Run() error {
 
 	namespace := os.Getenv(k8sutil.PodNamespaceEnvVar)
@@ -90,6 +102,12 @@ func (o *Operator) Run() error {
 		return fmt.Errorf("Rook operator namespace is not provided. Expose it via downward API in the rook operator manifest file using environment variable %s", k8sutil.PodNamespaceEnvVar)
 	}
 
+	return leader.Run(o.context.Clientset, leaderLockName, func(stopChan chan struct{}) error {
+		return o.run(namespace, stopChan)
+	})
+}
+
+func (o *Operator) run(namespace string, stopChan chan struct{}) error {
 	rookAgent := agent.New(o.context.Clientset)
 
 	if err := rookAgent.Start(namespace, o.rookImage, o.securityAccount); err != nil {
@@ -126,33 +144,31 @@ func (o *Operator) Run() error {
 		}
 	}
 
-	signalChan := make(chan os.Signal, 1)
-	stopChan := make(chan struct{})
-	signal.Notify(signalChan, syscall.SIGINT, syscall.SIGTERM)
-
-	// Run volume provisioner for each of the supported configurations
-	for name, vendor := range provisionerConfigs {
-		volumeProvisioner := provisioner.New(o.context, vendor)
-		pc := controller.NewProvisionController(
-			o.context.Clientset,
-			name,
-			volumeProvisioner,
-			serverVersion.GitVersion,
-		)
-		go pc.Run(stopChan)
-		logger.Infof("rook-provisioner %s started using %s flex vendor dir", name, vendor)
+	// Run volume provisioner for each of the supported configurations. The provisioner creates the PVs and watches the
+	// PVCs of all the namespaces, which requires cluster-wide access.
+	if cluster.WatchesAllNamespaces() {
+		for name, vendor := range provisionerConfigs {
+			volumeProvisioner := provisioner.New(o.context, vendor)
+			pc := controller.NewProvisionController(
+				o.context.Clientset,
+				name,
+				volumeProvisioner,
+				serverVersion.GitVersion,
+			)
+			go pc.Run(stopChan)
+			logger.Infof("rook-provisioner %s started using %s flex vendor dir", name, vendor)
+		}
+	} else {
+		logger.Infof("rook-provisioner not started since the operator only watches some namespaces. use the ceph-csi drivers to provision the volumes")
 	}
 
 	// watch for changes to the rook clusters
-	o.clusterController.StartWatch(v1.NamespaceAll, stopChan)
-
-	for {
-		select {
-		case <-signalChan:
-			logger.Infof("shutdown signal received, exiting...")
-			close(stopChan)
-			o.clusterController.StopWatch()
-			return nil
-		}
+	if err := o.clusterController.StartWatch(stopChan); err != nil {
+		return fmt.Errorf("failed to watch the clusters. %+v", err)
 	}
+
+	// stop the health checkers and the watchers of the clusters
+	<-stopChan
+	o.clusterController.StopWatch()
+	return nil
 }
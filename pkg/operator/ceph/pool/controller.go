@@ -19,6 +19,7 @@ package pool
 
 import (
 	"fmt"
+	"os"
 	"reflect"
 
 	"github.com/coreos/pkg/capnslog"
@@ -26,18 +27,31 @@ import (
 	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
 	cephbeta "github.com/rook/rook/pkg/apis/ceph.rook.io/v1beta1"
 	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/daemon/ceph/agent/flexvolume/attachment"
 	ceph "github.com/rook/rook/pkg/daemon/ceph/client"
 	"github.com/rook/rook/pkg/daemon/ceph/model"
+	"github.com/rook/rook/pkg/operator/ceph/deletion"
+	"github.com/rook/rook/pkg/operator/ceph/reconcile"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	"github.com/rook/rook/pkg/operator/webhook"
 	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 )
 
 const (
 	replicatedType         = "replicated"
 	erasureCodeType        = "erasure-coded"
 	poolApplicationNameRBD = "rbd"
+
+	// the flex volume options of the block PVs, the flexvolume package can't be imported without a cycle
+	pvClusterNamespaceKey = "clusterNamespace"
+	pvPoolKey             = "pool"
+	pvBlockPoolKey        = "blockPool"
+	pvDataBlockPoolKey    = "dataBlockPool"
 )
 
 var logger = capnslog.NewPackageLogger("github.com/rook/rook", "op-pool")
@@ -61,20 +75,32 @@ var PoolResourceRookLegacy = opkit.CustomResource{
 	Kind:    reflect.TypeOf(cephbeta.Pool{}).Name(),
 }
 
+var finalizerName = fmt.Sprintf("%s.%s", PoolResource.Name, PoolResource.Group)
+
 // PoolController represents a controller object for pool custom resources
 type PoolController struct {
-	context *clusterd.Context
+	context          *clusterd.Context
+	volumeAttachment attachment.Attachment
+	guard            *deletion.Guard
+	queue            *reconcile.Queue
+	reporter         *reconcile.Reporter
 }
 
 // NewPoolController create controller for watching pool custom resources created
-func NewPoolController(context *clusterd.Context) *PoolController {
-	return &PoolController{
-		context: context,
-	}
+func NewPoolController(context *clusterd.Context, volumeAttachment attachment.Attachment, recorder record.EventRecorder) *PoolController {
+	c := &PoolController{
+		context:          context,
+		volumeAttachment: volumeAttachment,
+		reporter:         reconcile.NewReporter(recorder),
+	}
+	c.guard = deletion.NewGuard(context, &poolClient{context: context}, "pool", recorder, c.reporter, finalizerName)
+	c.queue = reconcile.New(PoolResource.Name, c.reconcile)
+	return c
 }
 
 // Watch watches for instances of Pool custom resources and acts on them
 func (c *PoolController) StartWatch(namespace string, stopCh chan struct{}) error {
+	c.queue.Start(stopCh)
 
 	resourceHandlerFuncs := cache.ResourceEventHandlerFuncs{
 		AddFunc:    c.onAdd,
@@ -106,10 +132,7 @@ func (c *PoolController) onAdd(obj interface{}) {
 		return
 	}
 
-	err = createPool(c.context, pool)
-	if err != nil {
-		logger.Errorf("failed to create pool %s. %+v", pool.ObjectMeta.Name, err)
-	}
+	c.queue.Add(pool)
 }
 
 func (c *PoolController) onUpdate(oldObj, newObj interface{}) {
@@ -131,6 +154,11 @@ func (c *PoolController) onUpdate(oldObj, newObj interface{}) {
 		return
 	}
 
+	if pool.DeletionTimestamp != nil {
+		c.queue.Add(pool)
+		return
+	}
+
 	if oldPool.Name != pool.Name {
 		logger.Errorf("failed to update pool %s. name update not allowed", pool.Name)
 		return
@@ -146,9 +174,30 @@ func (c *PoolController) onUpdate(oldObj, newObj interface{}) {
 
 	// if the pool is modified, allow the pool to be created if it wasn't already
 	logger.Infof("updating pool %s", pool.Name)
+	c.queue.Add(pool)
+}
+
+// reconcile creates or updates the pool of the CephBlockPool, or deletes it if the CephBlockPool is being deleted
+func (c *PoolController) reconcile(namespace, name string) error {
+	pool, err := c.context.RookClientset.CephV1().CephBlockPools(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to get pool %s. %+v", name, err)
+	}
+
+	if pool.DeletionTimestamp != nil {
+		return c.handleDeletion(pool)
+	}
+	if err := c.guard.EnsureFinalizer(pool); err != nil {
+		return c.reporter.Failed(pool, fmt.Errorf("failed to add finalizer to pool %s. %+v", pool.Name, err))
+	}
 	if err := createPool(c.context, pool); err != nil {
-		logger.Errorf("failed to create (modify) pool %s. %+v", pool.ObjectMeta.Name, err)
+		return c.reporter.Failed(pool, fmt.Errorf("failed to create pool %s. %+v", pool.Name, err))
 	}
+	c.reporter.Succeeded(pool, "created pool %s", pool.Name)
+	return nil
 }
 
 func poolChanged(old, new cephv1.PoolSpec) bool {
@@ -171,9 +220,153 @@ func (c *PoolController) onDelete(obj interface{}) {
 		return
 	}
 
-	if err := deletePool(c.context, pool); err != nil {
-		logger.Errorf("failed to delete pool %s. %+v", pool.ObjectMeta.Name, err)
+	// the pool was deleted, or preserved, before the finalizer was removed
+	logger.Infof("pool %s removed from namespace %s", pool.Name, pool.Namespace)
+	c.queue.Remove(pool)
+}
+
+// handleDeletion deletes the pool of a CephBlockPool marked for deletion unless the pool still holds data,
+// then removes the finalizer so the CephBlockPool goes away. The deletion is retried while it is blocked.
+func (c *PoolController) handleDeletion(pool *cephv1.CephBlockPool) error {
+	if pool.Spec.PreservePoolOnDelete {
+		return c.guard.HandleDeletion(pool, nil, nil)
+	}
+	return c.guard.HandleDeletion(pool,
+		func() (string, error) { return c.deletionBlocked(pool) },
+		func() error { return deletePool(c.context, pool) })
+}
+
+// rbdMetadataObjects are the objects rbd creates in a pool that holds no images
+var rbdMetadataObjects = []string{
+	"rbd_directory",
+	"rbd_info",
+	"rbd_trash",
+	"rbd_children",
+	"rbd_mirroring",
+	"rbd_mirror_leader",
+	"rbd_namespace",
+	"rbd_task",
+}
+
+// deletionBlocked returns the reason the pool can't be deleted, or an empty string
+func (c *PoolController) deletionBlocked(pool *cephv1.CephBlockPool) (string, error) {
+	volumes, err := c.attachedVolumes(pool)
+	if err != nil {
+		return "", err
+	}
+	if len(volumes) > 0 {
+		return fmt.Sprintf("volumes %v of pool %s are attached", volumes, pool.Name), nil
+	}
+
+	// an empty pool still holds the rbd metadata objects, only its images are counted
+	images, err := ceph.ListImages(c.context, pool.Namespace, pool.Name)
+	if err != nil {
+		return "", err
+	}
+	if len(images) > 0 {
+		return fmt.Sprintf("pool %s holds %d images", pool.Name, len(images)), nil
+	}
+	trash, err := ceph.ListTrashImages(c.context, pool.Namespace, pool.Name)
+	if err != nil {
+		return "", err
+	}
+	if len(trash) > 0 {
+		return fmt.Sprintf("pool %s holds %d images in the trash", pool.Name, len(trash)), nil
+	}
+
+	// objects written directly with rados are not listed as images
+	stats, err := ceph.GetPoolStats(c.context, pool.Namespace)
+	if err != nil {
+		return "", err
+	}
+	for _, p := range stats.Pools {
+		if p.Name == pool.Name && int(p.Stats.Objects) > len(rbdMetadataObjects) {
+			return fmt.Sprintf("pool %s holds %d objects", pool.Name, int(p.Stats.Objects)), nil
+		}
+	}
+	return "", nil
+}
+
+// attachedVolumes returns the PVs of the pool attached to a pod
+func (c *PoolController) attachedVolumes(pool *cephv1.CephBlockPool) ([]string, error) {
+	if c.volumeAttachment == nil {
+		return nil, nil
+	}
+	vols, err := c.volumeAttachment.List(os.Getenv(k8sutil.PodNamespaceEnvVar))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get volume attachments. %+v", err)
+	}
+
+	volumes := []string{}
+	for _, vol := range vols.Items {
+		attached := false
+		for _, a := range vol.Attachments {
+			if a.ClusterName == pool.Namespace {
+				attached = true
+				break
+			}
+		}
+		if !attached {
+			continue
+		}
+
+		// the volume attachments are named after the PVs
+		pv, err := c.context.Clientset.CoreV1().PersistentVolumes().Get(vol.Name, metav1.GetOptions{})
+		if err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to get volume %s. %+v", vol.Name, err)
+		}
+		if pv.Spec.FlexVolume == nil || pv.Spec.FlexVolume.Options[pvClusterNamespaceKey] != pool.Namespace {
+			continue
+		}
+		options := pv.Spec.FlexVolume.Options
+		if options[pvPoolKey] == pool.Name || options[pvBlockPoolKey] == pool.Name || options[pvDataBlockPoolKey] == pool.Name {
+			volumes = append(volumes, vol.Name)
+		}
+	}
+	return volumes, nil
+}
+
+// RemoveFinalizers removes the finalizer of the CephBlockPools of a deleted cluster. Their pools are not deleted.
+func RemoveFinalizers(context *clusterd.Context, namespace string) error {
+	return deletion.RemoveFinalizers(&poolClient{context: context}, "pool", namespace, finalizerName)
+}
+
+// poolClient gets and updates the CephBlockPools for the deletion guard
+type poolClient struct {
+	context *clusterd.Context
+}
+
+func (c *poolClient) Get(namespace, name string) (deletion.Object, error) {
+	return c.context.RookClientset.CephV1().CephBlockPools(namespace).Get(name, metav1.GetOptions{})
+}
+
+func (c *poolClient) List(namespace string) ([]deletion.Object, error) {
+	list, err := c.context.RookClientset.CephV1().CephBlockPools(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	objs := []deletion.Object{}
+	for i := range list.Items {
+		objs = append(objs, &list.Items[i])
+	}
+	return objs, nil
+}
+
+func (c *poolClient) Update(obj deletion.Object) error {
+	_, err := c.context.RookClientset.CephV1().CephBlockPools(obj.GetNamespace()).Update(obj.(*cephv1.CephBlockPool))
+	return err
+}
+
+func (c *poolClient) SetStatus(obj deletion.Object, status cephv1.ResourceStatus) bool {
+	pool := obj.(*cephv1.CephBlockPool)
+	if pool.Status == status {
+		return false
 	}
+	pool.Status = status
+	return true
 }
 
 // Create the pool
@@ -227,35 +420,53 @@ func ModelToSpec(pool model.Pool) cephv1.PoolSpec {
 	}
 }
 
-// Validate the pool arguments
+// ValidatePool validates the pool arguments, and the failure domain and the crush root against the crush map of the cluster
 func ValidatePool(context *clusterd.Context, p *cephv1.CephBlockPool) error {
+	if err := validatePoolSettings(p); err != nil {
+		return err
+	}
+	return validateCrushProperties(context, p.Namespace, &p.Spec)
+}
+
+// validatePoolSettings validates the pool arguments without the state of the cluster
+func validatePoolSettings(p *cephv1.CephBlockPool) error {
 	if p.Name == "" {
 		return fmt.Errorf("missing name")
 	}
 	if p.Namespace == "" {
 		return fmt.Errorf("missing namespace")
 	}
-	if err := ValidatePoolSpec(context, p.Namespace, &p.Spec); err != nil {
+	return ValidatePoolSpecSettings(&p.Spec)
+}
+
+// ValidatePoolSpec validates the pool spec, and the failure domain and the crush root against the crush map of the cluster
+func ValidatePoolSpec(context *clusterd.Context, namespace string, p *cephv1.PoolSpec) error {
+	if err := ValidatePoolSpecSettings(p); err != nil {
 		return err
 	}
-	return nil
+	return validateCrushProperties(context, namespace, p)
 }
 
-func ValidatePoolSpec(context *clusterd.Context, namespace string, p *cephv1.PoolSpec) error {
+// ValidatePoolSpecSettings validates the pool spec without the state of the cluster, which may not be running yet when
+// the admission webhook validates the resources
+func ValidatePoolSpecSettings(p *cephv1.PoolSpec) error {
 	if p.Replication() != nil && p.ErasureCode() != nil {
 		return fmt.Errorf("both replication and erasure code settings cannot be specified")
 	}
 	if p.Replication() == nil && p.ErasureCode() == nil {
 		return fmt.Errorf("neither replication nor erasure code settings were specified")
 	}
+	return nil
+}
 
-	var crush ceph.CrushMap
-	var err error
-	if p.FailureDomain != "" || p.CrushRoot != "" {
-		crush, err = ceph.GetCrushMap(context, namespace)
-		if err != nil {
-			return fmt.Errorf("failed to get crush map. %+v", err)
-		}
+// validateCrushProperties checks that the failure domain and the crush root of the pool exist in the crush map
+func validateCrushProperties(context *clusterd.Context, namespace string, p *cephv1.PoolSpec) error {
+	if p.FailureDomain == "" && p.CrushRoot == "" {
+		return nil
+	}
+	crush, err := ceph.GetCrushMap(context, namespace)
+	if err != nil {
+		return fmt.Errorf("failed to get crush map. %+v", err)
 	}
 
 	// validate the failure domain if specified
@@ -289,6 +500,38 @@ func ValidatePoolSpec(context *clusterd.Context, namespace string, p *cephv1.Poo
 	return nil
 }
 
+// ValidatePoolSpecUpdate returns an error if the pool can't be changed from the old spec to the new one. Ceph can't
+// change the type of a pool or the erasure code profile of a pool once it is created.
+func ValidatePoolSpecUpdate(oldPool, newPool *cephv1.PoolSpec) error {
+	if (oldPool.ErasureCode() == nil) != (newPool.ErasureCode() == nil) {
+		return fmt.Errorf("a pool can't be changed between replicated and erasure coded")
+	}
+	if oldPool.ErasureCode() != nil &&
+		(oldPool.ErasureCoded.DataChunks != newPool.ErasureCoded.DataChunks || oldPool.ErasureCoded.CodingChunks != newPool.ErasureCoded.CodingChunks) {
+		return fmt.Errorf("the data and coding chunks of an erasure coded pool can't be changed from %d+%d to %d+%d",
+			oldPool.ErasureCoded.DataChunks, oldPool.ErasureCoded.CodingChunks, newPool.ErasureCoded.DataChunks, newPool.ErasureCoded.CodingChunks)
+	}
+	return nil
+}
+
+// Validator returns the validator of the CephBlockPools for the validating webhook. Only the spec is validated, the
+// failure domain and the crush root are checked against the cluster when the pool is created.
+func Validator(context *clusterd.Context) webhook.Validator {
+	return webhook.Validator{
+		NewObject: func() runtime.Object { return &cephv1.CephBlockPool{} },
+		Validate: func(oldObj, newObj runtime.Object) error {
+			p := newObj.(*cephv1.CephBlockPool)
+			if err := validatePoolSettings(p); err != nil {
+				return err
+			}
+			if oldObj == nil {
+				return nil
+			}
+			return ValidatePoolSpecUpdate(&oldObj.(*cephv1.CephBlockPool).Spec, &p.Spec)
+		},
+	}
+}
+
 func (c *PoolController) watchLegacyPools(namespace string, stopCh chan struct{}, resourceHandlerFuncs cache.ResourceEventHandlerFuncs) {
 	// watch for pool.rook.io/v1alpha1 events if the CRD exists
 	if _, err := c.context.RookClientset.CephV1beta1().Pools(namespace).List(metav1.ListOptions{}); err != nil {
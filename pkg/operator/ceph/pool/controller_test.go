@@ -110,6 +110,55 @@ func TestValidateCrushProperties(t *testing.T) {
 	assert.Nil(t, err)
 }
 
+func TestValidator(t *testing.T) {
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName, command, outputFile string, args ...string) (string, error) {
+			return "", fmt.Errorf("the cluster is not running")
+		},
+	}
+	validator := Validator(&clusterd.Context{Executor: executor})
+
+	// the crush properties are not checked against the cluster in the webhook
+	p := &cephv1.CephBlockPool{
+		ObjectMeta: metav1.ObjectMeta{Name: "mypool", Namespace: "myns"},
+		Spec: cephv1.PoolSpec{
+			Replicated:    cephv1.ReplicatedSpec{Size: 3},
+			FailureDomain: "host",
+			CrushRoot:     "default",
+		},
+	}
+	assert.Nil(t, validator.Validate(nil, p))
+
+	// the spec is still validated
+	p.Spec.ErasureCoded = cephv1.ErasureCodedSpec{DataChunks: 2, CodingChunks: 1}
+	assert.NotNil(t, validator.Validate(nil, p))
+
+	// and the update
+	ec := p.DeepCopy()
+	ec.Spec.Replicated.Size = 0
+	replicated := p.DeepCopy()
+	replicated.Spec.ErasureCoded = cephv1.ErasureCodedSpec{}
+	assert.Nil(t, validator.Validate(nil, ec))
+	assert.NotNil(t, validator.Validate(replicated, ec))
+}
+
+func TestValidatePoolSpecUpdate(t *testing.T) {
+	replicated := &cephv1.PoolSpec{Replicated: cephv1.ReplicatedSpec{Size: 1}}
+	ec := &cephv1.PoolSpec{ErasureCoded: cephv1.ErasureCodedSpec{DataChunks: 2, CodingChunks: 1}}
+
+	// the size of a replicated pool can change
+	assert.Nil(t, ValidatePoolSpecUpdate(replicated, &cephv1.PoolSpec{Replicated: cephv1.ReplicatedSpec{Size: 3}}))
+	assert.Nil(t, ValidatePoolSpecUpdate(ec, ec))
+
+	// the type of the pool can't change
+	assert.NotNil(t, ValidatePoolSpecUpdate(replicated, ec))
+	assert.NotNil(t, ValidatePoolSpecUpdate(ec, replicated))
+
+	// the erasure code profile can't change
+	assert.NotNil(t, ValidatePoolSpecUpdate(ec, &cephv1.PoolSpec{ErasureCoded: cephv1.ErasureCodedSpec{DataChunks: 1, CodingChunks: 1}}))
+	assert.NotNil(t, ValidatePoolSpecUpdate(ec, &cephv1.PoolSpec{ErasureCoded: cephv1.ErasureCodedSpec{DataChunks: 2, CodingChunks: 2}}))
+}
+
 func TestCreatePool(t *testing.T) {
 	executor := &exectest.MockExecutor{
 		MockExecuteCommandWithOutputFile: func(debug bool, actionName, command, outfile string, args ...string) (string, error) {
@@ -141,6 +190,37 @@ func TestCreatePool(t *testing.T) {
 	assert.Nil(t, err)
 }
 
+func TestReconcile(t *testing.T) {
+	created := false
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName, command, outfile string, args ...string) (string, error) {
+			if args[0] == "osd" && args[1] == "pool" && args[2] == "create" {
+				created = true
+			}
+			return "", nil
+		},
+	}
+	p := &cephv1.CephBlockPool{ObjectMeta: metav1.ObjectMeta{Name: "mypool", Namespace: "myns"}}
+	p.Spec.Replicated.Size = 1
+	context := &clusterd.Context{
+		Executor:      executor,
+		Clientset:     testop.New(1),
+		RookClientset: rookfake.NewSimpleClientset(p),
+	}
+	controller := NewPoolController(context, nil, nil)
+
+	// a pool that was removed is ignored
+	assert.Nil(t, controller.reconcile("myns", "otherpool"))
+	assert.False(t, created)
+
+	// the finalizer is added and the pool created
+	assert.Nil(t, controller.reconcile("myns", "mypool"))
+	assert.True(t, created)
+	latest, err := context.RookClientset.CephV1().CephBlockPools("myns").Get("mypool", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{finalizerName}, latest.Finalizers)
+}
+
 func TestUpdatePool(t *testing.T) {
 	// the pool did not change for properties that are updatable
 	old := cephv1.PoolSpec{FailureDomain: "osd", ErasureCoded: cephv1.ErasureCodedSpec{CodingChunks: 2, DataChunks: 2}}
@@ -185,6 +265,103 @@ func TestDeletePool(t *testing.T) {
 	assert.Nil(t, err)
 }
 
+func TestHandleDeletion(t *testing.T) {
+	images := `[{"image":"image1","size":1048576,"format":2}]`
+	trash := "[]"
+	objects := 3
+	deleted := false
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName string, command string, args ...string) (string, error) {
+			assert.Equal(t, "rbd", command)
+			if args[0] == "ls" {
+				return images, nil
+			}
+			if args[0] == "trash" && args[1] == "ls" {
+				return trash, nil
+			}
+			return "", nil
+		},
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName, command, outfile string, args ...string) (string, error) {
+			if args[0] == "df" {
+				return fmt.Sprintf(`{"pools":[{"name":"mypool","id":1,"stats":{"objects":%d}}]}`, objects), nil
+			}
+			if args[1] == "lspools" {
+				return `[{"poolnum":1,"poolname":"mypool"}]`, nil
+			}
+			if args[1] == "pool" && args[2] == "get" {
+				return `{"pool": "mypool","pool_id": 1,"size":1}`, nil
+			}
+			if args[1] == "pool" && args[2] == "delete" {
+				deleted = true
+			}
+			return "", nil
+		},
+	}
+	now := metav1.Now()
+	cluster := &cephv1.CephCluster{ObjectMeta: metav1.ObjectMeta{Name: "mycluster", Namespace: "myns"}}
+	pool := &cephv1.CephBlockPool{ObjectMeta: metav1.ObjectMeta{
+		Name: "mypool", Namespace: "myns", Finalizers: []string{finalizerName}, DeletionTimestamp: &now}}
+	context := &clusterd.Context{
+		Executor:      executor,
+		Clientset:     testop.New(1),
+		RookClientset: rookfake.NewSimpleClientset(cluster, pool),
+	}
+	controller := NewPoolController(context, nil, nil)
+
+	// the deletion is blocked while the pool holds images
+	err := controller.handleDeletion(pool)
+	assert.NotNil(t, err)
+	latest, err := context.RookClientset.CephV1().CephBlockPools("myns").Get("mypool", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.False(t, deleted)
+	assert.Equal(t, cephv1.ResourcePhaseDeletionBlocked, latest.Status.Phase)
+	assert.Equal(t, "pool mypool holds 1 images", latest.Status.Message)
+	assert.Equal(t, []string{finalizerName}, latest.Finalizers)
+
+	// or images in the trash
+	images = "[]"
+	trash = `[{"id":"1234","name":"image1"}]`
+	err = controller.handleDeletion(latest)
+	assert.NotNil(t, err)
+	latest, err = context.RookClientset.CephV1().CephBlockPools("myns").Get("mypool", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.False(t, deleted)
+	assert.Equal(t, "pool mypool holds 1 images in the trash", latest.Status.Message)
+
+	// or objects other than the rbd metadata
+	trash = "[]"
+	objects = 12
+	err = controller.handleDeletion(latest)
+	assert.NotNil(t, err)
+	latest, err = context.RookClientset.CephV1().CephBlockPools("myns").Get("mypool", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.False(t, deleted)
+	assert.Equal(t, "pool mypool holds 12 objects", latest.Status.Message)
+
+	// a pool without images is deleted and the finalizer removed
+	objects = 3
+	err = controller.handleDeletion(latest)
+	assert.Nil(t, err)
+	latest, err = context.RookClientset.CephV1().CephBlockPools("myns").Get("mypool", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.True(t, deleted)
+	assert.Equal(t, 0, len(latest.Finalizers))
+
+	// a preserved pool is not deleted even if it holds images
+	images = `[{"image":"image1","size":1048576,"format":2}]`
+	deleted = false
+	latest.Finalizers = []string{finalizerName}
+	latest.Spec.PreservePoolOnDelete = true
+	latest, err = context.RookClientset.CephV1().CephBlockPools("myns").Update(latest)
+	assert.Nil(t, err)
+	err = controller.handleDeletion(latest)
+	assert.Nil(t, err)
+	latest, err = context.RookClientset.CephV1().CephBlockPools("myns").Get("mypool", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.False(t, deleted)
+	assert.Equal(t, 0, len(latest.Finalizers))
+}
+
 func TestGetPoolObject(t *testing.T) {
 	// get a current version pool object, should return with no error and no migration needed
 	pool, migrationNeeded, err := getPoolObject(&cephv1.CephBlockPool{})
@@ -220,7 +397,7 @@ func TestMigratePoolObject(t *testing.T) {
 		Clientset:     clientset,
 		RookClientset: rookfake.NewSimpleClientset(legacyPool),
 	}
-	controller := NewPoolController(context)
+	controller := NewPoolController(context, nil, nil)
 
 	// convert the legacy pool object in memory and assert that a migration is needed
 	convertedPool, migrationNeeded, err := getPoolObject(legacyPool)
@@ -19,6 +19,7 @@ package provisioner
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/coreos/pkg/capnslog"
@@ -60,6 +61,12 @@ type provisionerConfig struct {
 
 	// Optional: For erasure coded pools the data pool must be given
 	dataBlockPool string
+
+	// Optional: The rbd client attaching the volume, `rbd` or `nbd`. Default is `rbd`
+	mounter string
+
+	// Optional: The features, object size, striping and qos limits of the image. Default is the cluster defaults
+	imageOptions ceph.ImageOptions
 }
 
 // New creates RookVolumeProvisioner
@@ -95,7 +102,7 @@ func (p *RookVolumeProvisioner) Provision(options controller.VolumeOptions) (*v1
 		return nil, err
 	}
 
-	blockImage, err := p.createVolume(imageName, cfg.blockPool, cfg.dataBlockPool, cfg.clusterNamespace, requestBytes)
+	blockImage, err := p.createVolume(imageName, cfg.blockPool, cfg.dataBlockPool, cfg.clusterNamespace, requestBytes, cfg.imageOptions)
 	if err != nil {
 		return nil, err
 	}
@@ -134,6 +141,7 @@ func (p *RookVolumeProvisioner) Provision(options controller.VolumeOptions) (*v1
 						flexvolume.ImageKey:            imageName,
 						flexvolume.ClusterNamespaceKey: cfg.clusterNamespace,
 						flexvolume.DataBlockPoolKey:    cfg.dataBlockPool,
+						flexvolume.MounterKey:          cfg.mounter,
 					},
 				},
 			},
@@ -144,12 +152,12 @@ func (p *RookVolumeProvisioner) Provision(options controller.VolumeOptions) (*v1
 }
 
 // createVolume creates a rook block volume.
-func (p *RookVolumeProvisioner) createVolume(image, pool, dataPool string, clusterNamespace string, size int64) (*ceph.CephBlockImage, error) {
+func (p *RookVolumeProvisioner) createVolume(image, pool, dataPool string, clusterNamespace string, size int64, options ceph.ImageOptions) (*ceph.CephBlockImage, error) {
 	if image == "" || pool == "" || clusterNamespace == "" || size == 0 {
 		return nil, fmt.Errorf("image missing required fields (image=%s, pool=%s, clusterNamespace=%s, size=%d)", image, pool, clusterNamespace, size)
 	}
 
-	createdImage, err := ceph.CreateImage(p.context, clusterNamespace, image, pool, dataPool, uint64(size))
+	createdImage, err := ceph.CreateImage(p.context, clusterNamespace, image, pool, dataPool, uint64(size), options)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to create rook block image %s/%s: %v", pool, image, err)
 	}
@@ -194,6 +202,7 @@ func parseStorageClass(options controller.VolumeOptions) (string, error) {
 
 func parseClassParameters(params map[string]string) (*provisionerConfig, error) {
 	var cfg provisionerConfig
+	var err error
 
 	for k, v := range params {
 		switch strings.ToLower(k) {
@@ -209,9 +218,30 @@ func parseClassParameters(params map[string]string) (*provisionerConfig, error)
 			cfg.fstype = v
 		case "datablockpool":
 			cfg.dataBlockPool = v
+		case "mounter":
+			cfg.mounter = v
+		case "imagefeatures":
+			for _, feature := range strings.Split(v, ",") {
+				if feature = strings.TrimSpace(feature); feature != "" {
+					cfg.imageOptions.Features = append(cfg.imageOptions.Features, feature)
+				}
+			}
+		case "objectsize":
+			cfg.imageOptions.ObjectSize, err = parseBytes(k, v)
+		case "stripeunit":
+			cfg.imageOptions.StripeUnit, err = parseBytes(k, v)
+		case "stripecount":
+			cfg.imageOptions.StripeCount, err = parseUint(k, v)
+		case "qosiopslimit":
+			cfg.imageOptions.QosIopsLimit, err = parseUint(k, v)
+		case "qosbpslimit":
+			cfg.imageOptions.QosBpsLimit, err = parseBytes(k, v)
 		default:
 			return nil, fmt.Errorf("invalid option %q for volume plugin %s", k, "rookVolumeProvisioner")
 		}
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	if len(cfg.blockPool) == 0 {
@@ -222,5 +252,39 @@ func parseClassParameters(params map[string]string) (*provisionerConfig, error)
 		cfg.clusterNamespace = cluster.DefaultClusterName
 	}
 
+	if cfg.mounter != "" && cfg.mounter != flexvolume.MounterRBD && cfg.mounter != flexvolume.MounterNbd {
+		return nil, fmt.Errorf("invalid mounter %q for volume plugin %s, must be %q or %q", cfg.mounter, "rookVolumeProvisioner", flexvolume.MounterRBD, flexvolume.MounterNbd)
+	}
+
+	if err := cfg.imageOptions.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid image options for volume plugin %s: %+v", "rookVolumeProvisioner", err)
+	}
+
 	return &cfg, nil
 }
+
+func parseUint(key, value string) (uint64, error) {
+	n, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid option %q value %q for volume plugin %s, must be a positive integer", key, value, "rookVolumeProvisioner")
+	}
+	return n, nil
+}
+
+// parseBytes parses a number of bytes with an optional K, M or G suffix in powers of 1024, as the rbd tool does
+func parseBytes(key, value string) (uint64, error) {
+	units := map[string]uint64{"K": 1 << 10, "M": 1 << 20, "G": 1 << 30}
+	number := strings.TrimSuffix(strings.ToUpper(value), "I")
+	unit := uint64(1)
+	if len(number) > 0 {
+		if u, ok := units[number[len(number)-1:]]; ok {
+			unit = u
+			number = number[:len(number)-1]
+		}
+	}
+	n, err := strconv.ParseUint(number, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid option %q value %q for volume plugin %s, must be a size in bytes with an optional K, M or G suffix", key, value, "rookVolumeProvisioner")
+	}
+	return n * unit, nil
+}
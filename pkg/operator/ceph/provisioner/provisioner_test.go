@@ -189,6 +189,54 @@ func TestParseClassParametersInvalidOption(t *testing.T) {
 	assert.EqualError(t, err, "invalid option \"foo\" for volume plugin rookVolumeProvisioner")
 }
 
+func TestParseClassParametersImageOptions(t *testing.T) {
+	cfg := make(map[string]string)
+	cfg["pool"] = "testPool"
+	cfg["imageFeatures"] = "layering, exclusive-lock,journaling"
+	cfg["objectSize"] = "8M"
+	cfg["stripeUnit"] = "64Ki"
+	cfg["stripeCount"] = "16"
+	cfg["qosIopsLimit"] = "500"
+	cfg["qosBpsLimit"] = "104857600"
+
+	provConfig, err := parseClassParameters(cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"layering", "exclusive-lock", "journaling"}, provConfig.imageOptions.Features)
+	assert.Equal(t, uint64(8388608), provConfig.imageOptions.ObjectSize)
+	assert.Equal(t, uint64(65536), provConfig.imageOptions.StripeUnit)
+	assert.Equal(t, uint64(16), provConfig.imageOptions.StripeCount)
+	assert.Equal(t, uint64(500), provConfig.imageOptions.QosIopsLimit)
+	assert.Equal(t, uint64(104857600), provConfig.imageOptions.QosBpsLimit)
+
+	cfg["qosIopsLimit"] = "-1"
+	_, err = parseClassParameters(cfg)
+	assert.EqualError(t, err, "invalid option \"qosIopsLimit\" value \"-1\" for volume plugin rookVolumeProvisioner, must be a positive integer")
+
+	cfg["qosIopsLimit"] = "500"
+	cfg["objectSize"] = "3M"
+	_, err = parseClassParameters(cfg)
+	assert.NotNil(t, err)
+
+	cfg["objectSize"] = "8M"
+	cfg["imageFeatures"] = "layering,journaling"
+	_, err = parseClassParameters(cfg)
+	assert.EqualError(t, err, "invalid image options for volume plugin rookVolumeProvisioner: image feature journaling requires the feature exclusive-lock")
+}
+
+func TestParseClassParametersMounter(t *testing.T) {
+	cfg := make(map[string]string)
+	cfg["pool"] = "testPool"
+	cfg["mounter"] = "nbd"
+
+	provConfig, err := parseClassParameters(cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, "nbd", provConfig.mounter)
+
+	cfg["mounter"] = "fuse"
+	_, err = parseClassParameters(cfg)
+	assert.EqualError(t, err, "invalid mounter \"fuse\" for volume plugin rookVolumeProvisioner, must be \"rbd\" or \"nbd\"")
+}
+
 func newVolumeOptions(storageClass *storagebeta.StorageClass, claim *v1.PersistentVolumeClaim, reclaimPolicy v1.PersistentVolumeReclaimPolicy) controller.VolumeOptions {
 	return controller.VolumeOptions{
 		PersistentVolumeReclaimPolicy: reclaimPolicy,
@@ -0,0 +1,107 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconcile
+
+import (
+	"fmt"
+	"sync"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+)
+
+const (
+	// ReasonReconciled is the reason of the events emitted when a resource converged to its spec
+	ReasonReconciled = "Reconciled"
+	// ReasonReconcileFailed is the reason of the events emitted when the reconciliation of a resource failed
+	ReasonReconcileFailed = "ReconcileFailed"
+	// ReasonDeleted is the reason of the events emitted when the Ceph resources of a deleted resource are removed
+	ReasonDeleted = "Deleted"
+)
+
+// Reporter records the outcome of the reconciliations as events on the resources, so that describing a resource shows
+// why it did not converge. A success is only recorded the first time a resource is reconciled and after a failure, so
+// the periodic resyncs do not flood the events.
+type Reporter struct {
+	recorder   record.EventRecorder
+	reconciled map[string]bool
+	mutex      sync.Mutex
+}
+
+// NewReporter creates the reporter of a controller. The events are only logged if the recorder is nil.
+func NewReporter(recorder record.EventRecorder) *Reporter {
+	return &Reporter{
+		recorder:   recorder,
+		reconciled: map[string]bool{},
+	}
+}
+
+// Succeeded records a normal event on the resource if it was not reconciled yet or its last reconciliation failed
+func (r *Reporter) Succeeded(obj runtime.Object, messageFmt string, args ...interface{}) {
+	if !r.setReconciled(obj, true) {
+		r.Normal(obj, ReasonReconciled, messageFmt, args...)
+	}
+}
+
+// Failed records a warning event with the error of the reconciliation of the resource. The error is returned.
+func (r *Reporter) Failed(obj runtime.Object, err error) error {
+	r.setReconciled(obj, false)
+	r.Warning(obj, ReasonReconcileFailed, "%+v", err)
+	return err
+}
+
+// Deleted records a normal event on a resource whose Ceph resources were removed
+func (r *Reporter) Deleted(obj runtime.Object, messageFmt string, args ...interface{}) {
+	r.setReconciled(obj, false)
+	r.Normal(obj, ReasonDeleted, messageFmt, args...)
+}
+
+// Normal records a normal event with the given reason on the resource
+func (r *Reporter) Normal(obj runtime.Object, reason, messageFmt string, args ...interface{}) {
+	logger.Infof("%s: %s", reason, fmt.Sprintf(messageFmt, args...))
+	if r.recorder != nil {
+		r.recorder.Eventf(obj, v1.EventTypeNormal, reason, messageFmt, args...)
+	}
+}
+
+// Warning records a warning event with the given reason on the resource, for a failure that may not fail the
+// reconciliation
+func (r *Reporter) Warning(obj runtime.Object, reason, messageFmt string, args ...interface{}) {
+	logger.Warningf("%s: %s", reason, fmt.Sprintf(messageFmt, args...))
+	if r.recorder != nil {
+		r.recorder.Eventf(obj, v1.EventTypeWarning, reason, messageFmt, args...)
+	}
+}
+
+// setReconciled sets whether the last reconciliation of the resource succeeded. Returns the previous value.
+func (r *Reporter) setReconciled(obj runtime.Object, reconciled bool) bool {
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		return false
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	previous := r.reconciled[key]
+	if reconciled {
+		r.reconciled[key] = true
+	} else {
+		delete(r.reconciled, key)
+	}
+	return previous
+}
@@ -0,0 +1,57 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconcile
+
+import (
+	"fmt"
+	"testing"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestReporter(t *testing.T) {
+	events := record.NewFakeRecorder(10)
+	r := NewReporter(events)
+	pool := &cephv1.CephBlockPool{ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "ns"}}
+
+	// the first success is recorded, not the resyncs
+	r.Succeeded(pool, "created pool %s", pool.Name)
+	r.Succeeded(pool, "created pool %s", pool.Name)
+	assert.Equal(t, "Normal Reconciled created pool a", <-events.Events)
+	assert.Equal(t, 0, len(events.Events))
+
+	// every failure is recorded, and the success following them
+	err := r.Failed(pool, fmt.Errorf("failed to create pool a. mon hiccup"))
+	assert.EqualError(t, err, "failed to create pool a. mon hiccup")
+	r.Failed(pool, fmt.Errorf("failed to create pool a. mon hiccup"))
+	r.Succeeded(pool, "created pool %s", pool.Name)
+	assert.Equal(t, "Warning ReconcileFailed failed to create pool a. mon hiccup", <-events.Events)
+	assert.Equal(t, "Warning ReconcileFailed failed to create pool a. mon hiccup", <-events.Events)
+	assert.Equal(t, "Normal Reconciled created pool a", <-events.Events)
+
+	// a recreated resource is reported again
+	r.Deleted(pool, "deleted pool %s", pool.Name)
+	r.Succeeded(pool, "created pool %s", pool.Name)
+	assert.Equal(t, "Normal Deleted deleted pool a", <-events.Events)
+	assert.Equal(t, "Normal Reconciled created pool a", <-events.Events)
+
+	// the events are only logged without a recorder
+	NewReporter(nil).Failed(pool, fmt.Errorf("mon hiccup"))
+}
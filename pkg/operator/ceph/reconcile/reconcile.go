@@ -0,0 +1,172 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package reconcile reconciles the Ceph custom resources from a rate limited work queue. The failed reconciliations
+// are retried with an exponential backoff and all the resources are reconciled again periodically.
+package reconcile
+
+import (
+	"sync"
+	"time"
+
+	"github.com/coreos/pkg/capnslog"
+	"github.com/rook/rook/pkg/operator/metrics"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+var logger = capnslog.NewPackageLogger("github.com/rook/rook", "op-reconcile")
+
+var (
+	// ResyncPeriod is the interval between two reconciliations of all the resources, repairing the drift from their spec
+	ResyncPeriod = time.Hour
+	// RetryBaseDelay is the delay before the first retry of a failed reconciliation, doubled at each failure
+	RetryBaseDelay = time.Second
+	// RetryMaxDelay is the maximum delay between the retries of a failed reconciliation
+	RetryMaxDelay = 5 * time.Minute
+)
+
+// Func reconciles the resource with the given namespace and name. The resource may not exist anymore.
+// The reconciliation is retried if an error is returned.
+type Func func(namespace, name string) error
+
+// Queue reconciles the resources of a controller one at a time, so a resource is never reconciled concurrently
+type Queue struct {
+	name      string
+	reconcile Func
+	queue     workqueue.RateLimitingInterface
+	keys      map[string]bool
+	// the last state of the deleted resources, kept until their deletion is reconciled
+	deleted map[string]interface{}
+	mutex   sync.Mutex
+}
+
+// New creates the queue of the controller with the given name
+func New(name string, reconcile Func) *Queue {
+	return &Queue{
+		name:      name,
+		reconcile: reconcile,
+		queue:     workqueue.NewNamedRateLimitingQueue(workqueue.NewItemExponentialFailureRateLimiter(RetryBaseDelay, RetryMaxDelay), name),
+		keys:      map[string]bool{},
+		deleted:   map[string]interface{}{},
+	}
+}
+
+// Add queues the reconciliation of the resource. The resource is reconciled again at each resync.
+func (q *Queue) Add(obj interface{}) {
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		logger.Errorf("failed to get the key of %s %+v. %+v", q.name, obj, err)
+		return
+	}
+	q.mutex.Lock()
+	q.keys[key] = true
+	delete(q.deleted, key)
+	q.mutex.Unlock()
+	q.queue.Add(key)
+}
+
+// Remove queues the reconciliation of the deleted resource. The resource is not resynced anymore. The deleted object
+// is kept until its deletion is reconciled, see Deleted.
+func (q *Queue) Remove(obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		logger.Errorf("failed to get the key of %s %+v. %+v", q.name, obj, err)
+		return
+	}
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	q.mutex.Lock()
+	delete(q.keys, key)
+	q.deleted[key] = obj
+	q.mutex.Unlock()
+	q.queue.Add(key)
+}
+
+// Deleted returns the last state of the deleted resource with the given namespace and name, or nil if the resource was
+// not deleted. The reconciliation of a resource deleted before it was ever reconciled cleans up from this state.
+func (q *Queue) Deleted(namespace, name string) interface{} {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return q.deleted[namespace+"/"+name]
+}
+
+// Start reconciles the queued resources in the background until the stop channel is closed
+func (q *Queue) Start(stopCh <-chan struct{}) {
+	go func() {
+		<-stopCh
+		q.queue.ShutDown()
+	}()
+	go wait.Until(q.runWorker, time.Second, stopCh)
+	go func() {
+		ticker := time.NewTicker(ResyncPeriod)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				q.resync()
+			case <-stopCh:
+				return
+			}
+		}
+	}()
+}
+
+func (q *Queue) resync() {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	logger.Debugf("resyncing %d %s resources", len(q.keys), q.name)
+	for key := range q.keys {
+		q.queue.Add(key)
+	}
+}
+
+func (q *Queue) runWorker() {
+	for q.processNextItem() {
+	}
+}
+
+func (q *Queue) processNextItem() bool {
+	item, shutdown := q.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer q.queue.Done(item)
+
+	key := item.(string)
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		logger.Errorf("invalid %s key %s. %+v", q.name, key, err)
+		q.queue.Forget(item)
+		return true
+	}
+
+	start := time.Now()
+	err = q.reconcile(namespace, name)
+	metrics.ObserveReconcile(q.name, namespace, name, start, err)
+	if err != nil {
+		logger.Errorf("failed to reconcile %s %s. retrying. %+v", q.name, key, err)
+		q.queue.AddRateLimited(item)
+		return true
+	}
+	q.queue.Forget(item)
+	q.mutex.Lock()
+	delete(q.deleted, key)
+	q.mutex.Unlock()
+	return true
+}
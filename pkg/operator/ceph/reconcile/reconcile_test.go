@@ -0,0 +1,127 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconcile
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+type recorder struct {
+	keys chan string
+	errs []error
+}
+
+func (r *recorder) reconcile(namespace, name string) error {
+	r.keys <- fmt.Sprintf("%s/%s", namespace, name)
+	if len(r.errs) == 0 {
+		return nil
+	}
+	err := r.errs[0]
+	r.errs = r.errs[1:]
+	return err
+}
+
+func (r *recorder) next(t *testing.T) string {
+	select {
+	case key := <-r.keys:
+		return key
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "timed out waiting for a reconciliation")
+		return ""
+	}
+}
+
+func (r *recorder) none(t *testing.T, wait time.Duration) {
+	select {
+	case key := <-r.keys:
+		assert.Fail(t, "unexpected reconciliation of "+key)
+	case <-time.After(wait):
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	RetryBaseDelay = time.Millisecond
+	RetryMaxDelay = 10 * time.Millisecond
+	r := &recorder{keys: make(chan string, 10), errs: []error{fmt.Errorf("mon hiccup"), fmt.Errorf("mon hiccup")}}
+	q := New("test", r.reconcile)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	q.Start(stopCh)
+
+	// a failed reconciliation is retried until it succeeds
+	q.Add(&cephv1.CephBlockPool{ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "ns"}})
+	assert.Equal(t, "ns/a", r.next(t))
+	assert.Equal(t, "ns/a", r.next(t))
+	assert.Equal(t, "ns/a", r.next(t))
+	r.none(t, 50*time.Millisecond)
+
+	// the deleted resources are reconciled once more
+	q.Remove(&cephv1.CephBlockPool{ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "ns"}})
+	assert.Equal(t, "ns/a", r.next(t))
+	r.none(t, 50*time.Millisecond)
+}
+
+func TestResync(t *testing.T) {
+	r := &recorder{keys: make(chan string, 10)}
+	q := New("test", r.reconcile)
+	q.Add(&cephv1.CephBlockPool{ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "ns"}})
+	q.Add(&cephv1.CephBlockPool{ObjectMeta: metav1.ObjectMeta{Name: "b", Namespace: "ns"}})
+	q.Remove(&cephv1.CephBlockPool{ObjectMeta: metav1.ObjectMeta{Name: "b", Namespace: "ns"}})
+	for q.queue.Len() > 0 {
+		assert.True(t, q.processNextItem())
+	}
+	assert.Equal(t, 2, len(r.keys))
+	<-r.keys
+	<-r.keys
+
+	// only the resources that were not deleted are resynced
+	q.resync()
+	assert.Equal(t, 1, q.queue.Len())
+	assert.True(t, q.processNextItem())
+	assert.Equal(t, "ns/a", <-r.keys)
+}
+
+func TestDeleted(t *testing.T) {
+	r := &recorder{keys: make(chan string, 10), errs: []error{fmt.Errorf("mon hiccup")}}
+	q := New("test", r.reconcile)
+	pool := &cephv1.CephBlockPool{ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "ns"}}
+	assert.Nil(t, q.Deleted("ns", "a"))
+
+	// the deleted object is kept until its deletion is reconciled
+	q.Remove(cache.DeletedFinalStateUnknown{Key: "ns/a", Obj: pool})
+	assert.Equal(t, pool, q.Deleted("ns", "a"))
+	assert.True(t, q.processNextItem())
+	assert.Equal(t, "ns/a", <-r.keys)
+	assert.Equal(t, pool, q.Deleted("ns", "a"))
+	q.queue.Forget("ns/a")
+	q.queue.Add("ns/a")
+	assert.True(t, q.processNextItem())
+	assert.Equal(t, "ns/a", <-r.keys)
+	assert.Nil(t, q.Deleted("ns", "a"))
+
+	// a recreated resource is not deleted anymore
+	q.Remove(pool)
+	q.Add(pool)
+	assert.Nil(t, q.Deleted("ns", "a"))
+}
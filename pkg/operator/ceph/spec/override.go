@@ -0,0 +1,39 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spec
+
+import (
+	"fmt"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	"github.com/rook/rook/pkg/clusterd"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GetDaemonOverrides returns the overrides of the daemons from the current cluster of the namespace. The controllers of
+// the daemons started by the CRDs call it at each reconcile to apply the overrides changed after they started. No
+// override is returned if the namespace has no cluster.
+func GetDaemonOverrides(context *clusterd.Context, namespace string) (cephv1.DaemonOverridesSpec, error) {
+	clusters, err := context.RookClientset.CephV1().CephClusters(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the cluster of namespace %s. %+v", namespace, err)
+	}
+	if len(clusters.Items) == 0 {
+		return nil, nil
+	}
+	return clusters.Items[0].Spec.DaemonOverrides, nil
+}
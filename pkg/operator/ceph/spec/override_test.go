@@ -0,0 +1,57 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spec
+
+import (
+	"testing"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	rookfake "github.com/rook/rook/pkg/client/clientset/versioned/fake"
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGetDaemonOverrides(t *testing.T) {
+	context := &clusterd.Context{RookClientset: rookfake.NewSimpleClientset()}
+
+	// no cluster in the namespace
+	overrides, err := GetDaemonOverrides(context, "ns")
+	assert.Nil(t, err)
+	assert.Equal(t, cephv1.DaemonOverride{}, cephv1.GetMDSOverride(overrides))
+
+	cluster := &cephv1.CephCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "rook-ceph", Namespace: "ns"},
+		Spec: cephv1.ClusterSpec{
+			DaemonOverrides: cephv1.DaemonOverridesSpec{cephv1.DaemonKeyMDS: {Image: "ceph/ceph:v14-hotfix"}},
+		},
+	}
+	_, err = context.RookClientset.CephV1().CephClusters("ns").Create(cluster)
+	assert.Nil(t, err)
+	overrides, err = GetDaemonOverrides(context, "ns")
+	assert.Nil(t, err)
+	assert.Equal(t, "ceph/ceph:v14-hotfix", cephv1.GetMDSOverride(overrides).Image)
+	assert.Equal(t, cephv1.DaemonOverride{}, cephv1.GetRGWOverride(overrides))
+
+	// the overrides changed after the controllers started are returned
+	cluster.Spec.DaemonOverrides[cephv1.DaemonKeyMDS] = cephv1.DaemonOverride{PriorityClassName: "storage"}
+	_, err = context.RookClientset.CephV1().CephClusters("ns").Update(cluster)
+	assert.Nil(t, err)
+	overrides, err = GetDaemonOverrides(context, "ns")
+	assert.Nil(t, err)
+	assert.Equal(t, cephv1.DaemonOverride{PriorityClassName: "storage"}, cephv1.GetMDSOverride(overrides))
+}
@@ -19,6 +19,7 @@ package spec
 
 import (
 	"fmt"
+	"path"
 
 	"github.com/coreos/pkg/capnslog"
 	cephconfig "github.com/rook/rook/pkg/daemon/ceph/config"
@@ -33,6 +34,9 @@ const (
 	// ConfigInitContainerName is the name which is given to the config initialization container
 	// in all Ceph pods.
 	ConfigInitContainerName = "config-init"
+
+	crashVolumeName = "rook-ceph-crash"
+	crashDirName    = "crash"
 )
 
 var logger = capnslog.NewPackageLogger("github.com/rook/rook", "ceph-spec")
@@ -67,6 +71,21 @@ func RookVolumeMounts() []v1.VolumeMount {
 	)
 }
 
+// CrashVolume returns the volume holding the crash reports written by the daemons, under the dataDirHostPath on the
+// host. The crash collector of the node posts the reports to the crash module of the mgr.
+func CrashVolume(dataDirHostPath string) v1.Volume {
+	src := v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}}
+	if dataDirHostPath != "" {
+		src = v1.VolumeSource{HostPath: &v1.HostPathVolumeSource{Path: path.Join(dataDirHostPath, crashDirName)}}
+	}
+	return v1.Volume{Name: crashVolumeName, VolumeSource: src}
+}
+
+// CrashVolumeMount returns the volume mount of the CrashVolume at the default crash dir of Ceph.
+func CrashVolumeMount() v1.VolumeMount {
+	return v1.VolumeMount{Name: crashVolumeName, MountPath: path.Join(config.VarLibCephDir, crashDirName)}
+}
+
 // DaemonVolumes returns the pod volumes used by all Ceph daemons.
 func DaemonVolumes(dataPaths *config.DataPathMap, keyringResourceName string) []v1.Volume {
 	vols := []v1.Volume{
@@ -21,6 +21,7 @@ import (
 
 	"github.com/rook/rook/pkg/operator/k8sutil"
 	"github.com/rook/rook/pkg/operator/test"
+	v1 "k8s.io/api/core/v1"
 )
 
 func TestPodVolumes(t *testing.T) {
@@ -32,6 +33,20 @@ func TestPodVolumes(t *testing.T) {
 	}
 }
 
+func TestCrashVolume(t *testing.T) {
+	vols := []v1.Volume{CrashVolume("")}
+	if err := test.VolumeIsEmptyDir(crashVolumeName, vols); err != nil {
+		t.Errorf("CrashVolume(\"\") - crash dir source is not EmptyDir: %s", err.Error())
+	}
+	vols = []v1.Volume{CrashVolume("/var/lib/rook")}
+	if err := test.VolumeIsHostPath(crashVolumeName, "/var/lib/rook/crash", vols); err != nil {
+		t.Errorf("CrashVolume(\"/var/lib/rook\") - crash dir source is not HostPath: %s", err.Error())
+	}
+	if CrashVolumeMount().MountPath != "/var/lib/ceph/crash" {
+		t.Errorf("CrashVolumeMount() - crash dir is not mounted at the default crash dir: %s", CrashVolumeMount().MountPath)
+	}
+}
+
 func TestMountsMatchVolumes(t *testing.T) {
 	volsMountsTestDef := test.VolumesAndMountsTestDefinition{
 		VolumesSpec: &test.VolumesSpec{
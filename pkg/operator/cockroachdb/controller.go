@@ -31,12 +31,15 @@ import (
 	rookv1alpha2 "github.com/rook/rook/pkg/apis/rook.io/v1alpha2"
 	"github.com/rook/rook/pkg/clusterd"
 	"github.com/rook/rook/pkg/operator/k8sutil"
+	"github.com/rook/rook/pkg/operator/metrics"
+	"github.com/rook/rook/pkg/operator/webhook"
 	appsv1beta1 "k8s.io/api/apps/v1beta1"
 	"k8s.io/api/core/v1"
 	policyv1beta1 "k8s.io/api/policy/v1beta1"
 	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/wait"
@@ -132,33 +135,39 @@ func (c *ClusterController) onAdd(obj interface{}) {
 
 	cluster := newCluster(clusterObj, c.context)
 
-	if err := validateClusterSpec(cluster.spec); err != nil {
+	start := time.Now()
+	var err error
+	defer func() {
+		metrics.ObserveReconcile(ClusterResource.Name, clusterObj.Namespace, clusterObj.Name, start, err)
+	}()
+
+	if err = validateClusterSpec(cluster.spec); err != nil {
 		logger.Errorf("invalid cluster spec: %+v", err)
 		return
 	}
 
-	if err := c.createClientService(cluster); err != nil {
+	if err = c.createClientService(cluster); err != nil {
 		logger.Errorf("failed to create client service: %+v", err)
 		return
 	}
 
-	if err := c.createReplicaService(cluster); err != nil {
+	if err = c.createReplicaService(cluster); err != nil {
 		logger.Errorf("failed to create replica service: %+v", err)
 		return
 	}
 
-	if err := c.createPodDisruptionBudget(cluster); err != nil {
+	if err = c.createPodDisruptionBudget(cluster); err != nil {
 		logger.Errorf("failed to create pod disruption budget: %+v", err)
 		return
 	}
 
-	if err := c.createStatefulSet(cluster); err != nil {
+	if err = c.createStatefulSet(cluster); err != nil {
 		logger.Errorf("failed to create stateful set: %+v", err)
 		return
 	}
 
 	// retry to init the cluster until it succeeds or times out
-	err := wait.Poll(c.createInitRetryInterval, createInitTimeout, func() (bool, error) {
+	err = wait.Poll(c.createInitRetryInterval, createInitTimeout, func() (bool, error) {
 		if err := c.isPodsRunning(cluster); err != nil {
 			logger.Warningf("pods are not yet running: %+v", err)
 			return false, nil
@@ -491,6 +500,16 @@ func (c *ClusterController) initCluster(cluster *cluster) error {
 	return nil
 }
 
+// Validator returns the validator of the cockroachdb clusters for the validating webhook
+func Validator() webhook.Validator {
+	return webhook.Validator{
+		NewObject: func() runtime.Object { return &cockroachdbv1alpha1.Cluster{} },
+		Validate: func(oldObj, newObj runtime.Object) error {
+			return validateClusterSpec(newObj.(*cockroachdbv1alpha1.Cluster).Spec)
+		},
+	}
+}
+
 func validateClusterSpec(spec cockroachdbv1alpha1.ClusterSpec) error {
 	if spec.Storage.NodeCount < 1 {
 		return fmt.Errorf("invalid node count: %d. Must be at least 1", spec.Storage.NodeCount)
@@ -18,18 +18,18 @@ limitations under the License.
 package cockroachdb
 
 import (
-	"os"
-	"os/signal"
-	"syscall"
-
 	"github.com/coreos/pkg/capnslog"
 	opkit "github.com/rook/operator-kit"
 	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/operator/leader"
 	"k8s.io/api/core/v1"
 )
 
 var logger = capnslog.NewPackageLogger("github.com/rook/rook", "cockroachdb-operator")
 
+// the name of the configmap storing the leader of the operator replicas
+const leaderLockName = "rook-cockroachdb-operator-leader"
+
 type Operator struct {
 	context           *clusterd.Context
 	resources         []opkit.CustomResource
@@ -52,20 +52,11 @@ func New(context *clusterd.Context, rookImage string) *Operator {
 
 // Run the operator instance
 func (o *Operator) Run() error {
+	return leader.Run(o.context.Clientset, leaderLockName, func(stopChan chan struct{}) error {
+		// watch for changes to the cockroachdb clusters
+		o.clusterController.StartWatch(v1.NamespaceAll, stopChan)
 
-	signalChan := make(chan os.Signal, 1)
-	stopChan := make(chan struct{})
-	signal.Notify(signalChan, syscall.SIGINT, syscall.SIGTERM)
-
-	// watch for changes to the cockroachdb clusters
-	o.clusterController.StartWatch(v1.NamespaceAll, stopChan)
-
-	for {
-		select {
-		case <-signalChan:
-			logger.Infof("shutdown signal received, exiting...")
-			close(stopChan)
-			return nil
-		}
-	}
+		<-stopChan
+		return nil
+	})
 }
@@ -302,6 +302,8 @@ func ListDevicesInUse(context *clusterd.Context, namespace, nodeName string) ([]
 func GetAvailableDevices(context *clusterd.Context, nodeName, clusterName string, devices []rookalpha.Device, filter string, useAllDevices bool) ([]rookalpha.Device, error) {
 	results := []rookalpha.Device{}
 	if len(devices) == 0 && len(filter) == 0 && !useAllDevices {
+		// the devices of the node are not selected anymore
+		claimStorageDevices(context, nodeName, clusterName, nil)
 		return results, nil
 	}
 	namespace := os.Getenv(k8sutil.PodNamespaceEnvVar)
@@ -396,5 +398,73 @@ func GetAvailableDevices(context *clusterd.Context, nodeName, clusterName string
 			}
 		}
 	}
+	claimStorageDevices(context, nodeName, clusterName, claimedDevices)
 	return results, nil
 }
+
+// claimStorageDevices records the cluster using the devices in their StorageDevice resources, and releases the
+// other devices of the node claimed by the cluster, which dropped out of its storage selection. The devices in use
+// configmap remains the reference for the claims, so a failure is only logged.
+func claimStorageDevices(context *clusterd.Context, nodeName, clusterName string, devices []sys.LocalDisk) {
+	claimed := map[string]bool{}
+	for _, d := range devices {
+		claimed[discoverDaemon.StorageDeviceName(nodeName, d.Name)] = true
+	}
+	listOpts := metav1.ListOptions{LabelSelector: fmt.Sprintf("%s=%s", discoverDaemon.NodeAttr, nodeName)}
+	updateStorageDeviceClaims(context, clusterName, listOpts, claimed)
+}
+
+// ClaimStorageDevices records the cluster using the devices of its OSDs, by node and device name, in their
+// StorageDevice resources, and releases the other devices claimed by the cluster. A failure is only logged.
+func ClaimStorageDevices(context *clusterd.Context, clusterName string, nodeDevices map[string][]string) {
+	claimed := map[string]bool{}
+	for node, devices := range nodeDevices {
+		for _, device := range devices {
+			claimed[discoverDaemon.StorageDeviceName(node, device)] = true
+		}
+	}
+	updateStorageDeviceClaims(context, clusterName, metav1.ListOptions{}, claimed)
+}
+
+// ReleaseStorageDevices clears the claims of a deleted cluster from the StorageDevice resources of all the nodes, so
+// that the devices can be used by another cluster. A failure is only logged.
+func ReleaseStorageDevices(context *clusterd.Context, clusterName string) {
+	updateStorageDeviceClaims(context, clusterName, metav1.ListOptions{}, nil)
+}
+
+// updateStorageDeviceClaims claims the listed StorageDevice resources in the claimed set for the cluster, and clears
+// the claims of the cluster from the others
+func updateStorageDeviceClaims(context *clusterd.Context, clusterName string, listOpts metav1.ListOptions, claimed map[string]bool) {
+	client := context.RookClientset.RookV1alpha2().StorageDevices()
+	devices, err := client.List(listOpts)
+	if err != nil {
+		logger.Warningf("failed to list the storage devices to update the claims of cluster %s. %+v", clusterName, err)
+		return
+	}
+	for i := range devices.Items {
+		device := &devices.Items[i]
+		if claimed[device.Name] {
+			if device.Spec.ClaimedBy == clusterName {
+				continue
+			}
+			if device.Spec.ClaimedBy != "" {
+				logger.Warningf("storage device %s claimed by cluster %s is also used by cluster %s", device.Name, device.Spec.ClaimedBy, clusterName)
+			}
+			device.Spec.ClaimedBy = clusterName
+			if _, err := client.Update(device); err != nil {
+				logger.Warningf("failed to claim storage device %s for cluster %s. %+v", device.Name, clusterName, err)
+			}
+			continue
+		}
+
+		if device.Spec.ClaimedBy != clusterName {
+			continue
+		}
+		device.Spec.ClaimedBy = ""
+		if _, err := client.Update(device); err != nil {
+			logger.Warningf("failed to release storage device %s claimed by cluster %s. %+v", device.Name, clusterName, err)
+			continue
+		}
+		logger.Infof("released storage device %s claimed by cluster %s", device.Name, clusterName)
+	}
+}
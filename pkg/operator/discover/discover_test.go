@@ -22,6 +22,7 @@ import (
 	"testing"
 
 	rookalpha "github.com/rook/rook/pkg/apis/rook.io/v1alpha2"
+	rookfake "github.com/rook/rook/pkg/client/clientset/versioned/fake"
 	"github.com/rook/rook/pkg/clusterd"
 	discoverDaemon "github.com/rook/rook/pkg/daemon/discover"
 	"github.com/rook/rook/pkg/operator/k8sutil"
@@ -93,8 +94,29 @@ func TestGetAvailableDevices(t *testing.T) {
 	}
 	_, err := clientset.CoreV1().ConfigMaps(ns).Create(cm)
 	assert.Nil(t, err)
+	rookClientset := rookfake.NewSimpleClientset()
+	device := &rookalpha.StorageDevice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   discoverDaemon.StorageDeviceName(nodeName, "sdc"),
+			Labels: map[string]string{discoverDaemon.NodeAttr: nodeName},
+		},
+		Spec: rookalpha.StorageDeviceSpec{Node: nodeName, Name: "sdc"},
+	}
+	_, err = rookClientset.RookV1alpha2().StorageDevices().Create(device)
+	assert.Nil(t, err)
+	// a device claimed by the cluster which is not selected anymore
+	released := &rookalpha.StorageDevice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   discoverDaemon.StorageDeviceName(nodeName, "sdb"),
+			Labels: map[string]string{discoverDaemon.NodeAttr: nodeName},
+		},
+		Spec: rookalpha.StorageDeviceSpec{Node: nodeName, Name: "sdb", ClaimedBy: ns},
+	}
+	_, err = rookClientset.RookV1alpha2().StorageDevices().Create(released)
+	assert.Nil(t, err)
 	context := &clusterd.Context{
-		Clientset: clientset,
+		Clientset:     clientset,
+		RookClientset: rookClientset,
 	}
 	d := []rookalpha.Device{
 		{
@@ -116,4 +138,18 @@ func TestGetAvailableDevices(t *testing.T) {
 	devices, err = GetAvailableDevices(context, nodeName, ns, d, "^sd.", false)
 	assert.Nil(t, err)
 	assert.Equal(t, 1, len(devices))
+
+	// the storage device is claimed by the cluster, and the device dropped out of the selection is released
+	device, err = rookClientset.RookV1alpha2().StorageDevices().Get("node123-sdc", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, ns, device.Spec.ClaimedBy)
+	device, err = rookClientset.RookV1alpha2().StorageDevices().Get("node123-sdb", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "", device.Spec.ClaimedBy)
+
+	// the claims are cleared when the cluster is deleted
+	ReleaseStorageDevices(context, ns)
+	device, err = rookClientset.RookV1alpha2().StorageDevices().Get("node123-sdc", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "", device.Spec.ClaimedBy)
 }
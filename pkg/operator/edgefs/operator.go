@@ -20,8 +20,6 @@ package edgefs
 import (
 	"fmt"
 	"os"
-	"os/signal"
-	"syscall"
 
 	"github.com/coreos/pkg/capnslog"
 	opkit "github.com/rook/operator-kit"
@@ -29,11 +27,15 @@ import (
 	"github.com/rook/rook/pkg/operator/discover"
 	"github.com/rook/rook/pkg/operator/edgefs/cluster"
 	"github.com/rook/rook/pkg/operator/k8sutil"
+	"github.com/rook/rook/pkg/operator/leader"
 	"k8s.io/api/core/v1"
 )
 
 var logger = capnslog.NewPackageLogger("github.com/rook/rook", "edgefs-operator")
 
+// the name of the configmap storing the leader of the operator replicas
+const leaderLockName = "rook-edgefs-operator-leader"
+
 type Operator struct {
 	context           *clusterd.Context
 	resources         []opkit.CustomResource
@@ -64,24 +66,17 @@ func (o *Operator) Run() error {
 		return fmt.Errorf("Rook operator namespace is not provided. Expose it via downward API in the rook operator manifest file using environment variable %s", k8sutil.PodNamespaceEnvVar)
 	}
 
-	rookDiscover := discover.New(o.context.Clientset)
-	if err := rookDiscover.Start(namespace, o.rookImage, o.securityAccount); err != nil {
-		return fmt.Errorf("Error starting device discovery daemonset: %v", err)
-	}
-
-	signalChan := make(chan os.Signal, 1)
-	stopChan := make(chan struct{})
-	signal.Notify(signalChan, syscall.SIGINT, syscall.SIGTERM)
+	return leader.Run(o.context.Clientset, leaderLockName, func(stopChan chan struct{}) error {
+		rookDiscover := discover.New(o.context.Clientset)
+		if err := rookDiscover.Start(namespace, o.rookImage, o.securityAccount); err != nil {
+			return fmt.Errorf("Error starting device discovery daemonset: %v", err)
+		}
 
-	// watch for changes to the edgefs clusters
-	o.clusterController.StartWatch(v1.NamespaceAll, stopChan)
+		// watch for changes to the edgefs clusters
+		o.clusterController.StartWatch(v1.NamespaceAll, stopChan)
 
-	for {
-		select {
-		case <-signalChan:
-			logger.Infof("shutdown signal received, exiting...")
-			close(stopChan)
-			return nil
-		}
-	}
+		<-stopChan
+		o.clusterController.StopWatch()
+		return nil
+	})
 }
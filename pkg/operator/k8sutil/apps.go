@@ -18,6 +18,7 @@ package k8sutil
 
 import (
 	"fmt"
+	"time"
 
 	apps "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -61,6 +62,39 @@ func CreateDaemonSet(name, namespace string, clientset kubernetes.Interface, ds
 	return err
 }
 
+// UpdateDaemonSetAndWait updates a daemonset and waits until its pods are all updated and available. The pods are
+// replaced one node at a time, so the wait is longer with more nodes.
+func UpdateDaemonSetAndWait(clientset kubernetes.Interface, namespace string, ds *apps.DaemonSet) error {
+	logger.Infof("updating daemonset %s", ds.Name)
+	updated, err := clientset.Apps().DaemonSets(namespace).Update(ds)
+	if err != nil {
+		return fmt.Errorf("failed to update daemonset %s. %+v", ds.Name, err)
+	}
+
+	sleepTime := 2
+	attemptsPerNode := 30
+	attempts := attemptsPerNode
+	for i := 0; i < attempts; i++ {
+		d, err := clientset.Apps().DaemonSets(namespace).Get(ds.Name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get daemonset %s. %+v", ds.Name, err)
+		}
+		desired := d.Status.DesiredNumberScheduled
+		if d.Status.ObservedGeneration >= updated.Generation && d.Status.UpdatedNumberScheduled == desired && d.Status.NumberAvailable == desired {
+			logger.Infof("finished waiting for updated daemonset %s", ds.Name)
+			return nil
+		}
+		if desired > 1 {
+			attempts = attemptsPerNode * int(desired)
+		}
+
+		logger.Debugf("daemonset %s status=%v", ds.Name, d.Status)
+		time.Sleep(time.Duration(sleepTime) * time.Second)
+	}
+
+	return fmt.Errorf("gave up waiting for daemonset %s to update", ds.Name)
+}
+
 // create a apps.statefulset and a headless svc
 func CreateStatefulSet(name, namespace, appName string, clientset kubernetes.Interface, ss *apps.StatefulSet) (*corev1.Service, error) {
 	label := ss.GetLabels()
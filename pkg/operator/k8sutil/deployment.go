@@ -22,6 +22,8 @@ import (
 	"github.com/rook/rook/pkg/clusterd"
 	"k8s.io/api/apps/v1"
 	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 )
@@ -119,6 +121,71 @@ func UpdateDeploymentAndWait(context *clusterd.Context, deployment *apps.Deploym
 	return nil, fmt.Errorf("gave up waiting for deployment %s to update", deployment.Name)
 }
 
+// RestartDeploymentAndWait restarts the pods of a deployment by deleting them, without changing the spec of the
+// deployment, and waits for the new pods to be ready
+func RestartDeploymentAndWait(clientset kubernetes.Interface, namespace, name string) error {
+	d, err := clientset.Apps().Deployments(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get deployment %s. %+v", name, err)
+	}
+	listOptions := metav1.ListOptions{LabelSelector: metav1.FormatLabelSelector(d.Spec.Selector)}
+	pods, err := clientset.CoreV1().Pods(namespace).List(listOptions)
+	if err != nil {
+		return fmt.Errorf("failed to list the pods of deployment %s. %+v", name, err)
+	}
+
+	logger.Infof("restarting deployment %s", name)
+	restarted := map[string]bool{}
+	for _, pod := range pods.Items {
+		err := clientset.CoreV1().Pods(namespace).Delete(pod.Name, &metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete pod %s of deployment %s. %+v", pod.Name, name, err)
+		}
+		restarted[pod.Name] = true
+	}
+
+	replicas := 1
+	if d.Spec.Replicas != nil {
+		replicas = int(*d.Spec.Replicas)
+	}
+	sleepTime := 2
+	attempts := 30
+	if d.Spec.ProgressDeadlineSeconds != nil {
+		// make the attempts double the progress deadline since the pod is both stopping and starting
+		attempts = 2 * (int(*d.Spec.ProgressDeadlineSeconds) / sleepTime)
+	}
+	for i := 0; i < attempts; i++ {
+		pods, err := clientset.CoreV1().Pods(namespace).List(listOptions)
+		if err != nil {
+			return fmt.Errorf("failed to list the pods of deployment %s. %+v", name, err)
+		}
+		ready := 0
+		for _, pod := range pods.Items {
+			if !restarted[pod.Name] && pod.DeletionTimestamp == nil && isPodReady(pod) {
+				ready++
+			}
+		}
+		if ready >= replicas {
+			logger.Infof("finished waiting for restarted deployment %s", name)
+			return nil
+		}
+
+		logger.Debugf("deployment %s has %d of %d restarted pods ready", name, ready, replicas)
+		time.Sleep(time.Duration(sleepTime) * time.Second)
+	}
+
+	return fmt.Errorf("gave up waiting for deployment %s to restart", name)
+}
+
+func isPodReady(pod corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
 // GetDeployments returns a list of deployment names labels matching a given selector
 // example of a label selector might be "app=rook-ceph-mon, mon!=b"
 // more: https://kubernetes.io/docs/concepts/overview/working-with-objects/labels/
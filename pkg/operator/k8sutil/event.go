@@ -0,0 +1,59 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package k8sutil
+
+import (
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+// NewEventRecorder returns a recorder writing the events of the given component to the Kubernetes API
+func NewEventRecorder(clientset kubernetes.Interface, component string) record.EventRecorder {
+	eventBroadcaster := record.NewBroadcaster()
+	eventBroadcaster.StartLogging(logger.Debugf)
+	eventBroadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: clientset.CoreV1().Events("")})
+	return eventBroadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: component})
+}
+
+// ObjectEventRecorder records the events of a single object. The events are not recorded if the recorder is nil.
+type ObjectEventRecorder struct {
+	recorder record.EventRecorder
+	obj      runtime.Object
+}
+
+// NewObjectEventRecorder creates the recorder of the events of the object
+func NewObjectEventRecorder(recorder record.EventRecorder, obj runtime.Object) *ObjectEventRecorder {
+	return &ObjectEventRecorder{recorder: recorder, obj: obj}
+}
+
+// Normalf records a normal event with the given reason on the object
+func (r *ObjectEventRecorder) Normalf(reason, messageFmt string, args ...interface{}) {
+	if r != nil && r.recorder != nil {
+		r.recorder.Eventf(r.obj, v1.EventTypeNormal, reason, messageFmt, args...)
+	}
+}
+
+// Warningf records a warning event with the given reason on the object
+func (r *ObjectEventRecorder) Warningf(reason, messageFmt string, args ...interface{}) {
+	if r != nil && r.recorder != nil {
+		r.recorder.Eventf(r.obj, v1.EventTypeWarning, reason, messageFmt, args...)
+	}
+}
@@ -0,0 +1,155 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package leader elects the replica of an operator running the controllers. The other replicas stand by and take over
+// when the leader stops renewing its leadership.
+package leader
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/coreos/pkg/capnslog"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+var logger = capnslog.NewPackageLogger("github.com/rook/rook", "op-leader")
+
+var (
+	// Enabled runs the controllers only in the replica holding the leadership. Every replica runs them if disabled.
+	Enabled = true
+	// LeaseDuration is the time the standby replicas wait for the leader to renew its leadership before taking over
+	LeaseDuration = 15 * time.Second
+	// RenewDeadline is the time the leader retries renewing its leadership before giving it up
+	RenewDeadline = 10 * time.Second
+	// RetryPeriod is the interval between the attempts to acquire or renew the leadership
+	RetryPeriod = 2 * time.Second
+)
+
+// Run runs the controllers of an operator until the process is asked to terminate. The run function starts the
+// controllers and blocks until the stop channel is closed, then stops them. The error of run is returned.
+//
+// With the leader election enabled, run is called once the replica holds the leadership stored in the configmap with
+// the given name in the namespace of the operator. The stop channel is closed if the leadership is lost, and an error
+// is returned once the controllers are stopped so the process exits and restarts as a standby.
+func Run(clientset kubernetes.Interface, name string, run func(stopCh chan struct{}) error) error {
+	signalChan := make(chan os.Signal, 1)
+	signal.Notify(signalChan, syscall.SIGINT, syscall.SIGTERM)
+	shutdown := make(chan struct{})
+	go func() {
+		<-signalChan
+		logger.Infof("shutdown signal received, exiting...")
+		close(shutdown)
+	}()
+
+	if !Enabled {
+		return run(shutdown)
+	}
+
+	namespace := os.Getenv(k8sutil.PodNamespaceEnvVar)
+	if namespace == "" {
+		return fmt.Errorf("the namespace of the leader election is not provided. Expose it via downward API in the rook operator manifest file using environment variable %s", k8sutil.PodNamespaceEnvVar)
+	}
+	identity := os.Getenv(k8sutil.PodNameEnvVar)
+	if identity == "" {
+		var err error
+		if identity, err = os.Hostname(); err != nil {
+			return fmt.Errorf("failed to get the identity of the operator. %+v", err)
+		}
+	}
+	return runElection(clientset, namespace, name, identity, shutdown, run)
+}
+
+func runElection(clientset kubernetes.Interface, namespace, name, identity string, shutdown <-chan struct{}, run func(stopCh chan struct{}) error) error {
+	lock, err := resourcelock.New(resourcelock.ConfigMapsResourceLock, namespace, name, clientset.CoreV1(), resourcelock.ResourceLockConfig{
+		Identity:      identity,
+		EventRecorder: k8sutil.NewEventRecorder(clientset, name),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create the lock of the leader election. %+v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-shutdown
+		cancel()
+	}()
+
+	leading := make(chan struct{})
+	stopped := make(chan struct{})
+	var runErr error
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Name:          name,
+		Lock:          lock,
+		LeaseDuration: LeaseDuration,
+		RenewDeadline: RenewDeadline,
+		RetryPeriod:   RetryPeriod,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				logger.Infof("%s acquired the leadership of %s, starting the controllers", identity, name)
+				close(leading)
+				stopCh := make(chan struct{})
+				go func() {
+					<-ctx.Done()
+					close(stopCh)
+				}()
+				// give up the leadership if the controllers fail
+				runErr = run(stopCh)
+				cancel()
+				close(stopped)
+			},
+			OnStoppedLeading: func() {
+				logger.Infof("%s is not leading %s anymore", identity, name)
+			},
+			OnNewLeader: func(leader string) {
+				if leader != identity {
+					logger.Infof("%s is the leader of %s, standing by", leader, name)
+				}
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create the leader election. %+v", err)
+	}
+
+	logger.Infof("%s waiting for the leadership of %s in namespace %s", identity, name, namespace)
+	elector.Run(ctx)
+
+	// wait for the controllers to stop before returning
+	select {
+	case <-leading:
+		<-stopped
+		if runErr != nil {
+			return runErr
+		}
+	default:
+	}
+
+	select {
+	case <-shutdown:
+		return nil
+	default:
+		return fmt.Errorf("%s lost the leadership of %s", identity, name)
+	}
+}
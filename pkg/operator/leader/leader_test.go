@@ -0,0 +1,131 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package leader
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	testop "github.com/rook/rook/pkg/operator/test"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+const (
+	testNamespace = "rook-system"
+	testLock      = "rook-test-operator"
+)
+
+func setTestDurations() {
+	LeaseDuration = time.Second
+	RenewDeadline = 500 * time.Millisecond
+	RetryPeriod = 100 * time.Millisecond
+}
+
+func startElection(clientset kubernetes.Interface, shutdown chan struct{}) (chan struct{}, chan struct{}, chan error) {
+	started := make(chan struct{})
+	stopped := make(chan struct{})
+	result := make(chan error, 1)
+	go func() {
+		result <- runElection(clientset, testNamespace, testLock, "operator-a", shutdown, func(stopCh chan struct{}) error {
+			close(started)
+			<-stopCh
+			close(stopped)
+			return nil
+		})
+	}()
+	return started, stopped, result
+}
+
+func TestShutdown(t *testing.T) {
+	setTestDurations()
+	clientset := testop.New(1)
+	shutdown := make(chan struct{})
+	started, stopped, result := startElection(clientset, shutdown)
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the leadership was not acquired")
+	}
+	cm, err := clientset.CoreV1().ConfigMaps(testNamespace).Get(testLock, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Contains(t, cm.Annotations[resourcelock.LeaderElectionRecordAnnotationKey], "operator-a")
+
+	// the controllers are stopped before the election returns
+	close(shutdown)
+	select {
+	case err := <-result:
+		assert.Nil(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the election did not return")
+	}
+	select {
+	case <-stopped:
+	default:
+		t.Fatal("the controllers were not stopped")
+	}
+}
+
+func TestLostLeadership(t *testing.T) {
+	setTestDurations()
+	clientset := testop.New(1)
+	shutdown := make(chan struct{})
+	defer close(shutdown)
+	started, stopped, result := startElection(clientset, shutdown)
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the leadership was not acquired")
+	}
+
+	// another replica takes over the lock until the election gives up the leadership
+	deadline := time.After(10 * time.Second)
+	for {
+		record := resourcelock.LeaderElectionRecord{
+			HolderIdentity:       "operator-b",
+			LeaseDurationSeconds: 1,
+			AcquireTime:          metav1.Now(),
+			RenewTime:            metav1.Now(),
+		}
+		data, err := json.Marshal(record)
+		assert.Nil(t, err)
+		cm, err := clientset.CoreV1().ConfigMaps(testNamespace).Get(testLock, metav1.GetOptions{})
+		assert.Nil(t, err)
+		cm.Annotations[resourcelock.LeaderElectionRecordAnnotationKey] = string(data)
+		_, err = clientset.CoreV1().ConfigMaps(testNamespace).Update(cm)
+		assert.Nil(t, err)
+
+		select {
+		case err := <-result:
+			assert.NotNil(t, err)
+			select {
+			case <-stopped:
+			default:
+				t.Fatal("the controllers were not stopped")
+			}
+			return
+		case <-deadline:
+			t.Fatal("the leadership was not lost")
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,209 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package metrics exposes Prometheus metrics about the Rook operators themselves.
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/coreos/pkg/capnslog"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	// DefaultPort is the default port the operator serves metrics on
+	DefaultPort = 9090
+	// Path is the http path where the metrics are served
+	Path = "/metrics"
+
+	namespace = "rook"
+
+	resultSuccess = "success"
+	resultError   = "error"
+)
+
+var logger = capnslog.NewPackageLogger("github.com/rook/rook", "op-metrics")
+
+var (
+	reconcileTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "operator",
+			Name:      "reconcile_total",
+			Help:      "Number of reconciliations of a custom resource by controller and result",
+		},
+		[]string{"controller", "resource_namespace", "resource_name", "result"},
+	)
+
+	reconcileDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "operator",
+			Name:      "reconcile_duration_seconds",
+			Help:      "Duration of the reconciliation of a custom resource by controller",
+			Buckets:   []float64{0.1, 0.5, 1, 5, 10, 30, 60, 300, 900, 1800, 3600},
+		},
+		[]string{"controller", "resource_namespace", "resource_name"},
+	)
+
+	orchestrationFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "operator",
+			Name:      "orchestration_failures_total",
+			Help:      "Number of failed orchestrations by cluster namespace and component",
+		},
+		[]string{"resource_namespace", "component"},
+	)
+
+	cephCommandDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "ceph",
+			Name:      "command_duration_seconds",
+			Help:      "Latency of the ceph CLI tools invoked by the operator",
+			Buckets:   prometheus.ExponentialBuckets(0.05, 2, 12),
+		},
+		[]string{"tool", "command"},
+	)
+
+	cephCommandErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "ceph",
+			Name:      "command_errors_total",
+			Help:      "Number of failed invocations of the ceph CLI tools",
+		},
+		[]string{"tool", "command"},
+	)
+
+	monsPendingFailover = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "ceph",
+			Name:      "mons_pending_failover",
+			Help:      "Number of mons out of quorum and waiting for the failover timeout",
+		},
+		[]string{"resource_namespace"},
+	)
+
+	osdsDown = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "ceph",
+			Name:      "osds_down_past_grace_period",
+			Help:      "Number of osds that have been down for longer than the grace period",
+		},
+		[]string{"resource_namespace"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(
+		reconcileTotal,
+		reconcileDuration,
+		orchestrationFailures,
+		cephCommandDuration,
+		cephCommandErrors,
+		monsPendingFailover,
+		osdsDown,
+	)
+}
+
+// StartServer serves the metrics of the operator on the given port in the background.
+// A port of zero or less disables the metrics endpoint.
+func StartServer(port int) {
+	if port <= 0 {
+		logger.Infof("metrics endpoint is disabled")
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(Path, promhttp.Handler())
+	addr := fmt.Sprintf(":%d", port)
+	go func() {
+		logger.Infof("serving operator metrics on %s%s", addr, Path)
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			logger.Errorf("failed to serve metrics on %s. %+v", addr, err)
+		}
+	}()
+}
+
+// ObserveReconcile records the result and duration of a reconciliation that began at the given time
+func ObserveReconcile(controller, namespace, name string, start time.Time, err error) {
+	result := resultSuccess
+	if err != nil {
+		result = resultError
+	}
+	reconcileTotal.WithLabelValues(controller, namespace, name, result).Inc()
+	reconcileDuration.WithLabelValues(controller, namespace, name).Observe(time.Since(start).Seconds())
+}
+
+// IncOrchestrationFailure counts a failed orchestration of a component in the given namespace
+func IncOrchestrationFailure(namespace, component string) {
+	orchestrationFailures.WithLabelValues(namespace, component).Inc()
+}
+
+// ObserveCephCommand records the latency and the result of a ceph CLI invocation
+func ObserveCephCommand(tool string, args []string, start time.Time, err error) {
+	command := commandName(args)
+	cephCommandDuration.WithLabelValues(tool, command).Observe(time.Since(start).Seconds())
+	if err != nil {
+		cephCommandErrors.WithLabelValues(tool, command).Inc()
+	}
+}
+
+// SetMonsPendingFailover sets the number of mons waiting for failover in the given namespace
+func SetMonsPendingFailover(namespace string, count int) {
+	monsPendingFailover.WithLabelValues(namespace).Set(float64(count))
+}
+
+// SetOSDsDown sets the number of osds down past their grace period in the given namespace
+func SetOSDsDown(namespace string, count int) {
+	osdsDown.WithLabelValues(namespace).Set(float64(count))
+}
+
+// commandName keeps the cardinality of the command label bounded by only keeping the leading
+// sub-commands (e.g. "osd pool create") and dropping the names, ids and flags that follow.
+func commandName(args []string) string {
+	const maxWords = 3
+	name := ""
+	words := 0
+	for _, arg := range args {
+		if words == maxWords || len(arg) == 0 || arg[0] == '-' || !isCommandWord(arg) {
+			break
+		}
+		if name != "" {
+			name += " "
+		}
+		name += arg
+		words++
+	}
+	return name
+}
+
+func isCommandWord(arg string) bool {
+	for _, c := range arg {
+		if (c < 'a' || c > 'z') && c != '-' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package metrics
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommandName(t *testing.T) {
+	assert.Equal(t, "status", commandName([]string{"status", "--format", "json"}))
+	assert.Equal(t, "osd pool create", commandName([]string{"osd", "pool", "create", "replicapool", "100"}))
+	assert.Equal(t, "auth get-or-create-key", commandName([]string{"auth", "get-or-create-key", "client.admin", "mon", "allow *"}))
+	assert.Equal(t, "osd dump", commandName([]string{"osd", "dump", "--connect-timeout=15"}))
+	assert.Equal(t, "osd out", commandName([]string{"osd", "out", "osd.1"}))
+	assert.Equal(t, "", commandName([]string{}))
+	assert.Equal(t, "", commandName([]string{"--version"}))
+}
+
+func TestObserve(t *testing.T) {
+	ObserveReconcile("pool", "rook-ceph", "replicapool", time.Now(), nil)
+	ObserveReconcile("pool", "rook-ceph", "replicapool", time.Now(), nil)
+	ObserveReconcile("pool", "rook-ceph", "replicapool", time.Now(), fmt.Errorf("mock failure"))
+	assert.Equal(t, float64(2), testutil.ToFloat64(reconcileTotal.WithLabelValues("pool", "rook-ceph", "replicapool", resultSuccess)))
+	assert.Equal(t, float64(1), testutil.ToFloat64(reconcileTotal.WithLabelValues("pool", "rook-ceph", "replicapool", resultError)))
+	assert.Equal(t, uint64(3), sampleCount(t, reconcileDuration.WithLabelValues("pool", "rook-ceph", "replicapool")))
+
+	// the failed commands are also timed, and the command label only keeps the sub-commands
+	ObserveCephCommand("ceph", []string{"status", "--format", "json"}, time.Now(), nil)
+	ObserveCephCommand("rbd", []string{"create", "pool/image"}, time.Now(), fmt.Errorf("mock failure"))
+	assert.Equal(t, uint64(1), sampleCount(t, cephCommandDuration.WithLabelValues("ceph", "status")))
+	assert.Equal(t, uint64(1), sampleCount(t, cephCommandDuration.WithLabelValues("rbd", "create")))
+	assert.Equal(t, float64(0), testutil.ToFloat64(cephCommandErrors.WithLabelValues("ceph", "status")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(cephCommandErrors.WithLabelValues("rbd", "create")))
+
+	IncOrchestrationFailure("rook-ceph", "osd")
+	assert.Equal(t, float64(1), testutil.ToFloat64(orchestrationFailures.WithLabelValues("rook-ceph", "osd")))
+	assert.Equal(t, float64(0), testutil.ToFloat64(orchestrationFailures.WithLabelValues("rook-ceph", "mon")))
+
+	SetMonsPendingFailover("rook-ceph", 2)
+	SetOSDsDown("rook-ceph", 3)
+	assert.Equal(t, float64(2), testutil.ToFloat64(monsPendingFailover.WithLabelValues("rook-ceph")))
+	assert.Equal(t, float64(3), testutil.ToFloat64(osdsDown.WithLabelValues("rook-ceph")))
+	SetOSDsDown("rook-ceph", 0)
+	assert.Equal(t, float64(0), testutil.ToFloat64(osdsDown.WithLabelValues("rook-ceph")))
+}
+
+func sampleCount(t *testing.T, observer prometheus.Observer) uint64 {
+	var m dto.Metric
+	require.Nil(t, observer.(prometheus.Metric).Write(&m))
+	return m.GetHistogram().GetSampleCount()
+}
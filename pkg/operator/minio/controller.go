@@ -20,12 +20,14 @@ package minio
 import (
 	"fmt"
 	"reflect"
+	"time"
 
 	"github.com/coreos/pkg/capnslog"
 	opkit "github.com/rook/operator-kit"
 	miniov1alpha1 "github.com/rook/rook/pkg/apis/minio.rook.io/v1alpha1"
 	"github.com/rook/rook/pkg/clusterd"
 	"github.com/rook/rook/pkg/operator/k8sutil"
+	"github.com/rook/rook/pkg/operator/metrics"
 	apps "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
 	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
@@ -296,8 +298,14 @@ func (c *Controller) onAdd(obj interface{}) {
 		UID:        types.UID(objectstore.ObjectMeta.UID),
 	}
 
+	start := time.Now()
+	var err error
+	defer func() {
+		metrics.ObserveReconcile(ObjectStoreResource.Name, objectstore.Namespace, objectstore.Name, start, err)
+	}()
+
 	// Validate object store config.
-	err := validateObjectStoreSpec(objectstore.Spec)
+	err = validateObjectStoreSpec(objectstore.Spec)
 	if err != nil {
 		logger.Errorf("failed to validate object store config")
 		return
@@ -21,17 +21,21 @@ import (
 	"fmt"
 	"reflect"
 	s "strings"
+	"time"
 
 	"github.com/coreos/pkg/capnslog"
 	opkit "github.com/rook/operator-kit"
 	nfsv1alpha1 "github.com/rook/rook/pkg/apis/nfs.rook.io/v1alpha1"
 	"github.com/rook/rook/pkg/clusterd"
 	"github.com/rook/rook/pkg/operator/k8sutil"
+	"github.com/rook/rook/pkg/operator/metrics"
+	"github.com/rook/rook/pkg/operator/webhook"
 	"k8s.io/api/apps/v1beta1"
 	"k8s.io/api/core/v1"
 	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/tools/cache"
@@ -403,26 +407,34 @@ func (c *Controller) onAdd(obj interface{}) {
 
 	nfsServer := newNfsServer(nfsObj, c.context)
 
+	start := time.Now()
+	var reconcileErr error
+	defer func() { metrics.ObserveReconcile(NFSResource.Name, nfsObj.Namespace, nfsObj.Name, start, reconcileErr) }()
+
 	logger.Infof("new NFS server %s added to namespace %s", nfsObj.Name, nfsServer.namespace)
 
 	logger.Infof("validating nfs server spec in namespace %s", nfsServer.namespace)
 	if err := validateNFSServerSpec(nfsServer.spec); err != nil {
+		reconcileErr = err
 		logger.Errorf("Invalid NFS Server spec: %+v", err)
 		return
 	}
 
 	logger.Infof("creating nfs server service in namespace %s", nfsServer.namespace)
 	if err := c.createNFSService(nfsServer); err != nil {
+		reconcileErr = err
 		logger.Errorf("Unable to create NFS service %+v", err)
 	}
 
 	logger.Infof("creating nfs server configuration in namespace %s", nfsServer.namespace)
 	if err := c.createNFSConfigMap(nfsServer); err != nil {
+		reconcileErr = err
 		logger.Errorf("Unable to create NFS ConfigMap %+v", err)
 	}
 
 	logger.Infof("creating nfs server stateful set in namespace %s", nfsServer.namespace)
 	if err := c.createNfsStatefulSet(nfsServer, int32(nfsServer.spec.Replicas)); err != nil {
+		reconcileErr = err
 		logger.Errorf("Unable to create NFS stateful set %+v", err)
 	}
 }
@@ -438,6 +450,16 @@ func (c *Controller) onDelete(obj interface{}) {
 	logger.Infof("cluster %s deleted from namespace %s", cluster.Name, cluster.Namespace)
 }
 
+// Validator returns the validator of the NFSServers for the validating webhook
+func Validator() webhook.Validator {
+	return webhook.Validator{
+		NewObject: func() runtime.Object { return &nfsv1alpha1.NFSServer{} },
+		Validate: func(oldObj, newObj runtime.Object) error {
+			return validateNFSServerSpec(newObj.(*nfsv1alpha1.NFSServer).Spec)
+		},
+	}
+}
+
 func validateNFSServerSpec(spec nfsv1alpha1.NFSServerSpec) error {
 	serverConfig := spec.Exports
 	for _, export := range serverConfig {
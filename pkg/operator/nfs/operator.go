@@ -18,14 +18,14 @@ limitations under the License.
 package nfs
 
 import (
-	"os"
-	"os/signal"
-	"syscall"
-
 	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/operator/leader"
 	"k8s.io/api/core/v1"
 )
 
+// the name of the configmap storing the leader of the operator replicas
+const leaderLockName = "rook-nfs-operator-leader"
+
 // Operator type for managing NFS Server.
 type Operator struct {
 	context        *clusterd.Context
@@ -46,20 +46,12 @@ func New(context *clusterd.Context, containerImage string) *Operator {
 
 // Run the operator instance.
 func (o *Operator) Run() error {
-	signalChan := make(chan os.Signal, 1)
-	stopChan := make(chan struct{})
-	signal.Notify(signalChan, syscall.SIGINT, syscall.SIGTERM)
-
-	// Watch for changes to the nfs server.
-	o.controller.StartWatch(v1.NamespaceAll, stopChan)
-	logger.Infof("Started watch for NFS Servers")
-
-	for {
-		select {
-		case <-signalChan:
-			logger.Infof("shutdown signal received, exiting...")
-			close(stopChan)
-			return nil
-		}
-	}
+	return leader.Run(o.context.Clientset, leaderLockName, func(stopChan chan struct{}) error {
+		// Watch for changes to the nfs server.
+		o.controller.StartWatch(v1.NamespaceAll, stopChan)
+		logger.Infof("Started watch for NFS Servers")
+
+		<-stopChan
+		return nil
+	})
 }
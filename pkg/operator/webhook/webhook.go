@@ -0,0 +1,190 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package webhook serves a validating admission webhook rejecting invalid custom resources when they are applied
+// instead of when the operator reconciles them.
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"reflect"
+
+	"github.com/coreos/pkg/capnslog"
+	opkit "github.com/rook/operator-kit"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	// DefaultCertDir is the default directory with the tls.crt and tls.key files of the webhook server
+	DefaultCertDir = "/etc/rook/webhook"
+	// Path is the http path where the admission reviews are served
+	Path = "/validate"
+
+	certFile = "tls.crt"
+	keyFile  = "tls.key"
+)
+
+var logger = capnslog.NewPackageLogger("github.com/rook/rook", "op-webhook")
+
+// Validator validates the custom resources of a kind
+type Validator struct {
+	// NewObject returns an empty custom resource to decode the objects of the admission requests into
+	NewObject func() runtime.Object
+	// Validate returns an error if the object is invalid. The old object is nil when the object is created,
+	// otherwise the validation can reject the changes the operator can't apply.
+	Validate func(oldObj, newObj runtime.Object) error
+}
+
+// Server serves the admission reviews of the custom resources with a registered validator
+type Server struct {
+	validators map[schema.GroupResource]Validator
+}
+
+// NewServer creates a webhook server without validators
+func NewServer() *Server {
+	return &Server{validators: map[schema.GroupResource]Validator{}}
+}
+
+// Register validates the custom resource with the validator
+func (s *Server) Register(resource opkit.CustomResource, validator Validator) {
+	s.validators[schema.GroupResource{Group: resource.Group, Resource: resource.Plural}] = validator
+}
+
+// Start serves the admission reviews over TLS on the given port in the background, with the certificate in the
+// given directory. A port of zero or less disables the webhook.
+func (s *Server) Start(port int, certDir string) {
+	if port <= 0 {
+		logger.Infof("validating webhook is disabled")
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(Path, s)
+	addr := fmt.Sprintf(":%d", port)
+	go func() {
+		logger.Infof("serving the validating webhook of %d resources on %s%s", len(s.validators), addr, Path)
+		if err := http.ListenAndServeTLS(addr, path.Join(certDir, certFile), path.Join(certDir, keyFile), mux); err != nil {
+			logger.Errorf("failed to serve the validating webhook on %s. %+v", addr, err)
+		}
+	}()
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to read the request. %+v", err), http.StatusBadRequest)
+		return
+	}
+	review := admissionv1beta1.AdmissionReview{}
+	if err := json.Unmarshal(body, &review); err != nil {
+		http.Error(w, fmt.Sprintf("invalid admission review. %+v", err), http.StatusBadRequest)
+		return
+	}
+	if review.Request == nil {
+		http.Error(w, "admission review without request", http.StatusBadRequest)
+		return
+	}
+
+	review.Response = &admissionv1beta1.AdmissionResponse{UID: review.Request.UID, Allowed: true}
+	if err := s.validate(review.Request); err != nil {
+		logger.Infof("rejected %s of %s %s in namespace %s. %+v", review.Request.Operation, review.Request.Resource.Resource,
+			review.Request.Name, review.Request.Namespace, err)
+		review.Response.Allowed = false
+		review.Response.Result = &metav1.Status{Status: metav1.StatusFailure, Message: err.Error(), Reason: metav1.StatusReasonInvalid}
+	}
+	review.Request = nil
+
+	response, err := json.Marshal(review)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to encode the admission response. %+v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(response); err != nil {
+		logger.Errorf("failed to write the admission response. %+v", err)
+	}
+}
+
+func (s *Server) validate(request *admissionv1beta1.AdmissionRequest) error {
+	validator, ok := s.validators[schema.GroupResource{Group: request.Resource.Group, Resource: request.Resource.Resource}]
+	if !ok {
+		return nil
+	}
+
+	var old runtime.Object
+	switch request.Operation {
+	case admissionv1beta1.Create:
+	case admissionv1beta1.Update:
+		// the updates of the metadata and the status are always allowed, the operator must be able to update
+		// its finalizers and annotations even if the spec became invalid
+		specChanged, err := specChanged(request.OldObject.Raw, request.Object.Raw)
+		if err != nil {
+			return err
+		}
+		if !specChanged {
+			return nil
+		}
+		if old, err = decode(validator, request.OldObject.Raw, request.Namespace); err != nil {
+			return err
+		}
+	default:
+		return nil
+	}
+
+	obj, err := decode(validator, request.Object.Raw, request.Namespace)
+	if err != nil {
+		return err
+	}
+	return validator.Validate(old, obj)
+}
+
+func decode(validator Validator, raw []byte, namespace string) (runtime.Object, error) {
+	obj := validator.NewObject()
+	if err := json.Unmarshal(raw, obj); err != nil {
+		return nil, fmt.Errorf("failed to decode the object. %+v", err)
+	}
+
+	// the namespace of the request is not always set in the object when it is created
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the metadata of the object. %+v", err)
+	}
+	if accessor.GetNamespace() == "" {
+		accessor.SetNamespace(namespace)
+	}
+	return obj, nil
+}
+
+func specChanged(oldRaw, newRaw []byte) (bool, error) {
+	var oldObj, newObj struct {
+		Spec interface{} `json:"spec"`
+	}
+	if err := json.Unmarshal(oldRaw, &oldObj); err != nil {
+		return false, fmt.Errorf("failed to decode the old object. %+v", err)
+	}
+	if err := json.Unmarshal(newRaw, &newObj); err != nil {
+		return false, fmt.Errorf("failed to decode the object. %+v", err)
+	}
+	return !reflect.DeepEqual(oldObj.Spec, newObj.Spec), nil
+}
@@ -0,0 +1,154 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	opkit "github.com/rook/operator-kit"
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	"github.com/stretchr/testify/assert"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+var poolResource = opkit.CustomResource{Plural: "cephblockpools", Group: cephv1.CustomResourceGroup}
+
+func newTestServer() (*Server, *[]*cephv1.CephBlockPool) {
+	validated := []*cephv1.CephBlockPool{}
+	server := NewServer()
+	server.Register(poolResource, Validator{
+		NewObject: func() runtime.Object { return &cephv1.CephBlockPool{} },
+		Validate: func(oldObj, newObj runtime.Object) error {
+			p := newObj.(*cephv1.CephBlockPool)
+			validated = append(validated, p)
+			if p.Spec.Replicated.Size == 0 {
+				return fmt.Errorf("invalid size")
+			}
+			if oldObj != nil && oldObj.(*cephv1.CephBlockPool).Spec.FailureDomain != p.Spec.FailureDomain {
+				return fmt.Errorf("the failure domain can't change")
+			}
+			return nil
+		},
+	})
+	return server, &validated
+}
+
+func review(t *testing.T, server *Server, request *admissionv1beta1.AdmissionRequest) *admissionv1beta1.AdmissionResponse {
+	body, err := json.Marshal(admissionv1beta1.AdmissionReview{Request: request})
+	assert.Nil(t, err)
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodPost, Path, bytes.NewReader(body)))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	response := admissionv1beta1.AdmissionReview{}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.NotNil(t, response.Response)
+	assert.Equal(t, request.UID, response.Response.UID)
+	return response.Response
+}
+
+func poolRequest(t *testing.T, operation admissionv1beta1.Operation, oldPool, newPool *cephv1.CephBlockPool) *admissionv1beta1.AdmissionRequest {
+	request := &admissionv1beta1.AdmissionRequest{
+		UID:       types.UID("123"),
+		Resource:  metav1.GroupVersionResource{Group: cephv1.CustomResourceGroup, Version: cephv1.Version, Resource: "cephblockpools"},
+		Namespace: "ns",
+		Name:      "mypool",
+		Operation: operation,
+	}
+	var err error
+	if newPool != nil {
+		request.Object.Raw, err = json.Marshal(newPool)
+		assert.Nil(t, err)
+	}
+	if oldPool != nil {
+		request.OldObject.Raw, err = json.Marshal(oldPool)
+		assert.Nil(t, err)
+	}
+	return request
+}
+
+func TestValidateCreate(t *testing.T) {
+	server, validated := newTestServer()
+	p := &cephv1.CephBlockPool{ObjectMeta: metav1.ObjectMeta{Name: "mypool"}, Spec: cephv1.PoolSpec{Replicated: cephv1.ReplicatedSpec{Size: 1}}}
+
+	response := review(t, server, poolRequest(t, admissionv1beta1.Create, nil, p))
+	assert.True(t, response.Allowed)
+	assert.Equal(t, 1, len(*validated))
+	// the namespace of the request is set in the object
+	assert.Equal(t, "ns", (*validated)[0].Namespace)
+
+	p.Spec.Replicated.Size = 0
+	response = review(t, server, poolRequest(t, admissionv1beta1.Create, nil, p))
+	assert.False(t, response.Allowed)
+	assert.Equal(t, "invalid size", response.Result.Message)
+}
+
+func TestValidateUpdate(t *testing.T) {
+	server, validated := newTestServer()
+	old := &cephv1.CephBlockPool{ObjectMeta: metav1.ObjectMeta{Name: "mypool", Namespace: "ns"},
+		Spec: cephv1.PoolSpec{Replicated: cephv1.ReplicatedSpec{Size: 1}, FailureDomain: "host"}}
+
+	// the update of the metadata is not validated
+	p := old.DeepCopy()
+	p.Annotations = map[string]string{"a": "b"}
+	response := review(t, server, poolRequest(t, admissionv1beta1.Update, old, p))
+	assert.True(t, response.Allowed)
+	assert.Equal(t, 0, len(*validated))
+
+	p.Spec.Replicated.Size = 3
+	response = review(t, server, poolRequest(t, admissionv1beta1.Update, old, p))
+	assert.True(t, response.Allowed)
+	assert.Equal(t, 1, len(*validated))
+
+	p.Spec.FailureDomain = "osd"
+	response = review(t, server, poolRequest(t, admissionv1beta1.Update, old, p))
+	assert.False(t, response.Allowed)
+	assert.Equal(t, "the failure domain can't change", response.Result.Message)
+
+	// deletions are not validated
+	response = review(t, server, poolRequest(t, admissionv1beta1.Delete, old, nil))
+	assert.True(t, response.Allowed)
+	assert.Equal(t, 2, len(*validated))
+}
+
+func TestUnknownResource(t *testing.T) {
+	server, validated := newTestServer()
+	request := poolRequest(t, admissionv1beta1.Create, nil, &cephv1.CephBlockPool{})
+	request.Resource.Resource = "cephfilesystems"
+	response := review(t, server, request)
+	assert.True(t, response.Allowed)
+	assert.Equal(t, 0, len(*validated))
+}
+
+func TestInvalidReview(t *testing.T) {
+	server, _ := newTestServer()
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodPost, Path, bytes.NewReader([]byte("{"))))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodPost, Path, bytes.NewReader([]byte("{}"))))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
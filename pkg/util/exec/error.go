@@ -48,6 +48,15 @@ func (e *CommandError) ExitStatus() int {
 	return exitStatus
 }
 
+// IsNotFound returns whether the command failed because its executable was not found
+func IsNotFound(err error) bool {
+	if cmdErr, ok := err.(*CommandError); ok {
+		err = cmdErr.Err
+	}
+	execErr, ok := err.(*exec.Error)
+	return ok && execErr.Err == exec.ErrNotFound
+}
+
 func createCommandError(err error, actionName string) error {
 	return &CommandError{ActionName: actionName, Err: err}
 }
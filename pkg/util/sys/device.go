@@ -78,6 +78,8 @@ type LocalDisk struct {
 	WWNVendorExtension string `json:"wwnVendorExtension"`
 	// Empty checks whether the device is completely empty
 	Empty bool `json:"empty"`
+	// Health is the SMART health of the device, nil when smartctl could not read it
+	Health *DeviceHealth `json:"health,omitempty"`
 }
 
 func ListDevices(executor exec.Executor) ([]string, error) {
@@ -0,0 +1,124 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sys
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/rook/rook/pkg/util/exec"
+)
+
+const (
+	smartctl = "smartctl"
+
+	// ATA attribute ids
+	reallocatedSectorsAttr = 5
+	pendingSectorsAttr     = 197
+
+	// the bits of the smartctl exit status telling the device could not be read
+	smartctlFailureMask = 0x7
+	// the message of the versions of smartctl older than 7.0, which do not have the json output
+	smartctlUnrecognizedOption = "UNRECOGNIZED OPTION"
+)
+
+// ErrSmartctlUnsupported is returned when smartctl is not installed, or is older than 7.0 and has no json output
+var ErrSmartctlUnsupported = errors.New("smartctl 7.0 or newer is required to read the health of the devices")
+
+// DeviceHealth is the SMART health of a device
+type DeviceHealth struct {
+	// Passed is the overall SMART health self-assessment of the device
+	Passed bool `json:"passed"`
+	// ReallocatedSectors is the number of sectors the disk remapped after errors (ATA)
+	ReallocatedSectors int64 `json:"reallocatedSectors"`
+	// PendingSectors is the number of unstable sectors waiting to be remapped (ATA)
+	PendingSectors int64 `json:"pendingSectors"`
+	// MediaErrors is the number of unrecovered data integrity errors (NVMe)
+	MediaErrors int64 `json:"mediaErrors"`
+	// PercentageUsed is the estimate of the device life used, in percent of its rated endurance (NVMe)
+	PercentageUsed int `json:"percentageUsed"`
+	// Temperature is the current temperature of the device in Celsius
+	Temperature int `json:"temperature"`
+}
+
+// smartctlOutput is the subset of the smartctl json output read by rook
+type smartctlOutput struct {
+	Smartctl struct {
+		ExitStatus int `json:"exit_status"`
+	} `json:"smartctl"`
+	SmartStatus *struct {
+		Passed bool `json:"passed"`
+	} `json:"smart_status"`
+	ATASmartAttributes struct {
+		Table []struct {
+			ID  int `json:"id"`
+			Raw struct {
+				Value int64 `json:"value"`
+			} `json:"raw"`
+		} `json:"table"`
+	} `json:"ata_smart_attributes"`
+	NVMeHealth struct {
+		MediaErrors    int64 `json:"media_errors"`
+		PercentageUsed int   `json:"percentage_used"`
+	} `json:"nvme_smart_health_information_log"`
+	Temperature struct {
+		Current int `json:"current"`
+	} `json:"temperature"`
+}
+
+// GetDeviceHealth reads the SMART health of the device with smartctl. smartctl 7.0 or newer is required for the
+// json output.
+func GetDeviceHealth(device string, executor exec.Executor) (*DeviceHealth, error) {
+	cmd := fmt.Sprintf("get smart health of %s", device)
+	// smartctl exits with a non-zero status when the disk is failing, the status is read from the output instead
+	output, err := executor.ExecuteCommandWithOutput(true, cmd, smartctl, "--json", "--all", fmt.Sprintf("/dev/%s", device))
+	if exec.IsNotFound(err) || strings.Contains(output, smartctlUnrecognizedOption) {
+		return nil, ErrSmartctlUnsupported
+	}
+	return parseSmartctlOutput(device, output)
+}
+
+func parseSmartctlOutput(device, output string) (*DeviceHealth, error) {
+	var result smartctlOutput
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		return nil, fmt.Errorf("failed to parse smartctl output for device %s. %+v", device, err)
+	}
+	if result.Smartctl.ExitStatus&smartctlFailureMask != 0 {
+		return nil, fmt.Errorf("smartctl failed to read device %s, exit status %d", device, result.Smartctl.ExitStatus)
+	}
+	if result.SmartStatus == nil {
+		return nil, fmt.Errorf("smart is not available on device %s", device)
+	}
+
+	health := &DeviceHealth{
+		Passed:         result.SmartStatus.Passed,
+		MediaErrors:    result.NVMeHealth.MediaErrors,
+		PercentageUsed: result.NVMeHealth.PercentageUsed,
+		Temperature:    result.Temperature.Current,
+	}
+	for _, attr := range result.ATASmartAttributes.Table {
+		switch attr.ID {
+		case reallocatedSectorsAttr:
+			health.ReallocatedSectors = attr.Raw.Value
+		case pendingSectorsAttr:
+			health.PendingSectors = attr.Raw.Value
+		}
+	}
+	return health, nil
+}
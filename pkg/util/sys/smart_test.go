@@ -0,0 +1,119 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sys
+
+import (
+	"errors"
+	osexec "os/exec"
+	"testing"
+
+	"github.com/rook/rook/pkg/util/exec"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	smartctlATAOutput = `{
+  "smartctl": {"version": [7, 0], "exit_status": 8},
+  "device": {"name": "/dev/sda", "type": "sat"},
+  "smart_status": {"passed": false},
+  "ata_smart_attributes": {
+    "revision": 16,
+    "table": [
+      {"id": 1, "name": "Raw_Read_Error_Rate", "raw": {"value": 0, "string": "0"}},
+      {"id": 5, "name": "Reallocated_Sector_Ct", "raw": {"value": 112, "string": "112"}},
+      {"id": 197, "name": "Current_Pending_Sector", "raw": {"value": 16, "string": "16"}}
+    ]
+  },
+  "temperature": {"current": 38}
+}`
+	smartctlNVMeOutput = `{
+  "smartctl": {"version": [7, 0], "exit_status": 0},
+  "device": {"name": "/dev/nvme0n1", "type": "nvme"},
+  "smart_status": {"passed": true},
+  "nvme_smart_health_information_log": {
+    "critical_warning": 0,
+    "temperature": 41,
+    "percentage_used": 87,
+    "media_errors": 4
+  },
+  "temperature": {"current": 41}
+}`
+	smartctlUnavailableOutput = `{
+  "smartctl": {"version": [7, 0], "exit_status": 2},
+  "device": {"name": "/dev/rbd0"}
+}`
+)
+
+func TestGetDeviceHealth(t *testing.T) {
+	output := ""
+	// smartctl exits with a non-zero status when the disk is failing
+	var execErr error = errors.New("exit status 8")
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName string, command string, args ...string) (string, error) {
+			assert.Equal(t, "smartctl", command)
+			assert.Equal(t, []string{"--json", "--all", "/dev/sda"}, args)
+			return output, execErr
+		},
+	}
+
+	// a failing ATA disk
+	output = smartctlATAOutput
+	health, err := GetDeviceHealth("sda", executor)
+	assert.Nil(t, err)
+	assert.False(t, health.Passed)
+	assert.Equal(t, int64(112), health.ReallocatedSectors)
+	assert.Equal(t, int64(16), health.PendingSectors)
+	assert.Equal(t, int64(0), health.MediaErrors)
+	assert.Equal(t, 38, health.Temperature)
+
+	// a worn out NVMe disk
+	output = smartctlNVMeOutput
+	health, err = GetDeviceHealth("sda", executor)
+	assert.Nil(t, err)
+	assert.True(t, health.Passed)
+	assert.Equal(t, int64(0), health.ReallocatedSectors)
+	assert.Equal(t, int64(4), health.MediaErrors)
+	assert.Equal(t, 87, health.PercentageUsed)
+	assert.Equal(t, 41, health.Temperature)
+
+	// the device could not be read
+	output = smartctlUnavailableOutput
+	health, err = GetDeviceHealth("sda", executor)
+	assert.NotNil(t, err)
+	assert.Nil(t, health)
+
+	// the output is empty
+	output = ""
+	health, err = GetDeviceHealth("sda", executor)
+	assert.NotNil(t, err)
+	assert.NotEqual(t, ErrSmartctlUnsupported, err)
+	assert.Nil(t, health)
+
+	// smartctl is older than 7.0 and has no json output
+	output = "smartctl 6.6 2016-05-31 r4324\n=======> UNRECOGNIZED OPTION: json\n"
+	execErr = errors.New("exit status 1")
+	health, err = GetDeviceHealth("sda", executor)
+	assert.Equal(t, ErrSmartctlUnsupported, err)
+	assert.Nil(t, health)
+
+	// smartctl is not installed
+	output = ""
+	execErr = &exec.CommandError{ActionName: "get smart health of sda", Err: &osexec.Error{Name: "smartctl", Err: osexec.ErrNotFound}}
+	health, err = GetDeviceHealth("sda", executor)
+	assert.Equal(t, ErrSmartctlUnsupported, err)
+	assert.Nil(t, health)
+}
@@ -380,8 +380,10 @@ func (h *CephInstaller) UninstallRookFromMultipleNS(helmInstalled bool, systemNa
 		"cephblockpools.ceph.rook.io",
 		"cephobjectstores.ceph.rook.io",
 		"cephobjectstoreusers.ceph.rook.io",
+		"cephdashboardusers.ceph.rook.io",
 		"cephfilesystems.ceph.rook.io",
-		"volumes.rook.io")
+		"volumes.rook.io",
+		"storagedevices.rook.io")
 	checkError(h.T(), err, "cannot delete CRDs")
 
 	if helmInstalled {
@@ -198,6 +198,20 @@ spec:
 ---
 apiVersion: apiextensions.k8s.io/v1beta1
 kind: CustomResourceDefinition
+metadata:
+  name: cephdashboardusers.ceph.rook.io
+spec:
+  group: ceph.rook.io
+  names:
+    kind: CephDashboardUser
+    listKind: CephDashboardUserList
+    plural: cephdashboardusers
+    singular: cephdashboarduser
+  scope: Namespaced
+  version: v1
+---
+apiVersion: apiextensions.k8s.io/v1beta1
+kind: CustomResourceDefinition
 metadata:
   name: cephblockpools.ceph.rook.io
 spec:
@@ -222,7 +236,37 @@ spec:
     plural: volumes
     singular: volume
   scope: Namespaced
-  version: v1alpha2`
+  version: v1alpha2
+---
+apiVersion: apiextensions.k8s.io/v1beta1
+kind: CustomResourceDefinition
+metadata:
+  name: storagedevices.rook.io
+spec:
+  group: rook.io
+  names:
+    kind: StorageDevice
+    listKind: StorageDeviceList
+    plural: storagedevices
+    singular: storagedevice
+  scope: Cluster
+  version: v1alpha2
+  additionalPrinterColumns:
+  - name: Node
+    type: string
+    JSONPath: .spec.node
+  - name: Device
+    type: string
+    JSONPath: .spec.name
+  - name: Size
+    type: integer
+    JSONPath: .spec.size
+  - name: Rotational
+    type: boolean
+    JSONPath: .spec.rotational
+  - name: ClaimedBy
+    type: string
+    JSONPath: .spec.claimedBy`
 }
 
 // GetRookOperator returns rook Operator manifest
@@ -317,6 +361,18 @@ rules:
   - create
   - update
   - delete
+- apiGroups:
+  - extensions
+  resources:
+  # ingresses are used to expose the dashboard
+  - ingresses
+  verbs:
+  - get
+  - list
+  - watch
+  - create
+  - update
+  - delete
 ---
 apiVersion: rbac.authorization.k8s.io/v1beta1
 kind: ClusterRole